@@ -0,0 +1,78 @@
+package beacon
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/byzcoin/contracts"
+	"go.dedis.ch/kyber/v3/sign/bls"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/network"
+)
+
+// Client is a structure to communicate with the beacon service.
+type Client struct {
+	*onet.Client
+}
+
+// NewClient instantiates a new beacon client.
+func NewClient() *Client {
+	return &Client{Client: onet.NewClient(cothority.Suite, ServiceName)}
+}
+
+// Setup asks the first node of the roster to create a beacon with a round
+// every interval. A threshold of 0 uses the default of the DKG.
+func (c *Client) Setup(r *onet.Roster, interval time.Duration, threshold uint32) (*SetupReply, error) {
+	if len(r.List) == 0 {
+		return nil, errors.New("Got an empty roster-list")
+	}
+	reply := &SetupReply{}
+	err := c.SendProtobuf(r.List[0], &Setup{
+		Roster:    r,
+		Interval:  interval,
+		Threshold: threshold,
+	}, reply)
+	if err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+// GetRound asks the node for a round of the beacon, or for the latest round
+// if round is 0. The round is verified against the public key of the
+// beacon, which has to be known to the caller.
+func (c *Client) GetRound(si *network.ServerIdentity, id ID, round uint64, public []byte) (*RoundReply, error) {
+	reply := &RoundReply{}
+	err := c.SendProtobuf(si, &GetRound{ID: id, Round: round}, reply)
+	if err != nil {
+		return nil, err
+	}
+	if err := VerifyRound(public, reply); err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+// VerifyRound checks that the round is signed by the distributed key of the
+// beacon and that the randomness comes from the signature.
+func VerifyRound(public []byte, r *RoundReply) error {
+	if !bytes.Equal(public, r.Public) {
+		return errors.New("round of another beacon")
+	}
+	point := suite.G2().Point()
+	if err := point.UnmarshalBinary(public); err != nil {
+		return err
+	}
+	err := bls.Verify(suite, point, contracts.RandomnessMessage(r.Previous, r.Round), r.Signature)
+	if err != nil {
+		return fmt.Errorf("invalid signature of round %d: %v", r.Round, err)
+	}
+	value := contracts.Randomness{Round: r.Round, Signature: r.Signature}.Value()
+	if !bytes.Equal(value, r.Randomness) {
+		return errors.New("randomness doesn't match the signature")
+	}
+	return nil
+}
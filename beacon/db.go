@@ -0,0 +1,147 @@
+package beacon
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/share"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/log"
+)
+
+const dbVersion = 1
+
+var storageKey = []byte("storage")
+
+// maxHistory is the number of rounds kept by the nodes for every beacon.
+const maxHistory = 10000
+
+// storage holds the beacons the node takes part in.
+type storage struct {
+	Beacons map[ID]*beaconState
+
+	sync.Mutex
+}
+
+// beaconState is a beacon as seen by a node, with the share of the node of
+// the distributed key. The points and scalars are marshalled, as they are
+// on G2 of bn256.
+type beaconState struct {
+	Roster     *onet.Roster
+	Interval   time.Duration
+	Threshold  uint32
+	Public     []byte
+	Commits    [][]byte
+	ShareIndex int
+	Share      []byte
+	Latest     uint64
+	Rounds     map[uint64][]byte
+}
+
+// pubPoly returns the public polynomial of the distributed key, to verify
+// the partial signatures.
+func (b *beaconState) pubPoly() (*share.PubPoly, error) {
+	commits := make([]kyber.Point, len(b.Commits))
+	for i, c := range b.Commits {
+		commits[i] = suite.G2().Point()
+		if err := commits[i].UnmarshalBinary(c); err != nil {
+			return nil, err
+		}
+	}
+	return share.NewPubPoly(suite.G2(), suite.G2().Point().Base(), commits), nil
+}
+
+// priShare returns the share of the node.
+func (b *beaconState) priShare() (*share.PriShare, error) {
+	v := suite.G2().Scalar()
+	if err := v.UnmarshalBinary(b.Share); err != nil {
+		return nil, err
+	}
+	return &share.PriShare{I: b.ShareIndex, V: v}, nil
+}
+
+// signature returns the signature of the round, round 0 having no
+// signature.
+func (b *beaconState) signature(round uint64) ([]byte, bool) {
+	if round == 0 {
+		return nil, true
+	}
+	sig, ok := b.Rounds[round]
+	return sig, ok
+}
+
+// addRound stores the signature of the round and drops the rounds too old
+// to be kept.
+func (b *beaconState) addRound(round uint64, sig []byte) {
+	if round == 0 {
+		return
+	}
+	b.Rounds[round] = sig
+	if round > b.Latest {
+		b.Latest = round
+	}
+	for r := range b.Rounds {
+		if r+maxHistory <= b.Latest {
+			delete(b.Rounds, r)
+		}
+	}
+}
+
+// saves all data.
+func (s *Service) save() error {
+	s.storage.Lock()
+	defer s.storage.Unlock()
+	err := s.Save(storageKey, s.storage)
+	if err != nil {
+		log.Error("Couldn't save data:", err)
+		return fmt.Errorf("saving data: %v", err)
+	}
+	return nil
+}
+
+// Tries to load the configuration and updates the data in the service
+// if it finds a valid config-file.
+func (s *Service) tryLoad() error {
+	s.storage = &storage{}
+	ver, err := s.LoadVersion()
+	if err != nil {
+		return fmt.Errorf("loading configuration: %v", err)
+	}
+
+	// Make sure we don't have any unallocated maps.
+	defer func() {
+		if len(s.storage.Beacons) == 0 {
+			s.storage.Beacons = make(map[ID]*beaconState)
+		}
+		for _, b := range s.storage.Beacons {
+			if len(b.Rounds) == 0 {
+				b.Rounds = make(map[uint64][]byte)
+			}
+		}
+	}()
+
+	if ver < dbVersion {
+		// There is no version 0. Save empty storage and update version number.
+		if err = s.save(); err != nil {
+			return fmt.Errorf("saving storage: %v", err)
+		}
+		return cothority.ErrorOrNil(s.SaveVersion(dbVersion), "saving version")
+	}
+	msg, err := s.Load(storageKey)
+	if err != nil {
+		return fmt.Errorf("loading storage: %v", err)
+	}
+	if msg == nil {
+		return nil
+	}
+	var ok bool
+	s.storage, ok = msg.(*storage)
+	if !ok {
+		return errors.New("data of wrong type")
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package beacon
+
+import (
+	"errors"
+	"time"
+
+	"go.dedis.ch/cothority/v3/byzcoin/contracts"
+	"go.dedis.ch/kyber/v3/share"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/log"
+)
+
+// roundName is the protocol of a round of the beacon.
+const roundName = "BeaconRound"
+
+func init() {
+	onet.GlobalProtocolRegister(roundName, newRoundProtocol)
+}
+
+// roundProtocol runs a round of a beacon on a tree of depth one. The leader
+// sends the announcement, the nodes send back their partial signature, and
+// the leader recovers the signature from the first Threshold valid ones and
+// sends it to the nodes.
+type roundProtocol struct {
+	*onet.TreeNodeInstance
+
+	// Announcement is the round to run, set by the leader.
+	Announcement *Announcement
+	// Threshold and Public are needed by the leader to recover the
+	// signature.
+	Threshold int
+	Public    *share.PubPoly
+	Timeout   time.Duration
+	// Sign returns the partial signature of the node, if it agrees to sign
+	// the round.
+	Sign func(*Announcement) ([]byte, error)
+	// Store is called by all the nodes with the result of the round.
+	Store func(*RoundResult) error
+	// Finished gets the signature of the round at the leader, or nil if it
+	// failed.
+	Finished chan []byte
+
+	partials chan partialChan
+}
+
+type partialChan struct {
+	*onet.TreeNode
+	Partial
+}
+
+func newRoundProtocol(n *onet.TreeNodeInstance) (onet.ProtocolInstance, error) {
+	p := &roundProtocol{
+		TreeNodeInstance: n,
+		Timeout:          10 * time.Second,
+		Finished:         make(chan []byte, 1),
+	}
+	if err := n.RegisterHandlers(p.handleAnnouncement, p.handleResult); err != nil {
+		return nil, err
+	}
+	if err := n.RegisterChannelLength(&p.partials, len(n.Roster().List)); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Start sends the announcement to the nodes and collects their partial
+// signatures in the background.
+func (p *roundProtocol) Start() error {
+	if p.Announcement == nil || p.Sign == nil || p.Public == nil {
+		p.Done()
+		return errors.New("round is not set up")
+	}
+	if errs := p.Broadcast(p.Announcement); len(errs) > 0 {
+		log.Warnf("%v could not announce the round to all nodes: %v", p.ServerIdentity(), errs)
+	}
+	go p.collect()
+	return nil
+}
+
+// collect waits for the partial signatures until the signature can be
+// recovered, and ends the round.
+func (p *roundProtocol) collect() {
+	defer p.Done()
+	a := p.Announcement
+	var sigs [][]byte
+	if sig, err := p.Sign(a); err != nil {
+		log.Errorf("%v cannot sign its own round: %v", p.ServerIdentity(), err)
+	} else {
+		sigs = append(sigs, sig)
+	}
+
+	n := len(p.Roster().List)
+	var final []byte
+	tryRecover := func() {
+		if len(sigs) < p.Threshold {
+			return
+		}
+		sig, err := contracts.RandomnessRecover(p.Public, a.Previous, a.Round, sigs, p.Threshold, n)
+		if err == nil {
+			final = sig
+		}
+	}
+	tryRecover()
+	timeout := time.After(p.Timeout)
+	for received := 1; final == nil && received < n; {
+		select {
+		case partial := <-p.partials:
+			received++
+			if len(partial.Signature) > 0 {
+				sigs = append(sigs, partial.Signature)
+				tryRecover()
+			}
+		case <-timeout:
+			log.Lvl2(p.ServerIdentity(), "timeout of round", a.Round)
+			received = n
+		}
+	}
+
+	res := &RoundResult{ID: a.ID, Round: a.Round, Previous: a.Previous, Signature: final}
+	p.Broadcast(res)
+	if final != nil && p.Store != nil {
+		if err := p.Store(res); err != nil {
+			log.Error(err)
+		}
+	}
+	p.Finished <- final
+}
+
+// handleAnnouncement sends the partial signature of the node to the leader,
+// or an empty one if it doesn't sign the round.
+func (p *roundProtocol) handleAnnouncement(a announcementChan) error {
+	var sig []byte
+	var err error
+	if p.Sign != nil {
+		sig, err = p.Sign(&a.Announcement)
+	} else {
+		err = errors.New("no signer")
+	}
+	if err != nil {
+		log.Lvlf2("%v doesn't sign round %d: %v", p.ServerIdentity(), a.Round, err)
+	}
+	return p.SendToParent(&Partial{Signature: sig})
+}
+
+// handleResult stores the result of the round and ends the protocol on the
+// node.
+func (p *roundProtocol) handleResult(r resultChan) error {
+	defer p.Done()
+	if r.Signature == nil || p.Store == nil {
+		return nil
+	}
+	return p.Store(&r.RoundResult)
+}
+
+type announcementChan struct {
+	*onet.TreeNode
+	Announcement
+}
+
+type resultChan struct {
+	*onet.TreeNode
+	RoundResult
+}
@@ -0,0 +1,395 @@
+// Package beacon implements a randomness beacon run by the nodes of a
+// roster. Like drand, the nodes create a distributed key on G2 of bn256,
+// and every round is the threshold BLS signature of the nodes on the
+// signature of the previous round. As the signature is unique, no set of
+// nodes below the threshold can bias or predict the randomness. The rounds
+// can be stored in byzcoin with the randomness contract.
+package beacon
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.dedis.ch/cothority/v3/byzcoin/contracts"
+	dkgprotocol "go.dedis.ch/cothority/v3/dkg/pedersen"
+	"go.dedis.ch/kyber/v3/pairing"
+	"go.dedis.ch/kyber/v3/pairing/bn256"
+	dkg "go.dedis.ch/kyber/v3/share/dkg/pedersen"
+	vss "go.dedis.ch/kyber/v3/share/vss/pedersen"
+	"go.dedis.ch/kyber/v3/sign/bls"
+	"go.dedis.ch/kyber/v3/util/key"
+	"go.dedis.ch/kyber/v3/util/random"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/onet/v3/network"
+	"go.dedis.ch/protobuf"
+)
+
+// ServiceName is the name of the beacon service.
+const ServiceName = "RandomnessBeacon"
+
+// dkgName is the DKG protocol creating the distributed key of a beacon.
+const dkgName = "BeaconDKG"
+
+// setupTimeout is how long the leader waits for the DKG to finish.
+const setupTimeout = 20 * time.Second
+
+var suite = pairing.NewSuiteBn256()
+
+// dkgSuite is G2 of bn256, on which the distributed keys are.
+var dkgSuite = bn256.NewSuite().G2().(vss.Suite)
+
+var beaconServiceID onet.ServiceID
+
+func init() {
+	var err error
+	beaconServiceID, err = onet.RegisterNewService(ServiceName, newService)
+	log.ErrFatal(err)
+	_, err = onet.GlobalProtocolRegister(dkgName, func(n *onet.TreeNodeInstance) (onet.ProtocolInstance, error) {
+		return dkgprotocol.CustomSetup(n, dkgSuite, nil)
+	})
+	log.ErrFatal(err)
+}
+
+// Service runs the beacons the node takes part in. The leader of a beacon
+// is the first node of its roster, and starts the rounds.
+type Service struct {
+	*onet.ServiceProcessor
+	storage   *storage
+	closing   chan bool
+	closeOnce sync.Once
+}
+
+// Setup creates the distributed key of a new beacon with the nodes of the
+// roster, and starts its rounds.
+func (s *Service) Setup(req *Setup) (*SetupReply, error) {
+	if req.Roster == nil || len(req.Roster.List) == 0 {
+		return nil, errors.New("no roster given")
+	}
+	if !req.Roster.List[0].Equal(s.ServerIdentity()) {
+		return nil, errors.New("the leader must be the first node of the roster")
+	}
+	if req.Interval <= 0 {
+		return nil, errors.New("the interval must be positive")
+	}
+	if int(req.Threshold) > len(req.Roster.List) {
+		return nil, fmt.Errorf("threshold %d is bigger than the roster", req.Threshold)
+	}
+	tree := req.Roster.GenerateNaryTreeWithRoot(len(req.Roster.List), s.ServerIdentity())
+	if tree == nil {
+		return nil, errors.New("error while generating tree")
+	}
+
+	var id ID
+	random.Bytes(id[:], random.New())
+	cfgBuf, err := protobuf.Encode(&setupConfig{ID: id, Interval: req.Interval})
+	if err != nil {
+		return nil, fmt.Errorf("serializing configuration: %v", err)
+	}
+	pi, err := s.CreateProtocol(dkgName, tree)
+	if err != nil {
+		return nil, fmt.Errorf("creating dkg protocol: %v", err)
+	}
+	setupDKG := pi.(*dkgprotocol.Setup)
+	setupDKG.Wait = true
+	if req.Threshold > 0 {
+		setupDKG.Threshold = req.Threshold
+	}
+	if err := setupDKG.SetConfig(&onet.GenericConfig{Data: cfgBuf}); err != nil {
+		return nil, fmt.Errorf("set dkg config: %v", err)
+	}
+	setupDKG.KeyPair = key.NewKeyPair(dkgSuite)
+	if err := pi.Start(); err != nil {
+		return nil, fmt.Errorf("starting dkg protocol: %v", err)
+	}
+
+	select {
+	case <-setupDKG.Finished:
+	case <-time.After(setupTimeout):
+		return nil, errors.New("dkg didn't finish in time")
+	}
+	_, dks, err := setupDKG.SharedSecret()
+	if err != nil {
+		return nil, fmt.Errorf("get shared secret: %v", err)
+	}
+	b, err := s.storeBeacon(id, req.Roster, req.Interval, dks)
+	if err != nil {
+		return nil, err
+	}
+	log.Lvlf2("%v created beacon %v", s.ServerIdentity(), id)
+	go s.run(id)
+	return &SetupReply{ID: id, Public: b.Public}, nil
+}
+
+// GetRound returns a round of the beacon, or the latest round if the round
+// is 0.
+func (s *Service) GetRound(req *GetRound) (*RoundReply, error) {
+	s.storage.Lock()
+	defer s.storage.Unlock()
+	b, ok := s.storage.Beacons[req.ID]
+	if !ok {
+		return nil, fmt.Errorf("unknown beacon %v", req.ID)
+	}
+	round := req.Round
+	if round == 0 {
+		round = b.Latest
+	}
+	if round == 0 {
+		return nil, errors.New("no round yet")
+	}
+	sig, ok := b.signature(round)
+	if !ok {
+		return nil, fmt.Errorf("unknown round %d", round)
+	}
+	prev, ok := b.signature(round - 1)
+	if !ok {
+		return nil, fmt.Errorf("round %d is too old", round)
+	}
+	return &RoundReply{
+		Round:      round,
+		Previous:   prev,
+		Signature:  sig,
+		Randomness: contracts.Randomness{Round: round, Signature: sig}.Value(),
+		Public:     b.Public,
+	}, nil
+}
+
+// NewProtocol intercepts the protocols of the beacon to give them the
+// share of the node.
+func (s *Service) NewProtocol(tn *onet.TreeNodeInstance, conf *onet.GenericConfig) (onet.ProtocolInstance, error) {
+	switch tn.ProtocolName() {
+	case dkgName:
+		if conf == nil {
+			return nil, errors.New("missing dkg config")
+		}
+		var cfg setupConfig
+		if err := protobuf.Decode(conf.Data, &cfg); err != nil {
+			return nil, fmt.Errorf("decoding dkg config: %v", err)
+		}
+		s.storage.Lock()
+		_, exists := s.storage.Beacons[cfg.ID]
+		s.storage.Unlock()
+		if exists {
+			return nil, fmt.Errorf("beacon %v already exists", cfg.ID)
+		}
+
+		pi, err := dkgprotocol.CustomSetup(tn, dkgSuite, key.NewKeyPair(dkgSuite))
+		if err != nil {
+			return nil, fmt.Errorf("error setting up dkg: %v", err)
+		}
+		setupDKG := pi.(*dkgprotocol.Setup)
+		go func() {
+			<-setupDKG.Finished
+			_, dks, err := setupDKG.SharedSecret()
+			if err != nil {
+				log.Error(err)
+				return
+			}
+			if _, err := s.storeBeacon(cfg.ID, tn.Roster(), cfg.Interval, dks); err != nil {
+				log.Error(err)
+			}
+		}()
+		return pi, nil
+	case roundName:
+		pi, err := newRoundProtocol(tn)
+		if err != nil {
+			return nil, err
+		}
+		p := pi.(*roundProtocol)
+		leader := tn.Root().ServerIdentity
+		p.Sign = func(a *Announcement) ([]byte, error) {
+			return s.signRound(leader, a)
+		}
+		p.Store = s.storeRound
+		return pi, nil
+	}
+	return nil, nil
+}
+
+// run starts the rounds of the beacon every interval, until the service is
+// closed.
+func (s *Service) run(id ID) {
+	s.storage.Lock()
+	interval := s.storage.Beacons[id].Interval
+	s.storage.Unlock()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.closing:
+			return
+		case <-ticker.C:
+			if err := s.runRound(id); err != nil {
+				log.Error(s.ServerIdentity(), err)
+			}
+		}
+	}
+}
+
+// runRound runs the round following the latest round of the beacon. A
+// failed round is run again at the next interval.
+func (s *Service) runRound(id ID) error {
+	s.storage.Lock()
+	b := s.storage.Beacons[id]
+	round := b.Latest + 1
+	prev, _ := b.signature(b.Latest)
+	roster := b.Roster
+	threshold := int(b.Threshold)
+	interval := b.Interval
+	public, err := b.pubPoly()
+	s.storage.Unlock()
+	if err != nil {
+		return err
+	}
+
+	children := len(roster.List) - 1
+	if children < 1 {
+		children = 1
+	}
+	tree := roster.GenerateNaryTreeWithRoot(children, s.ServerIdentity())
+	if tree == nil {
+		return errors.New("error while generating tree")
+	}
+	pi, err := s.CreateProtocol(roundName, tree)
+	if err != nil {
+		return err
+	}
+	p := pi.(*roundProtocol)
+	p.Announcement = &Announcement{ID: id, Round: round, Previous: prev}
+	p.Threshold = threshold
+	p.Public = public
+	p.Timeout = interval
+	p.Sign = func(a *Announcement) ([]byte, error) {
+		return s.signRound(s.ServerIdentity(), a)
+	}
+	p.Store = s.storeRound
+	if err := p.Start(); err != nil {
+		return err
+	}
+	if sig := <-p.Finished; sig == nil {
+		return fmt.Errorf("round %d of beacon %v failed", round, id)
+	}
+	return nil
+}
+
+// signRound returns the partial signature of the node for the round, if it
+// is asked by the leader of the beacon and the previous round is the one
+// known to the node.
+func (s *Service) signRound(leader *network.ServerIdentity, a *Announcement) ([]byte, error) {
+	s.storage.Lock()
+	defer s.storage.Unlock()
+	b, ok := s.storage.Beacons[a.ID]
+	if !ok {
+		return nil, fmt.Errorf("unknown beacon %v", a.ID)
+	}
+	if !b.Roster.List[0].Equal(leader) {
+		return nil, errors.New("the round is not started by the leader")
+	}
+	if a.Round == 0 {
+		return nil, errors.New("there is no round 0")
+	}
+	prev, ok := b.signature(a.Round - 1)
+	if !ok {
+		return nil, fmt.Errorf("unknown round %d", a.Round-1)
+	}
+	if !bytes.Equal(prev, a.Previous) {
+		return nil, fmt.Errorf("wrong signature of round %d", a.Round-1)
+	}
+	priv, err := b.priShare()
+	if err != nil {
+		return nil, err
+	}
+	return contracts.RandomnessSign(priv, prev, a.Round)
+}
+
+// storeRound verifies the signature of the round and stores it. As enough
+// nodes signed the round, its previous round is stored as well, so that
+// the node can sign the next round even if it missed the previous one.
+func (s *Service) storeRound(r *RoundResult) error {
+	if r.Round == 0 {
+		return errors.New("there is no round 0")
+	}
+	s.storage.Lock()
+	b, ok := s.storage.Beacons[r.ID]
+	if !ok {
+		s.storage.Unlock()
+		return fmt.Errorf("unknown beacon %v", r.ID)
+	}
+	public := suite.G2().Point()
+	if err := public.UnmarshalBinary(b.Public); err != nil {
+		s.storage.Unlock()
+		return err
+	}
+	err := bls.Verify(suite, public, contracts.RandomnessMessage(r.Previous, r.Round), r.Signature)
+	if err != nil {
+		s.storage.Unlock()
+		return fmt.Errorf("invalid signature of round %d: %v", r.Round, err)
+	}
+	if _, ok := b.signature(r.Round - 1); !ok {
+		b.addRound(r.Round-1, r.Previous)
+	}
+	b.addRound(r.Round, r.Signature)
+	s.storage.Unlock()
+	return s.save()
+}
+
+// storeBeacon stores the share of the node of a new beacon.
+func (s *Service) storeBeacon(id ID, roster *onet.Roster, interval time.Duration,
+	dks *dkg.DistKeyShare) (*beaconState, error) {
+	public, err := dks.Public().MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	commits := make([][]byte, len(dks.Commits))
+	for i, c := range dks.Commits {
+		if commits[i], err = c.MarshalBinary(); err != nil {
+			return nil, err
+		}
+	}
+	priv, err := dks.Share.V.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	b := &beaconState{
+		Roster:     roster,
+		Interval:   interval,
+		Threshold:  uint32(len(dks.Commits)),
+		Public:     public,
+		Commits:    commits,
+		ShareIndex: dks.Share.I,
+		Share:      priv,
+		Rounds:     make(map[uint64][]byte),
+	}
+	s.storage.Lock()
+	s.storage.Beacons[id] = b
+	s.storage.Unlock()
+	return b, s.save()
+}
+
+// TestClose stops the rounds started by this node.
+func (s *Service) TestClose() {
+	s.closeOnce.Do(func() { close(s.closing) })
+}
+
+func newService(c *onet.Context) (onet.Service, error) {
+	s := &Service{
+		ServiceProcessor: onet.NewServiceProcessor(c),
+		closing:          make(chan bool),
+	}
+	if err := s.RegisterHandlers(s.Setup, s.GetRound); err != nil {
+		return nil, errors.New("couldn't register messages")
+	}
+	if err := s.tryLoad(); err != nil {
+		log.Error(err)
+		return nil, fmt.Errorf("loading configuration: %v", err)
+	}
+	for id, b := range s.storage.Beacons {
+		if b.Roster.List[0].Equal(s.ServerIdentity()) {
+			go s.run(id)
+		}
+	}
+	return s, nil
+}
@@ -0,0 +1,86 @@
+package beacon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/log"
+)
+
+func TestMain(m *testing.M) {
+	log.MainTest(m)
+}
+
+func TestService_Rounds(t *testing.T) {
+	local := onet.NewTCPTest(cothority.Suite)
+	defer local.CloseAll()
+	hosts, roster, _ := local.GenTree(5, false)
+	services := local.GetServices(hosts, beaconServiceID)
+	defer services[0].(*Service).TestClose()
+
+	_, err := NewClient().Setup(roster, 0, 0)
+	require.Error(t, err)
+
+	reply, err := NewClient().Setup(roster, 200*time.Millisecond, 3)
+	require.NoError(t, err)
+
+	var latest *RoundReply
+	for i := 0; i < 50; i++ {
+		time.Sleep(100 * time.Millisecond)
+		latest, err = NewClient().GetRound(roster.List[0], reply.ID, 0, reply.Public)
+		if err == nil && latest.Round >= 3 {
+			break
+		}
+	}
+	require.NoError(t, err)
+	require.True(t, latest.Round >= 3)
+
+	// Every round is signed on the previous one, and the other nodes know
+	// the rounds as well.
+	first, err := NewClient().GetRound(roster.List[3], reply.ID, 1, reply.Public)
+	require.NoError(t, err)
+	require.Empty(t, first.Previous)
+	second, err := NewClient().GetRound(roster.List[4], reply.ID, 2, reply.Public)
+	require.NoError(t, err)
+	require.Equal(t, first.Signature, second.Previous)
+	require.NotEqual(t, first.Randomness, second.Randomness)
+
+	// A wrong signature is refused.
+	second.Signature = first.Signature
+	require.Error(t, VerifyRound(reply.Public, second))
+
+	// Only the leader starts rounds.
+	s := services[1].(*Service)
+	_, err = s.signRound(roster.List[1], &Announcement{ID: reply.ID, Round: 1})
+	require.Error(t, err)
+	_, err = s.signRound(roster.List[0], &Announcement{ID: reply.ID, Round: 1})
+	require.NoError(t, err)
+}
+
+func TestService_Offline(t *testing.T) {
+	local := onet.NewTCPTest(cothority.Suite)
+	defer local.CloseAll()
+	hosts, roster, _ := local.GenTree(4, false)
+	services := local.GetServices(hosts, beaconServiceID)
+	defer services[0].(*Service).TestClose()
+
+	reply, err := NewClient().Setup(roster, 200*time.Millisecond, 3)
+	require.NoError(t, err)
+
+	// With one node down, the threshold is still reached.
+	hosts[3].Pause()
+	var latest *RoundReply
+	for i := 0; i < 50; i++ {
+		time.Sleep(100 * time.Millisecond)
+		latest, err = NewClient().GetRound(roster.List[0], reply.ID, 0, reply.Public)
+		if err == nil && latest.Round >= 2 {
+			break
+		}
+	}
+	require.NoError(t, err)
+	require.True(t, latest.Round >= 2)
+	hosts[3].Unpause()
+}
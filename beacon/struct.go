@@ -0,0 +1,85 @@
+package beacon
+
+import (
+	"encoding/hex"
+	"time"
+
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/network"
+)
+
+func init() {
+	network.RegisterMessages(&Setup{}, &SetupReply{}, &GetRound{}, &RoundReply{},
+		&Announcement{}, &Partial{}, &RoundResult{}, &storage{}, &setupConfig{})
+}
+
+// ID identifies a beacon, it is chosen at random by the leader.
+type ID [32]byte
+
+// String returns the ID in hex.
+func (id ID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// Setup asks the first node of the roster to create a beacon. The nodes
+// create a distributed key, and the first node, the leader, starts a round
+// every Interval. A Threshold of 0 allows for less than a third of faulty
+// nodes.
+type Setup struct {
+	Roster    *onet.Roster
+	Interval  time.Duration
+	Threshold uint32
+}
+
+// SetupReply holds the ID of the new beacon with its distributed public
+// key, a marshalled point on G2 of bn256.
+type SetupReply struct {
+	ID     ID
+	Public []byte
+}
+
+// GetRound asks a node of the beacon for a round. Round 0 returns the
+// latest round known to the node.
+type GetRound struct {
+	ID    ID
+	Round uint64
+}
+
+// RoundReply is a round of the beacon. Signature is the threshold BLS
+// signature of the nodes on the previous signature and the round number,
+// and Randomness is the hash of the signature.
+type RoundReply struct {
+	Round      uint64
+	Previous   []byte
+	Signature  []byte
+	Randomness []byte
+	Public     []byte
+}
+
+// Announcement is sent by the leader to start a round.
+type Announcement struct {
+	ID       ID
+	Round    uint64
+	Previous []byte
+}
+
+// Partial is the partial signature of a node for a round.
+type Partial struct {
+	Signature []byte
+}
+
+// RoundResult is sent by the leader at the end of a round, with a nil
+// signature if the round failed.
+type RoundResult struct {
+	ID        ID
+	Round     uint64
+	Previous  []byte
+	Signature []byte
+}
+
+// setupConfig is sent by the leader to the other nodes when creating the
+// distributed key.
+type setupConfig struct {
+	ID       ID
+	Interval time.Duration
+}
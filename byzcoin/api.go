@@ -2,14 +2,18 @@ package byzcoin
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
 	"math"
 	"math/rand"
+	"strings"
 	"time"
 
 	"go.dedis.ch/cothority/v3"
 	"go.dedis.ch/cothority/v3/darc"
 	"go.dedis.ch/cothority/v3/darc/expression"
 	"go.dedis.ch/cothority/v3/skipchain"
+	"go.dedis.ch/kyber/v3"
 	"go.dedis.ch/kyber/v3/sign/schnorr"
 	"go.dedis.ch/onet/v3"
 	"go.dedis.ch/onet/v3/log"
@@ -35,6 +39,10 @@ type Client struct {
 	noncesSI map[uint64]*network.ServerIdentity
 	// Used for SendProtobufParallel. If it is nil, default values will be used.
 	options *onet.ParallelOptions
+	// version and features are set by Negotiate. A version of 0 means that
+	// no negotiation took place.
+	version  Version
+	features map[string]bool
 }
 
 // NewClient instantiates a new ByzCoin client.
@@ -75,6 +83,80 @@ func NewLedger(msg *CreateGenesisBlock, keep bool) (*Client, *CreateGenesisBlock
 	return c, reply, nil
 }
 
+// errUnregisteredMessage is returned by the conodes that don't know a
+// request.
+const errUnregisteredMessage = "The requested message hasn't been registered"
+
+// Negotiate asks the conodes for the highest version of the protocol and the
+// features supported by both sides. If the conodes are too old to know about
+// negotiation, the version of the latest known block is used and no feature
+// is enabled. Other errors are returned, so that a conode that is not
+// reachable doesn't disable the features.
+func (c *Client) Negotiate() (Version, error) {
+	reply := &NegotiateVersionResponse{}
+	_, err := c.SendProtobufParallel(c.Roster.List, &NegotiateVersion{
+		Version:  CurrentVersion,
+		Features: SupportedFeatures,
+	}, reply, c.options)
+	if err != nil {
+		if !strings.Contains(err.Error(), errUnregisteredMessage) {
+			return 0, xerrors.Errorf("negotiating: %v", err)
+		}
+		log.Lvl2("Conodes don't know negotiation, falling back to block version")
+		latest := c.getLatestKnownBlock()
+		if latest == nil {
+			if err := c.fetchGenesis(); err != nil {
+				return 0, xerrors.Errorf("fetching genesis: %v", err)
+			}
+			latest = c.Genesis
+		}
+		var header DataHeader
+		if err := protobuf.Decode(latest.Data, &header); err != nil {
+			return 0, xerrors.Errorf("decoding header: %v", err)
+		}
+		reply.Version = header.Version
+		reply.Features = nil
+	}
+	if reply.Version <= 0 || reply.Version > CurrentVersion {
+		return 0, xerrors.Errorf("got invalid version %d", reply.Version)
+	}
+
+	c.version = reply.Version
+	c.features = make(map[string]bool)
+	for _, f := range reply.Features {
+		c.features[f] = true
+	}
+	return c.version, nil
+}
+
+// ProtocolVersion returns the version negotiated with the conodes, or
+// CurrentVersion if Negotiate has not been called.
+func (c *Client) ProtocolVersion() Version {
+	if c.version == 0 {
+		return CurrentVersion
+	}
+	return c.version
+}
+
+// HasFeature returns true if the given feature has been negotiated with the
+// conodes. Before Negotiate is called, all features are assumed to be
+// present.
+func (c *Client) HasFeature(feature string) bool {
+	if c.features == nil {
+		return true
+	}
+	return c.features[feature]
+}
+
+// requireFeature returns an error if the negotiation showed that the
+// conodes don't support the feature.
+func (c *Client) requireFeature(feature string) error {
+	if !c.HasFeature(feature) {
+		return xerrors.Errorf("conodes don't support the feature %s", feature)
+	}
+	return nil
+}
+
 func (c *Client) getLatestKnownBlock() *skipchain.SkipBlock {
 	if c.Latest == nil {
 		return c.Genesis
@@ -131,6 +213,224 @@ func (c *Client) GetAllByzCoinIDs(si *network.ServerIdentity) (*GetAllByzCoinIDs
 	return reply, nil
 }
 
+// GetAllByzCoinIDsWithMetadata returns the Byzcoin chains known by the given
+// server together with their metadata. If identities are given, only the
+// chains where they are authorized in the genesis darc are returned. The
+// metadata is not verified.
+func (c *Client) GetAllByzCoinIDsWithMetadata(si *network.ServerIdentity, identities ...darc.Identity) (*GetAllByzCoinIDsResponse, error) {
+	reply := &GetAllByzCoinIDsResponse{}
+	err := c.SendProtobuf(si, &GetAllByzCoinIDsRequest{
+		WithMetadata: true,
+		Identities:   identities,
+	}, reply)
+	if err != nil {
+		return nil, xerrors.Errorf("client request: %v", err)
+	}
+	if len(reply.Metadata) != len(reply.IDs) {
+		return nil, xerrors.New("got wrong number of metadata")
+	}
+
+	return reply, nil
+}
+
+// GetStateChecksum returns the digest of the state of the chain at the
+// latest block stored by the given node, signed by the node. Comparing the
+// digests of the nodes at the same index shows whether their states
+// diverged.
+func (c *Client) GetStateChecksum(si *network.ServerIdentity) (*GetStateChecksumResponse, error) {
+	reply := &GetStateChecksumResponse{}
+	err := c.SendProtobuf(si, &GetStateChecksum{ByzCoinID: c.ID}, reply)
+	if err != nil {
+		return nil, xerrors.Errorf("client request: %v", err)
+	}
+	if !bytes.Equal(reply.Digest, reply.computeDigest()) {
+		return nil, xerrors.New("digest doesn't match the response")
+	}
+	if err := schnorr.Verify(cothority.Suite, si.Public, reply.Digest, reply.Signature); err != nil {
+		return nil, xerrors.Errorf("signature of the digest: %v", err)
+	}
+
+	return reply, nil
+}
+
+// GetAllInstanceVersion returns all the state changes of the instance that
+// are stored by the node. The state changes are not verified, use
+// GetInstanceAt to get a proof of a state change.
+func (c *Client) GetAllInstanceVersion(id InstanceID) (*GetAllInstanceVersionResponse, error) {
+	reply := &GetAllInstanceVersionResponse{}
+	err := c.SendProtobuf(c.Roster.List[0], &GetAllInstanceVersion{
+		SkipChainID: c.ID,
+		InstanceID:  id,
+	}, reply)
+	if err != nil {
+		return nil, xerrors.Errorf("client request: %v", err)
+	}
+	return reply, nil
+}
+
+// GetInstanceAt returns the value and the version of the instance as they
+// were after the block with the given index. The block of the returned state
+// change is verified against the genesis block.
+func (c *Client) GetInstanceAt(id InstanceID, blockIndex int) (*GetInstanceAtResponse, error) {
+	if err := c.requireFeature(FeatureInstanceAt); err != nil {
+		return nil, err
+	}
+	if c.Genesis == nil {
+		if err := c.fetchGenesis(); err != nil {
+			return nil, xerrors.Errorf("fetching genesis block: %v", err)
+		}
+	}
+
+	reply := &GetInstanceAtResponse{}
+	err := c.SendProtobuf(c.Roster.List[0], &GetInstanceAt{
+		SkipChainID: c.ID,
+		InstanceID:  id,
+		BlockIndex:  blockIndex,
+	}, reply)
+	if err != nil {
+		return nil, xerrors.Errorf("client request: %v", err)
+	}
+	if !bytes.Equal(reply.StateChange.InstanceID, id[:]) {
+		return nil, xerrors.New("got state change of another instance")
+	}
+	if reply.Block.Index > blockIndex {
+		return nil, xerrors.New("got state change after the requested block")
+	}
+	if err := reply.Verify(c.Genesis); err != nil {
+		return nil, xerrors.Errorf("verifying reply: %v", err)
+	}
+
+	return reply, nil
+}
+
+// GetEvictionProposals asks every conode of the roster for its proposals to
+// evict misbehaving members, and returns the ones that verify. The conodes
+// that don't answer are skipped, as they might be the misbehaving ones. The
+// admin has to check that the blocks of the evidence are part of the chain
+// before evicting a member.
+func (c *Client) GetEvictionProposals() ([]EvictionProposal, error) {
+	var proposals []EvictionProposal
+	var lastErr error
+	answered := 0
+	for _, si := range c.Roster.List {
+		reply := &GetEvictionProposalsResponse{}
+		err := c.SendProtobuf(si, &GetEvictionProposals{SkipChainID: c.ID}, reply)
+		if err != nil {
+			log.Lvlf2("%s didn't answer: %v", si, err)
+			lastErr = err
+			continue
+		}
+		answered++
+		for _, p := range reply.Proposals {
+			if !p.SkipChainID.Equal(c.ID) || p.Proposer == nil || !p.Proposer.Equal(si) {
+				log.Warnf("%s sent a proposal of another conode or chain", si)
+				continue
+			}
+			if err := p.Verify(); err != nil {
+				log.Warnf("%s sent an invalid proposal: %v", si, err)
+				continue
+			}
+			proposals = append(proposals, p)
+		}
+	}
+	if answered == 0 {
+		return nil, xerrors.Errorf("no conode answered: %v", lastErr)
+	}
+	return proposals, nil
+}
+
+// GetBlocksSince returns the blocks of the chain whose header has a timestamp
+// at or after since, from the oldest to the newest. If headersOnly is true,
+// the bodies of the blocks are removed.
+func (c *Client) GetBlocksSince(since time.Time, headersOnly bool) ([]*skipchain.SkipBlock, error) {
+	if err := c.requireFeature(FeatureBlocksSince); err != nil {
+		return nil, err
+	}
+	var blocks []*skipchain.SkipBlock
+	req := &GetBlocksSince{
+		SkipChainID: c.ID,
+		Since:       since.UnixNano(),
+		HeadersOnly: headersOnly,
+	}
+	for {
+		reply := &GetBlocksSinceResponse{}
+		if err := c.SendProtobuf(c.Roster.List[0], req, reply); err != nil {
+			return nil, xerrors.Errorf("client request: %v", err)
+		}
+		for _, sb := range reply.Blocks {
+			if !sb.CalculateHash().Equal(sb.Hash) || !sb.SkipChainID().Equal(c.ID) {
+				return nil, xerrors.New("got an invalid block")
+			}
+			if len(blocks) > 0 && sb.Index <= blocks[len(blocks)-1].Index {
+				return nil, xerrors.New("blocks are not in order")
+			}
+			blocks = append(blocks, sb)
+		}
+		if !reply.Truncated || len(reply.Blocks) == 0 {
+			return blocks, nil
+		}
+		req.StartIndex = blocks[len(blocks)-1].Index + 1
+	}
+}
+
+// SampleBlock checks that the body of the block is available by asking random
+// conodes for random chunks of the body, and verifying them against the
+// header of the block. The client has to trust the ID of the block, for
+// example from a verified chain of forward-links. Each sample that is not
+// available decreases the probability that the body can be recovered.
+func (c *Client) SampleBlock(blockID skipchain.SkipBlockID, samples int) error {
+	if err := c.requireFeature(FeatureBlockChunks); err != nil {
+		return err
+	}
+	ask := func(indexes []int) (*GetBlockChunksResponse, error) {
+		si := c.Roster.List[rand.Intn(len(c.Roster.List))]
+		reply := &GetBlockChunksResponse{}
+		err := c.SendProtobuf(si, &GetBlockChunks{
+			SkipChainID: c.ID,
+			BlockID:     blockID,
+			Indexes:     indexes,
+		}, reply)
+		if err != nil {
+			return nil, xerrors.Errorf("client request: %v", err)
+		}
+		if !reply.Block.CalculateHash().Equal(blockID) {
+			return nil, xerrors.New("got another block")
+		}
+		return reply, nil
+	}
+
+	reply, err := ask(nil)
+	if err != nil {
+		return xerrors.Errorf("getting the header: %v", err)
+	}
+	header, err := decodeBlockHeader(&reply.Block)
+	if err != nil {
+		return xerrors.Errorf("decoding header: %v", err)
+	}
+	if len(header.BodyRoot) == 0 {
+		return xerrors.New("block has no body root")
+	}
+	count := reply.Count
+	if count <= 0 {
+		return xerrors.New("invalid number of chunks")
+	}
+
+	for i := 0; i < samples; i++ {
+		index := rand.Intn(count)
+		reply, err := ask([]int{index})
+		if err != nil {
+			return xerrors.Errorf("sample %d: %v", index, err)
+		}
+		if len(reply.Chunks) != 1 || reply.Chunks[0].Index != index {
+			return xerrors.Errorf("sample %d is missing", index)
+		}
+		if err := reply.Chunks[0].Verify(header.BodyRoot, count); err != nil {
+			return xerrors.Errorf("sample %d: %v", index, err)
+		}
+	}
+	return nil
+}
+
 // CreateTransaction creates a transaction from a list of instructions.
 func (c *Client) CreateTransaction(instrs ...Instruction) (ClientTransaction, error) {
 	if c.Latest == nil {
@@ -182,7 +482,7 @@ func (c *Client) AddTransactionAndWait(tx ClientTransaction, wait int) (*AddTxRe
 
 	reply := &AddTxResponse{}
 	_, err := c.SendProtobufParallel(c.Roster.List, &AddTxRequest{
-		Version:       CurrentVersion,
+		Version:       c.ProtocolVersion(),
 		SkipchainID:   c.ID,
 		Transaction:   tx,
 		InclusionWait: wait,
@@ -254,6 +554,72 @@ func (c *Client) GetProofFrom(key []byte, from *skipchain.SkipBlock) (*GetProofR
 	return rep, cothority.ErrorOrNil(err, "request failed")
 }
 
+// GetProofsFromLatest returns the proofs for all keys, starting from the
+// latest known block by this client. All proofs end in the same block, so
+// they give a consistent view of the instances. The integrity of the proofs
+// is verified.
+func (c *Client) GetProofsFromLatest(keys ...[]byte) (*GetProofsResponse, error) {
+	if len(keys) == 0 {
+		return nil, xerrors.New("no keys given")
+	}
+	if len(keys) > maxProofsKeys {
+		return nil, xerrors.Errorf("cannot ask for more than %d proofs", maxProofsKeys)
+	}
+	if err := c.requireFeature(FeatureProofs); err != nil {
+		return nil, err
+	}
+	if c.Genesis == nil {
+		if err := c.fetchGenesis(); err != nil {
+			return nil, xerrors.Errorf("fetching genesis block: %v", err)
+		}
+	}
+	from := c.getLatestKnownBlock()
+
+	decoder := func(buf []byte, msg interface{}) error {
+		err := protobuf.Decode(buf, msg)
+		if err != nil {
+			return xerrors.Errorf("decoding: %+v", err)
+		}
+
+		gpr, ok := msg.(*GetProofsResponse)
+		if !ok {
+			return xerrors.New("couldn't cast msg")
+		}
+		if len(gpr.Proofs) != len(keys) {
+			return xerrors.New("got wrong number of proofs")
+		}
+		for i, pr := range gpr.Proofs {
+			if !pr.Latest.Hash.Equal(gpr.Proofs[0].Latest.Hash) {
+				return xerrors.New("proofs are not from the same block")
+			}
+			if _, err := pr.InclusionProof.Exists(keys[i]); err != nil {
+				return xerrors.Errorf("proof %d is not for its key: %+v", i, err)
+			}
+			if err := pr.VerifyFromBlock(from); err != nil {
+				return xerrors.Errorf("proof verification: %+v", err)
+			}
+		}
+
+		return nil
+	}
+
+	reply := &GetProofsResponse{}
+	_, err := c.SendProtobufParallelWithDecoder(c.Roster.List, &GetProofs{
+		Version: CurrentVersion,
+		Keys:    keys,
+		ID:      from.Hash,
+	}, reply, c.options, decoder)
+	if err != nil {
+		return nil, xerrors.Errorf("sending: %+v", err)
+	}
+
+	latest := reply.Proofs[0].Latest
+	if c.Latest == nil || c.Latest.Index < latest.Index {
+		c.Latest = &latest
+	}
+	return reply, nil
+}
+
 // GetProofAfter returns a proof for the key stored in the skipchain
 // starting from the latest known block by this client. The proof will always
 // be newer than the barrier or it will return an error.
@@ -357,6 +723,44 @@ func (c *Client) GetDeferredDataAfter(instrID InstanceID, barrier *skipchain.Ski
 	return &result, nil
 }
 
+// GetJob fetches the data of the given job instance. Use JobData.Done to
+// find out whether the job has been completed.
+func (c *Client) GetJob(jobID InstanceID) (*JobData, error) {
+	pr, err := c.GetProofFromLatest(jobID.Slice())
+	if err != nil {
+		return nil, xerrors.Errorf("getting proof: %w", err)
+	}
+	buf, cid, _, err := pr.Proof.Get(jobID.Slice())
+	if err != nil {
+		return nil, xerrors.Errorf("getting proof value: %w", err)
+	}
+	if cid != ContractJobID {
+		return nil, xerrors.Errorf("instance is of contract %s, not %s", cid, ContractJobID)
+	}
+	var jd JobData
+	if err = protobuf.Decode(buf, &jd); err != nil {
+		return nil, xerrors.Errorf("decoding job: %w", err)
+	}
+	return &jd, nil
+}
+
+// WaitJob polls the given job instance every interval until it is done, for
+// at most maxPolls times. The steps themselves must be triggered by sending
+// "invoke:job.step" instructions.
+func (c *Client) WaitJob(jobID InstanceID, interval time.Duration, maxPolls int) (*JobData, error) {
+	for i := 0; i < maxPolls; i++ {
+		jd, err := c.GetJob(jobID)
+		if err != nil {
+			return nil, xerrors.Errorf("getting job: %w", err)
+		}
+		if jd.Done {
+			return jd, nil
+		}
+		time.Sleep(interval)
+	}
+	return nil, xerrors.New("job didn't finish in time")
+}
+
 // CheckAuthorization verifies which actions the given set of identities can
 // execute in the given darc.
 func (c *Client) CheckAuthorization(dID darc.ID, ids ...darc.Identity) ([]darc.Action, error) {
@@ -430,6 +834,134 @@ func (c *Client) GetGenDarc() (*darc.Darc, error) {
 	return d, nil
 }
 
+// DarcVersion is one version of a darc in its evolution chain, as returned
+// by GetDarcHistory.
+type DarcVersion struct {
+	Darc *darc.Darc
+	// BlockIndex is the index of the block where the version has been
+	// stored, and Timestamp the time of this block.
+	BlockIndex int
+	Timestamp  time.Time
+	// Signers are the identities that signed the instruction creating
+	// this version.
+	Signers []darc.Identity
+}
+
+// GetDarcHistory walks the evolution chain of the darc and returns all its
+// versions, starting with version 0, together with the identities that
+// signed them. The blocks holding the versions are verified against the
+// genesis block, and every version must evolve from the previous one.
+func (c *Client) GetDarcHistory(id darc.ID) ([]DarcVersion, error) {
+	all, err := c.GetAllInstanceVersion(NewInstanceID(id))
+	if err != nil {
+		return nil, xerrors.Errorf("getting versions: %v", err)
+	}
+
+	var history []DarcVersion
+	done := make(map[int]bool)
+	for _, v := range all.StateChanges {
+		if done[v.BlockIndex] {
+			continue
+		}
+		done[v.BlockIndex] = true
+
+		// All the state changes of the block are verified, so that the
+		// versions created in the same block are found.
+		reply, err := c.GetInstanceAt(NewInstanceID(id), v.BlockIndex)
+		if err != nil {
+			return nil, xerrors.Errorf("getting block %d: %v", v.BlockIndex, err)
+		}
+		versions, err := darcVersionsOfBlock(id, reply)
+		if err != nil {
+			return nil, xerrors.Errorf("block %d: %v", v.BlockIndex, err)
+		}
+		history = append(history, versions...)
+	}
+	if len(history) == 0 {
+		return nil, xerrors.New("darc not found")
+	}
+
+	for i, v := range history {
+		if v.Darc.Version != uint64(i) {
+			return nil, xerrors.Errorf("expected version %d but got %d", i, v.Darc.Version)
+		}
+		if !v.Darc.GetBaseID().Equal(id) {
+			return nil, xerrors.Errorf("version %d is of another darc", i)
+		}
+		if i > 0 && !v.Darc.PrevID.Equal(history[i-1].Darc.GetID()) {
+			return nil, xerrors.Errorf("version %d does not evolve from the previous one", i)
+		}
+	}
+	return history, nil
+}
+
+// darcVersionsOfBlock returns the versions of the darc stored in the block
+// of the reply, which must already be verified, with the signers of the
+// accepted instructions that created them.
+func darcVersionsOfBlock(id darc.ID, reply *GetInstanceAtResponse) ([]DarcVersion, error) {
+	header, err := decodeBlockHeader(&reply.Block)
+	if err != nil {
+		return nil, xerrors.Errorf("decoding header: %v", err)
+	}
+	var body DataBody
+	if err := protobuf.Decode(reply.Block.Payload, &body); err != nil {
+		return nil, xerrors.Errorf("decoding body: %v", err)
+	}
+	body.TxResults.SetVersion(header.Version)
+	if !bytes.Equal(body.TxResults.Hash(), header.ClientTransactionHash) {
+		return nil, xerrors.New("transactions don't match the block")
+	}
+
+	var versions []DarcVersion
+	for _, sc := range reply.StateChanges {
+		if !bytes.Equal(sc.InstanceID, id) || sc.StateAction == Remove {
+			continue
+		}
+		d, err := darc.NewFromProtobuf(sc.Value)
+		if err != nil {
+			return nil, xerrors.Errorf("decoding darc: %v", err)
+		}
+		signers, err := darcSigners(body.TxResults, d.GetID())
+		if err != nil {
+			return nil, xerrors.Errorf("version %d: %v", d.Version, err)
+		}
+		versions = append(versions, DarcVersion{
+			Darc:       d,
+			BlockIndex: reply.Block.Index,
+			Timestamp:  time.Unix(0, header.Timestamp),
+			Signers:    signers,
+		})
+	}
+	return versions, nil
+}
+
+// darcSigners returns the signers of the accepted instruction that spawned
+// or evolved the darc with the given ID.
+func darcSigners(txs TxResults, id darc.ID) ([]darc.Identity, error) {
+	for _, tx := range txs {
+		if !tx.Accepted {
+			continue
+		}
+		for _, instr := range tx.ClientTransaction.Instructions {
+			var buf []byte
+			switch {
+			case instr.Spawn != nil:
+				buf = instr.Spawn.Args.Search("darc")
+			case instr.Invoke != nil:
+				buf = instr.Invoke.Args.Search("darc")
+			}
+			if buf == nil {
+				continue
+			}
+			d, err := darc.NewFromProtobuf(buf)
+			if err == nil && d.GetID().Equal(id) {
+				return instr.SignerIdentities, nil
+			}
+		}
+	}
+	return nil, xerrors.New("no instruction creates the darc")
+}
+
 // GetChainConfig uses the GetProof method to fetch the chain config
 // from ByzCoin.
 func (c *Client) GetChainConfig() (*ChainConfig, error) {
@@ -653,6 +1185,55 @@ func (c *Client) ResolveInstanceID(darcID darc.ID, name string) (InstanceID, err
 	return reply.InstanceID, cothority.ErrorOrNil(err, "request failed")
 }
 
+// Resolver resolves names that are set by the naming contract. The names are
+// looked up under the darc of the resolver. A name can also be a path of
+// names separated by ":", like "coins:alice". Every name but the last one
+// must then point to a darc, and the next name is looked up under this darc.
+type Resolver struct {
+	client *Client
+	darcID darc.ID
+}
+
+// NewResolver returns a resolver looking up names under the given darc.
+func NewResolver(c *Client, darcID darc.ID) *Resolver {
+	return &Resolver{client: c, darcID: darcID}
+}
+
+// Resolve returns the instance ID the path points to. All intermediate darcs
+// are verified using a proof from the chain.
+func (r *Resolver) Resolve(path string) (InstanceID, error) {
+	names := strings.Split(path, ":")
+	darcID := r.darcID
+	for i, name := range names {
+		iID, err := r.client.ResolveInstanceID(darcID, name)
+		if err != nil {
+			return InstanceID{}, xerrors.Errorf("resolving %s: %v", name, err)
+		}
+		if i == len(names)-1 {
+			return iID, nil
+		}
+
+		pr, err := r.client.GetProof(iID.Slice())
+		if err != nil {
+			return InstanceID{}, xerrors.Errorf("getting proof of %s: %v", name, err)
+		}
+		if err := pr.Proof.Verify(r.client.ID); err != nil {
+			return InstanceID{}, xerrors.Errorf("verifying proof of %s: %v", name, err)
+		}
+		_, value, _, _, err := pr.Proof.KeyValue()
+		if err != nil {
+			return InstanceID{}, xerrors.Errorf("reading proof of %s: %v", name, err)
+		}
+		// Darcs are always stored under their base ID.
+		d, err := darc.NewFromProtobuf(value)
+		if err != nil || !iID.Equal(NewInstanceID(d.GetBaseID())) {
+			return InstanceID{}, xerrors.Errorf("%s does not point to a darc", name)
+		}
+		darcID = d.GetBaseID()
+	}
+	return InstanceID{}, xerrors.New("empty path")
+}
+
 // WaitPropagation contacts all nodes in the cl.Roster until they all
 // have the same latest block. If there is an error when calling
 // `GetProof`, the error will be ignored. This helps when waiting
@@ -726,6 +1307,62 @@ func Debug(url string, byzcoinID *skipchain.SkipBlockID) (*DebugResponse, error)
 	return reply, cothority.ErrorOrNil(err, "request failed")
 }
 
+// DebugAction is the rule in the genesis darc that allows an identity to
+// send signed debug requests for this byzcoin instance.
+const DebugAction = darc.Action("_debug")
+
+// DebugRequestMessage returns the message to be signed for a DebugRequest
+// to the conode with the given public key. It covers the conode, so that the
+// request can't be replayed to the other nodes, and the fields that select
+// what is dumped.
+func DebugRequestMessage(conode kyber.Point, req *DebugRequest) ([]byte, error) {
+	pub, err := conode.MarshalBinary()
+	if err != nil {
+		return nil, xerrors.Errorf("marshaling public key: %v", err)
+	}
+	h := sha256.New()
+	write := func(buf []byte) {
+		binary.Write(h, binary.LittleEndian, uint64(len(buf)))
+		h.Write(buf)
+	}
+	write(pub)
+	write(req.ByzCoinID)
+	binary.Write(h, binary.LittleEndian, req.Timestamp)
+	write(req.Prefix)
+	binary.Write(h, binary.LittleEndian, uint64(len(req.ContractIDs)))
+	for _, id := range req.ContractIDs {
+		write([]byte(id))
+	}
+	return h.Sum(nil), nil
+}
+
+// DebugSigned sends a signed debug request that is also accepted on
+// non-loopback interfaces. If signer is nil, the request is signed with the
+// private key of the conode, which must be available in si. Else the signer
+// must be allowed to DebugAction in the genesis darc of req.ByzCoinID.
+// The ByzCoinID, Prefix and ContractIDs fields of req are used as is.
+func DebugSigned(si *network.ServerIdentity, req DebugRequest, signer *darc.Signer) (*DebugResponse, error) {
+	req.Timestamp = time.Now().Unix()
+	msg, err := DebugRequestMessage(si.Public, &req)
+	if err != nil {
+		return nil, xerrors.Errorf("debug message: %v", err)
+	}
+	if signer == nil {
+		req.Identity = nil
+		req.Signature, err = schnorr.Sign(cothority.Suite, si.GetPrivate(), msg)
+	} else {
+		id := signer.Identity()
+		req.Identity = &id
+		req.Signature, err = signer.Sign(msg)
+	}
+	if err != nil {
+		return nil, xerrors.Errorf("sign error: %v", err)
+	}
+	reply := &DebugResponse{}
+	err = onet.NewClient(cothority.Suite, ServiceName).SendProtobuf(si, &req, reply)
+	return reply, cothority.ErrorOrNil(err, "request failed")
+}
+
 // DebugRemove deletes an existing byzcoin-instance from the conode.
 func DebugRemove(si *network.ServerIdentity, byzcoinID skipchain.SkipBlockID) error {
 	sig, err := schnorr.Sign(cothority.Suite, si.GetPrivate(), byzcoinID)
@@ -740,6 +1377,25 @@ func DebugRemove(si *network.ServerIdentity, byzcoinID skipchain.SkipBlockID) er
 	return cothority.ErrorOrNil(err, "request failed")
 }
 
+// Backup asks the conode to write a snapshot of its database to the given
+// path on its machine. The private key of the conode must be available in
+// si. The snapshot can be used to restore a conode after it has been checked
+// with VerifyBackup.
+func Backup(si *network.ServerIdentity, path string) (*BackupResponse, error) {
+	ts := time.Now().Unix()
+	sig, err := schnorr.Sign(cothority.Suite, si.GetPrivate(), BackupRequestMessage(path, ts))
+	if err != nil {
+		return nil, xerrors.Errorf("sign error: %v", err)
+	}
+	reply := &BackupResponse{}
+	err = onet.NewClient(cothority.Suite, ServiceName).SendProtobuf(si, &BackupRequest{
+		Path:      path,
+		Timestamp: ts,
+		Signature: sig,
+	}, reply)
+	return reply, cothority.ErrorOrNil(err, "request failed")
+}
+
 // DefaultGenesisMsg creates the message that is used to for creating the
 // genesis Darc and block. It will contain rules for spawning and evolving the
 // darc contract.
@@ -166,6 +166,100 @@ func TestClient_GetProof(t *testing.T) {
 	require.Equal(t, 1, len(p.Proof.Links))
 }
 
+func TestClient_GetDarcHistory(t *testing.T) {
+	l := onet.NewTCPTest(cothority.Suite)
+	servers, roster, _ := l.GenTree(3, true)
+	registerDummy(servers)
+	defer l.CloseAll()
+
+	signer := darc.NewSignerEd25519(nil, nil)
+	msg, err := DefaultGenesisMsg(CurrentVersion, roster, []string{}, signer.Identity())
+	require.NoError(t, err)
+	msg.BlockInterval = 100 * time.Millisecond
+	d := &msg.GenesisDarc
+
+	c, _, err := NewLedger(msg, false)
+	require.NoError(t, err)
+
+	for i := 1; i <= 2; i++ {
+		inst, err := NewRevocationInstruction(d, []string{darc.NewSignerEd25519(nil, nil).Identity().String()}, nil)
+		require.NoError(t, err)
+		inst.SignerCounter = []uint64{uint64(i)}
+		ctx, err := c.CreateTransaction(inst)
+		require.NoError(t, err)
+		require.NoError(t, ctx.FillSignersAndSignWith(signer))
+		_, err = c.AddTransactionAndWait(ctx, 10)
+		require.NoError(t, err)
+		d, err = darc.NewFromProtobuf(inst.Invoke.Args.Search("darc"))
+		require.NoError(t, err)
+	}
+
+	history, err := c.GetDarcHistory(d.GetBaseID())
+	require.NoError(t, err)
+	require.Equal(t, 3, len(history))
+	require.Equal(t, 0, history[0].BlockIndex)
+	signerID := signer.Identity()
+	for i, v := range history {
+		require.Equal(t, uint64(i), v.Darc.Version)
+		if i > 0 {
+			require.True(t, v.BlockIndex > history[i-1].BlockIndex)
+			require.Equal(t, 1, len(v.Signers))
+			require.True(t, v.Signers[0].Equal(&signerID))
+		}
+	}
+	require.True(t, history[2].Darc.Equal(d))
+
+	_, err = c.GetDarcHistory(darc.ID(make([]byte, 32)))
+	require.Error(t, err)
+}
+
+func TestClient_GetProofsFromLatest(t *testing.T) {
+	l := onet.NewTCPTest(cothority.Suite)
+	servers, roster, _ := l.GenTree(3, true)
+	registerDummy(servers)
+	defer l.CloseAll()
+
+	signer := darc.NewSignerEd25519(nil, nil)
+	msg, err := DefaultGenesisMsg(CurrentVersion, roster, []string{"spawn:dummy"}, signer.Identity())
+	require.NoError(t, err)
+	msg.BlockInterval = 100 * time.Millisecond
+	d := msg.GenesisDarc
+
+	c, _, err := NewLedger(msg, false)
+	require.NoError(t, err)
+
+	value := []byte{5, 6, 7, 8}
+	tx, err := createOneClientTx(d.GetBaseID(), "dummy", value, signer)
+	require.NoError(t, err)
+	_, err = c.AddTransactionAndWait(tx, 10)
+	require.NoError(t, err)
+
+	newID := tx.Instructions[0].Hash()
+	missing := NewInstanceID([]byte("missing")).Slice()
+	resp, err := c.GetProofsFromLatest(newID, d.GetBaseID(), missing)
+	require.NoError(t, err)
+	require.Equal(t, 3, len(resp.Proofs))
+	for _, p := range resp.Proofs {
+		require.True(t, p.Latest.Hash.Equal(resp.Proofs[0].Latest.Hash))
+	}
+	_, v, _, _, err := resp.Proofs[0].KeyValue()
+	require.NoError(t, err)
+	require.Equal(t, value, v)
+	require.True(t, resp.Proofs[1].InclusionProof.Match(d.GetBaseID()))
+	require.False(t, resp.Proofs[2].InclusionProof.Match(missing))
+
+	_, err = c.GetProofsFromLatest()
+	require.Error(t, err)
+	_, err = c.GetProofsFromLatest(make([][]byte, maxProofsKeys+1)...)
+	require.Error(t, err)
+	_, err = servers[0].Service(ServiceName).(*Service).GetProofs(&GetProofs{
+		Version: CurrentVersion,
+		Keys:    make([][]byte, maxProofsKeys+1),
+		ID:      c.Genesis.Hash,
+	})
+	require.Error(t, err)
+}
+
 func TestClient_GetProofCorrupted(t *testing.T) {
 	l := onet.NewTCPTest(cothority.Suite)
 	servers, roster, _ := l.GenTree(1, true)
@@ -310,6 +404,168 @@ func TestClient_NoPhantomSkipchain(t *testing.T) {
 	require.Equal(t, 1, len(gac.IDs))
 }
 
+func TestClient_GetAllByzCoinIDsWithMetadata(t *testing.T) {
+	l := onet.NewTCPTest(cothority.Suite)
+	_, roster, _ := l.GenTree(3, true)
+	defer l.CloseAll()
+
+	signer := darc.NewSignerEd25519(nil, nil)
+	msg, err := DefaultGenesisMsg(CurrentVersion, roster, []string{"spawn:dummy"}, signer.Identity())
+	require.NoError(t, err)
+	msg.BlockInterval = 100 * time.Millisecond
+	c, _, err := NewLedger(msg, false)
+	require.NoError(t, err)
+
+	reply, err := c.GetAllByzCoinIDsWithMetadata(roster.List[0])
+	require.NoError(t, err)
+	require.Equal(t, 1, len(reply.IDs))
+	require.Equal(t, 0, reply.Metadata[0].LatestIndex)
+	require.Equal(t, msg.BlockInterval, reply.Metadata[0].BlockInterval)
+	require.Equal(t, len(roster.List), len(reply.Metadata[0].Roster.List))
+	require.NotZero(t, reply.Metadata[0].Created)
+	require.Equal(t, defaultBaseHeight, reply.Metadata[0].BaseHeight)
+	require.Equal(t, defaultMaximumHeight, reply.Metadata[0].MaximumHeight)
+
+	reply, err = c.GetAllByzCoinIDsWithMetadata(roster.List[0], signer.Identity())
+	require.NoError(t, err)
+	require.Equal(t, 1, len(reply.IDs))
+
+	other := darc.NewSignerEd25519(nil, nil)
+	reply, err = c.GetAllByzCoinIDsWithMetadata(roster.List[0], other.Identity())
+	require.NoError(t, err)
+	require.Equal(t, 0, len(reply.IDs))
+}
+
+func TestClient_SkiplistHeights(t *testing.T) {
+	l := onet.NewTCPTest(cothority.Suite)
+	_, roster, _ := l.GenTree(3, true)
+	defer l.CloseAll()
+
+	signer := darc.NewSignerEd25519(nil, nil)
+	msg, err := DefaultGenesisMsg(CurrentVersion, roster, []string{"spawn:dummy"}, signer.Identity())
+	require.NoError(t, err)
+	msg.BlockInterval = 100 * time.Millisecond
+	msg.BaseHeight = 1
+	msg.MaximumHeight = 3
+	_, _, err = NewLedger(msg, false)
+	require.Error(t, err)
+
+	msg.BaseHeight = 2
+	msg.MaximumHeight = 5
+	c, resp, err := NewLedger(msg, false)
+	require.NoError(t, err)
+	require.Equal(t, 2, resp.Skipblock.BaseHeight)
+	require.Equal(t, 5, resp.Skipblock.MaximumHeight)
+
+	reply, err := c.GetAllByzCoinIDsWithMetadata(roster.List[0])
+	require.NoError(t, err)
+	require.Equal(t, 1, len(reply.IDs))
+	require.Equal(t, 2, reply.Metadata[0].BaseHeight)
+	require.Equal(t, 5, reply.Metadata[0].MaximumHeight)
+}
+
+func TestClient_DebugSigned(t *testing.T) {
+	l := onet.NewTCPTest(cothority.Suite)
+	_, roster, _ := l.GenTree(3, true)
+	defer l.CloseAll()
+
+	signer := darc.NewSignerEd25519(nil, nil)
+	msg, err := DefaultGenesisMsg(CurrentVersion, roster,
+		[]string{"spawn:dummy", string(DebugAction)}, signer.Identity())
+	require.NoError(t, err)
+	c, _, err := NewLedger(msg, false)
+	require.NoError(t, err)
+	si := roster.List[0]
+
+	resp, err := DebugSigned(si, DebugRequest{}, nil)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(resp.Byzcoins))
+
+	resp, err = DebugSigned(si, DebugRequest{ByzCoinID: c.ID}, &signer)
+	require.NoError(t, err)
+	require.NotEqual(t, 0, len(resp.Dump))
+
+	resp, err = DebugSigned(si, DebugRequest{ByzCoinID: c.ID,
+		ContractIDs: []string{ContractConfigID}}, nil)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(resp.Dump))
+
+	// Listing all byzcoins needs the conode key.
+	_, err = DebugSigned(si, DebugRequest{}, &signer)
+	require.Error(t, err)
+
+	other := darc.NewSignerEd25519(nil, nil)
+	_, err = DebugSigned(si, DebugRequest{ByzCoinID: c.ID}, &other)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "is not allowed to _debug")
+
+	// The signature covers the conode and the scope of the dump.
+	id := signer.Identity()
+	req := DebugRequest{ByzCoinID: c.ID, Timestamp: time.Now().Unix(),
+		Identity: &id, ContractIDs: []string{ContractConfigID}}
+	reqMsg, err := DebugRequestMessage(si.Public, &req)
+	require.NoError(t, err)
+	req.Signature, err = signer.Sign(reqMsg)
+	require.NoError(t, err)
+	cl := onet.NewClient(cothority.Suite, ServiceName)
+	require.NoError(t, cl.SendProtobuf(si, &req, &DebugResponse{}))
+	// The same request can't be replayed.
+	err = cl.SendProtobuf(si, &req, &DebugResponse{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "already been used")
+	require.Error(t, cl.SendProtobuf(roster.List[1], &req, &DebugResponse{}))
+	req.ContractIDs = nil
+	require.Error(t, cl.SendProtobuf(si, &req, &DebugResponse{}))
+}
+
+func TestClient_Negotiate(t *testing.T) {
+	l := onet.NewTCPTest(cothority.Suite)
+	_, roster, _ := l.GenTree(3, true)
+	defer l.CloseAll()
+
+	signer := darc.NewSignerEd25519(nil, nil)
+	msg, err := DefaultGenesisMsg(CurrentVersion, roster, []string{"spawn:dummy"}, signer.Identity())
+	require.NoError(t, err)
+	c, _, err := NewLedger(msg, false)
+	require.NoError(t, err)
+
+	require.True(t, c.HasFeature("unknown"))
+	v, err := c.Negotiate()
+	require.NoError(t, err)
+	require.Equal(t, CurrentVersion, v)
+	require.Equal(t, CurrentVersion, c.ProtocolVersion())
+	require.True(t, c.HasFeature(FeatureProofs))
+	require.False(t, c.HasFeature("unknown"))
+
+	// A conode that doesn't know about negotiation makes the client fall
+	// back to the version of the latest block, without any feature.
+	old := &Client{
+		Client: onet.NewClient(cothority.Suite, testServiceName),
+		ID:     c.ID,
+		Roster: *roster,
+		Latest: c.Latest,
+	}
+	v, err = old.Negotiate()
+	require.NoError(t, err)
+	require.Equal(t, CurrentVersion, v)
+	require.False(t, old.HasFeature(FeatureProofs))
+	_, err = old.GetProofsFromLatest(c.ID)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "don't support the feature")
+
+	// A conode that can't be reached doesn't disable the features.
+	unreachable := &Client{
+		Client: onet.NewClient(cothority.Suite, ServiceName),
+		ID:     c.ID,
+		Roster: *onet.NewRoster([]*network.ServerIdentity{network.NewServerIdentity(
+			roster.List[0].Public, network.NewAddress(network.TLS, "127.0.0.1:1"))}),
+		Latest: c.Latest,
+	}
+	_, err = unreachable.Negotiate()
+	require.Error(t, err)
+	require.True(t, unreachable.HasFeature(FeatureProofs))
+}
+
 // Insure that the decoder will return an error if the reply
 // contains data from an old block.
 func TestClient_SignerCounterDecoder(t *testing.T) {
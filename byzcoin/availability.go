@@ -0,0 +1,179 @@
+package byzcoin
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+
+	"golang.org/x/xerrors"
+)
+
+// ChunkSize is the size of the chunks of the body of a block that light
+// clients sample to check that the body is available.
+const ChunkSize = 1024
+
+// maxChunksPerRequest limits the number of chunks returned for one request.
+const maxChunksPerRequest = 64
+
+// splitBody returns the chunks of the body of a block. An empty body has one
+// empty chunk.
+func splitBody(payload []byte) [][]byte {
+	chunks := [][]byte{}
+	for len(payload) > ChunkSize {
+		chunks = append(chunks, payload[:ChunkSize])
+		payload = payload[ChunkSize:]
+	}
+	return append(chunks, payload)
+}
+
+// BodyRoot returns the root of the Merkle tree of the chunks of the body of a
+// block, which is stored in the header of the block. It commits to the number
+// of chunks, so that a proof of a chunk can be verified with the root only.
+func BodyRoot(payload []byte) []byte {
+	chunks := splitBody(payload)
+	return bodyRootOf(len(chunks), merkleLevels(chunks)[0][0])
+}
+
+// bodyRootOf returns the root of the body given the number of chunks and the
+// root of their Merkle tree.
+func bodyRootOf(count int, treeRoot []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{2})
+	binary.Write(h, binary.LittleEndian, uint64(count))
+	h.Write(treeRoot)
+	return h.Sum(nil)
+}
+
+func chunkLeaf(chunk []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{0})
+	h.Write(chunk)
+	return h.Sum(nil)
+}
+
+func chunkNode(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{1})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// merkleLevels returns the levels of the Merkle tree of the chunks, from the
+// root to the leaves. A node without sibling is moved up unchanged.
+func merkleLevels(chunks [][]byte) [][][]byte {
+	level := make([][]byte, len(chunks))
+	for i, c := range chunks {
+		level[i] = chunkLeaf(c)
+	}
+	levels := [][][]byte{level}
+	for len(level) > 1 {
+		var next [][]byte
+		for i := 0; i < len(level); i += 2 {
+			if i+1 < len(level) {
+				next = append(next, chunkNode(level[i], level[i+1]))
+			} else {
+				next = append(next, level[i])
+			}
+		}
+		levels = append([][][]byte{next}, levels...)
+		level = next
+	}
+	return levels
+}
+
+// chunkProof returns the siblings of the path from the chunk to the root,
+// starting at the leaves.
+func chunkProof(levels [][][]byte, index int) [][]byte {
+	var proof [][]byte
+	for l := len(levels) - 1; l > 0; l-- {
+		if sibling := index ^ 1; sibling < len(levels[l]) {
+			proof = append(proof, levels[l][sibling])
+		}
+		index /= 2
+	}
+	return proof
+}
+
+// BlockChunk is a chunk of the body of a block with the proof that it is part
+// of the body.
+type BlockChunk struct {
+	Index int
+	Data  []byte
+	// Proof holds the siblings of the path from the chunk to the root of
+	// the Merkle tree, starting at the leaves.
+	Proof [][]byte
+}
+
+// Verify checks the chunk against the root of the body of a block with the
+// given number of chunks.
+func (c BlockChunk) Verify(bodyRoot []byte, count int) error {
+	if c.Index < 0 || c.Index >= count {
+		return xerrors.Errorf("index %d out of %d chunks", c.Index, count)
+	}
+	if len(c.Data) > ChunkSize || (c.Index < count-1 && len(c.Data) != ChunkSize) {
+		return xerrors.New("wrong size of the chunk")
+	}
+	h := chunkLeaf(c.Data)
+	index, width := c.Index, count
+	proof := c.Proof
+	for width > 1 {
+		if sibling := index ^ 1; sibling < width {
+			if len(proof) == 0 {
+				return xerrors.New("proof is too short")
+			}
+			if sibling < index {
+				h = chunkNode(proof[0], h)
+			} else {
+				h = chunkNode(h, proof[0])
+			}
+			proof = proof[1:]
+		}
+		index /= 2
+		width = (width + 1) / 2
+	}
+	if len(proof) > 0 {
+		return xerrors.New("proof is too long")
+	}
+	if !bytes.Equal(bodyRootOf(count, h), bodyRoot) {
+		return xerrors.New("chunk doesn't match the body root")
+	}
+	return nil
+}
+
+// GetBlockChunks returns chunks of the body of a block with their proofs, so
+// that a light client can check that the body is available without
+// downloading it. The block is returned without its body.
+func (s *Service) GetBlockChunks(req *GetBlockChunks) (*GetBlockChunksResponse, error) {
+	if len(req.Indexes) > maxChunksPerRequest {
+		return nil, xerrors.Errorf("cannot ask for more than %d chunks", maxChunksPerRequest)
+	}
+	sb := s.db().GetByID(req.BlockID)
+	if sb == nil || !sb.SkipChainID().Equal(req.SkipChainID) {
+		return nil, xerrors.New("unknown block")
+	}
+	header, err := decodeBlockHeader(sb)
+	if err != nil {
+		return nil, xerrors.Errorf("decoding header: %v", err)
+	}
+	if len(header.BodyRoot) == 0 {
+		return nil, xerrors.New("block has no body root")
+	}
+
+	chunks := splitBody(sb.Payload)
+	levels := merkleLevels(chunks)
+	reply := &GetBlockChunksResponse{Count: len(chunks)}
+	for _, i := range req.Indexes {
+		if i < 0 || i >= len(chunks) {
+			return nil, xerrors.Errorf("index %d out of %d chunks", i, len(chunks))
+		}
+		reply.Chunks = append(reply.Chunks, BlockChunk{
+			Index: i,
+			Data:  chunks[i],
+			Proof: chunkProof(levels, i),
+		})
+	}
+	reply.Block = *sb.Copy()
+	reply.Block.Payload = nil
+	return reply, nil
+}
@@ -0,0 +1,80 @@
+package byzcoin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/skipchain"
+)
+
+func TestBlockChunk_Verify(t *testing.T) {
+	for _, size := range []int{0, 1, ChunkSize, ChunkSize + 1, 5*ChunkSize + 7} {
+		payload := make([]byte, size)
+		for i := range payload {
+			payload[i] = byte(i)
+		}
+		root := BodyRoot(payload)
+		chunks := splitBody(payload)
+		levels := merkleLevels(chunks)
+		for i, data := range chunks {
+			c := BlockChunk{Index: i, Data: data, Proof: chunkProof(levels, i)}
+			require.NoError(t, c.Verify(root, len(chunks)))
+			require.Error(t, c.Verify(root, len(chunks)+1))
+			if len(data) > 0 {
+				c.Data = append([]byte{}, data...)
+				c.Data[0]++
+				require.Error(t, c.Verify(root, len(chunks)))
+			}
+		}
+	}
+
+	c := BlockChunk{Index: 1, Data: []byte{1}}
+	require.Error(t, c.Verify(BodyRoot(nil), 1))
+}
+
+func TestService_GetBlockChunks(t *testing.T) {
+	s := newSer(t, 1, testInterval)
+	defer s.local.CloseAll()
+
+	// The value of the instance gives a body of several chunks.
+	s.value = make([]byte, 3*ChunkSize)
+	tx, err := createOneClientTxWithCounter(s.darc.GetBaseID(), dummyContract, s.value, s.signer, 1)
+	require.NoError(t, err)
+	s.sendTxAndWait(t, tx, 10)
+
+	scid := s.genesis.SkipChainID()
+	latest, err := s.service().db().GetLatestByID(scid)
+	require.NoError(t, err)
+	header, err := decodeBlockHeader(latest)
+	require.NoError(t, err)
+	require.Equal(t, BodyRoot(latest.Payload), header.BodyRoot)
+
+	reply, err := s.service().GetBlockChunks(&GetBlockChunks{
+		SkipChainID: scid,
+		BlockID:     latest.Hash,
+		Indexes:     []int{0, 2},
+	})
+	require.NoError(t, err)
+	require.True(t, reply.Count > 3)
+	require.Nil(t, reply.Block.Payload)
+	require.True(t, reply.Block.CalculateHash().Equal(latest.Hash))
+	for _, c := range reply.Chunks {
+		require.NoError(t, c.Verify(header.BodyRoot, reply.Count))
+	}
+
+	_, err = s.service().GetBlockChunks(&GetBlockChunks{
+		SkipChainID: scid,
+		BlockID:     latest.Hash,
+		Indexes:     []int{reply.Count},
+	})
+	require.Error(t, err)
+	_, err = s.service().GetBlockChunks(&GetBlockChunks{
+		SkipChainID: scid,
+		BlockID:     skipchain.SkipBlockID{1, 2, 3},
+	})
+	require.Error(t, err)
+
+	cl := NewClient(scid, *s.roster)
+	require.NoError(t, cl.SampleBlock(latest.Hash, 5))
+	require.Error(t, cl.SampleBlock(skipchain.SkipBlockID{1, 2, 3}, 5))
+}
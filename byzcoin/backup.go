@@ -0,0 +1,148 @@
+package byzcoin
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/skipchain"
+	"go.dedis.ch/kyber/v3/sign/schnorr"
+	"go.dedis.ch/onet/v3/log"
+	"go.etcd.io/bbolt"
+	"golang.org/x/xerrors"
+)
+
+// skipchainBucket is the bucket used by the skipchain service to store the
+// blocks.
+var skipchainBucket = []byte("Skipchain_skipblocks")
+
+// BackupRequestMessage returns the message to be signed for a BackupRequest.
+func BackupRequestMessage(path string, timestamp int64) []byte {
+	msg := append([]byte(path), make([]byte, 8)...)
+	binary.LittleEndian.PutUint64(msg[len(path):], uint64(timestamp))
+	return msg
+}
+
+// Backup writes a consistent snapshot of the database of the conode to the
+// given path. The snapshot is taken in a single read-transaction while no
+// block is applied to the state tries, so that the skipchains, the tries and
+// the state changes match. The written file is verified using VerifyBackup
+// before returning.
+func (s *Service) Backup(req *BackupRequest) (*BackupResponse, error) {
+	if math.Abs(time.Since(time.Unix(req.Timestamp, 0)).Seconds()) > 60 {
+		return nil, xerrors.New("signature is too old")
+	}
+	err := schnorr.Verify(cothority.Suite, s.ServerIdentity().Public,
+		BackupRequestMessage(req.Path, req.Timestamp), req.Signature)
+	if err != nil {
+		return nil, xerrors.Errorf("verifying signature: %v", err)
+	}
+	if req.Path == "" {
+		return nil, xerrors.New("empty path")
+	}
+	log.Lvlf1("%s: writing backup to %s", s.ServerIdentity(), req.Path)
+
+	s.updateTrieLock.Lock()
+	err = s.db().View(func(tx *bbolt.Tx) error {
+		return tx.CopyFile(req.Path, 0600)
+	})
+	s.updateTrieLock.Unlock()
+	if err != nil {
+		return nil, xerrors.Errorf("writing backup: %v", err)
+	}
+
+	chains, err := VerifyBackup(req.Path)
+	if err != nil {
+		return nil, xerrors.Errorf("verifying backup: %v", err)
+	}
+	return &BackupResponse{Chains: chains}, nil
+}
+
+// VerifyBackup checks the database stored in path before it is used to
+// restore a conode. For every ByzCoin chain in the database, it verifies that
+// the blocks from the genesis block up to the block of the state trie are
+// correctly linked and signed, that the root of the state trie matches this
+// block, and that the contents of the trie match its root. The database is
+// opened read-only, so that it is not changed by the verification.
+func VerifyBackup(path string) ([]BackupChain, error) {
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: time.Second, ReadOnly: true})
+	if err != nil {
+		return nil, xerrors.Errorf("opening backup: %v", err)
+	}
+	defer db.Close()
+
+	err = db.View(func(tx *bbolt.Tx) error {
+		if tx.Bucket(skipchainBucket) == nil {
+			return xerrors.New("no skipchain bucket")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sbDB := skipchain.NewSkipBlockDB(db, skipchainBucket)
+	chains, err := sbDB.GetSkipchains()
+	if err != nil {
+		return nil, xerrors.Errorf("getting chains: %v", err)
+	}
+
+	var out []BackupChain
+	for _, sb := range chains {
+		genesis := sbDB.GetByID(sb.SkipChainID())
+		if genesis == nil {
+			return nil, xerrors.Errorf("missing genesis block of %x", sb.SkipChainID())
+		}
+		if !isByzCoinGenesis(genesis) {
+			continue
+		}
+		bc, err := verifyBackupChain(db, sbDB, genesis)
+		if err != nil {
+			return nil, xerrors.Errorf("chain %x: %v", genesis.Hash, err)
+		}
+		out = append(out, *bc)
+	}
+	return out, nil
+}
+
+func isByzCoinGenesis(sb *skipchain.SkipBlock) bool {
+	for _, x := range sb.VerifierIDs {
+		if x.Equal(Verify) {
+			return true
+		}
+	}
+	return false
+}
+
+func verifyBackupChain(db *bbolt.DB, sbDB *skipchain.SkipBlockDB, genesis *skipchain.SkipBlock) (*BackupChain, error) {
+	if !genesis.CalculateHash().Equal(genesis.Hash) {
+		return nil, xerrors.New("wrong hash of genesis block")
+	}
+	st, err := loadStateTrie(db, []byte(fmt.Sprintf("ByzCoin_%x", genesis.Hash)))
+	if err != nil {
+		return nil, xerrors.Errorf("loading trie: %v", err)
+	}
+	// The proof links the genesis block to the block of the trie and
+	// checks that the root of the trie is stored in this block.
+	proof, err := NewProof(st, sbDB, genesis.Hash, ConfigInstanceID.Slice())
+	if err != nil {
+		return nil, xerrors.Errorf("creating proof: %v", err)
+	}
+	if err := proof.Verify(genesis.Hash); err != nil {
+		return nil, xerrors.Errorf("verifying proof: %v", err)
+	}
+	if !bytes.Equal(proof.InclusionProof.GetRoot(), st.GetRoot()) {
+		return nil, xerrors.New("root of the trie doesn't match the proof")
+	}
+	if err := st.VerifyContents(); err != nil {
+		return nil, xerrors.Errorf("verifying trie: %v", err)
+	}
+	return &BackupChain{
+		ByzCoinID: genesis.Hash,
+		Index:     st.GetIndex(),
+		TrieRoot:  st.GetRoot(),
+	}, nil
+}
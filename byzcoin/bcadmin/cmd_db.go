@@ -4,6 +4,8 @@ import (
 	"encoding/hex"
 	"flag"
 	"fmt"
+	"io"
+	"os"
 	"strings"
 	"time"
 
@@ -37,6 +39,44 @@ func dbStatus(c *cli.Context) error {
 	return nil
 }
 
+// dbRestore verifies a backup created by "debug backup" and copies it to a
+// new db-file.
+func dbRestore(c *cli.Context) error {
+	if c.NArg() < 2 {
+		return xerrors.New("please give the following arguments: " +
+			"backup.db conode.db")
+	}
+	backup, dst := c.Args().Get(0), c.Args().Get(1)
+
+	chains, err := byzcoin.VerifyBackup(backup)
+	if err != nil {
+		return xerrors.Errorf("invalid backup: %+v", err)
+	}
+	for _, bc := range chains {
+		log.Infof("Verified ByzCoinID %x at index %d", bc.ByzCoinID, bc.Index)
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if c.Bool("overwrite") {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	out, err := os.OpenFile(dst, flags, 0600)
+	if err != nil {
+		return xerrors.Errorf("couldn't create db-file: %+v", err)
+	}
+	defer out.Close()
+	in, err := os.Open(backup)
+	if err != nil {
+		return xerrors.Errorf("couldn't open backup: %+v", err)
+	}
+	defer in.Close()
+	if _, err := io.Copy(out, in); err != nil {
+		return xerrors.Errorf("couldn't copy backup: %+v", err)
+	}
+	log.Infof("Restored %s to %s", backup, dst)
+	return nil
+}
+
 // dbCatchup uses the live byzcoin chain to update to the latest blocks
 func dbCatchup(c *cli.Context) error {
 	if c.NArg() < 3 {
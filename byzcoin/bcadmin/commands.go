@@ -33,6 +33,16 @@ var cmds = cli.Commands{
 				Name:  "blockSize",
 				Usage: "adjust the maximum block size",
 			},
+			cli.StringSliceFlag{
+				Name: "quota",
+				Usage: "set a storage quota as contract:ID:bytes:instances or " +
+					"darc:HEX:bytes:instances - 0 for both limits removes the quota",
+			},
+			cli.IntFlag{
+				Name:  "maxDarcDepth",
+				Value: -1,
+				Usage: "limit the number of nested darcs evaluated for an instruction - 0 for no limit",
+			},
 		},
 	},
 
@@ -468,6 +478,14 @@ var cmds = cli.Commands{
 				Usage: "the block interval for this ledger",
 				Value: 5 * time.Second,
 			},
+			cli.IntFlag{
+				Name:  "base-height",
+				Usage: "the base height of the skiplist, 0 for the default",
+			},
+			cli.IntFlag{
+				Name:  "max-height",
+				Usage: "the maximum height of the skiplist, 0 for the default",
+			},
 		},
 	},
 
@@ -720,6 +738,19 @@ var cmds = cli.Commands{
 					},
 				},
 			},
+			{
+				Name: "restore",
+				Usage: "Verify a backup and copy it to the given db-file, " +
+					"which must not be used by a running conode",
+				ArgsUsage: "conode.db",
+				Action:    dbRestore,
+				Flags: []cli.Flag{
+					cli.BoolFlag{
+						Name:  "overwrite",
+						Usage: "replace an existing db-file",
+					},
+				},
+			},
 		},
 	},
 
@@ -795,6 +826,12 @@ var cmds = cli.Commands{
 				ArgsUsage: "private.toml byzcoin-id",
 				Action:    debugRemove,
 			},
+			{
+				Name:      "backup",
+				Usage:     "writes a consistent snapshot of the db of the conode",
+				ArgsUsage: "private.toml path-on-conode",
+				Action:    debugBackup,
+			},
 			{
 				Name:      "counters",
 				Usage:     "shows the counter-state in all nodes",
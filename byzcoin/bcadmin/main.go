@@ -117,6 +117,8 @@ func create(c *cli.Context) error {
 	}
 
 	req.BlockInterval = interval
+	req.BaseHeight = c.Int("base-height")
+	req.MaximumHeight = c.Int("max-height")
 
 	cl, resp, err := byzcoin.NewLedger(req, false)
 	if err != nil {
@@ -575,6 +577,16 @@ func config(c *cli.Context) error {
 		}
 		chainConfig.MaxBlockSize = blockSize
 	}
+	for _, quota := range c.StringSlice("quota") {
+		q, err := parseQuota(quota)
+		if err != nil {
+			return xerrors.Errorf("couldn't parse quota: %v", err)
+		}
+		chainConfig.Quotas = setQuota(chainConfig.Quotas, q)
+	}
+	if depth := c.Int("maxDarcDepth"); depth >= 0 {
+		chainConfig.MaxDarcDepth = depth
+	}
 
 	err = updateConfig(cl, signer, chainConfig)
 	if err != nil {
@@ -586,6 +598,50 @@ func config(c *cli.Context) error {
 	return lib.WaitPropagation(c, cl)
 }
 
+// parseQuota parses a quota given as contract:ID:bytes:instances or
+// darc:HEX:bytes:instances.
+func parseQuota(s string) (q byzcoin.StorageQuota, err error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 4 {
+		return q, xerrors.New("need 4 parts separated by ':'")
+	}
+	switch parts[0] {
+	case "contract":
+		q.ContractID = parts[1]
+	case "darc":
+		q.DarcID, err = hex.DecodeString(parts[1])
+		if err != nil {
+			return q, xerrors.Errorf("decoding darc ID: %v", err)
+		}
+	default:
+		return q, xerrors.New("unknown quota type: " + parts[0])
+	}
+	q.MaxBytes, err = strconv.ParseUint(parts[2], 10, 64)
+	if err != nil {
+		return q, xerrors.Errorf("parsing bytes: %v", err)
+	}
+	q.MaxInstances, err = strconv.ParseUint(parts[3], 10, 64)
+	if err != nil {
+		return q, xerrors.Errorf("parsing instances: %v", err)
+	}
+	return q, nil
+}
+
+// setQuota replaces the quota with the same contract or darc, or removes it
+// if it has no limits.
+func setQuota(quotas []byzcoin.StorageQuota, q byzcoin.StorageQuota) []byzcoin.StorageQuota {
+	var out []byzcoin.StorageQuota
+	for _, old := range quotas {
+		if old.ContractID != q.ContractID || !bytes.Equal(old.DarcID, q.DarcID) {
+			out = append(out, old)
+		}
+	}
+	if q.MaxBytes > 0 || q.MaxInstances > 0 {
+		out = append(out, q)
+	}
+	return out
+}
+
 func mint(c *cli.Context) error {
 	if c.NArg() < 4 {
 		return xerrors.New("please give the following arguments: " +
@@ -1274,6 +1330,30 @@ func debugRemove(c *cli.Context) error {
 	return nil
 }
 
+func debugBackup(c *cli.Context) error {
+	if c.NArg() < 2 {
+		return xerrors.New("please give the following arguments: private.toml path-on-conode")
+	}
+
+	ccfg, err := app.LoadCothority(c.Args().First())
+	if err != nil {
+		return err
+	}
+	si, err := ccfg.GetServerIdentity()
+	if err != nil {
+		return err
+	}
+	resp, err := byzcoin.Backup(si, c.Args().Get(1))
+	if err != nil {
+		return err
+	}
+	for _, bc := range resp.Chains {
+		log.Infof("ByzCoinID %x at index %d", bc.ByzCoinID, bc.Index)
+	}
+	log.Infof("Successfully wrote backup to %s on %s", c.Args().Get(1), si.Address)
+	return nil
+}
+
 func debugCounters(c *cli.Context) error {
 	if c.NArg() < 2 {
 		return xerrors.New("please give the following arguments: bc-xxx.cfg key-xxx.cfg")
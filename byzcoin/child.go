@@ -0,0 +1,121 @@
+package byzcoin
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+
+	"go.dedis.ch/cothority/v3/darc"
+	"golang.org/x/xerrors"
+)
+
+// This file holds helpers for contracts that create child instances, like a
+// factory creating the instances it manages, or an instance storing part of
+// its data in separate instances. A child is identified by its parent and a
+// name, so that it can be found again without knowing the instruction that
+// created it, as it would be needed with Instruction.DeriveID.
+//
+// A typical Spawn or Invoke of a parent creates its children like this:
+//
+//   sc, err := NewChildStateChange(rst, Create, inst.InstanceID, "account",
+//       "coin", buf, nil)
+//
+// and a child that needs to find its parent stores a ChildRef in its data.
+
+// ChildID returns the instance ID of the child with the given name of the
+// parent.
+func ChildID(parent InstanceID, name string) InstanceID {
+	var b [4]byte
+	h := sha256.New()
+	h.Write([]byte("child"))
+	h.Write(parent[:])
+	binary.LittleEndian.PutUint32(b[:], uint32(len(name)))
+	h.Write(b[:])
+	h.Write([]byte(name))
+	return NewInstanceID(h.Sum(nil))
+}
+
+// ChildRef is the reference of a child to its parent. Contracts can store it
+// in the data of their children to look up the parent.
+type ChildRef struct {
+	Parent InstanceID
+	Name   string
+}
+
+// ID returns the instance ID of the child.
+func (cr ChildRef) ID() InstanceID {
+	return ChildID(cr.Parent, cr.Name)
+}
+
+// IsChildOf returns true if the reference points to the given child.
+func (cr ChildRef) IsChildOf(child InstanceID) bool {
+	return cr.ID().Equal(child)
+}
+
+// NewChildStateChange returns the state change for the child with the given
+// name of the parent. For a Create, the child must not exist yet, and if
+// darcID is nil, the child is governed by the darc of the parent. For an
+// Update or a Remove, the child must exist, and if darcID or contractID are
+// empty, the ones of the child are kept. The version of the state change is
+// set by the service, like for any other state change.
+func NewChildStateChange(rst ReadOnlyStateTrie, sa StateAction, parent InstanceID, name, contractID string, value []byte, darcID darc.ID) (StateChange, error) {
+	id := ChildID(parent, name)
+	old, _, cid, did, err := rst.GetValues(id.Slice())
+	if xerrors.Is(err, errKeyNotSet) {
+		old, err = nil, nil
+	}
+	if err != nil {
+		return StateChange{}, xerrors.Errorf("reading child %s: %v", name, err)
+	}
+	switch sa {
+	case Create:
+		if old != nil {
+			return StateChange{}, xerrors.Errorf("child %s already exists", name)
+		}
+		if darcID == nil {
+			_, _, _, darcID, err = GetValueContract(rst, parent.Slice())
+			if err != nil {
+				return StateChange{}, xerrors.Errorf("reading parent: %v", err)
+			}
+		}
+		if contractID == "" {
+			return StateChange{}, xerrors.New("need a contract ID to create a child")
+		}
+	case Update, Remove:
+		if old == nil {
+			return StateChange{}, xerrors.Errorf("child %s doesn't exist", name)
+		}
+		if darcID == nil {
+			darcID = did
+		}
+		if contractID == "" {
+			contractID = cid
+		}
+	default:
+		return StateChange{}, xerrors.Errorf("invalid state action %s", sa)
+	}
+	return NewStateChange(sa, id, contractID, value, darcID), nil
+}
+
+// GetChildValues returns the values of the child with the given name of the
+// parent. An error is returned if the child doesn't exist.
+func GetChildValues(rst ReadOnlyStateTrie, parent InstanceID, name string) (value []byte, version uint64, contractID string, darcID darc.ID, err error) {
+	value, version, contractID, darcID, err = GetValueContract(rst, ChildID(parent, name).Slice())
+	if err != nil {
+		err = xerrors.Errorf("reading child %s: %v", name, err)
+	}
+	return
+}
+
+// GetParentValues returns the values of the parent of a child, and verifies
+// that the reference points to the child.
+func GetParentValues(rst ReadOnlyStateTrie, child InstanceID, ref ChildRef) (value []byte, version uint64, contractID string, darcID darc.ID, err error) {
+	if !ref.IsChildOf(child) {
+		err = xerrors.New("reference doesn't point to the child")
+		return
+	}
+	value, version, contractID, darcID, err = GetValueContract(rst, ref.Parent.Slice())
+	if err != nil {
+		err = xerrors.Errorf("reading parent: %v", err)
+	}
+	return
+}
@@ -0,0 +1,85 @@
+package byzcoin
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/darc"
+)
+
+func TestChild(t *testing.T) {
+	st, err := newMemStateTrie([]byte("nonce"))
+	require.NoError(t, err)
+	parent := NewInstanceID([]byte("parent"))
+	parentDarc := darc.ID(make([]byte, 32))
+	require.NoError(t, st.StoreAll(StateChanges{
+		NewStateChange(Create, parent, "value", []byte("parent"), parentDarc),
+	}, 1, CurrentVersion))
+
+	require.NotEqual(t, ChildID(parent, "a"), ChildID(parent, "b"))
+	require.NotEqual(t, ChildID(parent, "a"), ChildID(NewInstanceID([]byte("other")), "a"))
+
+	// Children can only be updated once they exist.
+	_, err = NewChildStateChange(st, Update, parent, "a", "", []byte("a1"), nil)
+	require.Error(t, err)
+	_, err = NewChildStateChange(st, Create, parent, "a", "", []byte("a"), nil)
+	require.Error(t, err)
+	sc, err := NewChildStateChange(st, Create, parent, "a", "value", []byte("a"), nil)
+	require.NoError(t, err)
+	require.Equal(t, parentDarc, sc.DarcID)
+	require.Equal(t, ChildID(parent, "a").Slice(), sc.InstanceID)
+	require.NoError(t, st.StoreAll(StateChanges{sc}, 2, CurrentVersion))
+	_, err = NewChildStateChange(st, Create, parent, "a", "value", []byte("a"), nil)
+	require.Error(t, err)
+
+	otherDarc := darc.ID(make([]byte, 32))
+	otherDarc[0] = 1
+	sc, err = NewChildStateChange(st, Update, parent, "a", "", []byte("a1"), otherDarc)
+	require.NoError(t, err)
+	require.Equal(t, "value", sc.ContractID)
+	sc.Version = 1
+	require.NoError(t, st.StoreAll(StateChanges{sc}, 3, CurrentVersion))
+
+	value, version, _, darcID, err := GetChildValues(st, parent, "a")
+	require.NoError(t, err)
+	require.Equal(t, []byte("a1"), value)
+	require.Equal(t, uint64(1), version)
+	require.Equal(t, otherDarc, darcID)
+	_, _, _, _, err = GetChildValues(st, parent, "b")
+	require.Error(t, err)
+
+	ref := ChildRef{Parent: parent, Name: "a"}
+	require.True(t, ref.IsChildOf(ChildID(parent, "a")))
+	value, _, _, _, err = GetParentValues(st, ChildID(parent, "a"), ref)
+	require.NoError(t, err)
+	require.Equal(t, []byte("parent"), value)
+	_, _, _, _, err = GetParentValues(st, ChildID(parent, "b"), ref)
+	require.Error(t, err)
+
+	sc, err = NewChildStateChange(st, Remove, parent, "a", "", nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, otherDarc, sc.DarcID)
+}
+
+func ExampleNewChildStateChange() {
+	st, _ := newMemStateTrie([]byte("nonce"))
+	factory := NewInstanceID([]byte("factory"))
+	st.StoreAll(StateChanges{
+		NewStateChange(Create, factory, "value", []byte{}, darc.ID(make([]byte, 32))),
+	}, 1, CurrentVersion)
+
+	// In the Invoke method of the factory, the child is created with the
+	// darc of the factory.
+	sc, _ := NewChildStateChange(st, Create, factory, "item-1", "value",
+		[]byte("first item"), nil)
+	st.StoreAll(StateChanges{sc}, 2, CurrentVersion)
+
+	// Later on, the child can be found with the name only.
+	value, _, _, _, _ := GetChildValues(st, factory, "item-1")
+	fmt.Println(string(value))
+	fmt.Println(ChildID(factory, "item-1").Equal(NewInstanceID(sc.InstanceID)))
+	// Output:
+	// first item
+	// true
+}
@@ -145,6 +145,11 @@ func (c *contractSecureDarc) Invoke(rst ReadOnlyStateTrie, inst Instruction, coi
 			if !newD.Rules.IsSubset(oldD.Rules) {
 				return nil, nil, xerrors.New("rules in the new version must be a subset of the previous version")
 			}
+			for _, id := range oldD.Revoked {
+				if !newD.IsRevoked(id) {
+					return nil, nil, xerrors.New("removing a revocation needs the evolve_unrestricted command")
+				}
+			}
 		}
 		return []StateChange{
 			NewStateChange(Update, inst.InstanceID, ContractDarcID, darcBuf, darcID),
@@ -187,3 +192,34 @@ func isChangingEvolveUnrestricted(oldD *darc.Darc, newD *darc.Darc) bool {
 	}
 	return true
 }
+
+// NewRevocationInstruction returns the instruction evolving the darc to a new
+// version, where the identities of revoke are added to its revocation list,
+// and the ones of unrevoke are removed from it. As removing a revocation
+// needs the evolve_unrestricted command, this command is used if unrevoke is
+// not empty. The signer counters of the instruction must be set before
+// signing it.
+func NewRevocationInstruction(d *darc.Darc, revoke, unrevoke []string) (Instruction, error) {
+	newD := d.Copy()
+	if err := newD.EvolveFrom(d); err != nil {
+		return Instruction{}, xerrors.Errorf("evolving darc: %v", err)
+	}
+	newD.Revoke(revoke...)
+	newD.Unrevoke(unrevoke...)
+	buf, err := newD.ToProto()
+	if err != nil {
+		return Instruction{}, xerrors.Errorf("encoding darc: %v", err)
+	}
+	cmd := cmdDarcEvolve
+	if len(unrevoke) > 0 {
+		cmd = cmdDarcEvolveUnrestriction
+	}
+	return Instruction{
+		InstanceID: NewInstanceID(d.GetBaseID()),
+		Invoke: &Invoke{
+			ContractID: ContractDarcID,
+			Command:    cmd,
+			Args:       Arguments{{Name: "darc", Value: buf}},
+		},
+	}, nil
+}
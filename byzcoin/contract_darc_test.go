@@ -6,6 +6,7 @@ import (
 
 	"go.dedis.ch/cothority/v3"
 	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/cothority/v3/darc/expression"
 	"go.dedis.ch/cothority/v3/skipchain"
 	"go.dedis.ch/onet/v3"
 	"go.dedis.ch/onet/v3/log"
@@ -241,3 +242,97 @@ func TestSecureDarc(t *testing.T) {
 
 	require.NoError(t, local.WaitDone(5*genesisMsg.BlockInterval))
 }
+
+func TestSecureDarc_Revocation(t *testing.T) {
+	local := onet.NewTCPTest(cothority.Suite)
+	defer local.CloseAll()
+
+	signer := darc.NewSignerEd25519(nil, nil)
+	_, roster, _ := local.GenTree(3, true)
+
+	genesisMsg, err := DefaultGenesisMsg(CurrentVersion, roster, []string{}, signer.Identity())
+	require.NoError(t, err)
+	gDarc := &genesisMsg.GenesisDarc
+	genesisMsg.BlockInterval = time.Second
+	cl, _, err := NewLedger(genesisMsg, false)
+	require.NoError(t, err)
+
+	admin := darc.NewSignerEd25519(nil, nil)
+	compromised := darc.NewSignerEd25519(nil, nil)
+	invokeEvolve := darc.Action("invoke:" + ContractDarcID + "." + cmdDarcEvolve)
+	invokeEvolveUnrestricted := darc.Action("invoke:" + ContractDarcID + "." + cmdDarcEvolveUnrestriction)
+
+	secDarc := darc.NewDarc(darc.InitRules(nil, nil), []byte("revocation"))
+	require.NoError(t, secDarc.Rules.UpdateRule(invokeEvolve,
+		expression.InitOrExpr(admin.Identity().String(), compromised.Identity().String())))
+	require.NoError(t, secDarc.Rules.UpdateRule(invokeEvolveUnrestricted,
+		[]byte(admin.Identity().String())))
+	secDarcBuf, err := secDarc.ToProto()
+	require.NoError(t, err)
+	ctx, err := cl.CreateTransaction(Instruction{
+		InstanceID: NewInstanceID(gDarc.GetBaseID()),
+		Spawn: &Spawn{
+			ContractID: ContractDarcID,
+			Args:       Arguments{{Name: "darc", Value: secDarcBuf}},
+		},
+		SignerCounter: []uint64{1},
+	})
+	require.NoError(t, err)
+	require.NoError(t, ctx.FillSignersAndSignWith(signer))
+	_, err = cl.AddTransactionAndWait(ctx, 10)
+	require.NoError(t, err)
+
+	send := func(d *darc.Darc, revoke, unrevoke []string, s darc.Signer, counter uint64) (*darc.Darc, error) {
+		inst, err := NewRevocationInstruction(d, revoke, unrevoke)
+		require.NoError(t, err)
+		inst.SignerCounter = []uint64{counter}
+		ctx, err := cl.CreateTransaction(inst)
+		require.NoError(t, err)
+		require.NoError(t, ctx.FillSignersAndSignWith(s))
+		atr, err := cl.AddTransactionAndWait(ctx, 10)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := cl.GetProofAfter(d.GetBaseID(), false, &atr.Proof.Latest)
+		require.NoError(t, err)
+		newD := &darc.Darc{}
+		require.NoError(t, resp.Proof.VerifyAndDecode(cothority.Suite, ContractDarcID, newD))
+		return newD, nil
+	}
+
+	log.Lvl1("revoke the compromised key")
+	compromisedID := compromised.Identity().String()
+	secDarc, err = send(secDarc, []string{compromisedID}, nil, admin, 1)
+	require.NoError(t, err)
+	require.True(t, secDarc.IsRevoked(compromisedID))
+
+	log.Lvl1("the compromised key cannot evolve the darc anymore")
+	_, err = send(secDarc, []string{"ed25519:aa"}, nil, compromised, 1)
+	require.Error(t, err)
+
+	log.Lvl1("removing the revocation needs evolve_unrestricted")
+	secDarc2 := secDarc.Copy()
+	require.NoError(t, secDarc2.EvolveFrom(secDarc))
+	secDarc2.Unrevoke(compromisedID)
+	secDarc2Buf, err := secDarc2.ToProto()
+	require.NoError(t, err)
+	ctx, err = cl.CreateTransaction(Instruction{
+		InstanceID: NewInstanceID(secDarc.GetBaseID()),
+		Invoke: &Invoke{
+			ContractID: ContractDarcID,
+			Command:    cmdDarcEvolve,
+			Args:       Arguments{{Name: "darc", Value: secDarc2Buf}},
+		},
+		SignerCounter: []uint64{2},
+	})
+	require.NoError(t, err)
+	require.NoError(t, ctx.FillSignersAndSignWith(admin))
+	_, err = cl.AddTransactionAndWait(ctx, 10)
+	require.Error(t, err)
+
+	secDarc, err = send(secDarc, nil, []string{compromisedID}, admin, 2)
+	require.NoError(t, err)
+	require.False(t, secDarc.IsRevoked(compromisedID))
+
+	require.NoError(t, local.WaitDone(5*genesisMsg.BlockInterval))
+}
@@ -0,0 +1,223 @@
+package byzcoin
+
+import (
+	"fmt"
+	"strings"
+
+	"go.dedis.ch/protobuf"
+	"golang.org/x/xerrors"
+)
+
+// The job contract allows other contracts to run operations that are too
+// big for one block, like large migrations or batch mints. The contract
+// doing the work implements ContractStepper and is called once per block
+// with the checkpoint it returned the previous time, until it reports that
+// it is done. Clients poll the job instance to learn about its progress.
+
+// ContractJobID denotes a contract that runs the execution of another
+// contract over several blocks.
+const ContractJobID = "job"
+
+// JobData is the data stored in a job instance.
+type JobData struct {
+	// ContractID is the contract doing the work. It must implement
+	// ContractStepper.
+	ContractID string
+	// InstanceID is the instance the work is done on.
+	InstanceID InstanceID
+	// Args are all the arguments of the spawn instruction, except for
+	// "contractID" and "instanceID".
+	Args Arguments
+	// Checkpoint is the intermediate state returned by the last step.
+	Checkpoint []byte
+	// Steps counts the steps already executed.
+	Steps uint64
+	// Done is true once the stepper reported the end of the job.
+	Done bool
+	// StartIndex is the index of the block in which the job has been
+	// spawned.
+	StartIndex int
+	// LastIndex is the index of the block in which the last step has been
+	// executed. Only one step per job is executed in a block.
+	LastIndex int
+}
+
+// String returns a human readable representation of the job.
+func (jd JobData) String() string {
+	out := new(strings.Builder)
+	fmt.Fprintf(out, "- Contract: %s\n", jd.ContractID)
+	fmt.Fprintf(out, "- Instance: %x\n", jd.InstanceID[:])
+	fmt.Fprintf(out, "- Steps: %d\n", jd.Steps)
+	fmt.Fprintf(out, "- Done: %t\n", jd.Done)
+	fmt.Fprintf(out, "- Blocks: %d - %d\n", jd.StartIndex, jd.LastIndex)
+	return out.String()
+}
+
+// ContractStepper is implemented by contracts that support executions over
+// several blocks using the job contract.
+type ContractStepper interface {
+	// Step executes a bounded part of the job and returns the state changes
+	// of this part, the checkpoint to use for the next step, and whether
+	// the job is done. The checkpoint in job is nil for the first step.
+	Step(rst ReadOnlyStateTrie, job JobData) (sc StateChanges, checkpoint []byte, done bool, err error)
+}
+
+type contractJob struct {
+	BasicContract
+	JobData
+	contracts ReadOnlyContractRegistry
+}
+
+var _ Contract = (*contractJob)(nil)
+
+func contractJobFromBytes(in []byte) (Contract, error) {
+	c := &contractJob{}
+	err := protobuf.Decode(in, &c.JobData)
+	if err != nil {
+		return nil, xerrors.Errorf("couldn't unmarshal instance data: %v", err)
+	}
+	return c, nil
+}
+
+// SetRegistry keeps the reference of the contract registry.
+func (c *contractJob) SetRegistry(r ReadOnlyContractRegistry) {
+	c.contracts = r
+}
+
+// stepper returns the contract of the job's instance as a ContractStepper.
+func (c *contractJob) stepper(rst ReadOnlyStateTrie, jd JobData) (ContractStepper, error) {
+	if c.contracts == nil {
+		return nil, xerrors.New("contracts registry is missing due to bad initialization")
+	}
+	val, _, cid, _, err := GetValueContract(rst, jd.InstanceID.Slice())
+	if err != nil {
+		return nil, xerrors.Errorf("getting instance: %w", err)
+	}
+	if cid != jd.ContractID {
+		return nil, xerrors.Errorf("instance is of contract %s, not %s", cid, jd.ContractID)
+	}
+	fn, exists := c.contracts.Search(cid)
+	if !exists {
+		return nil, xerrors.Errorf("unknown contract %s", cid)
+	}
+	contract, err := fn(val)
+	if err != nil {
+		return nil, xerrors.Errorf("creating contract: %v", err)
+	}
+	if cwr, ok := contract.(ContractWithRegistry); ok {
+		cwr.SetRegistry(c.contracts)
+	}
+	s, ok := contract.(ContractStepper)
+	if !ok {
+		return nil, xerrors.Errorf("contract %s doesn't support jobs", cid)
+	}
+	return s, nil
+}
+
+// Spawn creates a new job. It needs the following arguments:
+//   - contractID string - the contract doing the work
+//   - instanceID InstanceID - the instance the work is done on
+//
+// All other arguments are stored in the job and passed to the stepper.
+func (c *contractJob) Spawn(rst ReadOnlyStateTrie, inst Instruction, coins []Coin) ([]StateChange, []Coin, error) {
+	_, _, _, darcID, err := rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return nil, nil, xerrors.Errorf("reading trie: %v", err)
+	}
+
+	jd := JobData{
+		ContractID: string(inst.Spawn.Args.Search("contractID")),
+		InstanceID: NewInstanceID(inst.Spawn.Args.Search("instanceID")),
+		StartIndex: rst.GetIndex(),
+		LastIndex:  rst.GetIndex(),
+	}
+	for _, arg := range inst.Spawn.Args {
+		if arg.Name != "contractID" && arg.Name != "instanceID" {
+			jd.Args = append(jd.Args, arg)
+		}
+	}
+	if _, err := c.stepper(rst, jd); err != nil {
+		return nil, nil, xerrors.Errorf("invalid job: %v", err)
+	}
+
+	jdBuf, err := protobuf.Encode(&jd)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("encoding job: %v", err)
+	}
+	return StateChanges{
+		NewStateChange(Create, inst.DeriveID(""), ContractJobID, jdBuf, darcID),
+	}, coins, nil
+}
+
+// VerifyInstruction verifies the instruction with the darc of the job and,
+// for the "step" command, also with the darc of the instance the job works
+// on. The rule of that darc is "invoke:<contractID>.step", so that being
+// allowed to spawn a job doesn't allow to change any instance.
+func (c *contractJob) VerifyInstruction(rst ReadOnlyStateTrie, inst Instruction, ctxHash []byte) error {
+	if err := c.BasicContract.VerifyInstruction(rst, inst, ctxHash); err != nil {
+		return err
+	}
+	if inst.GetType() != InvokeType || inst.Invoke.Command != "step" {
+		return nil
+	}
+	target := inst
+	target.InstanceID = c.InstanceID
+	target.Invoke = &Invoke{ContractID: c.ContractID, Command: "step"}
+	err := target.VerifyWithOption(rst, ctxHash,
+		&VerificationOptions{EvalAttr: c.MakeAttrInterpreters(rst, target)})
+	if err != nil {
+		return xerrors.Errorf("verifying with the darc of the instance: %v", err)
+	}
+	return nil
+}
+
+// Invoke has only the "step" command, which executes the next step of the
+// job. It fails if the job is done or if a step has already been executed
+// in the current block.
+func (c *contractJob) Invoke(rst ReadOnlyStateTrie, inst Instruction, coins []Coin) ([]StateChange, []Coin, error) {
+	_, _, _, darcID, err := rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return nil, nil, xerrors.Errorf("reading trie: %v", err)
+	}
+
+	switch inst.Invoke.Command {
+	case "step":
+		if c.Done {
+			return nil, nil, xerrors.New("job is already done")
+		}
+		if rst.GetIndex() <= c.LastIndex {
+			return nil, nil, xerrors.New("job already made a step in this block")
+		}
+		s, err := c.stepper(rst, c.JobData)
+		if err != nil {
+			return nil, nil, xerrors.Errorf("getting stepper: %v", err)
+		}
+		sc, cp, done, err := s.Step(rst, c.JobData)
+		if err != nil {
+			return nil, nil, xerrors.Errorf("step %d failed: %v", c.Steps, err)
+		}
+		c.Checkpoint = cp
+		c.Done = done
+		c.Steps++
+		c.LastIndex = rst.GetIndex()
+		jdBuf, err := protobuf.Encode(&c.JobData)
+		if err != nil {
+			return nil, nil, xerrors.Errorf("encoding job: %v", err)
+		}
+		sc = append(sc, NewStateChange(Update, inst.InstanceID, ContractJobID, jdBuf, darcID))
+		return sc, coins, nil
+	default:
+		return nil, nil, xerrors.New("job contract can only step")
+	}
+}
+
+// Delete removes the job, whether it is done or not.
+func (c *contractJob) Delete(rst ReadOnlyStateTrie, inst Instruction, coins []Coin) ([]StateChange, []Coin, error) {
+	_, _, _, darcID, err := rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return nil, nil, xerrors.Errorf("reading trie: %v", err)
+	}
+	return StateChanges{
+		NewStateChange(Remove, inst.InstanceID, ContractJobID, nil, darcID),
+	}, coins, nil
+}
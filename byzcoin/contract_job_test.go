@@ -0,0 +1,150 @@
+package byzcoin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/protobuf"
+)
+
+const testStepperID = "testStepper"
+
+// testStepper needs three steps to finish and stores the number of the last
+// step in its checkpoint.
+type testStepper struct {
+	BasicContract
+}
+
+func (testStepper) Step(rst ReadOnlyStateTrie, job JobData) (StateChanges, []byte, bool, error) {
+	step := byte(0)
+	if len(job.Checkpoint) > 0 {
+		step = job.Checkpoint[0] + 1
+	}
+	return nil, []byte{step}, step == 2, nil
+}
+
+func TestContractJob(t *testing.T) {
+	st, err := newMemStateTrie([]byte("nonce"))
+	require.NoError(t, err)
+	registry := newContractRegistry()
+	require.NoError(t, registry.register(testStepperID, func([]byte) (Contract, error) {
+		return testStepper{}, nil
+	}, false))
+	require.NoError(t, registry.register(ContractJobID, contractJobFromBytes, false))
+
+	darcID := darc.ID(make([]byte, 32))
+	target := NewInstanceID([]byte("target"))
+	require.NoError(t, st.StoreAll(StateChanges{
+		NewStateChange(Create, NewInstanceID(darcID), ContractDarcID, []byte{}, darcID),
+		NewStateChange(Create, target, testStepperID, []byte{}, darcID),
+	}, 1, CurrentVersion))
+
+	job := &contractJob{}
+	job.SetRegistry(registry)
+	spawn := Instruction{
+		InstanceID: NewInstanceID(darcID),
+		Spawn: &Spawn{
+			ContractID: ContractJobID,
+			Args: Arguments{
+				{Name: "contractID", Value: []byte(testStepperID)},
+				{Name: "instanceID", Value: target.Slice()},
+				{Name: "batch", Value: []byte("all")},
+			},
+		},
+	}
+	sc, _, err := job.Spawn(st, spawn, nil)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(sc))
+	jobID := spawn.DeriveID("")
+	require.NoError(t, st.StoreAll(sc, 2, CurrentVersion))
+
+	step := Instruction{
+		InstanceID: jobID,
+		Invoke:     &Invoke{ContractID: ContractJobID, Command: "step"},
+	}
+	getJob := func() *contractJob {
+		buf, _, _, _, err := st.GetValues(jobID.Slice())
+		require.NoError(t, err)
+		c, err := contractJobFromBytes(buf)
+		require.NoError(t, err)
+		c.(*contractJob).SetRegistry(registry)
+		return c.(*contractJob)
+	}
+
+	// Only one step is possible per block.
+	_, _, err = getJob().Invoke(st, step, nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "already made a step")
+
+	for i := 0; i < 3; i++ {
+		require.False(t, getJob().Done)
+		sc, _, err = getJob().Invoke(st, step, nil)
+		require.NoError(t, err)
+		require.NoError(t, st.StoreAll(sc, 3+i, CurrentVersion))
+	}
+
+	jd := getJob().JobData
+	require.True(t, jd.Done)
+	require.Equal(t, uint64(3), jd.Steps)
+	require.Equal(t, []byte{2}, jd.Checkpoint)
+	require.Equal(t, 2, jd.StartIndex)
+	require.Equal(t, 5, jd.LastIndex)
+	require.Equal(t, Arguments{{Name: "batch", Value: []byte("all")}}, jd.Args)
+
+	require.NoError(t, st.StoreAll(nil, 6, CurrentVersion))
+	_, _, err = getJob().Invoke(st, step, nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "already done")
+
+	// A job on a contract that doesn't support steps cannot be spawned.
+	spawn.Spawn.Args[0].Value = []byte(ContractDarcID)
+	spawn.Spawn.Args[1].Value = darcID
+	_, _, err = job.Spawn(st, spawn, nil)
+	require.Error(t, err)
+}
+
+// TestContractJob_Verify checks that the steps of a job must also be
+// allowed by the darc of the instance the job works on.
+func TestContractJob_Verify(t *testing.T) {
+	owner := darc.NewSignerEd25519(nil, nil)
+	other := darc.NewSignerEd25519(nil, nil)
+	both := []byte(owner.Identity().String() + " | " + other.Identity().String())
+	jobDarc := darc.NewDarc(darc.InitRules([]darc.Identity{owner.Identity()}, nil),
+		[]byte("job darc"))
+	require.NoError(t, jobDarc.Rules.AddRule("invoke:"+ContractJobID+".step", both))
+	targetDarc := darc.NewDarc(darc.InitRules([]darc.Identity{owner.Identity()}, nil),
+		[]byte("target darc"))
+	require.NoError(t, targetDarc.Rules.AddRule("invoke:"+testStepperID+".step",
+		[]byte(owner.Identity().String())))
+
+	st, err := newMemStateTrie([]byte("nonce"))
+	require.NoError(t, err)
+	targetBuf, err := targetDarc.ToProto()
+	require.NoError(t, err)
+	target := NewInstanceID([]byte("target"))
+	jd := JobData{ContractID: testStepperID, InstanceID: target}
+	jdBuf, err := protobuf.Encode(&jd)
+	require.NoError(t, err)
+	jobID := NewInstanceID([]byte("job"))
+	sc := append(darcTestStateChanges(t, jobDarc),
+		NewStateChange(Create, NewInstanceID(targetDarc.GetBaseID()), ContractDarcID,
+			targetBuf, targetDarc.GetBaseID()),
+		NewStateChange(Create, target, testStepperID, []byte{}, targetDarc.GetBaseID()),
+		NewStateChange(Create, jobID, ContractJobID, jdBuf, jobDarc.GetBaseID()))
+	require.NoError(t, st.StoreAll(sc, 1, CurrentVersion))
+
+	verify := func(signer darc.Signer) error {
+		ctx := NewClientTransaction(CurrentVersion, Instruction{
+			InstanceID:    jobID,
+			Invoke:        &Invoke{ContractID: ContractJobID, Command: "step"},
+			SignerCounter: []uint64{1},
+		})
+		require.NoError(t, ctx.FillSignersAndSignWith(signer))
+		c, err := contractJobFromBytes(jdBuf)
+		require.NoError(t, err)
+		return c.VerifyInstruction(st, ctx.Instructions[0], ctx.Instructions.Hash())
+	}
+	require.Error(t, verify(other))
+	require.NoError(t, verify(owner))
+}
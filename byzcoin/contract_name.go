@@ -26,9 +26,14 @@ import (
 // signed by the signer(s) that has the "_name" permission to spawn the to-be-named
 // instance ID.
 //
+// To point an existing name to another instance, use the update command with
+// the same two arguments. The new instance must be guarded by the same darc
+// as the old one, and the instruction must be signed by the signer(s) that
+// have the "_name" permission for the new instance.
+//
 // To get back a named instance ID, you should use the byzcoin API -
-// ResolveInstanceID. You need to provide a darc ID and the name. The darc ID
-// is the one that "guards" the the instance.
+// ResolveInstanceID, or a Resolver. You need to provide a darc ID and the
+// name. The darc ID is the one that "guards" the the instance.
 const ContractNamingID = "naming"
 
 // ContractNamingBody holds a reference of the latest naming entries. These
@@ -201,11 +206,7 @@ func (c *contractNaming) Invoke(rst ReadOnlyStateTrie, inst Instruction, coins [
 		if len(name) == 0 {
 			return nil, nil, xerrors.New("the name cannot be empty")
 		}
-		h := sha256.New()
-		h.Write(dID)
-		h.Write([]byte{'/'})
-		h.Write(name)
-		key := NewInstanceID(h.Sum(nil))
+		key := namingKey(dID, string(name))
 
 		// Check that we are not overwriting.
 		var oldEntryBuf []byte
@@ -260,11 +261,7 @@ func (c *contractNaming) Invoke(rst ReadOnlyStateTrie, inst Instruction, coins [
 		if len(name) == 0 {
 			return nil, nil, xerrors.New("the name cannot be empty")
 		}
-		h := sha256.New()
-		h.Write(dID)
-		h.Write([]byte{'/'})
-		h.Write(name)
-		key := NewInstanceID(h.Sum(nil))
+		key := namingKey(dID, string(name))
 
 		// Check that the name that we want to delete exists and is alive.
 		var oldEntryBuf []byte
@@ -289,6 +286,52 @@ func (c *contractNaming) Invoke(rst ReadOnlyStateTrie, inst Instruction, coins [
 			return nil, nil, xerrors.Errorf("encoding: %v", err)
 		}
 
+		sc := StateChanges{
+			NewStateChange(Update, key, "", entryBuf, nil),
+		}
+		return sc, coins, nil
+	case "update":
+		iID := inst.Invoke.Args.Search("instanceID")
+		var dID darc.ID
+		_, _, _, dID, err := rst.GetValues(iID)
+		if err != nil {
+			return nil, nil, xerrors.Errorf("reading trie: %v", err)
+		}
+
+		// Construct the key.
+		name := inst.Invoke.Args.Search("name")
+		if len(name) == 0 {
+			return nil, nil, xerrors.New("the name cannot be empty")
+		}
+		key := namingKey(dID, string(name))
+
+		// Check that the name that we want to update exists and is alive.
+		var oldEntryBuf []byte
+		oldEntryBuf, _, _, _, err = rst.GetValues(key.Slice())
+		if err != nil {
+			return nil, nil, xerrors.Errorf("reading trie: %v", err)
+		}
+		oldEntry := contractNamingEntry{}
+		err = protobuf.Decode(oldEntryBuf, &oldEntry)
+		if err != nil {
+			return nil, nil, xerrors.Errorf("decoding: %v", err)
+		}
+		if oldEntry.Removed {
+			return nil, nil, xerrors.New("cannot update a removed entry")
+		}
+		if oldEntry.IID.Equal(NewInstanceID(iID)) {
+			return nil, nil, xerrors.New("the name already points to this instance")
+		}
+
+		// Construct the value, the position in the linked list stays
+		// the same.
+		oldEntry.IID = NewInstanceID(iID)
+		var entryBuf []byte
+		entryBuf, err = protobuf.Encode(&oldEntry)
+		if err != nil {
+			return nil, nil, xerrors.Errorf("encoding: %v", err)
+		}
+
 		sc := StateChanges{
 			NewStateChange(Update, key, "", entryBuf, nil),
 		}
@@ -297,3 +340,13 @@ func (c *contractNaming) Invoke(rst ReadOnlyStateTrie, inst Instruction, coins [
 		return nil, nil, xerrors.New("invalid invoke command: " + inst.Invoke.Command)
 	}
 }
+
+// namingKey returns the key of the naming entry for the name given to an
+// instance guarded by the darc.
+func namingKey(darcID darc.ID, name string) InstanceID {
+	h := sha256.New()
+	h.Write(darcID)
+	h.Write([]byte{'/'})
+	h.Write([]byte(name))
+	return NewInstanceID(h.Sum(nil))
+}
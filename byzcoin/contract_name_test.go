@@ -21,7 +21,7 @@ func TestService_Naming(t *testing.T) {
 	hosts, roster, _ := local.GenTree(4, true)
 	s := local.GetServices(hosts, ByzCoinID)[0].(*Service)
 
-	genesisMsg, err := DefaultGenesisMsg(CurrentVersion, roster, []string{"_name:" + ContractDarcID}, signer.Identity())
+	genesisMsg, err := DefaultGenesisMsg(CurrentVersion, roster, []string{"_name:" + ContractDarcID, "_name:" + ContractConfigID}, signer.Identity())
 	require.Nil(t, err)
 	gDarc := &genesisMsg.GenesisDarc
 	genesisMsg.BlockInterval = time.Second
@@ -376,6 +376,60 @@ func TestService_Naming(t *testing.T) {
 	verifyNameResolution("your genesis darc")
 	verifyNameResolution("everyone's genesis darc")
 
+	// Tests below are for updates.
+	updateTx := func(iID InstanceID, name string, counter uint64) error {
+		tx, err := cl.CreateTransaction(Instruction{
+			InstanceID: NamingInstanceID,
+			Invoke: &Invoke{
+				ContractID: ContractNamingID,
+				Command:    "update",
+				Args: Arguments{
+					{
+						Name:  "instanceID",
+						Value: iID.Slice(),
+					},
+					{
+						Name:  "name",
+						Value: []byte(name),
+					},
+				},
+			},
+			SignerCounter: []uint64{counter},
+		})
+		require.NoError(t, err)
+		require.NoError(t, tx.FillSignersAndSignWith(signer))
+		_, err = cl.AddTransactionAndWait(tx, 10)
+		return err
+	}
+
+	// FAIL - a removed entry cannot be updated.
+	err = updateTx(ConfigInstanceID, "my genesis darc", 6)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "cannot update a removed entry")
+
+	// FAIL - the name must point to another instance.
+	err = updateTx(NewInstanceID(gDarc.GetBaseID()), "your genesis darc", 6)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "already points to this instance")
+
+	// SUCCEED - point the name to the config instance.
+	require.NoError(t, updateTx(ConfigInstanceID, "your genesis darc", 6))
+	iID, err := cl.ResolveInstanceID(gDarc.GetBaseID(), "your genesis darc")
+	require.NoError(t, err)
+	require.True(t, iID.Equal(ConfigInstanceID))
+	verifyNameResolution("everyone's genesis darc")
+
+	// Resolve paths of names.
+	r := NewResolver(cl, gDarc.GetBaseID())
+	iID, err = r.Resolve("everyone's genesis darc:your genesis darc")
+	require.NoError(t, err)
+	require.True(t, iID.Equal(ConfigInstanceID))
+	_, err = r.Resolve("your genesis darc:everyone's genesis darc")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "does not point to a darc")
+	_, err = r.Resolve("everyone's genesis darc:my genesis darc")
+	require.Error(t, err)
+
 	// Try to get the proof of the naming instance.
 	pResp, err := cl.GetProof(NamingInstanceID.Slice())
 	require.NoError(t, err)
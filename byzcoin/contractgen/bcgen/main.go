@@ -0,0 +1,50 @@
+// bcgen reads the schema of a byzcoin contract and writes a typed Go client
+// and the protobuf definitions of its arguments. It is meant to be used with
+// go:generate, for example:
+//
+//	//go:generate bcgen -schema value.toml -go value_client.go -proto value.proto
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+
+	"go.dedis.ch/cothority/v3/byzcoin/contractgen"
+	"go.dedis.ch/onet/v3/log"
+)
+
+var (
+	argSchema = flag.String("schema", "", "path to the TOML schema of the contract")
+	argGo     = flag.String("go", "", "output file for the Go client")
+	argProto  = flag.String("proto", "", "output file for the protobuf definitions (optional)")
+)
+
+func main() {
+	flag.Parse()
+
+	if *argSchema == "" || *argGo == "" {
+		log.Fatal("Both -schema and -go are required.")
+	}
+	s, err := contractgen.LoadSchema(*argSchema)
+	if err != nil {
+		log.Fatal("cannot load schema: ", err)
+	}
+
+	src, err := s.GenerateGo()
+	if err != nil {
+		log.Fatal("cannot generate Go client: ", err)
+	}
+	if err := ioutil.WriteFile(*argGo, src, 0644); err != nil {
+		log.Fatal("cannot write Go client: ", err)
+	}
+
+	if *argProto != "" {
+		proto, err := s.GenerateProto()
+		if err != nil {
+			log.Fatal("cannot generate protobuf: ", err)
+		}
+		if err := ioutil.WriteFile(*argProto, proto, 0644); err != nil {
+			log.Fatal("cannot write protobuf: ", err)
+		}
+	}
+}
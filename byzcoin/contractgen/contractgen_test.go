@@ -0,0 +1,142 @@
+package contractgen
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchema_GenerateGo(t *testing.T) {
+	s, err := LoadSchema("testdata/coin.toml")
+	require.NoError(t, err)
+	require.Equal(t, 2, len(s.Invoke))
+
+	src, err := s.GenerateGo()
+	require.NoError(t, err)
+
+	f, err := parser.ParseFile(token.NewFileSet(), "coin_client.go", src, 0)
+	require.NoError(t, err)
+	require.Equal(t, "coinclient", f.Name.Name)
+
+	funcs := make(map[string]bool)
+	types := make(map[string]bool)
+	for _, d := range f.Decls {
+		switch decl := d.(type) {
+		case *ast.FuncDecl:
+			funcs[decl.Name.Name] = true
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				if ts, ok := spec.(*ast.TypeSpec); ok {
+					types[ts.Name.Name] = true
+				}
+			}
+		}
+	}
+	for _, fn := range []string{"NewCoinClient", "Spawn", "InvokeMint",
+		"InvokeTransfer", "Get"} {
+		require.True(t, funcs[fn], fn)
+	}
+	require.False(t, funcs["Delete"])
+	for _, ty := range []string{"CoinClient", "CoinSpawnArgs", "CoinMintArgs",
+		"CoinTransferArgs"} {
+		require.True(t, types[ty], ty)
+	}
+	require.Contains(t, string(src), `Name: "destination", Value: a.Destination.Slice()`)
+	require.Contains(t, string(src), `"go.dedis.ch/protobuf"`)
+}
+
+// The generated code must also compile against the byzcoin API, so it is
+// type-checked in a temporary package of this module.
+func TestSchema_GenerateGoCompiles(t *testing.T) {
+	if testing.Short() {
+		t.Skip("needs the go command")
+	}
+	s, err := LoadSchema("testdata/coin.toml")
+	require.NoError(t, err)
+	src, err := s.GenerateGo()
+	require.NoError(t, err)
+
+	dir, err := ioutil.TempDir(".", "coinclient")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "coin_client.go"), src, 0644))
+
+	cmd := exec.Command("go", "vet", ".")
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+}
+
+func TestSchema_GenerateProto(t *testing.T) {
+	s, err := LoadSchema("testdata/coin.toml")
+	require.NoError(t, err)
+
+	proto, err := s.GenerateProto()
+	require.NoError(t, err)
+	require.True(t, strings.Contains(string(proto), "package coinclient;"))
+	require.True(t, strings.Contains(string(proto), "message CoinTransferArgs {"))
+	require.True(t, strings.Contains(string(proto), "optional fixed64 coins = 1;"))
+	require.True(t, strings.Contains(string(proto), "optional bytes destination = 2;"))
+}
+
+func TestSchema_Verify(t *testing.T) {
+	s := &Schema{ContractID: "value", Package: "value"}
+	require.NoError(t, s.Verify())
+
+	s.Package = "1value"
+	require.Error(t, s.Verify())
+	s.Package = "value"
+
+	s.Invoke = []Method{{Name: "update"}, {Name: "update"}}
+	require.Error(t, s.Verify())
+
+	s.Invoke = []Method{{Name: "update", Args: []Field{{Name: "value", Type: "float"}}}}
+	require.Error(t, s.Verify())
+
+	s.Invoke[0].Args[0].Type = TypeProto
+	require.Error(t, s.Verify())
+
+	s.Invoke[0].Args[0].GoType = "byzcoin.Coin"
+	s.Invoke[0].Args[0].ProtoType = "byzcoin.Coin"
+	require.NoError(t, s.Verify())
+
+	s.Invoke[0].Args = append(s.Invoke[0].Args, Field{Name: "1value", Type: TypeBytes})
+	require.Error(t, s.Verify())
+	s.Invoke[0].Args[1].Name = "value!"
+	require.Error(t, s.Verify())
+	s.Invoke[0].Args[1].Name = "Value"
+	require.Error(t, s.Verify())
+	s.Invoke[0].Args[1].Name = "max_value"
+	require.NoError(t, s.Verify())
+	s.Invoke[0].Args = append(s.Invoke[0].Args, Field{Name: "maxValue", Type: TypeBytes})
+	require.Error(t, s.Verify())
+	s.Invoke[0].Args = s.Invoke[0].Args[:2]
+
+	s.Invoke = append(s.Invoke, Method{Name: "Update"})
+	require.Error(t, s.Verify())
+	s.Invoke[1].Name = "spawn"
+	require.NoError(t, s.Verify())
+	s.Spawn = &Method{}
+	require.Error(t, s.Verify())
+	s.Invoke = s.Invoke[:1]
+	require.NoError(t, s.Verify())
+
+	s.ContractID = "my-contract"
+	require.Error(t, s.Verify())
+	s.Name = "MyContract"
+	require.NoError(t, s.Verify())
+}
+
+func TestGoName(t *testing.T) {
+	require.Equal(t, "MaxSupply", goName("max_supply"))
+	require.Equal(t, "CoinID", goName("coinID"))
+	require.Equal(t, "TransferBatch", goName("transferBatch"))
+}
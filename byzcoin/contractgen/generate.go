@@ -0,0 +1,386 @@
+package contractgen
+
+import (
+	"bytes"
+	"go/format"
+	"sort"
+	"strings"
+	"text/template"
+
+	"golang.org/x/xerrors"
+)
+
+// GenerateGo returns the formatted Go source of the client bindings for the
+// contract described by the schema.
+func (s *Schema) GenerateGo() ([]byte, error) {
+	if err := s.Verify(); err != nil {
+		return nil, xerrors.Errorf("invalid schema: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := goTemplate.Execute(&buf, s.templateData()); err != nil {
+		return nil, xerrors.Errorf("executing template: %v", err)
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, xerrors.Errorf("formatting generated code: %v", err)
+	}
+	return src, nil
+}
+
+// GenerateProto returns the protobuf definitions of the argument messages
+// of the contract described by the schema.
+func (s *Schema) GenerateProto() ([]byte, error) {
+	if err := s.Verify(); err != nil {
+		return nil, xerrors.Errorf("invalid schema: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := protoTemplate.Execute(&buf, s.templateData()); err != nil {
+		return nil, xerrors.Errorf("executing template: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+type templateField struct {
+	Field
+	GoName    string
+	GoType    string
+	ProtoName string
+	ProtoType string
+	Encode    string
+	Index     int
+}
+
+type templateMethod struct {
+	Name     string
+	GoName   string
+	TypeName string
+	Args     []templateField
+}
+
+type templateData struct {
+	*Schema
+	Prefix     string
+	Helper     string
+	ProtoPkg   string
+	StdImports []string
+	Imports    []string
+	SpawnArgs  *templateMethod
+	Commands   []templateMethod
+	ValueField *templateField
+}
+
+func (s *Schema) templateData() templateData {
+	prefix := goName(s.name())
+	td := templateData{
+		Schema:   s,
+		Prefix:   prefix,
+		Helper:   strings.ToLower(prefix[:1]) + prefix[1:],
+		ProtoPkg: s.protoPackage(),
+	}
+	needsProto := false
+	convert := func(fs []Field) []templateField {
+		out := make([]templateField, len(fs))
+		for i, f := range fs {
+			out[i] = td.field(f, i+1)
+			needsProto = needsProto || f.Type == TypeProto
+		}
+		return out
+	}
+	if s.Spawn != nil {
+		td.SpawnArgs = &templateMethod{
+			Name:     "spawn",
+			TypeName: prefix + "SpawnArgs",
+			Args:     convert(s.Spawn.Args),
+		}
+	}
+	for _, m := range s.Invoke {
+		td.Commands = append(td.Commands, templateMethod{
+			Name:     m.Name,
+			GoName:   goName(m.Name),
+			TypeName: prefix + goName(m.Name) + "Args",
+			Args:     convert(m.Args),
+		})
+	}
+	if s.Value != nil {
+		v := td.field(*s.Value, 1)
+		td.ValueField = &v
+		needsProto = needsProto || s.Value.Type == TypeProto
+	}
+
+	td.StdImports = []string{"encoding/binary"}
+	imports := map[string]bool{
+		"go.dedis.ch/cothority/v3/byzcoin": true,
+		"go.dedis.ch/cothority/v3/darc":    true,
+		"golang.org/x/xerrors":             true,
+	}
+	if needsProto {
+		imports["go.dedis.ch/protobuf"] = true
+	}
+	for _, i := range s.Imports {
+		imports[i] = true
+	}
+	for i := range imports {
+		td.Imports = append(td.Imports, i)
+	}
+	sort.Strings(td.Imports)
+	return td
+}
+
+func (td templateData) field(f Field, index int) templateField {
+	tf := templateField{
+		Field:     f,
+		GoName:    goName(f.Name),
+		GoType:    f.goType(),
+		ProtoName: f.protoName(),
+		ProtoType: f.protoType(),
+		Index:     index,
+	}
+	v := "a." + tf.GoName
+	switch f.Type {
+	case TypeBytes:
+		tf.Encode = v
+	case TypeString, TypeDarcID:
+		tf.Encode = "[]byte(" + v + ")"
+	case TypeUint64:
+		tf.Encode = td.Helper + "Uint64(" + v + ")"
+	case TypeInt64:
+		tf.Encode = td.Helper + "Uint64(uint64(" + v + "))"
+	case TypeBool:
+		tf.Encode = td.Helper + "Bool(" + v + ")"
+	case TypeInstanceID:
+		tf.Encode = v + ".Slice()"
+	case TypeProto:
+		tf.Encode = "protobuf.Encode(&" + v + ")"
+	}
+	return tf
+}
+
+var goTemplate = template.Must(template.New("go").Parse(`// Code generated by bcgen from the schema of the "{{.ContractID}}" contract. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+{{- range .StdImports}}
+	"{{.}}"
+{{- end}}
+{{range .Imports}}
+	"{{.}}"
+{{- end}}
+)
+
+// {{.Prefix}}ContractID is the ID of the contract wrapped by {{.Prefix}}Client.
+const {{.Prefix}}ContractID = "{{.ContractID}}"
+
+// {{.Prefix}}Client builds, signs and sends the instructions of the
+// "{{.ContractID}}" contract.
+type {{.Prefix}}Client struct {
+	*byzcoin.Client
+	// Signers sign all the instructions sent by this client. Their
+	// counters are fetched from the ledger before every instruction.
+	Signers []darc.Signer
+}
+
+// New{{.Prefix}}Client returns a client using the given byzcoin client and
+// signers.
+func New{{.Prefix}}Client(cl *byzcoin.Client, signers ...darc.Signer) *{{.Prefix}}Client {
+	return &{{.Prefix}}Client{Client: cl, Signers: signers}
+}
+{{- define "args"}}
+// {{.TypeName}} are the arguments of the {{.Name}} method.
+type {{.TypeName}} struct {
+{{- range .Args}}
+	{{.GoName}} {{.GoType}}
+{{- end}}
+}
+
+// Arguments encodes the fields as byzcoin.Arguments.
+func (a {{.TypeName}}) Arguments() (byzcoin.Arguments, error) {
+	var args byzcoin.Arguments
+{{- range .Args}}
+{{- if eq .Type "proto"}}
+	{
+		buf, err := {{.Encode}}
+		if err != nil {
+			return nil, xerrors.Errorf("encoding {{.Name}}: %v", err)
+		}
+		args = append(args, byzcoin.Argument{Name: "{{.Name}}", Value: buf})
+	}
+{{- else}}
+	args = append(args, byzcoin.Argument{Name: "{{.Name}}", Value: {{.Encode}}})
+{{- end}}
+{{- end}}
+	return args, nil
+}
+{{- end}}
+{{- with .SpawnArgs}}
+{{template "args" .}}
+{{- end}}
+{{- range .Commands}}
+{{template "args" .}}
+{{- end}}
+{{- if .SpawnArgs}}
+
+// Spawn creates a new instance using the spawn rule of the given darc and
+// waits up to wait blocks for its inclusion. The returned instance ID is
+// only correct if the contract derives it using DeriveID("").
+func (c *{{.Prefix}}Client) Spawn(darcID darc.ID, args {{.SpawnArgs.TypeName}}, wait int) (byzcoin.InstanceID, error) {
+	a, err := args.Arguments()
+	if err != nil {
+		return byzcoin.InstanceID{}, err
+	}
+	instr := byzcoin.Instruction{
+		InstanceID: byzcoin.NewInstanceID(darcID),
+		Spawn: &byzcoin.Spawn{
+			ContractID: {{.Prefix}}ContractID,
+			Args:       a,
+		},
+	}
+	tx, err := c.send(instr, wait)
+	if err != nil {
+		return byzcoin.InstanceID{}, err
+	}
+	return tx.Instructions[0].DeriveID(""), nil
+}
+{{- end}}
+{{- $prefix := .Prefix}}
+{{- range .Commands}}
+
+// Invoke{{.GoName}} calls the "{{.Name}}" command on the given instance and
+// waits up to wait blocks for its inclusion.
+func (c *{{$prefix}}Client) Invoke{{.GoName}}(id byzcoin.InstanceID, args {{.TypeName}}, wait int) error {
+	a, err := args.Arguments()
+	if err != nil {
+		return err
+	}
+	_, err = c.send(byzcoin.Instruction{
+		InstanceID: id,
+		Invoke: &byzcoin.Invoke{
+			ContractID: {{$prefix}}ContractID,
+			Command:    "{{.Name}}",
+			Args:       a,
+		},
+	}, wait)
+	return err
+}
+{{- end}}
+{{- if .Delete}}
+
+// Delete removes the given instance and waits up to wait blocks for its
+// inclusion.
+func (c *{{.Prefix}}Client) Delete(id byzcoin.InstanceID, wait int) error {
+	_, err := c.send(byzcoin.Instruction{
+		InstanceID: id,
+		Delete: &byzcoin.Delete{
+			ContractID: {{.Prefix}}ContractID,
+		},
+	}, wait)
+	return err
+}
+{{- end}}
+{{- with .ValueField}}
+
+// Get fetches and verifies a proof of the given instance and returns its
+// decoded value.
+func (c *{{$prefix}}Client) Get(id byzcoin.InstanceID) (v {{.GoType}}, err error) {
+	pr, err := c.GetProofFromLatest(id.Slice())
+	if err != nil {
+		return v, xerrors.Errorf("getting proof: %v", err)
+	}
+	buf, cid, _, err := pr.Proof.Get(id.Slice())
+	if err != nil {
+		return v, xerrors.Errorf("reading proof: %v", err)
+	}
+	if cid != {{$prefix}}ContractID {
+		return v, xerrors.Errorf("instance is of contract %s", cid)
+	}
+{{- if eq .Type "bytes"}}
+	v = buf
+{{- else if eq .Type "string"}}
+	v = string(buf)
+{{- else if eq .Type "uint64"}}
+	if len(buf) != 8 {
+		return v, xerrors.New("value has wrong length")
+	}
+	v = binary.LittleEndian.Uint64(buf)
+{{- else if eq .Type "int64"}}
+	if len(buf) != 8 {
+		return v, xerrors.New("value has wrong length")
+	}
+	v = int64(binary.LittleEndian.Uint64(buf))
+{{- else if eq .Type "bool"}}
+	v = len(buf) > 0 && buf[0] != 0
+{{- else if eq .Type "instanceid"}}
+	v = byzcoin.NewInstanceID(buf)
+{{- else if eq .Type "darcid"}}
+	v = darc.ID(buf)
+{{- else}}
+	if err = protobuf.Decode(buf, &v); err != nil {
+		return v, xerrors.Errorf("decoding value: %v", err)
+	}
+{{- end}}
+	return v, nil
+}
+{{- end}}
+
+func (c *{{.Prefix}}Client) send(instr byzcoin.Instruction, wait int) (byzcoin.ClientTransaction, error) {
+	ids := make([]string, len(c.Signers))
+	for i, s := range c.Signers {
+		ids[i] = s.Identity().String()
+	}
+	counters, err := c.GetSignerCounters(ids...)
+	if err != nil {
+		return byzcoin.ClientTransaction{}, xerrors.Errorf("getting counters: %v", err)
+	}
+	for _, ctr := range counters.Counters {
+		instr.SignerCounter = append(instr.SignerCounter, ctr+1)
+	}
+	tx, err := c.CreateTransaction(instr)
+	if err != nil {
+		return tx, xerrors.Errorf("creating transaction: %v", err)
+	}
+	if err := tx.FillSignersAndSignWith(c.Signers...); err != nil {
+		return tx, xerrors.Errorf("signing: %v", err)
+	}
+	if _, err := c.AddTransactionAndWait(tx, wait); err != nil {
+		return tx, xerrors.Errorf("sending transaction: %v", err)
+	}
+	return tx, nil
+}
+
+func {{.Helper}}Uint64(v uint64) []byte {
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buf, v)
+	return buf
+}
+
+func {{.Helper}}Bool(v bool) []byte {
+	if v {
+		return []byte{1}
+	}
+	return []byte{0}
+}
+`))
+
+var protoTemplate = template.Must(template.New("proto").Parse(`// Generated by bcgen from the schema of the "{{.ContractID}}" contract. DO NOT EDIT.
+// Every message lists the arguments of one method. Each field is sent as a
+// separate byzcoin.Argument with the field name as argument name. The value
+// of the argument is the encoding of the field alone, without tag: integers
+// are 8 bytes little endian and booleans a single byte 0 or 1.
+syntax = "proto2";
+package {{.ProtoPkg}};
+{{- define "message"}}
+
+// {{.TypeName}} are the arguments of the {{.Name}} method.
+message {{.TypeName}} {
+{{- range .Args}}
+  optional {{.ProtoType}} {{.ProtoName}} = {{.Index}};
+{{- end}}
+}
+{{- end}}
+{{- with .SpawnArgs}}
+{{- template "message" .}}
+{{- end}}
+{{- range .Commands}}
+{{- template "message" .}}
+{{- end}}
+`))
@@ -0,0 +1,251 @@
+// Package contractgen creates typed client bindings for byzcoin contracts.
+//
+// A contract author describes the arguments of the spawn, invoke and delete
+// methods of a contract in a schema, usually stored as a TOML file next to
+// the contract. From this schema, contractgen emits a Go client wrapper that
+// encodes the arguments correctly, and protobuf definitions of the argument
+// messages for clients in other languages. This avoids that every user of a
+// contract hand-encodes the byte slices of the byzcoin.Arguments.
+//
+// The bcgen command in this directory wraps the generator for use with
+// go:generate.
+package contractgen
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"golang.org/x/xerrors"
+)
+
+// The following types are supported for the arguments of a method. All the
+// integer types are encoded as 64-bit little endian values, which is the
+// encoding used by the coin contract. In the protobuf definitions, they are
+// declared as fixed64 and sfixed64, which use the same encoding.
+const (
+	TypeBytes      = "bytes"
+	TypeString     = "string"
+	TypeUint64     = "uint64"
+	TypeInt64      = "int64"
+	TypeBool       = "bool"
+	TypeInstanceID = "instanceid"
+	TypeDarcID     = "darcid"
+	// TypeProto is a protobuf-encoded structure. The GoType and ProtoType
+	// fields of the Field must be set.
+	TypeProto = "proto"
+)
+
+var identifier = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]*$`)
+
+// argumentName also allows the separators understood by goName.
+var argumentName = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_.:-]*$`)
+
+// Schema describes the methods of one contract.
+type Schema struct {
+	// ContractID is the ID under which the contract is registered.
+	ContractID string
+	// Name is used as a prefix for all generated types. If it is empty,
+	// the ContractID is used.
+	Name string `toml:",omitempty"`
+	// Package is the name of the Go package of the generated file.
+	Package string
+	// Imports are additional Go import paths needed by GoTypes of
+	// proto-fields.
+	Imports []string `toml:",omitempty"`
+	// ProtoPackage is the package of the generated protobuf file. If it
+	// is empty, Package is used.
+	ProtoPackage string `toml:",omitempty"`
+	// Spawn describes the arguments of the spawn method, or nil if the
+	// contract cannot be spawned.
+	Spawn *Method `toml:",omitempty"`
+	// Invoke lists all the commands of the contract.
+	Invoke []Method `toml:",omitempty"`
+	// Delete is true if instances of the contract can be deleted.
+	Delete bool `toml:",omitempty"`
+	// Value describes how the data of an instance is stored. If it is nil,
+	// no getter is generated.
+	Value *Field `toml:",omitempty"`
+}
+
+// Method is a spawn or an invoke with its arguments. For the spawn method,
+// the Name is ignored.
+type Method struct {
+	Name string
+	Args []Field `toml:",omitempty"`
+}
+
+// Field is one argument of a method.
+type Field struct {
+	// Name of the argument as searched for by the contract.
+	Name string
+	// Type is one of the Type* constants.
+	Type string
+	// GoType is the Go type for a TypeProto field, e.g. "byzcoin.Coin".
+	GoType string `toml:",omitempty"`
+	// ProtoType is the protobuf message for a TypeProto field, e.g.
+	// "byzcoin.Coin".
+	ProtoType string `toml:",omitempty"`
+}
+
+// LoadSchema reads a schema from a TOML file and verifies it.
+func LoadSchema(path string) (*Schema, error) {
+	s := &Schema{}
+	if _, err := toml.DecodeFile(path, s); err != nil {
+		return nil, xerrors.Errorf("decoding schema: %v", err)
+	}
+	if err := s.Verify(); err != nil {
+		return nil, xerrors.Errorf("invalid schema: %v", err)
+	}
+	return s, nil
+}
+
+// Verify makes sure the schema can be turned into Go code.
+func (s *Schema) Verify() error {
+	if s.ContractID == "" {
+		return xerrors.New("missing contract ID")
+	}
+	if !identifier.MatchString(s.Package) {
+		return xerrors.Errorf("invalid package name '%s'", s.Package)
+	}
+	if !identifier.MatchString(s.name()) {
+		return xerrors.Errorf("invalid name '%s', please set Name", s.name())
+	}
+	if s.Spawn != nil {
+		if err := s.Spawn.verify(); err != nil {
+			return xerrors.Errorf("spawn: %v", err)
+		}
+	}
+	// The generated types and methods use the Go name of the command, so
+	// "mint" and "Mint" would collide, as would "spawn" with the arguments
+	// of the spawn method.
+	commands := make(map[string]string)
+	if s.Spawn != nil {
+		commands["Spawn"] = "spawn"
+	}
+	for _, m := range s.Invoke {
+		if !identifier.MatchString(m.Name) {
+			return xerrors.Errorf("invalid command name '%s'", m.Name)
+		}
+		if other, ok := commands[goName(m.Name)]; ok {
+			return xerrors.Errorf("command '%s' collides with '%s'", m.Name, other)
+		}
+		commands[goName(m.Name)] = m.Name
+		if err := m.verify(); err != nil {
+			return xerrors.Errorf("command %s: %v", m.Name, err)
+		}
+	}
+	if s.Value != nil {
+		if err := s.Value.verify(); err != nil {
+			return xerrors.Errorf("value: %v", err)
+		}
+	}
+	return nil
+}
+
+func (s *Schema) name() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return s.ContractID
+}
+
+func (s *Schema) protoPackage() string {
+	if s.ProtoPackage != "" {
+		return s.ProtoPackage
+	}
+	return s.Package
+}
+
+func (m Method) verify() error {
+	// The protobuf name is the lower-case Go name, so it covers collisions
+	// of both, like "max_supply" and "maxSupply".
+	names := make(map[string]string)
+	for _, f := range m.Args {
+		if err := f.verify(); err != nil {
+			return xerrors.Errorf("argument %s: %v", f.Name, err)
+		}
+		if other, ok := names[f.protoName()]; ok {
+			return xerrors.Errorf("argument '%s' collides with '%s'", f.Name, other)
+		}
+		names[f.protoName()] = f.Name
+	}
+	return nil
+}
+
+func (f Field) verify() error {
+	if f.Name == "" {
+		return xerrors.New("missing name")
+	}
+	if !argumentName.MatchString(f.Name) || !identifier.MatchString(goName(f.Name)) {
+		return xerrors.Errorf("invalid name '%s'", f.Name)
+	}
+	switch f.Type {
+	case TypeBytes, TypeString, TypeUint64, TypeInt64, TypeBool,
+		TypeInstanceID, TypeDarcID:
+	case TypeProto:
+		if f.GoType == "" || f.ProtoType == "" {
+			return xerrors.New("proto type needs GoType and ProtoType")
+		}
+	default:
+		return xerrors.Errorf("unknown type '%s'", f.Type)
+	}
+	return nil
+}
+
+// goName converts a name like "max_supply" or "coinID" to "MaxSupply" and
+// "CoinID".
+func goName(name string) string {
+	var out strings.Builder
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool {
+		return r == '_' || r == '-' || r == '.' || r == ':'
+	}) {
+		out.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return out.String()
+}
+
+func (f Field) protoName() string {
+	return strings.ToLower(goName(f.Name))
+}
+
+func (f Field) goType() string {
+	switch f.Type {
+	case TypeBytes:
+		return "[]byte"
+	case TypeString:
+		return "string"
+	case TypeUint64:
+		return "uint64"
+	case TypeInt64:
+		return "int64"
+	case TypeBool:
+		return "bool"
+	case TypeInstanceID:
+		return "byzcoin.InstanceID"
+	case TypeDarcID:
+		return "darc.ID"
+	case TypeProto:
+		return f.GoType
+	}
+	panic(fmt.Sprintf("unknown type %s", f.Type))
+}
+
+func (f Field) protoType() string {
+	switch f.Type {
+	case TypeBytes, TypeInstanceID, TypeDarcID:
+		return "bytes"
+	case TypeString:
+		return "string"
+	case TypeUint64:
+		return "fixed64"
+	case TypeInt64:
+		return "sfixed64"
+	case TypeBool:
+		return "bool"
+	case TypeProto:
+		return f.ProtoType
+	}
+	panic(fmt.Sprintf("unknown type %s", f.Type))
+}
@@ -1,7 +1,9 @@
 package byzcoin
 
 import (
+	"bytes"
 	"encoding/binary"
+	"encoding/hex"
 	"fmt"
 	"net/url"
 	"strconv"
@@ -211,9 +213,10 @@ func (b BasicContract) VerifyDeferredInstruction(rst ReadOnlyStateTrie, inst Ins
 	return notImpl("VerifyDeferredInstruction")
 }
 
-// MakeAttrInterpreters provides one default attribute verification which check
-// whether the transaction is sent after a certain block index and before
-// another block index.
+// MakeAttrInterpreters provides two default attribute verifications. The
+// "block" attribute checks whether the transaction is sent after a certain
+// block index and before another block index. The "arg" attribute constrains
+// the arguments of the instruction, see argAttrInterpreter.
 func (b BasicContract) MakeAttrInterpreters(rst ReadOnlyStateTrie, inst Instruction) darc.AttrInterpreters {
 	cb := func(attr string) error {
 		vals, err := url.ParseQuery(attr)
@@ -252,7 +255,83 @@ func (b BasicContract) MakeAttrInterpreters(rst ReadOnlyStateTrie, inst Instruct
 		}
 		return xerrors.Errorf("the current block index is %d which does not fit in the interval (%d, %d)", rst.GetIndex(), after, before)
 	}
-	return darc.AttrInterpreters{"block": cb}
+	return darc.AttrInterpreters{"block": cb, "arg": argAttrInterpreter(inst)}
+}
+
+// argAttrInterpreter checks the arguments of the instruction against
+// predicates like "attr:arg:coins.uint64<=1000" or
+// "attr:arg:destination.hex==abcd", so that a rule can limit the amount of a
+// transfer or whitelist its destinations. The suffix of the name of the
+// argument tells how its value is read: "uint64" for a little-endian
+// integer, "hex" for its hexadecimal encoding and "string", the default, for
+// the value itself. Only the integers can be ordered, the other formats
+// must be equal, or not, byte for byte. A missing argument fails the
+// predicate.
+func argAttrInterpreter(inst Instruction) func(string) error {
+	return func(attr string) error {
+		end := strings.IndexAny(attr, "<>=!")
+		if end <= 0 {
+			return xerrors.New("expected the name of an argument and a predicate")
+		}
+		name, format := attr[:end], "string"
+		if i := strings.LastIndex(name, "."); i >= 0 {
+			name, format = name[:i], name[i+1:]
+		}
+		op, value, err := darc.ParseAttrPredicate(attr[end:])
+		if err != nil {
+			return xerrors.Errorf("parsing predicate: %v", err)
+		}
+
+		var args Arguments
+		switch {
+		case inst.Spawn != nil:
+			args = inst.Spawn.Args
+		case inst.Invoke != nil:
+			args = inst.Invoke.Args
+		}
+		buf := args.Search(name)
+		if buf == nil {
+			return xerrors.Errorf("argument %s is missing", name)
+		}
+
+		var expected []byte
+		switch format {
+		case "uint64":
+			if len(buf) != 8 {
+				return xerrors.Errorf("argument %s is not a uint64", name)
+			}
+			if _, err := strconv.ParseUint(value, 10, 64); err != nil {
+				return xerrors.Errorf("%s is not a uint64: %v", value, err)
+			}
+			actual := strconv.FormatUint(binary.LittleEndian.Uint64(buf), 10)
+			return cothority.ErrorOrNil(darc.CompareAttr(actual, op, value),
+				"argument "+name)
+		case "hex":
+			expected, err = hex.DecodeString(value)
+			if err != nil {
+				return xerrors.Errorf("decoding %s: %v", value, err)
+			}
+		case "string":
+			expected = []byte(value)
+		default:
+			return xerrors.Errorf("unknown format %s", format)
+		}
+
+		// The other formats are compared byte by byte, so that e.g. "1.0"
+		// is not equal to "1".
+		equal := bytes.Equal(buf, expected)
+		switch op {
+		case "==":
+		case "!=":
+			equal = !equal
+		default:
+			return xerrors.Errorf("cannot compare argument %s with %s", name, op)
+		}
+		if !equal {
+			return xerrors.Errorf("argument %s is not %s %s", name, op, value)
+		}
+		return nil
+	}
 }
 
 // Spawn is not implmented in a BasicContract. Types which embed BasicContract
@@ -380,6 +459,9 @@ func (c *contractConfig) FormatMethod(instr Instruction) string {
 //   - max_block_size int64
 //   - roster         onet.Roster
 //   - darc_contracts darcContractID
+//
+// The genesis transaction also holds the skiplist_heights argument, which is
+// only used by the service to create the genesis block.
 func (c *contractConfig) Spawn(rst ReadOnlyStateTrie, inst Instruction, coins []Coin) ([]StateChange, []Coin, error) {
 	darcBuf := inst.Spawn.Args.Search("darc")
 	d, err := darc.NewFromProtobuf(darcBuf)
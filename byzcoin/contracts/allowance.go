@@ -0,0 +1,156 @@
+package contracts
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/protobuf"
+	"golang.org/x/xerrors"
+)
+
+// ContractCoinAllowanceID denotes a contract that can spend coins of a coin
+// instance on behalf of its owner.
+const ContractCoinAllowanceID = "coinAllowance"
+
+// ContractCoinAllowance holds the number of coins a spender can still take
+// from a coin instance. It is created by the "approve" command of the coin
+// contract, and is governed by the darc of the spender, so that the spender
+// doesn't need any right on the darc of the coin instance.
+//
+// The following method is available:
+//  - transferFrom sends the coins given in the argument "coins" from the
+//    coin instance of the allowance to the coin instance given in the
+//    argument "destination". The "coins"-argument must be a 64-bit uint in
+//    LittleEndian. The allowance is removed once all coins are spent.
+type ContractCoinAllowance struct {
+	byzcoin.BasicContract
+	CoinAllowance
+}
+
+// CoinAllowance is the data stored in a coinAllowance instance.
+type CoinAllowance struct {
+	// Account is the coin instance the coins are taken from.
+	Account byzcoin.InstanceID
+	// Allowance is the type and the number of coins that can still be
+	// spent.
+	Allowance byzcoin.Coin
+}
+
+// AllowanceID returns the instance ID of the allowance given by the coin
+// instance account to the spender.
+func AllowanceID(account byzcoin.InstanceID, spender darc.ID) byzcoin.InstanceID {
+	h := sha256.New()
+	h.Write([]byte(ContractCoinAllowanceID))
+	h.Write(account.Slice())
+	h.Write(spender)
+	return byzcoin.NewInstanceID(h.Sum(nil))
+}
+
+func contractCoinAllowanceFromBytes(in []byte) (byzcoin.Contract, error) {
+	c := &ContractCoinAllowance{}
+	err := protobuf.Decode(in, &c.CoinAllowance)
+	if err != nil {
+		return nil, xerrors.Errorf("couldn't unmarshal instance data: %v", err)
+	}
+	return c, nil
+}
+
+// approve returns the state change storing the allowance of the spender.
+func (c *contractCoin) approve(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins uint64) (byzcoin.StateChanges, error) {
+	spender := darc.ID(inst.Invoke.Args.Search("spender"))
+	if len(spender) != len(byzcoin.InstanceID{}) {
+		return nil, xerrors.New("spender needs to be a darc ID")
+	}
+	id := AllowanceID(inst.InstanceID, spender)
+	_, _, cid, _, err := rst.GetValues(id.Slice())
+	exists := err == nil && cid == ContractCoinAllowanceID
+
+	if coins == 0 {
+		if !exists {
+			return nil, nil
+		}
+		return byzcoin.StateChanges{byzcoin.NewStateChange(byzcoin.Remove, id,
+			ContractCoinAllowanceID, nil, spender)}, nil
+	}
+
+	buf, err := protobuf.Encode(&CoinAllowance{
+		Account:   inst.InstanceID,
+		Allowance: byzcoin.Coin{Name: c.Name, Value: coins},
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("couldn't encode allowance: %v", err)
+	}
+	action := byzcoin.Create
+	if exists {
+		action = byzcoin.Update
+	}
+	log.Lvlf2("approving %d coins for %x", coins, []byte(spender))
+	return byzcoin.StateChanges{byzcoin.NewStateChange(action, id,
+		ContractCoinAllowanceID, buf, spender)}, nil
+}
+
+// Invoke implements the byzcoin.Contract interface.
+func (c *ContractCoinAllowance) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+
+	if inst.Invoke.Command != "transferFrom" {
+		return nil, nil, xerrors.New("coinAllowance contract can only transferFrom")
+	}
+
+	coinsBuf := inst.Invoke.Args.Search("coins")
+	if len(coinsBuf) != 8 {
+		return nil, nil, xerrors.New("argument \"coins\" is missing or wrong length")
+	}
+	amount := binary.LittleEndian.Uint64(coinsBuf)
+	if amount > c.Allowance.Value {
+		return nil, nil, xerrors.Errorf("only %d coins allowed", c.Allowance.Value)
+	}
+	dest := byzcoin.NewInstanceID(inst.Invoke.Args.Search("destination"))
+	if dest.Equal(c.Account) {
+		return nil, nil, xerrors.New("cannot send coins to the same instance")
+	}
+
+	account, accountDarc, err := getCoin(rst, c.Account.Slice())
+	if err != nil {
+		return nil, nil, xerrors.Errorf("account: %v", err)
+	}
+	if !account.Name.Equal(c.Allowance.Name) {
+		return nil, nil, xerrors.New("account holds another type of coin")
+	}
+	if err = account.SafeSub(amount); err != nil {
+		return
+	}
+	accountBuf, err := protobuf.Encode(&account)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("couldn't marshal account: %v", err)
+	}
+	sc, err = addCoins(rst, dest, byzcoin.Coin{Name: account.Name, Value: amount})
+	if err != nil {
+		return nil, nil, xerrors.Errorf("destination: %v", err)
+	}
+	sc = append(sc, byzcoin.NewStateChange(byzcoin.Update, c.Account,
+		ContractCoinID, accountBuf, accountDarc))
+
+	c.Allowance.Value -= amount
+	if c.Allowance.Value == 0 {
+		sc = append(sc, byzcoin.NewStateChange(byzcoin.Remove, inst.InstanceID,
+			ContractCoinAllowanceID, nil, darcID))
+		return
+	}
+	buf, err := protobuf.Encode(&c.CoinAllowance)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("couldn't encode allowance: %v", err)
+	}
+	sc = append(sc, byzcoin.NewStateChange(byzcoin.Update, inst.InstanceID,
+		ContractCoinAllowanceID, buf, darcID))
+	return
+}
@@ -0,0 +1,109 @@
+package contracts
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/protobuf"
+)
+
+func TestCoinAllowance(t *testing.T) {
+	ct := newCT("invoke:coin.approve", "invoke:coinAllowance.transferFrom")
+	owner, dest := iid("owner"), iid("dest")
+	store := func(id byzcoin.InstanceID, value uint64) {
+		buf, err := protobuf.Encode(&byzcoin.Coin{Name: CoinName, Value: value})
+		require.NoError(t, err)
+		ct.Store(id, buf, ContractCoinID, gdarc.GetBaseID())
+	}
+	store(owner, 10)
+	store(dest, 0)
+	spender := darc.ID(iid("spender").Slice())
+	coins := func(c uint64) []byte {
+		buf := make([]byte, 8)
+		binary.LittleEndian.PutUint64(buf, c)
+		return buf
+	}
+	apply := func(sc byzcoin.StateChanges) {
+		for _, s := range sc {
+			if s.StateAction == byzcoin.Remove {
+				delete(ct.values, string(s.InstanceID))
+				delete(ct.contractIDs, string(s.InstanceID))
+				continue
+			}
+			ct.Store(byzcoin.NewInstanceID(s.InstanceID), s.Value, s.ContractID, s.DarcID)
+		}
+	}
+
+	approve := byzcoin.Instruction{
+		InstanceID: owner,
+		Invoke: &byzcoin.Invoke{
+			ContractID: ContractCoinID,
+			Command:    "approve",
+			Args: byzcoin.Arguments{
+				{Name: "spender", Value: spender},
+				{Name: "coins", Value: coins(5)},
+			},
+		},
+		SignerIdentities: []darc.Identity{gsigner.Identity()},
+		SignerCounter:    []uint64{1},
+	}
+	sc, _, err := ct.getContract(owner).Invoke(ct, approve, nil)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(sc))
+	allowanceID := AllowanceID(owner, spender)
+	require.Equal(t, allowanceID.Slice(), sc[0].InstanceID)
+	require.Equal(t, byzcoin.Create, sc[0].StateAction)
+	require.Equal(t, spender, sc[0].DarcID)
+	apply(sc)
+
+	transferFrom := func(c uint64) (byzcoin.StateChanges, error) {
+		a, err := contractCoinAllowanceFromBytes(ct.values[string(allowanceID.Slice())])
+		require.NoError(t, err)
+		sc, _, err := a.Invoke(ct, byzcoin.Instruction{
+			InstanceID: allowanceID,
+			Invoke: &byzcoin.Invoke{
+				ContractID: ContractCoinAllowanceID,
+				Command:    "transferFrom",
+				Args: byzcoin.Arguments{
+					{Name: "destination", Value: dest.Slice()},
+					{Name: "coins", Value: coins(c)},
+				},
+			},
+			SignerIdentities: []darc.Identity{gsigner.Identity()},
+			SignerCounter:    []uint64{1},
+		}, nil)
+		if err == nil {
+			apply(sc)
+		}
+		return sc, err
+	}
+	value := func(id byzcoin.InstanceID) uint64 {
+		return ct.getContract(id).(*contractCoin).Value
+	}
+
+	_, err = transferFrom(6)
+	require.Error(t, err)
+	_, err = transferFrom(3)
+	require.NoError(t, err)
+	require.Equal(t, uint64(7), value(owner))
+	require.Equal(t, uint64(3), value(dest))
+
+	// Spending the rest of the allowance removes it.
+	sc, err = transferFrom(2)
+	require.NoError(t, err)
+	require.Equal(t, byzcoin.Remove, sc[len(sc)-1].StateAction)
+	require.Equal(t, uint64(5), value(owner))
+	require.Equal(t, uint64(5), value(dest))
+
+	// Approving 0 coins removes an existing allowance.
+	sc, _, err = ct.getContract(owner).Invoke(ct, approve, nil)
+	require.NoError(t, err)
+	apply(sc)
+	approve.Invoke.Args[1].Value = coins(0)
+	sc, _, err = ct.getContract(owner).Invoke(ct, approve, nil)
+	require.NoError(t, err)
+	require.Equal(t, byzcoin.Remove, sc[0].StateAction)
+}
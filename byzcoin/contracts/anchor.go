@@ -0,0 +1,383 @@
+package contracts
+
+import (
+	"bytes"
+	"sync"
+	"time"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/cothority/v3/skipchain"
+	"go.dedis.ch/kyber/v3/pairing"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/onet/v3/network"
+	"go.dedis.ch/protobuf"
+	"golang.org/x/xerrors"
+)
+
+// ContractAnchorID denotes a contract that anchors the blocks of another
+// skipchain.
+const ContractAnchorID = "anchor"
+
+// ContractAnchor anchors the blocks of another skipchain, the source, into
+// this byzcoin chain. The instance holds the latest anchored block of the
+// source, and anchoring a new block needs the forward-links from the
+// previously anchored block to the new one, which are verified by the
+// contract. Once a block of the source is anchored, forking the source before
+// this block also needs to fork this chain, so the source inherits its
+// finality.
+//
+// Spawn takes the argument "skipchainID" with the ID of the genesis block of
+// the source, which is the first anchored block, and the optional argument
+// "darcID" to define the darc of the anchor, which defines who can anchor
+// blocks.
+//
+// The following method is available:
+//  - anchor takes the argument "path" with the protobuf-encoded AnchorPath
+//    from the latest anchored block to the new block.
+//
+// Clients use AnchorInstruction to anchor blocks, or an Anchorer to do it
+// periodically, and GetAnchorProof to prove that a block is anchored.
+type ContractAnchor struct {
+	byzcoin.BasicContract
+	Anchor
+}
+
+// Anchor is the data stored in an anchor instance.
+type Anchor struct {
+	// SkipChainID is the ID of the source skipchain.
+	SkipChainID skipchain.SkipBlockID
+	// BlockID is the ID of the latest anchored block.
+	BlockID skipchain.SkipBlockID
+	// Index is the index of the latest anchored block.
+	Index uint64
+	// Timestamp is the timestamp of the latest block of this chain when the
+	// block was anchored, in Unix nanoseconds.
+	Timestamp int64
+}
+
+// AnchorPath is a list of blocks of the source skipchain, where each block
+// is reached by a forward-link of the previous one.
+type AnchorPath struct {
+	Blocks []*skipchain.SkipBlock
+}
+
+func contractAnchorFromBytes(in []byte) (byzcoin.Contract, error) {
+	c := &ContractAnchor{}
+	err := protobuf.Decode(in, &c.Anchor)
+	if err != nil {
+		return nil, xerrors.Errorf("couldn't unmarshal instance data: %v", err)
+	}
+	return c, nil
+}
+
+// Spawn implements the byzcoin.Contract interface.
+func (c *ContractAnchor) Spawn(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+	if did := inst.Spawn.Args.Search("darcID"); did != nil {
+		darcID = darc.ID(did)
+	}
+
+	scid := inst.Spawn.Args.Search("skipchainID")
+	if len(scid) != 32 {
+		return nil, nil, xerrors.New("need the 32 bytes ID of the skipchain")
+	}
+	c.Anchor = Anchor{SkipChainID: scid, BlockID: scid}
+	c.Timestamp, err = chainTime(rst)
+	if err != nil {
+		return
+	}
+
+	buf, err := protobuf.Encode(&c.Anchor)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("couldn't encode anchor: %v", err)
+	}
+	sc = []byzcoin.StateChange{
+		byzcoin.NewStateChange(byzcoin.Create, inst.DeriveID(""),
+			ContractAnchorID, buf, darcID),
+	}
+	return
+}
+
+// Invoke implements the byzcoin.Contract interface.
+func (c *ContractAnchor) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+
+	if inst.Invoke.Command != "anchor" {
+		return nil, nil, xerrors.New("anchor contract can only anchor")
+	}
+	var path AnchorPath
+	err = protobuf.DecodeWithConstructors(inst.Invoke.Args.Search("path"), &path,
+		network.DefaultConstructors(cothority.Suite))
+	if err != nil {
+		return nil, nil, xerrors.Errorf("decoding path: %v", err)
+	}
+	if len(path.Blocks) < 2 {
+		return nil, nil, xerrors.New("path needs at least a new block")
+	}
+	if !path.Blocks[0].Hash.Equal(c.BlockID) {
+		return nil, nil, xerrors.New("path doesn't start with the anchored block")
+	}
+	if err = verifyAnchorPath(path.Blocks); err != nil {
+		return nil, nil, xerrors.Errorf("verifying path: %v", err)
+	}
+	latest := path.Blocks[len(path.Blocks)-1]
+	if !latest.SkipChainID().Equal(c.SkipChainID) {
+		return nil, nil, xerrors.New("block of another skipchain")
+	}
+	if uint64(latest.Index) <= c.Index {
+		return nil, nil, xerrors.New("block is not newer than the anchored block")
+	}
+
+	c.BlockID = latest.Hash
+	c.Index = uint64(latest.Index)
+	c.Timestamp, err = chainTime(rst)
+	if err != nil {
+		return
+	}
+	buf, err := protobuf.Encode(&c.Anchor)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("couldn't encode anchor: %v", err)
+	}
+	log.Lvlf2("Anchoring block %d of skipchain %x", latest.Index, c.SkipChainID)
+	sc = []byzcoin.StateChange{
+		byzcoin.NewStateChange(byzcoin.Update, inst.InstanceID,
+			ContractAnchorID, buf, darcID),
+	}
+	return
+}
+
+// verifyAnchorPath checks that each block is reached by a valid forward-link
+// of the previous block, at any height.
+func verifyAnchorPath(blocks []*skipchain.SkipBlock) error {
+	for i, sb := range blocks {
+		if !sb.CalculateHash().Equal(sb.Hash) {
+			return xerrors.Errorf("wrong hash of block %d", sb.Index)
+		}
+		if i == 0 {
+			continue
+		}
+		prev := blocks[i-1]
+		if prev.Roster == nil {
+			return xerrors.Errorf("missing roster of block %d", prev.Index)
+		}
+		var link *skipchain.ForwardLink
+		for _, fl := range prev.ForwardLink {
+			if !fl.IsEmpty() && fl.From.Equal(prev.Hash) && fl.To.Equal(sb.Hash) {
+				link = fl
+			}
+		}
+		if link == nil {
+			return xerrors.Errorf("missing forward-link to block %d", sb.Index)
+		}
+		err := link.VerifyWithScheme(pairing.NewSuiteBn256(),
+			prev.Roster.ServicePublics(skipchain.ServiceName), prev.SignatureScheme)
+		if err != nil {
+			return xerrors.Errorf("forward-link of block %d: %v", prev.Index, err)
+		}
+	}
+	return nil
+}
+
+// AnchorInstruction returns the instruction anchoring the last block of the
+// path, which has to start with the latest anchored block. The signer
+// counters of the instruction must be set before signing it.
+func AnchorInstruction(anchor byzcoin.InstanceID, blocks []*skipchain.SkipBlock) (byzcoin.Instruction, error) {
+	buf, err := protobuf.Encode(&AnchorPath{Blocks: blocks})
+	if err != nil {
+		return byzcoin.Instruction{}, xerrors.Errorf("encoding path: %v", err)
+	}
+	return byzcoin.Instruction{
+		InstanceID: anchor,
+		Invoke: &byzcoin.Invoke{
+			ContractID: ContractAnchorID,
+			Command:    "anchor",
+			Args:       byzcoin.Arguments{{Name: "path", Value: buf}},
+		},
+	}, nil
+}
+
+// anchorFromProof returns the anchor proven by the byzcoin proof, which has
+// to be already verified.
+func anchorFromProof(p byzcoin.Proof, anchor byzcoin.InstanceID) (*Anchor, error) {
+	key, value, cid, _, err := p.KeyValue()
+	if err != nil {
+		return nil, xerrors.Errorf("reading proof: %v", err)
+	}
+	if !bytes.Equal(key, anchor.Slice()) {
+		return nil, xerrors.New("proof is not for the anchor")
+	}
+	if cid != ContractAnchorID {
+		return nil, xerrors.New("instance is not an anchor")
+	}
+	var a Anchor
+	if err := protobuf.Decode(value, &a); err != nil {
+		return nil, xerrors.Errorf("decoding anchor: %v", err)
+	}
+	return &a, nil
+}
+
+// AnchorProof proves that a block of the source skipchain is anchored. It
+// holds the byzcoin proof of the anchor, and the path from the block to the
+// latest anchored block.
+type AnchorProof struct {
+	Anchor byzcoin.InstanceID
+	Proof  byzcoin.Proof
+	Path   []*skipchain.SkipBlock
+}
+
+// GetAnchorProof returns the proof that the block is anchored, asking the
+// roster of the source skipchain for the path from the block to the latest
+// anchored block. The proof is verified before it is returned.
+func GetAnchorProof(bc *byzcoin.Client, sc *skipchain.Client, roster *onet.Roster,
+	anchor byzcoin.InstanceID, block *skipchain.SkipBlock) (*AnchorProof, error) {
+	pr, err := bc.GetProof(anchor.Slice())
+	if err != nil {
+		return nil, xerrors.Errorf("getting proof: %v", err)
+	}
+	a, err := anchorFromProof(pr.Proof, anchor)
+	if err != nil {
+		return nil, err
+	}
+	path := []*skipchain.SkipBlock{block}
+	if !block.Hash.Equal(a.BlockID) {
+		path, err = sc.GetShortestPath(roster, block, a.BlockID)
+		if err != nil {
+			return nil, xerrors.Errorf("getting path: %v", err)
+		}
+	}
+	ap := &AnchorProof{Anchor: anchor, Proof: pr.Proof, Path: path}
+	if _, err := ap.Verify(bc.ID, block.Hash); err != nil {
+		return nil, xerrors.Errorf("verifying proof: %v", err)
+	}
+	return ap, nil
+}
+
+// Verify checks that the block is anchored in the given byzcoin chain, and
+// returns the latest anchored block.
+func (ap AnchorProof) Verify(bcID skipchain.SkipBlockID, block skipchain.SkipBlockID) (*Anchor, error) {
+	if err := ap.Proof.Verify(bcID); err != nil {
+		return nil, xerrors.Errorf("verifying byzcoin proof: %v", err)
+	}
+	a, err := anchorFromProof(ap.Proof, ap.Anchor)
+	if err != nil {
+		return nil, err
+	}
+	if len(ap.Path) == 0 || !ap.Path[0].Hash.Equal(block) {
+		return nil, xerrors.New("path doesn't start with the block")
+	}
+	if !ap.Path[len(ap.Path)-1].Hash.Equal(a.BlockID) {
+		return nil, xerrors.New("path doesn't end with the anchored block")
+	}
+	if err := verifyAnchorPath(ap.Path); err != nil {
+		return nil, xerrors.Errorf("verifying path: %v", err)
+	}
+	return a, nil
+}
+
+// Anchorer periodically anchors the latest block of a source skipchain in an
+// anchor instance.
+type Anchorer struct {
+	// Source is the client of the source skipchain, and Roster the conodes
+	// asked for its new blocks. Roster is updated when the roster of the
+	// source changes.
+	Source *skipchain.Client
+	Roster *onet.Roster
+	// Target is the client of the byzcoin chain holding the anchor.
+	Target *byzcoin.Client
+	Anchor byzcoin.InstanceID
+	// Signer has to be allowed to invoke anchor.anchor by the darc of the
+	// anchor.
+	Signer darc.Signer
+
+	stop chan bool
+	wg   sync.WaitGroup
+}
+
+// AnchorLatest anchors the latest block of the source, if it is newer than
+// the anchored one, and returns the anchor.
+func (a *Anchorer) AnchorLatest() (*Anchor, error) {
+	pr, err := a.Target.GetProof(a.Anchor.Slice())
+	if err != nil {
+		return nil, xerrors.Errorf("getting proof: %v", err)
+	}
+	anchor, err := anchorFromProof(pr.Proof, a.Anchor)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := a.Source.GetUpdateChain(a.Roster, anchor.BlockID)
+	if err != nil {
+		return nil, xerrors.Errorf("getting new blocks: %v", err)
+	}
+	if len(reply.Update) < 2 {
+		return anchor, nil
+	}
+
+	inst, err := AnchorInstruction(a.Anchor, reply.Update)
+	if err != nil {
+		return nil, err
+	}
+	counters, err := a.Target.GetSignerCounters(a.Signer.Identity().String())
+	if err != nil {
+		return nil, xerrors.Errorf("getting signer counters: %v", err)
+	}
+	inst.SignerCounter = []uint64{counters.Counters[0] + 1}
+	ctx, err := a.Target.CreateTransaction(inst)
+	if err != nil {
+		return nil, xerrors.Errorf("creating transaction: %v", err)
+	}
+	if err := ctx.FillSignersAndSignWith(a.Signer); err != nil {
+		return nil, xerrors.Errorf("signing transaction: %v", err)
+	}
+	if _, err := a.Target.AddTransactionAndWait(ctx, 10); err != nil {
+		return nil, xerrors.Errorf("adding transaction: %v", err)
+	}
+
+	latest := reply.Update[len(reply.Update)-1]
+	a.Roster = latest.Roster
+	anchor.BlockID = latest.Hash
+	anchor.Index = uint64(latest.Index)
+	return anchor, nil
+}
+
+// Start anchors the latest block of the source every interval, until Stop is
+// called. Failures are logged, and the anchoring is tried again at the next
+// interval.
+func (a *Anchorer) Start(interval time.Duration) {
+	a.stop = make(chan bool)
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		for {
+			select {
+			case <-time.After(interval):
+				if _, err := a.AnchorLatest(); err != nil {
+					log.Error("Anchoring failed:", err)
+				}
+			case <-a.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the anchoring started with Start and waits for the current
+// anchoring to finish.
+func (a *Anchorer) Stop() {
+	close(a.stop)
+	a.wg.Wait()
+}
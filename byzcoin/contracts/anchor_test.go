@@ -0,0 +1,154 @@
+package contracts
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/cothority/v3/skipchain"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/protobuf"
+)
+
+// createSourceChain returns the blocks of a new skipchain.
+func createSourceChain(t *testing.T, roster *onet.Roster, n int) []*skipchain.SkipBlock {
+	sc := skipchain.NewClient()
+	genesis, err := sc.CreateGenesis(roster, 2, 3, skipchain.VerificationNone, nil)
+	require.NoError(t, err)
+	blocks := []*skipchain.SkipBlock{genesis}
+	for i := 1; i < n; i++ {
+		reply, err := sc.StoreSkipBlock(blocks[i-1], roster, nil)
+		require.NoError(t, err)
+		blocks = append(blocks, reply.Latest)
+	}
+	// Get the blocks again, with their forward-links.
+	for i, sb := range blocks {
+		blocks[i], err = sc.GetSingleBlock(roster, sb.Hash)
+		require.NoError(t, err)
+	}
+	return blocks
+}
+
+func TestAnchor_Invoke(t *testing.T) {
+	local := onet.NewTCPTest(cothority.Suite)
+	defer local.CloseAll()
+	_, roster, _ := local.GenTree(3, true)
+	blocks := createSourceChain(t, roster, 5)
+	other := createSourceChain(t, roster, 2)
+
+	ct := newCT()
+	tt := timeTest{cvTest: ct, timestamp: 1234}
+	spawn := byzcoin.Instruction{
+		InstanceID: byzcoin.NewInstanceID(gdarc.GetBaseID()),
+		Spawn: &byzcoin.Spawn{
+			ContractID: ContractAnchorID,
+			Args:       byzcoin.Arguments{{Name: "skipchainID", Value: blocks[0].Hash}},
+		},
+	}
+	c, err := contractAnchorFromBytes(nil)
+	require.NoError(t, err)
+	sc, _, err := c.Spawn(tt, spawn, nil)
+	require.NoError(t, err)
+	anchor := spawn.DeriveID("")
+	ct.Store(anchor, sc[0].Value, ContractAnchorID, gdarc.GetBaseID())
+
+	invoke := func(path ...*skipchain.SkipBlock) error {
+		c, err := contractAnchorFromBytes(ct.values[string(anchor.Slice())])
+		require.NoError(t, err)
+		inst, err := AnchorInstruction(anchor, path)
+		require.NoError(t, err)
+		sc, _, err := c.Invoke(tt, inst, nil)
+		if err != nil {
+			return err
+		}
+		ct.Store(anchor, sc[0].Value, ContractAnchorID, gdarc.GetBaseID())
+		return nil
+	}
+
+	require.Error(t, invoke(blocks[0]))
+	require.Error(t, invoke(blocks[1], blocks[2]))
+	require.Error(t, invoke(other[0], other[1]))
+	require.NoError(t, invoke(blocks[0], blocks[1], blocks[2]))
+	require.Error(t, invoke(blocks[2], blocks[1]))
+
+	// A block that doesn't match its hash is refused.
+	tampered := blocks[3].Copy()
+	tampered.Data = []byte("tampered")
+	require.Error(t, invoke(blocks[2], tampered))
+	require.NoError(t, invoke(blocks[2], blocks[3], blocks[4]))
+
+	var a Anchor
+	require.NoError(t, protobuf.Decode(ct.values[string(anchor.Slice())], &a))
+	require.True(t, a.SkipChainID.Equal(blocks[0].Hash))
+	require.True(t, a.BlockID.Equal(blocks[4].Hash))
+	require.Equal(t, uint64(4), a.Index)
+	require.Equal(t, int64(1234), a.Timestamp)
+}
+
+func TestAnchor_Anchorer(t *testing.T) {
+	local := onet.NewTCPTest(cothority.Suite)
+	defer local.CloseAll()
+
+	signer := darc.NewSignerEd25519(nil, nil)
+	_, roster, _ := local.GenTree(3, true)
+	blocks := createSourceChain(t, roster, 6)
+
+	genesisMsg, err := byzcoin.DefaultGenesisMsg(byzcoin.CurrentVersion, roster,
+		[]string{"spawn:anchor", "invoke:anchor.anchor"}, signer.Identity())
+	require.NoError(t, err)
+	gDarc := &genesisMsg.GenesisDarc
+	genesisMsg.BlockInterval = time.Second
+
+	cl, _, err := byzcoin.NewLedger(genesisMsg, false)
+	require.NoError(t, err)
+
+	ctx, err := cl.CreateTransaction(byzcoin.Instruction{
+		InstanceID: byzcoin.NewInstanceID(gDarc.GetBaseID()),
+		Spawn: &byzcoin.Spawn{
+			ContractID: ContractAnchorID,
+			Args:       byzcoin.Arguments{{Name: "skipchainID", Value: blocks[0].Hash}},
+		},
+		SignerCounter: []uint64{1},
+	})
+	require.NoError(t, err)
+	require.NoError(t, ctx.FillSignersAndSignWith(signer))
+	_, err = cl.AddTransactionAndWait(ctx, 10)
+	require.NoError(t, err)
+	anchor := ctx.Instructions[0].DeriveID("")
+
+	sc := skipchain.NewClient()
+	anchorer := &Anchorer{
+		Source: sc,
+		Roster: roster,
+		Target: cl,
+		Anchor: anchor,
+		Signer: signer,
+	}
+	a, err := anchorer.AnchorLatest()
+	require.NoError(t, err)
+	require.True(t, a.BlockID.Equal(blocks[5].Hash))
+	require.Equal(t, uint64(5), a.Index)
+	// Nothing new to anchor.
+	a, err = anchorer.AnchorLatest()
+	require.NoError(t, err)
+	require.Equal(t, uint64(5), a.Index)
+
+	for _, sb := range []*skipchain.SkipBlock{blocks[1], blocks[5]} {
+		ap, err := GetAnchorProof(cl, sc, roster, anchor, sb)
+		require.NoError(t, err)
+		a, err = ap.Verify(cl.ID, sb.Hash)
+		require.NoError(t, err)
+		require.Equal(t, uint64(5), a.Index)
+		_, err = ap.Verify(cl.ID, blocks[2].Hash)
+		require.Error(t, err)
+	}
+
+	// A block added later is not anchored yet.
+	reply, err := sc.StoreSkipBlock(blocks[5], roster, nil)
+	require.NoError(t, err)
+	_, err = GetAnchorProof(cl, sc, roster, anchor, reply.Latest)
+	require.Error(t, err)
+}
@@ -0,0 +1,424 @@
+package contracts
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/protobuf"
+	"golang.org/x/xerrors"
+)
+
+// ContractAuctionID denotes a contract that sells to the highest bidder.
+const ContractAuctionID = "auction"
+
+// ContractAuction runs an auction where bidders lock coins. Once the deadline
+// has passed, the auction can be settled by anybody: the highest bid is paid
+// to the seller, and all other locked coins are refunded. An auction is
+// either open and ascending, where every bid must be higher than the previous
+// one, or sealed, where the bids are only revealed after the deadline.
+//
+// Spawn needs the following arguments:
+//  - coinType is the name of the coins used for bidding
+//  - payTo is the coin instance of the seller, receiving the winning bid
+//  - duration is the number of blocks, as a 64-bit uint in LittleEndian,
+//    during which bids are accepted
+//  - revealDuration is optional, as a 64-bit uint in LittleEndian. If it is
+//    given, the auction is sealed, and the bids can be revealed during this
+//    number of blocks after the deadline.
+//  - reserve is the optional minimum bid, as a 64-bit uint in LittleEndian
+//  - description is an optional description of the item
+//  - darcID is optional and defines the darc of the auction, which must
+//    allow the bidders to invoke the commands
+//
+// The following methods are available:
+//  - bid locks the coins given to the instruction, and stores the bid. The
+//    argument "refund" is the coin instance of the bidder that receives
+//    refunds. For an open auction, the locked coins are the bid, and the
+//    previous highest bid is refunded. For a sealed auction, the argument
+//    "commitment" must be given, as returned by AuctionCommitment, and the
+//    locked coins must be at least the bid.
+//  - reveal opens a sealed bid with the arguments "amount", as a 64-bit uint
+//    in LittleEndian, and "salt". Bids that are not revealed are refunded.
+//  - settle pays the winning bid to the seller and refunds all other coins.
+//    After settlement, the auction only holds the winning bid, if any.
+type ContractAuction struct {
+	byzcoin.BasicContract
+	Auction
+}
+
+// Auction is the data stored in an auction instance.
+type Auction struct {
+	Description []byte
+	// CoinType is the name of the coins used for bidding.
+	CoinType byzcoin.InstanceID
+	// PayTo is the coin instance receiving the winning bid.
+	PayTo byzcoin.InstanceID
+	// Reserve is the minimum bid.
+	Reserve uint64
+	// Deadline is the index of the block from which no more bids are
+	// accepted.
+	Deadline int
+	// RevealDeadline is the index of the block from which sealed bids
+	// cannot be revealed anymore. It is 0 for an open auction.
+	RevealDeadline int
+	Bids           []AuctionBid
+	Settled        bool
+}
+
+// AuctionBid is one bid of an auction.
+type AuctionBid struct {
+	// Bidder is the identity of the first signer of the bid.
+	Bidder string
+	// Refund is the coin instance receiving the refunds.
+	Refund byzcoin.InstanceID
+	// Locked is the number of coins locked by the bid.
+	Locked uint64
+	// Amount is the bid, which is only known once a sealed bid is
+	// revealed.
+	Amount uint64
+	// Commitment is the commitment of a sealed bid.
+	Commitment []byte
+	Revealed   bool
+}
+
+func contractAuctionFromBytes(in []byte) (byzcoin.Contract, error) {
+	c := &ContractAuction{}
+	err := protobuf.Decode(in, &c.Auction)
+	if err != nil {
+		return nil, xerrors.Errorf("couldn't unmarshal instance data: %v", err)
+	}
+	return c, nil
+}
+
+// Spawn implements the byzcoin.Contract interface.
+func (c *ContractAuction) Spawn(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+	if did := inst.Spawn.Args.Search("darcID"); did != nil {
+		darcID = darc.ID(did)
+	}
+
+	args := inst.Spawn.Args
+	coinType := args.Search("coinType")
+	if len(coinType) != len(byzcoin.InstanceID{}) {
+		return nil, nil, xerrors.New("coinType needs to be an InstanceID")
+	}
+	c.CoinType = byzcoin.NewInstanceID(coinType)
+	c.PayTo = byzcoin.NewInstanceID(args.Search("payTo"))
+	payTo, _, err := getCoin(rst, c.PayTo.Slice())
+	if err != nil {
+		return nil, nil, xerrors.Errorf("payTo: %v", err)
+	}
+	if !payTo.Name.Equal(c.CoinType) {
+		return nil, nil, xerrors.New("payTo holds another type of coin")
+	}
+
+	duration, err := uint64Arg(args, "duration")
+	if err != nil {
+		return
+	}
+	if duration == 0 {
+		return nil, nil, xerrors.New("duration cannot be 0")
+	}
+	c.Deadline = rst.GetIndex() + int(duration)
+	if args.Search("revealDuration") != nil {
+		var reveal uint64
+		reveal, err = uint64Arg(args, "revealDuration")
+		if err != nil {
+			return
+		}
+		if reveal == 0 {
+			return nil, nil, xerrors.New("revealDuration cannot be 0")
+		}
+		c.RevealDeadline = c.Deadline + int(reveal)
+	}
+	if args.Search("reserve") != nil {
+		c.Reserve, err = uint64Arg(args, "reserve")
+		if err != nil {
+			return
+		}
+	}
+	c.Description = args.Search("description")
+
+	buf, err := protobuf.Encode(&c.Auction)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("couldn't encode auction: %v", err)
+	}
+	log.Lvlf2("Starting auction until block %d", c.Deadline)
+	sc = []byzcoin.StateChange{
+		byzcoin.NewStateChange(byzcoin.Create, inst.DeriveID(""),
+			ContractAuctionID, buf, darcID),
+	}
+	return
+}
+
+// Invoke implements the byzcoin.Contract interface.
+func (c *ContractAuction) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+	if c.Settled {
+		return nil, nil, xerrors.New("auction is settled")
+	}
+	sealed := c.RevealDeadline > 0
+	index := rst.GetIndex()
+
+	switch inst.Invoke.Command {
+	case "bid":
+		if index >= c.Deadline {
+			return nil, nil, xerrors.New("auction is closed")
+		}
+		var locked byzcoin.Coin
+		locked, err = sumCoins(coins)
+		if err != nil {
+			return nil, nil, xerrors.Errorf("locked coins: %v", err)
+		}
+		if !locked.Name.Equal(c.CoinType) {
+			return nil, nil, xerrors.New("wrong type of coins")
+		}
+		bid := AuctionBid{
+			Refund: byzcoin.NewInstanceID(inst.Invoke.Args.Search("refund")),
+			Locked: locked.Value,
+		}
+		if len(inst.SignerIdentities) > 0 {
+			bid.Bidder = inst.SignerIdentities[0].String()
+		}
+		var refund byzcoin.Coin
+		refund, _, err = getCoin(rst, bid.Refund.Slice())
+		if err != nil {
+			return nil, nil, xerrors.Errorf("refund: %v", err)
+		}
+		if !refund.Name.Equal(c.CoinType) {
+			return nil, nil, xerrors.New("refund holds another type of coin")
+		}
+		cout = nil
+
+		if sealed {
+			bid.Commitment = inst.Invoke.Args.Search("commitment")
+			if len(bid.Commitment) != sha256.Size {
+				return nil, nil, xerrors.New("sealed bid needs a commitment")
+			}
+			c.Bids = append(c.Bids, bid)
+			break
+		}
+
+		bid.Amount = bid.Locked
+		bid.Revealed = true
+		if bid.Amount < c.Reserve {
+			return nil, nil, xerrors.Errorf("bid is below the reserve of %d", c.Reserve)
+		}
+		if len(c.Bids) > 0 {
+			if bid.Amount <= c.Bids[0].Amount {
+				return nil, nil, xerrors.Errorf("bid must be higher than %d", c.Bids[0].Amount)
+			}
+			sc, err = payCoins(rst, c.CoinType, []byzcoin.InstanceID{c.Bids[0].Refund},
+				[]uint64{c.Bids[0].Locked})
+			if err != nil {
+				return nil, nil, xerrors.Errorf("refunding previous bid: %v", err)
+			}
+		}
+		c.Bids = []AuctionBid{bid}
+	case "reveal":
+		if !sealed {
+			return nil, nil, xerrors.New("only sealed bids can be revealed")
+		}
+		if index < c.Deadline || index >= c.RevealDeadline {
+			return nil, nil, xerrors.Errorf("bids can only be revealed from block %d to %d",
+				c.Deadline, c.RevealDeadline-1)
+		}
+		var amount uint64
+		amount, err = uint64Arg(inst.Invoke.Args, "amount")
+		if err != nil {
+			return
+		}
+		commitment := AuctionCommitment(amount, inst.Invoke.Args.Search("salt"))
+		found := false
+		for i := range c.Bids {
+			bid := &c.Bids[i]
+			if bid.Revealed || !bytes.Equal(bid.Commitment, commitment) {
+				continue
+			}
+			if amount > bid.Locked {
+				return nil, nil, xerrors.New("bid is higher than the locked coins")
+			}
+			bid.Amount = amount
+			bid.Revealed = true
+			found = true
+			break
+		}
+		if !found {
+			return nil, nil, xerrors.New("no unrevealed bid for this commitment")
+		}
+	case "settle":
+		end := c.Deadline
+		if sealed {
+			end = c.RevealDeadline
+		}
+		if index < end {
+			return nil, nil, xerrors.Errorf("auction cannot be settled before block %d", end)
+		}
+
+		winner := -1
+		for i, bid := range c.Bids {
+			if bid.Revealed && bid.Amount >= c.Reserve &&
+				(winner < 0 || bid.Amount > c.Bids[winner].Amount) {
+				winner = i
+			}
+		}
+		var ids []byzcoin.InstanceID
+		var values []uint64
+		for i, bid := range c.Bids {
+			refund := bid.Locked
+			if i == winner {
+				ids = append(ids, c.PayTo)
+				values = append(values, bid.Amount)
+				refund -= bid.Amount
+			}
+			ids = append(ids, bid.Refund)
+			values = append(values, refund)
+		}
+		sc, err = payCoins(rst, c.CoinType, ids, values)
+		if err != nil {
+			return nil, nil, xerrors.Errorf("paying out: %v", err)
+		}
+		if winner >= 0 {
+			c.Bids = []AuctionBid{c.Bids[winner]}
+		} else {
+			c.Bids = nil
+		}
+		c.Settled = true
+	default:
+		return nil, nil, xerrors.New("auction contract can only bid, reveal or settle")
+	}
+
+	buf, err := protobuf.Encode(&c.Auction)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("couldn't encode auction: %v", err)
+	}
+	sc = append(sc, byzcoin.NewStateChange(byzcoin.Update, inst.InstanceID,
+		ContractAuctionID, buf, darcID))
+	return
+}
+
+// payCoins returns the state changes adding the values to the coin
+// instances. The values for the same instance are added up, so that every
+// instance is updated only once.
+func payCoins(rst byzcoin.ReadOnlyStateTrie, name byzcoin.InstanceID, ids []byzcoin.InstanceID, values []uint64) (byzcoin.StateChanges, error) {
+	var order []byzcoin.InstanceID
+	sums := make(map[byzcoin.InstanceID]*byzcoin.Coin)
+	for i, id := range ids {
+		if values[i] == 0 {
+			continue
+		}
+		sum, ok := sums[id]
+		if !ok {
+			sum = &byzcoin.Coin{Name: name}
+			sums[id] = sum
+			order = append(order, id)
+		}
+		if err := sum.SafeAdd(values[i]); err != nil {
+			return nil, err
+		}
+	}
+	var scs byzcoin.StateChanges
+	for _, id := range order {
+		sc, err := addCoins(rst, id, *sums[id])
+		if err != nil {
+			return nil, xerrors.Errorf("instance %x: %v", id[:], err)
+		}
+		scs = append(scs, sc...)
+	}
+	return scs, nil
+}
+
+// uint64Arg returns the argument as a 64-bit uint in LittleEndian.
+func uint64Arg(args byzcoin.Arguments, name string) (uint64, error) {
+	buf := args.Search(name)
+	if len(buf) != 8 {
+		return 0, xerrors.Errorf("argument \"%s\" is missing or wrong length", name)
+	}
+	return binary.LittleEndian.Uint64(buf), nil
+}
+
+// AuctionCommitment returns the commitment of a sealed bid. The salt must be
+// kept secret until the bid is revealed.
+func AuctionCommitment(amount uint64, salt []byte) []byte {
+	h := sha256.New()
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buf, amount)
+	h.Write(buf)
+	h.Write(salt)
+	return h.Sum(nil)
+}
+
+// AuctionBidInstructions returns the instructions to fetch the coins from the
+// coin instance account and to lock them as a bid on the auction. For a
+// sealed auction, the commitment must be given. The signer counters of the
+// instructions must be set before signing them.
+func AuctionBidInstructions(auction, account, refund byzcoin.InstanceID, coins uint64, commitment []byte) byzcoin.Instructions {
+	coinsBuf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(coinsBuf, coins)
+	args := byzcoin.Arguments{{Name: "refund", Value: refund.Slice()}}
+	if commitment != nil {
+		args = append(args, byzcoin.Argument{Name: "commitment", Value: commitment})
+	}
+	return byzcoin.Instructions{
+		{
+			InstanceID: account,
+			Invoke: &byzcoin.Invoke{
+				ContractID: ContractCoinID,
+				Command:    "fetch",
+				Args:       byzcoin.Arguments{{Name: "coins", Value: coinsBuf}},
+			},
+		},
+		{
+			InstanceID: auction,
+			Invoke: &byzcoin.Invoke{
+				ContractID: ContractAuctionID,
+				Command:    "bid",
+				Args:       args,
+			},
+		},
+	}
+}
+
+// AuctionRevealInstruction returns the instruction revealing a sealed bid.
+func AuctionRevealInstruction(auction byzcoin.InstanceID, amount uint64, salt []byte) byzcoin.Instruction {
+	amountBuf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(amountBuf, amount)
+	return byzcoin.Instruction{
+		InstanceID: auction,
+		Invoke: &byzcoin.Invoke{
+			ContractID: ContractAuctionID,
+			Command:    "reveal",
+			Args: byzcoin.Arguments{
+				{Name: "amount", Value: amountBuf},
+				{Name: "salt", Value: salt},
+			},
+		},
+	}
+}
+
+// AuctionSettleInstruction returns the instruction settling the auction.
+func AuctionSettleInstruction(auction byzcoin.InstanceID) byzcoin.Instruction {
+	return byzcoin.Instruction{
+		InstanceID: auction,
+		Invoke: &byzcoin.Invoke{
+			ContractID: ContractAuctionID,
+			Command:    "settle",
+		},
+	}
+}
@@ -0,0 +1,122 @@
+package contracts
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/protobuf"
+)
+
+// newAuction stores three coin instances and spawns an auction that accepts
+// bids for 3 blocks.
+func newAuction(t *testing.T, ct *cvTest, sealed bool) byzcoin.InstanceID {
+	for _, id := range []string{"seller", "alice", "bob"} {
+		buf, err := protobuf.Encode(&byzcoin.Coin{Name: CoinName})
+		require.NoError(t, err)
+		ct.Store(iid(id), buf, ContractCoinID, gdarc.GetBaseID())
+	}
+
+	u64 := func(v uint64) []byte {
+		buf := make([]byte, 8)
+		binary.LittleEndian.PutUint64(buf, v)
+		return buf
+	}
+	args := byzcoin.Arguments{
+		{Name: "coinType", Value: CoinName.Slice()},
+		{Name: "payTo", Value: iid("seller").Slice()},
+		{Name: "duration", Value: u64(3)},
+		{Name: "reserve", Value: u64(3)},
+	}
+	if sealed {
+		args = append(args, byzcoin.Argument{Name: "revealDuration", Value: u64(2)})
+	}
+	inst := byzcoin.Instruction{
+		InstanceID: byzcoin.NewInstanceID(gdarc.GetBaseID()),
+		Spawn: &byzcoin.Spawn{
+			ContractID: ContractAuctionID,
+			Args:       args,
+		},
+	}
+	c, err := contractAuctionFromBytes(nil)
+	require.NoError(t, err)
+	sc, _, err := c.Spawn(ct, inst, nil)
+	require.NoError(t, err)
+	id := inst.DeriveID("")
+	ct.Store(id, sc[0].Value, ContractAuctionID, gdarc.GetBaseID())
+	return id
+}
+
+func coinsOf(ct *cvTest, id string) uint64 {
+	return ct.getContract(iid(id)).(*contractCoin).Value
+}
+
+// runAuction sends the instruction to the auction and applies the state
+// changes to ct.
+func runAuction(t *testing.T, ct *cvTest, inst byzcoin.Instruction, coins ...byzcoin.Coin) error {
+	c, err := contractAuctionFromBytes(ct.values[string(inst.InstanceID.Slice())])
+	require.NoError(t, err)
+	sc, _, err := c.Invoke(ct, inst, coins)
+	if err != nil {
+		return err
+	}
+	for _, s := range sc {
+		ct.Store(byzcoin.NewInstanceID(s.InstanceID), s.Value, s.ContractID, s.DarcID)
+	}
+	return nil
+}
+
+func TestAuction_Open(t *testing.T) {
+	ct := newCT()
+	auction := newAuction(t, ct, false)
+	bid := func(refund string, value uint64) error {
+		return runAuction(t, ct, AuctionBidInstructions(auction, iid(refund),
+			iid(refund), value, nil)[1], byzcoin.Coin{Name: CoinName, Value: value})
+	}
+
+	require.Error(t, bid("alice", 2))
+	require.NoError(t, bid("alice", 4))
+	require.Error(t, bid("bob", 4))
+	require.Error(t, runAuction(t, ct, AuctionSettleInstruction(auction)))
+	require.NoError(t, bid("bob", 5))
+	require.Equal(t, uint64(4), coinsOf(ct, "alice"))
+
+	// The auction is closed after the deadline.
+	require.Error(t, bid("alice", 6))
+	require.NoError(t, runAuction(t, ct, AuctionSettleInstruction(auction)))
+	require.Equal(t, uint64(5), coinsOf(ct, "seller"))
+	require.Equal(t, uint64(0), coinsOf(ct, "bob"))
+	require.Error(t, runAuction(t, ct, AuctionSettleInstruction(auction)))
+}
+
+func TestAuction_Sealed(t *testing.T) {
+	ct := newCT()
+	auction := newAuction(t, ct, true)
+	bid := func(refund string, value, amount uint64) error {
+		return runAuction(t, ct, AuctionBidInstructions(auction, iid(refund),
+			iid(refund), value, AuctionCommitment(amount, []byte(refund)))[1],
+			byzcoin.Coin{Name: CoinName, Value: value})
+	}
+	reveal := func(refund string, amount uint64) error {
+		return runAuction(t, ct, AuctionRevealInstruction(auction, amount, []byte(refund)))
+	}
+
+	// A sealed bid can lock more coins than the bid.
+	require.NoError(t, bid("alice", 10, 4))
+	require.NoError(t, reveal("bob", 6))
+	require.Error(t, reveal("alice", 4))
+	require.NoError(t, bid("bob", 6, 6))
+
+	// Reveal after the deadline, only with the correct amount.
+	require.Error(t, reveal("alice", 5))
+	require.NoError(t, reveal("alice", 4))
+	require.Error(t, reveal("alice", 4))
+	require.Error(t, runAuction(t, ct, AuctionSettleInstruction(auction)))
+	require.NoError(t, reveal("bob", 6))
+
+	require.NoError(t, runAuction(t, ct, AuctionSettleInstruction(auction)))
+	require.Equal(t, uint64(6), coinsOf(ct, "seller"))
+	require.Equal(t, uint64(10), coinsOf(ct, "alice"))
+	require.Equal(t, uint64(0), coinsOf(ct, "bob"))
+}
@@ -21,13 +21,32 @@ var CoinName = iid("byzCoin")
 // ContractCoin is a coin implementation that holds one instance per coin.
 // If you spawn a new ContractCoin, it will create an account with a value
 // of 0 coins.
+// If the spawn has a "maxSupply" argument as a 64-bit uint in LittleEndian,
+// the account becomes the genesis instance of a new type of coin, whose
+// name is the ID of the instance, as returned by CoinGenesisID. The number
+// of coins of this type that can be minted, minus the ones burnt, is then
+// limited by "maxSupply". The coins of such a type can only be minted in the
+// genesis instance, and the genesis instance cannot be deleted while coins
+// of its type exist. The maximum supply and the burn command are only
+// available since byzcoin.VersionCoinSupply.
 // The following methods are available:
 //  - mint will add the number of coins in the argument "coins" to the
 //    current coin instance. The argument must be a 64-bit uint in LittleEndian
+//  - burn removes the number of coins in the argument "coins" from the
+//    current coin instance, and from the supply of the coin type
 //  - transfer will send the coins given in the argument "coins" to the
 //    instance given in the argument "destination". The "coins"-argument must
 //    be a 64-bit uint in LittleEndian. The "destination" must be a 64-bit
-//    instanceID
+//    instanceID, and hold the same type of coin since
+//    byzcoin.VersionCoinSupply
+//  - transferBatch sends coins to several instances at once. The arguments
+//    are pairs of "destination" and "coins", in this order, with the same
+//    format as for transfer. Either all transfers succeed, or none.
+//  - approve allows the darc given in the argument "spender" to transfer up
+//    to "coins" from this instance using the coinAllowance contract. The
+//    allowance is stored in the instance returned by AllowanceID, and
+//    replaces any previous allowance for the same spender. Approving 0 coins
+//    removes the allowance.
 //  - fetch takes "coins" out of the account and returns it as an output
 //    parameter for the next instruction to interpret.
 //  - store puts the coins given to the instance back into the account.
@@ -83,6 +102,24 @@ func (c *contractCoin) Spawn(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruc
 	} else {
 		c.Name = CoinName
 	}
+	ms := inst.Spawn.Args.Search("maxSupply")
+	if ms != nil && rst.GetVersion() >= byzcoin.VersionCoinSupply {
+		if inst.Spawn.Args.Search("type") != nil {
+			return nil, nil, xerrors.New("cannot give a type and a maximum supply")
+		}
+		if coinID != nil {
+			return nil, nil, xerrors.New("cannot give a coinID and a maximum supply")
+		}
+		if len(ms) != 8 {
+			return nil, nil, xerrors.New("argument \"maxSupply\" is wrong length")
+		}
+		c.MaxSupply = binary.LittleEndian.Uint64(ms)
+		if c.MaxSupply == 0 {
+			return nil, nil, xerrors.New("maximum supply must be bigger than 0")
+		}
+		ca = CoinGenesisID(inst)
+		c.Name = ca
+	}
 	var ciBuf []byte
 	ciBuf, err = protobuf.Encode(&c.Coin)
 	if err != nil {
@@ -103,9 +140,10 @@ func (c *contractCoin) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instru
 		return
 	}
 
-	// Invoke is one of "mint", "transfer", "fetch", or "store".
+	// Invoke is one of "mint", "transfer", "transferBatch", "fetch", or
+	// "store".
 	var coinsArg uint64
-	if inst.Invoke.Command != "store" {
+	if cmd := inst.Invoke.Command; cmd != "store" && cmd != "transferBatch" {
 		coinsBuf := inst.Invoke.Args.Search("coins")
 		if coinsBuf == nil {
 			err = xerrors.New("argument \"coins\" is missing")
@@ -126,30 +164,45 @@ func (c *contractCoin) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instru
 		if err != nil {
 			return
 		}
+		// Before VersionCoinSupply, minting didn't check the supply,
+		// and the chains must still replay the same way.
+		if rst.GetVersion() >= byzcoin.VersionCoinSupply {
+			sc, err = c.changeSupply(rst, inst, coinsArg, true)
+			if err != nil {
+				return
+			}
+		}
+	case "burn":
+		if rst.GetVersion() < byzcoin.VersionCoinSupply {
+			err = xerrors.New("coin contract can only mine and transfer")
+			return
+		}
+		// burn destroys this amount of coins from the account.
+		log.Lvl2("burning", coinsArg)
+		err = c.SafeSub(coinsArg)
+		if err != nil {
+			return
+		}
+		sc, err = c.changeSupply(rst, inst, coinsArg, false)
+		if err != nil {
+			return
+		}
 	case "transfer":
 		// transfer sends a given amount of coins to another account.
 		target := inst.Invoke.Args.Search("destination")
-		var (
-			v   []byte
-			cid string
-			did darc.ID
-		)
 		if inst.InstanceID.Equal(byzcoin.NewInstanceID(target)) {
 			err = xerrors.New("cannot send coins to ourselves")
 			return
 		}
-		v, _, cid, did, err = rst.GetValues(target)
-		if err == nil && cid != ContractCoinID {
-			err = xerrors.New("destination is not a coin contract")
-		}
+		var targetCI byzcoin.Coin
+		var did darc.ID
+		targetCI, did, err = getCoin(rst, target)
 		if err != nil {
 			return
 		}
-
-		var targetCI byzcoin.Coin
-		err = protobuf.Decode(v, &targetCI)
-		if err != nil {
-			return nil, nil, xerrors.Errorf("couldn't unmarshal target account: %v", err)
+		if rst.GetVersion() >= byzcoin.VersionCoinSupply && !targetCI.Name.Equal(c.Name) {
+			err = xerrors.New("destination holds another type of coin")
+			return
 		}
 		err = c.SafeSub(coinsArg)
 		if err != nil {
@@ -166,6 +219,16 @@ func (c *contractCoin) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instru
 		log.Lvlf2("transferring %d to %x", coinsArg, target)
 		sc = append(sc, byzcoin.NewStateChange(byzcoin.Update, byzcoin.NewInstanceID(target),
 			ContractCoinID, targetBuf, did))
+	case "transferBatch":
+		sc, err = c.transferBatch(rst, inst)
+		if err != nil {
+			return
+		}
+	case "approve":
+		sc, err = c.approve(rst, inst, coinsArg)
+		if err != nil {
+			return
+		}
 	case "fetch":
 		// fetch removes coins from the account and passes it on to the next
 		// instruction.
@@ -201,6 +264,170 @@ func (c *contractCoin) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instru
 	return
 }
 
+// transferBatch sends the coins of all pairs of "destination" and "coins"
+// arguments and returns the state changes of the destinations. Several
+// transfers to the same destination are added up.
+func (c *contractCoin) transferBatch(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction) (byzcoin.StateChanges, error) {
+	args := inst.Invoke.Args
+	if len(args) == 0 || len(args)%2 != 0 {
+		return nil, xerrors.New("need pairs of \"destination\" and \"coins\" arguments")
+	}
+
+	var order []byzcoin.InstanceID
+	targets := make(map[byzcoin.InstanceID]*byzcoin.Coin)
+	darcIDs := make(map[byzcoin.InstanceID]darc.ID)
+	for i := 0; i < len(args); i += 2 {
+		if args[i].Name != "destination" || args[i+1].Name != "coins" {
+			return nil, xerrors.Errorf("argument %d is not a \"destination\" "+
+				"followed by \"coins\"", i)
+		}
+		if len(args[i+1].Value) != 8 {
+			return nil, xerrors.Errorf("argument %d: \"coins\" is wrong length", i+1)
+		}
+		coins := binary.LittleEndian.Uint64(args[i+1].Value)
+		dest := byzcoin.NewInstanceID(args[i].Value)
+		if inst.InstanceID.Equal(dest) {
+			return nil, xerrors.New("cannot send coins to ourselves")
+		}
+
+		target, ok := targets[dest]
+		if !ok {
+			coin, did, err := getCoin(rst, dest.Slice())
+			if err != nil {
+				return nil, xerrors.Errorf("destination %x: %v", dest[:], err)
+			}
+			if !coin.Name.Equal(c.Name) {
+				return nil, xerrors.Errorf("destination %x holds another type of coin", dest[:])
+			}
+			target = &coin
+			targets[dest] = target
+			darcIDs[dest] = did
+			order = append(order, dest)
+		}
+		if err := c.SafeSub(coins); err != nil {
+			return nil, xerrors.Errorf("transfer %d: %v", i/2, err)
+		}
+		if err := target.SafeAdd(coins); err != nil {
+			return nil, xerrors.Errorf("transfer %d: %v", i/2, err)
+		}
+		log.Lvlf2("transferring %d to %x", coins, dest[:])
+	}
+
+	var sc byzcoin.StateChanges
+	for _, dest := range order {
+		buf, err := protobuf.Encode(targets[dest])
+		if err != nil {
+			return nil, xerrors.Errorf("couldn't marshal target account: %v", err)
+		}
+		sc = append(sc, byzcoin.NewStateChange(byzcoin.Update, dest,
+			ContractCoinID, buf, darcIDs[dest]))
+	}
+	return sc, nil
+}
+
+// changeSupply adds the minted coins to the supply of the type of this coin,
+// or removes the burnt coins from it, if the type has a maximum supply. If the
+// genesis instance of the type is another instance, its state change is
+// returned.
+func (c *contractCoin) changeSupply(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins uint64, mint bool) (byzcoin.StateChanges, error) {
+	if c.Name.Equal(inst.InstanceID) {
+		return nil, updateSupply(&c.Coin, coins, mint)
+	}
+
+	// Types without a genesis instance, like CoinName, have no maximum
+	// supply.
+	buf, _, cid, darcID, err := rst.GetValues(c.Name.Slice())
+	if err != nil {
+		return nil, nil
+	}
+	switch cid {
+	case "":
+		return nil, nil
+	case ContractTokenID:
+		if mint {
+			return nil, xerrors.New("tokens can only be minted by their token instance")
+		}
+		return burnToken(rst, c.Name, coins)
+	case ContractCoinID:
+	default:
+		return nil, xerrors.New("type of coin is not a genesis instance")
+	}
+
+	var genesis byzcoin.Coin
+	if err := protobuf.Decode(buf, &genesis); err != nil {
+		return nil, xerrors.Errorf("couldn't unmarshal genesis coin: %v", err)
+	}
+	if genesis.MaxSupply == 0 || !genesis.Name.Equal(c.Name) {
+		return nil, xerrors.New("type of coin is not a genesis instance")
+	}
+	// Only the genesis instance can mint, so that its darc decides who can
+	// create coins of the type.
+	if mint {
+		return nil, xerrors.New("coins with a maximum supply can only be minted " +
+			"in their genesis instance")
+	}
+	if err := updateSupply(&genesis, coins, mint); err != nil {
+		return nil, err
+	}
+	buf, err = protobuf.Encode(&genesis)
+	if err != nil {
+		return nil, xerrors.Errorf("couldn't marshal genesis coin: %v", err)
+	}
+	return byzcoin.StateChanges{byzcoin.NewStateChange(byzcoin.Update, c.Name,
+		ContractCoinID, buf, darcID)}, nil
+}
+
+// CoinGenesisID returns the ID of the genesis instance spawned by the
+// instruction with a maximum supply, which is the name of its type of coin.
+// It depends on the signatures of the instruction, so that nobody can
+// create accounts of the type and mint coins before the genesis instance
+// exists.
+func CoinGenesisID(inst byzcoin.Instruction) byzcoin.InstanceID {
+	h := sha256.New()
+	for _, sig := range inst.Signatures {
+		h.Write(sig)
+	}
+	return inst.DeriveID(string(h.Sum(nil)))
+}
+
+// updateSupply adds or removes coins from the supply of the genesis
+// instance of a type of coin.
+func updateSupply(genesis *byzcoin.Coin, coins uint64, mint bool) error {
+	if genesis.MaxSupply == 0 {
+		return nil
+	}
+	if mint {
+		if coins > genesis.MaxSupply-genesis.Supply {
+			return xerrors.Errorf("minting %d coins would exceed the maximum "+
+				"supply of %d", coins, genesis.MaxSupply)
+		}
+		genesis.Supply += coins
+		return nil
+	}
+	if coins > genesis.Supply {
+		return xerrors.New("burning more coins than the supply")
+	}
+	genesis.Supply -= coins
+	return nil
+}
+
+// getCoin returns the coin stored in the given instance, and the darc
+// controlling it.
+func getCoin(rst byzcoin.ReadOnlyStateTrie, id []byte) (coin byzcoin.Coin, darcID darc.ID, err error) {
+	v, _, cid, darcID, err := rst.GetValues(id)
+	if err == nil && cid != ContractCoinID {
+		err = xerrors.New("destination is not a coin contract")
+	}
+	if err != nil {
+		return
+	}
+	err = protobuf.Decode(v, &coin)
+	if err != nil {
+		err = xerrors.Errorf("couldn't unmarshal target account: %v", err)
+	}
+	return
+}
+
 func (c *contractCoin) Delete(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
 	cout = coins
 
@@ -214,6 +441,10 @@ func (c *contractCoin) Delete(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instru
 		err = xerrors.New("cannot destroy a coinInstance that still has coins in it")
 		return
 	}
+	if c.Name.Equal(inst.InstanceID) && c.Supply > 0 {
+		err = xerrors.New("cannot destroy a genesis instance while its coins exist")
+		return
+	}
 	sc = byzcoin.StateChanges{
 		byzcoin.NewStateChange(byzcoin.Remove, inst.InstanceID, ContractCoinID, nil, darcID),
 	}
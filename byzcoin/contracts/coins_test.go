@@ -244,11 +244,256 @@ func TestCoin_InvokeTransfer(t *testing.T) {
 	require.Equal(t, byzcoin.NewStateChange(byzcoin.Update, coAddr1, ContractCoinID, ciZero, gdarc.GetBaseID()), sc[1])
 }
 
+func TestCoin_InvokeTransferBatch(t *testing.T) {
+	ct := newCT("invoke:transferBatch")
+	ct.setSignatureCounter(gsigner.Identity().String(), 0)
+
+	coAddr1 := byzcoin.InstanceID{}
+	one := make([]byte, 32)
+	one[31] = 1
+	coAddr2 := byzcoin.NewInstanceID(one)
+	two := make([]byte, 32)
+	two[31] = 2
+	coAddr3 := byzcoin.NewInstanceID(two)
+
+	ct.Store(coAddr1, ciTwo, ContractCoinID, gdarc.GetBaseID())
+	ct.Store(coAddr2, ciZero, ContractCoinID, gdarc.GetBaseID())
+	ct.Store(coAddr3, ciZero, ContractCoinID, gdarc.GetBaseID())
+
+	batch := func(args ...byzcoin.Argument) byzcoin.Instruction {
+		return byzcoin.Instruction{
+			InstanceID: coAddr1,
+			Invoke: &byzcoin.Invoke{
+				Command: "transferBatch",
+				Args:    args,
+			},
+			SignerIdentities: []darc.Identity{gsigner.Identity()},
+			SignerCounter:    []uint64{1},
+		}
+	}
+	dest := func(id byzcoin.InstanceID) byzcoin.Argument {
+		return byzcoin.Argument{Name: "destination", Value: id.Slice()}
+	}
+	coins := func(c []byte) byzcoin.Argument {
+		return byzcoin.Argument{Name: "coins", Value: c}
+	}
+
+	// Not enough coins for all transfers.
+	_, _, err := ct.getContract(coAddr1).Invoke(ct,
+		batch(dest(coAddr2), coins(coinTwo), dest(coAddr3), coins(coinOne)), nil)
+	require.Error(t, err)
+	// Wrong order of the arguments.
+	_, _, err = ct.getContract(coAddr1).Invoke(ct,
+		batch(coins(coinOne), dest(coAddr2)), nil)
+	require.Error(t, err)
+	// Sending to ourselves.
+	_, _, err = ct.getContract(coAddr1).Invoke(ct,
+		batch(dest(coAddr1), coins(coinOne)), nil)
+	require.Error(t, err)
+
+	sc, _, err := ct.getContract(coAddr1).Invoke(ct,
+		batch(dest(coAddr2), coins(coinOne), dest(coAddr3), coins(coinOne)), nil)
+	require.NoError(t, err)
+	require.Equal(t, 3, len(sc))
+	require.Equal(t, byzcoin.NewStateChange(byzcoin.Update, coAddr2, ContractCoinID, ciOne, gdarc.GetBaseID()), sc[0])
+	require.Equal(t, byzcoin.NewStateChange(byzcoin.Update, coAddr3, ContractCoinID, ciOne, gdarc.GetBaseID()), sc[1])
+	require.Equal(t, byzcoin.NewStateChange(byzcoin.Update, coAddr1, ContractCoinID, ciZero, gdarc.GetBaseID()), sc[2])
+
+	// Two transfers to the same destination are added up.
+	sc, _, err = ct.getContract(coAddr1).Invoke(ct,
+		batch(dest(coAddr2), coins(coinOne), dest(coAddr2), coins(coinOne)), nil)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(sc))
+	require.Equal(t, byzcoin.NewStateChange(byzcoin.Update, coAddr2, ContractCoinID, ciTwo, gdarc.GetBaseID()), sc[0])
+}
+
+func TestCoin_SupplyCap(t *testing.T) {
+	ct := newCT("spawn:coin", "invoke:mint", "invoke:burn")
+	ct.setSignatureCounter(gsigner.Identity().String(), 0)
+
+	maxSupply := make([]byte, 8)
+	binary.LittleEndian.PutUint64(maxSupply, 2)
+	inst := byzcoin.Instruction{
+		InstanceID: byzcoin.NewInstanceID(gdarc.GetBaseID()),
+		Spawn: &byzcoin.Spawn{
+			ContractID: ContractCoinID,
+			Args:       byzcoin.Arguments{{Name: "maxSupply", Value: maxSupply}},
+		},
+		SignerCounter: []uint64{1},
+	}
+	signTx(t, &inst, gsigner)
+	c, _ := contractCoinFromBytes(nil)
+	sc, _, err := c.Spawn(ct, inst, []byzcoin.Coin{})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(sc))
+	genesisID := CoinGenesisID(inst)
+	require.Equal(t, genesisID.Slice(), sc[0].InstanceID)
+	require.False(t, genesisID.Equal(inst.DeriveID("")))
+	ct.Store(genesisID, sc[0].Value, ContractCoinID, gdarc.GetBaseID())
+	genesis := ct.getContract(genesisID).(*contractCoin)
+	require.True(t, genesis.Name.Equal(genesisID))
+	require.Equal(t, uint64(2), genesis.MaxSupply)
+
+	// An account of the new type.
+	one := make([]byte, 32)
+	one[31] = 1
+	account := byzcoin.NewInstanceID(one)
+	accountBuf, err := protobuf.Encode(&byzcoin.Coin{Name: genesisID})
+	require.NoError(t, err)
+	ct.Store(account, accountBuf, ContractCoinID, gdarc.GetBaseID())
+
+	invoke := func(id byzcoin.InstanceID, cmd string, coins []byte) (byzcoin.StateChanges, error) {
+		sc, _, err := ct.getContract(id).Invoke(ct, byzcoin.Instruction{
+			InstanceID: id,
+			Invoke: &byzcoin.Invoke{
+				Command: cmd,
+				Args:    byzcoin.Arguments{{Name: "coins", Value: coins}},
+			},
+			SignerIdentities: []darc.Identity{gsigner.Identity()},
+			SignerCounter:    []uint64{1},
+		}, nil)
+		if err == nil {
+			for _, s := range sc {
+				ct.Store(byzcoin.NewInstanceID(s.InstanceID), s.Value, ContractCoinID, s.DarcID)
+			}
+		}
+		return sc, err
+	}
+	transfer := func(from, to byzcoin.InstanceID, coins []byte) error {
+		sc, _, err := ct.getContract(from).Invoke(ct, byzcoin.Instruction{
+			InstanceID: from,
+			Invoke: &byzcoin.Invoke{
+				Command: "transfer",
+				Args: byzcoin.Arguments{{Name: "coins", Value: coins},
+					{Name: "destination", Value: to.Slice()}},
+			},
+		}, nil)
+		for _, s := range sc {
+			ct.Store(byzcoin.NewInstanceID(s.InstanceID), s.Value, ContractCoinID, s.DarcID)
+		}
+		return err
+	}
+
+	// Only the genesis instance can mint, and coins of another type cannot
+	// be added to the accounts of the type.
+	_, err = invoke(account, "mint", coinOne)
+	require.Error(t, err)
+	_, err = invoke(genesisID, "mint", coinTwo)
+	require.NoError(t, err)
+	_, err = invoke(genesisID, "mint", coinOne)
+	require.Error(t, err)
+	require.Equal(t, uint64(2), ct.getContract(genesisID).(*contractCoin).Supply)
+	other := byzcoin.NewInstanceID(make([]byte, 32))
+	otherBuf, err := protobuf.Encode(&byzcoin.Coin{Name: CoinName, Value: 1})
+	require.NoError(t, err)
+	ct.Store(other, otherBuf, ContractCoinID, gdarc.GetBaseID())
+	require.Error(t, transfer(other, account, coinOne))
+
+	// Burning frees up the supply again.
+	require.NoError(t, transfer(genesisID, account, coinOne))
+	_, err = invoke(account, "burn", coinTwo)
+	require.Error(t, err)
+	_, err = invoke(account, "burn", coinOne)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), ct.getContract(account).(*contractCoin).Value)
+	require.Equal(t, uint64(1), ct.getContract(genesisID).(*contractCoin).Supply)
+	_, err = invoke(genesisID, "mint", coinOne)
+	require.NoError(t, err)
+
+	// The genesis cannot be deleted while coins of its type exist.
+	require.NoError(t, transfer(genesisID, account, coinTwo))
+	_, _, err = ct.getContract(genesisID).Delete(ct, byzcoin.Instruction{
+		InstanceID: genesisID,
+		Delete:     &byzcoin.Delete{},
+	}, nil)
+	require.Error(t, err)
+
+	// Accounts whose type cannot be decoded cannot burn.
+	ct.Store(genesisID, []byte("not a coin"), ContractCoinID, gdarc.GetBaseID())
+	_, err = invoke(account, "burn", coinOne)
+	require.Error(t, err)
+
+	// A type and a maximum supply cannot be given together, nor a coinID,
+	// which gives a predictable genesis ID.
+	typed := inst
+	typed.Spawn = &byzcoin.Spawn{ContractID: ContractCoinID,
+		Args: append(inst.Spawn.Args, byzcoin.Argument{Name: "type", Value: one})}
+	_, _, err = c.Spawn(ct, typed, []byzcoin.Coin{})
+	require.Error(t, err)
+	typed.Spawn = &byzcoin.Spawn{ContractID: ContractCoinID,
+		Args: append(inst.Spawn.Args, byzcoin.Argument{Name: "coinID", Value: one})}
+	_, _, err = c.Spawn(ct, typed, []byzcoin.Coin{})
+	require.Error(t, err)
+}
+
+// TestCoin_SupplyCapOldVersion checks that chains that are not yet upgraded
+// to VersionCoinSupply keep the previous behaviour of the coin contract.
+func TestCoin_SupplyCapOldVersion(t *testing.T) {
+	ct := newCT("spawn:coin", "invoke:mint", "invoke:burn", "invoke:transfer")
+	ct.version = byzcoin.VersionCoinSupply - 1
+
+	maxSupply := make([]byte, 8)
+	binary.LittleEndian.PutUint64(maxSupply, 2)
+	inst := byzcoin.Instruction{
+		InstanceID: byzcoin.NewInstanceID(gdarc.GetBaseID()),
+		Spawn: &byzcoin.Spawn{
+			ContractID: ContractCoinID,
+			Args:       byzcoin.Arguments{{Name: "maxSupply", Value: maxSupply}},
+		},
+		SignerCounter: []uint64{1},
+	}
+	signTx(t, &inst, gsigner)
+	c, _ := contractCoinFromBytes(nil)
+	sc, _, err := c.Spawn(ct, inst, []byzcoin.Coin{})
+	require.NoError(t, err)
+	require.Equal(t, inst.DeriveID("").Slice(), sc[0].InstanceID)
+	require.Equal(t, uint64(0), c.(*contractCoin).MaxSupply)
+
+	// An account whose type is another coin instance without a maximum
+	// supply, and an account of CoinName.
+	typeID := byzcoin.NewInstanceID(make([]byte, 32))
+	ct.Store(typeID, ciZero, ContractCoinID, gdarc.GetBaseID())
+	one := make([]byte, 32)
+	one[31] = 1
+	account := byzcoin.NewInstanceID(one)
+	accountBuf, err := protobuf.Encode(&byzcoin.Coin{Name: typeID})
+	require.NoError(t, err)
+	ct.Store(account, accountBuf, ContractCoinID, gdarc.GetBaseID())
+	two := make([]byte, 32)
+	two[31] = 2
+	other := byzcoin.NewInstanceID(two)
+	ct.Store(other, ciOne, ContractCoinID, gdarc.GetBaseID())
+
+	invoke := func(id byzcoin.InstanceID, cmd string, args byzcoin.Arguments) (byzcoin.StateChanges, error) {
+		sc, _, err := ct.getContract(id).Invoke(ct, byzcoin.Instruction{
+			InstanceID: id,
+			Invoke:     &byzcoin.Invoke{Command: cmd, Args: args},
+		}, nil)
+		return sc, err
+	}
+	coins := byzcoin.Arguments{{Name: "coins", Value: coinOne}}
+
+	sc, err = invoke(account, "mint", coins)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(sc))
+	_, err = invoke(account, "burn", coins)
+	require.Error(t, err)
+	_, err = invoke(other, "transfer", append(coins,
+		byzcoin.Argument{Name: "destination", Value: account.Slice()}))
+	require.NoError(t, err)
+
+	ct.version = byzcoin.VersionCoinSupply
+	_, err = invoke(other, "transfer", append(coins,
+		byzcoin.Argument{Name: "destination", Value: account.Slice()}))
+	require.Error(t, err)
+}
+
 type cvTest struct {
 	values      map[string][]byte
 	contractIDs map[string]string
 	darcIDs     map[string]darc.ID
 	index       int
+	version     byzcoin.Version
 }
 
 var gdarc *darc.Darc
@@ -260,6 +505,7 @@ func newCT(rStr ...string) *cvTest {
 		make(map[string]string),
 		make(map[string]darc.ID),
 		0,
+		byzcoin.CurrentVersion,
 	}
 	gsigner = darc.NewSignerEd25519(nil, nil)
 	rules := darc.InitRules([]darc.Identity{gsigner.Identity()},
@@ -299,7 +545,7 @@ func (ct cvTest) GetIndex() int {
 }
 
 func (ct cvTest) GetVersion() byzcoin.Version {
-	return byzcoin.CurrentVersion
+	return ct.version
 }
 
 func (ct cvTest) ForEach(f func(k, v []byte) error) error {
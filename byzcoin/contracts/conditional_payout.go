@@ -0,0 +1,243 @@
+package contracts
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/protobuf"
+	"golang.org/x/xerrors"
+)
+
+// ContractConditionalPayoutID denotes a contract paying out coins depending
+// on the value of an oracle.
+const ContractConditionalPayoutID = "conditionalPayout"
+
+// ContractConditionalPayout holds coins that are paid out to one of several
+// beneficiaries, depending on the value published by an oracle. The oracle
+// is a value instance, whose darc defines who can publish the outcome. This
+// can be used for simple prediction markets or insurances.
+//
+// Spawn takes the coins given to the instruction, which must all be of the
+// same type, and the following arguments:
+//  - oracle is the ID of the value instance of the oracle
+//  - deadline is the index of the block, as a 64-bit uint in LittleEndian,
+//    until which the payout can be resolved
+//  - outcome and beneficiary are pairs of arguments, in this order. If the
+//    oracle has the value of the outcome, the coins are paid to the coin
+//    instance of the beneficiary. This pair can be given many times.
+//  - refund is the coin instance that gets the coins back if no outcome is
+//    published before the deadline
+//  - darcID is optional and defines the darc of the payout
+//
+// The following methods are available:
+//  - resolve pays the coins to the beneficiary of the current value of the
+//    oracle, and removes the payout. It fails if the value is not one of
+//    the outcomes, or if the deadline is reached.
+//  - refund pays the coins back to the refund instance, and removes the
+//    payout. It is only possible once the deadline is reached.
+type ContractConditionalPayout struct {
+	byzcoin.BasicContract
+	ConditionalPayout
+}
+
+// ConditionalPayout is the data stored in a conditionalPayout instance.
+type ConditionalPayout struct {
+	Coins    byzcoin.Coin
+	Oracle   byzcoin.InstanceID
+	Deadline int
+	Outcomes []PayoutOutcome
+	Refund   byzcoin.InstanceID
+}
+
+// PayoutOutcome is one possible outcome of a conditionalPayout.
+type PayoutOutcome struct {
+	Value       []byte
+	Beneficiary byzcoin.InstanceID
+}
+
+func contractConditionalPayoutFromBytes(in []byte) (byzcoin.Contract, error) {
+	c := &ContractConditionalPayout{}
+	err := protobuf.Decode(in, &c.ConditionalPayout)
+	if err != nil {
+		return nil, xerrors.Errorf("couldn't unmarshal instance data: %v", err)
+	}
+	return c, nil
+}
+
+// Spawn implements the byzcoin.Contract interface.
+func (c *ContractConditionalPayout) Spawn(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+	args := inst.Spawn.Args
+	if did := args.Search("darcID"); did != nil {
+		darcID = darc.ID(did)
+	}
+
+	c.Coins, err = sumCoins(coins)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("payout coins: %v", err)
+	}
+	if c.Coins.Value == 0 {
+		return nil, nil, xerrors.New("need coins to pay out")
+	}
+	c.Oracle = byzcoin.NewInstanceID(args.Search("oracle"))
+	_, _, cid, _, err := rst.GetValues(c.Oracle.Slice())
+	if err != nil {
+		return nil, nil, xerrors.Errorf("reading oracle: %v", err)
+	}
+	if cid != ContractValueID {
+		return nil, nil, xerrors.New("oracle is not a value instance")
+	}
+	deadline, err := uint64Arg(args, "deadline")
+	if err != nil {
+		return
+	}
+	c.Deadline = int(deadline)
+	if c.Deadline <= rst.GetIndex() {
+		return nil, nil, xerrors.New("deadline is in the past")
+	}
+
+	for i := 0; i < len(args); i++ {
+		if args[i].Name != "outcome" {
+			continue
+		}
+		if i+1 == len(args) || args[i+1].Name != "beneficiary" {
+			return nil, nil, xerrors.Errorf("argument %d is not an \"outcome\" "+
+				"followed by a \"beneficiary\"", i)
+		}
+		if c.outcome(args[i].Value) != nil {
+			return nil, nil, xerrors.Errorf("outcome %x is given twice", args[i].Value)
+		}
+		o := PayoutOutcome{
+			Value:       args[i].Value,
+			Beneficiary: byzcoin.NewInstanceID(args[i+1].Value),
+		}
+		if err = c.checkDestination(rst, o.Beneficiary); err != nil {
+			return nil, nil, xerrors.Errorf("beneficiary: %v", err)
+		}
+		c.Outcomes = append(c.Outcomes, o)
+	}
+	if len(c.Outcomes) == 0 {
+		return nil, nil, xerrors.New("need at least one outcome")
+	}
+	c.Refund = byzcoin.NewInstanceID(args.Search("refund"))
+	if err = c.checkDestination(rst, c.Refund); err != nil {
+		return nil, nil, xerrors.Errorf("refund: %v", err)
+	}
+
+	buf, err := protobuf.Encode(&c.ConditionalPayout)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("couldn't encode payout: %v", err)
+	}
+	log.Lvlf2("Escrowing %d coins for %d outcomes", c.Coins.Value, len(c.Outcomes))
+	sc = []byzcoin.StateChange{
+		byzcoin.NewStateChange(byzcoin.Create, inst.DeriveID(""),
+			ContractConditionalPayoutID, buf, darcID),
+	}
+	return
+}
+
+// Invoke implements the byzcoin.Contract interface.
+func (c *ContractConditionalPayout) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+
+	var dest byzcoin.InstanceID
+	switch inst.Invoke.Command {
+	case "resolve":
+		if rst.GetIndex() >= c.Deadline {
+			return nil, nil, xerrors.New("deadline is reached")
+		}
+		var value []byte
+		var cid string
+		value, _, cid, _, err = rst.GetValues(c.Oracle.Slice())
+		if err != nil {
+			return nil, nil, xerrors.Errorf("reading oracle: %v", err)
+		}
+		if cid != ContractValueID {
+			return nil, nil, xerrors.New("oracle is not a value instance")
+		}
+		o := c.outcome(value)
+		if o == nil {
+			return nil, nil, xerrors.Errorf("oracle value %x is not an outcome", value)
+		}
+		dest = o.Beneficiary
+	case "refund":
+		if rst.GetIndex() < c.Deadline {
+			return nil, nil, xerrors.Errorf("cannot refund before block %d", c.Deadline)
+		}
+		dest = c.Refund
+	default:
+		return nil, nil, xerrors.New("conditionalPayout contract can only resolve or refund")
+	}
+
+	sc, err = addCoins(rst, dest, c.Coins)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("paying out: %v", err)
+	}
+	log.Lvlf2("Paying %d coins to %x", c.Coins.Value, dest[:])
+	sc = append(sc, byzcoin.NewStateChange(byzcoin.Remove, inst.InstanceID,
+		ContractConditionalPayoutID, nil, darcID))
+	return
+}
+
+// outcome returns the outcome with the given value, or nil.
+func (c *ContractConditionalPayout) outcome(value []byte) *PayoutOutcome {
+	for i := range c.Outcomes {
+		if bytes.Equal(c.Outcomes[i].Value, value) {
+			return &c.Outcomes[i]
+		}
+	}
+	return nil
+}
+
+// checkDestination returns an error if the instance cannot receive the
+// coins of the payout.
+func (c *ContractConditionalPayout) checkDestination(rst byzcoin.ReadOnlyStateTrie, id byzcoin.InstanceID) error {
+	coin, _, err := getCoin(rst, id.Slice())
+	if err != nil {
+		return err
+	}
+	if !coin.Name.Equal(c.Coins.Name) {
+		return xerrors.New("instance holds another type of coin")
+	}
+	return nil
+}
+
+// ConditionalPayoutSpawnInstruction returns the instruction spawning a
+// payout of the coins fetched by a previous instruction. The beneficiaries
+// are given by the outcomes. The signer counters of the instruction must be
+// set before signing it.
+func ConditionalPayoutSpawnInstruction(darcID darc.ID, oracle byzcoin.InstanceID, deadline uint64,
+	outcomes []PayoutOutcome, refund byzcoin.InstanceID) byzcoin.Instruction {
+	deadlineBuf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(deadlineBuf, deadline)
+	args := byzcoin.Arguments{
+		{Name: "oracle", Value: oracle.Slice()},
+		{Name: "deadline", Value: deadlineBuf},
+		{Name: "refund", Value: refund.Slice()},
+	}
+	for _, o := range outcomes {
+		args = append(args,
+			byzcoin.Argument{Name: "outcome", Value: o.Value},
+			byzcoin.Argument{Name: "beneficiary", Value: o.Beneficiary.Slice()})
+	}
+	return byzcoin.Instruction{
+		InstanceID: byzcoin.NewInstanceID(darcID),
+		Spawn: &byzcoin.Spawn{
+			ContractID: ContractConditionalPayoutID,
+			Args:       args,
+		},
+	}
+}
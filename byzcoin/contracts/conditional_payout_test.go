@@ -0,0 +1,87 @@
+package contracts
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/protobuf"
+)
+
+func TestConditionalPayout(t *testing.T) {
+	ct := newCT()
+	for _, dest := range []string{"rain", "sun", "insurer"} {
+		buf, err := protobuf.Encode(&byzcoin.Coin{Name: CoinName})
+		require.NoError(t, err)
+		ct.Store(iid(dest), buf, ContractCoinID, gdarc.GetBaseID())
+	}
+	oracle := iid("weather")
+	ct.Store(oracle, []byte("cloudy"), ContractValueID, gdarc.GetBaseID())
+	outcomes := []PayoutOutcome{
+		{Value: []byte("rain"), Beneficiary: iid("rain")},
+		{Value: []byte("sun"), Beneficiary: iid("sun")},
+	}
+
+	spawn := func(inst byzcoin.Instruction) (byzcoin.InstanceID, error) {
+		c, err := contractConditionalPayoutFromBytes(nil)
+		require.NoError(t, err)
+		sc, _, err := c.Spawn(ct, inst, []byzcoin.Coin{{Name: CoinName, Value: 10}})
+		if err != nil {
+			return byzcoin.InstanceID{}, err
+		}
+		ct.Store(inst.DeriveID(""), sc[0].Value, ContractConditionalPayoutID, gdarc.GetBaseID())
+		return inst.DeriveID(""), nil
+	}
+	run := func(id byzcoin.InstanceID, cmd string) error {
+		c, err := contractConditionalPayoutFromBytes(ct.values[string(id.Slice())])
+		require.NoError(t, err)
+		sc, _, err := c.Invoke(ct, byzcoin.Instruction{
+			InstanceID: id,
+			Invoke: &byzcoin.Invoke{
+				ContractID: ContractConditionalPayoutID,
+				Command:    cmd,
+			},
+		}, nil)
+		if err != nil {
+			return err
+		}
+		for _, s := range sc {
+			ct.Store(byzcoin.NewInstanceID(s.InstanceID), s.Value, s.ContractID, s.DarcID)
+		}
+		return nil
+	}
+
+	deadline := uint64(ct.GetIndex() + 5)
+	_, err := spawn(ConditionalPayoutSpawnInstruction(gdarc.GetBaseID(), iid("rain"),
+		deadline, outcomes, iid("insurer")))
+	require.Error(t, err)
+	_, err = spawn(ConditionalPayoutSpawnInstruction(gdarc.GetBaseID(), oracle,
+		deadline, nil, iid("insurer")))
+	require.Error(t, err)
+	_, err = spawn(ConditionalPayoutSpawnInstruction(gdarc.GetBaseID(), oracle,
+		1, outcomes, iid("insurer")))
+	require.Error(t, err)
+	payout, err := spawn(ConditionalPayoutSpawnInstruction(gdarc.GetBaseID(), oracle,
+		deadline, outcomes, iid("insurer")))
+	require.NoError(t, err)
+
+	// The oracle has no outcome yet.
+	require.Error(t, run(payout, "resolve"))
+	require.Error(t, run(payout, "refund"))
+	ct.Store(oracle, []byte("sun"), ContractValueID, gdarc.GetBaseID())
+	require.NoError(t, run(payout, "resolve"))
+	require.Equal(t, uint64(10), coinsOf(ct, "sun"))
+	require.Nil(t, ct.values[string(payout.Slice())])
+
+	// Without outcome before the deadline, the coins are refunded.
+	ct.Store(oracle, []byte("cloudy"), ContractValueID, gdarc.GetBaseID())
+	payout, err = spawn(ConditionalPayoutSpawnInstruction(gdarc.GetBaseID(), oracle,
+		uint64(ct.GetIndex()+2), outcomes, iid("insurer")))
+	require.NoError(t, err)
+	ct.index += 2
+	ct.Store(oracle, []byte("rain"), ContractValueID, gdarc.GetBaseID())
+	require.Error(t, run(payout, "resolve"))
+	require.NoError(t, run(payout, "refund"))
+	require.Equal(t, uint64(10), coinsOf(ct, "insurer"))
+	require.Equal(t, uint64(0), coinsOf(ct, "rain"))
+}
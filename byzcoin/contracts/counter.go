@@ -0,0 +1,108 @@
+package contracts
+
+import (
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/protobuf"
+	"golang.org/x/xerrors"
+)
+
+// ContractCounterID denotes a contract holding a counter.
+const ContractCounterID = "counter"
+
+// ContractCounter holds a counter that can be incremented by many clients at
+// the same time. The increment command doesn't take the expected value of
+// the counter, so increments from different transactions in the same block
+// don't conflict, and the final value doesn't depend on their order.
+//
+// Spawn takes the optional argument "value", as a 64-bit uint in
+// LittleEndian, to set the initial value, and the optional argument "darcID"
+// to define the darc of the counter.
+//
+// The following method is available:
+//  - increment adds the optional argument "delta", as a 64-bit uint in
+//    LittleEndian, to the counter. The default delta is 1.
+type ContractCounter struct {
+	byzcoin.BasicContract
+	Counter
+}
+
+// Counter is the data stored in a counter instance.
+type Counter struct {
+	Value uint64
+}
+
+func contractCounterFromBytes(in []byte) (byzcoin.Contract, error) {
+	c := &ContractCounter{}
+	err := protobuf.Decode(in, &c.Counter)
+	if err != nil {
+		return nil, xerrors.Errorf("couldn't unmarshal instance data: %v", err)
+	}
+	return c, nil
+}
+
+// Spawn implements the byzcoin.Contract interface.
+func (c *ContractCounter) Spawn(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+	if did := inst.Spawn.Args.Search("darcID"); did != nil {
+		darcID = darc.ID(did)
+	}
+	if inst.Spawn.Args.Search("value") != nil {
+		c.Value, err = uint64Arg(inst.Spawn.Args, "value")
+		if err != nil {
+			return
+		}
+	}
+
+	buf, err := protobuf.Encode(&c.Counter)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("couldn't encode counter: %v", err)
+	}
+	sc = []byzcoin.StateChange{
+		byzcoin.NewStateChange(byzcoin.Create, inst.DeriveID(""),
+			ContractCounterID, buf, darcID),
+	}
+	return
+}
+
+// Invoke implements the byzcoin.Contract interface.
+func (c *ContractCounter) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+
+	if inst.Invoke.Command != "increment" {
+		return nil, nil, xerrors.New("counter contract can only increment")
+	}
+	delta := uint64(1)
+	if inst.Invoke.Args.Search("delta") != nil {
+		delta, err = uint64Arg(inst.Invoke.Args, "delta")
+		if err != nil {
+			return
+		}
+	}
+	if c.Value+delta < c.Value {
+		return nil, nil, xerrors.New("counter overflow")
+	}
+	c.Value += delta
+
+	buf, err := protobuf.Encode(&c.Counter)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("couldn't encode counter: %v", err)
+	}
+	sc = []byzcoin.StateChange{
+		byzcoin.NewStateChange(byzcoin.Update, inst.InstanceID,
+			ContractCounterID, buf, darcID),
+	}
+	return
+}
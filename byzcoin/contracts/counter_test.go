@@ -0,0 +1,68 @@
+package contracts
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/cothority/v3/darc/expression"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/protobuf"
+)
+
+// Two clients increment the same counter in the same block.
+func TestCounter_Concurrent(t *testing.T) {
+	local := onet.NewTCPTest(cothority.Suite)
+	defer local.CloseAll()
+
+	signer1 := darc.NewSignerEd25519(nil, nil)
+	signer2 := darc.NewSignerEd25519(nil, nil)
+	_, roster, _ := local.GenTree(3, true)
+
+	genesisMsg, err := byzcoin.DefaultGenesisMsg(byzcoin.CurrentVersion, roster,
+		[]string{"spawn:counter"}, signer1.Identity())
+	require.NoError(t, err)
+	gDarc := &genesisMsg.GenesisDarc
+	require.NoError(t, gDarc.Rules.AddRule("invoke:counter.increment",
+		expression.InitOrExpr(signer1.Identity().String(), signer2.Identity().String())))
+	genesisMsg.BlockInterval = time.Second
+
+	cl, _, err := byzcoin.NewLedger(genesisMsg, false)
+	require.NoError(t, err)
+
+	ctx, err := cl.CreateTransaction(byzcoin.Instruction{
+		InstanceID: byzcoin.NewInstanceID(gDarc.GetBaseID()),
+		Spawn: &byzcoin.Spawn{
+			ContractID: ContractCounterID,
+		},
+		SignerCounter: []uint64{1},
+	})
+	require.NoError(t, err)
+	require.NoError(t, ctx.FillSignersAndSignWith(signer1))
+	_, err = cl.AddTransactionAndWait(ctx, 10)
+	require.NoError(t, err)
+	counterID := ctx.Instructions[0].DeriveID("")
+
+	for i, s := range []darc.Signer{signer1, signer2} {
+		ctx, err := cl.CreateTransaction(byzcoin.Instruction{
+			InstanceID: counterID,
+			Invoke: &byzcoin.Invoke{
+				ContractID: ContractCounterID,
+				Command:    "increment",
+			},
+			SignerCounter: []uint64{uint64(2 - i)},
+		})
+		require.NoError(t, err)
+		require.NoError(t, ctx.FillSignersAndSignWith(s))
+		_, err = cl.AddTransaction(ctx)
+		require.NoError(t, err)
+	}
+
+	expected, err := protobuf.Encode(&Counter{Value: 2})
+	require.NoError(t, err)
+	_, err = cl.WaitProof(counterID, 2*genesisMsg.BlockInterval, expected)
+	require.NoError(t, err)
+}
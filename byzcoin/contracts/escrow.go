@@ -0,0 +1,233 @@
+package contracts
+
+import (
+	"encoding/binary"
+	"math"
+
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/protobuf"
+	"golang.org/x/xerrors"
+)
+
+// ContractEscrowID denotes a contract that locks coins until they are
+// released to a beneficiary or refunded to the depositor.
+const ContractEscrowID = "escrow"
+
+// ContractEscrow locks coins of a depositor for a beneficiary. The coins are
+// paid out when both the depositor and the beneficiary sign a release, when
+// the arbiter decides, or they are refunded to the depositor once the
+// timeout has passed.
+//
+// Spawn takes the coins given to the instruction, which must all be of the
+// same type, usually from a previous "fetch" on a coin instance. The
+// following arguments are used:
+//  - depositor is the identity of the depositor, by default the first
+//    signer of the instruction
+//  - beneficiary is the identity of the beneficiary
+//  - arbiter is the optional identity of the arbiter
+//  - refund is the coin instance of the depositor
+//  - payout is the coin instance of the beneficiary
+//  - timeout is the number of blocks, as a 64-bit uint in LittleEndian,
+//    after which the depositor can get the coins back
+//  - darcID is optional and defines the darc of the escrow, which must
+//    allow the parties to invoke the commands
+//
+// The identities are given as strings, as returned by darc.Identity.String.
+// The following methods are available, and all of them remove the escrow:
+//  - release pays the coins to the beneficiary. It must be signed by the
+//    depositor and the beneficiary.
+//  - decide must be signed by the arbiter, and pays the coins to the
+//    beneficiary if the argument "to" is "beneficiary", or to the depositor
+//    if it is "depositor".
+//  - refund pays the coins back to the depositor once the timeout has
+//    passed.
+type ContractEscrow struct {
+	byzcoin.BasicContract
+	txSignatures
+	Escrow
+}
+
+// Escrow is the data stored in an escrow instance.
+type Escrow struct {
+	// Coins are the locked coins.
+	Coins       byzcoin.Coin
+	Depositor   string
+	Beneficiary string
+	Arbiter     string
+	// Refund is the coin instance of the depositor.
+	Refund byzcoin.InstanceID
+	// Payout is the coin instance of the beneficiary.
+	Payout byzcoin.InstanceID
+	// Timeout is the index of the block from which the coins can be
+	// refunded.
+	Timeout int
+}
+
+func contractEscrowFromBytes(in []byte) (byzcoin.Contract, error) {
+	c := &ContractEscrow{}
+	err := protobuf.Decode(in, &c.Escrow)
+	if err != nil {
+		return nil, xerrors.Errorf("couldn't unmarshal instance data: %v", err)
+	}
+	return c, nil
+}
+
+// Spawn implements the byzcoin.Contract interface.
+func (c *ContractEscrow) Spawn(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+	if did := inst.Spawn.Args.Search("darcID"); did != nil {
+		darcID = darc.ID(did)
+	}
+
+	c.Coins, err = sumCoins(coins)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("locked coins: %v", err)
+	}
+
+	c.Depositor = string(inst.Spawn.Args.Search("depositor"))
+	if c.Depositor == "" && len(inst.SignerIdentities) > 0 {
+		c.Depositor = inst.SignerIdentities[0].String()
+	}
+	c.Beneficiary = string(inst.Spawn.Args.Search("beneficiary"))
+	c.Arbiter = string(inst.Spawn.Args.Search("arbiter"))
+	if c.Depositor == "" || c.Beneficiary == "" {
+		return nil, nil, xerrors.New("need a depositor and a beneficiary")
+	}
+	for _, id := range []string{c.Depositor, c.Beneficiary, c.Arbiter} {
+		if id == "" {
+			continue
+		}
+		if _, err = darc.ParseIdentity(id); err != nil {
+			return nil, nil, xerrors.Errorf("parsing identity: %v", err)
+		}
+	}
+
+	c.Refund = byzcoin.NewInstanceID(inst.Spawn.Args.Search("refund"))
+	c.Payout = byzcoin.NewInstanceID(inst.Spawn.Args.Search("payout"))
+	for _, id := range []byzcoin.InstanceID{c.Refund, c.Payout} {
+		var co byzcoin.Coin
+		co, _, err = getCoin(rst, id.Slice())
+		if err != nil {
+			return nil, nil, xerrors.Errorf("instance %x: %v", id[:], err)
+		}
+		if !co.Name.Equal(c.Coins.Name) {
+			return nil, nil, xerrors.Errorf("instance %x holds another type of coin", id[:])
+		}
+	}
+
+	timeout := inst.Spawn.Args.Search("timeout")
+	if len(timeout) != 8 {
+		return nil, nil, xerrors.New("argument \"timeout\" is missing or wrong length")
+	}
+	blocks := binary.LittleEndian.Uint64(timeout)
+	if blocks > uint64(math.MaxInt32-rst.GetIndex()) {
+		return nil, nil, xerrors.New("timeout is too big")
+	}
+	c.Timeout = rst.GetIndex() + int(blocks)
+
+	buf, err := protobuf.Encode(&c.Escrow)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("couldn't encode escrow: %v", err)
+	}
+	log.Lvlf2("Locking %d coins until block %d", c.Coins.Value, c.Timeout)
+	sc = []byzcoin.StateChange{
+		byzcoin.NewStateChange(byzcoin.Create, inst.DeriveID(""),
+			ContractEscrowID, buf, darcID),
+	}
+	return
+}
+
+// VerifyInstruction implements the byzcoin.Contract interface, and keeps the
+// hash of the transaction to check the signatures of the parties.
+func (c *ContractEscrow) VerifyInstruction(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, ctxHash []byte) error {
+	c.ctxHash = ctxHash
+	return c.BasicContract.VerifyInstruction(rst, inst, ctxHash)
+}
+
+// Invoke implements the byzcoin.Contract interface.
+func (c *ContractEscrow) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+
+	var to byzcoin.InstanceID
+	switch inst.Invoke.Command {
+	case "release":
+		if !c.signedBy(inst, c.Depositor) || !c.signedBy(inst, c.Beneficiary) {
+			return nil, nil, xerrors.New("release must be signed by the depositor and the beneficiary")
+		}
+		to = c.Payout
+	case "decide":
+		if c.Arbiter == "" || !c.signedBy(inst, c.Arbiter) {
+			return nil, nil, xerrors.New("decision must be signed by the arbiter")
+		}
+		switch string(inst.Invoke.Args.Search("to")) {
+		case "beneficiary":
+			to = c.Payout
+		case "depositor":
+			to = c.Refund
+		default:
+			return nil, nil, xerrors.New("argument \"to\" must be beneficiary or depositor")
+		}
+	case "refund":
+		if rst.GetIndex() < c.Timeout {
+			return nil, nil, xerrors.Errorf("cannot refund before block %d", c.Timeout)
+		}
+		to = c.Refund
+	default:
+		return nil, nil, xerrors.New("escrow contract can only release, decide or refund")
+	}
+
+	sc, err = addCoins(rst, to, c.Coins)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("paying out: %v", err)
+	}
+	sc = append(sc, byzcoin.NewStateChange(byzcoin.Remove, inst.InstanceID,
+		ContractEscrowID, nil, darcID))
+	return
+}
+
+// txSignatures holds the hash of the transaction given to
+// VerifyInstruction. byzcoin evaluates the darc with the identities whose
+// signature is valid, but passes all the identities of the instruction to
+// the contract, so a contract acting on who signed must check the
+// signatures itself.
+type txSignatures struct {
+	ctxHash []byte
+}
+
+// signedBy returns true if the identity is one of the signers of the
+// instruction, and its signature on the transaction is valid.
+func (ts txSignatures) signedBy(inst byzcoin.Instruction, id string) bool {
+	for _, signer := range ts.signers(inst) {
+		if signer.String() == id {
+			return true
+		}
+	}
+	return false
+}
+
+// signers returns the signers of the instruction whose signature on the
+// transaction is valid.
+func (ts txSignatures) signers(inst byzcoin.Instruction) []darc.Identity {
+	if ts.ctxHash == nil {
+		return nil
+	}
+	var out []darc.Identity
+	for i, signer := range inst.SignerIdentities {
+		if i < len(inst.Signatures) && signer.Verify(ts.ctxHash, inst.Signatures[i]) == nil {
+			out = append(out, signer)
+		}
+	}
+	return out
+}
@@ -0,0 +1,163 @@
+package contracts
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/protobuf"
+)
+
+func TestEscrow(t *testing.T) {
+	ct := newCT("spawn:escrow", "invoke:escrow.release",
+		"invoke:escrow.decide", "invoke:escrow.refund")
+	depositor := darc.NewSignerEd25519(nil, nil)
+	beneficiary := darc.NewSignerEd25519(nil, nil)
+	arbiter := darc.NewSignerEd25519(nil, nil)
+
+	refund, payout := iid("refund"), iid("payout")
+	for _, id := range []byzcoin.InstanceID{refund, payout} {
+		buf, err := protobuf.Encode(&byzcoin.Coin{Name: CoinName})
+		require.NoError(t, err)
+		ct.Store(id, buf, ContractCoinID, gdarc.GetBaseID())
+	}
+
+	timeout := make([]byte, 8)
+	binary.LittleEndian.PutUint64(timeout, 10)
+	spawn := byzcoin.Instruction{
+		InstanceID: byzcoin.NewInstanceID(gdarc.GetBaseID()),
+		Spawn: &byzcoin.Spawn{
+			ContractID: ContractEscrowID,
+			Args: byzcoin.Arguments{
+				{Name: "beneficiary", Value: []byte(beneficiary.Identity().String())},
+				{Name: "arbiter", Value: []byte(arbiter.Identity().String())},
+				{Name: "refund", Value: refund.Slice()},
+				{Name: "payout", Value: payout.Slice()},
+				{Name: "timeout", Value: timeout},
+			},
+		},
+		SignerIdentities: []darc.Identity{depositor.Identity()},
+		SignerCounter:    []uint64{1},
+	}
+	c, _ := contractEscrowFromBytes(nil)
+	_, _, err := c.Spawn(ct, spawn, nil)
+	require.Error(t, err)
+	sc, _, err := c.Spawn(ct, spawn, []byzcoin.Coin{{Name: CoinName, Value: 5}})
+	require.NoError(t, err)
+	escrowID := spawn.DeriveID("")
+	ct.Store(escrowID, sc[0].Value, ContractEscrowID, gdarc.GetBaseID())
+
+	invokeForged := func(cmd string, to string, forged []darc.Signer, signers ...darc.Signer) (byzcoin.StateChanges, error) {
+		inst := byzcoin.Instruction{
+			InstanceID: escrowID,
+			Invoke: &byzcoin.Invoke{
+				ContractID: ContractEscrowID,
+				Command:    cmd,
+				Args:       byzcoin.Arguments{{Name: "to", Value: []byte(to)}},
+			},
+		}
+		signTx(t, &inst, signers...)
+		forgeSigners(&inst, forged...)
+		e, err := contractEscrowFromBytes(ct.values[string(escrowID.Slice())])
+		require.NoError(t, err)
+		e.(*ContractEscrow).ctxHash = testCtxHash
+		sc, _, err := e.Invoke(ct, inst, nil)
+		return sc, err
+	}
+	invoke := func(cmd string, to string, signers ...darc.Signer) (byzcoin.StateChanges, error) {
+		return invokeForged(cmd, to, nil, signers...)
+	}
+	paidTo := func(sc byzcoin.StateChanges, id byzcoin.InstanceID) {
+		require.Equal(t, 2, len(sc))
+		require.Equal(t, id.Slice(), sc[0].InstanceID)
+		var co byzcoin.Coin
+		require.NoError(t, protobuf.Decode(sc[0].Value, &co))
+		require.Equal(t, uint64(5), co.Value)
+		require.Equal(t, byzcoin.Remove, sc[1].StateAction)
+	}
+
+	// Release needs both parties, and their signatures must be valid.
+	_, err = invoke("release", "", depositor)
+	require.Error(t, err)
+	_, err = invokeForged("release", "", []darc.Signer{depositor}, beneficiary)
+	require.Error(t, err)
+	sc, err = invoke("release", "", depositor, beneficiary)
+	require.NoError(t, err)
+	paidTo(sc, payout)
+
+	// Only the arbiter can decide.
+	_, err = invoke("decide", "depositor", beneficiary)
+	require.Error(t, err)
+	_, err = invokeForged("decide", "beneficiary", []darc.Signer{arbiter}, beneficiary)
+	require.Error(t, err)
+	_, err = invoke("decide", "nobody", arbiter)
+	require.Error(t, err)
+	sc, err = invoke("decide", "depositor", arbiter)
+	require.NoError(t, err)
+	paidTo(sc, refund)
+
+	// Refund only after the timeout.
+	_, err = invoke("refund", "", depositor)
+	require.Error(t, err)
+	for i := 0; i < 10; i++ {
+		ct.Store(iid("dummy"), nil, "", nil)
+	}
+	sc, err = invoke("refund", "", depositor)
+	require.NoError(t, err)
+	paidTo(sc, refund)
+}
+
+func TestEscrow_Timeout(t *testing.T) {
+	ct := newCT("spawn:escrow")
+	depositor := darc.NewSignerEd25519(nil, nil)
+	beneficiary := darc.NewSignerEd25519(nil, nil)
+	buf, err := protobuf.Encode(&byzcoin.Coin{Name: CoinName})
+	require.NoError(t, err)
+	ct.Store(iid("coins"), buf, ContractCoinID, gdarc.GetBaseID())
+
+	// A timeout that overflows the block index would allow an immediate
+	// refund.
+	timeout := make([]byte, 8)
+	binary.LittleEndian.PutUint64(timeout, ^uint64(0))
+	spawn := byzcoin.Instruction{
+		InstanceID: byzcoin.NewInstanceID(gdarc.GetBaseID()),
+		Spawn: &byzcoin.Spawn{
+			ContractID: ContractEscrowID,
+			Args: byzcoin.Arguments{
+				{Name: "depositor", Value: []byte(depositor.Identity().String())},
+				{Name: "beneficiary", Value: []byte(beneficiary.Identity().String())},
+				{Name: "refund", Value: iid("coins").Slice()},
+				{Name: "payout", Value: iid("coins").Slice()},
+				{Name: "timeout", Value: timeout},
+			},
+		},
+	}
+	c, _ := contractEscrowFromBytes(nil)
+	_, _, err = c.Spawn(ct, spawn, []byzcoin.Coin{{Name: CoinName, Value: 5}})
+	require.Error(t, err)
+}
+
+// testCtxHash is the hash of the transaction signed in the tests.
+var testCtxHash = []byte("hash of the transaction")
+
+// signTx adds the signatures of the signers on testCtxHash to the
+// instruction.
+func signTx(t *testing.T, inst *byzcoin.Instruction, signers ...darc.Signer) {
+	for _, s := range signers {
+		sig, err := s.Sign(testCtxHash)
+		require.NoError(t, err)
+		inst.SignerIdentities = append(inst.SignerIdentities, s.Identity())
+		inst.Signatures = append(inst.Signatures, sig)
+	}
+}
+
+// forgeSigners adds the identities to the instruction, with signatures that
+// are not valid.
+func forgeSigners(inst *byzcoin.Instruction, forged ...darc.Signer) {
+	for _, s := range forged {
+		inst.SignerIdentities = append(inst.SignerIdentities, s.Identity())
+		inst.Signatures = append(inst.Signatures, make([]byte, 64))
+	}
+}
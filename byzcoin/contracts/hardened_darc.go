@@ -0,0 +1,172 @@
+package contracts
+
+import (
+	"bytes"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/cothority/v3/darc/expression"
+	"golang.org/x/xerrors"
+)
+
+// ContractHardenedDarcID denotes a darc-contract that can be used instead of
+// the insecure_darc contract outside of tests.
+const ContractHardenedDarcID = "hardened_darc"
+
+// ContractHardenedDarc is a spawnable darc-contract with the following
+// checks:
+//  - every evolution must increase the version by one and point to the
+//    previous version
+//  - all expressions of the rules must be valid, and the "_evolve" and
+//    "_sign" rules cannot be removed
+//  - the evolve command can only change or remove existing rules, new rules
+//    can only be added with the evolve_unrestricted command, which itself
+//    cannot be changed by the evolve command
+//  - a hardened darc can never hold the spawn:insecure_darc rule
+//
+// To use it, "hardened_darc" must be added to the DarcContractIDs of the
+// chain configuration. Existing insecure_darc instances can be moved to this
+// contract with the "migrate" command of the insecure_darc contract.
+type ContractHardenedDarc struct {
+	byzcoin.BasicContract
+	darc.Darc
+	contracts byzcoin.ReadOnlyContractRegistry
+}
+
+var _ byzcoin.Contract = (*ContractHardenedDarc)(nil)
+
+const cmdHardenedDarcEvolve = "evolve"
+const cmdHardenedDarcEvolveUnrestricted = "evolve_unrestricted"
+
+func contractHardenedDarcFromBytes(in []byte) (byzcoin.Contract, error) {
+	d, err := darc.NewFromProtobuf(in)
+	if err != nil {
+		return nil, xerrors.Errorf("darc decoding: %v", err)
+	}
+	c := &ContractHardenedDarc{Darc: *d}
+	return c, nil
+}
+
+// SetRegistry keeps the reference of the contract registry.
+func (c *ContractHardenedDarc) SetRegistry(r byzcoin.ReadOnlyContractRegistry) {
+	c.contracts = r
+}
+
+// Spawn implements the byzcoin.Contract interface.
+func (c *ContractHardenedDarc) Spawn(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	if inst.Spawn.ContractID == ContractHardenedDarcID {
+		darcBuf := inst.Spawn.Args.Search("darc")
+		d, err := darc.NewFromProtobuf(darcBuf)
+		if err != nil {
+			return nil, nil, xerrors.Errorf("given darc could not be decoded: %v", err)
+		}
+		if d.Version != 0 {
+			return nil, nil, xerrors.New("DARC version must start at 0")
+		}
+		if err := checkHardenedRules(d); err != nil {
+			return nil, nil, xerrors.Errorf("checking rules: %v", err)
+		}
+		id := d.GetBaseID()
+		return []byzcoin.StateChange{
+			byzcoin.NewStateChange(byzcoin.Create, byzcoin.NewInstanceID(id),
+				ContractHardenedDarcID, darcBuf, id),
+		}, coins, nil
+	}
+
+	// If we got here this is a spawn:xxx in order to spawn
+	// a new instance of contract xxx, so do that.
+
+	if c.contracts == nil {
+		return nil, nil, xerrors.New("contracts registry is missing due to bad initialization")
+	}
+
+	cfact, found := c.contracts.Search(inst.Spawn.ContractID)
+	if !found {
+		return nil, nil, xerrors.New("couldn't find this contract type: " + inst.Spawn.ContractID)
+	}
+
+	c2, err := cfact(nil)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("could not spawn new zero instance: %v", err)
+	}
+	if cwr, ok := c2.(byzcoin.ContractWithRegistry); ok {
+		cwr.SetRegistry(c.contracts)
+	}
+
+	scs, coins, err := c2.Spawn(rst, inst, coins)
+	return scs, coins, cothority.ErrorOrNil(err, "spawn instance")
+}
+
+// Invoke implements the byzcoin.Contract interface.
+func (c *ContractHardenedDarc) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return nil, nil, xerrors.Errorf("reading trie: %v", err)
+	}
+
+	switch inst.Invoke.Command {
+	case cmdHardenedDarcEvolve, cmdHardenedDarcEvolveUnrestricted:
+	default:
+		return nil, nil, xerrors.New("invalid command: " + inst.Invoke.Command)
+	}
+
+	darcBuf := inst.Invoke.Args.Search("darc")
+	newD, err := darc.NewFromProtobuf(darcBuf)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("darc decoding: %v", err)
+	}
+	if err = checkHardenedEvolution(&c.Darc, newD); err != nil {
+		return nil, nil, xerrors.Errorf("checking evolution: %v", err)
+	}
+
+	if inst.Invoke.Command == cmdHardenedDarcEvolve {
+		action := darc.Action("invoke:" + ContractHardenedDarcID + "." +
+			cmdHardenedDarcEvolveUnrestricted)
+		if !bytes.Equal(c.Rules.Get(action), newD.Rules.Get(action)) {
+			return nil, nil, xerrors.New("the evolve command is not allowed to change the evolve_unrestricted rule")
+		}
+		if !newD.Rules.IsSubset(c.Rules) {
+			return nil, nil, xerrors.New("the evolve command cannot add new rules")
+		}
+	}
+
+	return []byzcoin.StateChange{
+		byzcoin.NewStateChange(byzcoin.Update, inst.InstanceID,
+			ContractHardenedDarcID, darcBuf, darcID),
+	}, coins, nil
+}
+
+// checkHardenedEvolution verifies that newD is a valid evolution of oldD for
+// a hardened darc.
+func checkHardenedEvolution(oldD, newD *darc.Darc) error {
+	if err := newD.SanityCheck(oldD); err != nil {
+		return xerrors.Errorf("sanity check: %v", err)
+	}
+	return checkHardenedRules(newD)
+}
+
+// checkHardenedRules verifies that all expressions of the darc can be parsed
+// and that the darc can still be evolved and used to sign.
+func checkHardenedRules(d *darc.Darc) error {
+	for _, action := range []darc.Action{"_evolve", "_sign"} {
+		if len(d.Rules.Get(action)) == 0 {
+			return xerrors.Errorf("missing rule %s", action)
+		}
+	}
+	if d.Rules.Contains("spawn:" + ContractInsecureDarcID) {
+		return xerrors.New("a hardened DARC is not allowed to spawn an insecure DARC")
+	}
+	parser := expression.InitParser(func(string) bool { return true })
+	for _, r := range d.Rules.List {
+		if _, err := expression.Evaluate(parser, r.Expr); err != nil {
+			return xerrors.Errorf("invalid expression for %s: %v", r.Action, err)
+		}
+	}
+	return nil
+}
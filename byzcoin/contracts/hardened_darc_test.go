@@ -0,0 +1,97 @@
+package contracts
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+)
+
+func TestHardenedDarc(t *testing.T) {
+	ct := newCT("spawn:hardened_darc")
+	id := []byte(gsigner.Identity().String())
+
+	spawn := func(d *darc.Darc) (byzcoin.StateChanges, error) {
+		buf, err := d.ToProto()
+		require.NoError(t, err)
+		c, err := contractHardenedDarcFromBytes(nil)
+		require.NoError(t, err)
+		sc, _, err := c.Spawn(ct, byzcoin.Instruction{
+			InstanceID: byzcoin.NewInstanceID(gdarc.GetBaseID()),
+			Spawn: &byzcoin.Spawn{
+				ContractID: ContractHardenedDarcID,
+				Args:       byzcoin.Arguments{{Name: "darc", Value: buf}},
+			},
+		}, nil)
+		return sc, err
+	}
+
+	rules := darc.InitRules([]darc.Identity{gsigner.Identity()},
+		[]darc.Identity{gsigner.Identity()})
+	require.NoError(t, rules.AddRule("invoke:hardened_darc.evolve", id))
+	require.NoError(t, rules.AddRule("invoke:hardened_darc.evolve_unrestricted", id))
+	d := darc.NewDarc(rules, []byte("hardened"))
+
+	// Invalid expressions, missing rules and insecure darcs are refused.
+	bad := d.Copy()
+	require.NoError(t, bad.Rules.AddRule("spawn:value", []byte("ed25519:(")))
+	_, err := spawn(bad)
+	require.Error(t, err)
+	bad = d.Copy()
+	require.NoError(t, bad.Rules.DeleteRules("_sign"))
+	_, err = spawn(bad)
+	require.Error(t, err)
+	bad = d.Copy()
+	require.NoError(t, bad.Rules.AddRule("spawn:insecure_darc", id))
+	_, err = spawn(bad)
+	require.Error(t, err)
+
+	sc, err := spawn(d)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(sc))
+	ct.Store(byzcoin.NewInstanceID(d.GetBaseID()), sc[0].Value,
+		ContractHardenedDarcID, d.GetBaseID())
+
+	evolve := func(cmd string, newD *darc.Darc) error {
+		buf, err := newD.ToProto()
+		require.NoError(t, err)
+		c, err := contractHardenedDarcFromBytes(ct.values[string(d.GetBaseID())])
+		require.NoError(t, err)
+		_, _, err = c.Invoke(ct, byzcoin.Instruction{
+			InstanceID: byzcoin.NewInstanceID(d.GetBaseID()),
+			Invoke: &byzcoin.Invoke{
+				ContractID: ContractHardenedDarcID,
+				Command:    cmd,
+				Args:       byzcoin.Arguments{{Name: "darc", Value: buf}},
+			},
+		}, nil)
+		return err
+	}
+
+	// The version must increase by one.
+	d2 := d.Copy()
+	require.NoError(t, d2.EvolveFrom(d))
+	d2.Version++
+	require.Error(t, evolve("evolve", d2))
+	d2.Version--
+
+	// New rules need evolve_unrestricted.
+	require.NoError(t, d2.Rules.AddRule("spawn:value", id))
+	require.Error(t, evolve("evolve", d2))
+	require.NoError(t, evolve("evolve_unrestricted", d2))
+
+	// evolve cannot change evolve_unrestricted.
+	d2 = d.Copy()
+	require.NoError(t, d2.EvolveFrom(d))
+	require.NoError(t, d2.Rules.UpdateRule("invoke:hardened_darc.evolve_unrestricted",
+		[]byte(darc.NewSignerEd25519(nil, nil).Identity().String())))
+	require.Error(t, evolve("evolve", d2))
+
+	// Changing an existing rule is fine.
+	d2 = d.Copy()
+	require.NoError(t, d2.EvolveFrom(d))
+	require.NoError(t, d2.Rules.UpdateRule("invoke:hardened_darc.evolve",
+		[]byte(darc.NewSignerEd25519(nil, nil).Identity().String())))
+	require.NoError(t, evolve("evolve", d2))
+}
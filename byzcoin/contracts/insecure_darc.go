@@ -6,7 +6,9 @@ import (
 	"golang.org/x/xerrors"
 )
 
-// ContractInsecureDarcID denotes a darc-contract
+// ContractInsecureDarcID denotes a darc-contract. It should only be used in
+// tests, use ContractHardenedDarcID otherwise. Instances can be moved to the
+// hardened_darc contract with the "migrate" command.
 const ContractInsecureDarcID = "insecure_darc"
 
 type contractInsecureDarc struct {
@@ -96,6 +98,31 @@ func (c *contractInsecureDarc) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoi
 		return []byzcoin.StateChange{
 			byzcoin.NewStateChange(byzcoin.Update, inst.InstanceID, ContractInsecureDarcID, darcBuf, darcID),
 		}, coins, nil
+	case "migrate":
+		// Moves this instance to the hardened_darc contract. The new
+		// version of the darc must pass all the checks of a hardened
+		// darc.
+		var darcID darc.ID
+		_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+		if err != nil {
+			return
+		}
+
+		darcBuf := inst.Invoke.Args.Search("darc")
+		newD, err := darc.NewFromProtobuf(darcBuf)
+		if err != nil {
+			return nil, nil, xerrors.Errorf("darc decoding: %v", err)
+		}
+		oldD, err := byzcoin.LoadDarcFromTrie(rst, darcID)
+		if err != nil {
+			return nil, nil, xerrors.Errorf("darc from trie: %v", err)
+		}
+		if err := checkHardenedEvolution(oldD, newD); err != nil {
+			return nil, nil, xerrors.Errorf("checking evolution: %v", err)
+		}
+		return []byzcoin.StateChange{
+			byzcoin.NewStateChange(byzcoin.Update, inst.InstanceID, ContractHardenedDarcID, darcBuf, darcID),
+		}, coins, nil
 	default:
 		return nil, nil, xerrors.New("invalid command: " + inst.Invoke.Command)
 	}
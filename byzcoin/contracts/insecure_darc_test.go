@@ -21,7 +21,8 @@ func TestInsecureDarc(t *testing.T) {
 	genesisMsg, err := byzcoin.DefaultGenesisMsg(byzcoin.CurrentVersion, roster,
 		[]string{"spawn:insecure_darc"}, signer.Identity())
 	require.Nil(t, err)
-	genesisMsg.DarcContractIDs = append(genesisMsg.DarcContractIDs, ContractInsecureDarcID)
+	genesisMsg.DarcContractIDs = append(genesisMsg.DarcContractIDs,
+		ContractInsecureDarcID, ContractHardenedDarcID)
 	gDarc := &genesisMsg.GenesisDarc
 	genesisMsg.BlockInterval = time.Second
 	cl, _, err := byzcoin.NewLedger(genesisMsg, false)
@@ -75,6 +76,7 @@ func TestInsecureDarc(t *testing.T) {
 	newDarc2 := newDarc.Copy()
 	require.NoError(t, newDarc2.EvolveFrom(newDarc))
 	newDarc2.Rules.AddRule("spawn:coin", []byte(signer.Identity().String()))
+	newDarc2.Rules.AddRule("invoke:insecure_darc.migrate", []byte(signer.Identity().String()))
 	newDarc2Buf, err := newDarc2.ToProto()
 	ctx, err = cl.CreateTransaction(byzcoin.Instruction{
 		InstanceID: byzcoin.NewInstanceID(newDarc2.GetBaseID()),
@@ -92,4 +94,40 @@ func TestInsecureDarc(t *testing.T) {
 	require.NoError(t, ctx.FillSignersAndSignWith(signer))
 	_, err = cl.AddTransactionAndWait(ctx, 10)
 	require.NoError(t, err)
+
+	// migrate it to a hardened darc, which must not keep the
+	// spawn:insecure_darc rule
+	newDarc3 := newDarc2.Copy()
+	require.NoError(t, newDarc3.EvolveFrom(newDarc2))
+	migrate := func(d *darc.Darc, counter uint64) error {
+		buf, err := d.ToProto()
+		require.NoError(t, err)
+		ctx, err := cl.CreateTransaction(byzcoin.Instruction{
+			InstanceID: byzcoin.NewInstanceID(d.GetBaseID()),
+			Invoke: &byzcoin.Invoke{
+				ContractID: ContractInsecureDarcID,
+				Command:    "migrate",
+				Args: []byzcoin.Argument{{
+					Name:  "darc",
+					Value: buf,
+				}},
+			},
+			SignerCounter: []uint64{counter},
+		})
+		require.NoError(t, err)
+		require.NoError(t, ctx.FillSignersAndSignWith(signer))
+		_, err = cl.AddTransactionAndWait(ctx, 10)
+		return err
+	}
+	require.Error(t, migrate(newDarc3, 3))
+	require.NoError(t, newDarc3.Rules.DeleteRules("spawn:insecure_darc"))
+	require.NoError(t, newDarc3.Rules.AddRule("invoke:hardened_darc.evolve",
+		[]byte(signer.Identity().String())))
+	require.NoError(t, migrate(newDarc3, 3))
+
+	pr, err := cl.GetProof(newDarc3.GetBaseID())
+	require.NoError(t, err)
+	_, _, cid, _, err := pr.Proof.KeyValue()
+	require.NoError(t, err)
+	require.Equal(t, ContractHardenedDarcID, cid)
 }
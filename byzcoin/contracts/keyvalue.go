@@ -0,0 +1,224 @@
+package contracts
+
+import (
+	"encoding/hex"
+	"strings"
+
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/protobuf"
+	"golang.org/x/xerrors"
+)
+
+// ContractKeyValueID denotes a contract storing key/value pairs.
+const ContractKeyValueID = "keyValue"
+
+// ContractKeyValue stores key/value pairs that can be shared by several
+// parties. The darc of the instance defines who can use the commands, and
+// every key can have its own darc, which must also allow the update or the
+// removal of the key. This way, every party keeps the write rights over its
+// own keys.
+//
+// Spawn takes the optional argument "darcID" to define the darc of the
+// instance.
+//
+// The following methods are available:
+//  - add creates the key "key" with the value "value". The optional argument
+//    "keyDarc" is the ID of the darc of the key.
+//  - update replaces the value of the key "key" with "value". If the key has
+//    a darc, its rule "invoke:keyValue.update" must be fulfilled by the
+//    signers of the instruction. The optional argument "keyDarc" replaces the
+//    darc of the key.
+//  - delete removes the key "key". If the key has a darc, its rule
+//    "invoke:keyValue.delete" must be fulfilled by the signers of the
+//    instruction.
+type ContractKeyValue struct {
+	byzcoin.BasicContract
+	txSignatures
+	KeyValueStore
+}
+
+// KeyValueStore is the data stored in a keyValue instance.
+type KeyValueStore struct {
+	Entries []KeyValueEntry
+}
+
+// KeyValueEntry is one key of a keyValue instance.
+type KeyValueEntry struct {
+	Key   string
+	Value []byte
+	// DarcID is the darc of the key, or nil if the key is only governed by
+	// the darc of the instance.
+	DarcID darc.ID
+}
+
+// Get returns the value of the key, or nil if it doesn't exist.
+func (kv KeyValueStore) Get(key string) []byte {
+	if e := kv.entry(key); e != nil {
+		return e.Value
+	}
+	return nil
+}
+
+func (kv *KeyValueStore) entry(key string) *KeyValueEntry {
+	for i := range kv.Entries {
+		if kv.Entries[i].Key == key {
+			return &kv.Entries[i]
+		}
+	}
+	return nil
+}
+
+func contractKeyValueFromBytes(in []byte) (byzcoin.Contract, error) {
+	c := &ContractKeyValue{}
+	err := protobuf.Decode(in, &c.KeyValueStore)
+	if err != nil {
+		return nil, xerrors.Errorf("couldn't unmarshal instance data: %v", err)
+	}
+	return c, nil
+}
+
+// Spawn implements the byzcoin.Contract interface.
+func (c *ContractKeyValue) Spawn(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+	if did := inst.Spawn.Args.Search("darcID"); did != nil {
+		darcID = darc.ID(did)
+	}
+
+	buf, err := protobuf.Encode(&c.KeyValueStore)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("couldn't encode store: %v", err)
+	}
+	sc = []byzcoin.StateChange{
+		byzcoin.NewStateChange(byzcoin.Create, inst.DeriveID(""),
+			ContractKeyValueID, buf, darcID),
+	}
+	return
+}
+
+// VerifyInstruction implements the byzcoin.Contract interface, and keeps the
+// hash of the transaction to check the signers against the darcs of the
+// keys.
+func (c *ContractKeyValue) VerifyInstruction(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, ctxHash []byte) error {
+	c.ctxHash = ctxHash
+	return c.BasicContract.VerifyInstruction(rst, inst, ctxHash)
+}
+
+// Invoke implements the byzcoin.Contract interface.
+func (c *ContractKeyValue) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+
+	args := inst.Invoke.Args
+	key := string(args.Search("key"))
+	if key == "" {
+		return nil, nil, xerrors.New("need a key")
+	}
+	keyDarc := darc.ID(args.Search("keyDarc"))
+	if keyDarc != nil {
+		if _, err = byzcoin.LoadDarcFromTrie(rst, keyDarc); err != nil {
+			return nil, nil, xerrors.Errorf("loading darc of key: %v", err)
+		}
+	}
+
+	e := c.entry(key)
+	switch inst.Invoke.Command {
+	case "add":
+		if e != nil {
+			return nil, nil, xerrors.Errorf("key %s already exists", key)
+		}
+		c.Entries = append(c.Entries, KeyValueEntry{
+			Key:    key,
+			Value:  args.Search("value"),
+			DarcID: keyDarc,
+		})
+	case "update":
+		if e == nil {
+			return nil, nil, xerrors.Errorf("key %s doesn't exist", key)
+		}
+		if err = c.checkKeyDarc(rst, inst, e.DarcID); err != nil {
+			return
+		}
+		e.Value = args.Search("value")
+		if keyDarc != nil {
+			e.DarcID = keyDarc
+		}
+	case "delete":
+		if e == nil {
+			return nil, nil, xerrors.Errorf("key %s doesn't exist", key)
+		}
+		if err = c.checkKeyDarc(rst, inst, e.DarcID); err != nil {
+			return
+		}
+		for i := range c.Entries {
+			if c.Entries[i].Key == key {
+				c.Entries = append(c.Entries[:i], c.Entries[i+1:]...)
+				break
+			}
+		}
+	default:
+		return nil, nil, xerrors.New("keyValue contract can only add, update or delete")
+	}
+	log.Lvlf2("%s key %s", inst.Invoke.Command, key)
+
+	buf, err := protobuf.Encode(&c.KeyValueStore)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("couldn't encode store: %v", err)
+	}
+	sc = []byzcoin.StateChange{
+		byzcoin.NewStateChange(byzcoin.Update, inst.InstanceID,
+			ContractKeyValueID, buf, darcID),
+	}
+	return
+}
+
+// checkKeyDarc verifies that the signers of the instruction with a valid
+// signature fulfill the rule of the darc of a key for the command of the
+// instruction.
+func (c *ContractKeyValue) checkKeyDarc(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, darcID darc.ID) error {
+	if darcID == nil {
+		return nil
+	}
+	d, err := byzcoin.LoadDarcFromTrie(rst, darcID)
+	if err != nil {
+		return xerrors.Errorf("loading darc of key: %v", err)
+	}
+	action := darc.Action(inst.Action())
+	if !d.Rules.Contains(action) {
+		return xerrors.Errorf("darc of key has no rule %s", action)
+	}
+	getDarc := func(str string, latest bool) *darc.Darc {
+		if !strings.HasPrefix(str, "darc:") {
+			return nil
+		}
+		id, err := hex.DecodeString(str[5:])
+		if err != nil {
+			return nil
+		}
+		d, err := byzcoin.LoadDarcFromTrie(rst, id)
+		if err != nil {
+			return nil
+		}
+		return d
+	}
+	var ids []string
+	for _, id := range c.signers(inst) {
+		ids = append(ids, id.String())
+	}
+	if err = d.EvalRule(action, getDarc, nil, 0, ids...); err != nil {
+		return xerrors.Errorf("darc of key: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package contracts
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/cothority/v3/darc/expression"
+	"go.dedis.ch/protobuf"
+)
+
+func TestKeyValue(t *testing.T) {
+	ct := newCT()
+	config, err := protobuf.Encode(&byzcoin.ChainConfig{DarcContractIDs: []string{"darc"}})
+	require.NoError(t, err)
+	ct.Store(byzcoin.ConfigInstanceID, config, "config", gdarc.GetBaseID())
+
+	alice := darc.NewSignerEd25519(nil, nil)
+	bob := darc.NewSignerEd25519(nil, nil)
+	aliceDarc := darc.NewDarc(darc.InitRules([]darc.Identity{alice.Identity()},
+		[]darc.Identity{alice.Identity()}), []byte("alice"))
+	for _, cmd := range []string{"update", "delete"} {
+		require.NoError(t, aliceDarc.Rules.AddRule(darc.Action("invoke:"+ContractKeyValueID+"."+cmd),
+			expression.Expr(alice.Identity().String())))
+	}
+	buf, err := aliceDarc.ToProto()
+	require.NoError(t, err)
+	ct.Store(byzcoin.NewInstanceID(aliceDarc.GetBaseID()), buf, "darc", aliceDarc.GetBaseID())
+
+	spawn := byzcoin.Instruction{
+		InstanceID: byzcoin.NewInstanceID(gdarc.GetBaseID()),
+		Spawn:      &byzcoin.Spawn{ContractID: ContractKeyValueID},
+	}
+	c, err := contractKeyValueFromBytes(nil)
+	require.NoError(t, err)
+	sc, _, err := c.Spawn(ct, spawn, nil)
+	require.NoError(t, err)
+	kvID := spawn.DeriveID("")
+	ct.Store(kvID, sc[0].Value, ContractKeyValueID, gdarc.GetBaseID())
+
+	runForged := func(cmd string, forged []darc.Signer, signer darc.Signer, args ...byzcoin.Argument) error {
+		c, err := contractKeyValueFromBytes(ct.values[string(kvID.Slice())])
+		require.NoError(t, err)
+		c.(*ContractKeyValue).ctxHash = testCtxHash
+		inst := byzcoin.Instruction{
+			InstanceID: kvID,
+			Invoke: &byzcoin.Invoke{
+				ContractID: ContractKeyValueID,
+				Command:    cmd,
+				Args:       args,
+			},
+		}
+		signTx(t, &inst, signer)
+		forgeSigners(&inst, forged...)
+		sc, _, err := c.Invoke(ct, inst, nil)
+		if err != nil {
+			return err
+		}
+		ct.Store(kvID, sc[0].Value, ContractKeyValueID, gdarc.GetBaseID())
+		return nil
+	}
+	run := func(cmd string, signer darc.Signer, args ...byzcoin.Argument) error {
+		return runForged(cmd, nil, signer, args...)
+	}
+	get := func(key string) []byte {
+		var kv KeyValueStore
+		require.NoError(t, protobuf.Decode(ct.values[string(kvID.Slice())], &kv))
+		return kv.Get(key)
+	}
+	arg := func(name, value string) byzcoin.Argument {
+		return byzcoin.Argument{Name: name, Value: []byte(value)}
+	}
+	keyDarc := byzcoin.Argument{Name: "keyDarc", Value: aliceDarc.GetBaseID()}
+
+	require.Error(t, run("add", alice, arg("key", "a"), arg("value", "1"),
+		byzcoin.Argument{Name: "keyDarc", Value: iid("unknown").Slice()}))
+	require.NoError(t, run("add", alice, arg("key", "a"), arg("value", "1"), keyDarc))
+	require.Error(t, run("add", bob, arg("key", "a"), arg("value", "2")))
+	require.NoError(t, run("add", bob, arg("key", "b"), arg("value", "2")))
+
+	// Only alice can change her key, but anybody can change a key without
+	// darc.
+	require.Error(t, run("update", bob, arg("key", "a"), arg("value", "3")))
+	require.Error(t, runForged("update", []darc.Signer{alice}, bob, arg("key", "a"),
+		arg("value", "3")))
+	require.Equal(t, []byte("1"), get("a"))
+	require.NoError(t, run("update", alice, arg("key", "a"), arg("value", "3")))
+	require.Equal(t, []byte("3"), get("a"))
+	require.NoError(t, run("update", alice, arg("key", "b"), arg("value", "4")))
+	require.Equal(t, []byte("4"), get("b"))
+
+	require.Error(t, run("delete", bob, arg("key", "a")))
+	require.NoError(t, run("delete", alice, arg("key", "a")))
+	require.Nil(t, get("a"))
+	require.Error(t, run("delete", alice, arg("key", "a")))
+
+	// Bob gives his key to alice.
+	require.NoError(t, run("update", bob, arg("key", "b"), arg("value", "5"), keyDarc))
+	require.Error(t, run("delete", bob, arg("key", "b")))
+	require.NoError(t, run("delete", alice, arg("key", "b")))
+}
@@ -0,0 +1,355 @@
+package contracts
+
+import (
+	"encoding/binary"
+
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/protobuf"
+	"golang.org/x/xerrors"
+)
+
+// ContractMultiSigID denotes a wallet contract that needs m-of-n approvals
+// to transfer coins.
+const ContractMultiSigID = "multiSig"
+
+// ContractMultiSig is a wallet holding coins of one type. Coins can only be
+// sent out of the wallet once a proposal has been approved by a threshold of
+// the owners. The approvals are collected in the instance, so every owner can
+// approve in a different transaction.
+//
+// Spawn needs the following arguments:
+//  - owner is the identity of an owner, as returned by
+//    darc.Identity.String. This argument is given once for every owner.
+//  - threshold is the number of approvals needed, as a 64-bit uint in
+//    LittleEndian
+//  - coinType is the name of the coins held by the wallet
+//  - darcID is optional and defines the darc of the wallet, which must
+//    allow the owners to invoke the commands
+//
+// The following methods are available:
+//  - deposit stores all coins of the right type given to the instruction.
+//  - propose creates a proposal to send "coins", as a 64-bit uint in
+//    LittleEndian, to the coin instance "destination". The proposal gets
+//    the ID NextID, and is approved by all owners signing the instruction.
+//  - approve adds the approval of all owners signing the instruction to the
+//    proposal with the given "id", as a 64-bit uint in LittleEndian.
+//  - execute sends the coins of the proposal with the given "id", once it
+//    has enough approvals. The proposal is removed.
+//  - cancel removes the proposal with the given "id". It must be signed by
+//    an owner.
+type ContractMultiSig struct {
+	byzcoin.BasicContract
+	txSignatures
+	MultiSig
+}
+
+// MultiSig is the data stored in a multiSig instance.
+type MultiSig struct {
+	// Coins are the coins held by the wallet.
+	Coins byzcoin.Coin
+	// Owners are the identities of the owners.
+	Owners []string
+	// Threshold is the number of approvals needed to execute a proposal.
+	Threshold int
+	// Proposals are the pending proposals.
+	Proposals []MultiSigProposal
+	// NextID is the ID of the next proposal.
+	NextID uint64
+}
+
+// MultiSigProposal is a pending transfer of a multiSig wallet.
+type MultiSigProposal struct {
+	ID          uint64
+	Destination byzcoin.InstanceID
+	Value       uint64
+	// Approvals are the identities of the owners that approved the
+	// proposal.
+	Approvals []string
+}
+
+func contractMultiSigFromBytes(in []byte) (byzcoin.Contract, error) {
+	c := &ContractMultiSig{}
+	err := protobuf.Decode(in, &c.MultiSig)
+	if err != nil {
+		return nil, xerrors.Errorf("couldn't unmarshal instance data: %v", err)
+	}
+	return c, nil
+}
+
+// Spawn implements the byzcoin.Contract interface.
+func (c *ContractMultiSig) Spawn(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+	if did := inst.Spawn.Args.Search("darcID"); did != nil {
+		darcID = darc.ID(did)
+	}
+
+	for _, arg := range inst.Spawn.Args {
+		if arg.Name != "owner" {
+			continue
+		}
+		if _, err = darc.ParseIdentity(string(arg.Value)); err != nil {
+			return nil, nil, xerrors.Errorf("parsing owner: %v", err)
+		}
+		if c.isOwner(string(arg.Value)) {
+			return nil, nil, xerrors.New("duplicate owner")
+		}
+		c.Owners = append(c.Owners, string(arg.Value))
+	}
+	threshold, err := uint64Arg(inst.Spawn.Args, "threshold")
+	if err != nil {
+		return
+	}
+	if threshold == 0 || threshold > uint64(len(c.Owners)) {
+		return nil, nil, xerrors.Errorf("threshold must be between 1 and %d", len(c.Owners))
+	}
+	c.Threshold = int(threshold)
+
+	coinType := inst.Spawn.Args.Search("coinType")
+	if len(coinType) != len(byzcoin.InstanceID{}) {
+		return nil, nil, xerrors.New("coinType needs to be an InstanceID")
+	}
+	c.Coins.Name = byzcoin.NewInstanceID(coinType)
+	if cout, err = c.deposit(coins); err != nil {
+		return
+	}
+
+	buf, err := protobuf.Encode(&c.MultiSig)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("couldn't encode wallet: %v", err)
+	}
+	log.Lvlf2("Creating %d-of-%d wallet", c.Threshold, len(c.Owners))
+	sc = []byzcoin.StateChange{
+		byzcoin.NewStateChange(byzcoin.Create, inst.DeriveID(""),
+			ContractMultiSigID, buf, darcID),
+	}
+	return
+}
+
+// VerifyInstruction implements the byzcoin.Contract interface, and keeps the
+// hash of the transaction to check the signatures of the owners.
+func (c *ContractMultiSig) VerifyInstruction(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, ctxHash []byte) error {
+	c.ctxHash = ctxHash
+	return c.BasicContract.VerifyInstruction(rst, inst, ctxHash)
+}
+
+// Invoke implements the byzcoin.Contract interface.
+func (c *ContractMultiSig) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+
+	switch inst.Invoke.Command {
+	case "deposit":
+		if cout, err = c.deposit(coins); err != nil {
+			return
+		}
+	case "propose":
+		if len(c.owners(inst)) == 0 {
+			return nil, nil, xerrors.New("proposal must be signed by an owner")
+		}
+		p := MultiSigProposal{
+			ID:          c.NextID,
+			Destination: byzcoin.NewInstanceID(inst.Invoke.Args.Search("destination")),
+			Approvals:   c.owners(inst),
+		}
+		p.Value, err = uint64Arg(inst.Invoke.Args, "coins")
+		if err != nil {
+			return
+		}
+		if p.Value == 0 {
+			return nil, nil, xerrors.New("cannot propose to send 0 coins")
+		}
+		if _, _, err = getCoin(rst, p.Destination.Slice()); err != nil {
+			return nil, nil, xerrors.Errorf("destination: %v", err)
+		}
+		c.Proposals = append(c.Proposals, p)
+		c.NextID++
+	case "approve":
+		var p *MultiSigProposal
+		p, err = c.proposal(inst)
+		if err != nil {
+			return
+		}
+		owners := c.owners(inst)
+		if len(owners) == 0 {
+			return nil, nil, xerrors.New("approval must be signed by an owner")
+		}
+		for _, s := range owners {
+			if !contains(p.Approvals, s) {
+				p.Approvals = append(p.Approvals, s)
+			}
+		}
+	case "execute":
+		var p *MultiSigProposal
+		p, err = c.proposal(inst)
+		if err != nil {
+			return
+		}
+		if len(p.Approvals) < c.Threshold {
+			return nil, nil, xerrors.Errorf("proposal has %d of %d approvals",
+				len(p.Approvals), c.Threshold)
+		}
+		if err = c.Coins.SafeSub(p.Value); err != nil {
+			return nil, nil, xerrors.Errorf("wallet: %v", err)
+		}
+		sc, err = addCoins(rst, p.Destination, byzcoin.Coin{Name: c.Coins.Name, Value: p.Value})
+		if err != nil {
+			return nil, nil, xerrors.Errorf("destination: %v", err)
+		}
+		c.removeProposal(p.ID)
+	case "cancel":
+		var p *MultiSigProposal
+		p, err = c.proposal(inst)
+		if err != nil {
+			return
+		}
+		if len(c.owners(inst)) == 0 {
+			return nil, nil, xerrors.New("cancel must be signed by an owner")
+		}
+		c.removeProposal(p.ID)
+	default:
+		return nil, nil, xerrors.New("multiSig contract can only deposit, propose, approve, execute or cancel")
+	}
+
+	buf, err := protobuf.Encode(&c.MultiSig)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("couldn't encode wallet: %v", err)
+	}
+	sc = append(sc, byzcoin.NewStateChange(byzcoin.Update, inst.InstanceID,
+		ContractMultiSigID, buf, darcID))
+	return
+}
+
+// deposit adds the coins of the right type to the wallet, and returns the
+// other coins.
+func (c *ContractMultiSig) deposit(coins []byzcoin.Coin) ([]byzcoin.Coin, error) {
+	var out []byzcoin.Coin
+	for _, co := range coins {
+		if !co.Name.Equal(c.Coins.Name) {
+			out = append(out, co)
+			continue
+		}
+		if err := c.Coins.SafeAdd(co.Value); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// owners returns the owners that signed the instruction with a valid
+// signature.
+func (c *ContractMultiSig) owners(inst byzcoin.Instruction) []string {
+	var out []string
+	for _, id := range c.signers(inst) {
+		if c.isOwner(id.String()) && !contains(out, id.String()) {
+			out = append(out, id.String())
+		}
+	}
+	return out
+}
+
+func (c *ContractMultiSig) isOwner(id string) bool {
+	return contains(c.Owners, id)
+}
+
+// proposal returns the proposal given in the "id" argument.
+func (c *ContractMultiSig) proposal(inst byzcoin.Instruction) (*MultiSigProposal, error) {
+	id, err := uint64Arg(inst.Invoke.Args, "id")
+	if err != nil {
+		return nil, err
+	}
+	for i := range c.Proposals {
+		if c.Proposals[i].ID == id {
+			return &c.Proposals[i], nil
+		}
+	}
+	return nil, xerrors.Errorf("no proposal with id %d", id)
+}
+
+func (c *ContractMultiSig) removeProposal(id uint64) {
+	for i, p := range c.Proposals {
+		if p.ID == id {
+			c.Proposals = append(c.Proposals[:i], c.Proposals[i+1:]...)
+			return
+		}
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+// GetMultiSig returns the verified content of a multiSig wallet.
+func GetMultiSig(cl *byzcoin.Client, id byzcoin.InstanceID) (*MultiSig, error) {
+	pr, err := cl.GetProof(id.Slice())
+	if err != nil {
+		return nil, xerrors.Errorf("getting proof: %v", err)
+	}
+	if err := pr.Proof.Verify(cl.ID); err != nil {
+		return nil, xerrors.Errorf("verifying proof: %v", err)
+	}
+	_, value, cid, _, err := pr.Proof.KeyValue()
+	if err != nil {
+		return nil, xerrors.Errorf("reading proof: %v", err)
+	}
+	if cid != ContractMultiSigID {
+		return nil, xerrors.New("instance is not a multiSig wallet")
+	}
+	var ms MultiSig
+	if err := protobuf.Decode(value, &ms); err != nil {
+		return nil, xerrors.Errorf("decoding wallet: %v", err)
+	}
+	return &ms, nil
+}
+
+// MultiSigProposeInstruction returns the instruction proposing to send coins
+// from the wallet to the destination. The signer counters of the instruction
+// must be set before signing it.
+func MultiSigProposeInstruction(wallet, destination byzcoin.InstanceID, coins uint64) byzcoin.Instruction {
+	coinsBuf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(coinsBuf, coins)
+	return byzcoin.Instruction{
+		InstanceID: wallet,
+		Invoke: &byzcoin.Invoke{
+			ContractID: ContractMultiSigID,
+			Command:    "propose",
+			Args: byzcoin.Arguments{
+				{Name: "destination", Value: destination.Slice()},
+				{Name: "coins", Value: coinsBuf},
+			},
+		},
+	}
+}
+
+// MultiSigInstruction returns the instruction to approve, execute or cancel
+// the proposal with the given id. The signer counters of the instruction must
+// be set before signing it.
+func MultiSigInstruction(wallet byzcoin.InstanceID, command string, id uint64) byzcoin.Instruction {
+	idBuf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(idBuf, id)
+	return byzcoin.Instruction{
+		InstanceID: wallet,
+		Invoke: &byzcoin.Invoke{
+			ContractID: ContractMultiSigID,
+			Command:    command,
+			Args:       byzcoin.Arguments{{Name: "id", Value: idBuf}},
+		},
+	}
+}
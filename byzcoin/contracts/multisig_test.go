@@ -0,0 +1,111 @@
+package contracts
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/protobuf"
+)
+
+func TestMultiSig(t *testing.T) {
+	ct := newCT()
+	owners := []darc.Signer{darc.NewSignerEd25519(nil, nil),
+		darc.NewSignerEd25519(nil, nil), darc.NewSignerEd25519(nil, nil)}
+	stranger := darc.NewSignerEd25519(nil, nil)
+	dest := iid("dest")
+	buf, err := protobuf.Encode(&byzcoin.Coin{Name: CoinName})
+	require.NoError(t, err)
+	ct.Store(dest, buf, ContractCoinID, gdarc.GetBaseID())
+
+	threshold := make([]byte, 8)
+	binary.LittleEndian.PutUint64(threshold, 2)
+	spawn := byzcoin.Instruction{
+		InstanceID: byzcoin.NewInstanceID(gdarc.GetBaseID()),
+		Spawn: &byzcoin.Spawn{
+			ContractID: ContractMultiSigID,
+			Args: byzcoin.Arguments{
+				{Name: "threshold", Value: threshold},
+				{Name: "coinType", Value: CoinName.Slice()},
+			},
+		},
+	}
+	for _, o := range owners {
+		spawn.Spawn.Args = append(spawn.Spawn.Args,
+			byzcoin.Argument{Name: "owner", Value: []byte(o.Identity().String())})
+	}
+	c, err := contractMultiSigFromBytes(nil)
+	require.NoError(t, err)
+	sc, cout, err := c.Spawn(ct, spawn, []byzcoin.Coin{{Name: CoinName, Value: 10}})
+	require.NoError(t, err)
+	require.Equal(t, 0, len(cout))
+	wallet := spawn.DeriveID("")
+	ct.Store(wallet, sc[0].Value, ContractMultiSigID, gdarc.GetBaseID())
+
+	runForged := func(inst byzcoin.Instruction, forged []darc.Signer, signers ...darc.Signer) (byzcoin.StateChanges, error) {
+		signTx(t, &inst, signers...)
+		forgeSigners(&inst, forged...)
+		c, err := contractMultiSigFromBytes(ct.values[string(wallet.Slice())])
+		require.NoError(t, err)
+		c.(*ContractMultiSig).ctxHash = testCtxHash
+		sc, _, err := c.Invoke(ct, inst, nil)
+		if err != nil {
+			return nil, err
+		}
+		ct.Store(wallet, sc[len(sc)-1].Value, ContractMultiSigID, gdarc.GetBaseID())
+		return sc, nil
+	}
+	run := func(inst byzcoin.Instruction, signers ...darc.Signer) (byzcoin.StateChanges, error) {
+		return runForged(inst, nil, signers...)
+	}
+
+	_, err = run(MultiSigProposeInstruction(wallet, dest, 4), stranger)
+	require.Error(t, err)
+	_, err = run(MultiSigProposeInstruction(wallet, dest, 4), owners[0])
+	require.NoError(t, err)
+
+	// An owner cannot add the approvals of the others without their
+	// signatures.
+	_, err = runForged(MultiSigProposeInstruction(wallet, dest, 10),
+		owners[1:], stranger)
+	require.Error(t, err)
+	_, err = runForged(MultiSigInstruction(wallet, "approve", 0), owners[1:], stranger)
+	require.Error(t, err)
+	_, err = runForged(MultiSigInstruction(wallet, "approve", 0), owners[1:], owners[0])
+	require.NoError(t, err)
+
+	// One approval is not enough, and approving twice doesn't count.
+	_, err = run(MultiSigInstruction(wallet, "execute", 0), owners[0])
+	require.Error(t, err)
+	_, err = run(MultiSigInstruction(wallet, "approve", 0), owners[0])
+	require.NoError(t, err)
+	_, err = run(MultiSigInstruction(wallet, "execute", 0), owners[0])
+	require.Error(t, err)
+	_, err = run(MultiSigInstruction(wallet, "approve", 0), stranger)
+	require.Error(t, err)
+
+	_, err = run(MultiSigInstruction(wallet, "approve", 0), owners[2])
+	require.NoError(t, err)
+	sc, err = run(MultiSigInstruction(wallet, "execute", 0), stranger)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(sc))
+	require.Equal(t, dest.Slice(), sc[0].InstanceID)
+	var ms MultiSig
+	require.NoError(t, protobuf.Decode(sc[1].Value, &ms))
+	require.Equal(t, uint64(6), ms.Coins.Value)
+	require.Equal(t, 0, len(ms.Proposals))
+	_, err = run(MultiSigInstruction(wallet, "execute", 0), owners[0])
+	require.Error(t, err)
+
+	// Proposals can be cancelled by an owner.
+	_, err = run(MultiSigProposeInstruction(wallet, dest, 20), owners[1], owners[2])
+	require.NoError(t, err)
+	_, err = run(MultiSigInstruction(wallet, "execute", 1), owners[1])
+	require.Error(t, err)
+	_, err = run(MultiSigInstruction(wallet, "cancel", 1), stranger)
+	require.Error(t, err)
+	_, err = run(MultiSigInstruction(wallet, "cancel", 1), owners[0])
+	require.NoError(t, err)
+}
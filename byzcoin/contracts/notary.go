@@ -0,0 +1,345 @@
+package contracts
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/cothority/v3/skipchain"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/protobuf"
+	"golang.org/x/xerrors"
+)
+
+// ContractNotaryID denotes a contract that anchors hashes of external
+// documents.
+const ContractNotaryID = "notary"
+
+// ContractNotaryBatchID denotes a batch of hashes anchored by a notary.
+const ContractNotaryBatchID = "notaryBatch"
+
+// ContractNotary anchors batches of hashes of external documents. Only the
+// root of the Merkle tree of a batch is stored, together with the timestamp
+// of the block, so that any number of hashes can be anchored in a single
+// instance. The existence of a hash is then proven by a NotaryProof, which
+// holds the byzcoin proof of the batch and the Merkle path of the hash.
+//
+// Spawn takes the optional argument "darcID" to define the darc of the
+// notary, which defines who can notarize hashes.
+//
+// The following method is available:
+//  - notarize anchors all the "hash" arguments, which must be 32 bytes long.
+//    The batch is stored in the child of the notary named after the
+//    hex-encoded root of the batch. The same batch cannot be notarized twice.
+//
+// Clients use NotarizeInstruction to notarize hashes, and GetNotaryProof to
+// get the existence proof of a hash.
+type ContractNotary struct {
+	byzcoin.BasicContract
+	Notary
+}
+
+// Notary is the data stored in a notary instance.
+type Notary struct {
+	// Batches is the number of batches anchored by the notary.
+	Batches uint64
+}
+
+// NotaryBatch is the data stored in a notaryBatch instance.
+type NotaryBatch struct {
+	// Root is the root of the Merkle tree of the hashes, as returned by
+	// NotaryRoot.
+	Root []byte
+	// Count is the number of hashes in the batch.
+	Count uint64
+	// Timestamp is the timestamp of the latest block when the batch was
+	// anchored, in Unix nanoseconds.
+	Timestamp int64
+}
+
+func contractNotaryFromBytes(in []byte) (byzcoin.Contract, error) {
+	c := &ContractNotary{}
+	err := protobuf.Decode(in, &c.Notary)
+	if err != nil {
+		return nil, xerrors.Errorf("couldn't unmarshal instance data: %v", err)
+	}
+	return c, nil
+}
+
+// Spawn implements the byzcoin.Contract interface.
+func (c *ContractNotary) Spawn(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+	if did := inst.Spawn.Args.Search("darcID"); did != nil {
+		darcID = darc.ID(did)
+	}
+
+	buf, err := protobuf.Encode(&c.Notary)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("couldn't encode notary: %v", err)
+	}
+	sc = []byzcoin.StateChange{
+		byzcoin.NewStateChange(byzcoin.Create, inst.DeriveID(""),
+			ContractNotaryID, buf, darcID),
+	}
+	return
+}
+
+// Invoke implements the byzcoin.Contract interface.
+func (c *ContractNotary) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+
+	if inst.Invoke.Command != "notarize" {
+		return nil, nil, xerrors.New("notary contract can only notarize")
+	}
+	var hashes [][]byte
+	for _, arg := range inst.Invoke.Args {
+		if arg.Name != "hash" {
+			continue
+		}
+		if len(arg.Value) != sha256.Size {
+			return nil, nil, xerrors.Errorf("hash %d must be %d bytes long",
+				len(hashes), sha256.Size)
+		}
+		hashes = append(hashes, arg.Value)
+	}
+	if len(hashes) == 0 {
+		return nil, nil, xerrors.New("need at least one hash")
+	}
+
+	batch := NotaryBatch{
+		Root:  NotaryRoot(hashes),
+		Count: uint64(len(hashes)),
+	}
+	batch.Timestamp, err = chainTime(rst)
+	if err != nil {
+		return
+	}
+	batchBuf, err := protobuf.Encode(&batch)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("couldn't encode batch: %v", err)
+	}
+	batchSC, err := byzcoin.NewChildStateChange(rst, byzcoin.Create, inst.InstanceID,
+		hex.EncodeToString(batch.Root), ContractNotaryBatchID, batchBuf, darcID)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("creating batch: %v", err)
+	}
+
+	c.Batches++
+	buf, err := protobuf.Encode(&c.Notary)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("couldn't encode notary: %v", err)
+	}
+	log.Lvlf2("Notarizing %d hashes with root %x", len(hashes), batch.Root)
+	sc = []byzcoin.StateChange{
+		batchSC,
+		byzcoin.NewStateChange(byzcoin.Update, inst.InstanceID,
+			ContractNotaryID, buf, darcID),
+	}
+	return
+}
+
+func contractNotaryBatchFromBytes(in []byte) (byzcoin.Contract, error) {
+	c := &contractNotaryBatch{}
+	err := protobuf.Decode(in, &c.NotaryBatch)
+	if err != nil {
+		return nil, xerrors.Errorf("couldn't unmarshal instance data: %v", err)
+	}
+	return c, nil
+}
+
+// contractNotaryBatch only holds the data of a batch, and cannot be spawned,
+// invoked or deleted.
+type contractNotaryBatch struct {
+	byzcoin.BasicContract
+	NotaryBatch
+}
+
+// NotaryStep is one step of the Merkle path of a hash.
+type NotaryStep struct {
+	// Sibling is the hash of the sibling node, or nil if the node has no
+	// sibling and is passed on to the next level as it is.
+	Sibling []byte
+	// Left is true if the sibling is on the left.
+	Left bool
+}
+
+func notaryLeaf(hash []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{0})
+	h.Write(hash)
+	return h.Sum(nil)
+}
+
+func notaryNode(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{1})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// notaryTree returns all levels of the Merkle tree of the hashes, starting
+// with the leaves.
+func notaryTree(hashes [][]byte) [][][]byte {
+	level := make([][]byte, len(hashes))
+	for i, h := range hashes {
+		level[i] = notaryLeaf(h)
+	}
+	tree := [][][]byte{level}
+	for len(level) > 1 {
+		var next [][]byte
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+			} else {
+				next = append(next, notaryNode(level[i], level[i+1]))
+			}
+		}
+		tree = append(tree, next)
+		level = next
+	}
+	return tree
+}
+
+// NotaryRoot returns the root of the Merkle tree of the hashes.
+func NotaryRoot(hashes [][]byte) []byte {
+	if len(hashes) == 0 {
+		return nil
+	}
+	tree := notaryTree(hashes)
+	return tree[len(tree)-1][0]
+}
+
+// NotaryPath returns the Merkle path of the hash at the given position.
+func NotaryPath(hashes [][]byte, i int) ([]NotaryStep, error) {
+	if i < 0 || i >= len(hashes) {
+		return nil, xerrors.New("index out of range")
+	}
+	tree := notaryTree(hashes)
+	var path []NotaryStep
+	for _, level := range tree[:len(tree)-1] {
+		sibling := i ^ 1
+		if sibling < len(level) {
+			path = append(path, NotaryStep{Sibling: level[sibling], Left: sibling < i})
+		} else {
+			path = append(path, NotaryStep{})
+		}
+		i /= 2
+	}
+	return path, nil
+}
+
+// NotaryPathRoot returns the root of the Merkle tree given by the hash and
+// its path.
+func NotaryPathRoot(hash []byte, path []NotaryStep) []byte {
+	node := notaryLeaf(hash)
+	for _, s := range path {
+		switch {
+		case s.Sibling == nil:
+		case s.Left:
+			node = notaryNode(s.Sibling, node)
+		default:
+			node = notaryNode(node, s.Sibling)
+		}
+	}
+	return node
+}
+
+// NotaryBatchID returns the instance ID of the batch with the given root,
+// anchored by the notary.
+func NotaryBatchID(notary byzcoin.InstanceID, root []byte) byzcoin.InstanceID {
+	return byzcoin.ChildID(notary, hex.EncodeToString(root))
+}
+
+// NotarizeInstruction returns the instruction anchoring the hashes in the
+// notary. The signer counters of the instruction must be set before signing
+// it.
+func NotarizeInstruction(notary byzcoin.InstanceID, hashes [][]byte) byzcoin.Instruction {
+	var args byzcoin.Arguments
+	for _, h := range hashes {
+		args = append(args, byzcoin.Argument{Name: "hash", Value: h})
+	}
+	return byzcoin.Instruction{
+		InstanceID: notary,
+		Invoke: &byzcoin.Invoke{
+			ContractID: ContractNotaryID,
+			Command:    "notarize",
+			Args:       args,
+		},
+	}
+}
+
+// NotaryProof proves that a hash has been anchored by a notary. It only
+// holds the Merkle path of the hash, and not the other hashes of the batch.
+type NotaryProof struct {
+	Notary byzcoin.InstanceID
+	Hash   []byte
+	Path   []NotaryStep
+	// Proof is the byzcoin proof of the batch holding the hash.
+	Proof byzcoin.Proof
+}
+
+// GetNotaryProof returns the existence proof of the hash at the given
+// position in the batch of hashes anchored by the notary. The proof is
+// verified before it is returned.
+func GetNotaryProof(cl *byzcoin.Client, notary byzcoin.InstanceID, hashes [][]byte, i int) (*NotaryProof, error) {
+	path, err := NotaryPath(hashes, i)
+	if err != nil {
+		return nil, xerrors.Errorf("getting path: %v", err)
+	}
+	pr, err := cl.GetProof(NotaryBatchID(notary, NotaryRoot(hashes)).Slice())
+	if err != nil {
+		return nil, xerrors.Errorf("getting proof: %v", err)
+	}
+	np := &NotaryProof{
+		Notary: notary,
+		Hash:   hashes[i],
+		Path:   path,
+		Proof:  pr.Proof,
+	}
+	if _, err := np.Verify(cl.ID); err != nil {
+		return nil, xerrors.Errorf("verifying proof: %v", err)
+	}
+	return np, nil
+}
+
+// Verify checks that the hash of the proof has been anchored by the notary
+// in the given byzcoin chain, and returns the batch holding it.
+func (np NotaryProof) Verify(scID skipchain.SkipBlockID) (*NotaryBatch, error) {
+	if err := np.Proof.Verify(scID); err != nil {
+		return nil, xerrors.Errorf("verifying byzcoin proof: %v", err)
+	}
+	root := NotaryPathRoot(np.Hash, np.Path)
+	key, value, cid, _, err := np.Proof.KeyValue()
+	if err != nil {
+		return nil, xerrors.Errorf("reading proof: %v", err)
+	}
+	if !bytes.Equal(key, NotaryBatchID(np.Notary, root).Slice()) {
+		return nil, xerrors.New("hash is not in the batch of the proof")
+	}
+	if cid != ContractNotaryBatchID {
+		return nil, xerrors.New("instance is not a notary batch")
+	}
+	var batch NotaryBatch
+	if err := protobuf.Decode(value, &batch); err != nil {
+		return nil, xerrors.Errorf("decoding batch: %v", err)
+	}
+	if !bytes.Equal(batch.Root, root) {
+		return nil, xerrors.New("wrong root in batch")
+	}
+	return &batch, nil
+}
@@ -0,0 +1,137 @@
+package contracts
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/protobuf"
+)
+
+func notaryHashes(n int) [][]byte {
+	var hashes [][]byte
+	for i := 0; i < n; i++ {
+		h := sha256.Sum256([]byte(fmt.Sprintf("document %d", i)))
+		hashes = append(hashes, h[:])
+	}
+	return hashes
+}
+
+func TestNotary_Path(t *testing.T) {
+	for n := 1; n <= 9; n++ {
+		hashes := notaryHashes(n)
+		root := NotaryRoot(hashes)
+		for i := range hashes {
+			path, err := NotaryPath(hashes, i)
+			require.NoError(t, err)
+			require.Equal(t, root, NotaryPathRoot(hashes[i], path))
+			require.NotEqual(t, root, NotaryPathRoot(hashes[(i+1)%n][:31], path))
+		}
+		_, err := NotaryPath(hashes, n)
+		require.Error(t, err)
+	}
+	require.NotEqual(t, NotaryRoot(notaryHashes(2)), NotaryRoot(notaryHashes(3)))
+}
+
+func TestNotary_Invoke(t *testing.T) {
+	ct := newCT()
+	tt := timeTest{cvTest: ct, timestamp: 1234}
+	spawn := byzcoin.Instruction{
+		InstanceID: byzcoin.NewInstanceID(gdarc.GetBaseID()),
+		Spawn:      &byzcoin.Spawn{ContractID: ContractNotaryID},
+	}
+	c, err := contractNotaryFromBytes(nil)
+	require.NoError(t, err)
+	sc, _, err := c.Spawn(ct, spawn, nil)
+	require.NoError(t, err)
+	notary := spawn.DeriveID("")
+	ct.Store(notary, sc[0].Value, ContractNotaryID, gdarc.GetBaseID())
+
+	notarize := func(rst byzcoin.ReadOnlyStateTrie, hashes [][]byte) error {
+		c, err := contractNotaryFromBytes(ct.values[string(notary.Slice())])
+		require.NoError(t, err)
+		sc, _, err := c.Invoke(rst, NotarizeInstruction(notary, hashes), nil)
+		if err != nil {
+			return err
+		}
+		for _, s := range sc {
+			ct.Store(byzcoin.NewInstanceID(s.InstanceID), s.Value, s.ContractID, s.DarcID)
+		}
+		return nil
+	}
+
+	hashes := notaryHashes(5)
+	require.Error(t, notarize(tt, nil))
+	require.Error(t, notarize(tt, [][]byte{[]byte("short")}))
+	// The time of the chain is needed.
+	require.Error(t, notarize(ct, hashes))
+	require.NoError(t, notarize(tt, hashes))
+	require.Error(t, notarize(tt, hashes))
+	require.NoError(t, notarize(tt, hashes[1:]))
+
+	var batch NotaryBatch
+	buf := ct.values[string(NotaryBatchID(notary, NotaryRoot(hashes)).Slice())]
+	require.NoError(t, protobuf.Decode(buf, &batch))
+	require.Equal(t, NotaryRoot(hashes), batch.Root)
+	require.Equal(t, uint64(5), batch.Count)
+	require.Equal(t, int64(1234), batch.Timestamp)
+	var n Notary
+	require.NoError(t, protobuf.Decode(ct.values[string(notary.Slice())], &n))
+	require.Equal(t, uint64(2), n.Batches)
+}
+
+func TestNotary_Proof(t *testing.T) {
+	local := onet.NewTCPTest(cothority.Suite)
+	defer local.CloseAll()
+
+	signer := darc.NewSignerEd25519(nil, nil)
+	_, roster, _ := local.GenTree(3, true)
+
+	genesisMsg, err := byzcoin.DefaultGenesisMsg(byzcoin.CurrentVersion, roster,
+		[]string{"spawn:notary", "invoke:notary.notarize"}, signer.Identity())
+	require.NoError(t, err)
+	gDarc := &genesisMsg.GenesisDarc
+	genesisMsg.BlockInterval = time.Second
+
+	cl, _, err := byzcoin.NewLedger(genesisMsg, false)
+	require.NoError(t, err)
+
+	ctx, err := cl.CreateTransaction(byzcoin.Instruction{
+		InstanceID:    byzcoin.NewInstanceID(gDarc.GetBaseID()),
+		Spawn:         &byzcoin.Spawn{ContractID: ContractNotaryID},
+		SignerCounter: []uint64{1},
+	})
+	require.NoError(t, err)
+	require.NoError(t, ctx.FillSignersAndSignWith(signer))
+	_, err = cl.AddTransactionAndWait(ctx, 10)
+	require.NoError(t, err)
+	notary := ctx.Instructions[0].DeriveID("")
+
+	hashes := notaryHashes(7)
+	inst := NotarizeInstruction(notary, hashes)
+	inst.SignerCounter = []uint64{2}
+	ctx, err = cl.CreateTransaction(inst)
+	require.NoError(t, err)
+	require.NoError(t, ctx.FillSignersAndSignWith(signer))
+	_, err = cl.AddTransactionAndWait(ctx, 10)
+	require.NoError(t, err)
+
+	np, err := GetNotaryProof(cl, notary, hashes, 3)
+	require.NoError(t, err)
+	batch, err := np.Verify(cl.ID)
+	require.NoError(t, err)
+	require.NotEqual(t, int64(0), batch.Timestamp)
+
+	// A hash that is not in the batch cannot use its proof.
+	np.Hash = notaryHashes(8)[7]
+	_, err = np.Verify(cl.ID)
+	require.Error(t, err)
+	_, err = GetNotaryProof(cl, notary, notaryHashes(8), 7)
+	require.Error(t, err)
+}
@@ -0,0 +1,422 @@
+package contracts
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/protobuf"
+	"golang.org/x/xerrors"
+)
+
+// ContractPaymentChannelID denotes a contract holding the coins of a payment
+// channel between two parties.
+const ContractPaymentChannelID = "paymentChannel"
+
+// ContractPaymentChannel is a payment channel between two parties. The
+// coins of both parties are locked in the channel, and the parties pay each
+// other off-chain by signing new ChannelStates, with an increasing nonce.
+// Only the opening, the deposits and the closing of the channel are stored
+// on-chain.
+//
+// A party can close the channel with the latest state it has. The other
+// party then has a dispute window to send a state with a higher nonce, in
+// case an older state has been used. If both parties agree, they can sign a
+// final state that settles the channel at once.
+//
+// Spawn needs the following arguments:
+//  - partyA and partyB are the identities of the parties, as returned by
+//    darc.Identity.String
+//  - destinationA and destinationB are the coin instances the parties are
+//    paid to when the channel is settled
+//  - coinType is the name of the coins of the channel
+//  - dispute is the length of the dispute window in blocks, as a 64-bit uint
+//    in LittleEndian
+//  - darcID is optional and defines the darc of the channel, which must
+//    allow both parties to invoke the commands
+// The coins given to the spawn instruction are the deposit of partyA.
+//
+// The following methods are available:
+//  - deposit adds the coins given to the instruction to the channel. The
+//    argument "state" is the protobuf-encoded ChannelState with the balances
+//    after the deposit, signed by both parties, so that the states signed
+//    before the deposit are replaced by one both parties agree on. It is
+//    only possible while the channel is open.
+//  - close starts the dispute window. The optional argument "state" is the
+//    protobuf-encoded latest ChannelState. If the state is final, the
+//    channel is settled at once.
+//  - dispute replaces the state of a closing channel by the ChannelState in
+//    the argument "state", which must have a higher nonce. It is only
+//    possible during the dispute window.
+//  - settle pays the balances to the destinations and removes the channel,
+//    once the dispute window is over.
+// All commands need to be signed by one of the parties.
+type ContractPaymentChannel struct {
+	byzcoin.BasicContract
+	txSignatures
+	PaymentChannel
+}
+
+// PaymentChannel is the data stored in a paymentChannel instance.
+type PaymentChannel struct {
+	// CoinType is the name of the coins of the channel.
+	CoinType byzcoin.InstanceID
+	// Parties are the two parties of the channel, with their balances as of
+	// the latest state.
+	Parties []ChannelParty
+	// Nonce is the nonce of the latest state sent to the channel.
+	Nonce uint64
+	// Dispute is the length of the dispute window in blocks.
+	Dispute int
+	// Closing is set once a party closed the channel.
+	Closing bool
+	// CloseIndex is the index of the block from which the channel can be
+	// settled.
+	CloseIndex int
+}
+
+// ChannelParty is one of the parties of a payment channel.
+type ChannelParty struct {
+	Identity    string
+	Destination byzcoin.InstanceID
+	Balance     uint64
+}
+
+// ChannelState is the off-chain state of a payment channel, signed by both
+// parties.
+type ChannelState struct {
+	Channel byzcoin.InstanceID
+	Nonce   uint64
+	// Balances are the balances of the parties, in the order of the
+	// channel. Their sum must be equal to the coins of the channel.
+	Balances []uint64
+	// Final is set if the parties agree to settle the channel with this
+	// state.
+	Final bool
+	// Signatures are the signatures of the parties on the Hash of the
+	// state, in the order of the channel.
+	Signatures [][]byte
+}
+
+// Hash returns the hash of the state that is signed by the parties.
+func (cs ChannelState) Hash() []byte {
+	h := sha256.New()
+	h.Write(cs.Channel.Slice())
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buf, cs.Nonce)
+	h.Write(buf)
+	for _, b := range cs.Balances {
+		binary.LittleEndian.PutUint64(buf, b)
+		h.Write(buf)
+	}
+	if cs.Final {
+		h.Write([]byte{1})
+	} else {
+		h.Write([]byte{0})
+	}
+	return h.Sum(nil)
+}
+
+// Sign adds the signature of the party with the given index in the channel.
+func (cs *ChannelState) Sign(party int, signer darc.Signer) error {
+	if party < 0 || party > 1 {
+		return xerrors.New("party must be 0 or 1")
+	}
+	sig, err := signer.Sign(cs.Hash())
+	if err != nil {
+		return xerrors.Errorf("signing: %v", err)
+	}
+	for len(cs.Signatures) < 2 {
+		cs.Signatures = append(cs.Signatures, nil)
+	}
+	cs.Signatures[party] = sig
+	return nil
+}
+
+// verify checks that the state belongs to the channel, is newer than the
+// latest state, holds the total of coins, and is signed by both parties.
+func (pc PaymentChannel) verify(id byzcoin.InstanceID, cs ChannelState, total uint64) error {
+	if !cs.Channel.Equal(id) {
+		return xerrors.New("state is for another channel")
+	}
+	if cs.Nonce <= pc.Nonce {
+		return xerrors.Errorf("nonce must be bigger than %d", pc.Nonce)
+	}
+	if len(cs.Balances) != 2 || len(cs.Signatures) != 2 {
+		return xerrors.New("state needs 2 balances and 2 signatures")
+	}
+	if cs.Balances[0]+cs.Balances[1] < cs.Balances[0] ||
+		cs.Balances[0]+cs.Balances[1] != total {
+		return xerrors.Errorf("balances must add up to %d", total)
+	}
+	for i, p := range pc.Parties {
+		ident, err := darc.ParseIdentity(p.Identity)
+		if err != nil {
+			return xerrors.Errorf("parsing party: %v", err)
+		}
+		if err = ident.Verify(cs.Hash(), cs.Signatures[i]); err != nil {
+			return xerrors.Errorf("signature of party %d: %v", i, err)
+		}
+	}
+	return nil
+}
+
+func (pc PaymentChannel) total() uint64 {
+	return pc.Parties[0].Balance + pc.Parties[1].Balance
+}
+
+// party returns the index of the party that signed the instruction, or -1.
+func (c *ContractPaymentChannel) party(inst byzcoin.Instruction) int {
+	for i, p := range c.Parties {
+		if c.signedBy(inst, p.Identity) {
+			return i
+		}
+	}
+	return -1
+}
+
+func contractPaymentChannelFromBytes(in []byte) (byzcoin.Contract, error) {
+	c := &ContractPaymentChannel{}
+	err := protobuf.Decode(in, &c.PaymentChannel)
+	if err != nil {
+		return nil, xerrors.Errorf("couldn't unmarshal instance data: %v", err)
+	}
+	return c, nil
+}
+
+// Spawn implements the byzcoin.Contract interface.
+func (c *ContractPaymentChannel) Spawn(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+	args := inst.Spawn.Args
+	if did := args.Search("darcID"); did != nil {
+		darcID = darc.ID(did)
+	}
+
+	coinType := args.Search("coinType")
+	if len(coinType) != len(byzcoin.InstanceID{}) {
+		return nil, nil, xerrors.New("coinType needs to be an InstanceID")
+	}
+	c.CoinType = byzcoin.NewInstanceID(coinType)
+	for _, p := range []string{"A", "B"} {
+		party := ChannelParty{
+			Identity:    string(args.Search("party" + p)),
+			Destination: byzcoin.NewInstanceID(args.Search("destination" + p)),
+		}
+		if _, err = darc.ParseIdentity(party.Identity); err != nil {
+			return nil, nil, xerrors.Errorf("parsing party%s: %v", p, err)
+		}
+		var dest byzcoin.Coin
+		dest, _, err = getCoin(rst, party.Destination.Slice())
+		if err != nil {
+			return nil, nil, xerrors.Errorf("destination%s: %v", p, err)
+		}
+		if !dest.Name.Equal(c.CoinType) {
+			return nil, nil, xerrors.Errorf("destination%s holds another type of coin", p)
+		}
+		c.Parties = append(c.Parties, party)
+	}
+	if c.Parties[0].Identity == c.Parties[1].Identity {
+		return nil, nil, xerrors.New("parties must be different")
+	}
+	dispute, err := uint64Arg(args, "dispute")
+	if err != nil {
+		return
+	}
+	if dispute == 0 {
+		return nil, nil, xerrors.New("dispute window must be at least 1 block")
+	}
+	c.Dispute = int(dispute)
+	if cout, c.Parties[0].Balance, err = c.collect(coins); err != nil {
+		return
+	}
+
+	buf, err := protobuf.Encode(&c.PaymentChannel)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("couldn't encode channel: %v", err)
+	}
+	log.Lvlf2("Opening channel with %d coins", c.total())
+	sc = []byzcoin.StateChange{
+		byzcoin.NewStateChange(byzcoin.Create, inst.DeriveID(""),
+			ContractPaymentChannelID, buf, darcID),
+	}
+	return
+}
+
+// VerifyInstruction implements the byzcoin.Contract interface, and keeps the
+// hash of the transaction to check the signature of the party.
+func (c *ContractPaymentChannel) VerifyInstruction(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, ctxHash []byte) error {
+	c.ctxHash = ctxHash
+	return c.BasicContract.VerifyInstruction(rst, inst, ctxHash)
+}
+
+// Invoke implements the byzcoin.Contract interface.
+func (c *ContractPaymentChannel) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+
+	if c.party(inst) < 0 {
+		return nil, nil, xerrors.New("instruction must be signed by a party")
+	}
+	switch inst.Invoke.Command {
+	case "deposit":
+		if c.Closing {
+			return nil, nil, xerrors.New("channel is closing")
+		}
+		if cout, err = c.deposit(inst, coins); err != nil {
+			return
+		}
+	case "close":
+		if c.Closing {
+			return nil, nil, xerrors.New("channel is already closing")
+		}
+		var final bool
+		if inst.Invoke.Args.Search("state") != nil {
+			if final, err = c.applyState(inst, c.total()); err != nil {
+				return
+			}
+		}
+		if final {
+			return c.settle(rst, inst, darcID)
+		}
+		c.Closing = true
+		c.CloseIndex = rst.GetIndex() + c.Dispute
+	case "dispute":
+		if !c.Closing {
+			return nil, nil, xerrors.New("channel is not closing")
+		}
+		if rst.GetIndex() >= c.CloseIndex {
+			return nil, nil, xerrors.New("dispute window is over")
+		}
+		var final bool
+		if final, err = c.applyState(inst, c.total()); err != nil {
+			return
+		}
+		if final {
+			return c.settle(rst, inst, darcID)
+		}
+	case "settle":
+		if !c.Closing {
+			return nil, nil, xerrors.New("channel is not closing")
+		}
+		if rst.GetIndex() < c.CloseIndex {
+			return nil, nil, xerrors.Errorf("dispute window lasts until block %d", c.CloseIndex)
+		}
+		return c.settle(rst, inst, darcID)
+	default:
+		return nil, nil, xerrors.New("paymentChannel contract can only deposit, close, dispute or settle")
+	}
+
+	buf, err := protobuf.Encode(&c.PaymentChannel)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("couldn't encode channel: %v", err)
+	}
+	sc = append(sc, byzcoin.NewStateChange(byzcoin.Update, inst.InstanceID,
+		ContractPaymentChannelID, buf, darcID))
+	return
+}
+
+// collect returns the coins of the right type and their value, and the
+// other coins.
+func (c *ContractPaymentChannel) collect(coins []byzcoin.Coin) ([]byzcoin.Coin, uint64, error) {
+	var out []byzcoin.Coin
+	sum := byzcoin.Coin{Name: c.CoinType}
+	for _, co := range coins {
+		if !co.Name.Equal(c.CoinType) {
+			out = append(out, co)
+			continue
+		}
+		if err := sum.SafeAdd(co.Value); err != nil {
+			return nil, 0, err
+		}
+	}
+	return out, sum.Value, nil
+}
+
+// deposit adds the coins of the right type to the channel, with the
+// balances of the state in the argument "state", and returns the other
+// coins.
+func (c *ContractPaymentChannel) deposit(inst byzcoin.Instruction, coins []byzcoin.Coin) ([]byzcoin.Coin, error) {
+	out, value, err := c.collect(coins)
+	if err != nil {
+		return nil, err
+	}
+	if value == 0 {
+		return nil, xerrors.New("nothing to deposit")
+	}
+	if value > ^uint64(0)-c.total() {
+		return nil, xerrors.New("channel overflow")
+	}
+	final, err := c.applyState(inst, c.total()+value)
+	if err != nil {
+		return nil, err
+	}
+	if final {
+		return nil, xerrors.New("the state of a deposit cannot be final")
+	}
+	return out, nil
+}
+
+// applyState verifies the state in the argument "state", which must hold
+// the total of coins, and stores its balances. It returns whether the state
+// is final.
+func (c *ContractPaymentChannel) applyState(inst byzcoin.Instruction, total uint64) (bool, error) {
+	var cs ChannelState
+	if err := protobuf.Decode(inst.Invoke.Args.Search("state"), &cs); err != nil {
+		return false, xerrors.Errorf("decoding state: %v", err)
+	}
+	if err := c.verify(inst.InstanceID, cs, total); err != nil {
+		return false, xerrors.Errorf("invalid state: %v", err)
+	}
+	c.Nonce = cs.Nonce
+	for i := range c.Parties {
+		c.Parties[i].Balance = cs.Balances[i]
+	}
+	return cs.Final, nil
+}
+
+// settle pays the balances to the destinations and removes the channel.
+func (c *ContractPaymentChannel) settle(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, darcID darc.ID) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	sc, err = payCoins(rst, c.CoinType,
+		[]byzcoin.InstanceID{c.Parties[0].Destination, c.Parties[1].Destination},
+		[]uint64{c.Parties[0].Balance, c.Parties[1].Balance})
+	if err != nil {
+		return nil, nil, xerrors.Errorf("paying parties: %v", err)
+	}
+	log.Lvlf2("Settling channel with %d and %d coins", c.Parties[0].Balance,
+		c.Parties[1].Balance)
+	sc = append(sc, byzcoin.NewStateChange(byzcoin.Remove, inst.InstanceID,
+		ContractPaymentChannelID, nil, darcID))
+	return
+}
+
+// ChannelInstruction returns the instruction invoking the command on the
+// channel, with the state if it is not nil. The signer counters of the
+// instruction must be set before signing it.
+func ChannelInstruction(channel byzcoin.InstanceID, command string, state *ChannelState) (byzcoin.Instruction, error) {
+	inst := byzcoin.Instruction{
+		InstanceID: channel,
+		Invoke: &byzcoin.Invoke{
+			ContractID: ContractPaymentChannelID,
+			Command:    command,
+		},
+	}
+	if state != nil {
+		buf, err := protobuf.Encode(state)
+		if err != nil {
+			return byzcoin.Instruction{}, xerrors.Errorf("encoding state: %v", err)
+		}
+		inst.Invoke.Args = byzcoin.Arguments{{Name: "state", Value: buf}}
+	}
+	return inst, nil
+}
@@ -0,0 +1,164 @@
+package contracts
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/protobuf"
+)
+
+// openChannel spawns a channel between alice and bob, with 10 coins of
+// alice and a dispute window of 2 blocks.
+func openChannel(t *testing.T, ct *cvTest, alice, bob darc.Signer) byzcoin.InstanceID {
+	for _, dest := range []string{"destA", "destB"} {
+		buf, err := protobuf.Encode(&byzcoin.Coin{Name: CoinName})
+		require.NoError(t, err)
+		ct.Store(iid(dest), buf, ContractCoinID, gdarc.GetBaseID())
+	}
+	dispute := make([]byte, 8)
+	binary.LittleEndian.PutUint64(dispute, 2)
+	spawn := byzcoin.Instruction{
+		InstanceID: byzcoin.NewInstanceID(gdarc.GetBaseID()),
+		Spawn: &byzcoin.Spawn{
+			ContractID: ContractPaymentChannelID,
+			Args: byzcoin.Arguments{
+				{Name: "partyA", Value: []byte(alice.Identity().String())},
+				{Name: "partyB", Value: []byte(bob.Identity().String())},
+				{Name: "destinationA", Value: iid("destA").Slice()},
+				{Name: "destinationB", Value: iid("destB").Slice()},
+				{Name: "coinType", Value: CoinName.Slice()},
+				{Name: "dispute", Value: dispute},
+			},
+		},
+	}
+	c, err := contractPaymentChannelFromBytes(nil)
+	require.NoError(t, err)
+	sc, cout, err := c.Spawn(ct, spawn, []byzcoin.Coin{{Name: CoinName, Value: 10}})
+	require.NoError(t, err)
+	require.Equal(t, 0, len(cout))
+	id := spawn.DeriveID("")
+	ct.Store(id, sc[0].Value, ContractPaymentChannelID, gdarc.GetBaseID())
+	return id
+}
+
+func runChannel(t *testing.T, ct *cvTest, id byzcoin.InstanceID, cmd string, state *ChannelState, signer darc.Signer, coins ...byzcoin.Coin) error {
+	inst, err := ChannelInstruction(id, cmd, state)
+	require.NoError(t, err)
+	signTx(t, &inst, signer)
+	return invokeChannel(t, ct, inst, coins...)
+}
+
+func invokeChannel(t *testing.T, ct *cvTest, inst byzcoin.Instruction, coins ...byzcoin.Coin) error {
+	c, err := contractPaymentChannelFromBytes(ct.values[string(inst.InstanceID.Slice())])
+	require.NoError(t, err)
+	c.(*ContractPaymentChannel).ctxHash = testCtxHash
+	sc, _, err := c.Invoke(ct, inst, coins)
+	if err != nil {
+		return err
+	}
+	for _, s := range sc {
+		ct.Store(byzcoin.NewInstanceID(s.InstanceID), s.Value, s.ContractID, s.DarcID)
+	}
+	return nil
+}
+
+func channelState(t *testing.T, id byzcoin.InstanceID, nonce, a, b uint64, final bool, signers ...darc.Signer) *ChannelState {
+	cs := &ChannelState{Channel: id, Nonce: nonce, Balances: []uint64{a, b}, Final: final}
+	for i, s := range signers {
+		require.NoError(t, cs.Sign(i, s))
+	}
+	return cs
+}
+
+func TestPaymentChannel_Dispute(t *testing.T) {
+	ct := newCT()
+	alice := darc.NewSignerEd25519(nil, nil)
+	bob := darc.NewSignerEd25519(nil, nil)
+	stranger := darc.NewSignerEd25519(nil, nil)
+	id := openChannel(t, ct, alice, bob)
+
+	// Deposits need a new state signed by both parties, with the deposit.
+	five := byzcoin.Coin{Name: CoinName, Value: 5}
+	deposit := channelState(t, id, 1, 10, 5, false, alice, bob)
+	require.Error(t, runChannel(t, ct, id, "deposit", deposit, stranger, five))
+	require.Error(t, runChannel(t, ct, id, "deposit", nil, bob, five))
+	require.Error(t, runChannel(t, ct, id, "deposit",
+		channelState(t, id, 1, 10, 0, false, alice, bob), bob, five))
+	require.Error(t, runChannel(t, ct, id, "deposit",
+		channelState(t, id, 1, 10, 5, false, bob), bob, five))
+	require.NoError(t, runChannel(t, ct, id, "deposit", deposit, bob, five))
+
+	// Off-chain payments.
+	old := channelState(t, id, 2, 8, 7, false, alice, bob)
+	latest := channelState(t, id, 3, 5, 10, false, alice, bob)
+
+	// States must be signed by both parties and keep the coins.
+	require.Error(t, runChannel(t, ct, id, "close",
+		channelState(t, id, 4, 0, 15, false, bob), bob))
+	require.Error(t, runChannel(t, ct, id, "close",
+		channelState(t, id, 4, 0, 16, false, alice, bob), bob))
+
+	// A party cannot act as the other one.
+	inst, err := ChannelInstruction(id, "close", old)
+	require.NoError(t, err)
+	signTx(t, &inst, stranger)
+	forgeSigners(&inst, alice)
+	require.Error(t, invokeChannel(t, ct, inst))
+
+	// Alice closes with an old state, and bob disputes it.
+	require.Error(t, runChannel(t, ct, id, "close", old, stranger))
+	require.NoError(t, runChannel(t, ct, id, "close", old, alice))
+	require.Error(t, runChannel(t, ct, id, "deposit",
+		channelState(t, id, 4, 13, 7, false, alice, bob), alice, five))
+	require.Error(t, runChannel(t, ct, id, "settle", nil, alice))
+	require.NoError(t, runChannel(t, ct, id, "dispute", latest, bob))
+	require.Error(t, runChannel(t, ct, id, "dispute", old, alice))
+
+	// The dispute window is over.
+	ct.index += 2
+	require.Error(t, runChannel(t, ct, id, "dispute",
+		channelState(t, id, 4, 15, 0, false, alice, bob), alice))
+	require.NoError(t, runChannel(t, ct, id, "settle", nil, alice))
+	require.Equal(t, uint64(5), coinsOf(ct, "destA"))
+	require.Equal(t, uint64(10), coinsOf(ct, "destB"))
+	require.Nil(t, ct.values[string(id.Slice())])
+}
+
+// TestPaymentChannel_StaleDeposit checks that a deposit cannot be used to
+// close the channel with balances older than the off-chain states.
+func TestPaymentChannel_StaleDeposit(t *testing.T) {
+	ct := newCT()
+	alice := darc.NewSignerEd25519(nil, nil)
+	bob := darc.NewSignerEd25519(nil, nil)
+	id := openChannel(t, ct, alice, bob)
+
+	// Alice paid 6 coins to bob off-chain, and then tries to deposit on
+	// the balances of the opening.
+	paid := channelState(t, id, 1, 4, 6, false, alice, bob)
+	stale := channelState(t, id, 1, 11, 0, false, alice)
+	require.Error(t, runChannel(t, ct, id, "deposit", stale, alice,
+		byzcoin.Coin{Name: CoinName, Value: 1}))
+
+	require.NoError(t, runChannel(t, ct, id, "close", nil, alice))
+	require.NoError(t, runChannel(t, ct, id, "dispute", paid, bob))
+	ct.index += 2
+	require.NoError(t, runChannel(t, ct, id, "settle", nil, bob))
+	require.Equal(t, uint64(4), coinsOf(ct, "destA"))
+	require.Equal(t, uint64(6), coinsOf(ct, "destB"))
+}
+
+func TestPaymentChannel_Final(t *testing.T) {
+	ct := newCT()
+	alice := darc.NewSignerEd25519(nil, nil)
+	bob := darc.NewSignerEd25519(nil, nil)
+	id := openChannel(t, ct, alice, bob)
+
+	require.NoError(t, runChannel(t, ct, id, "close",
+		channelState(t, id, 4, 3, 7, true, alice, bob), bob))
+	require.Equal(t, uint64(3), coinsOf(ct, "destA"))
+	require.Equal(t, uint64(7), coinsOf(ct, "destB"))
+	require.Nil(t, ct.values[string(id.Slice())])
+}
@@ -0,0 +1,182 @@
+package contracts
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/kyber/v3/pairing"
+	"go.dedis.ch/kyber/v3/share"
+	"go.dedis.ch/kyber/v3/sign/bls"
+	"go.dedis.ch/kyber/v3/sign/tbls"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/protobuf"
+	"golang.org/x/xerrors"
+)
+
+// ContractRandomnessID denotes a contract storing the rounds of a randomness
+// beacon.
+const ContractRandomnessID = "randomness"
+
+// ContractRandomness stores the latest round of a randomness beacon. Like in
+// drand, every round is a threshold BLS signature on the signature of the
+// previous round. As the signature is unique for a given distributed key, no
+// subset of the signers below the threshold can bias or predict the value.
+//
+// The distributed key is created by the nodes of the roster, for example
+// with the dkg/pedersen protocol on the G2 group of bn256. For every round,
+// the nodes sign RandomnessMessage with RandomnessSign, and anybody can
+// recover the signature from enough shares with RandomnessRecover.
+//
+// Spawn needs the argument "public", which is the marshalled distributed
+// public key, a point on G2 of bn256. The optional argument "darcID" defines
+// the darc of the beacon.
+//
+// The following method is available:
+//  - round stores the next round given in the argument "signature". The
+//    signature is verified against the distributed public key.
+//
+// Other contracts can read the latest value with GetRandomness.
+type ContractRandomness struct {
+	byzcoin.BasicContract
+	Randomness
+}
+
+// Randomness is the data stored in a randomness instance.
+type Randomness struct {
+	// Public is the marshalled distributed public key.
+	Public []byte
+	// Round is the number of the latest round, starting at 1.
+	Round uint64
+	// Signature is the signature of the latest round.
+	Signature []byte
+}
+
+// Value returns the random value of the latest round.
+func (r Randomness) Value() []byte {
+	h := sha256.Sum256(r.Signature)
+	return h[:]
+}
+
+// RandomnessMessage returns the message signed for the given round.
+func RandomnessMessage(prev []byte, round uint64) []byte {
+	h := sha256.New()
+	h.Write(prev)
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buf, round)
+	h.Write(buf)
+	return h.Sum(nil)
+}
+
+// RandomnessSign returns the partial signature of a node for the given
+// round.
+func RandomnessSign(priv *share.PriShare, prev []byte, round uint64) ([]byte, error) {
+	return tbls.Sign(pairing.NewSuiteBn256(), priv, RandomnessMessage(prev, round))
+}
+
+// RandomnessRecover returns the signature of the given round from at least
+// t partial signatures of the n nodes.
+func RandomnessRecover(public *share.PubPoly, prev []byte, round uint64, sigs [][]byte, t, n int) ([]byte, error) {
+	return tbls.Recover(pairing.NewSuiteBn256(), public, RandomnessMessage(prev, round), sigs, t, n)
+}
+
+// GetRandomness returns the randomness stored in the instance. It can be
+// used by other contracts to get the latest random value.
+func GetRandomness(rst byzcoin.ReadOnlyStateTrie, id byzcoin.InstanceID) (*Randomness, error) {
+	buf, _, cid, _, err := rst.GetValues(id.Slice())
+	if err != nil {
+		return nil, xerrors.Errorf("reading trie: %v", err)
+	}
+	if cid != ContractRandomnessID {
+		return nil, xerrors.New("instance is not a randomness beacon")
+	}
+	var r Randomness
+	if err = protobuf.Decode(buf, &r); err != nil {
+		return nil, xerrors.Errorf("decoding randomness: %v", err)
+	}
+	if r.Round == 0 {
+		return nil, xerrors.New("no round yet")
+	}
+	return &r, nil
+}
+
+func contractRandomnessFromBytes(in []byte) (byzcoin.Contract, error) {
+	c := &ContractRandomness{}
+	err := protobuf.Decode(in, &c.Randomness)
+	if err != nil {
+		return nil, xerrors.Errorf("couldn't unmarshal instance data: %v", err)
+	}
+	return c, nil
+}
+
+// Spawn implements the byzcoin.Contract interface.
+func (c *ContractRandomness) Spawn(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+	if did := inst.Spawn.Args.Search("darcID"); did != nil {
+		darcID = darc.ID(did)
+	}
+
+	c.Public = inst.Spawn.Args.Search("public")
+	if err = pairing.NewSuiteBn256().G2().Point().UnmarshalBinary(c.Public); err != nil {
+		return nil, nil, xerrors.Errorf("invalid public key: %v", err)
+	}
+
+	buf, err := protobuf.Encode(&c.Randomness)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("couldn't encode randomness: %v", err)
+	}
+	sc = []byzcoin.StateChange{
+		byzcoin.NewStateChange(byzcoin.Create, inst.DeriveID(""),
+			ContractRandomnessID, buf, darcID),
+	}
+	return
+}
+
+// Invoke implements the byzcoin.Contract interface.
+func (c *ContractRandomness) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+
+	if inst.Invoke.Command != "round" {
+		return nil, nil, xerrors.New("randomness contract can only add a round")
+	}
+	suite := pairing.NewSuiteBn256()
+	public := suite.G2().Point()
+	if err = public.UnmarshalBinary(c.Public); err != nil {
+		return nil, nil, xerrors.Errorf("invalid public key: %v", err)
+	}
+	sig := inst.Invoke.Args.Search("signature")
+	if bytes.Equal(sig, c.Signature) {
+		return nil, nil, xerrors.New("round already stored")
+	}
+	err = bls.Verify(suite, public, RandomnessMessage(c.Signature, c.Round+1), sig)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("verifying round %d: %v", c.Round+1, err)
+	}
+	c.Round++
+	c.Signature = sig
+	log.Lvlf2("Storing round %d of randomness", c.Round)
+
+	buf, err := protobuf.Encode(&c.Randomness)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("couldn't encode randomness: %v", err)
+	}
+	sc = []byzcoin.StateChange{
+		byzcoin.NewStateChange(byzcoin.Update, inst.InstanceID,
+			ContractRandomnessID, buf, darcID),
+	}
+	return
+}
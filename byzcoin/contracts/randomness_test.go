@@ -0,0 +1,103 @@
+package contracts
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/kyber/v3/pairing"
+	"go.dedis.ch/kyber/v3/share"
+)
+
+func TestRandomness(t *testing.T) {
+	ct := newCT()
+	suite := pairing.NewSuiteBn256()
+	n, th := 5, 3
+	secret := suite.G2().Scalar().Pick(suite.RandomStream())
+	priPoly := share.NewPriPoly(suite.G2(), th, secret, suite.RandomStream())
+	pubPoly := priPoly.Commit(suite.G2().Point().Base())
+	public, err := pubPoly.Commit().MarshalBinary()
+	require.NoError(t, err)
+
+	spawn := byzcoin.Instruction{
+		InstanceID: byzcoin.NewInstanceID(gdarc.GetBaseID()),
+		Spawn: &byzcoin.Spawn{
+			ContractID: ContractRandomnessID,
+			Args:       byzcoin.Arguments{{Name: "public", Value: public}},
+		},
+	}
+	c, err := contractRandomnessFromBytes(nil)
+	require.NoError(t, err)
+	_, _, err = c.Spawn(ct, byzcoin.Instruction{
+		InstanceID: spawn.InstanceID,
+		Spawn:      &byzcoin.Spawn{ContractID: ContractRandomnessID},
+	}, nil)
+	require.Error(t, err)
+	sc, _, err := c.Spawn(ct, spawn, nil)
+	require.NoError(t, err)
+	rid := spawn.DeriveID("")
+	ct.Store(rid, sc[0].Value, ContractRandomnessID, gdarc.GetBaseID())
+	_, err = GetRandomness(ct, rid)
+	require.Error(t, err)
+
+	round := func(sig []byte) error {
+		c, err := contractRandomnessFromBytes(ct.values[string(rid.Slice())])
+		require.NoError(t, err)
+		sc, _, err := c.Invoke(ct, byzcoin.Instruction{
+			InstanceID: rid,
+			Invoke: &byzcoin.Invoke{
+				ContractID: ContractRandomnessID,
+				Command:    "round",
+				Args:       byzcoin.Arguments{{Name: "signature", Value: sig}},
+			},
+		}, nil)
+		if err != nil {
+			return err
+		}
+		ct.Store(rid, sc[0].Value, ContractRandomnessID, gdarc.GetBaseID())
+		return nil
+	}
+	sign := func(prev []byte, r uint64, signers int) []byte {
+		var sigs [][]byte
+		for _, s := range priPoly.Shares(n)[:signers] {
+			sig, err := RandomnessSign(s, prev, r)
+			require.NoError(t, err)
+			sigs = append(sigs, sig)
+		}
+		sig, err := RandomnessRecover(pubPoly, prev, r, sigs, th, n)
+		if err != nil {
+			return nil
+		}
+		return sig
+	}
+
+	// Not enough shares to recover the signature.
+	require.Nil(t, sign(nil, 1, th-1))
+
+	var prev []byte
+	for r := uint64(1); r <= 3; r++ {
+		// A signature of the wrong round is refused.
+		require.Error(t, round(sign(prev, r+1, th)))
+		sig := sign(prev, r, th)
+		require.NoError(t, round(sig))
+		require.Error(t, round(sig))
+
+		rnd, err := GetRandomness(ct, rid)
+		require.NoError(t, err)
+		require.Equal(t, r, rnd.Round)
+		require.Equal(t, sig, rnd.Signature)
+		require.Equal(t, 32, len(rnd.Value()))
+		prev = sig
+	}
+
+	// Any subset of the signers gives the same signature.
+	var sigs [][]byte
+	for _, s := range priPoly.Shares(n)[n-th:] {
+		sig, err := RandomnessSign(s, prev, 4)
+		require.NoError(t, err)
+		sigs = append(sigs, sig)
+	}
+	sig, err := RandomnessRecover(pubPoly, prev, 4, sigs, th, n)
+	require.NoError(t, err)
+	require.Equal(t, sign(prev, 4, th), sig)
+}
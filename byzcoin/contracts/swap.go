@@ -0,0 +1,215 @@
+package contracts
+
+import (
+	"encoding/binary"
+
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/protobuf"
+	"golang.org/x/xerrors"
+)
+
+// ContractCoinSwapID denotes a contract that exchanges coins of one type
+// against coins of another type.
+const ContractCoinSwapID = "coinSwap"
+
+// ContractCoinSwap allows two parties to atomically exchange coins of two
+// different types. The first party spawns an offer, which holds the offered
+// coins until the second party accepts it. Accepting the offer pays the
+// wanted coins to the first party and the offered coins to the second party
+// in the same instruction, so either both transfers happen, or none.
+//
+// Spawn takes the coins given to the instruction, which must all be of the
+// same type, usually from a previous "fetch" on a coin instance. The
+// following arguments are needed:
+//  - wantType is the name of the wanted coins
+//  - wantCoins is the number of wanted coins, as a 64-bit uint in
+//    LittleEndian
+//  - payTo is the coin instance receiving the wanted coins
+//  - darcID is optional and defines the darc of the offer. It can be used
+//    to give the second party the right to accept the offer.
+//
+// The following methods are available:
+//  - accept takes the wanted coins from the coins given to the instruction
+//    and sends them to "payTo". The offered coins are sent to the coin
+//    instance given in the argument "destination". Remaining coins are
+//    passed on to the next instruction. The offer is removed.
+//  - cancel sends the offered coins back to the coin instance given in the
+//    argument "destination", and removes the offer.
+type ContractCoinSwap struct {
+	byzcoin.BasicContract
+	CoinSwap
+}
+
+// CoinSwap is the data stored in a coinSwap instance.
+type CoinSwap struct {
+	// Offered are the coins held by the offer.
+	Offered byzcoin.Coin
+	// Wanted are the coins asked in exchange.
+	Wanted byzcoin.Coin
+	// PayTo is the coin instance receiving the wanted coins.
+	PayTo byzcoin.InstanceID
+}
+
+func contractCoinSwapFromBytes(in []byte) (byzcoin.Contract, error) {
+	c := &ContractCoinSwap{}
+	err := protobuf.Decode(in, &c.CoinSwap)
+	if err != nil {
+		return nil, xerrors.Errorf("couldn't unmarshal instance data: %v", err)
+	}
+	return c, nil
+}
+
+// Spawn implements the byzcoin.Contract interface.
+func (c *ContractCoinSwap) Spawn(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+	if did := inst.Spawn.Args.Search("darcID"); did != nil {
+		darcID = darc.ID(did)
+	}
+
+	c.Offered, err = sumCoins(coins)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("offered coins: %v", err)
+	}
+
+	wantType := inst.Spawn.Args.Search("wantType")
+	if len(wantType) != len(byzcoin.InstanceID{}) {
+		return nil, nil, xerrors.New("wantType needs to be an InstanceID")
+	}
+	c.Wanted.Name = byzcoin.NewInstanceID(wantType)
+	if c.Wanted.Name.Equal(c.Offered.Name) {
+		return nil, nil, xerrors.New("cannot swap coins of the same type")
+	}
+	wantCoins := inst.Spawn.Args.Search("wantCoins")
+	if len(wantCoins) != 8 {
+		return nil, nil, xerrors.New("argument \"wantCoins\" is wrong length")
+	}
+	c.Wanted.Value = binary.LittleEndian.Uint64(wantCoins)
+
+	c.PayTo = byzcoin.NewInstanceID(inst.Spawn.Args.Search("payTo"))
+	payTo, _, err := getCoin(rst, c.PayTo.Slice())
+	if err != nil {
+		return nil, nil, xerrors.Errorf("payTo: %v", err)
+	}
+	if !payTo.Name.Equal(c.Wanted.Name) {
+		return nil, nil, xerrors.New("payTo holds another type of coin")
+	}
+
+	buf, err := protobuf.Encode(&c.CoinSwap)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("couldn't encode swap: %v", err)
+	}
+	log.Lvlf2("Offering %d coins for %d coins", c.Offered.Value, c.Wanted.Value)
+	sc = []byzcoin.StateChange{
+		byzcoin.NewStateChange(byzcoin.Create, inst.DeriveID(""),
+			ContractCoinSwapID, buf, darcID),
+	}
+	return
+}
+
+// Invoke implements the byzcoin.Contract interface.
+func (c *ContractCoinSwap) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+
+	switch inst.Invoke.Command {
+	case "accept":
+		cout, err = takeCoins(coins, c.Wanted)
+		if err != nil {
+			return
+		}
+		var payScs, destScs byzcoin.StateChanges
+		payScs, err = addCoins(rst, c.PayTo, c.Wanted)
+		if err != nil {
+			return nil, nil, xerrors.Errorf("paying: %v", err)
+		}
+		destScs, err = addCoins(rst, byzcoin.NewInstanceID(
+			inst.Invoke.Args.Search("destination")), c.Offered)
+		if err != nil {
+			return nil, nil, xerrors.Errorf("destination: %v", err)
+		}
+		sc = append(payScs, destScs...)
+	case "cancel":
+		sc, err = addCoins(rst, byzcoin.NewInstanceID(
+			inst.Invoke.Args.Search("destination")), c.Offered)
+		if err != nil {
+			return nil, nil, xerrors.Errorf("destination: %v", err)
+		}
+	default:
+		return nil, nil, xerrors.New("coinSwap contract can only accept or cancel")
+	}
+
+	sc = append(sc, byzcoin.NewStateChange(byzcoin.Remove, inst.InstanceID,
+		ContractCoinSwapID, nil, darcID))
+	return
+}
+
+// sumCoins adds up the given coins, which must all be of the same type.
+func sumCoins(coins []byzcoin.Coin) (sum byzcoin.Coin, err error) {
+	if len(coins) == 0 {
+		return sum, xerrors.New("no coins given")
+	}
+	sum.Name = coins[0].Name
+	for _, co := range coins {
+		if !co.Name.Equal(sum.Name) {
+			return sum, xerrors.New("coins must all be of the same type")
+		}
+		if err = sum.SafeAdd(co.Value); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// takeCoins removes the wanted coins from the given coins and returns the
+// remaining coins.
+func takeCoins(coins []byzcoin.Coin, wanted byzcoin.Coin) ([]byzcoin.Coin, error) {
+	var out []byzcoin.Coin
+	missing := wanted.Value
+	for _, co := range coins {
+		if co.Name.Equal(wanted.Name) && missing > 0 {
+			if co.Value > missing {
+				co.Value -= missing
+				missing = 0
+			} else {
+				missing -= co.Value
+				continue
+			}
+		}
+		out = append(out, co)
+	}
+	if missing > 0 {
+		return nil, xerrors.Errorf("missing %d coins", missing)
+	}
+	return out, nil
+}
+
+// addCoins returns the state change adding the coins to the coin instance.
+func addCoins(rst byzcoin.ReadOnlyStateTrie, id byzcoin.InstanceID, coins byzcoin.Coin) (byzcoin.StateChanges, error) {
+	target, darcID, err := getCoin(rst, id.Slice())
+	if err != nil {
+		return nil, err
+	}
+	if !target.Name.Equal(coins.Name) {
+		return nil, xerrors.New("instance holds another type of coin")
+	}
+	if err = target.SafeAdd(coins.Value); err != nil {
+		return nil, err
+	}
+	buf, err := protobuf.Encode(&target)
+	if err != nil {
+		return nil, xerrors.Errorf("couldn't marshal target account: %v", err)
+	}
+	return byzcoin.StateChanges{byzcoin.NewStateChange(byzcoin.Update, id,
+		ContractCoinID, buf, darcID)}, nil
+}
@@ -0,0 +1,104 @@
+package contracts
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/protobuf"
+)
+
+func TestCoinSwap(t *testing.T) {
+	ct := newCT("spawn:coinSwap", "invoke:coinSwap.accept", "invoke:coinSwap.cancel")
+	typeA := iid("typeA")
+	typeB := iid("typeB")
+
+	// Alice offers 2 coins of type A against 3 coins of type B.
+	aliceA, aliceB := iid("aliceA"), iid("aliceB")
+	bobA, bobB := iid("bobA"), iid("bobB")
+	store := func(id, name byzcoin.InstanceID, value uint64) {
+		buf, err := protobuf.Encode(&byzcoin.Coin{Name: name, Value: value})
+		require.NoError(t, err)
+		ct.Store(id, buf, ContractCoinID, gdarc.GetBaseID())
+	}
+	store(aliceA, typeA, 0)
+	store(aliceB, typeB, 0)
+	store(bobA, typeA, 0)
+	store(bobB, typeB, 0)
+
+	wantCoins := make([]byte, 8)
+	binary.LittleEndian.PutUint64(wantCoins, 3)
+	spawn := byzcoin.Instruction{
+		InstanceID: byzcoin.NewInstanceID(gdarc.GetBaseID()),
+		Spawn: &byzcoin.Spawn{
+			ContractID: ContractCoinSwapID,
+			Args: byzcoin.Arguments{
+				{Name: "wantType", Value: typeB.Slice()},
+				{Name: "wantCoins", Value: wantCoins},
+				{Name: "payTo", Value: aliceB.Slice()},
+			},
+		},
+		SignerIdentities: []darc.Identity{gsigner.Identity()},
+		SignerCounter:    []uint64{1},
+	}
+	c, _ := contractCoinSwapFromBytes(nil)
+	_, _, err := c.Spawn(ct, spawn, nil)
+	require.Error(t, err)
+	_, _, err = c.Spawn(ct, spawn, []byzcoin.Coin{{Name: typeB, Value: 2}})
+	require.Error(t, err)
+	sc, cout, err := c.Spawn(ct, spawn, []byzcoin.Coin{{Name: typeA, Value: 2}})
+	require.NoError(t, err)
+	require.Equal(t, 0, len(cout))
+	require.Equal(t, 1, len(sc))
+	swapID := spawn.DeriveID("")
+	ct.Store(swapID, sc[0].Value, ContractCoinSwapID, gdarc.GetBaseID())
+
+	getSwap := func() byzcoin.Contract {
+		c, err := contractCoinSwapFromBytes(ct.values[string(swapID.Slice())])
+		require.NoError(t, err)
+		return c
+	}
+	invoke := func(cmd string, dest byzcoin.InstanceID) byzcoin.Instruction {
+		return byzcoin.Instruction{
+			InstanceID: swapID,
+			Invoke: &byzcoin.Invoke{
+				ContractID: ContractCoinSwapID,
+				Command:    cmd,
+				Args:       byzcoin.Arguments{{Name: "destination", Value: dest.Slice()}},
+			},
+			SignerIdentities: []darc.Identity{gsigner.Identity()},
+			SignerCounter:    []uint64{1},
+		}
+	}
+	coinValue := func(s byzcoin.StateChange) byzcoin.Coin {
+		var co byzcoin.Coin
+		require.NoError(t, protobuf.Decode(s.Value, &co))
+		return co
+	}
+
+	// Bob doesn't give enough coins.
+	_, _, err = getSwap().Invoke(ct, invoke("accept", bobA), []byzcoin.Coin{{Name: typeB, Value: 2}})
+	require.Error(t, err)
+	// Bob gives the wrong destination.
+	_, _, err = getSwap().Invoke(ct, invoke("accept", bobB), []byzcoin.Coin{{Name: typeB, Value: 3}})
+	require.Error(t, err)
+
+	sc, cout, err = getSwap().Invoke(ct, invoke("accept", bobA), []byzcoin.Coin{{Name: typeB, Value: 4}})
+	require.NoError(t, err)
+	require.Equal(t, []byzcoin.Coin{{Name: typeB, Value: 1}}, cout)
+	require.Equal(t, 3, len(sc))
+	require.Equal(t, aliceB.Slice(), sc[0].InstanceID)
+	require.Equal(t, uint64(3), coinValue(sc[0]).Value)
+	require.Equal(t, bobA.Slice(), sc[1].InstanceID)
+	require.Equal(t, uint64(2), coinValue(sc[1]).Value)
+	require.Equal(t, byzcoin.Remove, sc[2].StateAction)
+
+	sc, _, err = getSwap().Invoke(ct, invoke("cancel", aliceA), nil)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(sc))
+	require.Equal(t, aliceA.Slice(), sc[0].InstanceID)
+	require.Equal(t, uint64(2), coinValue(sc[0]).Value)
+	require.Equal(t, byzcoin.Remove, sc[1].StateAction)
+}
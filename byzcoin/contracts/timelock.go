@@ -0,0 +1,196 @@
+package contracts
+
+import (
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/protobuf"
+	"golang.org/x/xerrors"
+)
+
+// ContractTimeLockID denotes a contract that holds coins or data until a
+// given block or time.
+const ContractTimeLockID = "timeLock"
+
+// ContractTimeLock holds coins and data that can only be released once a
+// given block index or time has been reached. The time is the timestamp of
+// the latest block of the chain, so that it is the same for all nodes.
+//
+// Spawn takes the coins given to the instruction, which must all be of the
+// same type, and the following arguments:
+//  - unlockIndex is the optional index of the block, as a 64-bit uint in
+//    LittleEndian, from which the lock can be released
+//  - unlockTime is the optional Unix timestamp in nanoseconds, as a 64-bit
+//    int in LittleEndian, from which the lock can be released
+//  - value is optional data that will be stored in a value instance on
+//    release
+//  - darcID is optional and defines the darc of the lock, which will also
+//    be the darc of the released value instance
+//
+// At least one of unlockIndex and unlockTime must be given. If both are
+// given, both must be reached.
+//
+// The following method is available:
+//  - release sends the coins to the coin instance given in the argument
+//    "destination", or passes them on to the next instruction if there is no
+//    destination. If the lock holds data, it is stored in a new value
+//    instance with the ID ReleaseID. The lock is removed.
+type ContractTimeLock struct {
+	byzcoin.BasicContract
+	TimeLock
+}
+
+// TimeLock is the data stored in a timeLock instance.
+type TimeLock struct {
+	// Coins are the locked coins, if any.
+	Coins byzcoin.Coin
+	// Value is the locked data, if any.
+	Value []byte
+	// ReleaseID is the ID of the value instance created on release.
+	ReleaseID byzcoin.InstanceID
+	// UnlockIndex is the index of the block from which the lock can be
+	// released.
+	UnlockIndex int
+	// UnlockTime is the Unix timestamp in nanoseconds from which the lock
+	// can be released.
+	UnlockTime int64
+}
+
+func contractTimeLockFromBytes(in []byte) (byzcoin.Contract, error) {
+	c := &ContractTimeLock{}
+	err := protobuf.Decode(in, &c.TimeLock)
+	if err != nil {
+		return nil, xerrors.Errorf("couldn't unmarshal instance data: %v", err)
+	}
+	return c, nil
+}
+
+// Spawn implements the byzcoin.Contract interface.
+func (c *ContractTimeLock) Spawn(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+	if did := inst.Spawn.Args.Search("darcID"); did != nil {
+		darcID = darc.ID(did)
+	}
+
+	args := inst.Spawn.Args
+	if args.Search("unlockIndex") == nil && args.Search("unlockTime") == nil {
+		return nil, nil, xerrors.New("need unlockIndex or unlockTime")
+	}
+	if args.Search("unlockIndex") != nil {
+		var index uint64
+		index, err = uint64Arg(args, "unlockIndex")
+		if err != nil {
+			return
+		}
+		c.UnlockIndex = int(index)
+	}
+	if args.Search("unlockTime") != nil {
+		var ts uint64
+		ts, err = uint64Arg(args, "unlockTime")
+		if err != nil {
+			return
+		}
+		c.UnlockTime = int64(ts)
+	}
+
+	if len(coins) > 0 {
+		c.Coins, err = sumCoins(coins)
+		if err != nil {
+			return nil, nil, xerrors.Errorf("locked coins: %v", err)
+		}
+	}
+	c.Value = args.Search("value")
+	if c.Coins.Value == 0 && c.Value == nil {
+		return nil, nil, xerrors.New("nothing to lock")
+	}
+	c.ReleaseID = inst.DeriveID("release")
+
+	buf, err := protobuf.Encode(&c.TimeLock)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("couldn't encode lock: %v", err)
+	}
+	log.Lvlf2("Locking %d coins until block %d and time %d", c.Coins.Value,
+		c.UnlockIndex, c.UnlockTime)
+	sc = []byzcoin.StateChange{
+		byzcoin.NewStateChange(byzcoin.Create, inst.DeriveID(""),
+			ContractTimeLockID, buf, darcID),
+	}
+	return
+}
+
+// Invoke implements the byzcoin.Contract interface.
+func (c *ContractTimeLock) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+
+	if inst.Invoke.Command != "release" {
+		return nil, nil, xerrors.New("timeLock contract can only release")
+	}
+	if err = c.checkUnlocked(rst); err != nil {
+		return
+	}
+
+	if c.Coins.Value > 0 {
+		dest := inst.Invoke.Args.Search("destination")
+		if dest == nil {
+			cout = append(cout, c.Coins)
+		} else {
+			sc, err = addCoins(rst, byzcoin.NewInstanceID(dest), c.Coins)
+			if err != nil {
+				return nil, nil, xerrors.Errorf("destination: %v", err)
+			}
+		}
+	}
+	if c.Value != nil {
+		sc = append(sc, byzcoin.NewStateChange(byzcoin.Create, c.ReleaseID,
+			ContractValueID, c.Value, darcID))
+	}
+	sc = append(sc, byzcoin.NewStateChange(byzcoin.Remove, inst.InstanceID,
+		ContractTimeLockID, nil, darcID))
+	return
+}
+
+// checkUnlocked returns an error if the lock cannot be released yet.
+func (c *ContractTimeLock) checkUnlocked(rst byzcoin.ReadOnlyStateTrie) error {
+	if rst.GetIndex() < c.UnlockIndex {
+		return xerrors.Errorf("locked until block %d", c.UnlockIndex)
+	}
+	if c.UnlockTime == 0 {
+		return nil
+	}
+	now, err := chainTime(rst)
+	if err != nil {
+		return err
+	}
+	if now < c.UnlockTime {
+		return xerrors.Errorf("locked until time %d", c.UnlockTime)
+	}
+	return nil
+}
+
+// chainTime returns the timestamp of the latest block of the chain, in Unix
+// nanoseconds. Unlike the local time, it is the same for all nodes.
+func chainTime(rst byzcoin.ReadOnlyStateTrie) (int64, error) {
+	gs, ok := rst.(byzcoin.GlobalState)
+	if !ok {
+		return 0, xerrors.New("cannot read the time of the chain")
+	}
+	sb, err := gs.GetBlockByIndex(rst.GetIndex())
+	if err != nil {
+		return 0, xerrors.Errorf("getting latest block: %v", err)
+	}
+	var header byzcoin.DataHeader
+	if err = protobuf.Decode(sb.Data, &header); err != nil {
+		return 0, xerrors.Errorf("decoding header: %v", err)
+	}
+	return header.Timestamp, nil
+}
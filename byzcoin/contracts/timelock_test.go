@@ -0,0 +1,126 @@
+package contracts
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/skipchain"
+	"go.dedis.ch/protobuf"
+	"golang.org/x/xerrors"
+)
+
+// timeTest adds a skipchain to cvTest, whose blocks all have the same
+// timestamp.
+type timeTest struct {
+	*cvTest
+	timestamp int64
+}
+
+func (tt timeTest) GetLatest() (*skipchain.SkipBlock, error) {
+	return tt.GetBlockByIndex(tt.GetIndex())
+}
+
+func (tt timeTest) GetGenesisBlock() (*skipchain.SkipBlock, error) {
+	return tt.GetBlockByIndex(0)
+}
+
+func (tt timeTest) GetBlock(skipchain.SkipBlockID) (*skipchain.SkipBlock, error) {
+	return nil, xerrors.New("not implemented")
+}
+
+func (tt timeTest) GetBlockByIndex(idx int) (*skipchain.SkipBlock, error) {
+	buf, err := protobuf.Encode(&byzcoin.DataHeader{Timestamp: tt.timestamp})
+	if err != nil {
+		return nil, err
+	}
+	sb := skipchain.NewSkipBlock()
+	sb.Index = idx
+	sb.Data = buf
+	return sb, nil
+}
+
+func TestTimeLock(t *testing.T) {
+	ct := newCT("spawn:timeLock", "invoke:timeLock.release")
+	tt := timeTest{cvTest: ct, timestamp: 100}
+	buf, err := protobuf.Encode(&byzcoin.Coin{Name: CoinName})
+	require.NoError(t, err)
+	dest := iid("dest")
+	ct.Store(dest, buf, ContractCoinID, gdarc.GetBaseID())
+
+	u64 := func(v uint64) []byte {
+		buf := make([]byte, 8)
+		binary.LittleEndian.PutUint64(buf, v)
+		return buf
+	}
+	spawn := func(args byzcoin.Arguments, coins []byzcoin.Coin) (byzcoin.InstanceID, error) {
+		inst := byzcoin.Instruction{
+			InstanceID: byzcoin.NewInstanceID(gdarc.GetBaseID()),
+			Spawn: &byzcoin.Spawn{
+				ContractID: ContractTimeLockID,
+				Args:       args,
+			},
+		}
+		c, err := contractTimeLockFromBytes(nil)
+		require.NoError(t, err)
+		sc, _, err := c.Spawn(ct, inst, coins)
+		if err != nil {
+			return byzcoin.InstanceID{}, err
+		}
+		ct.Store(inst.DeriveID(""), sc[0].Value, ContractTimeLockID, gdarc.GetBaseID())
+		return inst.DeriveID(""), nil
+	}
+	release := func(rst byzcoin.ReadOnlyStateTrie, id byzcoin.InstanceID, args byzcoin.Arguments) (byzcoin.StateChanges, []byzcoin.Coin, error) {
+		c, err := contractTimeLockFromBytes(ct.values[string(id.Slice())])
+		require.NoError(t, err)
+		return c.Invoke(rst, byzcoin.Instruction{
+			InstanceID: id,
+			Invoke: &byzcoin.Invoke{
+				ContractID: ContractTimeLockID,
+				Command:    "release",
+				Args:       args,
+			},
+		}, nil)
+	}
+	coins := []byzcoin.Coin{{Name: CoinName, Value: 10}}
+
+	_, err = spawn(nil, coins)
+	require.Error(t, err)
+	_, err = spawn(byzcoin.Arguments{{Name: "unlockIndex", Value: u64(1)}}, nil)
+	require.Error(t, err)
+
+	// Lock coins until a block index.
+	index := uint64(ct.GetIndex() + 2)
+	lock, err := spawn(byzcoin.Arguments{{Name: "unlockIndex", Value: u64(index)}}, coins)
+	require.NoError(t, err)
+	_, _, err = release(ct, lock, nil)
+	require.Error(t, err)
+	ct.Store(iid("dummy"), nil, "", nil)
+	_, cout, err := release(ct, lock, nil)
+	require.NoError(t, err)
+	require.Equal(t, coins, cout)
+	sc, _, err := release(ct, lock, byzcoin.Arguments{{Name: "destination", Value: dest.Slice()}})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(sc))
+	require.Equal(t, dest.Slice(), sc[0].InstanceID)
+	require.Equal(t, byzcoin.Remove, sc[1].StateAction)
+
+	// Lock data until a time.
+	lock, err = spawn(byzcoin.Arguments{
+		{Name: "unlockTime", Value: u64(200)},
+		{Name: "value", Value: []byte("data")},
+	}, nil)
+	require.NoError(t, err)
+	_, _, err = release(ct, lock, nil)
+	require.Error(t, err)
+	_, _, err = release(tt, lock, nil)
+	require.Error(t, err)
+	tt.timestamp = 200
+	sc, _, err = release(tt, lock, nil)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(sc))
+	require.Equal(t, byzcoin.Create, sc[0].StateAction)
+	require.Equal(t, ContractValueID, sc[0].ContractID)
+	require.Equal(t, []byte("data"), sc[0].Value)
+}
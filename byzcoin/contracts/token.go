@@ -0,0 +1,340 @@
+package contracts
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/protobuf"
+	"golang.org/x/xerrors"
+)
+
+// ContractTokenFactoryID denotes a contract that creates new types of coins.
+const ContractTokenFactoryID = "tokenFactory"
+
+// ContractTokenID denotes a contract holding the metadata of a type of coins
+// created by a tokenFactory.
+const ContractTokenID = "token"
+
+// ContractTokenFactory creates new types of coins, called tokens, and keeps
+// the list of the tokens it created. The darc of the factory defines who can
+// create tokens.
+//
+// Spawn takes the optional argument "darcID" to define the darc of the
+// factory.
+//
+// The following method is available:
+//  - create creates a token instance with the ID returned by TokenID. It
+//    needs the arguments "name", "symbol", which must be unique in the
+//    factory, "decimals" and "admin", the darc allowed to mint the token. The
+//    optional argument "maxSupply" limits the number of coins that can be
+//    minted. The numbers are 64-bit uints in LittleEndian.
+//
+// The tokens of a factory can be listed with GetFactoryTokens.
+type ContractTokenFactory struct {
+	byzcoin.BasicContract
+	TokenFactory
+}
+
+// TokenFactory is the data stored in a tokenFactory instance.
+type TokenFactory struct {
+	// Tokens are the IDs of the token instances created by the factory.
+	Tokens []byzcoin.InstanceID
+}
+
+// ContractToken holds the metadata and the supply of a type of coins. The
+// name of the coins is the ID of the token instance, and the darc of the
+// instance is the minting authority.
+//
+// The following methods are available:
+//  - mint adds the number of coins in the argument "coins", as a 64-bit uint
+//    in LittleEndian, to the coin instance "destination", which must hold
+//    coins of this token.
+//  - transferAdmin replaces the darc of the token, and thus the minting
+//    authority, with the darc given in the argument "darcID".
+//
+// Coins of the token that are burnt are removed from its supply.
+type ContractToken struct {
+	byzcoin.BasicContract
+	Token
+}
+
+// Token is the data stored in a token instance.
+type Token struct {
+	// Factory is the ID of the factory that created the token.
+	Factory   byzcoin.InstanceID
+	Name      string
+	Symbol    string
+	Decimals  uint64
+	MaxSupply uint64
+	// Supply is the number of coins minted minus the ones burnt.
+	Supply uint64
+}
+
+// TokenID returns the instance ID of the token with the given symbol,
+// created by the factory. It is also the name of the coins of the token.
+func TokenID(factory byzcoin.InstanceID, symbol string) byzcoin.InstanceID {
+	h := sha256.New()
+	h.Write([]byte(ContractTokenID))
+	h.Write(factory.Slice())
+	h.Write([]byte(symbol))
+	return byzcoin.NewInstanceID(h.Sum(nil))
+}
+
+func contractTokenFactoryFromBytes(in []byte) (byzcoin.Contract, error) {
+	c := &ContractTokenFactory{}
+	err := protobuf.Decode(in, &c.TokenFactory)
+	if err != nil {
+		return nil, xerrors.Errorf("couldn't unmarshal instance data: %v", err)
+	}
+	return c, nil
+}
+
+// Spawn implements the byzcoin.Contract interface.
+func (c *ContractTokenFactory) Spawn(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+	if did := inst.Spawn.Args.Search("darcID"); did != nil {
+		darcID = darc.ID(did)
+	}
+
+	buf, err := protobuf.Encode(&c.TokenFactory)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("couldn't encode factory: %v", err)
+	}
+	sc = []byzcoin.StateChange{
+		byzcoin.NewStateChange(byzcoin.Create, inst.DeriveID(""),
+			ContractTokenFactoryID, buf, darcID),
+	}
+	return
+}
+
+// Invoke implements the byzcoin.Contract interface.
+func (c *ContractTokenFactory) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+
+	if inst.Invoke.Command != "create" {
+		return nil, nil, xerrors.New("tokenFactory contract can only create")
+	}
+	args := inst.Invoke.Args
+	token := Token{
+		Factory: inst.InstanceID,
+		Name:    string(args.Search("name")),
+		Symbol:  string(args.Search("symbol")),
+	}
+	if token.Name == "" || token.Symbol == "" {
+		return nil, nil, xerrors.New("need a name and a symbol")
+	}
+	token.Decimals, err = uint64Arg(args, "decimals")
+	if err != nil {
+		return
+	}
+	if args.Search("maxSupply") != nil {
+		token.MaxSupply, err = uint64Arg(args, "maxSupply")
+		if err != nil {
+			return
+		}
+	}
+	admin := darc.ID(args.Search("admin"))
+	if len(admin) != len(byzcoin.InstanceID{}) {
+		return nil, nil, xerrors.New("admin needs to be a darc ID")
+	}
+
+	id := TokenID(inst.InstanceID, token.Symbol)
+	if buf, _, _, _, err := rst.GetValues(id.Slice()); err == nil && buf != nil {
+		return nil, nil, xerrors.Errorf("symbol %s already exists", token.Symbol)
+	}
+	tokenBuf, err := protobuf.Encode(&token)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("couldn't encode token: %v", err)
+	}
+	c.Tokens = append(c.Tokens, id)
+	buf, err := protobuf.Encode(&c.TokenFactory)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("couldn't encode factory: %v", err)
+	}
+	log.Lvlf2("Creating token %s (%s)", token.Name, token.Symbol)
+	sc = []byzcoin.StateChange{
+		byzcoin.NewStateChange(byzcoin.Create, id, ContractTokenID, tokenBuf, admin),
+		byzcoin.NewStateChange(byzcoin.Update, inst.InstanceID,
+			ContractTokenFactoryID, buf, darcID),
+	}
+	return
+}
+
+func contractTokenFromBytes(in []byte) (byzcoin.Contract, error) {
+	c := &ContractToken{}
+	err := protobuf.Decode(in, &c.Token)
+	if err != nil {
+		return nil, xerrors.Errorf("couldn't unmarshal instance data: %v", err)
+	}
+	return c, nil
+}
+
+// Invoke implements the byzcoin.Contract interface.
+func (c *ContractToken) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+
+	switch inst.Invoke.Command {
+	case "mint":
+		var value uint64
+		value, err = uint64Arg(inst.Invoke.Args, "coins")
+		if err != nil {
+			return
+		}
+		if err = c.changeSupply(value, true); err != nil {
+			return
+		}
+		dest := byzcoin.NewInstanceID(inst.Invoke.Args.Search("destination"))
+		sc, err = addCoins(rst, dest, byzcoin.Coin{Name: inst.InstanceID, Value: value})
+		if err != nil {
+			return nil, nil, xerrors.Errorf("destination: %v", err)
+		}
+		log.Lvlf2("Minting %d %s", value, c.Symbol)
+	case "transferAdmin":
+		admin := darc.ID(inst.Invoke.Args.Search("darcID"))
+		if len(admin) != len(byzcoin.InstanceID{}) {
+			return nil, nil, xerrors.New("darcID needs to be a darc ID")
+		}
+		if _, err = byzcoin.LoadDarcFromTrie(rst, admin); err != nil {
+			return nil, nil, xerrors.Errorf("loading new admin: %v", err)
+		}
+		darcID = admin
+	default:
+		return nil, nil, xerrors.New("token contract can only mint or transferAdmin")
+	}
+
+	buf, err := protobuf.Encode(&c.Token)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("couldn't encode token: %v", err)
+	}
+	sc = append(sc, byzcoin.NewStateChange(byzcoin.Update, inst.InstanceID,
+		ContractTokenID, buf, darcID))
+	return
+}
+
+// changeSupply adds the minted coins to the supply, or removes the burnt
+// coins from it.
+func (c *ContractToken) changeSupply(coins uint64, mint bool) error {
+	if !mint {
+		if coins > c.Supply {
+			return xerrors.New("burning more coins than the supply")
+		}
+		c.Supply -= coins
+		return nil
+	}
+	if c.Supply+coins < c.Supply {
+		return xerrors.New("supply overflow")
+	}
+	if c.MaxSupply > 0 && c.Supply+coins > c.MaxSupply {
+		return xerrors.Errorf("minting %d coins would exceed the maximum "+
+			"supply of %d", coins, c.MaxSupply)
+	}
+	c.Supply += coins
+	return nil
+}
+
+// burnToken removes the burnt coins from the supply of the token with the
+// given ID. It returns nil if the ID is not a token.
+func burnToken(rst byzcoin.ReadOnlyStateTrie, id byzcoin.InstanceID, coins uint64) (byzcoin.StateChanges, error) {
+	buf, _, cid, darcID, err := rst.GetValues(id.Slice())
+	if err != nil || cid != ContractTokenID {
+		return nil, nil
+	}
+	var c ContractToken
+	if err = protobuf.Decode(buf, &c.Token); err != nil {
+		return nil, xerrors.Errorf("couldn't unmarshal token: %v", err)
+	}
+	if err = c.changeSupply(coins, false); err != nil {
+		return nil, err
+	}
+	buf, err = protobuf.Encode(&c.Token)
+	if err != nil {
+		return nil, xerrors.Errorf("couldn't encode token: %v", err)
+	}
+	return byzcoin.StateChanges{byzcoin.NewStateChange(byzcoin.Update, id,
+		ContractTokenID, buf, darcID)}, nil
+}
+
+// GetFactoryTokens returns the verified tokens created by the factory, in
+// the order of their creation.
+func GetFactoryTokens(cl *byzcoin.Client, factory byzcoin.InstanceID) ([]Token, error) {
+	var tf TokenFactory
+	if err := getVerified(cl, factory, ContractTokenFactoryID, &tf); err != nil {
+		return nil, xerrors.Errorf("getting factory: %v", err)
+	}
+	tokens := make([]Token, len(tf.Tokens))
+	for i, id := range tf.Tokens {
+		if err := getVerified(cl, id, ContractTokenID, &tokens[i]); err != nil {
+			return nil, xerrors.Errorf("getting token %x: %v", id[:], err)
+		}
+	}
+	return tokens, nil
+}
+
+// getVerified decodes the data of the instance into out, after verifying
+// its proof and its contract.
+func getVerified(cl *byzcoin.Client, id byzcoin.InstanceID, contractID string, out interface{}) error {
+	pr, err := cl.GetProof(id.Slice())
+	if err != nil {
+		return xerrors.Errorf("getting proof: %v", err)
+	}
+	if err := pr.Proof.Verify(cl.ID); err != nil {
+		return xerrors.Errorf("verifying proof: %v", err)
+	}
+	_, value, cid, _, err := pr.Proof.KeyValue()
+	if err != nil {
+		return xerrors.Errorf("reading proof: %v", err)
+	}
+	if cid != contractID {
+		return xerrors.Errorf("instance is not a %s", contractID)
+	}
+	return protobuf.Decode(value, out)
+}
+
+// TokenCreateInstruction returns the instruction creating a token in the
+// factory. A maxSupply of 0 means the supply is not limited. The signer
+// counters of the instruction must be set before signing it.
+func TokenCreateInstruction(factory byzcoin.InstanceID, name, symbol string,
+	decimals, maxSupply uint64, admin darc.ID) byzcoin.Instruction {
+	decBuf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(decBuf, decimals)
+	args := byzcoin.Arguments{
+		{Name: "name", Value: []byte(name)},
+		{Name: "symbol", Value: []byte(symbol)},
+		{Name: "decimals", Value: decBuf},
+		{Name: "admin", Value: admin},
+	}
+	if maxSupply > 0 {
+		msBuf := make([]byte, 8)
+		binary.LittleEndian.PutUint64(msBuf, maxSupply)
+		args = append(args, byzcoin.Argument{Name: "maxSupply", Value: msBuf})
+	}
+	return byzcoin.Instruction{
+		InstanceID: factory,
+		Invoke: &byzcoin.Invoke{
+			ContractID: ContractTokenFactoryID,
+			Command:    "create",
+			Args:       args,
+		},
+	}
+}
@@ -0,0 +1,140 @@
+package contracts
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/protobuf"
+)
+
+func TestTokenFactory(t *testing.T) {
+	ct := newCT()
+	config, err := protobuf.Encode(&byzcoin.ChainConfig{DarcContractIDs: []string{"darc"}})
+	require.NoError(t, err)
+	ct.Store(byzcoin.ConfigInstanceID, config, "config", gdarc.GetBaseID())
+
+	spawn := byzcoin.Instruction{
+		InstanceID: byzcoin.NewInstanceID(gdarc.GetBaseID()),
+		Spawn:      &byzcoin.Spawn{ContractID: ContractTokenFactoryID},
+	}
+	c, err := contractTokenFactoryFromBytes(nil)
+	require.NoError(t, err)
+	sc, _, err := c.Spawn(ct, spawn, nil)
+	require.NoError(t, err)
+	factory := spawn.DeriveID("")
+	ct.Store(factory, sc[0].Value, ContractTokenFactoryID, gdarc.GetBaseID())
+
+	create := func(inst byzcoin.Instruction) error {
+		c, err := contractTokenFactoryFromBytes(ct.values[string(factory.Slice())])
+		require.NoError(t, err)
+		sc, _, err := c.Invoke(ct, inst, nil)
+		if err != nil {
+			return err
+		}
+		for _, s := range sc {
+			ct.Store(byzcoin.NewInstanceID(s.InstanceID), s.Value, s.ContractID, s.DarcID)
+		}
+		return nil
+	}
+	require.NoError(t, create(TokenCreateInstruction(factory, "Gold", "GLD", 2, 100, gdarc.GetBaseID())))
+	require.Error(t, create(TokenCreateInstruction(factory, "Fake gold", "GLD", 2, 0, gdarc.GetBaseID())))
+	require.Error(t, create(TokenCreateInstruction(factory, "Silver", "SLV", 2, 0, nil)))
+	require.NoError(t, create(TokenCreateInstruction(factory, "Silver", "SLV", 0, 0, gdarc.GetBaseID())))
+
+	var tf TokenFactory
+	require.NoError(t, protobuf.Decode(ct.values[string(factory.Slice())], &tf))
+	gold := TokenID(factory, "GLD")
+	require.Equal(t, []byzcoin.InstanceID{gold, TokenID(factory, "SLV")}, tf.Tokens)
+	require.Equal(t, gdarc.GetBaseID(), ct.darcIDs[string(gold.Slice())])
+
+	account := iid("account")
+	buf, err := protobuf.Encode(&byzcoin.Coin{Name: gold})
+	require.NoError(t, err)
+	ct.Store(account, buf, ContractCoinID, gdarc.GetBaseID())
+
+	invoke := func(cmd string, args byzcoin.Arguments) error {
+		c, err := contractTokenFromBytes(ct.values[string(gold.Slice())])
+		require.NoError(t, err)
+		sc, _, err := c.Invoke(ct, byzcoin.Instruction{
+			InstanceID: gold,
+			Invoke: &byzcoin.Invoke{
+				ContractID: ContractTokenID,
+				Command:    cmd,
+				Args:       args,
+			},
+		}, nil)
+		if err != nil {
+			return err
+		}
+		for _, s := range sc {
+			ct.Store(byzcoin.NewInstanceID(s.InstanceID), s.Value, s.ContractID, s.DarcID)
+		}
+		return nil
+	}
+	mint := func(value uint64) error {
+		buf := make([]byte, 8)
+		binary.LittleEndian.PutUint64(buf, value)
+		return invoke("mint", byzcoin.Arguments{
+			{Name: "coins", Value: buf},
+			{Name: "destination", Value: account.Slice()},
+		})
+	}
+	supply := func() uint64 {
+		var tok Token
+		require.NoError(t, protobuf.Decode(ct.values[string(gold.Slice())], &tok))
+		return tok.Supply
+	}
+
+	require.NoError(t, mint(60))
+	require.Error(t, mint(41))
+	require.Equal(t, uint64(60), coinsOf(ct, "account"))
+	require.Equal(t, uint64(60), supply())
+
+	// Burnt coins are removed from the supply of the token.
+	cc, err := contractCoinFromBytes(ct.values[string(account.Slice())])
+	require.NoError(t, err)
+	burn := make([]byte, 8)
+	binary.LittleEndian.PutUint64(burn, 20)
+	sc, _, err = cc.Invoke(ct, byzcoin.Instruction{
+		InstanceID: account,
+		Invoke: &byzcoin.Invoke{
+			ContractID: ContractCoinID,
+			Command:    "burn",
+			Args:       byzcoin.Arguments{{Name: "coins", Value: burn}},
+		},
+	}, nil)
+	require.NoError(t, err)
+	for _, s := range sc {
+		ct.Store(byzcoin.NewInstanceID(s.InstanceID), s.Value, s.ContractID, s.DarcID)
+	}
+	require.Equal(t, uint64(40), supply())
+	require.NoError(t, mint(60))
+
+	// The accounts of the token cannot mint themselves.
+	cc, err = contractCoinFromBytes(ct.values[string(account.Slice())])
+	require.NoError(t, err)
+	_, _, err = cc.Invoke(ct, byzcoin.Instruction{
+		InstanceID: account,
+		Invoke: &byzcoin.Invoke{
+			ContractID: ContractCoinID,
+			Command:    "mint",
+			Args:       byzcoin.Arguments{{Name: "coins", Value: burn}},
+		},
+	}, nil)
+	require.Error(t, err)
+
+	// The minting authority can only be given to an existing darc.
+	require.Error(t, invoke("transferAdmin", byzcoin.Arguments{
+		{Name: "darcID", Value: iid("unknown").Slice()}}))
+	admin := darc.NewDarc(darc.InitRules([]darc.Identity{gsigner.Identity()},
+		[]darc.Identity{gsigner.Identity()}), []byte("admin"))
+	adminBuf, err := admin.ToProto()
+	require.NoError(t, err)
+	ct.Store(byzcoin.NewInstanceID(admin.GetBaseID()), adminBuf, "darc", admin.GetBaseID())
+	require.NoError(t, invoke("transferAdmin", byzcoin.Arguments{
+		{Name: "darcID", Value: admin.GetBaseID()}}))
+	require.Equal(t, admin.GetBaseID(), ct.darcIDs[string(gold.Slice())])
+}
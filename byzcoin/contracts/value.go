@@ -1,6 +1,9 @@
 package contracts
 
 import (
+	"bytes"
+	"sort"
+
 	"go.dedis.ch/cothority/v3/byzcoin"
 	"go.dedis.ch/cothority/v3/darc"
 	"golang.org/x/xerrors"
@@ -18,6 +21,10 @@ var ContractValueID = "value"
 // can put any data inside as wished.
 // It can spawn new value instances and will store the "value" argument in these
 // new instances. Existing value instances can be updated and deleted.
+//
+// The append command adds the "value" argument to the end of the stored
+// value. If the darc of an instance only allows append, the instance becomes
+// an append-only log, and GetValueHistory returns the verified entries.
 type ContractValue struct {
 	byzcoin.BasicContract
 	value []byte
@@ -64,8 +71,19 @@ func (c ContractValue) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instru
 				ContractValueID, inst.Invoke.Args.Search("value"), darcID),
 		}
 		return
+	case "append":
+		entry := inst.Invoke.Args.Search("value")
+		if len(entry) == 0 {
+			return nil, nil, xerrors.New("nothing to append")
+		}
+		value := append(append([]byte{}, c.value...), entry...)
+		sc = []byzcoin.StateChange{
+			byzcoin.NewStateChange(byzcoin.Update, inst.InstanceID,
+				ContractValueID, value, darcID),
+		}
+		return
 	default:
-		return nil, nil, xerrors.New("Value contract can only update")
+		return nil, nil, xerrors.New("Value contract can only update or append")
 	}
 }
 
@@ -90,3 +108,72 @@ func (c ContractValue) Delete(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instru
 func (c ContractValue) VerifyDeferredInstruction(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, ctxHash []byte) error {
 	return inst.VerifyWithOption(rst, ctxHash, &byzcoin.VerificationOptions{IgnoreCounters: true})
 }
+
+// ValueEntry is one entry of the history of a value instance.
+type ValueEntry struct {
+	// Version is the version of the instance that added this entry.
+	Version uint64
+	// BlockIndex is the index of the block holding the entry.
+	BlockIndex int
+	// Entry is the value given to spawn or to append.
+	Entry []byte
+	// Proof holds the block with the state change of this entry.
+	Proof *byzcoin.GetInstanceAtResponse
+}
+
+// GetValueHistory returns the entries of a value instance that has only been
+// appended to, in the order they were added. Every entry is verified against
+// the blocks of the chain, and an error is returned if the instance has
+// been updated or removed.
+func GetValueHistory(cl *byzcoin.Client, id byzcoin.InstanceID) ([]ValueEntry, error) {
+	all, err := cl.GetAllInstanceVersion(id)
+	if err != nil {
+		return nil, xerrors.Errorf("getting versions: %v", err)
+	}
+	var blocks []int
+	for _, sc := range all.StateChanges {
+		blocks = append(blocks, sc.BlockIndex)
+	}
+	sort.Ints(blocks)
+
+	// The node is only used to know the blocks holding the state changes,
+	// which are then taken from the verified blocks.
+	var history []ValueEntry
+	var value []byte
+	for i, index := range blocks {
+		if i > 0 && index == blocks[i-1] {
+			continue
+		}
+		resp, err := cl.GetInstanceAt(id, index)
+		if err != nil {
+			return nil, xerrors.Errorf("getting block %d: %v", index, err)
+		}
+		for _, sc := range resp.StateChanges {
+			if !bytes.Equal(sc.InstanceID, id.Slice()) {
+				continue
+			}
+			if sc.ContractID != ContractValueID {
+				return nil, xerrors.Errorf("version %d is not a value", sc.Version)
+			}
+			switch {
+			case sc.StateAction == byzcoin.Create && len(history) == 0:
+			case sc.StateAction == byzcoin.Update && len(history) > 0 &&
+				sc.Version == history[len(history)-1].Version+1 &&
+				len(sc.Value) > len(value) && bytes.HasPrefix(sc.Value, value):
+			default:
+				return nil, xerrors.Errorf("version %d is not an append", sc.Version)
+			}
+			history = append(history, ValueEntry{
+				Version:    sc.Version,
+				BlockIndex: index,
+				Entry:      sc.Value[len(value):],
+				Proof:      resp,
+			})
+			value = sc.Value
+		}
+	}
+	if len(history) == 0 {
+		return nil, xerrors.New("no history found")
+	}
+	return history, nil
+}
@@ -135,3 +135,78 @@ func TestValue_Invoke(t *testing.T) {
 
 	local.WaitDone(genesisMsg.BlockInterval)
 }
+
+// This test appends entries to a value instance and verifies its history.
+func TestValue_Append(t *testing.T) {
+	local := onet.NewTCPTest(cothority.Suite)
+	defer local.CloseAll()
+
+	signer := darc.NewSignerEd25519(nil, nil)
+	_, roster, _ := local.GenTree(3, true)
+
+	genesisMsg, err := byzcoin.DefaultGenesisMsg(byzcoin.CurrentVersion, roster,
+		[]string{"spawn:value", "invoke:value.append", "invoke:value.update"}, signer.Identity())
+	require.Nil(t, err)
+	gDarc := &genesisMsg.GenesisDarc
+
+	genesisMsg.BlockInterval = time.Second
+
+	cl, _, err := byzcoin.NewLedger(genesisMsg, false)
+	require.Nil(t, err)
+
+	ctx, err := cl.CreateTransaction(byzcoin.Instruction{
+		InstanceID: byzcoin.NewInstanceID(gDarc.GetBaseID()),
+		Spawn: &byzcoin.Spawn{
+			ContractID: ContractValueID,
+			Args: []byzcoin.Argument{{
+				Name:  "value",
+				Value: []byte("a"),
+			}},
+		},
+		SignerCounter: []uint64{1},
+	})
+	require.NoError(t, err)
+	require.Nil(t, ctx.FillSignersAndSignWith(signer))
+	_, err = cl.AddTransactionAndWait(ctx, 10)
+	require.Nil(t, err)
+	myID := ctx.Instructions[0].DeriveID("")
+
+	invoke := func(cmd string, value []byte, counter uint64) error {
+		ctx, err := cl.CreateTransaction(byzcoin.Instruction{
+			InstanceID: myID,
+			Invoke: &byzcoin.Invoke{
+				ContractID: ContractValueID,
+				Command:    cmd,
+				Args: []byzcoin.Argument{{
+					Name:  "value",
+					Value: value,
+				}},
+			},
+			SignerCounter: []uint64{counter},
+		})
+		require.NoError(t, err)
+		require.Nil(t, ctx.FillSignersAndSignWith(signer))
+		_, err = cl.AddTransactionAndWait(ctx, 10)
+		return err
+	}
+	require.Error(t, invoke("append", nil, 2))
+	require.NoError(t, invoke("append", []byte("b"), 2))
+	require.NoError(t, invoke("append", []byte("c"), 3))
+
+	history, err := GetValueHistory(cl, myID)
+	require.NoError(t, err)
+	require.Equal(t, 3, len(history))
+	for i, entry := range []string{"a", "b", "c"} {
+		require.Equal(t, uint64(i), history[i].Version)
+		require.Equal(t, []byte(entry), history[i].Entry)
+		require.NotNil(t, history[i].Proof)
+	}
+
+	// Once the value is updated, the history is not an append-only log
+	// anymore.
+	require.NoError(t, invoke("update", []byte("x"), 4))
+	_, err = GetValueHistory(cl, myID)
+	require.Error(t, err)
+
+	local.WaitDone(genesisMsg.BlockInterval)
+}
@@ -0,0 +1,245 @@
+package contracts
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/protobuf"
+	"golang.org/x/xerrors"
+)
+
+// ContractVerifiableCredentialID denotes a contract that anchors credentials
+// signed by an issuer.
+const ContractVerifiableCredentialID = "verifiableCredential"
+
+// ContractVerifiableCredential stores a credential: a set of attributes
+// about a subject, signed by an issuer. The darc used to spawn the credential
+// defines who can store credentials, and the darc of the credential defines
+// who can revoke it.
+//
+// Spawn stores a credential with the argument "credential", which holds a
+// protobuf-encoded VerifiableCredential that is signed by the issuer. The
+// darc of the credential is chosen by the issuer and covered by its
+// signature, so whoever stores the credential first cannot take over its
+// revocation. The instance ID of a credential is returned by CredentialID.
+//
+// The following methods are available:
+//  - revoke marks the credential as revoked.
+//  - verify returns an error if the credential is revoked or expired. It
+//    doesn't change the credential, and can be used in a transaction that
+//    must only be accepted with a valid credential.
+type ContractVerifiableCredential struct {
+	byzcoin.BasicContract
+	VerifiableCredential
+}
+
+// VerifiableCredential holds the attributes given by an issuer to a
+// subject.
+type VerifiableCredential struct {
+	// Issuer is the identity of the issuer, as returned by
+	// darc.Identity.String.
+	Issuer string
+	// Darc is the darc of the credential, which defines who can revoke
+	// it.
+	Darc darc.ID
+	// Subject is the identity of the subject, in a format defined by the
+	// application.
+	Subject    string
+	Attributes []CredentialAttribute
+	// Expiry is the Unix timestamp in nanoseconds at which the credential
+	// expires, or 0 if it doesn't expire.
+	Expiry int64
+	// Signature is the signature of the issuer on the Hash of the
+	// credential.
+	Signature []byte
+	// Revoked is set by the revoke command.
+	Revoked bool
+}
+
+// CredentialAttribute is one attribute of a credential.
+type CredentialAttribute struct {
+	Name  string
+	Value []byte
+}
+
+// Hash returns the hash of the credential that is signed by the issuer. It
+// doesn't include the signature and the revocation.
+func (vc VerifiableCredential) Hash() []byte {
+	h := sha256.New()
+	write := func(b []byte) {
+		l := make([]byte, 8)
+		binary.LittleEndian.PutUint64(l, uint64(len(b)))
+		h.Write(l)
+		h.Write(b)
+	}
+	write([]byte(vc.Issuer))
+	write(vc.Darc)
+	write([]byte(vc.Subject))
+	for _, a := range vc.Attributes {
+		write([]byte(a.Name))
+		write(a.Value)
+	}
+	expiry := make([]byte, 8)
+	binary.LittleEndian.PutUint64(expiry, uint64(vc.Expiry))
+	h.Write(expiry)
+	return h.Sum(nil)
+}
+
+// Sign sets the signature of the issuer.
+func (vc *VerifiableCredential) Sign(issuer darc.Signer) error {
+	if issuer.Identity().String() != vc.Issuer {
+		return xerrors.New("signer is not the issuer")
+	}
+	sig, err := issuer.Sign(vc.Hash())
+	if err != nil {
+		return xerrors.Errorf("signing: %v", err)
+	}
+	vc.Signature = sig
+	return nil
+}
+
+// Verify checks the signature of the issuer, and that the credential is
+// neither revoked nor expired at the given time, in Unix nanoseconds.
+func (vc VerifiableCredential) Verify(now int64) error {
+	issuer, err := darc.ParseIdentity(vc.Issuer)
+	if err != nil {
+		return xerrors.Errorf("parsing issuer: %v", err)
+	}
+	if err = issuer.Verify(vc.Hash(), vc.Signature); err != nil {
+		return xerrors.Errorf("verifying signature: %v", err)
+	}
+	if vc.Revoked {
+		return xerrors.New("credential is revoked")
+	}
+	if vc.Expiry != 0 && now >= vc.Expiry {
+		return xerrors.New("credential is expired")
+	}
+	return nil
+}
+
+// Attribute returns the value of the attribute, or nil if it doesn't exist.
+func (vc VerifiableCredential) Attribute(name string) []byte {
+	for _, a := range vc.Attributes {
+		if a.Name == name {
+			return a.Value
+		}
+	}
+	return nil
+}
+
+// CredentialID returns the instance ID of the credential.
+func CredentialID(vc VerifiableCredential) byzcoin.InstanceID {
+	h := sha256.New()
+	h.Write([]byte(ContractVerifiableCredentialID))
+	h.Write(vc.Hash())
+	return byzcoin.NewInstanceID(h.Sum(nil))
+}
+
+func contractVerifiableCredentialFromBytes(in []byte) (byzcoin.Contract, error) {
+	c := &ContractVerifiableCredential{}
+	err := protobuf.Decode(in, &c.VerifiableCredential)
+	if err != nil {
+		return nil, xerrors.Errorf("couldn't unmarshal instance data: %v", err)
+	}
+	return c, nil
+}
+
+// Spawn implements the byzcoin.Contract interface.
+func (c *ContractVerifiableCredential) Spawn(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	err = protobuf.Decode(inst.Spawn.Args.Search("credential"), &c.VerifiableCredential)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("decoding credential: %v", err)
+	}
+	c.Revoked = false
+	if len(c.Darc) == 0 {
+		return nil, nil, xerrors.New("the credential has no darc")
+	}
+	if err = c.Verify(0); err != nil {
+		return nil, nil, xerrors.Errorf("invalid credential: %v", err)
+	}
+	if _, _, cid, _, err := rst.GetValues(c.Darc); err != nil || cid == "" {
+		return nil, nil, xerrors.New("unknown darc of the credential")
+	}
+
+	buf, err := protobuf.Encode(&c.VerifiableCredential)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("couldn't encode credential: %v", err)
+	}
+	log.Lvlf2("Issuing credential for %s", c.Subject)
+	sc = []byzcoin.StateChange{
+		byzcoin.NewStateChange(byzcoin.Create, CredentialID(c.VerifiableCredential),
+			ContractVerifiableCredentialID, buf, c.Darc),
+	}
+	return
+}
+
+// Invoke implements the byzcoin.Contract interface.
+func (c *ContractVerifiableCredential) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+
+	switch inst.Invoke.Command {
+	case "revoke":
+		if c.Revoked {
+			return nil, nil, xerrors.New("credential is already revoked")
+		}
+		c.Revoked = true
+		var buf []byte
+		buf, err = protobuf.Encode(&c.VerifiableCredential)
+		if err != nil {
+			return nil, nil, xerrors.Errorf("couldn't encode credential: %v", err)
+		}
+		sc = []byzcoin.StateChange{
+			byzcoin.NewStateChange(byzcoin.Update, inst.InstanceID,
+				ContractVerifiableCredentialID, buf, darcID),
+		}
+	case "verify":
+		var now int64
+		if c.Expiry != 0 {
+			now, err = chainTime(rst)
+			if err != nil {
+				return
+			}
+		}
+		if err = c.Verify(now); err != nil {
+			return
+		}
+	default:
+		return nil, nil, xerrors.New("verifiableCredential contract can only revoke or verify")
+	}
+	return
+}
+
+// GetVerifiableCredential returns the credential stored in the instance. The
+// proof of the instance is verified, but not the credential itself.
+func GetVerifiableCredential(cl *byzcoin.Client, id byzcoin.InstanceID) (*VerifiableCredential, error) {
+	pr, err := cl.GetProof(id.Slice())
+	if err != nil {
+		return nil, xerrors.Errorf("getting proof: %v", err)
+	}
+	if err := pr.Proof.Verify(cl.ID); err != nil {
+		return nil, xerrors.Errorf("verifying proof: %v", err)
+	}
+	_, value, cid, _, err := pr.Proof.KeyValue()
+	if err != nil {
+		return nil, xerrors.Errorf("reading proof: %v", err)
+	}
+	if cid != ContractVerifiableCredentialID {
+		return nil, xerrors.New("instance is not a verifiable credential")
+	}
+	var vc VerifiableCredential
+	if err := protobuf.Decode(value, &vc); err != nil {
+		return nil, xerrors.Errorf("decoding credential: %v", err)
+	}
+	return &vc, nil
+}
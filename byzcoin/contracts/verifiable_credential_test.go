@@ -0,0 +1,99 @@
+package contracts
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/protobuf"
+)
+
+func TestVerifiableCredential(t *testing.T) {
+	ct := newCT("spawn:verifiableCredential", "invoke:verifiableCredential.revoke",
+		"invoke:verifiableCredential.verify")
+	issuer := darc.NewSignerEd25519(nil, nil)
+	vc := VerifiableCredential{
+		Issuer:     issuer.Identity().String(),
+		Darc:       gdarc.GetBaseID(),
+		Subject:    "alice",
+		Attributes: []CredentialAttribute{{Name: "age", Value: []byte("42")}},
+		Expiry:     200,
+	}
+	require.Error(t, vc.Sign(gsigner))
+	require.NoError(t, vc.Sign(issuer))
+	require.NoError(t, vc.Verify(100))
+	require.Error(t, vc.Verify(200))
+	require.Equal(t, []byte("42"), vc.Attribute("age"))
+
+	issue := func(vc VerifiableCredential) (byzcoin.StateChanges, error) {
+		buf, err := protobuf.Encode(&vc)
+		require.NoError(t, err)
+		c, err := contractVerifiableCredentialFromBytes(nil)
+		require.NoError(t, err)
+		sc, _, err := c.Spawn(ct, byzcoin.Instruction{
+			InstanceID: byzcoin.NewInstanceID(gdarc.GetBaseID()),
+			Spawn: &byzcoin.Spawn{
+				ContractID: ContractVerifiableCredentialID,
+				Args:       byzcoin.Arguments{{Name: "credential", Value: buf}},
+			},
+		}, nil)
+		return sc, err
+	}
+
+	// The signature of the issuer must match, and covers the darc of the
+	// credential, so that nobody else can store it with another darc.
+	tampered := vc
+	tampered.Subject = "bob"
+	_, err := issue(tampered)
+	require.Error(t, err)
+	tampered = vc
+	tampered.Darc = darc.ID(iid("other darc").Slice())
+	_, err = issue(tampered)
+	require.Error(t, err)
+	require.NoError(t, tampered.Sign(issuer))
+	_, err = issue(tampered)
+	require.Error(t, err)
+	noDarc := vc
+	noDarc.Darc = nil
+	require.NoError(t, noDarc.Sign(issuer))
+	_, err = issue(noDarc)
+	require.Error(t, err)
+	sc, err := issue(vc)
+	require.NoError(t, err)
+	id := CredentialID(vc)
+	require.Equal(t, id.Slice(), sc[0].InstanceID)
+	require.Equal(t, gdarc.GetBaseID(), sc[0].DarcID)
+	ct.Store(id, sc[0].Value, ContractVerifiableCredentialID, gdarc.GetBaseID())
+
+	invoke := func(rst byzcoin.ReadOnlyStateTrie, cmd string) (byzcoin.StateChanges, error) {
+		c, err := contractVerifiableCredentialFromBytes(ct.values[string(id.Slice())])
+		require.NoError(t, err)
+		sc, _, err := c.Invoke(rst, byzcoin.Instruction{
+			InstanceID: id,
+			Invoke: &byzcoin.Invoke{
+				ContractID: ContractVerifiableCredentialID,
+				Command:    cmd,
+			},
+		}, nil)
+		return sc, err
+	}
+
+	// Verification uses the time of the chain.
+	tt := timeTest{cvTest: ct, timestamp: 100}
+	sc, err = invoke(tt, "verify")
+	require.NoError(t, err)
+	require.Equal(t, 0, len(sc))
+	tt.timestamp = 200
+	_, err = invoke(tt, "verify")
+	require.Error(t, err)
+
+	tt.timestamp = 100
+	sc, err = invoke(tt, "revoke")
+	require.NoError(t, err)
+	ct.Store(id, sc[0].Value, ContractVerifiableCredentialID, gdarc.GetBaseID())
+	_, err = invoke(tt, "verify")
+	require.Error(t, err)
+	_, err = invoke(tt, "revoke")
+	require.Error(t, err)
+}
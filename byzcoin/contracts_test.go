@@ -1,9 +1,12 @@
 package byzcoin
 
 import (
+	"encoding/binary"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/cothority/v3/darc/expression"
 )
 
 func testContractFn(in []byte) (Contract, error) {
@@ -36,3 +39,48 @@ func TestContracts_Registry(t *testing.T) {
 	require.Error(t, r.register("c", testContractFn, false))
 	require.NoError(t, r.register("c", testContractFn, true))
 }
+
+// Test the constraints on the arguments of the instructions.
+func TestContracts_ArgAttr(t *testing.T) {
+	amount := make([]byte, 8)
+	binary.LittleEndian.PutUint64(amount, 500)
+	dest := NewInstanceID([]byte("destination"))
+	inst := Instruction{Invoke: &Invoke{
+		ContractID: "coin",
+		Command:    "transfer",
+		Args: Arguments{
+			{Name: "coins", Value: amount},
+			{Name: "destination", Value: dest.Slice()},
+			{Name: "memo", Value: []byte("rent")},
+			{Name: "tier", Value: []byte("1")},
+		},
+	}}
+	attrFuncs := BasicContract{}.MakeAttrInterpreters(nil, inst)
+	arg := attrFuncs["arg"]
+
+	require.NoError(t, arg("coins.uint64<=1000"))
+	require.Error(t, arg("coins.uint64<500"))
+	require.NoError(t, arg("destination.hex=="+dest.String()))
+	require.Error(t, arg("destination.hex!="+dest.String()))
+	require.NoError(t, arg("memo==rent"))
+	require.Error(t, arg("memo.string==food"))
+	require.Error(t, arg("memo.uint64<=10"))
+	require.Error(t, arg("memo.base64==cmVudA"))
+	require.Error(t, arg("memo<=rent"))
+	require.Error(t, arg("destination.hex=="+dest.String()+"0"))
+	require.Error(t, arg("coins.uint64<=1e4"))
+	require.NoError(t, arg("tier==1"))
+	require.Error(t, arg("tier==1.0"))
+	require.NoError(t, arg("tier!=01"))
+	require.Error(t, arg("missing==1"))
+	require.Error(t, arg("coins"))
+
+	// The constraints are used in the rules.
+	id := darc.NewSignerEd25519(nil, nil).Identity().String()
+	other := NewInstanceID([]byte("other")).String()
+	expr := expression.Expr(id + " & attr:arg:coins.uint64<=1000 & " +
+		"(attr:arg:destination.hex==" + other + " | attr:arg:destination.hex==" + dest.String() + " )")
+	require.NoError(t, darc.EvalExprAttr(expr, nil, attrFuncs, id))
+	binary.LittleEndian.PutUint64(amount, 2000)
+	require.Error(t, darc.EvalExprAttr(expr, nil, attrFuncs, id))
+}
@@ -0,0 +1,349 @@
+package byzcoin
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/blscosi/bdnproto"
+	"go.dedis.ch/cothority/v3/blscosi/protocol"
+	"go.dedis.ch/cothority/v3/skipchain"
+	"go.dedis.ch/kyber/v3/sign"
+	"go.dedis.ch/kyber/v3/sign/bls"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/onet/v3/network"
+	"go.dedis.ch/protobuf"
+	"golang.org/x/xerrors"
+)
+
+// EvictionWindow is the number of consecutive blocks whose forward-links have
+// been signed without a member of the roster for the member to be proposed
+// for eviction.
+var EvictionWindow = 10
+
+// The reasons to evict a member of the roster.
+const (
+	// EvictionUnresponsive is used when the member didn't take part in the
+	// signature of the last EvictionWindow blocks.
+	EvictionUnresponsive = "unresponsive"
+	// EvictionEquivocation is used when the member signed two different
+	// forward-links from the same block.
+	EvictionEquivocation = "equivocation"
+)
+
+// EvictionProposal is the proposal of a conode to remove a misbehaving member
+// from the roster of a chain, with the evidence of the misbehaviour. Anybody
+// can verify it, and the admin of the chain can turn it into an update of the
+// configuration with Instruction.
+type EvictionProposal struct {
+	SkipChainID skipchain.SkipBlockID
+	Member      *network.ServerIdentity
+	Reason      string
+	// Blocks are consecutive blocks whose level-0 forward-links have been
+	// signed without the member, if the reason is EvictionUnresponsive.
+	Blocks []*skipchain.SkipBlock
+	// Equivocation holds the forward-links signed by the member, if the
+	// reason is EvictionEquivocation.
+	Equivocation *skipchain.Equivocation
+	// NewRoster is the latest roster of the chain without the member.
+	NewRoster onet.Roster
+	// Proposer is the conode of the roster that signed the proposal.
+	Proposer  *network.ServerIdentity
+	Signature []byte
+}
+
+// evictionDomain separates the signatures of the eviction proposals from the
+// other signatures of the conodes.
+const evictionDomain = "byzcoin.EvictionProposal"
+
+// Hash returns the digest of the proposal signed by the proposer.
+func (p *EvictionProposal) Hash() []byte {
+	h := sha256.New()
+	write := func(buf []byte) {
+		lenBuf := make([]byte, 8)
+		binary.LittleEndian.PutUint64(lenBuf, uint64(len(buf)))
+		h.Write(lenBuf)
+		h.Write(buf)
+	}
+	write([]byte(evictionDomain))
+	write(p.SkipChainID)
+	if p.Member != nil {
+		write(p.Member.ID[:])
+	} else {
+		write(nil)
+	}
+	write([]byte(p.Reason))
+	countBuf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(countBuf, uint64(len(p.Blocks)))
+	h.Write(countBuf)
+	for _, sb := range p.Blocks {
+		write(sb.Hash)
+	}
+	if e := p.Equivocation; e != nil && e.Block != nil && e.First != nil && e.Second != nil {
+		write(e.Block.Hash)
+		write(e.First.Hash())
+		write(e.Second.Hash())
+	} else {
+		write(nil)
+	}
+	write(p.NewRoster.ID[:])
+	if p.Proposer != nil {
+		write(p.Proposer.ID[:])
+	} else {
+		write(nil)
+	}
+	return h.Sum(nil)
+}
+
+// Verify checks the evidence against the member and the signature of the
+// proposer, which has to be part of the roster of the evidence. The new roster
+// must be the roster of the evidence without the member. The caller has to
+// make sure that the blocks of the evidence are part of the chain.
+func (p *EvictionProposal) Verify() error {
+	if p.Member == nil || p.Proposer == nil {
+		return xerrors.New("incomplete proposal")
+	}
+
+	var roster *onet.Roster
+	var err error
+	switch p.Reason {
+	case EvictionUnresponsive:
+		roster, err = p.verifyUnresponsive()
+	case EvictionEquivocation:
+		roster, err = p.verifyEquivocation()
+	default:
+		return xerrors.Errorf("unknown reason: %s", p.Reason)
+	}
+	if err != nil {
+		return xerrors.Errorf("invalid evidence: %v", err)
+	}
+
+	errRoster := xerrors.New("new roster must be the roster of the evidence without the member")
+	expected := rosterWithout(roster, p.Member)
+	if expected == nil || len(p.NewRoster.List) == 0 {
+		return errRoster
+	}
+	expectedBuf, err := protobuf.Encode(expected)
+	if err != nil {
+		return xerrors.Errorf("encoding roster: %v", err)
+	}
+	newRosterBuf, err := protobuf.Encode(&p.NewRoster)
+	if err != nil {
+		return xerrors.Errorf("encoding roster: %v", err)
+	}
+	if !bytes.Equal(expectedBuf, newRosterBuf) {
+		return errRoster
+	}
+
+	_, proposer := roster.Search(p.Proposer.ID)
+	if proposer == nil {
+		return xerrors.New("proposer is not in the roster")
+	}
+	err = bls.Verify(pairingSuite, proposer.ServicePublic(ServiceName), p.Hash(), p.Signature)
+	return cothority.ErrorOrNil(err, "signature")
+}
+
+// rosterWithout returns the roster without the member, or nil if no other
+// member is left.
+func rosterWithout(roster *onet.Roster, member *network.ServerIdentity) *onet.Roster {
+	var list []*network.ServerIdentity
+	for _, si := range roster.List {
+		if !si.ID.Equal(member.ID) {
+			list = append(list, si)
+		}
+	}
+	if len(list) == 0 {
+		return nil
+	}
+	return onet.NewRoster(list)
+}
+
+// verifyUnresponsive checks that the member didn't sign the forward-links of
+// the blocks, and returns the roster of the last block.
+func (p *EvictionProposal) verifyUnresponsive() (*onet.Roster, error) {
+	if len(p.Blocks) < EvictionWindow {
+		return nil, xerrors.Errorf("need %d blocks but got %d", EvictionWindow, len(p.Blocks))
+	}
+	for i, sb := range p.Blocks {
+		if !sb.CalculateHash().Equal(sb.Hash) {
+			return nil, xerrors.New("wrong hash of a block")
+		}
+		if !sb.SkipChainID().Equal(p.SkipChainID) {
+			return nil, xerrors.New("block of another chain")
+		}
+		if len(sb.ForwardLink) == 0 {
+			return nil, xerrors.Errorf("missing forward-link of block %d", sb.Index)
+		}
+		if i+1 < len(p.Blocks) && !sb.ForwardLink[0].To.Equal(p.Blocks[i+1].Hash) {
+			return nil, xerrors.New("blocks are not consecutive")
+		}
+		signed, err := signedBy(sb, sb.ForwardLink[0], p.Member)
+		if err != nil {
+			return nil, xerrors.Errorf("block %d: %v", sb.Index, err)
+		}
+		if signed {
+			return nil, xerrors.Errorf("member signed block %d", sb.Index)
+		}
+	}
+	return p.Blocks[len(p.Blocks)-1].Roster, nil
+}
+
+// verifyEquivocation checks that the member signed both forward-links of the
+// equivocation, and returns the roster of its block.
+func (p *EvictionProposal) verifyEquivocation() (*onet.Roster, error) {
+	e := p.Equivocation
+	if e == nil {
+		return nil, xerrors.New("missing equivocation")
+	}
+	if err := e.Verify(); err != nil {
+		return nil, err
+	}
+	if !e.Block.SkipChainID().Equal(p.SkipChainID) {
+		return nil, xerrors.New("equivocation of another chain")
+	}
+	for _, fl := range []*skipchain.ForwardLink{e.First, e.Second} {
+		signed, err := signedBy(e.Block, fl, p.Member)
+		if err != nil {
+			return nil, err
+		}
+		if !signed {
+			return nil, xerrors.New("member didn't sign both forward-links")
+		}
+	}
+	return e.Block.Roster, nil
+}
+
+// Instruction returns the instruction replacing the roster of the
+// configuration with the new roster of the proposal. It has to be signed by
+// the admin of the chain, as allowed by the genesis darc.
+func (p *EvictionProposal) Instruction(config ChainConfig) (Instruction, error) {
+	config.Roster = p.NewRoster
+	configBuf, err := protobuf.Encode(&config)
+	if err != nil {
+		return Instruction{}, xerrors.Errorf("encoding config: %v", err)
+	}
+	return Instruction{
+		InstanceID: NewInstanceID(nil),
+		Invoke: &Invoke{
+			ContractID: ContractConfigID,
+			Command:    "update_config",
+			Args:       Arguments{{Name: "config", Value: configBuf}},
+		},
+	}, nil
+}
+
+// signedBy verifies the forward-link from the block, and returns whether the
+// member of its roster took part in the signature.
+func signedBy(sb *skipchain.SkipBlock, fl *skipchain.ForwardLink, member *network.ServerIdentity) (bool, error) {
+	_, si := sb.Roster.Search(member.ID)
+	if si == nil {
+		return false, xerrors.New("member is not in the roster")
+	}
+	publics := sb.Roster.ServicePublics(skipchain.ServiceName)
+	if err := fl.VerifyWithScheme(pairingSuite, publics, sb.SignatureScheme); err != nil {
+		return false, xerrors.Errorf("forward-link: %v", err)
+	}
+
+	var mask *sign.Mask
+	var err error
+	switch sb.SignatureScheme {
+	case skipchain.BlsSignatureSchemeIndex:
+		mask, err = protocol.BlsSignature(fl.Signature.Sig).GetMask(pairingSuite, publics)
+	case skipchain.BdnSignatureSchemeIndex:
+		mask, err = bdnproto.BdnSignature(fl.Signature.Sig).GetMask(pairingSuite, publics)
+	default:
+		return false, xerrors.New("unknown signature scheme")
+	}
+	if err != nil {
+		return false, xerrors.Errorf("mask: %v", err)
+	}
+	public := si.ServicePublic(skipchain.ServiceName)
+	for _, p := range mask.Participants() {
+		if p.Equal(public) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// GetEvictionProposals returns the proposals of the conode to evict the
+// members of the latest roster of the chain it has evidence against.
+func (s *Service) GetEvictionProposals(req *GetEvictionProposals) (*GetEvictionProposalsResponse, error) {
+	latest, err := s.db().GetLatestByID(req.SkipChainID)
+	if err != nil {
+		return nil, xerrors.Errorf("getting latest block: %v", err)
+	}
+	if !s.hasByzCoinVerification(req.SkipChainID) {
+		return nil, xerrors.New("not a byzcoin chain")
+	}
+
+	reply := &GetEvictionProposalsResponse{}
+	for _, member := range latest.Roster.List {
+		if member.Equal(s.ServerIdentity()) {
+			continue
+		}
+		p := s.findEvictionEvidence(latest, member)
+		if p == nil {
+			continue
+		}
+		var roster *onet.Roster
+		if p.Reason == EvictionEquivocation {
+			roster = p.Equivocation.Block.Roster
+		} else {
+			roster = p.Blocks[len(p.Blocks)-1].Roster
+		}
+		newRoster := rosterWithout(roster, member)
+		if newRoster == nil {
+			continue
+		}
+		p.NewRoster = *newRoster
+		p.Proposer = s.ServerIdentity()
+		p.Signature, err = bls.Sign(pairingSuite, s.ServerIdentity().ServicePrivate(ServiceName), p.Hash())
+		if err != nil {
+			return nil, xerrors.Errorf("signing proposal: %v", err)
+		}
+		log.Lvlf2("%s: proposing to evict %s from %x: %s", s.ServerIdentity(), member,
+			req.SkipChainID, p.Reason)
+		reply.Proposals = append(reply.Proposals, *p)
+	}
+	return reply, nil
+}
+
+// findEvictionEvidence returns an unsigned proposal to evict the member, with
+// the evidence found in the blocks known by the conode, or nil if there is
+// none.
+func (s *Service) findEvictionEvidence(latest *skipchain.SkipBlock, member *network.ServerIdentity) *EvictionProposal {
+	scid := latest.SkipChainID()
+	equivocations, err := s.skService().GetEquivocations(&skipchain.GetEquivocations{SkipchainID: scid})
+	if err == nil {
+		for _, e := range equivocations.Equivocations {
+			p := &EvictionProposal{SkipChainID: scid, Member: member,
+				Reason: EvictionEquivocation, Equivocation: e}
+			if _, err := p.verifyEquivocation(); err == nil {
+				return p
+			}
+		}
+	}
+
+	// The blocks preceding the latest one have their level-0 forward-link.
+	var blocks []*skipchain.SkipBlock
+	sb := latest
+	for len(blocks) < EvictionWindow && sb.Index > 0 {
+		sb = s.db().GetByID(sb.BackLinkIDs[0])
+		if sb == nil || len(sb.ForwardLink) == 0 {
+			return nil
+		}
+		signed, err := signedBy(sb, sb.ForwardLink[0], member)
+		if err != nil || signed {
+			return nil
+		}
+		blocks = append([]*skipchain.SkipBlock{sb}, blocks...)
+	}
+	if len(blocks) < EvictionWindow {
+		return nil
+	}
+	return &EvictionProposal{SkipChainID: scid, Member: member,
+		Reason: EvictionUnresponsive, Blocks: blocks}
+}
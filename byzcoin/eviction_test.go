@@ -0,0 +1,83 @@
+package byzcoin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/onet/v3"
+)
+
+func TestService_EvictionProposals(t *testing.T) {
+	defer func(w int) { EvictionWindow = w }(EvictionWindow)
+	EvictionWindow = 2
+
+	s := newSer(t, 1, testInterval)
+	defer s.local.CloseAll()
+	scid := s.genesis.SkipChainID()
+
+	reply, err := s.service().GetEvictionProposals(&GetEvictionProposals{SkipChainID: scid})
+	require.NoError(t, err)
+	require.Empty(t, reply.Proposals)
+
+	last := len(s.hosts) - 1
+	s.services[last].TestClose()
+	s.hosts[last].Pause()
+	defer s.hosts[last].Unpause()
+
+	counter := uint64(1)
+	for ; counter <= uint64(EvictionWindow+1); counter++ {
+		tx, err := createOneClientTxWithCounter(s.darc.GetBaseID(), dummyContract, s.value, s.signer, counter)
+		require.NoError(t, err)
+		s.sendTxAndWait(t, tx, 10)
+	}
+
+	reply, err = s.service().GetEvictionProposals(&GetEvictionProposals{SkipChainID: scid})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(reply.Proposals))
+	p := reply.Proposals[0]
+	require.True(t, p.Member.Equal(s.roster.List[last]))
+	require.Equal(t, EvictionUnresponsive, p.Reason)
+	require.Equal(t, len(s.roster.List)-1, len(p.NewRoster.List))
+	require.NoError(t, p.Verify())
+
+	// Not enough evidence.
+	blocks := p.Blocks
+	p.Blocks = blocks[1:]
+	require.Error(t, p.Verify())
+	p.Blocks = blocks
+	// Not the member that didn't sign.
+	member := p.Member
+	p.Member = s.roster.List[1]
+	require.Error(t, p.Verify())
+	p.Member = member
+	// Not signed by the proposer.
+	p.Proposer = s.roster.List[1]
+	require.Error(t, p.Verify())
+	p.Proposer = s.roster.List[0]
+	require.NoError(t, p.Verify())
+	// The new roster must not drop other members, nor add new ones.
+	newRoster := p.NewRoster
+	p.NewRoster = *onet.NewRoster(newRoster.List[1:])
+	require.Error(t, p.Verify())
+	p.NewRoster = *onet.NewRoster(append(newRoster.List, s.roster.List[last]))
+	require.Error(t, p.Verify())
+	p.NewRoster = newRoster
+	require.NoError(t, p.Verify())
+
+	// The admin evicts the member.
+	config, err := s.service().LoadConfig(scid)
+	require.NoError(t, err)
+	inst, err := p.Instruction(*config)
+	require.NoError(t, err)
+	inst.SignerIdentities = []darc.Identity{s.signer.Identity()}
+	inst.SignerCounter = []uint64{counter}
+	inst.version = CurrentVersion
+	ctx, err := combineInstrsAndSign(s.signer, inst)
+	require.NoError(t, err)
+	s.sendTxAndWait(t, ctx, 10)
+
+	config, err = s.service().LoadConfig(scid)
+	require.NoError(t, err)
+	require.True(t, config.Roster.ID.Equal(p.NewRoster.ID))
+}
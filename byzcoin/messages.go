@@ -15,6 +15,14 @@ func init() {
 		&CreateGenesisBlock{}, &CreateGenesisBlockResponse{},
 		&AddTxRequest{}, &AddTxResponse{},
 		&GetSignerCounters{}, &GetSignerCountersResponse{},
+		&NegotiateVersion{}, &NegotiateVersionResponse{},
+		&GetProofs{}, &GetProofsResponse{},
+		&GetStateChecksum{}, &GetStateChecksumResponse{},
+		&BackupRequest{}, &BackupResponse{},
+		&GetInstanceAt{}, &GetInstanceAtResponse{},
+		&GetEvictionProposals{}, &GetEvictionProposalsResponse{},
+		&GetBlockChunks{}, &GetBlockChunksResponse{},
+		&GetBlocksSince{}, &GetBlocksSinceResponse{},
 	)
 }
 
@@ -24,4 +32,45 @@ func init() {
 type Version int
 
 // CurrentVersion is what we're running now
-const CurrentVersion Version = 3
+const CurrentVersion Version = 4
+
+// The following versions change the outcome or the hashes of the blocks, so
+// the nodes only apply them once the chain has been upgraded to that version.
+const (
+	// VersionRejectionReason covers the reason of the refused transactions
+	// with the hash of the block.
+	VersionRejectionReason Version = 4
+	// VersionCoinSupply adds the maximum supply and the burn command to the
+	// coin contract, and refuses transfers between types of coins.
+	VersionCoinSupply Version = 4
+	// VersionStorageQuotas enforces the quotas of the configuration and
+	// keeps their usage in the trie.
+	VersionStorageQuotas Version = 4
+	// VersionBodyRoot requires the BodyRoot in the header of the blocks, so
+	// that light clients can sample all of them.
+	VersionBodyRoot Version = 4
+)
+
+// The following features can be negotiated between a client and a conode
+// using NegotiateVersion. A conode only returns the features it knows, so a
+// newer client must not use the other ones and fall back to what the
+// negotiated version offers.
+const (
+	// FeatureProofs is set if the conode supports GetProofs.
+	FeatureProofs = "proofs"
+	// FeatureInstanceAt is set if the conode supports GetInstanceAt.
+	FeatureInstanceAt = "instance_at"
+	// FeatureBlocksSince is set if the conode supports GetBlocksSince.
+	FeatureBlocksSince = "blocks_since"
+	// FeatureBlockChunks is set if the conode supports GetBlockChunks.
+	FeatureBlockChunks = "block_chunks"
+)
+
+// SupportedFeatures lists all features known by this version of the
+// conode.
+var SupportedFeatures = []string{
+	FeatureProofs,
+	FeatureInstanceAt,
+	FeatureBlocksSince,
+	FeatureBlockChunks,
+}
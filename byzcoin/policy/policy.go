@@ -0,0 +1,155 @@
+// Package policy creates darcs for common access control patterns on
+// ByzCoin. Composing the rules by hand often leads to darcs that cannot be
+// evolved anymore, to empty expressions, or to identities that get more
+// rights than intended through the "_sign" rule. The constructors of this
+// package set all the rules needed by ByzCoin and check their arguments.
+//
+// All the darcs can be evolved with the "invoke:darc.evolve" and
+// "invoke:darc.evolve_unrestricted" actions, and the "_sign" rule holds the
+// identities that may act on behalf of the darc when it is used as an
+// identity in other darcs.
+package policy
+
+import (
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/cothority/v3/darc/expression"
+	"golang.org/x/xerrors"
+)
+
+const sign = darc.Action("_sign")
+
+// EvolveAction and EvolveUnrestrictedAction are the actions needed to evolve
+// a darc on ByzCoin.
+var (
+	EvolveAction             = darc.Action("invoke:" + byzcoin.ContractDarcID + ".evolve")
+	EvolveUnrestrictedAction = darc.Action("invoke:" + byzcoin.ContractDarcID + ".evolve_unrestricted")
+)
+
+// OwnerAdmins returns a darc where the owner alone can evolve the darc, and
+// where the owner or any of the admins can execute the actions and sign on
+// behalf of the darc.
+func OwnerAdmins(owner darc.Identity, admins []darc.Identity, actions []darc.Action,
+	desc []byte) (*darc.Darc, error) {
+	if err := checkIDs(append([]darc.Identity{owner}, admins...)); err != nil {
+		return nil, xerrors.Errorf("invalid identities: %v", err)
+	}
+	ownerExpr := expression.Expr(owner.String())
+	usersExpr := expression.InitOrExpr(idStrings(owner, admins)...)
+	return newDarc(desc, ownerExpr, usersExpr, usersExpr, actions)
+}
+
+// Board returns a darc where m of the n members are needed for every
+// action, including evolving the darc and signing on behalf of it.
+func Board(m int, members []darc.Identity, actions []darc.Action, desc []byte) (*darc.Darc, error) {
+	if err := checkIDs(members); err != nil {
+		return nil, xerrors.Errorf("invalid members: %v", err)
+	}
+	if m < 1 || m > len(members) {
+		return nil, xerrors.Errorf("threshold %d must be between 1 and the %d members",
+			m, len(members))
+	}
+	ids := idStrings(members[0], members[1:])
+	expr := expression.InitThresholdExpr(m, ids...)
+	if m == len(members) {
+		expr = expression.InitAndExpr(ids...)
+	}
+	return newDarc(desc, expr, expr, expr, actions)
+}
+
+// Viewers returns a darc where the owner alone can evolve the darc and sign
+// on behalf of it, and where the owner and the viewers can execute the read
+// actions, like "spawn:calypsoRead". The viewers are not added to the
+// "_sign" rule, so that they don't get the rights of the darc in other
+// darcs.
+func Viewers(owner darc.Identity, viewers []darc.Identity, readActions []darc.Action,
+	desc []byte) (*darc.Darc, error) {
+	if err := checkIDs(append([]darc.Identity{owner}, viewers...)); err != nil {
+		return nil, xerrors.Errorf("invalid identities: %v", err)
+	}
+	if len(readActions) == 0 {
+		return nil, xerrors.New("no read actions given")
+	}
+	ownerExpr := expression.Expr(owner.String())
+	readExpr := expression.InitOrExpr(idStrings(owner, viewers)...)
+	return newDarc(desc, ownerExpr, ownerExpr, readExpr, readActions)
+}
+
+// WithRecovery adds an emergency recovery key to the darc, which can replace
+// all the rules of the darc with evolve_unrestricted, for example when the
+// keys of the owners are lost. The recovery key has no other right, and
+// should be kept offline.
+func WithRecovery(d *darc.Darc, recovery darc.Identity) error {
+	if err := checkIDs([]darc.Identity{recovery}); err != nil {
+		return xerrors.Errorf("invalid recovery identity: %v", err)
+	}
+	expr := expression.Expr(recovery.String())
+	if d.Rules.Contains(EvolveUnrestrictedAction) {
+		expr = expression.Expr("(" + string(d.Rules.Get(EvolveUnrestrictedAction)) +
+			") | " + string(expr))
+		if err := d.Rules.UpdateRule(EvolveUnrestrictedAction, expr); err != nil {
+			return xerrors.Errorf("updating rule: %v", err)
+		}
+		return nil
+	}
+	if err := d.Rules.AddRule(EvolveUnrestrictedAction, expr); err != nil {
+		return xerrors.Errorf("adding rule: %v", err)
+	}
+	return nil
+}
+
+// newDarc creates the darc with the evolve, sign and other actions. The
+// actions must not be one of the evolve actions or "_sign".
+func newDarc(desc []byte, evolveExpr, signExpr, actionExpr expression.Expr,
+	actions []darc.Action) (*darc.Darc, error) {
+	rules := darc.NewRules()
+	for _, r := range []struct {
+		action darc.Action
+		expr   expression.Expr
+	}{
+		{EvolveAction, evolveExpr},
+		{EvolveUnrestrictedAction, evolveExpr},
+		{sign, signExpr},
+	} {
+		if err := rules.AddRule(r.action, r.expr); err != nil {
+			return nil, xerrors.Errorf("adding rule %s: %v", r.action, err)
+		}
+	}
+	for _, a := range actions {
+		if rules.Contains(a) {
+			return nil, xerrors.Errorf("action %s is set by the template or given twice", a)
+		}
+		if err := rules.AddRule(a, actionExpr); err != nil {
+			return nil, xerrors.Errorf("adding rule %s: %v", a, err)
+		}
+	}
+	return darc.NewDarc(rules, desc), nil
+}
+
+// checkIDs returns an error if one of the identities is empty or if an
+// identity is given twice.
+func checkIDs(ids []darc.Identity) error {
+	if len(ids) == 0 {
+		return xerrors.New("no identities given")
+	}
+	seen := make(map[string]bool)
+	for _, id := range ids {
+		if id.Type() < 0 {
+			return xerrors.New("empty identity")
+		}
+		s := id.String()
+		if seen[s] {
+			return xerrors.Errorf("identity %s is given twice", s)
+		}
+		seen[s] = true
+	}
+	return nil
+}
+
+func idStrings(first darc.Identity, others []darc.Identity) []string {
+	ids := []string{first.String()}
+	for _, id := range others {
+		ids = append(ids, id.String())
+	}
+	return ids
+}
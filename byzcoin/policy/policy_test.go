@@ -0,0 +1,109 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/darc"
+)
+
+func allowed(d *darc.Darc, action darc.Action, ids ...darc.Identity) bool {
+	var strs []string
+	for _, id := range ids {
+		strs = append(strs, id.String())
+	}
+	return d.EvalRule(action, nil, nil, 0, strs...) == nil
+}
+
+func newIDs(n int) []darc.Identity {
+	ids := make([]darc.Identity, n)
+	for i := range ids {
+		ids[i] = darc.NewSignerEd25519(nil, nil).Identity()
+	}
+	return ids
+}
+
+func TestOwnerAdmins(t *testing.T) {
+	ids := newIDs(3)
+	owner, admins := ids[0], ids[1:]
+	write := darc.Action("spawn:value")
+	d, err := OwnerAdmins(owner, admins, []darc.Action{write}, []byte("owner"))
+	require.NoError(t, err)
+
+	require.True(t, allowed(d, EvolveAction, owner))
+	require.True(t, allowed(d, EvolveUnrestrictedAction, owner))
+	require.False(t, allowed(d, EvolveAction, admins...))
+	for _, id := range ids {
+		require.True(t, allowed(d, write, id))
+		require.True(t, allowed(d, sign, id))
+	}
+
+	_, err = OwnerAdmins(owner, []darc.Identity{owner}, nil, nil)
+	require.Error(t, err)
+	_, err = OwnerAdmins(owner, []darc.Identity{{}}, nil, nil)
+	require.Error(t, err)
+	_, err = OwnerAdmins(owner, admins, []darc.Action{EvolveAction}, nil)
+	require.Error(t, err)
+	_, err = OwnerAdmins(owner, admins, []darc.Action{write, write}, nil)
+	require.Error(t, err)
+}
+
+func TestBoard(t *testing.T) {
+	members := newIDs(3)
+	write := darc.Action("spawn:value")
+	d, err := Board(2, members, []darc.Action{write}, []byte("board"))
+	require.NoError(t, err)
+	for _, a := range []darc.Action{EvolveAction, EvolveUnrestrictedAction, sign, write} {
+		require.False(t, allowed(d, a, members[0]))
+		require.True(t, allowed(d, a, members[0], members[2]))
+	}
+
+	d, err = Board(3, members, nil, nil)
+	require.NoError(t, err)
+	require.False(t, allowed(d, EvolveAction, members[:2]...))
+	require.True(t, allowed(d, EvolveAction, members...))
+
+	_, err = Board(0, members, nil, nil)
+	require.Error(t, err)
+	_, err = Board(4, members, nil, nil)
+	require.Error(t, err)
+	_, err = Board(1, nil, nil, nil)
+	require.Error(t, err)
+}
+
+func TestViewers(t *testing.T) {
+	ids := newIDs(3)
+	owner, viewers := ids[0], ids[1:]
+	read := darc.Action("spawn:calypsoRead")
+	d, err := Viewers(owner, viewers, []darc.Action{read}, []byte("viewers"))
+	require.NoError(t, err)
+
+	require.True(t, allowed(d, sign, owner))
+	for _, v := range viewers {
+		require.True(t, allowed(d, read, v))
+		require.False(t, allowed(d, sign, v))
+		require.False(t, allowed(d, EvolveAction, v))
+	}
+
+	_, err = Viewers(owner, viewers, nil, nil)
+	require.Error(t, err)
+}
+
+func TestWithRecovery(t *testing.T) {
+	ids := newIDs(3)
+	recovery := ids[2]
+	d, err := Board(2, ids[:2], nil, []byte("recovery"))
+	require.NoError(t, err)
+	require.NoError(t, WithRecovery(d, recovery))
+
+	require.True(t, allowed(d, EvolveUnrestrictedAction, recovery))
+	require.True(t, allowed(d, EvolveUnrestrictedAction, ids[:2]...))
+	require.False(t, allowed(d, EvolveUnrestrictedAction, ids[0]))
+	require.False(t, allowed(d, EvolveAction, recovery))
+	require.False(t, allowed(d, sign, recovery))
+
+	d = darc.NewDarc(darc.NewRules(), nil)
+	require.NoError(t, WithRecovery(d, recovery))
+	require.True(t, allowed(d, EvolveUnrestrictedAction, recovery))
+	require.Error(t, WithRecovery(d, darc.Identity{}))
+}
@@ -118,19 +118,25 @@ func (p Proof) Verify(sbID skipchain.SkipBlockID) error {
 		return cothority.WrapError(err)
 	}
 
-	if len(p.Links) == 0 {
+	return verifyForwardLinks(p.Links, sbID, &p.Latest)
+}
+
+// verifyForwardLinks checks that the links lead from the block sbID to the
+// latest block. The roster of the first link must be verified by the caller.
+func verifyForwardLinks(links []skipchain.ForwardLink, sbID skipchain.SkipBlockID, latest *skipchain.SkipBlock) error {
+	if len(links) == 0 {
 		return cothority.WrapError(ErrorMissingForwardLinks)
 	}
-	if p.Links[0].NewRoster == nil {
+	if links[0].NewRoster == nil {
 		return cothority.WrapError(ErrorMalformedForwardLink)
 	}
 
 	// Get the first from the synthetic link which is assumed to be verified
 	// before against the block with ID stored in the To field by the caller.
-	publics := p.Links[0].NewRoster.ServicePublics(skipchain.ServiceName)
+	publics := links[0].NewRoster.ServicePublics(skipchain.ServiceName)
 
-	for _, l := range p.Links[1:] {
-		if err = l.VerifyWithScheme(pairing.NewSuiteBn256(), publics, p.Latest.SignatureScheme); err != nil {
+	for _, l := range links[1:] {
+		if err := l.VerifyWithScheme(pairing.NewSuiteBn256(), publics, latest.SignatureScheme); err != nil {
 			return cothority.WrapError(ErrorVerifySkipchain)
 		}
 		if !l.From.Equal(sbID) {
@@ -143,7 +149,7 @@ func (p Proof) Verify(sbID skipchain.SkipBlockID) error {
 	}
 
 	// Check that the given latest block matches the last forward link target
-	if !p.Latest.CalculateHash().Equal(sbID) {
+	if !latest.CalculateHash().Equal(sbID) {
 		return cothority.WrapError(ErrorVerifyHash)
 	}
 
@@ -228,3 +234,40 @@ func (p Proof) VerifyAndDecode(suite network.Suite, cid string, value interface{
 	err = protobuf.DecodeWithConstructors(buf, value, network.DefaultConstructors(suite))
 	return cothority.ErrorOrNil(err, "decoding")
 }
+
+// Verify checks that the state change of the response has been applied by
+// a block of the chain starting with the given genesis block. It cannot
+// prove that the instance has not been changed again between this block and
+// the requested one.
+func (r GetInstanceAtResponse) Verify(genesis *skipchain.SkipBlock) error {
+	if len(r.Links) > 0 {
+		r.Links[0].NewRoster = genesis.Roster
+	}
+	err := verifyForwardLinks(r.Links, genesis.Hash, &r.Block)
+	if err != nil {
+		return xerrors.Errorf("verifying links: %v", err)
+	}
+
+	header, err := decodeBlockHeader(&r.Block)
+	if err != nil {
+		return xerrors.Errorf("decoding header: %v", err)
+	}
+	if !bytes.Equal(StateChanges(r.StateChanges).Hash(), header.StateChangesHash) {
+		return xerrors.New("state changes don't match the block")
+	}
+
+	scBuf, err := protobuf.Encode(&r.StateChange)
+	if err != nil {
+		return xerrors.Errorf("encoding state change: %v", err)
+	}
+	for _, sc := range r.StateChanges {
+		buf, err := protobuf.Encode(&sc)
+		if err != nil {
+			return xerrors.Errorf("encoding state change: %v", err)
+		}
+		if bytes.Equal(buf, scBuf) {
+			return nil
+		}
+	}
+	return xerrors.New("state change is not in the block")
+}
@@ -29,11 +29,37 @@ import (
 
 // GetAllByzCoinIDsRequest is a request to get all the Byzcoin chains from a server.
 type GetAllByzCoinIDsRequest struct {
+	// WithMetadata asks the server to return the ChainMetadata of every
+	// chain.
+	WithMetadata bool `protobuf:"opt"`
+	// Identities restricts the list to the chains where the identities
+	// are authorized for at least one rule of the genesis darc.
+	Identities []darc.Identity `protobuf:"opt"`
 }
 
 // GetAllByzCoinIDsResponse contains the list of Byzcoin chains known by a server.
 type GetAllByzCoinIDsResponse struct {
 	IDs []skipchain.SkipBlockID
+	// Metadata is only set if WithMetadata was true in the request, and
+	// holds one entry per ID, in the same order.
+	Metadata []ChainMetadata `protobuf:"opt"`
+}
+
+// ChainMetadata holds an overview of a Byzcoin chain, as seen by the server.
+// As it is only meant for display, no proof is returned.
+type ChainMetadata struct {
+	// LatestIndex is the index of the latest block known by the server.
+	LatestIndex int
+	// Roster is the current roster of the chain.
+	Roster onet.Roster
+	// BlockInterval is the current block interval of the chain.
+	BlockInterval time.Duration
+	// Created is the timestamp of the genesis block in nanoseconds.
+	Created int64
+	// BaseHeight and MaximumHeight are the parameters of the skiplist of the
+	// chain.
+	BaseHeight    int `protobuf:"opt"`
+	MaximumHeight int `protobuf:"opt"`
 }
 
 // DataHeader is the data passed to the Skipchain
@@ -50,6 +76,10 @@ type DataHeader struct {
 	Timestamp int64
 	// Version is the version of ByzCoin at the creation of the block.
 	Version Version `protobuf:"opt"`
+	// BodyRoot is the root of the Merkle tree of the chunks of the body,
+	// used by light clients to sample the body. It is required since
+	// VersionBodyRoot, and empty in the blocks created by older conodes.
+	BodyRoot []byte `protobuf:"opt"`
 }
 
 // DataBody is stored in the body of the skipblock, and it's hash is stored
@@ -78,6 +108,13 @@ type CreateGenesisBlock struct {
 	// DarcContracts is the set of contracts that can be parsed as a DARC.
 	// At least one contract must be given.
 	DarcContractIDs []string
+	// BaseHeight and MaximumHeight are the parameters of the skiplist of the
+	// chain. Zero (or not present in protobuf) means use the defaults, 4 and
+	// 32.
+	// optional
+	BaseHeight int `protobuf:"opt"`
+	// optional
+	MaximumHeight int `protobuf:"opt"`
 }
 
 // CreateGenesisBlockResponse holds the genesis-block of the new skipchain.
@@ -139,6 +176,28 @@ type GetProofResponse struct {
 	Proof Proof
 }
 
+// GetProofs returns the proofs for several keys, all anchored at the same
+// block. This gives a consistent view of related instances.
+type GetProofs struct {
+	// Version of the protocol
+	Version Version
+	// Keys are the keys we want to look up
+	Keys [][]byte
+	// ID is any block that is known to us in the skipchain, can be the genesis
+	// block or any later block. The proofs returned will be starting at this block.
+	ID skipchain.SkipBlockID
+}
+
+// GetProofsResponse can be used together with the Genesis block to proof that
+// the returned keys exist or not, all in the same block. The proofs are in
+// the same order as the keys of the request.
+type GetProofsResponse struct {
+	// Version of the protocol
+	Version Version
+	// Proofs are the proofs of the requested keys.
+	Proofs []Proof
+}
+
 // CheckAuthorization returns the list of actions that could be executed if the
 // signatures of the given identities are present and valid
 type CheckAuthorization struct {
@@ -166,15 +225,42 @@ type ChainConfig struct {
 	Roster          onet.Roster
 	MaxBlockSize    int
 	DarcContractIDs []string
+	// Quotas limit the state that can be created by a contract or a darc.
+	// They are only enforced since VersionStorageQuotas. Adding a quota
+	// goes once through the whole trie, on every node, to compute its
+	// usage, so the transaction adding it takes time with a big state.
+	Quotas []StorageQuota `protobuf:"opt"`
+	// MaxDarcDepth limits the number of nested darc identities evaluated
+	// when verifying an instruction. 0 means that there is no limit.
+	MaxDarcDepth int `protobuf:"opt"`
+	// PropagationTimeout is the timeout of the propagation of the new blocks
+	// and forward-links to the roster. 0 means the default of the conodes.
+	PropagationTimeout time.Duration `protobuf:"opt"`
+	// VerificationTimeout is the timeout of the verification and collective
+	// signature of a new block. 0 means the default of the conodes.
+	VerificationTimeout time.Duration `protobuf:"opt"`
+}
+
+// StorageQuota limits the state held by all the instances of a contract, or
+// by all the instances governed by a darc. Exactly one of ContractID and
+// DarcID must be set. A limit of 0 means that there is no limit.
+type StorageQuota struct {
+	ContractID string  `protobuf:"opt"`
+	DarcID     darc.ID `protobuf:"opt"`
+	// MaxBytes is the maximum sum of the lengths of the values of the
+	// instances.
+	MaxBytes uint64 `protobuf:"opt"`
+	// MaxInstances is the maximum number of instances.
+	MaxInstances uint64 `protobuf:"opt"`
 }
 
 // Proof represents everything necessary to verify a given
 // key/value pair is stored in a skipchain. The proof is in three parts:
-//   1. InclusionProof proves the presence or absence of the key. In case of
-//   the key being present, the value is included in the proof.
-//   2. Latest is used to verify the Merkle tree root used in the proof is
-//   stored in the latest skipblock.
-//   3. Links proves that the latest skipblock is part of the skipchain.
+//  1. InclusionProof proves the presence or absence of the key. In case of
+//     the key being present, the value is included in the proof.
+//  2. Latest is used to verify the Merkle tree root used in the proof is
+//     stored in the latest skipblock.
+//  3. Links proves that the latest skipblock is part of the skipchain.
 //
 // This Structure could later be moved to cothority/skipchain.
 type Proof struct {
@@ -209,6 +295,10 @@ type Instruction struct {
 	// Signatures that are verified using the Darc controlling access to
 	// the instance.
 	Signatures [][]byte
+	// verified holds the result of the batch verification of the
+	// signatures, if they have been verified with the other signatures of
+	// the block.
+	verified *batchResult
 	// version is a private field that can allow an instruction to be passed
 	// around with the context of a block with a specific version.
 	// This field must be the last field of the struct, so that the
@@ -263,6 +353,11 @@ type ClientTransaction struct {
 type TxResult struct {
 	ClientTransaction ClientTransaction
 	Accepted          bool
+	// Error holds the reason why the transaction has been refused. It is
+	// a code followed by the message of the contract, and is covered by
+	// the hash of the block since VersionRejectionReason.
+	Error   string `protobuf:"opt"`
+	version Version
 }
 
 // StateChange is one new state that will be applied to the collection.
@@ -288,6 +383,13 @@ type Coin struct {
 	Name InstanceID
 	// Value is the total number of coins of that type.
 	Value uint64
+	// MaxSupply is the maximum number of coins of that type that can exist.
+	// It is only set in the genesis instance of the coin, and 0 means that
+	// there is no maximum.
+	MaxSupply uint64 `protobuf:"opt"`
+	// Supply is the number of coins of that type in existence. It is only
+	// kept up to date if MaxSupply is set.
+	Supply uint64 `protobuf:"opt"`
 }
 
 // StreamingRequest is a request asking the service to start streaming blocks
@@ -452,6 +554,78 @@ type CheckStateChangeValidityResponse struct {
 	BlockID      skipchain.SkipBlockID
 }
 
+// GetInstanceAt is a request asking for the value of an instance as it was
+// after the block with the given index has been applied.
+type GetInstanceAt struct {
+	SkipChainID skipchain.SkipBlockID
+	InstanceID  InstanceID
+	BlockIndex  int
+}
+
+// GetInstanceAtResponse holds the last state change of the instance up to
+// the requested block, and the block where this state change happened, so
+// that it can be verified without trusting the conode.
+type GetInstanceAtResponse struct {
+	StateChange StateChange
+	// Block is the block where the state change happened.
+	Block skipchain.SkipBlock
+	// Links go from the genesis block to Block. The first link is
+	// synthetic and holds the roster of the genesis block.
+	Links []skipchain.ForwardLink
+	// StateChanges are all the state changes of Block, whose hash is
+	// stored in the header of the block.
+	StateChanges []StateChange
+}
+
+// GetEvictionProposals asks a conode for its proposals to evict misbehaving
+// members of the roster of a chain.
+type GetEvictionProposals struct {
+	SkipChainID skipchain.SkipBlockID
+}
+
+// GetEvictionProposalsResponse holds the proposals of the conode, each one
+// with its evidence.
+type GetEvictionProposalsResponse struct {
+	Proposals []EvictionProposal
+}
+
+// GetBlockChunks asks for chunks of the body of a block, given by their
+// indexes.
+type GetBlockChunks struct {
+	SkipChainID skipchain.SkipBlockID
+	BlockID     skipchain.SkipBlockID
+	Indexes     []int
+}
+
+// GetBlockChunksResponse holds the block without its body, the number of
+// chunks of the body, and the requested chunks with their proofs.
+type GetBlockChunksResponse struct {
+	Block  skipchain.SkipBlock
+	Count  int
+	Chunks []BlockChunk
+}
+
+// GetBlocksSince asks for the blocks created at or after a time.
+type GetBlocksSince struct {
+	SkipChainID skipchain.SkipBlockID
+	// Since is a Unix timestamp in nanoseconds, compared to the timestamps
+	// of the headers of the blocks.
+	Since int64
+	// HeadersOnly removes the bodies of the blocks from the response.
+	HeadersOnly bool
+	// StartIndex skips the blocks with a lower index, to continue after a
+	// truncated response.
+	StartIndex int `protobuf:"opt"`
+}
+
+// GetBlocksSinceResponse holds the blocks from the oldest to the newest. If
+// Truncated is set, the newest blocks are missing and have to be asked for
+// with another request.
+type GetBlocksSinceResponse struct {
+	Blocks    []*skipchain.SkipBlock
+	Truncated bool
+}
+
 // ResolveInstanceID is the request for resolving the instance ID based on the
 // Darc ID and the name.
 type ResolveInstanceID struct {
@@ -467,8 +641,26 @@ type ResolvedInstanceID struct {
 
 // DebugRequest returns the list of all byzcoins if byzcoinid is empty, else it returns
 // a dump of all instances if byzcoinid is given and exists.
+// Unsigned requests are only accepted on the loopback interface. Signed
+// requests are either signed by the private key of the conode, or by an
+// identity that is allowed to "_debug" in the genesis darc of the byzcoin
+// instance.
 type DebugRequest struct {
 	ByzCoinID []byte `protobuf:"opt"`
+	// Timestamp is the unix time in seconds when the request has been
+	// signed.
+	Timestamp int64 `protobuf:"opt"`
+	// Identity is the signer of the request. If it is nil, the request
+	// must be signed by the conode.
+	Identity *darc.Identity `protobuf:"opt"`
+	// Signature is on the message returned by DebugRequestMessage, which
+	// covers the public key of the conode and all the other fields. Each
+	// signature is accepted only once.
+	Signature []byte `protobuf:"opt"`
+	// Prefix restricts the dump to the instances whose ID starts with it.
+	Prefix []byte `protobuf:"opt"`
+	// ContractIDs restricts the dump to the instances of these contracts.
+	ContractIDs []string `protobuf:"opt"`
 }
 
 // DebugResponse is returned from the server. Either Byzcoins is returned and holds a
@@ -499,3 +691,81 @@ type DebugRemoveRequest struct {
 	ByzCoinID []byte
 	Signature []byte
 }
+
+// NegotiateVersion is sent by a client to learn the version and the features
+// of the protocol supported by a conode.
+type NegotiateVersion struct {
+	// Version is the highest version supported by the client.
+	Version Version
+	// Features are the features the client would like to use.
+	Features []string
+}
+
+// NegotiateVersionResponse holds the result of the negotiation.
+type NegotiateVersionResponse struct {
+	// Version is the highest version supported by both the client and
+	// the conode.
+	Version Version
+	// ConodeVersion is the highest version supported by the conode.
+	ConodeVersion Version
+	// Features are the features requested by the client that are
+	// supported by the conode.
+	Features []string
+}
+
+// GetStateChecksum asks a conode for a digest of its state of a chain, so
+// that operators can compare the state of different nodes.
+type GetStateChecksum struct {
+	ByzCoinID skipchain.SkipBlockID
+}
+
+// GetStateChecksumResponse holds the digest of the state at the latest block
+// stored by the conode.
+type GetStateChecksumResponse struct {
+	// Index of the block corresponding to the state.
+	Index int
+	// TrieRoot is the root of the state trie.
+	TrieRoot []byte
+	// Contracts holds the statistics of every contract present in the
+	// state, sorted by contract ID.
+	Contracts []ContractStats
+	// Digest is the sha256 of the fields above.
+	Digest []byte
+	// Signature is the schnorr signature of the digest by the conode.
+	Signature []byte
+}
+
+// ContractStats holds the number of instances of a contract and the size
+// of their values.
+type ContractStats struct {
+	ContractID string
+	Instances  uint64
+	Bytes      uint64
+}
+
+// BackupRequest asks the conode to write a consistent snapshot of its
+// database to Path, on the machine of the conode. It needs to be signed by
+// the private key of the conode.
+type BackupRequest struct {
+	Path string
+	// Timestamp is the unix time in seconds when the request has been
+	// signed.
+	Timestamp int64
+	// Signature is on Path | Timestamp, with the timestamp as 64-bit
+	// little endian.
+	Signature []byte
+}
+
+// BackupResponse lists the ByzCoin chains verified in the snapshot.
+type BackupResponse struct {
+	Chains []BackupChain
+}
+
+// BackupChain describes the state of one chain in a snapshot.
+type BackupChain struct {
+	ByzCoinID skipchain.SkipBlockID
+	// Index of the block corresponding to the state trie.
+	Index int
+	// TrieRoot is the root of the state trie.
+	TrieRoot []byte
+}
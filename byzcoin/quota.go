@@ -0,0 +1,219 @@
+package byzcoin
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+
+	"go.dedis.ch/protobuf"
+	"golang.org/x/xerrors"
+)
+
+// quotaUsageContractID is the contract of the instances holding the usage of
+// the quotas. No contract is registered under this ID, so that only the
+// quota checks can change these instances.
+const quotaUsageContractID = "quotaUsage"
+
+// storageUsage is the state held by the instances matching a quota.
+type storageUsage struct {
+	Bytes     int64
+	Instances int64
+}
+
+// usageID returns the ID of the instance holding the usage of the quota.
+func (q StorageQuota) usageID() InstanceID {
+	h := sha256.New()
+	h.Write([]byte(quotaUsageContractID))
+	h.Write([]byte(q.String()))
+	return NewInstanceID(h.Sum(nil))
+}
+
+// matches returns true if the given instance is counted by the quota.
+func (q StorageQuota) matches(body *StateChangeBody) bool {
+	if body == nil {
+		return false
+	}
+	if q.ContractID != "" {
+		return body.ContractID == q.ContractID
+	}
+	return bytes.Equal(body.DarcID, q.DarcID)
+}
+
+func (q StorageQuota) String() string {
+	if q.ContractID != "" {
+		return "contract " + q.ContractID
+	}
+	return fmt.Sprintf("darc %x", []byte(q.DarcID))
+}
+
+func (q StorageQuota) sanityCheck() error {
+	if (q.ContractID == "") == (len(q.DarcID) == 0) {
+		return xerrors.New("quota needs either a contract or a darc")
+	}
+	if q.ContractID == ContractConfigID {
+		return xerrors.New("cannot set a quota on the config contract")
+	}
+	if q.ContractID == quotaUsageContractID {
+		return xerrors.New("cannot set a quota on the usage of the quotas")
+	}
+	return nil
+}
+
+// getBody returns the instance stored under key, or nil if it doesn't exist.
+func getBody(st ReadOnlyStateTrie, key []byte) (*StateChangeBody, error) {
+	value, version, contractID, darcID, err := st.GetValues(key)
+	if xerrors.Is(err, errKeyNotSet) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, xerrors.Errorf("reading trie: %v", err)
+	}
+	return &StateChangeBody{
+		ContractID: contractID,
+		Value:      value,
+		Version:    version,
+		DarcID:     darcID,
+	}, nil
+}
+
+// checkQuotas verifies that the state changes, applied to the trie before
+// to give the trie after, don't make a contract or a darc go over its quota.
+// Only the quotas whose usage grows with the state changes are checked, so
+// that instances over the quota can still be updated to shrink or removed.
+//
+// The usage of every quota is kept in an instance of the trie, and updated
+// with the state changes that are returned. The usage of a new quota is
+// computed by going once through the trie, and the usage of a removed quota
+// is deleted. This makes the transaction adding a quota linear in the size of
+// the state, while all the other transactions only read the instances they
+// change and the usage of the quotas.
+func checkQuotas(quotas []StorageQuota, before, after ReadOnlyStateTrie, scs StateChanges) (StateChanges, error) {
+	var oldQuotas []StorageQuota
+	if config, err := LoadConfigFromTrie(before); err == nil {
+		oldQuotas = config.Quotas
+	}
+
+	var usageScs StateChanges
+	kept := make(map[string]bool)
+	for _, q := range quotas {
+		kept[q.String()] = true
+	}
+	for _, q := range oldQuotas {
+		if kept[q.String()] {
+			continue
+		}
+		id := q.usageID()
+		old, err := getBody(before, id.Slice())
+		if err != nil {
+			return nil, err
+		}
+		if old != nil {
+			usageScs = append(usageScs, NewStateChange(Remove, id,
+				quotaUsageContractID, nil, nil))
+		}
+	}
+	if len(quotas) == 0 {
+		return usageScs, nil
+	}
+
+	deltas := make([]storageUsage, len(quotas))
+	seen := make(map[string]bool)
+	for _, sc := range scs {
+		// The configuration must always be updatable, else it would not
+		// be possible to change the quotas anymore.
+		if ConfigInstanceID.Equal(NewInstanceID(sc.InstanceID)) ||
+			seen[string(sc.InstanceID)] {
+			continue
+		}
+		seen[string(sc.InstanceID)] = true
+
+		old, err := getBody(before, sc.InstanceID)
+		if err != nil {
+			return nil, err
+		}
+		cur, err := getBody(after, sc.InstanceID)
+		if err != nil {
+			return nil, err
+		}
+		for i, q := range quotas {
+			if q.matches(old) {
+				deltas[i].Instances--
+				deltas[i].Bytes -= int64(len(old.Value))
+			}
+			if q.matches(cur) {
+				deltas[i].Instances++
+				deltas[i].Bytes += int64(len(cur.Value))
+			}
+		}
+	}
+
+	for i, q := range quotas {
+		id := q.usageID()
+		old, err := getBody(before, id.Slice())
+		if err != nil {
+			return nil, err
+		}
+		var usage storageUsage
+		action := Update
+		if old == nil {
+			action = Create
+			usage, err = storageUsageOf(after, q)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			if deltas[i] == (storageUsage{}) {
+				continue
+			}
+			if err := protobuf.Decode(old.Value, &usage); err != nil {
+				return nil, xerrors.Errorf("decoding usage of %s: %v", q, err)
+			}
+			usage.Bytes += deltas[i].Bytes
+			usage.Instances += deltas[i].Instances
+		}
+
+		if q.MaxBytes > 0 && deltas[i].Bytes > 0 &&
+			usage.Bytes > int64(q.MaxBytes) {
+			return nil, xerrors.Errorf("quota of %s exceeded: %d bytes > %d",
+				q, usage.Bytes, q.MaxBytes)
+		}
+		if q.MaxInstances > 0 && deltas[i].Instances > 0 &&
+			usage.Instances > int64(q.MaxInstances) {
+			return nil, xerrors.Errorf("quota of %s exceeded: %d instances > %d",
+				q, usage.Instances, q.MaxInstances)
+		}
+
+		buf, err := protobuf.Encode(&usage)
+		if err != nil {
+			return nil, xerrors.Errorf("encoding usage of %s: %v", q, err)
+		}
+		usageScs = append(usageScs, NewStateChange(action, id,
+			quotaUsageContractID, buf, nil))
+	}
+	return usageScs, nil
+}
+
+// storageUsageOf goes through the whole trie and returns the usage of the
+// quota. Like in checkQuotas, the configuration is not counted. It is only
+// called once per quota, when the quota is added.
+func storageUsageOf(st ReadOnlyStateTrie, q StorageQuota) (storageUsage, error) {
+	var usage storageUsage
+	err := st.ForEach(func(k, v []byte) error {
+		if ConfigInstanceID.Equal(NewInstanceID(k)) {
+			return nil
+		}
+		body, err := decodeStateChangeBody(v)
+		if err != nil {
+			return xerrors.Errorf("decoding %x: %v", k, err)
+		}
+		if q.matches(&body) {
+			usage.Instances++
+			usage.Bytes += int64(len(body.Value))
+		}
+		return nil
+	})
+	if err != nil {
+		return usage, xerrors.Errorf("iterating trie: %v", err)
+	}
+	return usage, nil
+}
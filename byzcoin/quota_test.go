@@ -0,0 +1,130 @@
+package byzcoin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/protobuf"
+)
+
+func TestQuota_Check(t *testing.T) {
+	st, err := newMemStateTrie([]byte("nonce"))
+	require.NoError(t, err)
+	darcID := darc.ID(make([]byte, 32))
+	require.NoError(t, st.StoreAll(StateChanges{
+		NewStateChange(Create, NewInstanceID([]byte("a")), "value", make([]byte, 10), darcID),
+		NewStateChange(Create, NewInstanceID([]byte("b")), "value", make([]byte, 10), darcID),
+		NewStateChange(Create, NewInstanceID([]byte("c")), "other", make([]byte, 100), nil),
+	}, 1, CurrentVersion))
+
+	check := func(quotas []StorageQuota, scs StateChanges) error {
+		after, err := st.StoreAllToReplica(scs)
+		require.NoError(t, err)
+		_, err = checkQuotas(quotas, st, after, scs)
+		return err
+	}
+	create := StateChanges{NewStateChange(Create, NewInstanceID([]byte("d")), "value", make([]byte, 10), darcID)}
+
+	require.NoError(t, check(nil, create))
+	require.NoError(t, check([]StorageQuota{{ContractID: "value", MaxInstances: 3}}, create))
+	require.Error(t, check([]StorageQuota{{ContractID: "value", MaxInstances: 2}}, create))
+	require.NoError(t, check([]StorageQuota{{ContractID: "value", MaxBytes: 30}}, create))
+	require.Error(t, check([]StorageQuota{{ContractID: "value", MaxBytes: 29}}, create))
+	require.Error(t, check([]StorageQuota{{DarcID: darcID, MaxInstances: 2}}, create))
+	require.NoError(t, check([]StorageQuota{{ContractID: "other", MaxInstances: 1}}, create))
+
+	// Instances over the quota can still be shrunk or removed.
+	quotas := []StorageQuota{{ContractID: "value", MaxBytes: 5, MaxInstances: 1}}
+	require.NoError(t, check(quotas, StateChanges{
+		NewStateChange(Update, NewInstanceID([]byte("a")), "value", make([]byte, 5), darcID),
+	}))
+	require.NoError(t, check(quotas, StateChanges{
+		NewStateChange(Remove, NewInstanceID([]byte("a")), "value", nil, darcID),
+	}))
+	require.Error(t, check(quotas, StateChanges{
+		NewStateChange(Update, NewInstanceID([]byte("a")), "value", make([]byte, 11), darcID),
+	}))
+
+	// Moving an instance to another darc counts for the new darc.
+	other := darc.ID(make([]byte, 32))
+	other[0] = 1
+	require.Error(t, check([]StorageQuota{{DarcID: other, MaxBytes: 50}}, StateChanges{
+		NewStateChange(Update, NewInstanceID([]byte("c")), "other", make([]byte, 100), other),
+	}))
+}
+
+// TestQuota_Usage checks that the usage of the quotas is kept in the trie and
+// updated with the state changes.
+func TestQuota_Usage(t *testing.T) {
+	st, err := newMemStateTrie([]byte("nonce"))
+	require.NoError(t, err)
+	darcID := darc.ID(make([]byte, 32))
+	quotas := []StorageQuota{{ContractID: "value", MaxInstances: 3}}
+	index := 1
+	apply := func(quotas []StorageQuota, scs StateChanges) (StateChanges, error) {
+		after, err := st.StoreAllToReplica(scs)
+		require.NoError(t, err)
+		usageScs, err := checkQuotas(quotas, st, after, scs)
+		if err != nil {
+			return nil, err
+		}
+		require.NoError(t, st.StoreAll(append(scs, usageScs...), index, CurrentVersion))
+		index++
+		return usageScs, nil
+	}
+	create := func(name string) StateChanges {
+		return StateChanges{NewStateChange(Create, NewInstanceID([]byte(name)),
+			"value", make([]byte, 10), darcID)}
+	}
+	usage := func() storageUsage {
+		body, err := getBody(st, quotas[0].usageID().Slice())
+		require.NoError(t, err)
+		require.NotNil(t, body)
+		var u storageUsage
+		require.NoError(t, protobuf.Decode(body.Value, &u))
+		return u
+	}
+
+	// The usage is computed from the trie the first time.
+	require.NoError(t, st.StoreAll(create("a"), index, CurrentVersion))
+	usageScs, err := apply(quotas, create("b"))
+	require.NoError(t, err)
+	require.Equal(t, 1, len(usageScs))
+	require.Equal(t, Create, usageScs[0].StateAction)
+	require.Equal(t, storageUsage{Bytes: 20, Instances: 2}, usage())
+
+	// Then it is only updated with the state changes.
+	_, err = apply(quotas, create("c"))
+	require.NoError(t, err)
+	require.Equal(t, storageUsage{Bytes: 30, Instances: 3}, usage())
+	_, err = apply(quotas, create("d"))
+	require.Error(t, err)
+	_, err = apply(quotas, StateChanges{NewStateChange(Remove, NewInstanceID([]byte("a")),
+		"value", nil, darcID)})
+	require.NoError(t, err)
+	require.Equal(t, storageUsage{Bytes: 20, Instances: 2}, usage())
+	usageScs, err = apply(quotas, StateChanges{NewStateChange(Create, NewInstanceID([]byte("e")),
+		"other", make([]byte, 1), nil)})
+	require.NoError(t, err)
+	require.Equal(t, 0, len(usageScs))
+
+	// The usage of a removed quota is deleted.
+	config, err := protobuf.Encode(&ChainConfig{Quotas: quotas})
+	require.NoError(t, err)
+	require.NoError(t, st.StoreAll(StateChanges{NewStateChange(Create, ConfigInstanceID,
+		ContractConfigID, config, darcID)}, index, CurrentVersion))
+	usageScs, err = apply(nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(usageScs))
+	require.Equal(t, Remove, usageScs[0].StateAction)
+}
+
+func TestQuota_SanityCheck(t *testing.T) {
+	require.Error(t, StorageQuota{}.sanityCheck())
+	require.Error(t, StorageQuota{ContractID: "value", DarcID: darc.ID{1}}.sanityCheck())
+	require.Error(t, StorageQuota{ContractID: ContractConfigID}.sanityCheck())
+	require.Error(t, StorageQuota{ContractID: quotaUsageContractID}.sanityCheck())
+	require.NoError(t, StorageQuota{ContractID: "value"}.sanityCheck())
+	require.NoError(t, StorageQuota{DarcID: darc.ID{1}}.sanityCheck())
+}
@@ -11,9 +11,12 @@ import (
 	"net"
 	"net/http"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"go.dedis.ch/cothority/v3"
 	"go.dedis.ch/cothority/v3/blscosi/protocol"
@@ -52,6 +55,10 @@ var catchupMinimumInterval = 10 * time.Minute
 // How many blocks it should fetch in one go.
 var catchupFetchBlocks = 10
 
+// debugWindow is how far the timestamp of a signed debug request can be
+// from the time of the conode.
+const debugWindow = time.Minute
+
 // How many DB-entries to download in one go.
 var catchupFetchDBEntries = 100
 
@@ -96,6 +103,10 @@ func init() {
 	if err != nil {
 		panic(err)
 	}
+	err = RegisterGlobalContract(ContractJobID, contractJobFromBytes)
+	if err != nil {
+		panic(err)
+	}
 }
 
 // GenNonce returns a random nonce.
@@ -138,6 +149,10 @@ type Service struct {
 	// contracts map kinds to kind specific verification functions
 	contracts *contractRegistry
 
+	// txValidators are run on the transactions sent by the clients to this
+	// node.
+	txValidators txValidators
+
 	storage *bcStorage
 
 	createSkipChainMut sync.Mutex
@@ -160,6 +175,18 @@ type Service struct {
 	catchingUpHistory     map[string]time.Time
 	catchingUpHistoryLock sync.Mutex
 
+	// knownIndex holds the highest block index this node heard of for
+	// every chain, which can be higher than the stored index while the
+	// node is catching up.
+	knownIndex     map[string]int
+	knownIndexLock sync.Mutex
+
+	// debugSeen holds the signatures of the debug requests accepted
+	// within the debugWindow, with the time they expire, so that they
+	// can't be replayed.
+	debugSeen     map[string]time.Time
+	debugSeenLock sync.Mutex
+
 	downloadState downloadState
 
 	rotationWindow time.Duration
@@ -191,6 +218,13 @@ const defaultInterval = 5 * time.Second
 // defaultMaxBlockSize is used when the config cannot be loaded.
 const defaultMaxBlockSize = 4 * 1e6
 
+// defaultBaseHeight and defaultMaximumHeight are the parameters of the
+// skiplist used if they are not set in the genesis request.
+const (
+	defaultBaseHeight    = 4
+	defaultMaximumHeight = 32
+)
+
 // bcStorage is used to save our data locally.
 type bcStorage struct {
 	// PropTimeout is used when sending the request to integrate a new block
@@ -209,25 +243,99 @@ func (s *Service) GetProtocolVersion() Version {
 	return v
 }
 
+// NegotiateVersion returns the highest version supported by both the client
+// and this conode, together with the requested features this conode knows.
+func (s *Service) NegotiateVersion(req *NegotiateVersion) (*NegotiateVersionResponse, error) {
+	if req.Version <= 0 {
+		return nil, xerrors.Errorf("invalid version %d", req.Version)
+	}
+	resp := &NegotiateVersionResponse{
+		Version:       s.GetProtocolVersion(),
+		ConodeVersion: s.GetProtocolVersion(),
+	}
+	if req.Version < resp.Version {
+		resp.Version = req.Version
+	}
+	for _, f := range req.Features {
+		if containsString(SupportedFeatures, f) {
+			resp.Features = append(resp.Features, f)
+		}
+	}
+	return resp, nil
+}
+
 // GetAllByzCoinIDs returns the list of Byzcoin chains known by the server.
+// If identities are given, only the chains where they are authorized for at
+// least one rule of the genesis darc are returned.
 func (s *Service) GetAllByzCoinIDs(req *GetAllByzCoinIDsRequest) (*GetAllByzCoinIDsResponse, error) {
 	chains, err := s.skService().GetDB().GetSkipchains()
 	if err != nil {
 		return nil, xerrors.Errorf("getting chains: %v", err)
 	}
 
-	ids := make([]skipchain.SkipBlockID, len(chains))
-	index := 0
+	resp := &GetAllByzCoinIDsResponse{}
 	for k := range chains {
 		id := skipchain.SkipBlockID(k)
+		if !s.hasByzCoinVerification(id) {
+			continue
+		}
 
-		if s.hasByzCoinVerification(id) {
-			ids[index] = id
-			index++
+		if len(req.Identities) > 0 {
+			st, err := s.GetReadOnlyStateTrie(id)
+			if err != nil {
+				log.Warnf("skipping chain %x: %v", id, err)
+				continue
+			}
+			d, err := s.LoadGenesisDarc(id)
+			if err != nil {
+				log.Warnf("skipping chain %x: %v", id, err)
+				continue
+			}
+			if len(authorizedActions(st, d, req.Identities)) == 0 {
+				continue
+			}
 		}
+
+		if req.WithMetadata {
+			md, err := s.chainMetadata(id)
+			if err != nil {
+				log.Warnf("skipping chain %x: %v", id, err)
+				continue
+			}
+			resp.Metadata = append(resp.Metadata, *md)
+		}
+		resp.IDs = append(resp.IDs, id)
 	}
 
-	return &GetAllByzCoinIDsResponse{IDs: ids[:index]}, nil
+	return resp, nil
+}
+
+// chainMetadata collects the metadata of the given chain.
+func (s *Service) chainMetadata(id skipchain.SkipBlockID) (*ChainMetadata, error) {
+	gen := s.db().GetByID(id)
+	if gen == nil {
+		return nil, xerrors.New("didn't find genesis block")
+	}
+	var header DataHeader
+	if err := protobuf.Decode(gen.Data, &header); err != nil {
+		return nil, xerrors.Errorf("decoding header: %v", err)
+	}
+	latest, err := s.db().GetLatest(gen)
+	if err != nil {
+		return nil, xerrors.Errorf("getting latest block: %v", err)
+	}
+	config, err := s.LoadConfig(id)
+	if err != nil {
+		return nil, xerrors.Errorf("loading config: %v", err)
+	}
+	return &ChainMetadata{
+		LatestIndex:   latest.Index,
+		Roster:        config.Roster,
+		BlockInterval: config.BlockInterval,
+		Created:       header.Timestamp,
+		BaseHeight:    gen.BaseHeight,
+		MaximumHeight: gen.MaximumHeight,
+	}, nil
 }
 
 // CreateGenesisBlock asks the service to create a new skipchain ready to
@@ -268,6 +376,22 @@ func (s *Service) CreateGenesisBlock(req *CreateGenesisBlock) (
 	bsBuf := make([]byte, 8)
 	binary.PutVarint(bsBuf, int64(req.MaxBlockSize))
 
+	if req.BaseHeight == 0 {
+		req.BaseHeight = defaultBaseHeight
+	}
+	if req.MaximumHeight == 0 {
+		req.MaximumHeight = defaultMaximumHeight
+	}
+	if req.BaseHeight < 0 || req.MaximumHeight < 0 {
+		return nil, xerrors.New("heights of the skiplist cannot be negative")
+	}
+	if req.BaseHeight == 1 && req.MaximumHeight > 1 {
+		return nil, xerrors.New("maximum height must be 1 when the base height is 1")
+	}
+	heightsBuf := make([]byte, 16)
+	binary.PutVarint(heightsBuf, int64(req.BaseHeight))
+	binary.PutVarint(heightsBuf[8:], int64(req.MaximumHeight))
+
 	rosterBuf, err := protobuf.Encode(&req.Roster)
 	if err != nil {
 		return nil, xerrors.Errorf("encoding roster: %v", err)
@@ -305,6 +429,7 @@ func (s *Service) CreateGenesisBlock(req *CreateGenesisBlock) (
 			{Name: "roster", Value: rosterBuf},
 			{Name: "trie_nonce", Value: nonce[:]},
 			{Name: "darc_contracts", Value: darcContractIDsBuf},
+			{Name: "skiplist_heights", Value: heightsBuf},
 		},
 	}
 
@@ -336,7 +461,10 @@ func (s *Service) prepareTxResponse(req *AddTxRequest, tx *TxResult) (*AddTxResp
 	errMsg, exists := s.txErrorBuf.get(tx.ClientTransaction.Instructions.HashWithSignatures())
 	if !tx.Accepted {
 		if !exists {
-			return nil, xerrors.New("transaction is in block, but got refused for unknown error")
+			if tx.Error == "" {
+				return nil, xerrors.New("transaction is in block, but got refused for unknown error")
+			}
+			errMsg = tx.Error
 		}
 		// We cannot return an error here because onet will ignore the response if an error occurs.
 		// The length of the error message is limited if we return an error, so we have to return the
@@ -426,6 +554,15 @@ func (s *Service) AddTransaction(req *AddTxRequest) (*AddTxResponse, error) {
 		log.Lvlf2("Instruction[%d]: %s on instance ID %s", i, instr.Action(), instr.InstanceID.String())
 	}
 
+	st, err := s.getStateTrie(req.SkipchainID)
+	if err != nil {
+		return nil, xerrors.Errorf("getting state trie: %v", err)
+	}
+	err = s.txValidators.validate(st, req.SkipchainID, req.Transaction)
+	if err != nil {
+		return nil, xerrors.Errorf("validating transaction: %v", err)
+	}
+
 	// Note to my future self: s.txBuffer.add used to be out here. It used to work
 	// even. But while investigating other race conditions, we realized that
 	// IF there will be a wait channel, THEN it must exist before the call to add().
@@ -531,6 +668,129 @@ func (s *Service) GetProof(req *GetProof) (*GetProofResponse, error) {
 	}, nil
 }
 
+// maxProofsKeys is the number of keys of a GetProofs request, so that a
+// client can't hold the locks of the trie for too long.
+const maxProofsKeys = 1000
+
+// GetProofs returns the proofs of presence or absence of several keys. All
+// proofs are created from the same state, so they end in the same block.
+func (s *Service) GetProofs(req *GetProofs) (*GetProofsResponse, error) {
+	if len(req.Keys) == 0 {
+		return nil, xerrors.New("no keys given")
+	}
+	if len(req.Keys) > maxProofsKeys {
+		return nil, xerrors.Errorf("cannot give more than %d proofs", maxProofsKeys)
+	}
+
+	s.catchingLock.Lock()
+	s.updateTrieLock.Lock()
+
+	defer func() {
+		s.updateTrieLock.Unlock()
+		s.catchingLock.Unlock()
+	}()
+
+	s.closedMutex.Lock()
+	defer s.closedMutex.Unlock()
+	if s.closed {
+		return nil, xerrors.New("cannot get proof while in closed state")
+	}
+
+	sb := s.db().GetByID(req.ID)
+	if sb == nil {
+		return nil, xerrors.New("cannot find skipblock while getting proof")
+	}
+	st, err := s.GetReadOnlyStateTrie(sb.SkipChainID())
+	if err != nil {
+		return nil, xerrors.Errorf("getting state trie: %w", err)
+	}
+	first, err := NewProof(st, s.db(), req.ID, req.Keys[0])
+	if err != nil {
+		return nil, xerrors.Errorf("making proof: %w", err)
+	}
+
+	resp := &GetProofsResponse{
+		Version: CurrentVersion,
+		Proofs:  []Proof{*first},
+	}
+	for _, key := range req.Keys[1:] {
+		pr, err := st.GetProof(key)
+		if err != nil {
+			return nil, xerrors.Errorf("making proof for %x: %w", key, err)
+		}
+		resp.Proofs = append(resp.Proofs, Proof{
+			InclusionProof: *pr,
+			Latest:         first.Latest,
+			Links:          first.Links,
+		})
+	}
+	log.Lvlf2("%s: Returning %d proofs from chain %x at index %v", s.ServerIdentity(),
+		len(req.Keys), sb.SkipChainID(), first.Latest.Index)
+	return resp, nil
+}
+
+// GetStateChecksum returns the root of the state trie and statistics about
+// the instances of every contract, at the latest block stored by this
+// conode. The digest of the response is signed by the conode.
+func (s *Service) GetStateChecksum(req *GetStateChecksum) (*GetStateChecksumResponse, error) {
+	st, err := s.getStateTrie(req.ByzCoinID)
+	if err != nil {
+		return nil, xerrors.Errorf("getting state trie: %v", err)
+	}
+
+	// The trie is read in a single read-only transaction, which is a
+	// consistent snapshot that doesn't stop the new blocks.
+	resp := &GetStateChecksumResponse{}
+	stats := make(map[string]*ContractStats)
+	err = st.DB().View(func(b trie.Bucket) error {
+		resp.Index = st.GetIndexWithBucket(b)
+		resp.TrieRoot = append([]byte{}, st.GetRootWithBucket(b)...)
+		return st.ForEachWithBucket(func(k, v []byte) error {
+			scb, err := decodeStateChangeBody(v)
+			if err != nil {
+				return xerrors.Errorf("decoding %x: %v", k, err)
+			}
+			cs, ok := stats[scb.ContractID]
+			if !ok {
+				cs = &ContractStats{ContractID: scb.ContractID}
+				stats[scb.ContractID] = cs
+			}
+			cs.Instances++
+			cs.Bytes += uint64(len(scb.Value))
+			return nil
+		}, b)
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("iterating trie: %v", err)
+	}
+
+	for _, cs := range stats {
+		resp.Contracts = append(resp.Contracts, *cs)
+	}
+	sort.Slice(resp.Contracts, func(i, j int) bool {
+		return resp.Contracts[i].ContractID < resp.Contracts[j].ContractID
+	})
+	resp.Digest = resp.computeDigest()
+	resp.Signature, err = schnorr.Sign(cothority.Suite, s.ServerIdentity().GetPrivate(), resp.Digest)
+	if err != nil {
+		return nil, xerrors.Errorf("signing digest: %v", err)
+	}
+	return resp, nil
+}
+
+// computeDigest returns the hash of all fields except the digest.
+func (r GetStateChecksumResponse) computeDigest() []byte {
+	h := sha256.New()
+	binary.Write(h, binary.LittleEndian, int64(r.Index))
+	h.Write(r.TrieRoot)
+	for _, cs := range r.Contracts {
+		h.Write([]byte(cs.ContractID))
+		binary.Write(h, binary.LittleEndian, cs.Instances)
+		binary.Write(h, binary.LittleEndian, cs.Bytes)
+	}
+	return h.Sum(nil)
+}
+
 // CheckAuthorization verifies whether a given combination of identities can
 // fulfill a given rule of a given darc. Because all darcs are now used in
 // an online fashion, we need to offer this check.
@@ -546,6 +806,14 @@ func (s *Service) CheckAuthorization(req *CheckAuthorization) (resp *CheckAuthor
 	if err != nil {
 		return nil, xerrors.Errorf("couldn't find darc: %v", err)
 	}
+	resp.Actions = authorizedActions(st, d, req.Identities)
+	return resp, nil
+}
+
+// authorizedActions returns the actions of the darc whose rules are
+// fulfilled by the given identities. Darcs referenced in the rules are
+// looked up in the trie.
+func authorizedActions(st ReadOnlyStateTrie, d *darc.Darc, identities []darc.Identity) (actions []darc.Action) {
 	getDarcs := func(s string, latest bool) *darc.Darc {
 		if !latest {
 			log.Error("cannot handle intermediate darcs")
@@ -564,16 +832,15 @@ func (s *Service) CheckAuthorization(req *CheckAuthorization) (resp *CheckAuthor
 		return d
 	}
 	var ids []string
-	for _, i := range req.Identities {
+	for _, i := range identities {
 		ids = append(ids, i.String())
 	}
 	for _, r := range d.Rules.List {
-		err = darc.EvalExprDarc(r.Expr, getDarcs, true, ids...)
-		if err == nil {
-			resp.Actions = append(resp.Actions, r.Action)
+		if darc.EvalExprDarc(r.Expr, getDarcs, true, ids...) == nil {
+			actions = append(actions, r.Action)
 		}
 	}
-	return resp, nil
+	return
 }
 
 // GetSignerCounters gets the latest signer counters for the given identities.
@@ -725,6 +992,179 @@ func (s *Service) GetAllInstanceVersion(req *GetAllInstanceVersion) (res *GetAll
 	return &GetAllInstanceVersionResponse{StateChanges: scs}, nil
 }
 
+// GetInstanceAt reconstructs the value of an instance as it was after the
+// block with the given index, using the stored state changes. The response
+// holds the block of the last state change and the links from the genesis
+// block, so that the client can verify it.
+func (s *Service) GetInstanceAt(req *GetInstanceAt) (*GetInstanceAtResponse, error) {
+	latest, err := s.db().GetLatestByID(req.SkipChainID)
+	if err != nil {
+		return nil, xerrors.Errorf("getting latest block: %v", err)
+	}
+	if req.BlockIndex < 0 || req.BlockIndex > latest.Index {
+		return nil, xerrors.Errorf("block index %d out of range", req.BlockIndex)
+	}
+
+	sces, err := s.stateChangeStorage.getAll(req.InstanceID[:], req.SkipChainID)
+	if err != nil {
+		return nil, xerrors.Errorf("getting state changes: %v", err)
+	}
+	if len(sces) == 0 {
+		return nil, cothority.WrapError(errKeyNotSet)
+	}
+	// The entries are sorted by version, but an instance that has been
+	// removed and created again restarts at version 0.
+	sort.SliceStable(sces, func(i, j int) bool {
+		if sces[i].BlockIndex != sces[j].BlockIndex {
+			return sces[i].BlockIndex < sces[j].BlockIndex
+		}
+		return sces[i].TxIndex < sces[j].TxIndex
+	})
+
+	var sce *StateChangeEntry
+	for i := range sces {
+		if sces[i].BlockIndex > req.BlockIndex {
+			break
+		}
+		sce = &sces[i]
+	}
+	if sce == nil {
+		if sces[0].StateChange.StateAction == Create {
+			return nil, xerrors.Errorf("instance did not exist at block %d",
+				req.BlockIndex)
+		}
+		return nil, xerrors.New("history of the instance is not available anymore")
+	}
+	if sce.StateChange.StateAction == Remove {
+		return nil, xerrors.Errorf("instance was removed at block %d",
+			sce.BlockIndex)
+	}
+
+	sb, err := s.skService().GetSingleBlockByIndex(&skipchain.GetSingleBlockByIndex{
+		Genesis: req.SkipChainID,
+		Index:   sce.BlockIndex,
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("getting block: %v", err)
+	}
+
+	blockScs, err := s.stateChangeStorage.getByBlock(req.SkipChainID, sce.BlockIndex)
+	if err != nil {
+		return nil, xerrors.Errorf("getting state changes: %v", err)
+	}
+
+	resp := &GetInstanceAtResponse{
+		StateChange: sce.StateChange,
+		Block:       *sb.SkipBlock,
+	}
+	for _, l := range sb.Links {
+		resp.Links = append(resp.Links, *l)
+	}
+	for _, e := range blockScs {
+		resp.StateChanges = append(resp.StateChanges, e.StateChange.Copy())
+	}
+	return resp, nil
+}
+
+// maxBlocksSince is the maximum number of blocks returned by GetBlocksSince.
+// It is not a constant so that the tests can change it.
+var maxBlocksSince = 1000
+
+// GetBlocksSince returns the blocks of the chain with a timestamp at or after
+// the one of the request, from the oldest to the newest. The timestamps of
+// consecutive blocks can be out of order by the window allowed by the
+// verification, so the search for the first block goes back until it finds a
+// block older than twice this window. If there are more than maxBlocksSince
+// blocks, the oldest ones are returned and the response is truncated. The
+// next blocks are then returned by asking again with the index after the last
+// block as the start index, from which the blocks are read forward.
+func (s *Service) GetBlocksSince(req *GetBlocksSince) (*GetBlocksSinceResponse, error) {
+	latest, err := s.db().GetLatestByID(req.SkipChainID)
+	if err != nil {
+		return nil, xerrors.Errorf("getting latest block: %v", err)
+	}
+
+	reply := &GetBlocksSinceResponse{}
+	var first *skipchain.SkipBlock
+	if req.StartIndex > 0 {
+		if req.StartIndex > latest.Index {
+			return reply, nil
+		}
+		sb, err := s.skService().GetSingleBlockByIndex(&skipchain.GetSingleBlockByIndex{
+			Genesis: req.SkipChainID,
+			Index:   req.StartIndex,
+		})
+		if err != nil {
+			return nil, xerrors.Errorf("getting start block: %v", err)
+		}
+		first = sb.SkipBlock
+	} else {
+		first, err = s.firstBlockSince(latest, req.Since)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for sb := first; sb != nil; {
+		header, err := decodeBlockHeader(sb)
+		if err != nil {
+			return nil, xerrors.Errorf("decoding header: %v", err)
+		}
+		if header.Timestamp >= req.Since {
+			if len(reply.Blocks) == maxBlocksSince {
+				reply.Truncated = true
+				break
+			}
+			if req.HeadersOnly {
+				sb = sb.Copy()
+				sb.Payload = nil
+			}
+			reply.Blocks = append(reply.Blocks, sb)
+		}
+		if len(sb.ForwardLink) == 0 {
+			break
+		}
+		sb = s.db().GetByID(sb.ForwardLink[0].To)
+	}
+	return reply, nil
+}
+
+// firstBlockSince goes back from the latest block and returns the oldest
+// block with a timestamp at or after since, or nil if there is none.
+func (s *Service) firstBlockSince(latest *skipchain.SkipBlock, since int64) (*skipchain.SkipBlock, error) {
+	interval, _, err := s.LoadBlockInfo(latest.SkipChainID())
+	if err != nil {
+		return nil, xerrors.Errorf("loading block info: %v", err)
+	}
+	window := 4 * interval
+	if window < minTimestampWindow {
+		window = minTimestampWindow
+	}
+	bound := since - 2*window.Nanoseconds()
+
+	var first *skipchain.SkipBlock
+	for sb := latest; ; {
+		header, err := decodeBlockHeader(sb)
+		if err != nil {
+			return nil, xerrors.Errorf("decoding header: %v", err)
+		}
+		if header.Timestamp < bound {
+			break
+		}
+		if header.Timestamp >= since {
+			first = sb
+		}
+		if sb.Index == 0 {
+			break
+		}
+		sb = s.db().GetByID(sb.BackLinkIDs[0])
+		if sb == nil {
+			return nil, xerrors.New("missing block")
+		}
+	}
+	return first, nil
+}
+
 // CheckStateChangeValidity gets the list of state changes belonging to the same
 // block as the targeted one so that a hash can be computed and compared to the
 // one stored in the block
@@ -773,11 +1213,7 @@ func (s *Service) ResolveInstanceID(req *ResolveInstanceID) (*ResolvedInstanceID
 		return nil, xerrors.New("darc ID must be set")
 	}
 
-	h := sha256.New()
-	h.Write(req.DarcID)
-	h.Write([]byte{'/'})
-	h.Write([]byte(req.Name))
-	key := NewInstanceID(h.Sum(nil))
+	key := namingKey(req.DarcID, req.Name)
 	val, _, _, _, err := st.GetValues(key[:])
 	if err != nil {
 		return nil, xerrors.Errorf("reading trie: %v", err)
@@ -812,10 +1248,17 @@ func (s *Service) ProcessClientRequest(req *http.Request, path string, buf []byt
 		}
 		ip := net.ParseIP(h)
 
+		log.Lvlf1("Debug audit: %s called from %s", path, req.RemoteAddr)
 		if !ip.IsLoopback() {
-			return nil, nil, xerrors.New("the 'debug'-endpoint is only allowed on loopback")
+			dr := &DebugRequest{}
+			if err := protobuf.Decode(buf, dr); err != nil || len(dr.Signature) == 0 {
+				return nil, nil, xerrors.New("the 'debug'-endpoint is only allowed on loopback for unsigned requests")
+			}
 		}
 	}
+	if path == "DebugRemove" {
+		log.Lvlf1("Debug audit: %s called from %s", path, req.RemoteAddr)
+	}
 
 	buf, stream, err := s.ServiceProcessor.ProcessClientRequest(req, path, buf)
 	return buf, stream, cothority.ErrorOrNil(err, "processing request")
@@ -824,6 +1267,10 @@ func (s *Service) ProcessClientRequest(req *http.Request, path string, buf []byt
 // Debug can be used to dump things from a byzcoin service. If byzcoinID is nil, it will return all
 // existing byzcoin instances. If byzcoinID is given, it will return all instances for that ID.
 func (s *Service) Debug(req *DebugRequest) (resp *DebugResponse, err error) {
+	if err := s.verifyDebugRequest(req); err != nil {
+		return nil, xerrors.Errorf("verifying request: %v", err)
+	}
+
 	resp = &DebugResponse{}
 	if len(req.ByzCoinID) != 32 {
 		rep, err := s.skService().GetAllSkipChainIDs(nil)
@@ -863,8 +1310,14 @@ func (s *Service) Debug(req *DebugRequest) (resp *DebugResponse, err error) {
 						// Not all key/value pairs are valid statechanges
 						return nil
 					}
+					if !bytes.HasPrefix(ln.Key, req.Prefix) {
+						return nil
+					}
 					scb := StateChangeBody{}
 					err = protobuf.Decode(ln.Value, &scb)
+					if len(req.ContractIDs) > 0 && !containsString(req.ContractIDs, scb.ContractID) {
+						return nil
+					}
 					resp.Dump = append(resp.Dump, DebugResponseState{Key: ln.Key, State: scb})
 				}
 			}
@@ -876,12 +1329,97 @@ func (s *Service) Debug(req *DebugRequest) (resp *DebugResponse, err error) {
 	return
 }
 
+// verifyDebugRequest checks the signature of a debug request and writes
+// an entry to the audit log. Unsigned requests are accepted, as
+// ProcessClientRequest only lets them through on the loopback interface.
+func (s *Service) verifyDebugRequest(req *DebugRequest) error {
+	if len(req.Signature) == 0 {
+		log.Lvlf1("Debug audit: unsigned request for byzcoin %x", req.ByzCoinID)
+		return nil
+	}
+	if math.Abs(time.Since(time.Unix(req.Timestamp, 0)).Seconds()) > debugWindow.Seconds() {
+		return xerrors.New("signature is too old")
+	}
+	msg, err := DebugRequestMessage(s.ServerIdentity().Public, req)
+	if err != nil {
+		return xerrors.Errorf("debug message: %v", err)
+	}
+
+	if req.Identity == nil {
+		err := schnorr.Verify(cothority.Suite, s.ServerIdentity().Public, msg, req.Signature)
+		if err != nil {
+			return xerrors.Errorf("verifying conode signature: %v", err)
+		}
+		if s.debugReplayed(req) {
+			return xerrors.New("request has already been used")
+		}
+		log.Lvlf1("Debug audit: conode-signed request for byzcoin %x", req.ByzCoinID)
+		return nil
+	}
+
+	if len(req.ByzCoinID) != 32 {
+		return xerrors.New("listing all byzcoins needs a conode signature")
+	}
+	if err := req.Identity.Verify(msg, req.Signature); err != nil {
+		return xerrors.Errorf("verifying signature: %v", err)
+	}
+	if s.debugReplayed(req) {
+		return xerrors.New("request has already been used")
+	}
+	scID := skipchain.SkipBlockID(req.ByzCoinID)
+	st, err := s.GetReadOnlyStateTrie(scID)
+	if err != nil {
+		return xerrors.Errorf("getting trie: %v", err)
+	}
+	d, err := s.LoadGenesisDarc(scID)
+	if err != nil {
+		return xerrors.Errorf("loading genesis darc: %v", err)
+	}
+	for _, a := range authorizedActions(st, d, []darc.Identity{*req.Identity}) {
+		if a == DebugAction {
+			log.Lvlf1("Debug audit: request by %s for byzcoin %x", req.Identity, req.ByzCoinID)
+			return nil
+		}
+	}
+	return xerrors.Errorf("%s is not allowed to %s", req.Identity, DebugAction)
+}
+
+// debugReplayed returns true if the signature of the request has already
+// been seen. Else it is remembered until the timestamp of the request falls
+// out of the debugWindow, after which the request is refused anyway.
+func (s *Service) debugReplayed(req *DebugRequest) bool {
+	s.debugSeenLock.Lock()
+	defer s.debugSeenLock.Unlock()
+	now := time.Now()
+	for sig, expiry := range s.debugSeen {
+		if now.After(expiry) {
+			delete(s.debugSeen, sig)
+		}
+	}
+	key := string(req.Signature)
+	if _, ok := s.debugSeen[key]; ok {
+		return true
+	}
+	s.debugSeen[key] = time.Unix(req.Timestamp, 0).Add(debugWindow)
+	return false
+}
+
+func containsString(list []string, str string) bool {
+	for _, l := range list {
+		if l == str {
+			return true
+		}
+	}
+	return false
+}
+
 // DebugRemove deletes an existing byzcoin-instance from the conode.
 func (s *Service) DebugRemove(req *DebugRemoveRequest) (*DebugResponse, error) {
 	if err := schnorr.Verify(cothority.Suite, s.ServerIdentity().Public, req.ByzCoinID, req.Signature); err != nil {
 		log.Error("Signature failure:", err)
 		return nil, xerrors.Errorf("verifying signature: %v", err)
 	}
+	log.Lvlf1("Debug audit: removing byzcoin %x", req.ByzCoinID)
 	idStr := string(req.ByzCoinID)
 	if s.heartbeats.exists(idStr) {
 		log.Lvl2("Removing heartbeat")
@@ -936,6 +1474,73 @@ func (s *Service) DebugRemove(req *DebugRemoveRequest) (*DebugResponse, error) {
 	return &DebugResponse{}, nil
 }
 
+// updateKnownIndex remembers the given index if it is the highest one seen
+// for this chain.
+func (s *Service) updateKnownIndex(scID skipchain.SkipBlockID, index int) {
+	s.knownIndexLock.Lock()
+	if index > s.knownIndex[string(scID)] {
+		s.knownIndex[string(scID)] = index
+	}
+	s.knownIndexLock.Unlock()
+}
+
+// GetStatus returns the synchronisation state of every chain, so that a
+// monitoring tool can detect a node falling behind. For every chain, the
+// keys are prefixed with the first 4 bytes of the chain ID:
+//   - Known: the highest block index this node heard of
+//   - Stored: the index of the latest stored block
+//   - Applied: the index of the latest block applied to the state trie
+//   - Lag: Known - Applied
+//   - LastBlock: the timestamp of the latest stored block
+func (s *Service) GetStatus() *onet.Status {
+	out := make(map[string]string)
+
+	s.updateTrieLock.Lock()
+	out["CatchingUp"] = strconv.FormatBool(s.catchingUp)
+	s.updateTrieLock.Unlock()
+
+	gasr, err := s.skService().GetAllSkipChainIDs(&skipchain.GetAllSkipChainIDs{})
+	if err != nil {
+		log.Error(err)
+		return &onet.Status{Field: out}
+	}
+	chains := 0
+	for _, scID := range gasr.IDs {
+		if !s.hasByzCoinVerification(scID) {
+			continue
+		}
+		chains++
+		latest, err := s.db().GetLatestByID(scID)
+		if err != nil {
+			log.Error(err)
+			continue
+		}
+		prefix := fmt.Sprintf("%x.", []byte(scID)[:4])
+
+		s.knownIndexLock.Lock()
+		known := s.knownIndex[string(scID)]
+		s.knownIndexLock.Unlock()
+		if known < latest.Index {
+			known = latest.Index
+		}
+		applied := -1
+		if st, err := s.getStateTrie(scID); err == nil {
+			applied = st.GetIndex()
+		}
+		out[prefix+"Known"] = strconv.Itoa(known)
+		out[prefix+"Stored"] = strconv.Itoa(latest.Index)
+		out[prefix+"Applied"] = strconv.Itoa(applied)
+		out[prefix+"Lag"] = strconv.Itoa(known - applied)
+
+		var header DataHeader
+		if err := protobuf.Decode(latest.Data, &header); err == nil {
+			out[prefix+"LastBlock"] = time.Unix(0, header.Timestamp).UTC().Format(time.RFC3339)
+		}
+	}
+	out["Chains"] = strconv.Itoa(chains)
+	return &onet.Status{Field: out}
+}
+
 // SetPropagationTimeout overrides the default propagation timeout that is used
 // when a new block is announced to the nodes as well as the skipchain
 // propagation timeout.
@@ -965,8 +1570,7 @@ func (s *Service) createNewBlock(scID skipchain.SkipBlockID, r *onet.Roster, tx
 			return nil, xerrors.New("need roster for genesis block")
 		}
 		sb = skipchain.NewSkipBlock()
-		sb.MaximumHeight = 32
-		sb.BaseHeight = 4
+		sb.BaseHeight, sb.MaximumHeight = loadHeightsFromTxs(tx)
 		// We have to register the verification functions in the genesis block
 		sb.VerifierIDs = []skipchain.VerifierID{skipchain.VerifyBase, Verify}
 
@@ -1035,6 +1639,7 @@ func (s *Service) createNewBlock(scID skipchain.SkipBlockID, r *onet.Roster, tx
 		StateChangesHash:      scs.Hash(),
 		Timestamp:             time.Now().UnixNano(),
 		Version:               version,
+		BodyRoot:              BodyRoot(sb.Payload),
 	}
 	sb.Data, err = protobuf.Encode(header)
 	if err != nil {
@@ -1118,6 +1723,7 @@ func (s *Service) createUpgradeVersionBlock(scID skipchain.SkipBlockID, version
 		StateChangesHash:      scs.Hash(),
 		Timestamp:             time.Now().UnixNano(),
 		Version:               version,
+		BodyRoot:              BodyRoot(sb.Payload),
 	})
 	if err != nil {
 		return nil, xerrors.Errorf("Couldn't marshal data: %v", err)
@@ -1377,6 +1983,7 @@ func (s *Service) catchupFromID(r *onet.Roster, scID skipchain.SkipBlockID, sbID
 // the full DB over the network.
 func (s *Service) catchUp(sb *skipchain.SkipBlock) {
 	log.Lvlf1("%v Catching up %x / %d", s.ServerIdentity(), sb.SkipChainID(), sb.Index)
+	s.updateKnownIndex(sb.SkipChainID(), sb.Index)
 
 	// Load the trie.
 	download := false
@@ -1650,6 +2257,7 @@ func (s *Service) updateTrieCallback(sbID skipchain.SkipBlockID) error {
 	if err != nil {
 		return xerrors.Errorf("loading block info: %v", err)
 	}
+	s.applyChainTimeouts(sb.SkipChainID())
 	if nodeInNew && !s.catchingUp {
 		// Update or start heartbeats
 		if s.heartbeats.exists(string(sb.SkipChainID())) {
@@ -1813,6 +2421,17 @@ func (s *Service) LoadConfig(scID skipchain.SkipBlockID) (*ChainConfig, error) {
 	return cfg, cothority.ErrorOrNil(err, "reading trie")
 }
 
+// applyChainTimeouts passes the timeouts of the configuration of the chain to
+// the skipchain service, which uses them to create the next blocks.
+func (s *Service) applyChainTimeouts(scID skipchain.SkipBlockID) {
+	config, err := s.LoadConfig(scID)
+	if err != nil {
+		log.Lvlf2("%s: couldn't load the timeouts of %x: %v", s.ServerIdentity(), scID, err)
+		return
+	}
+	s.skService().SetChainTimeouts(scID, config.PropagationTimeout, config.VerificationTimeout)
+}
+
 // LoadGenesisDarc loads the genesis darc of the given skipchain ID.
 func (s *Service) LoadGenesisDarc(scID skipchain.SkipBlockID) (*darc.Darc, error) {
 	st, err := s.GetReadOnlyStateTrie(scID)
@@ -1939,6 +2558,15 @@ func (s *Service) verifySkipBlock(newID []byte, newSB *skipchain.SkipBlock) bool
 		return false
 	}
 
+	if len(header.BodyRoot) == 0 && header.Version >= VersionBodyRoot {
+		log.Error(s.ServerIdentity(), "block has no body root")
+		return false
+	}
+	if len(header.BodyRoot) > 0 && !bytes.Equal(header.BodyRoot, BodyRoot(newSB.Payload)) {
+		log.Error(s.ServerIdentity(), "body root doesn't match the body")
+		return false
+	}
+
 	var body DataBody
 	err = protobuf.Decode(newSB.Payload, &body)
 	if err != nil {
@@ -1995,6 +2623,19 @@ func (s *Service) verifySkipBlock(newID []byte, newSB *skipchain.SkipBlock) bool
 			log.Lvl2(s.ServerIdentity(), "Client Transaction accept mistmatch on tx", i)
 			return false
 		}
+		if body.TxResults[i].Accepted && body.TxResults[i].Error != "" {
+			log.Lvl2(s.ServerIdentity(), "Accepted Client Transaction has an error", i)
+			return false
+		}
+		if len(body.TxResults[i].Error) > maxTxErrorLength {
+			log.Lvl2(s.ServerIdentity(), "Rejection reason is too long on tx", i)
+			return false
+		}
+		if header.Version >= VersionRejectionReason &&
+			txOut[i].Error != body.TxResults[i].Error {
+			log.Lvl2(s.ServerIdentity(), "Rejection reason mismatch on tx", i)
+			return false
+		}
 	}
 
 	// Check that the hashes in DataHeader are right.
@@ -2036,12 +2677,8 @@ func (s *Service) verifySkipBlock(newID []byte, newSB *skipchain.SkipBlock) bool
 		window = minTimestampWindow
 	}
 
-	now := time.Now()
-	t1 := now.Add(-window)
-	t2 := now.Add(window)
-	ts := time.Unix(0, header.Timestamp)
-	if ts.Before(t1) || ts.After(t2) {
-		log.Errorf("timestamp %v is outside the acceptable range %v to %v", ts, t1, t2)
+	if err := skipchain.CheckTimestamp(time.Unix(0, header.Timestamp), window); err != nil {
+		log.Error(err)
 		return false
 	}
 
@@ -2104,6 +2741,10 @@ func (s *Service) createStateChanges(sst *stagingStateTrie, scID skipchain.SkipB
 
 	sstTemp = sst.Clone()
 
+	// Verifying the signatures of all the instructions at once is faster
+	// than verifying them in every call to VerifyWithOption.
+	txIn.BatchVerifySignatures()
+
 	for _, tx := range txIn {
 		txsz := txSize(tx)
 
@@ -2112,6 +2753,7 @@ func (s *Service) createStateChanges(sst *stagingStateTrie, scID skipchain.SkipB
 		statesTemp, sstTempC, err = s.processOneTx(sstTemp, tx.ClientTransaction, scID)
 		if err != nil {
 			tx.Accepted = false
+			tx.Error = truncateTxError(err.Error())
 			txOut = append(txOut, tx)
 			log.Error(s.ServerIdentity(), err)
 		} else {
@@ -2145,6 +2787,7 @@ func (s *Service) createStateChanges(sst *stagingStateTrie, scID skipchain.SkipB
 			}
 
 			tx.Accepted = true
+			tx.Error = ""
 			sstTemp = sstTempC
 			blocksz += txsz
 			states = append(states, statesTemp...)
@@ -2162,6 +2805,52 @@ func (s *Service) createStateChanges(sst *stagingStateTrie, scID skipchain.SkipB
 	return
 }
 
+// maxTxErrorLength is the maximum length of the error stored in a TxResult,
+// so that refused transactions don't fill up the blocks.
+const maxTxErrorLength = 256
+
+// truncateTxError cuts msg to maxTxErrorLength bytes without splitting a
+// UTF-8 sequence.
+func truncateTxError(msg string) string {
+	if len(msg) <= maxTxErrorLength {
+		return msg
+	}
+	cut := maxTxErrorLength
+	for cut > 0 && !utf8.RuneStart(msg[cut]) {
+		cut--
+	}
+	return msg[:cut]
+}
+
+// The following codes prefix the rejection reason stored in a TxResult.
+const (
+	rejectContract    = "contract"
+	rejectCounters    = "counters"
+	rejectStateChange = "statechange"
+	rejectStorage     = "storage"
+	rejectQuota       = "quota"
+)
+
+// txRejection is returned by processOneTx for a refused transaction. Unlike
+// the error kept for the client, it doesn't hold the identity of the node,
+// so that all the nodes store the same reason in the TxResult.
+type txRejection struct {
+	code   string
+	reason string
+}
+
+func (r *txRejection) Error() string {
+	return r.code + ": " + r.reason
+}
+
+// rejectTx stores the error of the transaction for the client and returns
+// the rejection to put in the block.
+func (s *Service) rejectTx(tx ClientTransaction, code, reason string) error {
+	rej := &txRejection{code: code, reason: reason}
+	s.addError(tx, xerrors.Errorf("%s %v", s.ServerIdentity(), rej))
+	return rej
+}
+
 // addError simply stores the given error using the hash with signatures of the
 // given instruction as the key.
 func (s *Service) addError(tx ClientTransaction, err error) {
@@ -2170,16 +2859,19 @@ func (s *Service) addError(tx ClientTransaction, err error) {
 
 // processOneTx takes one transaction and creates a set of StateChanges. It
 // also returns the temporary StateTrie with the StateChanges applied. Any data
-// from the trie should be read from sst and not the service.
+// from the trie should be read from sst and not the service. If the
+// transaction is refused, the error is a *txRejection.
 func (s *Service) processOneTx(sst *stagingStateTrie, tx ClientTransaction,
 	scID skipchain.SkipBlockID) (StateChanges, *stagingStateTrie, error) {
 
 	// Make a new trie for each instruction. If the instruction is
 	// sucessfully implemented and changes applied, then keep it
 	// otherwise dump it.
+	before := sst
 	sst = sst.Clone()
 	h := tx.Instructions.Hash()
 	var statesTemp StateChanges
+	var contractScs StateChanges
 	var cin []Coin
 	for _, instr := range tx.Instructions {
 		scs, cout, err := s.executeInstruction(sst, cin, instr, h, scID)
@@ -2188,18 +2880,14 @@ func (s *Service) processOneTx(sst *stagingStateTrie, tx ClientTransaction,
 			if err2 != nil {
 				err = xerrors.Errorf("%v - while getting value: %v", err, err2)
 			}
-			err = xerrors.Errorf("%s Contract %s got %x and returned error: %v",
-				s.ServerIdentity(), cid, instr.Hash(), err)
-			s.addError(tx, err)
-			return nil, nil, err
+			return nil, nil, s.rejectTx(tx, rejectContract,
+				fmt.Sprintf("Contract %s got %x and returned error: %v", cid, instr.Hash(), err))
 		}
 
 		counterScs, err := incrementSignerCounters(sst, instr.SignerIdentities)
 		if err != nil {
-			err = xerrors.Errorf("%s failed to update signature counters: %v",
-				s.ServerIdentity(), err)
-			s.addError(tx, err)
-			return nil, nil, err
+			return nil, nil, s.rejectTx(tx, rejectCounters,
+				fmt.Sprintf("failed to update signature counters: %v", err))
 		}
 
 		// Verify the validity of the state-changes:
@@ -2226,40 +2914,51 @@ func (s *Service) processOneTx(sst *stagingStateTrie, tx ClientTransaction,
 				var contractID string
 				_, _, contractID, _, err = sst.GetValues(instr.InstanceID.Slice())
 				if err != nil {
-					err = xerrors.Errorf("%s couldn't get contractID from the "+
-						"following instruction: %x (with instanceID %x)",
-						s.ServerIdentity(), instr.Hash(), instr.InstanceID.Slice())
-					s.addError(tx, err)
-					return nil, nil, err
+					return nil, nil, s.rejectTx(tx, rejectStateChange,
+						fmt.Sprintf("couldn't get contractID from the "+
+							"following instruction: %x (with instanceID %x)",
+							instr.Hash(), instr.InstanceID.Slice()))
 				}
-				err = xerrors.Errorf("%s: contract %s %s %x", s.ServerIdentity(),
-					contractID, reason, sc.InstanceID)
-				s.addError(tx, err)
-				return nil, nil, err
+				return nil, nil, s.rejectTx(tx, rejectStateChange,
+					fmt.Sprintf("contract %s %s %x", contractID, reason, sc.InstanceID))
 			}
 			log.Lvlf2("StateChange %s for id %x - contract: %s", sc.StateAction,
 				sc.InstanceID, sc.ContractID)
 			err = sst.StoreAll(StateChanges{sc})
 			if err != nil {
-				err = xerrors.Errorf("%s StoreAll failed: %v", s.ServerIdentity(), err)
-				s.addError(tx, err)
-				return nil, nil, err
+				return nil, nil, s.rejectTx(tx, rejectStorage,
+					fmt.Sprintf("StoreAll failed: %v", err))
 			}
 		}
 		if err = sst.StoreAll(counterScs); err != nil {
-			err = xerrors.Errorf("%s StoreAll failed to add counter changes: %v",
-				s.ServerIdentity(), err)
-			s.addError(tx, err)
-			return nil, nil, err
+			return nil, nil, s.rejectTx(tx, rejectStorage,
+				fmt.Sprintf("StoreAll failed to add counter changes: %v", err))
 		}
 		statesTemp = append(statesTemp, scs...)
 		statesTemp = append(statesTemp, counterScs...)
+		contractScs = append(contractScs, scs...)
 		cin = cout
 	}
 	if len(cin) != 0 {
 		log.Lvl2(s.ServerIdentity(), "Leftover coins detected, discarding.")
 	}
 
+	// The configuration doesn't exist before the genesis transaction. The
+	// quotas change the state, so they are only enforced once all the nodes
+	// know them.
+	config, err := LoadConfigFromTrie(sst)
+	if err == nil && sst.GetVersion() >= VersionStorageQuotas {
+		usageScs, err := checkQuotas(config.Quotas, before, sst, contractScs)
+		if err != nil {
+			return nil, nil, s.rejectTx(tx, rejectQuota, err.Error())
+		}
+		if err = sst.StoreAll(usageScs); err != nil {
+			return nil, nil, s.rejectTx(tx, rejectStorage,
+				fmt.Sprintf("StoreAll failed to update the quotas: %v", err))
+		}
+		statesTemp = append(statesTemp, usageScs...)
+	}
+
 	return statesTemp, sst, nil
 }
 
@@ -2473,6 +3172,26 @@ func loadNonceFromTxs(txs TxResults) ([]byte, error) {
 	return nonce, nil
 }
 
+// loadHeightsFromTxs returns the base and maximum heights of the skiplist
+// given in the genesis transaction, or the defaults if they are missing.
+func loadHeightsFromTxs(txs TxResults) (int, int) {
+	base, maximum := defaultBaseHeight, defaultMaximumHeight
+	if len(txs) == 0 || len(txs[0].ClientTransaction.Instructions) == 0 {
+		return base, maximum
+	}
+	spawn := txs[0].ClientTransaction.Instructions[0].Spawn
+	if spawn == nil {
+		return base, maximum
+	}
+	buf := spawn.Args.Search("skiplist_heights")
+	if len(buf) != 16 {
+		return base, maximum
+	}
+	b, _ := binary.Varint(buf[:8])
+	m, _ := binary.Varint(buf[8:])
+	return int(b), int(m)
+}
+
 // TestClose closes the go-routines that are polling for transactions. It is
 // exported because we need it in tests, it should not be used in non-test code
 // outside of this package.
@@ -2589,6 +3308,7 @@ func (s *Service) startAllChains() error {
 	s.pollChanMut.Unlock()
 
 	s.skService().RegisterStoreSkipblockCallback(s.updateTrieCallback)
+	s.skService().RegisterMilestoneState(milestoneState)
 
 	// All the logic necessary to start the chains is delayed to a goroutine so that
 	// the other services can start immediately and are not blocked by Byzcoin.
@@ -2643,6 +3363,7 @@ func (s *Service) startChain(genesisID skipchain.SkipBlockID) error {
 		return xerrors.Errorf("%s ignoring chain %x because we can't load blockInterval: %v",
 			s.ServerIdentity(), genesisID, err)
 	}
+	s.applyChainTimeouts(genesisID)
 
 	if s.db().GetByID(genesisID) == nil {
 		return xerrors.Errorf("%s ignoring chain with missing genesis-block %x",
@@ -2701,12 +3422,7 @@ func (s *Service) hasByzCoinVerification(gen skipchain.SkipBlockID) bool {
 		// if it does, just say "not ours".
 		return false
 	}
-	for _, x := range sb.VerifierIDs {
-		if x.Equal(Verify) {
-			return true
-		}
-	}
-	return false
+	return isByzCoinGenesis(sb)
 }
 
 // saves this service's config information
@@ -2840,6 +3556,16 @@ func decodeBlockHeader(sb *skipchain.SkipBlock) (*DataHeader, error) {
 	return &header, nil
 }
 
+// milestoneState returns the root of the trie after the block, which is the
+// state committed to by the skipchain milestones.
+func milestoneState(sb *skipchain.SkipBlock) ([]byte, error) {
+	header, err := decodeBlockHeader(sb)
+	if err != nil {
+		return nil, xerrors.Errorf("decoding header: %v", err)
+	}
+	return header.TrieRoot, nil
+}
+
 var existingDB = regexp.MustCompile(`^ByzCoin_[0-9a-f]+$`)
 
 // newService receives the context that holds information about the node it's
@@ -2862,6 +3588,8 @@ func newService(c *onet.Context) (onet.Service, error) {
 		streamingMan:           streamingManager{},
 		closed:                 true,
 		catchingUpHistory:      make(map[string]time.Time),
+		knownIndex:             make(map[string]int),
+		debugSeen:              make(map[string]time.Time),
 		rotationWindow:         defaultRotationWindow,
 		defaultVersion:         CurrentVersion,
 		// We need a large enough buffer for all errors in 2 blocks
@@ -2874,16 +3602,24 @@ func newService(c *onet.Context) (onet.Service, error) {
 		s.CreateGenesisBlock,
 		s.AddTransaction,
 		s.GetProof,
+		s.GetProofs,
+		s.GetStateChecksum,
+		s.Backup,
 		s.CheckAuthorization,
 		s.GetSignerCounters,
 		s.DownloadState,
 		s.GetInstanceVersion,
 		s.GetLastInstanceVersion,
 		s.GetAllInstanceVersion,
+		s.GetInstanceAt,
 		s.CheckStateChangeValidity,
 		s.ResolveInstanceID,
+		s.NegotiateVersion,
 		s.Debug,
-		s.DebugRemove)
+		s.DebugRemove,
+		s.GetEvictionProposals,
+		s.GetBlockChunks,
+		s.GetBlocksSince)
 	if err != nil {
 		return nil, err
 	}
@@ -2892,6 +3628,7 @@ func newService(c *onet.Context) (onet.Service, error) {
 		return nil, xerrors.Errorf("registering handlers: %v", err)
 	}
 	s.RegisterProcessorFunc(viewChangeMsgID, s.handleViewChangeReq)
+	s.RegisterStatusReporter("ByzCoin", s)
 
 	if err := skipchain.RegisterVerification(c, Verify, s.verifySkipBlock); err != nil {
 		log.ErrFatal(err)
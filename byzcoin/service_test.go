@@ -6,6 +6,9 @@ import (
 	"encoding/binary"
 	"encoding/hex"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"testing"
@@ -22,6 +25,7 @@ import (
 	"go.dedis.ch/cothority/v3/darc/expression"
 	"go.dedis.ch/cothority/v3/skipchain"
 	"go.dedis.ch/kyber/v3/sign/eddsa"
+	"go.dedis.ch/kyber/v3/sign/schnorr"
 	"go.dedis.ch/kyber/v3/suites"
 	"go.dedis.ch/kyber/v3/util/random"
 	"go.dedis.ch/onet/v3"
@@ -70,6 +74,119 @@ func TestService_GetAllByzCoinIDs(t *testing.T) {
 	require.Equal(t, 1, len(resp.IDs))
 }
 
+func TestService_GetStateChecksum(t *testing.T) {
+	s := newSerN(t, 1, testInterval, 4, disableViewChange)
+	defer s.local.CloseAll()
+
+	// Make sure all nodes have the block with the dummy instance.
+	for i := range s.services {
+		s.waitProofWithIdx(t, s.tx.Instructions[0].Hash(), i)
+	}
+
+	var digest []byte
+	for _, service := range s.services {
+		resp, err := service.GetStateChecksum(&GetStateChecksum{ByzCoinID: s.genesis.SkipChainID()})
+		require.NoError(t, err)
+		require.Equal(t, 1, resp.Index)
+		require.Equal(t, resp.computeDigest(), resp.Digest)
+		require.NoError(t, schnorr.Verify(cothority.Suite, service.ServerIdentity().Public,
+			resp.Digest, resp.Signature))
+
+		contracts := make(map[string]uint64)
+		for _, cs := range resp.Contracts {
+			contracts[cs.ContractID] = cs.Instances
+		}
+		require.Equal(t, uint64(1), contracts[dummyContract])
+		require.Equal(t, uint64(1), contracts[ContractConfigID])
+
+		if digest == nil {
+			digest = resp.Digest
+		}
+		require.Equal(t, digest, resp.Digest)
+	}
+
+	_, err := s.service().GetStateChecksum(&GetStateChecksum{ByzCoinID: skipchain.SkipBlockID{}})
+	require.Error(t, err)
+}
+
+func TestService_Backup(t *testing.T) {
+	s := newSerN(t, 1, testInterval, 4, disableViewChange)
+	defer s.local.CloseAll()
+	s.waitProofWithIdx(t, s.tx.Instructions[0].Hash(), 0)
+
+	dir, err := ioutil.TempDir("", "backup")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "conode.db")
+
+	ts := time.Now().Unix()
+	priv := s.service().ServerIdentity().GetPrivate()
+	sig, err := schnorr.Sign(cothority.Suite, priv, BackupRequestMessage(path, ts))
+	require.NoError(t, err)
+	resp, err := s.service().Backup(&BackupRequest{Path: path, Timestamp: ts, Signature: sig})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(resp.Chains))
+	require.True(t, resp.Chains[0].ByzCoinID.Equal(s.genesis.SkipChainID()))
+
+	st, err := s.service().getStateTrie(s.genesis.SkipChainID())
+	require.NoError(t, err)
+	require.Equal(t, st.GetIndex(), resp.Chains[0].Index)
+	require.Equal(t, st.GetRoot(), resp.Chains[0].TrieRoot)
+
+	before, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	chains, err := VerifyBackup(path)
+	require.NoError(t, err)
+	require.Equal(t, resp.Chains, chains)
+	after, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, before, after)
+
+	// A value changed in the trie doesn't match the root anymore.
+	db, err := bbolt.Open(path, 0600, nil)
+	require.NoError(t, err)
+	bucket := []byte(fmt.Sprintf("ByzCoin_%x", s.genesis.SkipChainID()))
+	require.NoError(t, db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(bucket)
+		c := b.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			if i := bytes.Index(v, s.value); i >= 0 {
+				v = append([]byte{}, v...)
+				v[i] ^= 1
+				return b.Put(append([]byte{}, k...), v)
+			}
+		}
+		return xerrors.New("value not found")
+	}))
+	require.NoError(t, db.Close())
+	_, err = VerifyBackup(path)
+	require.Error(t, err)
+
+	// A request with a wrong signature must be refused.
+	_, err = s.service().Backup(&BackupRequest{Path: path + ".2", Timestamp: ts, Signature: sig})
+	require.Error(t, err)
+}
+
+func TestService_GetStatus(t *testing.T) {
+	s := newSerN(t, 1, testInterval, 4, disableViewChange)
+	defer s.local.CloseAll()
+
+	prefix := fmt.Sprintf("%x.", []byte(s.genesis.SkipChainID())[:4])
+	status := s.service().GetStatus().Field
+	require.Equal(t, "1", status["Chains"])
+	require.Equal(t, "false", status["CatchingUp"])
+	require.Equal(t, "1", status[prefix+"Stored"])
+	require.Equal(t, "1", status[prefix+"Applied"])
+	require.Equal(t, "0", status[prefix+"Lag"])
+	require.NotEmpty(t, status[prefix+"LastBlock"])
+
+	// Simulate a node that heard of newer blocks.
+	s.service().updateKnownIndex(s.genesis.SkipChainID(), 5)
+	status = s.service().GetStatus().Field
+	require.Equal(t, "5", status[prefix+"Known"])
+	require.Equal(t, "4", status[prefix+"Lag"])
+}
+
 func TestService_CreateGenesisBlock(t *testing.T) {
 	s := newSerN(t, 0, testInterval, 4, disableViewChange)
 	defer s.local.CloseAll()
@@ -379,6 +496,17 @@ func TestService_AddTransaction_ValidInvalid(t *testing.T) {
 	require.NoError(t, err)
 	require.Contains(t, resp.Error, "contract darc tried to create existing instanceID")
 
+	// the reason of the refusal is also stored in the block
+	latest, err := s.service().db().GetLatestByID(s.genesis.SkipChainID())
+	require.NoError(t, err)
+	var body DataBody
+	require.NoError(t, protobuf.Decode(latest.Payload, &body))
+	require.Equal(t, 1, len(body.TxResults))
+	require.False(t, body.TxResults[0].Accepted)
+	require.True(t, strings.HasPrefix(body.TxResults[0].Error,
+		"statechange: contract darc tried to create existing instanceID"))
+	require.NotContains(t, body.TxResults[0].Error, s.service().ServerIdentity().String())
+
 	// add a third tx that holds two valid instructions
 	log.Lvl1("Adding a third, valid tx")
 	instr1 = createInvokeInstr(NewInstanceID(dcID), ContractDarcID, cmdDarcEvolve, "data", dcID)
@@ -1615,6 +1743,105 @@ func TestService_SetConfig(t *testing.T) {
 	require.Equal(t, blocksize, newBlocksize)
 }
 
+func TestService_SetConfigTimeouts(t *testing.T) {
+	s := newSer(t, 1, testInterval)
+	defer s.local.CloseAll()
+
+	config, err := s.service().LoadConfig(s.genesis.SkipChainID())
+	require.NoError(t, err)
+	config.PropagationTimeout = -time.Second
+	require.Error(t, config.sanityCheck(nil))
+	config.PropagationTimeout = 7 * time.Second
+	config.VerificationTimeout = 9 * time.Second
+	require.NoError(t, config.sanityCheck(nil))
+	configBuf, err := protobuf.Encode(config)
+	require.NoError(t, err)
+
+	ctx, err := combineInstrsAndSign(s.signer, Instruction{
+		InstanceID: NewInstanceID(nil),
+		Invoke: &Invoke{
+			ContractID: ContractConfigID,
+			Command:    "update_config",
+			Args:       []Argument{{Name: "config", Value: configBuf}},
+		},
+		SignerIdentities: []darc.Identity{s.signer.Identity()},
+		SignerCounter:    []uint64{1},
+		version:          CurrentVersion,
+	})
+	require.NoError(t, err)
+	s.sendTxAndWait(t, ctx, 10)
+
+	prop, verif := s.service().skService().ChainTimeouts(s.genesis.SkipChainID())
+	require.Equal(t, 7*time.Second, prop)
+	require.Equal(t, 9*time.Second, verif)
+}
+
+func TestService_GetBlocksSince(t *testing.T) {
+	s := newSer(t, 1, testInterval)
+	defer s.local.CloseAll()
+	scid := s.genesis.SkipChainID()
+
+	var mid time.Time
+	for counter := uint64(1); counter <= 3; counter++ {
+		if counter == 2 {
+			mid = time.Now()
+		}
+		tx, err := createOneClientTxWithCounter(s.darc.GetBaseID(), dummyContract, s.value, s.signer, counter)
+		require.NoError(t, err)
+		s.sendTxAndWait(t, tx, 10)
+	}
+	latest, err := s.service().db().GetLatestByID(scid)
+	require.NoError(t, err)
+
+	reply, err := s.service().GetBlocksSince(&GetBlocksSince{SkipChainID: scid})
+	require.NoError(t, err)
+	require.Equal(t, latest.Index+1, len(reply.Blocks))
+	require.False(t, reply.Truncated)
+	require.NotNil(t, reply.Blocks[1].Payload)
+
+	reply, err = s.service().GetBlocksSince(&GetBlocksSince{SkipChainID: scid,
+		Since: mid.UnixNano(), HeadersOnly: true})
+	require.NoError(t, err)
+	require.True(t, len(reply.Blocks) >= 2)
+	require.True(t, reply.Blocks[len(reply.Blocks)-1].Hash.Equal(latest.Hash))
+	for _, sb := range reply.Blocks {
+		require.Nil(t, sb.Payload)
+		header, err := decodeBlockHeader(sb)
+		require.NoError(t, err)
+		require.True(t, header.Timestamp >= mid.UnixNano())
+	}
+
+	reply, err = s.service().GetBlocksSince(&GetBlocksSince{SkipChainID: scid,
+		StartIndex: latest.Index})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(reply.Blocks))
+
+	reply, err = s.service().GetBlocksSince(&GetBlocksSince{SkipChainID: scid,
+		Since: time.Now().Add(time.Hour).UnixNano()})
+	require.NoError(t, err)
+	require.Empty(t, reply.Blocks)
+
+	blocks, err := NewClient(scid, *s.roster).GetBlocksSince(mid, true)
+	require.NoError(t, err)
+	require.True(t, blocks[len(blocks)-1].Hash.Equal(latest.Hash))
+
+	// The blocks are returned page by page.
+	defer func(max int) { maxBlocksSince = max }(maxBlocksSince)
+	maxBlocksSince = 2
+	reply, err = s.service().GetBlocksSince(&GetBlocksSince{SkipChainID: scid})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(reply.Blocks))
+	require.True(t, reply.Truncated)
+	require.Equal(t, 0, reply.Blocks[0].Index)
+	reply, err = s.service().GetBlocksSince(&GetBlocksSince{SkipChainID: scid,
+		StartIndex: 2})
+	require.NoError(t, err)
+	require.Equal(t, 2, reply.Blocks[0].Index)
+	blocks, err = NewClient(scid, *s.roster).GetBlocksSince(time.Unix(0, 0), true)
+	require.NoError(t, err)
+	require.Equal(t, latest.Index+1, len(blocks))
+}
+
 func TestService_SetConfigInterval(t *testing.T) {
 	defer log.SetShowTime(log.ShowTime())
 	log.SetShowTime(true)
@@ -2394,6 +2621,51 @@ func TestService_StateChangeStorage(t *testing.T) {
 	}
 }
 
+func TestService_GetInstanceAt(t *testing.T) {
+	s := newSerN(t, 1, testInterval, 4, disableViewChange)
+	defer s.local.CloseAll()
+
+	pr := s.waitProofWithIdx(t, s.tx.Instructions[0].Hash(), 0)
+	first := pr.Latest.Index
+
+	tx, err := createOneClientTxWithCounter(s.darc.GetBaseID(), dummyContract, s.value, s.signer, 2)
+	require.NoError(t, err)
+	s.sendTxAndWait(t, tx, 10)
+	pr = s.waitProofWithIdx(t, tx.Instructions[0].Hash(), 0)
+	second := pr.Latest.Index
+	require.True(t, second > first)
+
+	counter := NewInstanceID(publicVersionKey(s.signer.Identity().String()))
+	getAt := func(idx int) (*GetInstanceAtResponse, error) {
+		return s.service().GetInstanceAt(&GetInstanceAt{
+			SkipChainID: s.genesis.SkipChainID(),
+			InstanceID:  counter,
+			BlockIndex:  idx,
+		})
+	}
+
+	resp, err := getAt(first)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), resp.StateChange.Version)
+	require.Equal(t, first, resp.Block.Index)
+	require.NoError(t, resp.Verify(s.genesis))
+
+	resp, err = getAt(second)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), resp.StateChange.Version)
+	require.NoError(t, resp.Verify(s.genesis))
+
+	// A tampered value is refused by the verification.
+	resp.StateChange.Value = []byte("tampered")
+	require.Error(t, resp.Verify(s.genesis))
+
+	_, err = getAt(0)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "did not exist")
+	_, err = getAt(second + 100)
+	require.Error(t, err)
+}
+
 // Tests that the state change storage will be caught up by a new conode
 func TestService_StateChangeStorageCatchUp(t *testing.T) {
 	cda := catchupDownloadAll
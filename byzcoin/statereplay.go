@@ -170,7 +170,7 @@ func (s *Service) ReplayStateContLog(id skipchain.SkipBlockID,
 					log.Error("couldn't decode body:", errDecode)
 				} else {
 					for i, tx := range body.TxResults {
-						log.Infof("Transaction %d: %t", i, tx.Accepted)
+						log.Infof("Transaction %d: %t %s", i, tx.Accepted, tx.Error)
 						for j, ct := range tx.ClientTransaction.Instructions {
 							log.Infof("Instruction %d: %s", j, ct)
 						}
@@ -233,7 +233,15 @@ func (t *stateTrie) GetValues(key []byte) (value []byte, version uint64, contrac
 
 // GetIndex gets the latest index.
 func (t *stateTrie) GetIndex() int {
-	indexBuf := t.GetMetadata([]byte(trieIndexKey))
+	return decodeIndex(t.GetMetadata([]byte(trieIndexKey)))
+}
+
+// GetIndexWithBucket is like GetIndex, but in an existing transaction.
+func (t *stateTrie) GetIndexWithBucket(b trie.Bucket) int {
+	return decodeIndex(t.GetMetadataWithBucket([]byte(trieIndexKey), b))
+}
+
+func decodeIndex(indexBuf []byte) int {
 	if indexBuf == nil {
 		return -1
 	}
@@ -645,6 +645,22 @@ func (c ChainConfig) sanityCheck(old *ChainConfig) error {
 	if len(c.Roster.List) < 3 {
 		return xerrors.New("need at least 3 nodes to have a majority")
 	}
+	if c.MaxDarcDepth < 0 {
+		return xerrors.New("max darc depth is negative")
+	}
+	if c.PropagationTimeout < 0 || c.VerificationTimeout < 0 {
+		return xerrors.New("timeouts cannot be negative")
+	}
+	seen := make(map[string]bool)
+	for _, q := range c.Quotas {
+		if err := q.sanityCheck(); err != nil {
+			return xerrors.Errorf("quota: %v", err)
+		}
+		if seen[q.String()] {
+			return xerrors.Errorf("duplicate quota for %s", q)
+		}
+		seen[q.String()] = true
+	}
 	if old != nil {
 		return cothority.ErrorOrNil(old.checkNewRoster(c.Roster), "roster check: %v")
 	}
@@ -701,5 +717,21 @@ func (c ChainConfig) String() string {
 	for i, darcID := range c.DarcContractIDs {
 		fmt.Fprintf(res, "--- darc contract ID %d: %s\n", i, darcID)
 	}
+	if c.MaxDarcDepth > 0 {
+		fmt.Fprintf(res, "-- MaxDarcDepth: %d\n", c.MaxDarcDepth)
+	}
+	if c.PropagationTimeout > 0 {
+		fmt.Fprintf(res, "-- PropagationTimeout: %s\n", c.PropagationTimeout)
+	}
+	if c.VerificationTimeout > 0 {
+		fmt.Fprintf(res, "-- VerificationTimeout: %s\n", c.VerificationTimeout)
+	}
+	if len(c.Quotas) > 0 {
+		res.WriteString("-- Quotas:\n")
+		for _, q := range c.Quotas {
+			fmt.Fprintf(res, "--- %s: %d bytes, %d instances\n", q,
+				q.MaxBytes, q.MaxInstances)
+		}
+	}
 	return res.String()
 }
@@ -319,6 +319,68 @@ func (instr *Instruction) SignWith(msg []byte, signers ...darc.Signer) error {
 	return nil
 }
 
+// AggregateBLSSignatures replaces the signatures of the BLS signers of the
+// instruction by their aggregate, to make the instruction smaller. It must be
+// called after the instruction has been signed. The aggregate is stored as
+// the signature of the first BLS signer, and the signatures of the other BLS
+// signers are left empty.
+func (instr *Instruction) AggregateBLSSignatures() error {
+	first := -1
+	var sigs [][]byte
+	for i, id := range instr.SignerIdentities {
+		if id.BLS == nil || i >= len(instr.Signatures) {
+			continue
+		}
+		if first < 0 {
+			first = i
+		}
+		sigs = append(sigs, instr.Signatures[i])
+	}
+	if len(sigs) < 2 {
+		return nil
+	}
+	agg, err := darc.AggregateBLSSignatures(sigs...)
+	if err != nil {
+		return xerrors.Errorf("aggregating signatures: %v", err)
+	}
+	for i, id := range instr.SignerIdentities {
+		if id.BLS != nil && i < len(instr.Signatures) {
+			instr.Signatures[i] = []byte{}
+		}
+	}
+	instr.Signatures[first] = agg
+	return nil
+}
+
+// blsAggregate returns the BLS identities of the instruction and their
+// aggregate signature, if the signatures have been aggregated with
+// AggregateBLSSignatures. Otherwise it returns nil. If more than one BLS
+// signature is set, the returned signature is nil, so that the verification
+// fails.
+func (instr Instruction) blsAggregate() ([]darc.Identity, []byte) {
+	var ids []darc.Identity
+	var sigs [][]byte
+	aggregated := false
+	for i, id := range instr.SignerIdentities {
+		if id.BLS == nil || i >= len(instr.Signatures) {
+			continue
+		}
+		ids = append(ids, id)
+		if len(instr.Signatures[i]) == 0 {
+			aggregated = true
+		} else {
+			sigs = append(sigs, instr.Signatures[i])
+		}
+	}
+	if !aggregated {
+		return nil, nil
+	}
+	if len(sigs) != 1 {
+		return ids, nil
+	}
+	return ids, sigs[0]
+}
+
 // GetIdentityStrings gets a slice of identities who are signing the
 // instruction.
 func (instr Instruction) GetIdentityStrings() []string {
@@ -343,6 +405,20 @@ func (instr Instruction) Verify(st ReadOnlyStateTrie, msg []byte) error {
 	return instr.VerifyWithOption(st, msg, nil)
 }
 
+// chainIDOf returns the ID of the skipchain of the trie, or nil if the trie
+// is not part of a GlobalState.
+func chainIDOf(st ReadOnlyStateTrie) []byte {
+	sc, ok := st.(ReadOnlySkipChain)
+	if !ok {
+		return nil
+	}
+	genesis, err := sc.GetGenesisBlock()
+	if err != nil {
+		return nil
+	}
+	return genesis.Hash
+}
+
 // VerifyWithOption adds the ability to the Verify(...) method to specify if
 // the counters should be checked. This is used with the "defered" contract
 // where the clients sign the root instruction without the counters.
@@ -357,6 +433,14 @@ func (instr Instruction) VerifyWithOption(st ReadOnlyStateTrie, msg []byte, ops
 			" signatures")
 	}
 
+	// The keys of a DID are resolved outside of the ledger, so the nodes
+	// could disagree on them.
+	for _, id := range instr.SignerIdentities {
+		if id.DID != nil {
+			return xerrors.New("DID identities can only sign off-chain requests")
+		}
+	}
+
 	// check the signature counters
 	if !ops.IgnoreCounters {
 		if err := verifySignerCounters(st, instr.SignerCounter, instr.SignerIdentities); err != nil {
@@ -387,7 +471,37 @@ func (instr Instruction) VerifyWithOption(st ReadOnlyStateTrie, msg []byte, ops
 	// check the signature
 	// Save the identities that provide good signatures
 	identitiesWithCorrectSignatures := make([]string, 0)
+	blsIDs, blsSig := instr.blsAggregate()
+	if blsIDs != nil {
+		if err := darc.VerifyBLSAggregate(msg, blsIDs, blsSig); err == nil {
+			for _, id := range blsIDs {
+				identitiesWithCorrectSignatures = append(identitiesWithCorrectSignatures, id.String())
+			}
+		}
+	}
+	batched := instr.verified != nil && bytes.Equal(instr.verified.msg, msg) &&
+		len(instr.verified.valid) == len(instr.Signatures)
 	for i := range instr.Signatures {
+		if blsIDs != nil && instr.SignerIdentities[i].BLS != nil {
+			continue
+		}
+		// A delegation token is valid until the block with the index of
+		// its expiry, and the instruction goes in the next block.
+		if token := instr.SignerIdentities[i].Token; token != nil {
+			err := token.VerifyFor(chainIDOf(st), instr.InstanceID.Slice(),
+				darc.Action(instr.Action()), uint64(st.GetIndex()+1),
+				msg, instr.Signatures[i])
+			if err == nil {
+				identitiesWithCorrectSignatures = append(identitiesWithCorrectSignatures, instr.SignerIdentities[i].String())
+			}
+			continue
+		}
+		if batched {
+			if instr.verified.valid[i] {
+				identitiesWithCorrectSignatures = append(identitiesWithCorrectSignatures, instr.SignerIdentities[i].String())
+			}
+			continue
+		}
 		if err := instr.SignerIdentities[i].Verify(msg, instr.Signatures[i]); err == nil {
 			identitiesWithCorrectSignatures = append(identitiesWithCorrectSignatures, instr.SignerIdentities[i].String())
 		}
@@ -414,11 +528,8 @@ func (instr Instruction) VerifyWithOption(st ReadOnlyStateTrie, msg []byte, ops
 		return d
 	}
 
-	if ops.EvalAttr != nil {
-		err := darc.EvalExprAttr(d.Rules.Get(darc.Action(instr.Action())), getDarc, ops.EvalAttr, identitiesWithCorrectSignatures...)
-		return cothority.ErrorOrNil(err, "evaluating darc")
-	}
-	err = darc.EvalExpr(d.Rules.Get(darc.Action(instr.Action())), getDarc, identitiesWithCorrectSignatures...)
+	err = d.EvalRule(darc.Action(instr.Action()), getDarc, ops.EvalAttr,
+		config.MaxDarcDepth, identitiesWithCorrectSignatures...)
 	return cothority.ErrorOrNil(err, "evaluating darc")
 }
 
@@ -508,15 +619,75 @@ func (txr TxResults) Hash() []byte {
 		} else {
 			h.Write(zero[:])
 		}
+		if tx.version >= VersionRejectionReason {
+			lenBuf := make([]byte, 8)
+			binary.LittleEndian.PutUint64(lenBuf, uint64(len(tx.Error)))
+			h.Write(lenBuf)
+			h.Write([]byte(tx.Error))
+		}
 	}
 	return h.Sum(nil)
 }
 
+// BatchVerifySignatures verifies the signatures of all the instructions
+// with a darc.BatchVerifier, and stores the results in the instructions, so
+// that VerifyWithOption doesn't verify them one by one. The instructions
+// with an aggregated BLS signature or a DID signer are left out, and the
+// results are only valid as long as the transactions are not changed.
+func (txr TxResults) BatchVerifySignatures() {
+	batchable := func(instr Instruction) bool {
+		for _, id := range instr.SignerIdentities {
+			if id.DID != nil {
+				return false
+			}
+		}
+		ids, _ := instr.blsAggregate()
+		return ids == nil && len(instr.SignerIdentities) == len(instr.Signatures)
+	}
+
+	var bv darc.BatchVerifier
+	for _, tx := range txr {
+		msg := tx.ClientTransaction.Instructions.Hash()
+		for _, instr := range tx.ClientTransaction.Instructions {
+			if !batchable(instr) {
+				continue
+			}
+			for i, id := range instr.SignerIdentities {
+				bv.Add(id, msg, instr.Signatures[i])
+			}
+		}
+	}
+
+	valid := bv.Verify()
+	for _, tx := range txr {
+		msg := tx.ClientTransaction.Instructions.Hash()
+		for i, instr := range tx.ClientTransaction.Instructions {
+			if !batchable(instr) {
+				continue
+			}
+			n := len(instr.Signatures)
+			tx.ClientTransaction.Instructions[i].verified = &batchResult{
+				msg:   msg,
+				valid: valid[:n:n],
+			}
+			valid = valid[n:]
+		}
+	}
+}
+
+// batchResult is the result of BatchVerifySignatures for the signatures of
+// one instruction on msg.
+type batchResult struct {
+	msg   []byte
+	valid []bool
+}
+
 // SetVersion makes sure the underlying data will use the implementation
 // of the given version.
 func (txr TxResults) SetVersion(version Version) {
-	for _, tx := range txr {
-		tx.ClientTransaction.Instructions.SetVersion(version)
+	for i := range txr {
+		txr[i].version = version
+		txr[i].ClientTransaction.Instructions.SetVersion(version)
 	}
 }
 
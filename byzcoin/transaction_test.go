@@ -2,12 +2,17 @@ package byzcoin
 
 import (
 	"encoding/binary"
+	"strings"
 	"testing"
+	"unicode/utf8"
 
 	"github.com/stretchr/testify/require"
 	"go.dedis.ch/cothority/v3/byzcoin/trie"
 	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/cothority/v3/darc/expression"
+	"go.dedis.ch/cothority/v3/skipchain"
 	"go.dedis.ch/protobuf"
+	"golang.org/x/xerrors"
 )
 
 func TestTransaction_Signing(t *testing.T) {
@@ -75,6 +80,240 @@ func TestTransaction_Signing(t *testing.T) {
 	require.NoError(t, ctx.Instructions[0].Verify(sst, ctxHash))
 }
 
+// TestTransaction_BLSAggregate verifies an instruction where the signatures
+// of the BLS signers are aggregated.
+func TestTransaction_BLSAggregate(t *testing.T) {
+	signers := []darc.Signer{
+		darc.NewSignerBLS(nil, nil),
+		darc.NewSignerEd25519(nil, nil),
+		darc.NewSignerBLS(nil, nil),
+		darc.NewSignerBLS(nil, nil),
+	}
+	var ids []string
+	for _, s := range signers {
+		ids = append(ids, s.Identity().String())
+	}
+	owner := []darc.Identity{signers[1].Identity()}
+	d := darc.NewDarc(darc.InitRules(owner, owner), []byte("bls darc"))
+	require.NoError(t, d.Rules.AddRule("spawn:dummy_kind", expression.InitAndExpr(ids...)))
+
+	sst := newDarcTestTrie(t, d)
+	instr := createSpawnInstr(d.GetBaseID(), "dummy_kind", "data", []byte("value"))
+	instr.SignerCounter = []uint64{1, 1, 1, 1}
+	ctx := NewClientTransaction(CurrentVersion, instr)
+	require.NoError(t, ctx.FillSignersAndSignWith(signers...))
+	ctxHash := ctx.Instructions.Hash()
+	require.NoError(t, ctx.Instructions[0].Verify(sst, ctxHash))
+
+	require.NoError(t, ctx.Instructions[0].AggregateBLSSignatures())
+	sigs := ctx.Instructions[0].Signatures
+	require.NotEmpty(t, sigs[0])
+	require.NotEmpty(t, sigs[1])
+	require.Empty(t, sigs[2])
+	require.Empty(t, sigs[3])
+	require.NoError(t, ctx.Instructions[0].Verify(sst, ctxHash))
+
+	// Without one of the BLS signers, the aggregate is not valid anymore.
+	wrong := ctx.Instructions[0]
+	wrong.SignerIdentities = append([]darc.Identity{}, wrong.SignerIdentities...)
+	wrong.SignerIdentities[3] = darc.NewSignerBLS(nil, nil).Identity()
+	require.Error(t, wrong.Verify(sst, ctxHash))
+}
+
+// TestTxResults_BatchVerifySignatures checks that the instructions are
+// verified with the results of the batch verification.
+func TestTxResults_BatchVerifySignatures(t *testing.T) {
+	signers := []darc.Signer{darc.NewSignerEd25519(nil, nil), darc.NewSignerEd25519(nil, nil)}
+	d := darc.NewDarc(darc.InitRules([]darc.Identity{signers[0].Identity()}, nil),
+		[]byte("batch darc"))
+	require.NoError(t, d.Rules.AddRule("spawn:dummy_kind",
+		expression.InitOrExpr(signers[0].Identity().String(), signers[1].Identity().String())))
+	sst := newDarcTestTrie(t, d)
+
+	var txs TxResults
+	for i, s := range signers {
+		instr := createSpawnInstr(d.GetBaseID(), "dummy_kind", "data", []byte{byte(i)})
+		ctx := NewClientTransaction(CurrentVersion, instr)
+		require.NoError(t, ctx.FillSignersAndSignWith(s))
+		txs = append(txs, TxResult{ClientTransaction: ctx})
+	}
+	// The signature of the second instruction is on another transaction.
+	txs[1].ClientTransaction.Instructions[0].Signatures = txs[0].ClientTransaction.Instructions[0].Signatures
+
+	txs.BatchVerifySignatures()
+	for i, tx := range txs {
+		instr := tx.ClientTransaction.Instructions[0]
+		require.NotNil(t, instr.verified)
+		require.Equal(t, []bool{i == 0}, instr.verified.valid)
+	}
+	hash := txs[0].ClientTransaction.Instructions.Hash()
+	require.NoError(t, txs[0].ClientTransaction.Instructions[0].Verify(sst, hash))
+	hash = txs[1].ClientTransaction.Instructions.Hash()
+	require.Error(t, txs[1].ClientTransaction.Instructions[0].Verify(sst, hash))
+
+	// The results are only used for the message they have been computed
+	// for.
+	hash = txs[0].ClientTransaction.Instructions.Hash()
+	instr := txs[0].ClientTransaction.Instructions[0]
+	instr.verified = &batchResult{msg: []byte("other message"), valid: []bool{false}}
+	require.NoError(t, instr.Verify(sst, hash))
+	instr.verified.msg = hash
+	require.Error(t, instr.Verify(sst, hash))
+}
+
+// TestTxResults_HashRejectionReason checks that the reason of a refused
+// transaction is only covered by the hash since VersionRejectionReason.
+func TestTxResults_HashRejectionReason(t *testing.T) {
+	instr := createSpawnInstr(darc.ID(make([]byte, 32)), "dummy_kind", "data", []byte{1})
+	txs := TxResults{{ClientTransaction: NewClientTransaction(CurrentVersion, instr)}}
+
+	txs.SetVersion(VersionRejectionReason - 1)
+	hash := txs.Hash()
+	txs[0].Error = "contract: refused"
+	require.Equal(t, hash, txs.Hash())
+
+	txs.SetVersion(VersionRejectionReason)
+	hash = txs.Hash()
+	txs[0].Error = "contract: other reason"
+	require.NotEqual(t, hash, txs.Hash())
+
+	// Long reasons are cut without splitting a character.
+	reason := truncateTxError(strings.Repeat("é", maxTxErrorLength))
+	require.True(t, utf8.ValidString(reason))
+	require.Equal(t, maxTxErrorLength, len(reason))
+	reason = truncateTxError("a" + strings.Repeat("é", maxTxErrorLength))
+	require.True(t, utf8.ValidString(reason))
+	require.Equal(t, maxTxErrorLength-1, len(reason))
+}
+
+// TestTransaction_DelegationToken checks that a delegation token can sign
+// for the issuer, but only for its actions and before its expiry.
+func TestTransaction_DelegationToken(t *testing.T) {
+	issuer := darc.NewSignerEd25519(nil, nil)
+	delegate := darc.NewSignerEd25519(nil, nil)
+	d := darc.NewDarc(darc.InitRules([]darc.Identity{issuer.Identity()}, nil),
+		[]byte("token darc"))
+	for _, r := range []string{"spawn:dummy_kind", "spawn:other_kind"} {
+		require.NoError(t, d.Rules.AddRule(darc.Action(r), []byte(issuer.Identity().String())))
+	}
+
+	st, err := newMemStateTrie([]byte("my nonce"))
+	require.NoError(t, err)
+	require.NoError(t, st.StoreAll(darcTestStateChanges(t, d), 9, CurrentVersion))
+
+	chainID := []byte("chain")
+	gs := globalState{st, genesisTest{chainID}}
+
+	sign := func(kind string, expiry uint64, chainID, target []byte) Instruction {
+		token, err := darc.NewIdentityToken(issuer, delegate.Identity(), chainID,
+			target, []darc.Action{"spawn:dummy_kind"}, expiry)
+		require.NoError(t, err)
+		signer, err := darc.NewSignerToken(token, delegate)
+		require.NoError(t, err)
+		ctx := NewClientTransaction(CurrentVersion,
+			createSpawnInstr(d.GetBaseID(), kind, "data", []byte("value")))
+		require.NoError(t, ctx.FillSignersAndSignWith(signer))
+		return ctx.Instructions[0]
+	}
+	verify := func(instr Instruction) error {
+		return instr.Verify(gs, Instructions{instr}.Hash())
+	}
+
+	// The instruction goes into block 10.
+	require.NoError(t, verify(sign("dummy_kind", 11, chainID, nil)))
+	require.Error(t, verify(sign("dummy_kind", 10, chainID, nil)))
+	require.Error(t, verify(sign("other_kind", 11, chainID, nil)))
+
+	// The token is bound to the chain, and to the target if it has one.
+	require.Error(t, verify(sign("dummy_kind", 11, []byte("other chain"), nil)))
+	instr := sign("dummy_kind", 11, chainID, nil)
+	require.Error(t, instr.Verify(st, Instructions{instr}.Hash()))
+	require.NoError(t, verify(sign("dummy_kind", 11, chainID,
+		NewInstanceID(d.GetBaseID()).Slice())))
+	require.Error(t, verify(sign("dummy_kind", 11, chainID, []byte("other"))))
+}
+
+// genesisTest is a ReadOnlySkipChain that only knows the hash of the genesis
+// block.
+type genesisTest struct {
+	hash skipchain.SkipBlockID
+}
+
+func (g genesisTest) GetLatest() (*skipchain.SkipBlock, error) {
+	return nil, xerrors.New("not implemented")
+}
+
+func (g genesisTest) GetGenesisBlock() (*skipchain.SkipBlock, error) {
+	return &skipchain.SkipBlock{SkipBlockFix: &skipchain.SkipBlockFix{}, Hash: g.hash}, nil
+}
+
+func (g genesisTest) GetBlock(skipchain.SkipBlockID) (*skipchain.SkipBlock, error) {
+	return nil, xerrors.New("not implemented")
+}
+
+func (g genesisTest) GetBlockByIndex(int) (*skipchain.SkipBlock, error) {
+	return nil, xerrors.New("not implemented")
+}
+
+// newDarcTestTrie returns a trie holding a configuration and the darc.
+func newDarcTestTrie(t *testing.T, d *darc.Darc) *stagingStateTrie {
+	mdb := trie.NewMemDB()
+	tr, err := trie.NewTrie(mdb, []byte("my nonce"))
+	require.NoError(t, err)
+	sst := &stagingStateTrie{*tr.MakeStagingTrie()}
+	require.NoError(t, sst.StoreAll(darcTestStateChanges(t, d)))
+	return sst
+}
+
+// darcTestStateChanges returns the state changes creating a configuration
+// and the darc.
+func darcTestStateChanges(t *testing.T, d *darc.Darc) StateChanges {
+	configBuf, err := protobuf.Encode(&ChainConfig{DarcContractIDs: []string{"darc"}})
+	require.NoError(t, err)
+	darcBuf, err := d.ToProto()
+	require.NoError(t, err)
+	return StateChanges{
+		{
+			InstanceID:  NewInstanceID(nil).Slice(),
+			StateAction: Create,
+			ContractID:  ContractConfigID,
+			Value:       configBuf,
+		},
+		{
+			InstanceID:  d.GetBaseID(),
+			StateAction: Create,
+			ContractID:  ContractDarcID,
+			Value:       darcBuf,
+			DarcID:      d.GetBaseID(),
+		},
+	}
+}
+
+// TestTransaction_DID checks that a DID identity, whose keys are resolved
+// outside of the ledger, can't sign an instruction.
+func TestTransaction_DID(t *testing.T) {
+	key := darc.NewSignerEd25519(nil, nil)
+	darc.RegisterDIDResolver("example", darc.DIDResolverFunc(func(string) ([]darc.Identity, error) {
+		return []darc.Identity{key.Identity()}, nil
+	}))
+	defer darc.RegisterDIDResolver("example", nil)
+	signer := darc.NewSignerDID("did:example:123", key)
+	d := darc.NewDarc(darc.InitRules([]darc.Identity{signer.Identity()}, nil),
+		[]byte("did darc"))
+	require.NoError(t, d.Rules.AddRule("spawn:dummy_kind", []byte(signer.Identity().String())))
+
+	st, err := newMemStateTrie([]byte("my nonce"))
+	require.NoError(t, err)
+	require.NoError(t, st.StoreAll(darcTestStateChanges(t, d), 9, CurrentVersion))
+
+	ctx := NewClientTransaction(CurrentVersion,
+		createSpawnInstr(d.GetBaseID(), "dummy_kind", "data", []byte("value")))
+	require.NoError(t, ctx.FillSignersAndSignWith(signer))
+	err = ctx.Instructions[0].Verify(st, ctx.Instructions.Hash())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "DID identities")
+}
+
 func TestTransactionBuffer_Add(t *testing.T) {
 	b := newTxBuffer()
 	key := "abc"
@@ -571,16 +571,21 @@ func (t *Trie) del(depth int, nodeKey []byte, bits []bool, key []byte, b Bucket)
 // iteration stops and the function returns an error when the callback returns
 // an error.
 func (t *Trie) ForEach(cb func(k, v []byte) error) error {
-	p := leafCallbackProcessor{cb}
 	return t.db.View(func(b Bucket) error {
-		rootKey := t.GetRootWithBucket(b)
-		if rootKey == nil {
-			return xerrors.New("no root key")
-		}
-		return t.dfs(&p, rootKey, b)
+		return t.ForEachWithBucket(cb, b)
 	})
 }
 
+// ForEachWithBucket is like ForEach, but in an existing transaction.
+func (t *Trie) ForEachWithBucket(cb func(k, v []byte) error, b Bucket) error {
+	p := leafCallbackProcessor{cb}
+	rootKey := t.GetRootWithBucket(b)
+	if rootKey == nil {
+		return xerrors.New("no root key")
+	}
+	return t.dfs(&p, rootKey, b)
+}
+
 // IsValid checks whether the trie is valid.
 func (t *Trie) IsValid() error {
 	p := countNodeProcessor{}
@@ -631,6 +636,58 @@ func (t *Trie) IsValid() error {
 	return nil
 }
 
+// VerifyContents checks that every node of the trie is stored under its hash,
+// so that all the key/value pairs of the trie match its root.
+func (t *Trie) VerifyContents() error {
+	return t.db.View(func(b Bucket) error {
+		rootKey := t.GetRootWithBucket(b)
+		if rootKey == nil {
+			return xerrors.New("no root key")
+		}
+		return t.verifyNode(rootKey, b)
+	})
+}
+
+func (t *Trie) verifyNode(nodeKey []byte, b Bucket) error {
+	nodeVal := b.Get(nodeKey)
+	if len(nodeVal) == 0 {
+		return xerrors.Errorf("missing node %x", nodeKey)
+	}
+	var hash []byte
+	switch nodeType(nodeVal[0]) {
+	case typeEmpty:
+		node, err := decodeEmptyNode(nodeVal)
+		if err != nil {
+			return err
+		}
+		hash = node.hash(t.nonce)
+	case typeLeaf:
+		node, err := decodeLeafNode(nodeVal)
+		if err != nil {
+			return err
+		}
+		hash = node.hash(t.nonce)
+	case typeInterior:
+		node, err := decodeInteriorNode(nodeVal)
+		if err != nil {
+			return err
+		}
+		hash = node.hash()
+		if err := t.verifyNode(node.Left, b); err != nil {
+			return err
+		}
+		if err := t.verifyNode(node.Right, b); err != nil {
+			return err
+		}
+	default:
+		return xerrors.New("invalid node type")
+	}
+	if !bytes.Equal(hash, nodeKey) {
+		return xerrors.Errorf("node %x is stored under a wrong key", hash)
+	}
+	return nil
+}
+
 // getRaw gets the value, it returns nil if the value does not exist.
 func (t *Trie) getRaw(key []byte) ([]byte, error) {
 	var val []byte
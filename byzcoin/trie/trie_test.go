@@ -298,6 +298,7 @@ func TestIsValid(t *testing.T) {
 
 	require.NoError(t, testTrie.Set([]byte{0xff}, []byte{0xff}))
 	require.NoError(t, testTrie.Set([]byte{0xdf}, []byte{0xdf}))
+	require.NoError(t, testTrie.VerifyContents())
 
 	p, err := testTrie.GetProof([]byte{0xff})
 	require.NoError(t, err)
@@ -328,6 +329,7 @@ func TestIsValid(t *testing.T) {
 	})
 	require.NoError(t, err)
 	require.NotNil(t, testTrie.IsValid())
+	require.Error(t, testTrie.VerifyContents())
 }
 
 func TestQuickCheck(t *testing.T) {
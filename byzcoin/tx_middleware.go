@@ -0,0 +1,120 @@
+package byzcoin
+
+import (
+	"sync"
+
+	"go.dedis.ch/cothority/v3/skipchain"
+	"golang.org/x/xerrors"
+)
+
+// TxValidator can be registered by the operator of a conode to inspect the
+// client transactions sent to this conode, before they are added to the
+// transaction buffer and reach the contracts. It can be used, e.g., for
+// compliance filters or to require additional signatures on some contracts.
+//
+// The validators are only run by the conode that receives the transaction
+// from the client. Transactions proposed by other nodes are not checked, so
+// a validator must not be used to enforce rules on the content of the chain.
+type TxValidator interface {
+	// ValidateTx returns an error if the transaction must be refused. The
+	// state trie is the latest state of the chain known to this conode.
+	ValidateTx(rst ReadOnlyStateTrie, scID skipchain.SkipBlockID, tx ClientTransaction) error
+}
+
+// TxValidatorFunc allows to use an ordinary function as a TxValidator.
+type TxValidatorFunc func(rst ReadOnlyStateTrie, scID skipchain.SkipBlockID, tx ClientTransaction) error
+
+// ValidateTx implements TxValidator.
+func (f TxValidatorFunc) ValidateTx(rst ReadOnlyStateTrie, scID skipchain.SkipBlockID, tx ClientTransaction) error {
+	return f(rst, scID, tx)
+}
+
+// ContractTxValidator returns a validator that only calls v for the
+// transactions having at least one instruction for the given contract. An
+// instruction is for the contract if it spawns a new instance of it, or if
+// its instance is stored with this contract, regardless of the contract ID
+// given by the client.
+func ContractTxValidator(contractID string, v TxValidator) TxValidator {
+	return TxValidatorFunc(func(rst ReadOnlyStateTrie, scID skipchain.SkipBlockID, tx ClientTransaction) error {
+		for _, instr := range tx.Instructions {
+			if instr.ContractID() == contractID {
+				return v.ValidateTx(rst, scID, tx)
+			}
+			_, _, cid, _, err := rst.GetValues(instr.InstanceID.Slice())
+			if err == nil && cid == contractID {
+				return v.ValidateTx(rst, scID, tx)
+			}
+		}
+		return nil
+	})
+}
+
+type namedTxValidator struct {
+	name      string
+	validator TxValidator
+}
+
+// txValidators holds the validators in the order of their registration.
+type txValidators struct {
+	sync.Mutex
+	list []namedTxValidator
+}
+
+func (tv *txValidators) register(name string, v TxValidator) error {
+	tv.Lock()
+	defer tv.Unlock()
+	for _, nv := range tv.list {
+		if nv.name == name {
+			return xerrors.Errorf("validator %s already registered", name)
+		}
+	}
+	tv.list = append(tv.list, namedTxValidator{name: name, validator: v})
+	return nil
+}
+
+func (tv *txValidators) unregister(name string) bool {
+	tv.Lock()
+	defer tv.Unlock()
+	for i, nv := range tv.list {
+		if nv.name == name {
+			tv.list = append(tv.list[:i], tv.list[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// validate runs all the validators and returns the error of the first one
+// refusing the transaction.
+func (tv *txValidators) validate(rst ReadOnlyStateTrie, scID skipchain.SkipBlockID, tx ClientTransaction) error {
+	tv.Lock()
+	list := append([]namedTxValidator{}, tv.list...)
+	tv.Unlock()
+	for _, nv := range list {
+		if err := nv.validator.ValidateTx(rst, scID, tx); err != nil {
+			return xerrors.Errorf("refused by %s: %v", nv.name, err)
+		}
+	}
+	return nil
+}
+
+// RegisterTxValidator adds a validator to the byzcoin service of the conode.
+// The validators are called in the order of their registration, and the
+// name must be unique.
+func RegisterTxValidator(s skipchain.GetService, name string, v TxValidator) error {
+	scs := s.Service(ServiceName)
+	if scs == nil {
+		return xerrors.New("Didn't find our service: " + ServiceName)
+	}
+	return scs.(*Service).txValidators.register(name, v)
+}
+
+// UnregisterTxValidator removes the validator with the given name. It
+// returns false if no such validator has been registered.
+func UnregisterTxValidator(s skipchain.GetService, name string) bool {
+	scs := s.Service(ServiceName)
+	if scs == nil {
+		return false
+	}
+	return scs.(*Service).txValidators.unregister(name)
+}
@@ -0,0 +1,49 @@
+package byzcoin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/skipchain"
+	"golang.org/x/xerrors"
+)
+
+func TestService_TxValidator(t *testing.T) {
+	s := newSerN(t, 1, testInterval, 4, disableViewChange)
+	defer s.local.CloseAll()
+	s.waitProofWithIdx(t, s.tx.Instructions[0].Hash(), 0)
+
+	called := 0
+	refuse := TxValidatorFunc(func(rst ReadOnlyStateTrie, scID skipchain.SkipBlockID, tx ClientTransaction) error {
+		called++
+		return xerrors.New("compliance")
+	})
+	require.NoError(t, RegisterTxValidator(s.hosts[0], "compliance",
+		ContractTxValidator(dummyContract, refuse)))
+	require.Error(t, RegisterTxValidator(s.hosts[0], "compliance", refuse))
+
+	tx, err := createOneClientTxWithCounter(s.darc.GetBaseID(), dummyContract, s.value, s.signer, 2)
+	require.NoError(t, err)
+	_, err = s.service().AddTransaction(&AddTxRequest{
+		Version:     CurrentVersion,
+		SkipchainID: s.genesis.SkipChainID(),
+		Transaction: tx,
+	})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "refused by compliance")
+	require.Equal(t, 1, called)
+
+	// Only the node where the validator has been registered refuses the
+	// transaction.
+	s.sendTxTo(t, tx, 1)
+	s.waitProofWithIdx(t, tx.Instructions[0].Hash(), 1)
+
+	// Instructions for other contracts are not checked.
+	tx, err = createOneClientTxWithCounter(s.darc.GetBaseID(), slowContract, s.value, s.signer, 3)
+	require.NoError(t, err)
+	s.sendTx(t, tx)
+	require.Equal(t, 1, called)
+
+	require.True(t, UnregisterTxValidator(s.hosts[0], "compliance"))
+	require.False(t, UnregisterTxValidator(s.hosts[0], "compliance"))
+}
@@ -123,7 +123,19 @@ func (c *CoSi) CreateCommitment(s cipher.Stream) kyber.Point {
 func (c *CoSi) Commit(s cipher.Stream, subComms []kyber.Point) kyber.Point {
 	// generate our own commit
 	c.genCommit(s)
+	return c.aggregate(subComms)
+}
+
+// CommitWith is like Commit, but with a secret and its commitment computed
+// beforehand. They must never be used for another round.
+func (c *CoSi) CommitWith(secret kyber.Scalar, commitment kyber.Point, subComms []kyber.Point) kyber.Point {
+	c.random = secret
+	c.commitment = commitment
+	return c.aggregate(subComms)
+}
 
+// aggregate adds the commitments of the children to our own.
+func (c *CoSi) aggregate(subComms []kyber.Point) kyber.Point {
 	// add our own commitment to the aggregate commitment
 	c.aggregateCommitment = c.suite.Point().Add(c.suite.Point().Null(), c.commitment)
 	// take the children commitments
@@ -232,8 +244,25 @@ func (c *CoSi) VerifyResponses(aggregatedPublic kyber.Point) error {
 // signature will take care of removing the indivual public keys that did not
 // participate
 func VerifySignature(suite kyber.Group, publics []kyber.Point, message, sig []byte) error {
+	return verifySignature(suite, publics, message, sig, nil)
+}
+
+// VerifySignatureWithPolicy verifies the signature like VerifySignature, and
+// also checks that the nodes that signed fulfill the policy. A nil policy
+// requires all the nodes to have signed.
+func VerifySignatureWithPolicy(suite kyber.Group, publics []kyber.Point, message, sig []byte, policy Policy) error {
+	if policy == nil {
+		policy = CompletePolicy{}
+	}
+	return verifySignature(suite, publics, message, sig, policy)
+}
+
+func verifySignature(suite kyber.Group, publics []kyber.Point, message, sig []byte, policy Policy) error {
 	lenC := suite.PointLen()
 	lenSig := lenC + suite.ScalarLen()
+	if len(sig) < lenSig {
+		return errors.New("signature is too short")
+	}
 	aggCommitBuff := sig[:lenC]
 	aggCommit := suite.Point()
 	if err := aggCommit.UnmarshalBinary(aggCommitBuff); err != nil {
@@ -244,6 +273,9 @@ func VerifySignature(suite kyber.Group, publics []kyber.Point, message, sig []by
 	maskBuff := sig[lenSig:]
 	mask := newMask(suite, publics)
 	mask.SetMask(maskBuff)
+	if policy != nil && !policy.Check(mask.participants()) {
+		return errors.New("the policy is not fulfilled")
+	}
 	aggPublic := mask.Aggregate()
 	aggPublicMarshal, err := aggPublic.MarshalBinary()
 	if err != nil {
@@ -272,12 +304,65 @@ func VerifySignature(suite kyber.Group, publics []kyber.Point, message, sig []by
 	return nil
 }
 
+// SignatureChallenge returns the challenge of the signature of the message,
+// which is the one the signers responded to. The signature is not checked.
+func SignatureChallenge(suite kyber.Group, publics []kyber.Point, message, sig []byte) (kyber.Scalar, error) {
+	lenC := suite.PointLen()
+	lenSig := lenC + suite.ScalarLen()
+	if len(sig) < lenSig {
+		return nil, errors.New("signature is too short")
+	}
+	mask := newMask(suite, publics)
+	if err := mask.SetMask(sig[lenSig:]); err != nil {
+		return nil, err
+	}
+	hash := sha512.New()
+	hash.Write(sig[:lenC])
+	if _, err := mask.Aggregate().MarshalTo(hash); err != nil {
+		return nil, err
+	}
+	hash.Write(message)
+	return suite.Scalar().SetBytes(hash.Sum(nil)), nil
+}
+
+// EdDSASignature returns the signature in the Ed25519 format R || s, with the
+// aggregate public key of the signers that verifies it. As the challenge of a
+// CoSi signature is the one of Ed25519, any Ed25519 implementation can verify
+// it. The signature is not checked.
+func EdDSASignature(suite kyber.Group, publics []kyber.Point, sig []byte) (public, edSig []byte, err error) {
+	lenSig := suite.PointLen() + suite.ScalarLen()
+	if len(sig) < lenSig {
+		return nil, nil, errors.New("signature is too short")
+	}
+	mask := newMask(suite, publics)
+	if err := mask.SetMask(sig[lenSig:]); err != nil {
+		return nil, nil, err
+	}
+	public, err = mask.Aggregate().MarshalBinary()
+	if err != nil {
+		return nil, nil, err
+	}
+	return public, append([]byte{}, sig[:lenSig]...), nil
+}
+
 // AggregateResponse returns the aggregated response that this cosi has
 // accumulated.
 func (c *CoSi) AggregateResponse() kyber.Scalar {
 	return c.aggregateResponse
 }
 
+// AggregateCommitment returns the commitment of this cosi aggregated with the
+// ones of its children.
+func (c *CoSi) AggregateCommitment() kyber.Point {
+	return c.aggregateCommitment
+}
+
+// Mask returns the participation mask, where the bit of a cosigner is set if
+// it is disabled.
+func (c *CoSi) Mask() []byte {
+	return c.mask.bytes()
+}
+
 // GetChallenge returns the challenge that were passed down to this cosi.
 func (c *CoSi) GetChallenge() kyber.Scalar {
 	return c.challenge
@@ -431,6 +516,15 @@ func (cm *mask) MaskBit(signer int) bool {
 	return (cm.mask[byt] & bit) != 0
 }
 
+// participants returns for each cosigner whether it is enabled.
+func (cm *mask) participants() []bool {
+	parts := make([]bool, len(cm.publics))
+	for i := range parts {
+		parts[i] = !cm.MaskBit(i)
+	}
+	return parts
+}
+
 // bytes returns the byte representation of the mask
 // The bits that are left are set to a default value (1) for
 // non malleability.
@@ -1,12 +1,14 @@
 package crypto
 
 import (
+	"crypto/ed25519"
 	"fmt"
 	"testing"
 
 	xEd25519 "github.com/bford/golang-x-crypto/ed25519"
 	"github.com/bford/golang-x-crypto/ed25519/cosi"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.dedis.ch/kyber/v3"
 	"go.dedis.ch/kyber/v3/suites"
 	"go.dedis.ch/kyber/v3/util/key"
@@ -149,6 +151,42 @@ func TestCosiSignatureWithMask(t *testing.T) {
 
 }
 
+func TestCosiSignatureWithPolicy(t *testing.T) {
+	msg := []byte("Hello World Cosi")
+	cosis, publics := genCosisFailing(6, 2)
+	genFinalCosi(cosis, msg)
+	sig := cosis[0].Signature()
+
+	require.NoError(t, VerifySignatureWithPolicy(testSuite, publics, msg, sig, NewThresholdPolicy(4)))
+	require.Error(t, VerifySignatureWithPolicy(testSuite, publics, msg, sig, NewThresholdPolicy(5)))
+	require.NoError(t, VerifySignatureWithPolicy(testSuite, publics, msg, sig, NewFractionPolicy(2, 3)))
+	require.Error(t, VerifySignatureWithPolicy(testSuite, publics, msg, sig, NewFractionPolicy(3, 4)))
+	require.Error(t, VerifySignatureWithPolicy(testSuite, publics, msg, sig, nil))
+	require.Error(t, VerifySignatureWithPolicy(testSuite, publics, msg, sig[:10], NewThresholdPolicy(0)))
+
+	// The policy doesn't make a wrong signature valid.
+	require.Error(t, VerifySignatureWithPolicy(testSuite, publics, []byte("other"), sig, NewThresholdPolicy(0)))
+
+	cosis, publics = genCosisFailing(3, 0)
+	genFinalCosi(cosis, msg)
+	require.NoError(t, VerifySignatureWithPolicy(testSuite, publics, msg, cosis[0].Signature(), nil))
+}
+
+func TestEdDSASignature(t *testing.T) {
+	msg := []byte("Hello World Cosi")
+	cosis, publics := genCosisFailing(5, 2)
+	genFinalCosi(cosis, msg)
+	sig := cosis[0].Signature()
+
+	public, edSig, err := EdDSASignature(testSuite, publics, sig)
+	require.NoError(t, err)
+	require.True(t, ed25519.Verify(public, msg, edSig))
+	require.False(t, ed25519.Verify(public, []byte("other"), edSig))
+
+	_, _, err = EdDSASignature(testSuite, publics, sig[:10])
+	require.Error(t, err)
+}
+
 func genKeyPair(nb int) ([]*key.Pair, []kyber.Point) {
 	var kps []*key.Pair
 	var publics []kyber.Point
@@ -0,0 +1,63 @@
+package crypto
+
+// Policy decides whether the nodes that took part in a collective signature
+// are enough for the signature to be accepted.
+type Policy interface {
+	// Check returns true if the signature is accepted, participants[i] being
+	// true if the i-th public key signed.
+	Check(participants []bool) bool
+}
+
+// CompletePolicy accepts only the signatures of all the nodes.
+type CompletePolicy struct{}
+
+// Check implements Policy.
+func (CompletePolicy) Check(participants []bool) bool {
+	return count(participants) == len(participants)
+}
+
+// thresholdPolicy accepts the signatures of at least threshold nodes.
+type thresholdPolicy struct {
+	threshold int
+}
+
+// NewThresholdPolicy returns a policy accepting the signatures of at least
+// threshold nodes.
+func NewThresholdPolicy(threshold int) Policy {
+	return &thresholdPolicy{threshold: threshold}
+}
+
+// Check implements Policy.
+func (p *thresholdPolicy) Check(participants []bool) bool {
+	return count(participants) >= p.threshold
+}
+
+// fractionPolicy accepts the signatures of at least num/den of the nodes.
+type fractionPolicy struct {
+	num, den int
+}
+
+// NewFractionPolicy returns a policy accepting the signatures of at least
+// num/den of the nodes, so that NewFractionPolicy(2, 3) requires two thirds
+// of the roster.
+func NewFractionPolicy(num, den int) Policy {
+	return &fractionPolicy{num: num, den: den}
+}
+
+// Check implements Policy.
+func (p *fractionPolicy) Check(participants []bool) bool {
+	if p.den <= 0 {
+		return false
+	}
+	return count(participants)*p.den >= len(participants)*p.num
+}
+
+func count(participants []bool) int {
+	n := 0
+	for _, p := range participants {
+		if p {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,185 @@
+package cosi
+
+import (
+	"errors"
+	"fmt"
+
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/pairing"
+	"go.dedis.ch/kyber/v3/sign"
+	"go.dedis.ch/kyber/v3/sign/bls"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/onet/v3/network"
+)
+
+// BlsName can be used to reference the registered BLS protocol.
+var BlsName = "BlsCoSi"
+
+func init() {
+	onet.GlobalProtocolRegister(BlsName, NewBlsProtocol)
+	network.RegisterMessages(&BlsAnnouncement{}, &BlsResponse{})
+}
+
+// BlsCoSi is the BLS version of the CoSi protocol. It has only two phases:
+//   - Announcement of the message down the tree
+//   - Response with the aggregate signature and the participation mask
+//
+// The keys of the nodes have to be on the bn256 curve, which is the case for
+// the service keys of a service registered with the pairing suite. The final
+// signature has a constant size and can be verified by external systems with
+// VerifyBlsSignature. As the signatures are simply aggregated, the public keys
+// must be known to belong to their nodes, as it is the case for a roster.
+type BlsCoSi struct {
+	// The node that represents us
+	*onet.TreeNodeInstance
+	// the message we want to sign typically given by the Root
+	Message []byte
+	// FinalSignature receives the aggregate signature followed by the
+	// participation mask at the root
+	FinalSignature chan []byte
+
+	suite    *pairing.SuiteBn256
+	start    chan bool
+	announce chan blsChanAnnouncement
+	response chan []blsChanResponse
+}
+
+// BlsAnnouncement is sent down the tree with the message to sign.
+type BlsAnnouncement struct {
+	Message []byte
+}
+
+// BlsResponse holds the aggregate signature of the subtree with the mask of
+// the nodes that signed.
+type BlsResponse struct {
+	Signature []byte
+	Mask      []byte
+}
+
+type blsChanAnnouncement struct {
+	*onet.TreeNode
+	BlsAnnouncement
+}
+
+type blsChanResponse struct {
+	*onet.TreeNode
+	BlsResponse
+}
+
+// NewBlsProtocol returns a BlsCoSi with the node set with the right channels.
+func NewBlsProtocol(node *onet.TreeNodeInstance) (onet.ProtocolInstance, error) {
+	c := &BlsCoSi{
+		TreeNodeInstance: node,
+		FinalSignature:   make(chan []byte, 1),
+		suite:            pairing.NewSuiteBn256(),
+		start:            make(chan bool, 1),
+	}
+	if err := node.RegisterChannel(&c.announce); err != nil {
+		return c, err
+	}
+	if err := node.RegisterChannel(&c.response); err != nil {
+		return c, err
+	}
+	return c, nil
+}
+
+// Start starts the round at the root with the message given with
+// SigningMessage.
+func (c *BlsCoSi) Start() error {
+	if len(c.Message) == 0 {
+		return errors.New("no message to sign")
+	}
+	c.start <- true
+	return nil
+}
+
+// SigningMessage simply set the message to sign for this round
+func (c *BlsCoSi) SigningMessage(msg []byte) {
+	c.Message = msg
+	log.Lvlf2("%s Root will sign message %x", c.Name(), c.Message)
+}
+
+// Dispatch sends the message down the tree, then waits for the responses of
+// the children and sends the aggregate up the tree.
+func (c *BlsCoSi) Dispatch() error {
+	defer c.Done()
+
+	if c.IsRoot() {
+		<-c.start
+	} else {
+		log.Lvl3(c.Name(), "Waiting for announcement")
+		c.Message = (<-c.announce).Message
+	}
+
+	var responses []blsChanResponse
+	if !c.IsLeaf() {
+		if err := c.SendToChildren(&BlsAnnouncement{Message: c.Message}); err != nil {
+			return err
+		}
+		responses = <-c.response
+	}
+
+	resp, err := c.aggregate(responses)
+	if err != nil {
+		return err
+	}
+	if !c.IsRoot() {
+		return c.SendToParent(resp)
+	}
+	c.FinalSignature <- append(resp.Signature, resp.Mask...)
+	return nil
+}
+
+// aggregate signs the message and aggregates the signature with the ones of
+// the children.
+func (c *BlsCoSi) aggregate(responses []blsChanResponse) (*BlsResponse, error) {
+	mask, err := sign.NewMask(c.suite, c.Publics(), c.Public())
+	if err != nil {
+		return nil, err
+	}
+	sig, err := bls.Sign(c.suite, c.Private(), c.Message)
+	if err != nil {
+		return nil, err
+	}
+	sigs := [][]byte{sig}
+	for _, r := range responses {
+		if err := mask.Merge(r.Mask); err != nil {
+			return nil, err
+		}
+		sigs = append(sigs, r.Signature)
+	}
+	agg, err := bls.AggregateSignatures(c.suite, sigs...)
+	if err != nil {
+		return nil, err
+	}
+	return &BlsResponse{Signature: agg, Mask: mask.Mask()}, nil
+}
+
+// VerifyBlsSignature verifies a signature of BlsCoSi, made of the aggregate
+// signature followed by the participation mask. The mask is checked against
+// the policy, and a nil policy requires all the nodes to have signed.
+func VerifyBlsSignature(suite pairing.Suite, publics []kyber.Point, msg, sig []byte, policy sign.Policy) error {
+	lenSig := suite.G1().PointLen()
+	if len(sig) < lenSig {
+		return errors.New("signature is too short")
+	}
+	mask, err := sign.NewMask(suite, publics, nil)
+	if err != nil {
+		return err
+	}
+	if err := mask.SetMask(sig[lenSig:]); err != nil {
+		return err
+	}
+	if policy == nil {
+		policy = sign.CompletePolicy{}
+	}
+	if !policy.Check(mask) {
+		return errors.New("the policy is not fulfilled")
+	}
+	aggPub := bls.AggregatePublicKeys(suite, mask.Participants()...)
+	if err := bls.Verify(suite, aggPub, msg, sig[:lenSig]); err != nil {
+		return fmt.Errorf("invalid signature: %v", err)
+	}
+	return nil
+}
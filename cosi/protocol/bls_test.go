@@ -0,0 +1,52 @@
+package cosi
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/kyber/v3/pairing"
+	"go.dedis.ch/kyber/v3/sign"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/log"
+)
+
+var blsSuite = pairing.NewSuiteBn256()
+
+func TestBlsCoSi(t *testing.T) {
+	for _, nbrHosts := range []int{1, 3, 13} {
+		log.Lvl2("Running BLS cosi with", nbrHosts, "hosts")
+		local := onet.NewLocalTest(blsSuite)
+		_, el, tree := local.GenBigTree(nbrHosts, nbrHosts, 3, true)
+		msg := []byte("Hello World BLS Cosi")
+
+		p, err := local.CreateProtocol(BlsName, tree)
+		require.NoError(t, err)
+		root := p.(*BlsCoSi)
+		root.SigningMessage(msg)
+		require.NoError(t, root.Start())
+
+		select {
+		case sig := <-root.FinalSignature:
+			publics := el.Publics()
+			require.NoError(t, VerifyBlsSignature(blsSuite, publics, msg, sig, nil))
+			require.Error(t, VerifyBlsSignature(blsSuite, publics, []byte("other"), sig, nil))
+
+			// Removing a signer from the mask makes the signature invalid, even
+			// if the policy accepts the mask.
+			if nbrHosts > 1 {
+				lenSig := blsSuite.G1().PointLen()
+				mask, err := sign.NewMask(blsSuite, publics, nil)
+				require.NoError(t, err)
+				require.NoError(t, mask.SetMask(sig[lenSig:]))
+				require.NoError(t, mask.SetBit(nbrHosts-1, false))
+				partial := append(append([]byte{}, sig[:lenSig]...), mask.Mask()...)
+				require.Error(t, VerifyBlsSignature(blsSuite, publics, msg, partial,
+					sign.NewThresholdPolicy(nbrHosts-1)))
+			}
+		case <-time.After(time.Second * 5):
+			t.Fatal("Could not get the signature in time")
+		}
+		local.CloseAll()
+	}
+}
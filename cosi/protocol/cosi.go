@@ -2,7 +2,10 @@
 package cosi
 
 import (
+	"errors"
+	"fmt"
 	"sync"
+	"time"
 
 	"go.dedis.ch/cothority/v3/cosi/crypto"
 	"go.dedis.ch/kyber/v3"
@@ -13,6 +16,18 @@ import (
 // Name can be used to reference the registered protocol.
 var Name = "CoSi"
 
+// DefaultTimeout is the time a node waits for the messages of a child that is
+// a leaf. It is multiplied by the height of the subtree for the other
+// children, so that the nodes below give up before their parent does.
+const DefaultTimeout = 5 * time.Second
+
+// DefaultThreshold returns the minimum number of nodes that have to take part
+// in a signature out of n, which is the number of nodes that can be trusted
+// if up to a third of them are faulty.
+func DefaultThreshold(n int) int {
+	return n - (n-1)/3
+}
+
 func init() {
 	onet.GlobalProtocolRegister(Name, NewProtocol)
 }
@@ -23,6 +38,12 @@ func init() {
 //  - Commitment
 //  - Challenge
 //  - Response
+//
+// A child that doesn't send its commitment in time is left out of the
+// signature with its subtree: its nodes are listed as exceptions in the
+// commitment sent to the parent, and the root removes them from the mask of
+// the signature. The round fails if fewer nodes than the threshold committed,
+// or if a child that committed doesn't send its response in time.
 
 // CoSi is the main structure holding the round and the onet.Node.
 type CoSi struct {
@@ -33,20 +54,47 @@ type CoSi struct {
 	cosi *crypto.CoSi
 	// the message we want to sign typically given by the Root
 	Message []byte
+	// Data is sent down the tree with the message for the nodes to verify
+	// the message before they commit. If it is nil, the nodes don't get
+	// the message.
+	Data []byte
+	// Auth is sent down the tree with the message for the nodes to check
+	// that the client can ask them for the signature.
+	Auth []byte
+	// Timeout is the time to wait for the messages of a child that is a
+	// leaf. The root sends it down the tree with the announcement.
+	Timeout time.Duration
+	// Threshold is the minimum number of nodes that have to commit for the
+	// root to continue the round.
+	Threshold int
+	// Tracer gets the time spent in each phase, and defaults to the one
+	// given to SetTracer.
+	Tracer Tracer
+	// Commitments holds the precomputed commitment of the node, and
+	// defaults to the pool given to SetCommitments.
+	Commitments *Commitments
 	// The channel waiting for Announcement message
 	announce chan chanAnnouncement
-	// the channel waiting for Commitment message
-	commit chan []chanCommitment
+	// the channel waiting for Commitment messages
+	commit chan chanCommitment
 	// the channel waiting for Challenge message
 	challenge chan chanChallenge
-	// the channel waiting for Response message
-	response chan []chanResponse
+	// the channel waiting for Response messages
+	response chan chanResponse
+	// started is closed when the root starts the round
+	started chan bool
 	// the channel that indicates if we are finished or not
-	done chan bool
+	done     chan bool
+	doneOnce sync.Once
 	// temporary buffer of commitment messages
 	tempCommitment []kyber.Point
 	// lock associated
 	tempCommitLock *sync.Mutex
+	// committed holds the children whose commitment was received
+	committed []*onet.TreeNode
+	// exceptions holds the roster indexes of the nodes of the subtree that
+	// didn't commit
+	exceptions []int
 	// temporary buffer of Response messages
 	tempResponse []kyber.Scalar
 	// lock associated
@@ -58,18 +106,24 @@ type CoSi struct {
 	challengeHook    ChallengeHook
 	responseHook     ResponseHook
 	signatureHook    SignatureHook
+	doneHook         DoneHook
+	errorHook        ErrorHook
+	verificationHook VerificationHook
 }
 
-// AnnouncementHook allows for handling what should happen upon an
-// announcement
-type AnnouncementHook func() error
+// AnnouncementHook is called by each node with the announcement before it is
+// sent to the children, and can change it. A node returning an error refuses
+// to sign and is left out of the signature with its subtree.
+type AnnouncementHook func(in *Announcement) error
 
-// CommitmentHook allows for handling what should happen when all
-// commitments are received
+// CommitmentHook is called by each node that is not a leaf with the
+// commitments of its children before they are aggregated. An error stops the
+// node, and makes the round fail at the root.
 type CommitmentHook func(in []kyber.Point) error
 
-// ChallengeHook allows for handling what should happen when a
-// challenge is received
+// ChallengeHook is called by each node with the challenge before it is sent
+// to the children. An error stops the node, which makes the round fail as
+// its response is missing.
 type ChallengeHook func(ch kyber.Scalar) error
 
 // ResponseHook allows for handling what should happen when all
@@ -79,6 +133,17 @@ type ResponseHook func(in []kyber.Scalar)
 // SignatureHook allows registering a handler when the signature is done
 type SignatureHook func(sig []byte)
 
+// DoneHook allows registering a handler getting the complete signature when
+// the protocol is done
+type DoneHook func(sig *Signature)
+
+// VerificationHook allows checking the message and its data before a node
+// commits. A node refusing the message is left out of the signature.
+type VerificationHook func(msg, data []byte) error
+
+// ErrorHook allows registering a handler when the round fails at the root
+type ErrorHook func(err error)
+
 // NewProtocol returns a ProtocolCosi with the node set with the right channels.
 // Use this function like this:
 // ```
@@ -101,6 +166,11 @@ func NewProtocol(node *onet.TreeNodeInstance) (onet.ProtocolInstance, error) {
 	c := &CoSi{
 		cosi:             crypto.NewCosi(node.Suite(), node.Private(), publics),
 		TreeNodeInstance: node,
+		Timeout:          DefaultTimeout,
+		Threshold:        DefaultThreshold(len(node.Roster().List)),
+		Tracer:           getTracer(),
+		Commitments:      getCommitments(node.Suite()),
+		started:          make(chan bool),
 		done:             make(chan bool),
 		tempCommitLock:   new(sync.Mutex),
 		tempResponseLock: new(sync.Mutex),
@@ -125,51 +195,168 @@ func NewProtocol(node *onet.TreeNodeInstance) (onet.ProtocolInstance, error) {
 
 // Dispatch will listen on the four channels we use (i.e. four steps)
 func (c *CoSi) Dispatch() error {
-	nbrChild := len(c.Children())
-	if !c.IsRoot() {
+	if c.IsRoot() {
+		<-c.started
+	} else {
 		log.Lvl3(c.Name(), "Waiting for announcement")
 		ann := (<-c.announce).Announcement
+		start := time.Now()
 		err := c.handleAnnouncement(&ann)
+		c.tracePhase(PhaseAnnouncement, start, err)
 		if err != nil {
-			return err
+			return c.fail(err)
 		}
 	}
 	if !c.IsLeaf() {
-		for n, commit := range <-c.commit {
-			log.Lvlf3("%s Handling commitment %d/%d",
-				c.Name(), n+1, nbrChild)
-			err := c.handleCommitment(&commit.Commitment)
-			if err != nil {
-				return err
-			}
+		start := time.Now()
+		err := c.waitCommitments()
+		c.tracePhase(PhaseCommitment, start, err)
+		if err != nil {
+			return c.fail(err)
 		}
 	}
 	if !c.IsRoot() {
 		log.Lvl3(c.Name(), "Waiting for Challenge")
-		challenge := (<-c.challenge).Challenge
-		err := c.handleChallenge(&challenge)
+		start := time.Now()
+		var err error
+		select {
+		case challenge := <-c.challenge:
+			err = c.handleChallenge(&challenge.Challenge)
+		case <-time.After(2 * c.subtreeTimeout(c.Root())):
+			err = errors.New("timeout waiting for the challenge")
+		}
+		c.tracePhase(PhaseChallenge, start, err)
 		if err != nil {
-			return err
+			return c.fail(err)
 		}
 	}
 	if !c.IsLeaf() {
-		for n, response := range <-c.response {
-			log.Lvlf3("%s Handling response of child %d/%d", c.Name(), n+1, nbrChild)
+		start := time.Now()
+		err := c.waitResponses()
+		c.tracePhase(PhaseResponse, start, err)
+		if err != nil {
+			return c.fail(err)
+		}
+	}
+	<-c.done
+	return nil
+}
+
+// waitCommitments handles the commitments of the children until all of them
+// are received or the timeout of the subtree expires. The missing children
+// are added to the exceptions with their subtree.
+func (c *CoSi) waitCommitments() error {
+	start := time.Now()
+	timeout := time.After(c.subtreeTimeout(c.TreeNode()))
+	pending := make(map[onet.TreeNodeID]*onet.TreeNode)
+	for _, child := range c.Children() {
+		pending[child.ID] = child
+	}
+	for len(pending) > 0 {
+		select {
+		case commit := <-c.commit:
+			if _, ok := pending[commit.TreeNode.ID]; !ok {
+				continue
+			}
+			log.Lvlf3("%s Handling commitment %d/%d", c.Name(),
+				len(c.Children())-len(pending)+1, len(c.Children()))
+			delete(pending, commit.TreeNode.ID)
+			c.traceChild(commit.TreeNode, PhaseCommitment, start)
+			c.committed = append(c.committed, commit.TreeNode)
+			c.exceptions = append(c.exceptions, commit.Exceptions...)
+			if err := c.handleCommitment(&commit.Commitment); err != nil {
+				return err
+			}
+		case <-timeout:
+			for _, child := range pending {
+				log.Warnf("%s: no commitment from %s", c.Name(), child.ServerIdentity)
+				c.exceptions = append(c.exceptions, subtreeIndexes(child)...)
+			}
+			return c.handleCommitment(nil)
+		}
+	}
+	return nil
+}
+
+// waitResponses handles the responses of the children that committed. They
+// are all needed, as the challenge depends on their commitments.
+func (c *CoSi) waitResponses() error {
+	start := time.Now()
+	timeout := time.After(c.subtreeTimeout(c.TreeNode()))
+	for n := range c.committed {
+		select {
+		case response := <-c.response:
+			c.traceChild(response.TreeNode, PhaseResponse, start)
+			log.Lvlf3("%s Handling response of child %d/%d", c.Name(), n+1, len(c.committed))
 			err := c.handleResponse(&response.Response)
 			if err != nil {
 				return err
 			}
+		case <-timeout:
+			return fmt.Errorf("%s: missing %d responses", c.Name(), len(c.committed)-n)
 		}
 	}
-	<-c.done
 	return nil
 }
 
+// fail stops the protocol after an error.
+func (c *CoSi) fail(err error) error {
+	log.Error(c.Name(), err)
+	if c.IsRoot() && c.errorHook != nil {
+		c.errorHook(err)
+	}
+	c.finish()
+	return err
+}
+
+// finish closes the protocol once.
+func (c *CoSi) finish() {
+	c.doneOnce.Do(func() {
+		close(c.done)
+		c.Done()
+	})
+}
+
+// subtreeTimeout returns the time to wait for the messages of the subtree of
+// the node.
+func (c *CoSi) subtreeTimeout(n *onet.TreeNode) time.Duration {
+	return c.Timeout * time.Duration(subtreeHeight(n))
+}
+
+// subtreeHeight returns the number of levels below the node.
+func subtreeHeight(n *onet.TreeNode) int {
+	height := 0
+	for _, child := range n.Children {
+		if h := subtreeHeight(child) + 1; h > height {
+			height = h
+		}
+	}
+	return height
+}
+
+// subtreeIndexes returns the roster indexes of the nodes of the subtree.
+func subtreeIndexes(n *onet.TreeNode) []int {
+	indexes := []int{n.RosterIndex}
+	for _, child := range n.Children {
+		indexes = append(indexes, subtreeIndexes(child)...)
+	}
+	return indexes
+}
+
 // Start will call the announcement function of its inner Round structure. It
 // will pass nil as *in* message.
 func (c *CoSi) Start() error {
-	out := &Announcement{}
-	return c.handleAnnouncement(out)
+	close(c.started)
+	out := &Announcement{Timeout: c.Timeout}
+	if c.Data != nil || c.Auth != nil {
+		out.Message = c.Message
+		out.Data = c.Data
+		out.Auth = c.Auth
+	}
+	start := time.Now()
+	err := c.handleAnnouncement(out)
+	c.tracePhase(PhaseAnnouncement, start, err)
+	return err
 }
 
 // VerifySignature verifies if the challenge and the secret (from the response phase) form a
@@ -180,55 +367,107 @@ func VerifySignature(suite kyber.Group, publics []kyber.Point, msg, sig []byte)
 	return crypto.VerifySignature(suite, publics, msg, sig)
 }
 
+// VerifySignatureWithPolicy verifies the signature and checks that the nodes
+// that signed fulfill the policy, like crypto.VerifySignatureWithPolicy.
+func VerifySignatureWithPolicy(suite kyber.Group, publics []kyber.Point, msg, sig []byte, policy crypto.Policy) error {
+	return crypto.VerifySignatureWithPolicy(suite, publics, msg, sig, policy)
+}
+
 // handleAnnouncement will pass the message to the round and send back the
 // output. If in == nil, we are root and we start the round.
 func (c *CoSi) handleAnnouncement(in *Announcement) error {
 	log.Lvlf3("Message: %x", c.Message)
-	// If we have a hook on announcement call the hook
+	if in.Timeout > 0 {
+		c.Timeout = in.Timeout
+	}
+	if !c.IsRoot() && (in.Data != nil || in.Auth != nil) {
+		c.Message = in.Message
+		c.Data = in.Data
+		c.Auth = in.Auth
+	}
+	if !c.IsRoot() && in.Data != nil {
+		if c.verificationHook != nil {
+			if err := c.verificationHook(c.Message, c.Data); err != nil {
+				return fmt.Errorf("refusing to sign: %v", err)
+			}
+		}
+	}
 	if c.announcementHook != nil {
-		return c.announcementHook()
+		if err := c.announcementHook(in); err != nil {
+			return fmt.Errorf("refusing to sign: %v", err)
+		}
 	}
 
 	// If we are leaf, we should go to commitment
 	if c.IsLeaf() {
 		return c.handleCommitment(nil)
 	}
-	// send to children
-	return c.SendToChildren(in)
+	// send to children, the ones that cannot be reached become exceptions
+	// when their commitment is missing
+	for _, child := range c.Children() {
+		if err := c.SendTo(child, in); err != nil {
+			log.Warnf("%s: cannot announce to %s: %v", c.Name(), child.ServerIdentity, err)
+		}
+	}
+	return nil
 }
 
-// handleAllCommitment relay the commitments up in the tree
-// It expects *in* to be the full set of messages from the children.
+// handleCommitment relay the commitments up in the tree
+// It is called with each commitment of the children, and with nil once the
+// missing children are added to the exceptions.
 // The children's commitment must remain constants.
 func (c *CoSi) handleCommitment(in *Commitment) error {
-	if !c.IsLeaf() {
+	if !c.IsLeaf() && in != nil {
 		// add to temporary
 		c.tempCommitLock.Lock()
 		c.tempCommitment = append(c.tempCommitment, in.Comm)
 		c.tempCommitLock.Unlock()
 		// do we have enough ?
-		// TODO: exception mechanism will be put into another protocol
 		if len(c.tempCommitment) < len(c.Children()) {
 			return nil
 		}
 	}
 	log.Lvl3(c.Name(), "aggregated")
-	// pass it to the hook
-	if c.commitmentHook != nil {
-		return c.commitmentHook(c.tempCommitment)
+	if c.commitmentHook != nil && !c.IsLeaf() {
+		if err := c.commitmentHook(c.tempCommitment); err != nil {
+			return err
+		}
 	}
 
 	// go to Commit()
-	out := c.cosi.Commit(c.Suite().RandomStream(), c.tempCommitment)
+	var out kyber.Point
+	if c.Commitments != nil {
+		secret, commitment := c.Commitments.Get()
+		out = c.cosi.CommitWith(secret, commitment, c.tempCommitment)
+	} else {
+		out = c.cosi.Commit(c.Suite().RandomStream(), c.tempCommitment)
+	}
 
 	// if we are the root, we need to start the Challenge
 	if c.IsRoot() {
+		nodes := len(c.Roster().List)
+		for _, i := range c.exceptions {
+			if i < 0 || i >= nodes {
+				return fmt.Errorf("invalid exception %d", i)
+			}
+			c.cosi.SetMaskBit(i, false)
+		}
+		committed := 0
+		for i := 0; i < nodes; i++ {
+			if !c.cosi.MaskBit(i) {
+				committed++
+			}
+		}
+		if committed < c.Threshold {
+			return fmt.Errorf("only %d nodes committed out of the %d needed", committed, c.Threshold)
+		}
 		return c.startChallenge()
 	}
 
 	// otherwise send it to parent
 	outMsg := &Commitment{
-		Comm: out,
+		Comm:       out,
+		Exceptions: c.exceptions,
 	}
 	return c.SendTo(c.Parent(), outMsg)
 }
@@ -254,7 +493,9 @@ func (c *CoSi) handleChallenge(in *Challenge) error {
 	c.cosi.Challenge(in.Chall)
 
 	if c.challengeHook != nil {
-		c.challengeHook(in.Chall)
+		if err := c.challengeHook(in.Chall); err != nil {
+			return err
+		}
 	}
 
 	// if we are leaf, then go to response
@@ -262,29 +503,34 @@ func (c *CoSi) handleChallenge(in *Challenge) error {
 		return c.handleResponse(nil)
 	}
 
-	// otherwise send it to children
-	return c.SendToChildren(in)
+	// otherwise send it to the children that committed
+	for _, child := range c.committed {
+		if err := c.SendTo(child, in); err != nil {
+			return err
+		}
+	}
+	if len(c.committed) == 0 {
+		return c.handleResponse(nil)
+	}
+	return nil
 }
 
 // handleResponse brings up the response of each node in the tree to the root.
 func (c *CoSi) handleResponse(in *Response) error {
-	if !c.IsLeaf() {
+	if in != nil {
 		// add to temporary
 		c.tempResponseLock.Lock()
 		c.tempResponse = append(c.tempResponse, in.Resp)
 		c.tempResponseLock.Unlock()
 		// do we have enough ?
 		log.Lvl3(c.Name(), "has", len(c.tempResponse), "responses")
-		if len(c.tempResponse) < len(c.Children()) {
+		if len(c.tempResponse) < len(c.committed) {
 			return nil
 		}
 	}
 
-	defer func() {
-		// protocol is finished
-		close(c.done)
-		c.Done()
-	}()
+	// protocol is finished
+	defer c.finish()
 
 	log.Lvl3(c.Name(), "aggregated")
 	outResponse, err := c.cosi.Response(c.tempResponse)
@@ -309,6 +555,9 @@ func (c *CoSi) handleResponse(in *Response) error {
 	if c.signatureHook != nil {
 		c.signatureHook(c.cosi.Signature())
 	}
+	if c.doneHook != nil {
+		c.doneHook(c.signature())
+	}
 	return nil
 }
 
@@ -353,3 +602,28 @@ func (c *CoSi) RegisterResponseHook(fn ResponseHook) {
 func (c *CoSi) RegisterSignatureHook(fn SignatureHook) {
 	c.signatureHook = fn
 }
+
+// RegisterDoneHook allows for getting the complete signature when the
+// protocol is done
+func (c *CoSi) RegisterDoneHook(fn DoneHook) {
+	c.doneHook = fn
+}
+
+// RegisterVerificationHook allows for checking the message before the node
+// commits, if the root gives data with the message
+func (c *CoSi) RegisterVerificationHook(fn VerificationHook) {
+	c.verificationHook = fn
+}
+
+// RegisterErrorHook allows for handling what should happen when the round
+// fails at the root
+func (c *CoSi) RegisterErrorHook(fn ErrorHook) {
+	c.errorHook = fn
+}
+
+// Exceptions returns the roster indexes of the nodes that didn't commit in
+// the subtree of the node. At the root, it can be called once the signature
+// or the error hook is called.
+func (c *CoSi) Exceptions() []int {
+	return c.exceptions
+}
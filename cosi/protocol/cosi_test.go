@@ -1,9 +1,13 @@
 package cosi
 
 import (
+	"crypto/ed25519"
+	"errors"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/require"
 	"go.dedis.ch/cothority/v3"
 	"go.dedis.ch/kyber/v3"
 	"go.dedis.ch/onet/v3"
@@ -69,3 +73,243 @@ func TestCosi(t *testing.T) {
 		local.CloseAll()
 	}
 }
+
+const failingName = "CoSiFailing"
+
+func init() {
+	onet.GlobalProtocolRegister(failingName, newFailingProtocol)
+}
+
+// newFailingProtocol returns a CoSi where the last node of the roster never
+// sends its commitment.
+func newFailingProtocol(n *onet.TreeNodeInstance) (onet.ProtocolInstance, error) {
+	pi, err := NewProtocol(n)
+	if err != nil {
+		return nil, err
+	}
+	if n.TreeNode().RosterIndex == len(n.Roster().List)-1 {
+		pi.(*CoSi).RegisterAnnouncementHook(func(*Announcement) error {
+			return errors.New("failing node")
+		})
+	}
+	return pi, nil
+}
+
+const hooksName = "CoSiHooks"
+
+// hookCalls counts the calls of the hooks of the nodes of hooksName.
+var hookCalls struct {
+	sync.Mutex
+	announcements map[string]int
+	commitments   int
+	challenges    int
+}
+
+func init() {
+	onet.GlobalProtocolRegister(hooksName, newHooksProtocol)
+}
+
+// newHooksProtocol returns a CoSi whose hooks count their calls. The last
+// node of the roster refuses an announcement with the data "veto".
+func newHooksProtocol(n *onet.TreeNodeInstance) (onet.ProtocolInstance, error) {
+	pi, err := NewProtocol(n)
+	if err != nil {
+		return nil, err
+	}
+	c := pi.(*CoSi)
+	c.RegisterAnnouncementHook(func(in *Announcement) error {
+		hookCalls.Lock()
+		hookCalls.announcements[string(in.Data)]++
+		hookCalls.Unlock()
+		if string(in.Data) == "veto" && n.TreeNode().RosterIndex == len(n.Roster().List)-1 {
+			return errors.New("veto")
+		}
+		return nil
+	})
+	c.RegisterCommitmentHook(func(in []kyber.Point) error {
+		hookCalls.Lock()
+		hookCalls.commitments += len(in)
+		hookCalls.Unlock()
+		return nil
+	})
+	c.RegisterChallengeHook(func(kyber.Scalar) error {
+		hookCalls.Lock()
+		hookCalls.challenges++
+		hookCalls.Unlock()
+		return nil
+	})
+	return c, nil
+}
+
+func TestCosi_Exceptions(t *testing.T) {
+	nbrHosts := 13
+	local := onet.NewLocalTest(tSuite)
+	defer local.CloseAll()
+	_, el, tree := local.GenBigTree(nbrHosts, nbrHosts, 3, true)
+	msg := []byte("Hello World Cosi")
+
+	p, err := local.CreateProtocol(failingName, tree)
+	require.NoError(t, err)
+	root := p.(*CoSi)
+	root.Message = msg
+	root.Timeout = 100 * time.Millisecond
+	sigs := make(chan []byte, 1)
+	root.RegisterSignatureHook(func(sig []byte) { sigs <- sig })
+	go root.Start()
+
+	select {
+	case sig := <-sigs:
+		require.NoError(t, VerifySignature(tSuite, el.Publics(), msg, sig))
+		require.True(t, root.cosi.MaskBit(nbrHosts-1))
+		require.False(t, root.cosi.MaskBit(0))
+	case <-time.After(time.Second * 5):
+		t.Fatal("Could not get the signature with a missing node")
+	}
+
+	// The round fails if all the nodes are required.
+	p, err = local.CreateProtocol(failingName, tree)
+	require.NoError(t, err)
+	root = p.(*CoSi)
+	root.Message = msg
+	root.Timeout = 100 * time.Millisecond
+	root.Threshold = nbrHosts
+	root.RegisterSignatureHook(func(sig []byte) { sigs <- sig })
+	go root.Start()
+
+	select {
+	case <-sigs:
+		t.Fatal("Got a signature without enough nodes")
+	case <-time.After(time.Second):
+	}
+}
+
+func TestCosi_DoneHook(t *testing.T) {
+	nbrHosts := 7
+	local := onet.NewLocalTest(tSuite)
+	defer local.CloseAll()
+	_, el, tree := local.GenBigTree(nbrHosts, nbrHosts, 2, true)
+	msg := []byte("Hello World Cosi")
+
+	p, err := local.CreateProtocol(failingName, tree)
+	require.NoError(t, err)
+	root := p.(*CoSi)
+	root.Message = msg
+	root.Timeout = 100 * time.Millisecond
+	raw := make(chan []byte, 1)
+	root.RegisterSignatureHook(func(sig []byte) { raw <- sig })
+	done := make(chan *Signature, 1)
+	root.RegisterDoneHook(func(sig *Signature) { done <- sig })
+	go root.Start()
+
+	select {
+	case sig := <-done:
+		require.Equal(t, msg, sig.Message)
+		require.Equal(t, []int{0, 1, 2, 3, 4, 5}, sig.Participants)
+		require.NoError(t, sig.Verify(tSuite, el.Publics()))
+		buf, err := sig.Bytes()
+		require.NoError(t, err)
+		require.Equal(t, <-raw, buf)
+
+		aggPublic := tSuite.Point().Null()
+		for _, i := range sig.Participants {
+			aggPublic.Add(aggPublic, el.List[i].Public)
+		}
+		require.True(t, aggPublic.Equal(sig.AggregatePublic))
+
+		// R = s*B - c*A
+		r := tSuite.Point().Mul(sig.Response, nil)
+		r.Sub(r, tSuite.Point().Mul(sig.Challenge, sig.AggregatePublic))
+		require.True(t, r.Equal(sig.Commitment))
+
+		public, edSig, err := sig.EdDSA()
+		require.NoError(t, err)
+		require.True(t, ed25519.Verify(public, msg, edSig))
+
+		sig.Message = []byte("other")
+		require.Error(t, sig.Verify(tSuite, el.Publics()))
+	case <-time.After(time.Second * 5):
+		t.Fatal("Could not get the signature in time")
+	}
+}
+
+func TestCosi_Hooks(t *testing.T) {
+	nbrHosts := 7
+	local := onet.NewLocalTest(tSuite)
+	defer local.CloseAll()
+	_, el, tree := local.GenBigTree(nbrHosts, nbrHosts, 2, true)
+	msg := []byte("Hello World Cosi")
+	hookCalls.announcements = make(map[string]int)
+
+	runRound := func(data string) (*CoSi, []byte) {
+		p, err := local.CreateProtocol(hooksName, tree)
+		require.NoError(t, err)
+		root := p.(*CoSi)
+		root.Message = msg
+		root.Timeout = 100 * time.Millisecond
+		sigs := make(chan []byte, 1)
+		root.RegisterSignatureHook(func(sig []byte) { sigs <- sig })
+		// The root sets the data in its announcement hook, and the other
+		// nodes get it.
+		announce := root.announcementHook
+		root.RegisterAnnouncementHook(func(in *Announcement) error {
+			in.Message = msg
+			in.Data = []byte(data)
+			return announce(in)
+		})
+		go root.Start()
+		select {
+		case sig := <-sigs:
+			return root, sig
+		case <-time.After(time.Second * 2):
+			return root, nil
+		}
+	}
+
+	// All the hooks are called.
+	root, sig := runRound("data")
+	require.NotNil(t, sig)
+	require.NoError(t, VerifySignature(tSuite, el.Publics(), msg, sig))
+	require.False(t, root.cosi.MaskBit(nbrHosts-1))
+	hookCalls.Lock()
+	require.Equal(t, nbrHosts, hookCalls.announcements["data"])
+	require.Equal(t, nbrHosts-1, hookCalls.commitments)
+	hookCalls.Unlock()
+	for i := 0; i < 100; i++ {
+		hookCalls.Lock()
+		challenges := hookCalls.challenges
+		hookCalls.Unlock()
+		if challenges == nbrHosts {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	hookCalls.Lock()
+	require.Equal(t, nbrHosts, hookCalls.challenges)
+	hookCalls.Unlock()
+
+	// The node vetoing the announcement is left out.
+	root, sig = runRound("veto")
+	require.NotNil(t, sig)
+	require.NoError(t, VerifySignature(tSuite, el.Publics(), msg, sig))
+	require.True(t, root.cosi.MaskBit(nbrHosts-1))
+
+	// A veto of the root on the challenge stops the round.
+	p, err := local.CreateProtocol(hooksName, tree)
+	require.NoError(t, err)
+	root = p.(*CoSi)
+	root.Message = msg
+	sigs := make(chan []byte, 1)
+	errs := make(chan error, 1)
+	root.RegisterSignatureHook(func(sig []byte) { sigs <- sig })
+	root.RegisterErrorHook(func(err error) { errs <- err })
+	root.RegisterChallengeHook(func(kyber.Scalar) error { return errors.New("veto") })
+	go root.Start()
+	select {
+	case <-sigs:
+		t.Fatal("Got a signature despite the veto")
+	case err := <-errs:
+		require.Contains(t, err.Error(), "veto")
+	case <-time.After(time.Second * 2):
+		t.Fatal("The round didn't fail")
+	}
+}
@@ -0,0 +1,178 @@
+package cosi
+
+import (
+	"sync"
+	"time"
+
+	"go.dedis.ch/onet/v3"
+)
+
+// Phase is one of the four phases of a round of CoSi.
+type Phase int
+
+const (
+	// PhaseAnnouncement is the handling of the announcement by a node.
+	PhaseAnnouncement Phase = iota
+	// PhaseCommitment is the wait for the commitments of the children.
+	PhaseCommitment
+	// PhaseChallenge is the wait for the challenge once the commitment is
+	// sent to the parent.
+	PhaseChallenge
+	// PhaseResponse is the wait for the responses of the children.
+	PhaseResponse
+)
+
+// String returns the name of the phase.
+func (p Phase) String() string {
+	switch p {
+	case PhaseAnnouncement:
+		return "announcement"
+	case PhaseCommitment:
+		return "commitment"
+	case PhaseChallenge:
+		return "challenge"
+	case PhaseResponse:
+		return "response"
+	}
+	return "unknown"
+}
+
+// Tracer gets the time spent by the nodes in the phases of the rounds, to
+// find the slow subtrees of a cothority. The calls of the nodes of a conode
+// can be concurrent.
+type Tracer interface {
+	// Phase is called by a node at the end of a phase of the round, with
+	// the time spent in it and the error that ended it, if any.
+	Phase(round onet.RoundID, node *onet.TreeNode, phase Phase, d time.Duration, err error)
+	// Child is called by a node when it gets the message of a child for
+	// the phase, with the time since the start of the phase.
+	Child(round onet.RoundID, node, child *onet.TreeNode, phase Phase, d time.Duration)
+}
+
+var defaultTracer struct {
+	sync.Mutex
+	tracer Tracer
+}
+
+// SetTracer sets the tracer of the rounds started after the call, which is
+// used by all the nodes of this conode. A nil tracer disables the tracing.
+func SetTracer(t Tracer) {
+	defaultTracer.Lock()
+	defaultTracer.tracer = t
+	defaultTracer.Unlock()
+}
+
+func getTracer() Tracer {
+	defaultTracer.Lock()
+	defer defaultTracer.Unlock()
+	return defaultTracer.tracer
+}
+
+// Latency holds the durations measured for a phase.
+type Latency struct {
+	Count  int
+	Errors int
+	Total  time.Duration
+	Max    time.Duration
+}
+
+// Mean returns the mean duration.
+func (l Latency) Mean() time.Duration {
+	if l.Count == 0 {
+		return 0
+	}
+	return l.Total / time.Duration(l.Count)
+}
+
+func (l *Latency) add(d time.Duration, err error) {
+	l.Count++
+	l.Total += d
+	if d > l.Max {
+		l.Max = d
+	}
+	if err != nil {
+		l.Errors++
+	}
+}
+
+// Metrics is a Tracer that keeps the latency of each phase, and of the
+// messages of each child.
+type Metrics struct {
+	sync.Mutex
+	phases   map[Phase]*Latency
+	children map[string]map[Phase]*Latency
+}
+
+// NewMetrics returns empty metrics.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		phases:   make(map[Phase]*Latency),
+		children: make(map[string]map[Phase]*Latency),
+	}
+}
+
+// Phase implements Tracer.
+func (m *Metrics) Phase(round onet.RoundID, node *onet.TreeNode, phase Phase, d time.Duration, err error) {
+	m.Lock()
+	defer m.Unlock()
+	l, ok := m.phases[phase]
+	if !ok {
+		l = &Latency{}
+		m.phases[phase] = l
+	}
+	l.add(d, err)
+}
+
+// Child implements Tracer.
+func (m *Metrics) Child(round onet.RoundID, node, child *onet.TreeNode, phase Phase, d time.Duration) {
+	m.Lock()
+	defer m.Unlock()
+	key := child.ServerIdentity.String()
+	phases, ok := m.children[key]
+	if !ok {
+		phases = make(map[Phase]*Latency)
+		m.children[key] = phases
+	}
+	l, ok := phases[phase]
+	if !ok {
+		l = &Latency{}
+		phases[phase] = l
+	}
+	l.add(d, nil)
+}
+
+// PhaseLatency returns the latency of the phase over all the rounds.
+func (m *Metrics) PhaseLatency(phase Phase) Latency {
+	m.Lock()
+	defer m.Unlock()
+	if l, ok := m.phases[phase]; ok {
+		return *l
+	}
+	return Latency{}
+}
+
+// ChildLatency returns the latency of the messages of the child for the
+// phase, where the child is given by the String of its ServerIdentity.
+func (m *Metrics) ChildLatency(child string, phase Phase) Latency {
+	m.Lock()
+	defer m.Unlock()
+	if l, ok := m.children[child][phase]; ok {
+		return *l
+	}
+	return Latency{}
+}
+
+// tracePhase passes the duration of the phase to the tracer, if any.
+func (c *CoSi) tracePhase(phase Phase, start time.Time, err error) {
+	if c.Tracer != nil {
+		c.Tracer.Phase(c.Token().RoundID, c.TreeNode(), phase, time.Since(start), err)
+	}
+}
+
+// traceChild passes the delay of the message of the child to the tracer, if
+// any.
+func (c *CoSi) traceChild(child *onet.TreeNode, phase Phase, start time.Time) {
+	if c.Tracer != nil {
+		c.Tracer.Child(c.Token().RoundID, c.TreeNode(), child, phase, time.Since(start))
+	}
+}
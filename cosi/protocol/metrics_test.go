@@ -0,0 +1,84 @@
+package cosi
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/onet/v3"
+)
+
+// roundTracer records the rounds seen by the tracer.
+type roundTracer struct {
+	sync.Mutex
+	rounds map[onet.RoundID]bool
+}
+
+func (r *roundTracer) Phase(round onet.RoundID, node *onet.TreeNode, phase Phase, d time.Duration, err error) {
+	r.Lock()
+	r.rounds[round] = true
+	r.Unlock()
+}
+
+func (r *roundTracer) Child(round onet.RoundID, node, child *onet.TreeNode, phase Phase, d time.Duration) {
+}
+
+func TestCosi_Metrics(t *testing.T) {
+	metrics := NewMetrics()
+	SetTracer(metrics)
+	defer SetTracer(nil)
+
+	local := onet.NewLocalTest(tSuite)
+	defer local.CloseAll()
+	_, el, tree := local.GenBigTree(7, 7, 2, true)
+
+	done := make(chan []byte, 1)
+	p, err := local.CreateProtocol(Name, tree)
+	require.NoError(t, err)
+	root := p.(*CoSi)
+	root.Message = []byte("Hello World Cosi")
+	root.RegisterSignatureHook(func(sig []byte) { done <- sig })
+	require.NoError(t, root.Start())
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Could not get the signature in time")
+	}
+
+	// The nodes of the tree with 7 nodes and a branching factor of 2 are the
+	// root, 2 intermediate nodes and 4 leaves.
+	for i := 0; i < 100 && (metrics.PhaseLatency(PhaseChallenge).Count < 6 ||
+		metrics.PhaseLatency(PhaseResponse).Count < 3); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.Equal(t, 7, metrics.PhaseLatency(PhaseAnnouncement).Count)
+	require.Equal(t, 3, metrics.PhaseLatency(PhaseCommitment).Count)
+	require.Equal(t, 6, metrics.PhaseLatency(PhaseChallenge).Count)
+	require.Equal(t, 3, metrics.PhaseLatency(PhaseResponse).Count)
+	require.Equal(t, 0, metrics.PhaseLatency(PhaseResponse).Errors)
+	require.True(t, metrics.PhaseLatency(PhaseCommitment).Max > 0)
+
+	for _, si := range el.List[1:] {
+		require.Equal(t, 1, metrics.ChildLatency(si.String(), PhaseCommitment).Count)
+		require.Equal(t, 1, metrics.ChildLatency(si.String(), PhaseResponse).Count)
+	}
+	require.Equal(t, 0, metrics.ChildLatency(el.List[0].String(), PhaseCommitment).Count)
+
+	// A tracer given to the root only gets its phases, with the ID of the
+	// round.
+	SetTracer(nil)
+	tracer := &roundTracer{rounds: make(map[onet.RoundID]bool)}
+	p, err = local.CreateProtocol(Name, tree)
+	require.NoError(t, err)
+	root = p.(*CoSi)
+	root.Message = []byte("Hello again")
+	root.Tracer = tracer
+	root.RegisterSignatureHook(func(sig []byte) { done <- sig })
+	require.NoError(t, root.Start())
+	<-done
+	tracer.Lock()
+	require.True(t, tracer.rounds[root.Token().RoundID])
+	require.Equal(t, 1, len(tracer.rounds))
+	tracer.Unlock()
+}
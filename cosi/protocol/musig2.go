@@ -0,0 +1,465 @@
+package cosi
+
+import (
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/sign/eddsa"
+	"go.dedis.ch/kyber/v3/util/random"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/onet/v3/network"
+)
+
+// MuSig2Name can be used to reference the registered MuSig2 protocol.
+var MuSig2Name = "MuSig2"
+
+// muSigNonceExpiration is how long a node keeps the secret nonces of a
+// session that is not signed.
+const muSigNonceExpiration = 10 * time.Minute
+
+func init() {
+	onet.GlobalProtocolRegister(MuSig2Name, NewMuSig2Protocol)
+	network.RegisterMessages(&MuSigNonceRequest{}, &MuSigNonces{},
+		&MuSigSign{}, &MuSigResponse{})
+}
+
+// MuSig2 is a two-round Schnorr multi-signature protocol, following the
+// MuSig2 paper of Nick, Ruffing and Seurin:
+//   - Nonces: each node sends two nonces up the tree
+//   - Signature: the root sends the message with the aggregate nonces down
+//     the tree and each node sends its partial signature up the tree
+//
+// As the nonces don't depend on the message, each node sends fresh nonces
+// with its partial signature. A root given the NextNonces of the previous
+// round on the same tree skips the first round, so that a signature only
+// takes one round-trip instead of the two of CoSi.
+//
+// All the nodes have to sign. The final signature is a plain Ed25519
+// signature for the aggregate key of the roster, which is returned by
+// AggregateMuSigKey and can be verified with VerifyMuSigSignature or any
+// Ed25519 implementation.
+type MuSig2 struct {
+	// The node that represents us
+	*onet.TreeNodeInstance
+	// the message we want to sign typically given by the Root
+	Message []byte
+	// Nonces are the aggregate nonces of a previous round on the same tree.
+	// If they are given, the round starts directly with the signature.
+	Nonces *MuSigNonces
+	// NextNonces holds the aggregate nonces to use for the next round on
+	// the same tree, once the root sent the signature.
+	NextNonces *MuSigNonces
+	// Timeout is the time to wait for the messages of a child that is a
+	// leaf. It is multiplied by the height of the subtree for the other
+	// children.
+	Timeout time.Duration
+	// FinalSignature receives the Ed25519 signature at the root
+	FinalSignature chan []byte
+
+	start        chan bool
+	nonceRequest chan muSigChanNonceRequest
+	nonces       chan muSigChanNonces
+	sign         chan muSigChanSign
+	response     chan muSigChanResponse
+}
+
+// MuSigNonceRequest asks the nodes for the nonces of a session.
+type MuSigNonceRequest struct {
+	Session []byte
+	Timeout time.Duration
+}
+
+// MuSigNonces holds the two public nonces of a session, aggregated over a
+// subtree.
+type MuSigNonces struct {
+	Session []byte
+	R1      kyber.Point
+	R2      kyber.Point
+}
+
+// MuSigSign asks the nodes to sign the message with the nonces of a session,
+// and to send the nonces of the next session.
+type MuSigSign struct {
+	Message []byte
+	Nonces  MuSigNonces
+	Next    []byte
+	Timeout time.Duration
+}
+
+// MuSigResponse holds the partial signature of a subtree with its nonces for
+// the next session.
+type MuSigResponse struct {
+	S    kyber.Scalar
+	Next MuSigNonces
+}
+
+type muSigChanNonceRequest struct {
+	*onet.TreeNode
+	MuSigNonceRequest
+}
+
+type muSigChanNonces struct {
+	*onet.TreeNode
+	MuSigNonces
+}
+
+type muSigChanSign struct {
+	*onet.TreeNode
+	MuSigSign
+}
+
+type muSigChanResponse struct {
+	*onet.TreeNode
+	MuSigResponse
+}
+
+// muSigSecret holds the secret nonces of a session.
+type muSigSecret struct {
+	r1, r2  kyber.Scalar
+	created time.Time
+}
+
+// muSigSecrets holds the secret nonces of the nodes of this conode, by public
+// key and session. They are removed once used, so that a node never signs
+// twice with the same nonces.
+var muSigSecrets = struct {
+	sync.Mutex
+	m map[string]*muSigSecret
+}{m: make(map[string]*muSigSecret)}
+
+// NewMuSig2Protocol returns a MuSig2 with the node set with the right
+// channels.
+func NewMuSig2Protocol(node *onet.TreeNodeInstance) (onet.ProtocolInstance, error) {
+	m := &MuSig2{
+		TreeNodeInstance: node,
+		Timeout:          DefaultTimeout,
+		FinalSignature:   make(chan []byte, 1),
+		start:            make(chan bool, 1),
+	}
+	for _, c := range []interface{}{&m.nonceRequest, &m.nonces, &m.sign, &m.response} {
+		if err := node.RegisterChannel(c); err != nil {
+			return m, err
+		}
+	}
+	return m, nil
+}
+
+// Start starts the round at the root with the message given with
+// SigningMessage.
+func (m *MuSig2) Start() error {
+	if len(m.Message) == 0 {
+		return errors.New("no message to sign")
+	}
+	m.start <- true
+	return nil
+}
+
+// SigningMessage simply set the message to sign for this round
+func (m *MuSig2) SigningMessage(msg []byte) {
+	m.Message = msg
+	log.Lvlf2("%s Root will sign message %x", m.Name(), m.Message)
+}
+
+// Dispatch runs the nonce round if needed, then the signature round.
+func (m *MuSig2) Dispatch() error {
+	defer m.Done()
+
+	var sign *MuSigSign
+	if m.IsRoot() {
+		<-m.start
+		if m.Nonces == nil {
+			nonces, err := m.handleNonceRequest(&MuSigNonceRequest{
+				Session: newMuSigSession(),
+				Timeout: m.Timeout,
+			})
+			if err != nil {
+				return err
+			}
+			m.Nonces = nonces
+		}
+		sign = &MuSigSign{
+			Message: m.Message,
+			Nonces:  *m.Nonces,
+			Next:    newMuSigSession(),
+			Timeout: m.Timeout,
+		}
+	} else {
+		log.Lvl3(m.Name(), "Waiting for nonce request or signature")
+		select {
+		case req := <-m.nonceRequest:
+			if req.Timeout > 0 {
+				m.Timeout = req.Timeout
+			}
+			nonces, err := m.handleNonceRequest(&req.MuSigNonceRequest)
+			if err != nil {
+				return err
+			}
+			if err := m.SendToParent(nonces); err != nil {
+				return err
+			}
+			select {
+			case s := <-m.sign:
+				sign = &s.MuSigSign
+			case <-time.After(2 * m.subtreeTimeout(m.Root())):
+				return errors.New("timeout waiting for the message to sign")
+			}
+		case s := <-m.sign:
+			sign = &s.MuSigSign
+		}
+	}
+
+	resp, err := m.handleSign(sign)
+	if err != nil {
+		return err
+	}
+	if !m.IsRoot() {
+		return m.SendToParent(resp)
+	}
+
+	sig, err := m.signature(sign, resp.S)
+	if err != nil {
+		return err
+	}
+	next := resp.Next
+	m.NextNonces = &next
+	m.FinalSignature <- sig
+	return nil
+}
+
+// handleNonceRequest sends the request down the tree and returns the nonces
+// of the subtree.
+func (m *MuSig2) handleNonceRequest(req *MuSigNonceRequest) (*MuSigNonces, error) {
+	if !m.IsLeaf() {
+		if err := m.SendToChildren(req); err != nil {
+			return nil, err
+		}
+	}
+	nonces := m.newNonces(req.Session)
+	timeout := time.After(m.subtreeTimeout(m.TreeNode()))
+	for range m.Children() {
+		select {
+		case n := <-m.nonces:
+			nonces.R1.Add(nonces.R1, n.R1)
+			nonces.R2.Add(nonces.R2, n.R2)
+		case <-timeout:
+			return nil, errors.New("timeout waiting for the nonces")
+		}
+	}
+	return nonces, nil
+}
+
+// handleSign sends the message down the tree and returns the partial
+// signature of the subtree with its nonces for the next session.
+func (m *MuSig2) handleSign(sign *MuSigSign) (*MuSigResponse, error) {
+	m.Message = sign.Message
+	if sign.Timeout > 0 {
+		m.Timeout = sign.Timeout
+	}
+	if !m.IsLeaf() {
+		if err := m.SendToChildren(sign); err != nil {
+			return nil, err
+		}
+	}
+
+	s, err := m.partialSignature(sign)
+	if err != nil {
+		return nil, err
+	}
+	resp := &MuSigResponse{S: s, Next: *m.newNonces(sign.Next)}
+	timeout := time.After(m.subtreeTimeout(m.TreeNode()))
+	for range m.Children() {
+		select {
+		case r := <-m.response:
+			resp.S.Add(resp.S, r.S)
+			resp.Next.R1.Add(resp.Next.R1, r.Next.R1)
+			resp.Next.R2.Add(resp.Next.R2, r.Next.R2)
+		case <-timeout:
+			return nil, errors.New("timeout waiting for the partial signatures")
+		}
+	}
+	return resp, nil
+}
+
+// newNonces creates the secret nonces of the node for the session, and
+// returns the public ones.
+func (m *MuSig2) newNonces(session []byte) *MuSigNonces {
+	suite := m.Suite()
+	secret := &muSigSecret{
+		r1:      suite.Scalar().Pick(suite.RandomStream()),
+		r2:      suite.Scalar().Pick(suite.RandomStream()),
+		created: time.Now(),
+	}
+	muSigSecrets.Lock()
+	for k, s := range muSigSecrets.m {
+		if time.Since(s.created) > muSigNonceExpiration {
+			delete(muSigSecrets.m, k)
+		}
+	}
+	muSigSecrets.m[m.secretKey(session)] = secret
+	muSigSecrets.Unlock()
+	return &MuSigNonces{
+		Session: session,
+		R1:      suite.Point().Mul(secret.r1, nil),
+		R2:      suite.Point().Mul(secret.r2, nil),
+	}
+}
+
+// partialSignature returns r1 + b*r2 + c*a*x, where a is the coefficient of
+// the key of the node. The secret nonces of the session are removed.
+func (m *MuSig2) partialSignature(sign *MuSigSign) (kyber.Scalar, error) {
+	key := m.secretKey(sign.Nonces.Session)
+	muSigSecrets.Lock()
+	secret, ok := muSigSecrets.m[key]
+	delete(muSigSecrets.m, key)
+	muSigSecrets.Unlock()
+	if !ok {
+		return nil, errors.New("unknown or already used nonces")
+	}
+
+	suite := m.Suite()
+	coefs, agg, err := muSigKeyAggregation(suite, m.Publics())
+	if err != nil {
+		return nil, err
+	}
+	b, r, err := muSigNonce(suite, agg, &sign.Nonces, sign.Message)
+	if err != nil {
+		return nil, err
+	}
+	c, err := muSigChallenge(suite, r, agg, sign.Message)
+	if err != nil {
+		return nil, err
+	}
+	s := suite.Scalar().Mul(b, secret.r2)
+	s.Add(s, secret.r1)
+	cax := suite.Scalar().Mul(c, coefs[m.TreeNode().RosterIndex])
+	cax.Mul(cax, m.Private())
+	return s.Add(s, cax), nil
+}
+
+// signature returns the Ed25519 signature made of the aggregate nonce and the
+// sum of the partial signatures, after verifying it.
+func (m *MuSig2) signature(sign *MuSigSign, s kyber.Scalar) ([]byte, error) {
+	suite := m.Suite()
+	_, agg, err := muSigKeyAggregation(suite, m.Publics())
+	if err != nil {
+		return nil, err
+	}
+	_, r, err := muSigNonce(suite, agg, &sign.Nonces, sign.Message)
+	if err != nil {
+		return nil, err
+	}
+	rBuf, err := r.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	sBuf, err := s.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	sig := append(rBuf, sBuf...)
+	if err := eddsa.Verify(agg, sign.Message, sig); err != nil {
+		return nil, fmt.Errorf("invalid signature: %v", err)
+	}
+	return sig, nil
+}
+
+// secretKey returns the key of the secret nonces of the node for the
+// session, as several nodes can run on the same conode.
+func (m *MuSig2) secretKey(session []byte) string {
+	return m.Public().String() + string(session)
+}
+
+// subtreeTimeout returns the time to wait for the messages of the subtree of
+// the node.
+func (m *MuSig2) subtreeTimeout(n *onet.TreeNode) time.Duration {
+	return m.Timeout * time.Duration(subtreeHeight(n))
+}
+
+func newMuSigSession() []byte {
+	session := make([]byte, 32)
+	random.Bytes(session, random.New())
+	return session
+}
+
+// AggregateMuSigKey returns the aggregate key of the public keys, which
+// verifies the signatures of MuSig2 as Ed25519 signatures.
+func AggregateMuSigKey(suite kyber.Group, publics []kyber.Point) (kyber.Point, error) {
+	_, agg, err := muSigKeyAggregation(suite, publics)
+	return agg, err
+}
+
+// VerifyMuSigSignature verifies a signature of MuSig2 for all the public
+// keys, which have to be given in the order of the roster.
+func VerifyMuSigSignature(suite kyber.Group, publics []kyber.Point, msg, sig []byte) error {
+	agg, err := AggregateMuSigKey(suite, publics)
+	if err != nil {
+		return err
+	}
+	return eddsa.Verify(agg, msg, sig)
+}
+
+// muSigKeyAggregation returns the coefficient of each public key and the
+// aggregate key, which is the sum of the keys multiplied by their
+// coefficient.
+func muSigKeyAggregation(suite kyber.Group, publics []kyber.Point) ([]kyber.Scalar, kyber.Point, error) {
+	if len(publics) == 0 {
+		return nil, nil, errors.New("no public keys given")
+	}
+	list := sha512.New()
+	for _, p := range publics {
+		if _, err := p.MarshalTo(list); err != nil {
+			return nil, nil, err
+		}
+	}
+	l := list.Sum(nil)
+
+	coefs := make([]kyber.Scalar, len(publics))
+	agg := suite.Point().Null()
+	for i, p := range publics {
+		h := sha512.New()
+		h.Write([]byte("MuSig2/agg"))
+		h.Write(l)
+		if _, err := p.MarshalTo(h); err != nil {
+			return nil, nil, err
+		}
+		coefs[i] = suite.Scalar().SetBytes(h.Sum(nil))
+		agg.Add(agg, suite.Point().Mul(coefs[i], p))
+	}
+	return coefs, agg, nil
+}
+
+// muSigNonce returns the coefficient b of the second nonce and the final
+// nonce R1 + b*R2.
+func muSigNonce(suite kyber.Group, agg kyber.Point, n *MuSigNonces, msg []byte) (kyber.Scalar, kyber.Point, error) {
+	if n.R1 == nil || n.R2 == nil {
+		return nil, nil, errors.New("missing nonces")
+	}
+	h := sha512.New()
+	h.Write([]byte("MuSig2/non"))
+	for _, p := range []kyber.Point{agg, n.R1, n.R2} {
+		if _, err := p.MarshalTo(h); err != nil {
+			return nil, nil, err
+		}
+	}
+	h.Write(msg)
+	b := suite.Scalar().SetBytes(h.Sum(nil))
+	r := suite.Point().Mul(b, n.R2)
+	return b, r.Add(r, n.R1), nil
+}
+
+// muSigChallenge returns the Ed25519 challenge H(R || A || msg).
+func muSigChallenge(suite kyber.Group, r, agg kyber.Point, msg []byte) (kyber.Scalar, error) {
+	h := sha512.New()
+	for _, p := range []kyber.Point{r, agg} {
+		if _, err := p.MarshalTo(h); err != nil {
+			return nil, err
+		}
+	}
+	h.Write(msg)
+	return suite.Scalar().SetBytes(h.Sum(nil)), nil
+}
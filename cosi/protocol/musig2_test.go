@@ -0,0 +1,63 @@
+package cosi
+
+import (
+	"crypto/ed25519"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/log"
+)
+
+func TestMuSig2(t *testing.T) {
+	for _, nbrHosts := range []int{1, 3, 13} {
+		log.Lvl2("Running MuSig2 with", nbrHosts, "hosts")
+		local := onet.NewLocalTest(tSuite)
+		_, el, tree := local.GenBigTree(nbrHosts, nbrHosts, 3, true)
+		publics := el.Publics()
+		agg, err := AggregateMuSigKey(tSuite, publics)
+		require.NoError(t, err)
+		aggBuf, err := agg.MarshalBinary()
+		require.NoError(t, err)
+
+		runMuSig2 := func(msg []byte, nonces *MuSigNonces) (*MuSig2, []byte) {
+			p, err := local.CreateProtocol(MuSig2Name, tree)
+			require.NoError(t, err)
+			root := p.(*MuSig2)
+			root.SigningMessage(msg)
+			root.Nonces = nonces
+			root.Timeout = 500 * time.Millisecond
+			require.NoError(t, root.Start())
+			select {
+			case sig := <-root.FinalSignature:
+				return root, sig
+			case <-time.After(time.Second * 2):
+				return root, nil
+			}
+		}
+
+		// The first round asks for the nonces.
+		msg := []byte("Hello World MuSig2")
+		root, sig := runMuSig2(msg, nil)
+		require.NotNil(t, sig)
+		require.NoError(t, VerifyMuSigSignature(tSuite, publics, msg, sig))
+		require.Error(t, VerifyMuSigSignature(tSuite, publics, []byte("other"), sig))
+		require.True(t, ed25519.Verify(ed25519.PublicKey(aggBuf), msg, sig))
+		require.NotNil(t, root.NextNonces)
+
+		// The next one uses the nonces sent with the signature.
+		msg2 := []byte("Hello again")
+		root2, sig := runMuSig2(msg2, root.NextNonces)
+		require.NotNil(t, sig)
+		require.NoError(t, VerifyMuSigSignature(tSuite, publics, msg2, sig))
+		require.True(t, ed25519.Verify(ed25519.PublicKey(aggBuf), msg2, sig))
+		require.NotNil(t, root2.NextNonces)
+
+		// The nonces can't be used twice.
+		_, sig = runMuSig2([]byte("reuse"), root.NextNonces)
+		require.Nil(t, sig)
+
+		local.CloseAll()
+	}
+}
@@ -2,6 +2,7 @@ package cosi
 
 import (
 	"errors"
+	"time"
 
 	"go.dedis.ch/kyber/v3"
 	"go.dedis.ch/onet/v3"
@@ -116,11 +117,21 @@ func (p *MessageProxy) Name() string {
 
 // Announcement is sent down the tree to start the collective signature.
 type Announcement struct {
+	// Timeout is the time to wait for the messages of a leaf.
+	Timeout time.Duration
+	// Message, Data and Auth are only sent if the nodes have to verify the
+	// message or its client.
+	Message []byte
+	Data    []byte
+	Auth    []byte
 }
 
 // Commitment of all nodes, aggregated over all children.
 type Commitment struct {
 	Comm kyber.Point
+	// Exceptions holds the roster indexes of the nodes of the subtree that
+	// didn't commit.
+	Exceptions []int
 }
 
 // Challenge is the challenge against the aggregate commitment.
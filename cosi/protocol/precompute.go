@@ -0,0 +1,107 @@
+package cosi
+
+import (
+	"sync"
+
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/util/random"
+)
+
+// Commitments computes the secrets and commitments of the nodes between the
+// rounds, so that a node only has to aggregate the commitments of its
+// children when it gets the announcement. A pair is removed when it is used,
+// so that it is never used twice.
+type Commitments struct {
+	suite    kyber.Group
+	pairs    chan commitmentPair
+	stop     chan bool
+	stopped  chan bool
+	stopOnce sync.Once
+}
+
+type commitmentPair struct {
+	secret     kyber.Scalar
+	commitment kyber.Point
+}
+
+// NewCommitments returns a pool of size pairs, which is filled in the
+// background until Stop is called.
+func NewCommitments(suite kyber.Group, size int) *Commitments {
+	cs := &Commitments{
+		suite:   suite,
+		pairs:   make(chan commitmentPair, size),
+		stop:    make(chan bool),
+		stopped: make(chan bool),
+	}
+	go cs.fill()
+	return cs
+}
+
+// fill computes pairs as long as the pool is not full.
+func (cs *Commitments) fill() {
+	defer close(cs.stopped)
+	for {
+		select {
+		case cs.pairs <- cs.newPair():
+		case <-cs.stop:
+			return
+		}
+	}
+}
+
+func (cs *Commitments) newPair() commitmentPair {
+	secret := cs.suite.Scalar().Pick(random.New())
+	return commitmentPair{
+		secret:     secret,
+		commitment: cs.suite.Point().Mul(secret, nil),
+	}
+}
+
+// Get returns a secret with its commitment, which is computed now if the
+// pool is empty.
+func (cs *Commitments) Get() (kyber.Scalar, kyber.Point) {
+	var p commitmentPair
+	select {
+	case p = <-cs.pairs:
+	default:
+		p = cs.newPair()
+	}
+	return p.secret, p.commitment
+}
+
+// Len returns the number of pairs in the pool.
+func (cs *Commitments) Len() int {
+	return len(cs.pairs)
+}
+
+// Stop stops filling the pool, and returns once no more pairs are added.
+func (cs *Commitments) Stop() {
+	cs.stopOnce.Do(func() { close(cs.stop) })
+	<-cs.stopped
+}
+
+var defaultCommitments struct {
+	sync.Mutex
+	commitments *Commitments
+}
+
+// SetCommitments sets the pool used by the nodes of this conode for the rounds
+// started after the call. A nil pool makes the nodes compute their commitment
+// during the round.
+func SetCommitments(cs *Commitments) {
+	defaultCommitments.Lock()
+	defaultCommitments.commitments = cs
+	defaultCommitments.Unlock()
+}
+
+// getCommitments returns the pool set with SetCommitments, if it is for the
+// suite of the node.
+func getCommitments(suite kyber.Group) *Commitments {
+	defaultCommitments.Lock()
+	defer defaultCommitments.Unlock()
+	cs := defaultCommitments.commitments
+	if cs == nil || cs.suite.String() != suite.String() {
+		return nil
+	}
+	return cs
+}
@@ -0,0 +1,54 @@
+package cosi
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/onet/v3"
+)
+
+func TestCommitments(t *testing.T) {
+	pool := NewCommitments(tSuite, 20)
+	for i := 0; i < 100 && pool.Len() < 20; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.Equal(t, 20, pool.Len())
+	pool.Stop()
+
+	secret, commitment := pool.Get()
+	require.True(t, tSuite.Point().Mul(secret, nil).Equal(commitment))
+	secret2, _ := pool.Get()
+	require.False(t, secret.Equal(secret2))
+	require.Equal(t, 18, pool.Len())
+
+	// The nodes of a round take their commitment from the pool.
+	SetCommitments(pool)
+	defer SetCommitments(nil)
+	local := onet.NewLocalTest(tSuite)
+	defer local.CloseAll()
+	_, el, tree := local.GenBigTree(7, 7, 2, true)
+	msg := []byte("Hello World Cosi")
+
+	p, err := local.CreateProtocol(Name, tree)
+	require.NoError(t, err)
+	root := p.(*CoSi)
+	root.Message = msg
+	sigs := make(chan []byte, 1)
+	root.RegisterSignatureHook(func(sig []byte) { sigs <- sig })
+	go root.Start()
+	select {
+	case sig := <-sigs:
+		require.NoError(t, VerifySignature(tSuite, el.Publics(), msg, sig))
+		require.Equal(t, 11, pool.Len())
+	case <-time.After(time.Second * 2):
+		t.Fatal("Could not get the signature in time")
+	}
+
+	// An empty pool computes the commitments on demand.
+	for pool.Len() > 0 {
+		pool.Get()
+	}
+	secret, commitment = pool.Get()
+	require.True(t, tSuite.Point().Mul(secret, nil).Equal(commitment))
+}
@@ -0,0 +1,81 @@
+package cosi
+
+import (
+	"go.dedis.ch/cothority/v3/cosi/crypto"
+	"go.dedis.ch/kyber/v3"
+)
+
+// Signature is the complete result of a round, given to the DoneHook, so
+// that the callers don't have to decode the signature.
+type Signature struct {
+	// Message is the signed message
+	Message []byte
+	// Commitment is the aggregate commitment of the nodes that signed
+	Commitment kyber.Point
+	// Challenge is the hash of the commitment, the aggregate public key and
+	// the message
+	Challenge kyber.Scalar
+	// Response is the aggregate response of the nodes that signed
+	Response kyber.Scalar
+	// AggregatePublic is the aggregate public key of the nodes that signed
+	AggregatePublic kyber.Point
+	// Mask has the bit of each node of the roster that didn't sign set
+	Mask []byte
+	// Participants holds the roster indexes of the nodes that signed
+	Participants []int
+}
+
+// Bytes returns the signature in the format given to the SignatureHook and
+// checked by VerifySignature: Commitment || Response || Mask.
+func (s *Signature) Bytes() ([]byte, error) {
+	comm, err := s.Commitment.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.Response.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return append(append(comm, resp...), s.Mask...), nil
+}
+
+// Verify checks the signature against the public keys of the roster.
+func (s *Signature) Verify(suite kyber.Group, publics []kyber.Point) error {
+	sig, err := s.Bytes()
+	if err != nil {
+		return err
+	}
+	return crypto.VerifySignature(suite, publics, s.Message, sig)
+}
+
+// EdDSA returns the signature in the Ed25519 format R || s, with the
+// aggregate public key of the nodes that signed, which verifies it.
+func (s *Signature) EdDSA() (public, sig []byte, err error) {
+	public, err = s.AggregatePublic.MarshalBinary()
+	if err != nil {
+		return nil, nil, err
+	}
+	sig, err = s.Bytes()
+	if err != nil {
+		return nil, nil, err
+	}
+	return public, sig[:len(sig)-len(s.Mask)], nil
+}
+
+// signature returns the complete signature of the root.
+func (c *CoSi) signature() *Signature {
+	sig := &Signature{
+		Message:         c.Message,
+		Commitment:      c.cosi.AggregateCommitment().Clone(),
+		Challenge:       c.cosi.GetChallenge().Clone(),
+		Response:        c.cosi.AggregateResponse().Clone(),
+		AggregatePublic: c.cosi.Aggregate().Clone(),
+		Mask:            c.cosi.Mask(),
+	}
+	for i := range c.Roster().List {
+		if !c.cosi.MaskBit(i) {
+			sig.Participants = append(sig.Participants, i)
+		}
+	}
+	return sig
+}
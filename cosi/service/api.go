@@ -2,8 +2,10 @@ package service
 
 import (
 	"errors"
+	"time"
 
 	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/darc"
 	"go.dedis.ch/onet/v3"
 	"go.dedis.ch/onet/v3/log"
 )
@@ -12,6 +14,10 @@ import (
 // service
 type Client struct {
 	*onet.Client
+	// Signer or Token authenticate the requests for signatures, if the
+	// conodes restrict their clients.
+	Signer *darc.Signer
+	Token  []byte
 }
 
 // NewClient instantiates a new cosi.Client
@@ -22,17 +28,106 @@ func NewClient() *Client {
 // SignatureRequest sends a CoSi sign request to the Cothority defined by the given
 // Roster
 func (c *Client) SignatureRequest(r *onet.Roster, msg []byte) (*SignatureResponse, error) {
+	return c.SignatureRequestWithTopology(r, msg, TreeTopology{})
+}
+
+// SignatureRequestWithTopology is like SignatureRequest, but the conodes use
+// a tree of the given topology.
+func (c *Client) SignatureRequestWithTopology(r *onet.Roster, msg []byte, topology TreeTopology) (*SignatureResponse, error) {
+	if len(r.List) == 0 {
+		return nil, errors.New("Got an empty roster-list")
+	}
+	auth, err := c.authentication(msg)
+	if err != nil {
+		return nil, err
+	}
 	serviceReq := &SignatureRequest{
+		Roster:   r,
+		Message:  msg,
+		Topology: topology,
+		Auth:     auth,
+	}
+	dst := r.List[0]
+	log.Lvl4("Sending message to", dst)
+	reply := &SignatureResponse{}
+	err = c.SendProtobuf(dst, serviceReq, reply)
+	if err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+// AsyncSignatureRequest asks the first conode of the roster to sign the
+// message in the background. It returns the ID to give to SignatureStatus.
+func (c *Client) AsyncSignatureRequest(r *onet.Roster, msg []byte) ([]byte, error) {
+	if len(r.List) == 0 {
+		return nil, errors.New("Got an empty roster-list")
+	}
+	auth, err := c.authentication(msg)
+	if err != nil {
+		return nil, err
+	}
+	reply := &AsyncSignatureReply{}
+	err = c.SendProtobuf(r.List[0], &AsyncSignatureRequest{
 		Roster:  r,
 		Message: msg,
+		Auth:    auth,
+	}, reply)
+	if err != nil {
+		return nil, err
 	}
+	return reply.RequestID, nil
+}
+
+// SignatureStatus asks the first conode of the roster for the state of an
+// asynchronous request.
+func (c *Client) SignatureStatus(r *onet.Roster, id []byte) (*SignatureStatusReply, error) {
 	if len(r.List) == 0 {
 		return nil, errors.New("Got an empty roster-list")
 	}
-	dst := r.List[0]
-	log.Lvl4("Sending message to", dst)
-	reply := &SignatureResponse{}
-	err := c.SendProtobuf(dst, serviceReq, reply)
+	reply := &SignatureStatusReply{}
+	err := c.SendProtobuf(r.List[0], &SignatureStatus{RequestID: id}, reply)
+	if err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+// WaitSignature polls the first conode of the roster every interval until the
+// asynchronous request is done, and returns its signature.
+func (c *Client) WaitSignature(r *onet.Roster, id []byte, interval time.Duration) (*SignatureResponse, error) {
+	for {
+		reply, err := c.SignatureStatus(r, id)
+		if err != nil {
+			return nil, err
+		}
+		if reply.Done {
+			if reply.Error != "" {
+				return nil, errors.New(reply.Error)
+			}
+			return reply.Response, nil
+		}
+		time.Sleep(interval)
+	}
+}
+
+// BatchSignatureRequest asks the first conode of the roster to sign the
+// message in a batch with the messages of other clients. The reply can be
+// verified with VerifyBatchSignature.
+func (c *Client) BatchSignatureRequest(r *onet.Roster, msg []byte) (*BatchSignatureResponse, error) {
+	if len(r.List) == 0 {
+		return nil, errors.New("Got an empty roster-list")
+	}
+	auth, err := c.authentication(msg)
+	if err != nil {
+		return nil, err
+	}
+	reply := &BatchSignatureResponse{}
+	err = c.SendProtobuf(r.List[0], &BatchSignatureRequest{
+		Roster:  r,
+		Message: msg,
+		Auth:    auth,
+	}, reply)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,310 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/protobuf"
+)
+
+// Authentication identifies the client of a signature request. The client
+// gives either a token known to the conode, or a darc identity with its
+// signature on the message to sign and the time of the request.
+type Authentication struct {
+	Token    []byte
+	Identity *darc.Identity
+	// Timestamp is the time of the signature in nanoseconds since the
+	// epoch. The conodes refuse signatures older or newer than authWindow.
+	Timestamp int64
+	Signature []byte
+}
+
+// authWindow is how long a signature of a client is valid.
+const authWindow = time.Minute
+
+// NewAuthentication returns the authentication of the signer for the
+// request of a signature of the message.
+func NewAuthentication(s darc.Signer, msg []byte) (*Authentication, error) {
+	ts := time.Now().UnixNano()
+	sig, err := s.Sign(authMessage(msg, ts))
+	if err != nil {
+		return nil, err
+	}
+	id := s.Identity()
+	return &Authentication{Identity: &id, Timestamp: ts, Signature: sig}, nil
+}
+
+// authentication returns the authentication of the client for the request
+// of a signature of the message, if it has a token or a signer.
+func (c *Client) authentication(msg []byte) (*Authentication, error) {
+	if len(c.Token) > 0 {
+		return &Authentication{Token: c.Token}, nil
+	}
+	if c.Signer != nil {
+		return NewAuthentication(*c.Signer, msg)
+	}
+	return nil, nil
+}
+
+// authMessage returns what a client signs to ask for the signature of the
+// message at the given time.
+func authMessage(msg []byte, timestamp int64) []byte {
+	h := sha256.New()
+	h.Write([]byte("cosi authentication"))
+	tsBuf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(tsBuf, uint64(timestamp))
+	h.Write(tsBuf)
+	h.Write(msg)
+	return h.Sum(nil)
+}
+
+// AccessControl restricts the clients of a conode, so that it is not a free
+// signing oracle. If Identities or Tokens are given, only these clients can
+// ask for signatures. If Rate is positive, each client can ask for at most
+// Rate signatures every Period. The conode can't tell apart the clients
+// without authentication, so they share the same limit.
+type AccessControl struct {
+	Identities []darc.Identity
+	Tokens     [][]byte
+	Rate       int
+	Period     time.Duration
+}
+
+// accessControl holds the access control of the service with the requests
+// of the clients in the current period, and the signatures of the clients
+// that are still valid.
+type accessControl struct {
+	sync.Mutex
+	AccessControl
+	windows map[string]*rateWindow
+	seen    map[string]time.Time
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+// SetAccessControl sets the access control of the requests for signatures.
+// A nil access control lets anybody ask for signatures. A rate needs a
+// positive period.
+func (cs *CoSi) SetAccessControl(ac *AccessControl) error {
+	if ac == nil {
+		ac = &AccessControl{}
+	}
+	if ac.Rate > 0 && ac.Period <= 0 {
+		return errors.New("the rate limit needs a positive period")
+	}
+	cs.access.Lock()
+	defer cs.access.Unlock()
+	cs.access.AccessControl = *ac
+	cs.access.windows = make(map[string]*rateWindow)
+	cs.access.seen = make(map[string]time.Time)
+	return nil
+}
+
+// authorize checks that the client can ask for the signature of the
+// message, and counts the request in its rate. A signature of the client
+// is only counted once, so that whoever sees a request can't use up the
+// rate of the client by replaying it.
+func (cs *CoSi) authorize(msg []byte, auth *Authentication) error {
+	cs.access.Lock()
+	defer cs.access.Unlock()
+	now := time.Now()
+	client, err := cs.access.client(msg, auth, now)
+	if err != nil {
+		return err
+	}
+	if client != "" && auth.Identity != nil && cs.access.replayed(auth, now) {
+		return nil
+	}
+	return cs.access.count(client)
+}
+
+// roundAuth is sent with the announcement of a round, for each node to check
+// the clients of the round against its own access control. The round signs
+// either the message of a client, or the root of the Merkle tree of the
+// messages of a batch.
+type roundAuth struct {
+	Messages [][]byte
+	Auths    []Authentication
+	Batch    bool
+}
+
+// clientAuth returns the authentication of a round signing the message of
+// one client.
+func clientAuth(msg []byte, auth *Authentication) *roundAuth {
+	return &roundAuth{Messages: [][]byte{msg}, Auths: []Authentication{authOrEmpty(auth)}}
+}
+
+// authOrEmpty returns the authentication, or an empty one for a client
+// without authentication, as the list of a round can't hold nil values.
+func authOrEmpty(auth *Authentication) Authentication {
+	if auth == nil {
+		return Authentication{}
+	}
+	return *auth
+}
+
+// authorizeRound checks that the clients of a round the conode is asked to
+// sign can ask it for the signature of the message, so that another node
+// can't use it as a signing oracle by starting the round itself.
+func (cs *CoSi) authorizeRound(msg, buf []byte) error {
+	if len(buf) == 0 {
+		return cs.authorize(msg, nil)
+	}
+	ra := &roundAuth{}
+	if err := protobuf.Decode(buf, ra); err != nil {
+		return fmt.Errorf("invalid authentication of the round: %v", err)
+	}
+	if len(ra.Messages) == 0 || len(ra.Messages) != len(ra.Auths) {
+		return errors.New("invalid authentication of the round")
+	}
+	signed := ra.Messages[0]
+	if ra.Batch {
+		signed = merkleLevels(ra.Messages)[0][0]
+	} else if len(ra.Messages) != 1 {
+		return errors.New("invalid authentication of the round")
+	}
+	if !bytes.Equal(signed, msg) {
+		return errors.New("authentication is not for the message of the round")
+	}
+	for i := range ra.Messages {
+		if err := cs.authorize(ra.Messages[i], &ra.Auths[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// client returns the name of the client given by the authentication, or an
+// empty name if the conode is open to anybody.
+func (ac *accessControl) client(msg []byte, auth *Authentication, now time.Time) (string, error) {
+	if len(ac.Identities) == 0 && len(ac.Tokens) == 0 {
+		return "", nil
+	}
+	if auth == nil {
+		return "", errors.New("authentication required")
+	}
+	if len(auth.Token) > 0 {
+		for _, t := range ac.Tokens {
+			if subtle.ConstantTimeCompare(t, auth.Token) == 1 {
+				h := sha256.Sum256(t)
+				return "token:" + hex.EncodeToString(h[:]), nil
+			}
+		}
+		return "", errors.New("unknown token")
+	}
+	if auth.Identity == nil {
+		return "", errors.New("authentication without token or identity")
+	}
+	for _, id := range ac.Identities {
+		if !id.Equal(auth.Identity) {
+			continue
+		}
+		if err := id.Verify(authMessage(msg, auth.Timestamp), auth.Signature); err != nil {
+			return "", fmt.Errorf("invalid authentication: %v", err)
+		}
+		if age := now.Sub(time.Unix(0, auth.Timestamp)); age > authWindow || age < -authWindow {
+			return "", errors.New("authentication is too old or in the future")
+		}
+		return id.String(), nil
+	}
+	return "", errors.New("identity is not allowed")
+}
+
+// replayed returns whether the signature of the authentication has already
+// been seen, and remembers it until it expires.
+func (ac *accessControl) replayed(auth *Authentication, now time.Time) bool {
+	for k, expiry := range ac.seen {
+		if now.After(expiry) {
+			delete(ac.seen, k)
+		}
+	}
+	key := auth.Identity.String() + ":" + hex.EncodeToString(auth.Signature)
+	if _, ok := ac.seen[key]; ok {
+		return true
+	}
+	ac.seen[key] = time.Unix(0, auth.Timestamp).Add(authWindow)
+	return false
+}
+
+// count adds a request of the client to its current period, and returns an
+// error if the client asked for too many signatures.
+func (ac *accessControl) count(client string) error {
+	if ac.Rate <= 0 {
+		return nil
+	}
+	now := time.Now()
+	for k, w := range ac.windows {
+		if now.Sub(w.start) >= ac.Period {
+			delete(ac.windows, k)
+		}
+	}
+	w, ok := ac.windows[client]
+	if !ok {
+		w = &rateWindow{start: now}
+		ac.windows[client] = w
+	}
+	if w.count >= ac.Rate {
+		return fmt.Errorf("rate limit of %d signatures every %v exceeded", ac.Rate, ac.Period)
+	}
+	w.count++
+	return nil
+}
+
+// accessControlFromEnv returns the access control given by the environment:
+// COTHORITY_COSI_AUTH_IDENTITIES holds the comma-separated darc identities
+// and COTHORITY_COSI_AUTH_TOKENS the comma-separated tokens of the clients,
+// and COTHORITY_COSI_RATE_LIMIT is the rate of each client, like "10/1m".
+func accessControlFromEnv() (*AccessControl, error) {
+	ac := &AccessControl{}
+	for _, s := range splitEnv("COTHORITY_COSI_AUTH_IDENTITIES") {
+		id, err := darc.ParseIdentity(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid COTHORITY_COSI_AUTH_IDENTITIES: %v", err)
+		}
+		ac.Identities = append(ac.Identities, id)
+	}
+	for _, s := range splitEnv("COTHORITY_COSI_AUTH_TOKENS") {
+		ac.Tokens = append(ac.Tokens, []byte(s))
+	}
+	if rate := os.Getenv("COTHORITY_COSI_RATE_LIMIT"); rate != "" {
+		parts := strings.SplitN(rate, "/", 2)
+		if len(parts) != 2 {
+			return nil, errors.New("COTHORITY_COSI_RATE_LIMIT must be like 10/1m")
+		}
+		n, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid COTHORITY_COSI_RATE_LIMIT: %v", err)
+		}
+		d, err := time.ParseDuration(parts[1])
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid COTHORITY_COSI_RATE_LIMIT period: %s", parts[1])
+		}
+		ac.Rate = n
+		ac.Period = d
+	}
+	return ac, nil
+}
+
+func splitEnv(name string) []string {
+	var list []string
+	for _, s := range strings.Split(os.Getenv(name), ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			list = append(list, s)
+		}
+	}
+	return list
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/cosi/crypto"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/protobuf"
+)
+
+func TestAccessControl(t *testing.T) {
+	signer := darc.NewSignerEd25519(nil, nil)
+	other := darc.NewSignerEd25519(nil, nil)
+	cs := &CoSi{}
+	require.NoError(t, cs.SetAccessControl(&AccessControl{
+		Identities: []darc.Identity{signer.Identity()},
+		Tokens:     [][]byte{[]byte("secret")},
+		Rate:       2,
+		Period:     100 * time.Millisecond,
+	}))
+	msg := []byte("message")
+
+	require.Error(t, cs.authorize(msg, nil))
+	require.Error(t, cs.authorize(msg, &Authentication{Token: []byte("guess")}))
+	auth, err := NewAuthentication(other, msg)
+	require.NoError(t, err)
+	require.Error(t, cs.authorize(msg, auth))
+
+	// The signature is on the message of the request.
+	auth, err = NewAuthentication(signer, msg)
+	require.NoError(t, err)
+	require.Error(t, cs.authorize([]byte("other message"), auth))
+
+	// Each client has its own rate, and replaying an authentication
+	// doesn't count against it.
+	newAuth := func() *Authentication {
+		a, err := NewAuthentication(signer, msg)
+		require.NoError(t, err)
+		return a
+	}
+	require.NoError(t, cs.authorize(msg, auth))
+	require.NoError(t, cs.authorize(msg, auth))
+	require.NoError(t, cs.authorize(msg, newAuth()))
+	require.Error(t, cs.authorize(msg, newAuth()))
+	token := &Authentication{Token: []byte("secret")}
+	require.NoError(t, cs.authorize(msg, token))
+	time.Sleep(150 * time.Millisecond)
+	require.NoError(t, cs.authorize(msg, newAuth()))
+
+	// The signature covers the time of the request, which must be recent.
+	old := time.Now().Add(-2 * authWindow).UnixNano()
+	sig, err := signer.Sign(authMessage(msg, old))
+	require.NoError(t, err)
+	oldAuth := &Authentication{Identity: auth.Identity, Timestamp: old, Signature: sig}
+	require.Error(t, cs.authorize(msg, oldAuth))
+	oldAuth.Timestamp = time.Now().UnixNano()
+	require.Error(t, cs.authorize(msg, oldAuth))
+
+	// Without identities nor tokens, everybody shares the rate.
+	require.NoError(t, cs.SetAccessControl(&AccessControl{Rate: 1, Period: time.Minute}))
+	require.NoError(t, cs.authorize(msg, nil))
+	require.Error(t, cs.authorize(msg, auth))
+	require.NoError(t, cs.SetAccessControl(nil))
+	require.NoError(t, cs.authorize(msg, nil))
+
+	// A rate needs a period, else it would never be enforced.
+	require.Error(t, cs.SetAccessControl(&AccessControl{Rate: 1}))
+	require.Error(t, cs.SetAccessControl(&AccessControl{Rate: 1, Period: -time.Second}))
+}
+
+func TestAccessControl_Round(t *testing.T) {
+	signer := darc.NewSignerEd25519(nil, nil)
+	cs := &CoSi{}
+	require.NoError(t, cs.SetAccessControl(&AccessControl{
+		Identities: []darc.Identity{signer.Identity()},
+	}))
+	msg := []byte("message")
+	auth, err := NewAuthentication(signer, msg)
+	require.NoError(t, err)
+
+	require.Error(t, cs.authorizeRound(msg, nil))
+	buf, err := protobuf.Encode(clientAuth(msg, auth))
+	require.NoError(t, err)
+	require.NoError(t, cs.authorizeRound(msg, buf))
+	// The authentication can't be used for another message.
+	require.Error(t, cs.authorizeRound([]byte("other message"), buf))
+	buf, err = protobuf.Encode(clientAuth(msg, nil))
+	require.NoError(t, err)
+	require.Error(t, cs.authorizeRound(msg, buf))
+
+	// A batch is checked against the root of its messages.
+	batch := &roundAuth{
+		Messages: [][]byte{msg, msg},
+		Auths:    []Authentication{*auth, *auth},
+		Batch:    true,
+	}
+	buf, err = protobuf.Encode(batch)
+	require.NoError(t, err)
+	require.Error(t, cs.authorizeRound(msg, buf))
+	require.NoError(t, cs.authorizeRound(merkleLevels(batch.Messages)[0][0], buf))
+	batch.Auths[1] = Authentication{}
+	buf, err = protobuf.Encode(batch)
+	require.NoError(t, err)
+	require.Error(t, cs.authorizeRound(merkleLevels(batch.Messages)[0][0], buf))
+}
+
+func TestAccessControlFromEnv(t *testing.T) {
+	signer := darc.NewSignerEd25519(nil, nil)
+	os.Setenv("COTHORITY_COSI_AUTH_IDENTITIES", signer.Identity().String())
+	os.Setenv("COTHORITY_COSI_AUTH_TOKENS", "one, two")
+	os.Setenv("COTHORITY_COSI_RATE_LIMIT", "10/1m")
+	defer os.Unsetenv("COTHORITY_COSI_AUTH_IDENTITIES")
+	defer os.Unsetenv("COTHORITY_COSI_AUTH_TOKENS")
+	defer os.Unsetenv("COTHORITY_COSI_RATE_LIMIT")
+
+	ac, err := accessControlFromEnv()
+	require.NoError(t, err)
+	require.Equal(t, 1, len(ac.Identities))
+	id := signer.Identity()
+	require.True(t, ac.Identities[0].Equal(&id))
+	require.Equal(t, [][]byte{[]byte("one"), []byte("two")}, ac.Tokens)
+	require.Equal(t, 10, ac.Rate)
+	require.Equal(t, time.Minute, ac.Period)
+
+	os.Setenv("COTHORITY_COSI_RATE_LIMIT", "10")
+	_, err = accessControlFromEnv()
+	require.Error(t, err)
+}
+
+func TestServiceCosi_Authentication(t *testing.T) {
+	local := onet.NewTCPTest(tSuite)
+	hosts, el, _ := local.GenTree(3, false)
+	defer local.CloseAll()
+
+	signer := darc.NewSignerEd25519(nil, nil)
+	services := local.GetServices(hosts, onet.ServiceFactory.ServiceID(ServiceName))
+	require.NoError(t, services[0].(*CoSi).SetAccessControl(&AccessControl{
+		Identities: []darc.Identity{signer.Identity()},
+	}))
+
+	client := NewClient()
+	_, err := client.SignatureRequest(el, []byte("anonymous"))
+	require.Error(t, err)
+
+	client.Signer = &signer
+	reply, err := client.SignatureRequest(el, []byte("authenticated"))
+	require.NoError(t, err)
+	require.NoError(t, crypto.VerifySignature(hosts[0].Suite(), el.Publics(),
+		[]byte("authenticated"), reply.Signature))
+}
+
+func TestServiceCosi_AuthenticationOnEveryNode(t *testing.T) {
+	local := onet.NewTCPTest(tSuite)
+	hosts, el, _ := local.GenTree(4, false)
+	defer local.CloseAll()
+
+	signer := darc.NewSignerEd25519(nil, nil)
+	services := local.GetServices(hosts, onet.ServiceFactory.ServiceID(ServiceName))
+	require.NoError(t, services[1].(*CoSi).SetAccessControl(&AccessControl{
+		Identities: []darc.Identity{signer.Identity()},
+	}))
+
+	// The root is open, but the node with an access control refuses to
+	// sign for an anonymous client.
+	client := NewClient()
+	reply, err := client.SignatureRequest(el, []byte("anonymous"))
+	require.NoError(t, err)
+	require.Equal(t, 3, len(reply.Participants))
+	for _, si := range reply.Participants {
+		require.False(t, si.Equal(hosts[1].ServerIdentity))
+	}
+
+	client.Signer = &signer
+	reply, err = client.SignatureRequest(el, []byte("authenticated"))
+	require.NoError(t, err)
+	require.Equal(t, 4, len(reply.Participants))
+}
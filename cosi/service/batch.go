@@ -0,0 +1,218 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"time"
+
+	cosi "go.dedis.ch/cothority/v3/cosi/protocol"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/network"
+)
+
+func init() {
+	network.RegisterMessage(&BatchSignatureRequest{})
+	network.RegisterMessage(&BatchSignatureResponse{})
+}
+
+// batchWindow is how long the first message of a batch waits for the
+// messages of other clients before the batch is signed.
+var batchWindow = 100 * time.Millisecond
+
+// maxBatchSize is the number of messages that makes a batch signed at once.
+const maxBatchSize = 1024
+
+// BatchSignatureRequest asks for the signature of a message together with the
+// messages sent by other clients for the same roster. All the messages are
+// signed in a single round of the protocol.
+type BatchSignatureRequest struct {
+	Message []byte
+	Roster  *onet.Roster
+	Auth    *Authentication
+}
+
+// BatchSignatureResponse holds the collective signature of the root of the
+// Merkle tree of the messages of the batch, and the proof that the message
+// of the client is at the given index of the tree.
+type BatchSignatureResponse struct {
+	Root      []byte
+	Index     int
+	Count     int
+	Proof     [][]byte
+	Signature []byte
+}
+
+// batch holds the messages waiting to be signed for a roster.
+type batch struct {
+	roster   *onet.Roster
+	messages [][]byte
+	auths    []Authentication
+	replies  []chan batchResult
+	started  bool
+}
+
+type batchResult struct {
+	response *BatchSignatureResponse
+	err      error
+}
+
+// BatchSignatureRequest adds the message to the batch of its roster and
+// returns once the batch is signed.
+func (cs *CoSi) BatchSignatureRequest(req *BatchSignatureRequest) (*BatchSignatureResponse, error) {
+	if req.Roster == nil || len(req.Roster.List) == 0 {
+		return nil, errors.New("no roster given")
+	}
+	if err := cs.authorize(req.Message, req.Auth); err != nil {
+		return nil, err
+	}
+	key := rosterKey(req.Roster)
+	reply := make(chan batchResult, 1)
+
+	cs.batchesMutex.Lock()
+	b, ok := cs.batches[key]
+	if !ok {
+		b = &batch{roster: req.Roster}
+		cs.batches[key] = b
+		time.AfterFunc(batchWindow, func() { cs.signBatch(key, b) })
+	}
+	b.messages = append(b.messages, req.Message)
+	b.auths = append(b.auths, authOrEmpty(req.Auth))
+	b.replies = append(b.replies, reply)
+	full := len(b.messages) >= maxBatchSize
+	cs.batchesMutex.Unlock()
+
+	if full {
+		go cs.signBatch(key, b)
+	}
+	res := <-reply
+	return res.response, res.err
+}
+
+// signBatch signs the root of the Merkle tree of the messages of the batch
+// and sends the result to each request.
+func (cs *CoSi) signBatch(key string, b *batch) {
+	cs.batchesMutex.Lock()
+	if cs.batches[key] == b {
+		delete(cs.batches, key)
+	}
+	if b.started {
+		cs.batchesMutex.Unlock()
+		return
+	}
+	b.started = true
+	cs.batchesMutex.Unlock()
+
+	levels := merkleLevels(b.messages)
+	root := levels[0][0]
+	resp, err := cs.sign(&SignatureRequest{Message: root, Roster: b.roster}, nil,
+		&roundAuth{Messages: b.messages, Auths: b.auths, Batch: true})
+	for i, reply := range b.replies {
+		if err != nil {
+			reply <- batchResult{err: err}
+			continue
+		}
+		reply <- batchResult{response: &BatchSignatureResponse{
+			Root:      root,
+			Index:     i,
+			Count:     len(b.messages),
+			Proof:     merkleProof(levels, i),
+			Signature: resp.Signature,
+		}}
+	}
+}
+
+// rosterKey identifies the roster by the public keys of its nodes, as the
+// clients may not give an ID.
+func rosterKey(r *onet.Roster) string {
+	var key bytes.Buffer
+	for _, si := range r.List {
+		key.WriteString(si.Public.String())
+	}
+	return key.String()
+}
+
+// VerifyBatchSignature checks that the message is part of the batch and that
+// the root of the batch is signed by the roster.
+func VerifyBatchSignature(suite kyber.Group, publics []kyber.Point, msg []byte, resp *BatchSignatureResponse) error {
+	if resp.Index < 0 || resp.Index >= resp.Count {
+		return errors.New("index out of the batch")
+	}
+	h := merkleLeaf(msg)
+	index, width := resp.Index, resp.Count
+	proof := resp.Proof
+	for width > 1 {
+		if sibling := index ^ 1; sibling < width {
+			if len(proof) == 0 {
+				return errors.New("proof is too short")
+			}
+			if sibling < index {
+				h = merkleNode(proof[0], h)
+			} else {
+				h = merkleNode(h, proof[0])
+			}
+			proof = proof[1:]
+		}
+		index /= 2
+		width = (width + 1) / 2
+	}
+	if len(proof) > 0 {
+		return errors.New("proof is too long")
+	}
+	if !bytes.Equal(h, resp.Root) {
+		return errors.New("message is not part of the batch")
+	}
+	return cosi.VerifySignature(suite, publics, resp.Root, resp.Signature)
+}
+
+func merkleLeaf(msg []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{0})
+	h.Write(msg)
+	return h.Sum(nil)
+}
+
+func merkleNode(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{1})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// merkleLevels returns the levels of the Merkle tree of the messages, from
+// the root to the leaves. A node without sibling is moved up unchanged.
+func merkleLevels(msgs [][]byte) [][][]byte {
+	level := make([][]byte, len(msgs))
+	for i, m := range msgs {
+		level[i] = merkleLeaf(m)
+	}
+	levels := [][][]byte{level}
+	for len(level) > 1 {
+		var next [][]byte
+		for i := 0; i < len(level); i += 2 {
+			if i+1 < len(level) {
+				next = append(next, merkleNode(level[i], level[i+1]))
+			} else {
+				next = append(next, level[i])
+			}
+		}
+		levels = append([][][]byte{next}, levels...)
+		level = next
+	}
+	return levels
+}
+
+// merkleProof returns the siblings of the path from the message to the root,
+// starting at the leaves.
+func merkleProof(levels [][][]byte, index int) [][]byte {
+	var proof [][]byte
+	for l := len(levels) - 1; l > 0; l-- {
+		if sibling := index ^ 1; sibling < len(levels[l]) {
+			proof = append(proof, levels[l][sibling])
+		}
+		index /= 2
+	}
+	return proof
+}
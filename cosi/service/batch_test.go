@@ -0,0 +1,48 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/onet/v3"
+)
+
+func TestServiceCosi_Batch(t *testing.T) {
+	defer func(w time.Duration) { batchWindow = w }(batchWindow)
+	batchWindow = time.Second
+
+	local := onet.NewTCPTest(tSuite)
+	hosts, el, _ := local.GenTree(5, false)
+	defer local.CloseAll()
+
+	n := 5
+	type reply struct {
+		msg []byte
+		res *BatchSignatureResponse
+		err error
+	}
+	replies := make(chan reply, n)
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			msg := []byte(fmt.Sprintf("message %d", i))
+			res, err := NewClient().BatchSignatureRequest(el, msg)
+			replies <- reply{msg, res, err}
+		}(i)
+	}
+
+	var root []byte
+	for i := 0; i < n; i++ {
+		r := <-replies
+		require.NoError(t, r.err)
+		res := r.res
+		require.Equal(t, n, res.Count)
+		if root == nil {
+			root = res.Root
+		}
+		require.Equal(t, root, res.Root)
+		require.NoError(t, VerifyBatchSignature(hosts[0].Suite(), el.Publics(), r.msg, res))
+		require.Error(t, VerifyBatchSignature(hosts[0].Suite(), el.Publics(), []byte("other"), res))
+	}
+}
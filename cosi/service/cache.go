@@ -0,0 +1,90 @@
+package service
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// defaultCacheTTL is how long a signature is returned again to the requests
+// for the same message and roster. COTHORITY_COSI_CACHE_TTL overrides it,
+// and a TTL of 0 disables the cache.
+const defaultCacheTTL = time.Minute
+
+// signatureCache holds the signatures of the recent requests, so that
+// identical requests are signed in a single round. A request arriving while
+// the round of an identical one runs waits for its result.
+type signatureCache struct {
+	sync.Mutex
+	ttl     time.Duration
+	entries map[string]*cacheEntry
+}
+
+type cacheEntry struct {
+	done     chan struct{}
+	finished time.Time
+	resp     *SignatureResponse
+	err      error
+}
+
+func newSignatureCache(ttl time.Duration) *signatureCache {
+	return &signatureCache{
+		ttl:     ttl,
+		entries: make(map[string]*cacheEntry),
+	}
+}
+
+// signatureKey identifies the signatures of the message by the roster, with
+// the format of the response. The parameters of the rounds are part of the
+// key too, as they change which nodes end up signing.
+func signatureKey(req *SignatureRequest) string {
+	h := sha256.Sum256(req.Message)
+	key := rosterKey(req.Roster) + string(h[:]) +
+		fmt.Sprintf("%+v/%d/%d", req.Topology, req.Timeout, req.Retries)
+	if req.EdDSA {
+		key += "eddsa"
+	}
+	if req.Receipts {
+		key += "receipts"
+	}
+	return key
+}
+
+// sign returns the response of the identical request if there is one, or
+// calls fn. Errors are not kept.
+func (c *signatureCache) sign(key string, fn func() (*SignatureResponse, error)) (*SignatureResponse, error) {
+	if c.ttl <= 0 {
+		return fn()
+	}
+
+	c.Lock()
+	for k, e := range c.entries {
+		if !e.finished.IsZero() && time.Since(e.finished) > c.ttl {
+			delete(c.entries, k)
+		}
+	}
+	e, ok := c.entries[key]
+	if ok {
+		c.Unlock()
+		<-e.done
+	} else {
+		e = &cacheEntry{done: make(chan struct{})}
+		c.entries[key] = e
+		c.Unlock()
+
+		e.resp, e.err = fn()
+		c.Lock()
+		e.finished = time.Now()
+		if e.err != nil {
+			delete(c.entries, key)
+		}
+		c.Unlock()
+		close(e.done)
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+	resp := *e.resp
+	return &resp, nil
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/onet/v3"
+)
+
+func TestSignatureCache(t *testing.T) {
+	cache := newSignatureCache(100 * time.Millisecond)
+	var calls int
+	var callsMutex sync.Mutex
+	release := make(chan bool)
+	fn := func() (*SignatureResponse, error) {
+		callsMutex.Lock()
+		calls++
+		callsMutex.Unlock()
+		<-release
+		return &SignatureResponse{Signature: []byte("sig")}, nil
+	}
+
+	// The requests arriving during the round wait for it.
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := cache.sign("key", fn)
+			require.NoError(t, err)
+			require.Equal(t, []byte("sig"), resp.Signature)
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	require.Equal(t, 1, calls)
+
+	// The signature is kept until the TTL expires.
+	_, err := cache.sign("key", fn)
+	require.NoError(t, err)
+	require.Equal(t, 1, calls)
+	time.Sleep(150 * time.Millisecond)
+	_, err = cache.sign("key", fn)
+	require.NoError(t, err)
+	require.Equal(t, 2, calls)
+
+	// Errors are not kept.
+	failing := func() (*SignatureResponse, error) {
+		calls++
+		return nil, errors.New("failed")
+	}
+	_, err = cache.sign("other", failing)
+	require.Error(t, err)
+	_, err = cache.sign("other", failing)
+	require.Error(t, err)
+	require.Equal(t, 4, calls)
+
+	// A TTL of 0 disables the cache.
+	cache = newSignatureCache(0)
+	cache.sign("key", fn)
+	cache.sign("key", fn)
+	require.Equal(t, 6, calls)
+}
+
+func TestSignatureKey(t *testing.T) {
+	local := onet.NewLocalTest(tSuite)
+	_, el, _ := local.GenTree(3, false)
+	defer local.CloseAll()
+
+	req := &SignatureRequest{Message: []byte("msg"), Roster: el}
+	key := signatureKey(req)
+	require.Equal(t, key, signatureKey(&SignatureRequest{Message: []byte("msg"), Roster: el}))
+	for _, other := range []SignatureRequest{
+		{Message: []byte("other"), Roster: el},
+		{Message: []byte("msg"), Roster: el, Retries: 1},
+		{Message: []byte("msg"), Roster: el, Timeout: time.Second},
+		{Message: []byte("msg"), Roster: el, Topology: TreeTopology{Shape: ShapeFlat}},
+		{Message: []byte("msg"), Roster: el, EdDSA: true},
+	} {
+		require.NotEqual(t, key, signatureKey(&other))
+	}
+}
+
+func TestServiceCosi_Cache(t *testing.T) {
+	local := onet.NewTCPTest(tSuite)
+	_, el, _ := local.GenTree(5, false)
+	defer local.CloseAll()
+
+	client := NewClient()
+	res1, err := client.SignatureRequest(el, []byte("hello cosi service"))
+	require.NoError(t, err)
+	res2, err := client.SignatureRequest(el, []byte("hello cosi service"))
+	require.NoError(t, err)
+	require.Equal(t, res1.Signature, res2.Signature)
+
+	res3, err := client.SignatureRequest(el, []byte("hello again"))
+	require.NoError(t, err)
+	require.NotEqual(t, res1.Signature, res3.Signature)
+}
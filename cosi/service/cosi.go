@@ -5,13 +5,20 @@ package service
 import (
 	"errors"
 	"fmt"
+	"os"
+	"strconv"
+	"sync"
 	"time"
 
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/cosi/crypto"
 	"go.dedis.ch/cothority/v3/cosi/protocol"
 	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/util/random"
 	"go.dedis.ch/onet/v3"
 	"go.dedis.ch/onet/v3/log"
 	"go.dedis.ch/onet/v3/network"
+	"go.dedis.ch/protobuf"
 	"gopkg.in/satori/go.uuid.v1"
 )
 
@@ -27,66 +34,351 @@ func init() {
 	onet.RegisterNewService(ServiceName, newCoSiService)
 	network.RegisterMessage(&SignatureRequest{})
 	network.RegisterMessage(&SignatureResponse{})
+	network.RegisterMessage(&AsyncSignatureRequest{})
+	network.RegisterMessage(&AsyncSignatureReply{})
+	network.RegisterMessage(&SignatureStatus{})
+	network.RegisterMessage(&SignatureStatusReply{})
+
+	// The nodes compute their commitments between the rounds if
+	// COTHORITY_COSI_PRECOMPUTE gives the number of commitments to keep.
+	if n, err := strconv.Atoi(os.Getenv("COTHORITY_COSI_PRECOMPUTE")); err == nil && n > 0 {
+		log.Lvl2("Precomputing", n, "cosi commitments")
+		cosi.SetCommitments(cosi.NewCommitments(cothority.Suite, n))
+	}
 }
 
+// defaultRequestTimeout is the time a round of the protocol can take when
+// the request gives no timeout.
+const defaultRequestTimeout = time.Minute
+
+// asyncExpiration is how long the result of an asynchronous request is kept
+// after the signature is done.
+const asyncExpiration = 10 * time.Minute
+
 // CoSi is the service that handles collective signing operations
 type CoSi struct {
 	*onet.ServiceProcessor
+	// requests holds the asynchronous requests by ID
+	requests      map[string]*asyncRequest
+	requestsMutex sync.Mutex
+	// batches holds the messages waiting to be signed by roster
+	batches      map[string]*batch
+	batchesMutex sync.Mutex
+	// uploads holds the payloads uploaded by the clients by ID
+	uploads      map[string]*upload
+	uploadsMutex sync.Mutex
+	// cache holds the signatures of the recent requests
+	cache *signatureCache
+	// challenges holds the challenges the node responded to, to give
+	// receipts for them
+	challenges      map[string]time.Time
+	challengesMutex sync.Mutex
+	// participation holds the participation of the nodes by public key
+	participation      map[string]*NodeParticipation
+	participationMutex sync.Mutex
+	// access restricts the clients that can ask for signatures
+	access accessControl
+}
+
+// asyncRequest holds the state of an asynchronous request.
+type asyncRequest struct {
+	done     bool
+	response *SignatureResponse
+	err      error
+	finished time.Time
 }
 
 // SignatureRequest is what the Cosi service is expected to receive from clients.
 type SignatureRequest struct {
 	Message []byte
 	Roster  *onet.Roster
+	// Topology is the shape of the tree, binary by default.
+	Topology TreeTopology
+	// Timeout is the time a round of the protocol can take, one minute by
+	// default.
+	Timeout time.Duration
+	// Retries is the number of times the protocol is run again without the
+	// nodes that failed, if a round doesn't have enough nodes.
+	Retries int
+	// EdDSA asks for the signature in the Ed25519 format too.
+	EdDSA bool
+	// Receipts asks for the receipts of the participants too.
+	Receipts bool
+	// Auth identifies the client, if the conode restricts its clients.
+	Auth *Authentication
 }
 
 // SignatureResponse is what the Cosi service will reply to clients.
 type SignatureResponse struct {
 	Hash      []byte
 	Signature []byte
+	// Roster is the roster of the last round, whose public keys verify the
+	// signature. It is smaller than the roster of the request if the
+	// protocol was run again without some nodes.
+	Roster *onet.Roster
+	// Participants holds the nodes that took part in the signature.
+	Participants []*network.ServerIdentity
+	// EdDSASignature is the signature in the Ed25519 format, which
+	// verifies with AggregatePublic in any Ed25519 implementation. They
+	// are only set if the request asks for it.
+	EdDSASignature  []byte
+	AggregatePublic []byte
+	// Receipts holds the receipts of the participants, if the request asks
+	// for them.
+	Receipts []*Receipt
+}
+
+// AsyncSignatureRequest is like SignatureRequest, but the conode replies
+// immediately with an ID the client polls with SignatureStatus.
+type AsyncSignatureRequest struct {
+	Message  []byte
+	Roster   *onet.Roster
+	Topology TreeTopology
+	Timeout  time.Duration
+	Retries  int
+	EdDSA    bool
+	Receipts bool
+	Auth     *Authentication
+}
+
+// AsyncSignatureReply holds the ID of an asynchronous request.
+type AsyncSignatureReply struct {
+	RequestID []byte
+}
+
+// SignatureStatus asks for the state of an asynchronous request.
+type SignatureStatus struct {
+	RequestID []byte
+}
+
+// SignatureStatusReply tells if the signature of an asynchronous request is
+// done. Then either Response or Error is set.
+type SignatureStatusReply struct {
+	Done     bool
+	Response *SignatureResponse
+	Error    string
 }
 
 // SignatureRequest treats external request to this service.
 func (cs *CoSi) SignatureRequest(req *SignatureRequest) (network.Message, error) {
+	if err := cs.authorize(req.Message, req.Auth); err != nil {
+		return nil, err
+	}
+	return cs.sign(req, nil, clientAuth(req.Message, req.Auth))
+}
+
+// AsyncSignatureRequest starts the signature of the request in the
+// background and returns the ID to poll with SignatureStatus, so that the
+// client doesn't have to keep the connection open for the whole protocol.
+func (cs *CoSi) AsyncSignatureRequest(req *AsyncSignatureRequest) (*AsyncSignatureReply, error) {
+	if req.Roster == nil {
+		return nil, errors.New("no roster given")
+	}
+	if err := cs.authorize(req.Message, req.Auth); err != nil {
+		return nil, err
+	}
+	id := make([]byte, 32)
+	random.Bytes(id, random.New())
+
+	cs.requestsMutex.Lock()
+	for k, r := range cs.requests {
+		if r.done && time.Since(r.finished) > asyncExpiration {
+			delete(cs.requests, k)
+		}
+	}
+	ar := &asyncRequest{}
+	cs.requests[string(id)] = ar
+	cs.requestsMutex.Unlock()
+
+	go func() {
+		resp, err := cs.sign(&SignatureRequest{
+			Message:  req.Message,
+			Roster:   req.Roster,
+			Topology: req.Topology,
+			Timeout:  req.Timeout,
+			Retries:  req.Retries,
+			EdDSA:    req.EdDSA,
+			Receipts: req.Receipts,
+		}, nil, clientAuth(req.Message, req.Auth))
+		cs.requestsMutex.Lock()
+		ar.done = true
+		ar.response = resp
+		ar.err = err
+		ar.finished = time.Now()
+		cs.requestsMutex.Unlock()
+	}()
+	return &AsyncSignatureReply{RequestID: id}, nil
+}
+
+// SignatureStatus returns the state of an asynchronous request.
+func (cs *CoSi) SignatureStatus(req *SignatureStatus) (*SignatureStatusReply, error) {
+	cs.requestsMutex.Lock()
+	defer cs.requestsMutex.Unlock()
+	ar, ok := cs.requests[string(req.RequestID)]
+	if !ok {
+		return nil, errors.New("unknown request")
+	}
+	reply := &SignatureStatusReply{Done: ar.done, Response: ar.response}
+	if ar.err != nil {
+		reply.Error = ar.err.Error()
+	}
+	return reply, nil
+}
+
+// sign returns the signature of the message of the request, which is taken
+// from the cache if the same message was signed by the same roster recently.
+// If data is given, it is sent to the nodes with the message to verify it.
+// The authentication of the clients is sent to the nodes for them to check
+// their own access control.
+func (cs *CoSi) sign(req *SignatureRequest, data []byte, auth *roundAuth) (*SignatureResponse, error) {
+	if req.Roster == nil {
+		return nil, errors.New("no roster given")
+	}
+	if data != nil {
+		return cs.signRounds(req, data, auth)
+	}
+	return cs.cache.sign(signatureKey(req), func() (*SignatureResponse, error) {
+		return cs.signRounds(req, nil, auth)
+	})
+}
+
+// signRounds runs the protocol to sign the message of the request. If a
+// round fails because some nodes didn't commit, it is run again without
+// them, as many times as the request allows.
+func (cs *CoSi) signRounds(req *SignatureRequest, data []byte, auth *roundAuth) (*SignatureResponse, error) {
 	suite, ok := cs.Suite().(kyber.HashFactory)
 	if !ok {
 		return nil, errors.New("suite is unusable")
 	}
+	authBuf, err := protobuf.Encode(auth)
+	if err != nil {
+		return nil, err
+	}
 
 	if req.Roster.ID.IsNil() {
 		req.Roster.ID = onet.RosterID(uuid.NewV4())
 	}
+	timeout := req.Timeout
+	if timeout <= 0 {
+		timeout = defaultRequestTimeout
+	}
 
-	_, root := req.Roster.Search(cs.ServerIdentity().ID)
+	roster := req.Roster
+	for attempt := 0; ; attempt++ {
+		sig, exceptions, err := cs.runProtocol(roster, req.Topology, req.Message, data, authBuf, timeout)
+		if err == nil {
+			if log.DebugVisible() > 1 {
+				fmt.Printf("%s: Signed a message.\n", time.Now().Format("Mon Jan 2 15:04:05 -0700 MST 2006"))
+			}
+			h := suite.Hash()
+			h.Write(req.Message)
+			resp := &SignatureResponse{
+				Hash:         h.Sum(nil),
+				Signature:    sig,
+				Roster:       roster,
+				Participants: removeNodes(roster, exceptions).List,
+			}
+			if req.EdDSA {
+				resp.AggregatePublic, resp.EdDSASignature, err = crypto.EdDSASignature(
+					cs.Suite(), roster.Publics(), sig)
+				if err != nil {
+					return nil, err
+				}
+			}
+			if req.Receipts {
+				resp.Receipts, err = NewClient().CollectReceipts(resp, req.Message)
+				if err != nil {
+					log.Warnf("%v: %v", cs.ServerIdentity(), err)
+				}
+			}
+			return resp, nil
+		}
+		if attempt >= req.Retries || len(exceptions) == 0 {
+			return nil, err
+		}
+		log.Warnf("%v: signing again without %d nodes: %v", cs.ServerIdentity(), len(exceptions), err)
+		roster = removeNodes(roster, exceptions)
+	}
+}
+
+// runProtocol runs one round of the protocol with the roster. It returns the
+// roster indexes of the nodes that didn't commit, with the signature or the
+// error.
+func (cs *CoSi) runProtocol(roster *onet.Roster, topology TreeTopology, msg, data, auth []byte,
+	timeout time.Duration) ([]byte, []int, error) {
+	_, root := roster.Search(cs.ServerIdentity().ID)
 	if root == nil {
-		return nil, errors.New("Couldn't find a serverIdetity in Roster")
+		return nil, nil, errors.New("Couldn't find a serverIdetity in Roster")
+	}
+	tree, err := topology.tree(roster, root)
+	if err != nil {
+		return nil, nil, err
 	}
-	tree := req.Roster.GenerateNaryTreeWithRoot(2, root)
 	tni := cs.NewTreeNodeInstance(tree, tree.Root, cosi.Name)
 	pi, err := cosi.NewProtocol(tni)
 	if err != nil {
-		return nil, errors.New("Couldn't make new protocol: " + err.Error())
+		return nil, nil, errors.New("Couldn't make new protocol: " + err.Error())
 	}
 	cs.RegisterProtocolInstance(pi)
 	pcosi := pi.(*cosi.CoSi)
-	pcosi.SigningMessage(req.Message)
-	h := suite.Hash()
-	h.Write(req.Message)
-	response := make(chan []byte)
+	pcosi.SigningMessage(msg)
+	pcosi.Data = data
+	pcosi.Auth = auth
+	pcosi.RegisterChallengeHook(cs.recordChallenge)
+	// The nodes give up on their children early enough for the root to
+	// finish the round before the timeout.
+	pcosi.Timeout = timeout / time.Duration(2*treeHeight(tree.Root)+1)
+	response := make(chan []byte, 1)
 	pcosi.RegisterSignatureHook(func(sig []byte) {
 		response <- sig
 	})
+	failure := make(chan error, 1)
+	pcosi.RegisterErrorHook(func(err error) {
+		failure <- err
+	})
 	log.Lvl3("Cosi Service starting up root protocol")
 	go pi.Dispatch()
 	go pi.Start()
-	sig := <-response
-	if log.DebugVisible() > 1 {
-		fmt.Printf("%s: Signed a message.\n", time.Now().Format("Mon Jan 2 15:04:05 -0700 MST 2006"))
+	select {
+	case sig := <-response:
+		cs.recordParticipation(roster, pcosi.Exceptions(), true)
+		return sig, pcosi.Exceptions(), nil
+	case err := <-failure:
+		cs.recordParticipation(roster, pcosi.Exceptions(), false)
+		return nil, pcosi.Exceptions(), err
+	case <-time.After(timeout):
+		cs.recordParticipation(roster, nil, false)
+		return nil, nil, errors.New("timeout while signing")
+	}
+}
+
+// treeHeight returns the number of levels below the node.
+func treeHeight(n *onet.TreeNode) int {
+	height := 0
+	for _, child := range n.Children {
+		if h := treeHeight(child) + 1; h > height {
+			height = h
+		}
+	}
+	return height
+}
+
+// removeNodes returns the roster without the nodes at the given indexes.
+func removeNodes(r *onet.Roster, indexes []int) *onet.Roster {
+	if len(indexes) == 0 {
+		return r
 	}
-	return &SignatureResponse{
-		Hash:      h.Sum(nil),
-		Signature: sig,
-	}, nil
+	removed := make(map[int]bool)
+	for _, i := range indexes {
+		removed[i] = true
+	}
+	var list []*network.ServerIdentity
+	for i, si := range r.List {
+		if !removed[i] {
+			list = append(list, si)
+		}
+	}
+	return onet.NewRoster(list)
 }
 
 // NewProtocol is called on all nodes of a Tree (except the root, since it is
@@ -95,14 +387,44 @@ func (cs *CoSi) SignatureRequest(req *SignatureRequest) (network.Message, error)
 func (cs *CoSi) NewProtocol(tn *onet.TreeNodeInstance, conf *onet.GenericConfig) (onet.ProtocolInstance, error) {
 	log.Lvl3("Cosi Service received New Protocol event")
 	pi, err := cosi.NewProtocol(tn)
-	return pi, err
+	if err != nil {
+		return nil, err
+	}
+	pi.(*cosi.CoSi).RegisterVerificationHook(cs.verifyUpload)
+	pi.(*cosi.CoSi).RegisterAnnouncementHook(func(in *cosi.Announcement) error {
+		return cs.authorizeRound(in.Message, in.Auth)
+	})
+	pi.(*cosi.CoSi).RegisterChallengeHook(cs.recordChallenge)
+	return pi, nil
 }
 
 func newCoSiService(c *onet.Context) (onet.Service, error) {
 	s := &CoSi{
 		ServiceProcessor: onet.NewServiceProcessor(c),
+		requests:         make(map[string]*asyncRequest),
+		batches:          make(map[string]*batch),
+		uploads:          make(map[string]*upload),
+		cache:            newSignatureCache(defaultCacheTTL),
+		challenges:       make(map[string]time.Time),
+		participation:    make(map[string]*NodeParticipation),
+	}
+	if ttl := os.Getenv("COTHORITY_COSI_CACHE_TTL"); ttl != "" {
+		d, err := time.ParseDuration(ttl)
+		if err != nil {
+			return nil, fmt.Errorf("invalid COTHORITY_COSI_CACHE_TTL: %v", err)
+		}
+		s.cache.ttl = d
+	}
+	ac, err := accessControlFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.SetAccessControl(ac); err != nil {
+		return nil, err
 	}
-	err := s.RegisterHandler(s.SignatureRequest)
+	err = s.RegisterHandlers(s.SignatureRequest, s.AsyncSignatureRequest,
+		s.SignatureStatus, s.BatchSignatureRequest, s.UploadChunk,
+		s.UploadSignatureRequest, s.ReceiptRequest, s.ParticipationRequest)
 	if err != nil {
 		log.Error(err, "Couldn't register message:")
 		return nil, err
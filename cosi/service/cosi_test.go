@@ -1,7 +1,9 @@
 package service
 
 import (
+	"crypto/ed25519"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	"go.dedis.ch/cothority/v3"
@@ -43,6 +45,42 @@ func TestServiceCosi(t *testing.T) {
 	}
 }
 
+func TestServiceCosi_EdDSA(t *testing.T) {
+	local := onet.NewTCPTest(tSuite)
+	_, el, _ := local.GenTree(5, false)
+	defer local.CloseAll()
+
+	msg := []byte("hello cosi service")
+	reply := &SignatureResponse{}
+	err := NewClient().SendProtobuf(el.List[0], &SignatureRequest{
+		Roster:  el,
+		Message: msg,
+		EdDSA:   true,
+	}, reply)
+	require.NoError(t, err)
+	require.Equal(t, ed25519.SignatureSize, len(reply.EdDSASignature))
+	require.True(t, ed25519.Verify(reply.AggregatePublic, msg, reply.EdDSASignature))
+	require.False(t, ed25519.Verify(reply.AggregatePublic, []byte("other"), reply.EdDSASignature))
+
+	// The aggregate public key is the sum of the keys of the participants.
+	agg := tSuite.Point().Null()
+	for _, si := range reply.Participants {
+		agg.Add(agg, si.Public)
+	}
+	buf, err := agg.MarshalBinary()
+	require.NoError(t, err)
+	require.Equal(t, buf, reply.AggregatePublic)
+
+	// The signature is only converted on demand.
+	reply = &SignatureResponse{}
+	err = NewClient().SendProtobuf(el.List[0], &SignatureRequest{
+		Roster:  el,
+		Message: msg,
+	}, reply)
+	require.NoError(t, err)
+	require.Empty(t, reply.EdDSASignature)
+}
+
 func TestCreateAggregate(t *testing.T) {
 	local := onet.NewTCPTest(tSuite)
 	// generate 5 hosts, they don't connect, they process messages, and they
@@ -67,3 +105,56 @@ func TestCreateAggregate(t *testing.T) {
 	require.Nil(t, cosi.VerifySignature(hosts[0].Suite(), el.Publics(),
 		msg, res.Signature))
 }
+
+func TestServiceCosi_Async(t *testing.T) {
+	local := onet.NewTCPTest(tSuite)
+	hosts, el, _ := local.GenTree(5, false)
+	defer local.CloseAll()
+
+	client := NewClient()
+	msg := []byte("hello async cosi service")
+	id, err := client.AsyncSignatureRequest(el, msg)
+	require.NoError(t, err)
+	require.NotEmpty(t, id)
+
+	res, err := client.WaitSignature(el, id, 10*time.Millisecond)
+	require.NoError(t, err)
+	require.NoError(t, crypto.VerifySignature(hosts[0].Suite(), el.Publics(),
+		msg, res.Signature))
+
+	_, err = client.SignatureStatus(el, []byte("unknown"))
+	require.Error(t, err)
+}
+
+func TestServiceCosi_Retries(t *testing.T) {
+	local := onet.NewTCPTest(tSuite)
+	hosts, el, _ := local.GenTree(5, false)
+	defer local.CloseAll()
+
+	// Two nodes out of five are too many for the default threshold.
+	for _, h := range hosts[3:] {
+		h.Pause()
+		defer h.Unpause()
+	}
+
+	client := NewClient()
+	msg := []byte("hello cosi service")
+	req := &SignatureRequest{
+		Roster:  el,
+		Message: msg,
+		Timeout: 2 * time.Second,
+	}
+	res := &SignatureResponse{}
+	require.Error(t, client.SendProtobuf(el.List[0], req, res))
+
+	req.Retries = 1
+	require.NoError(t, client.SendProtobuf(el.List[0], req, res))
+	require.Equal(t, 3, len(res.Roster.List))
+	require.Equal(t, 3, len(res.Participants))
+	for _, si := range res.Participants {
+		i, _ := el.Search(si.ID)
+		require.True(t, i < 3)
+	}
+	require.NoError(t, crypto.VerifySignature(hosts[0].Suite(), res.Roster.Publics(),
+		msg, res.Signature))
+}
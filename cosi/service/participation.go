@@ -0,0 +1,90 @@
+package service
+
+import (
+	"sort"
+
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/network"
+)
+
+func init() {
+	network.RegisterMessage(&ParticipationRequest{})
+	network.RegisterMessage(&ParticipationReply{})
+}
+
+// ParticipationRequest asks a conode for the participation of the nodes in
+// the rounds it started.
+type ParticipationRequest struct{}
+
+// ParticipationReply holds the participation of each node seen in a round.
+type ParticipationReply struct {
+	Nodes []NodeParticipation
+}
+
+// NodeParticipation counts the rounds a node was part of. Rounds that failed
+// for another reason than the exceptions count neither as signed nor as an
+// exception.
+type NodeParticipation struct {
+	ServerIdentity *network.ServerIdentity
+	// Rounds is the number of rounds with the node in the roster.
+	Rounds int
+	// Signed is the number of successful rounds the node took part in.
+	Signed int
+	// Exceptions is the number of rounds the node didn't commit to.
+	Exceptions int
+}
+
+// recordParticipation counts the round of the roster, with the roster
+// indexes of the nodes that didn't commit.
+func (cs *CoSi) recordParticipation(roster *onet.Roster, exceptions []int, success bool) {
+	excepted := make(map[int]bool)
+	for _, i := range exceptions {
+		excepted[i] = true
+	}
+	cs.participationMutex.Lock()
+	defer cs.participationMutex.Unlock()
+	for i, si := range roster.List {
+		key := si.Public.String()
+		p, ok := cs.participation[key]
+		if !ok {
+			p = &NodeParticipation{ServerIdentity: si}
+			cs.participation[key] = p
+		}
+		p.Rounds++
+		switch {
+		case excepted[i]:
+			p.Exceptions++
+		case success:
+			p.Signed++
+		}
+	}
+}
+
+// ParticipationRequest returns the participation of the nodes, the ones
+// with the most exceptions first.
+func (cs *CoSi) ParticipationRequest(req *ParticipationRequest) (*ParticipationReply, error) {
+	cs.participationMutex.Lock()
+	defer cs.participationMutex.Unlock()
+	reply := &ParticipationReply{}
+	for _, p := range cs.participation {
+		reply.Nodes = append(reply.Nodes, *p)
+	}
+	sort.Slice(reply.Nodes, func(i, j int) bool {
+		a, b := reply.Nodes[i], reply.Nodes[j]
+		if a.Exceptions != b.Exceptions {
+			return a.Exceptions > b.Exceptions
+		}
+		return a.ServerIdentity.String() < b.ServerIdentity.String()
+	})
+	return reply, nil
+}
+
+// Participation returns the participation of the nodes in the rounds
+// started by the conode.
+func (c *Client) Participation(si *network.ServerIdentity) (*ParticipationReply, error) {
+	reply := &ParticipationReply{}
+	if err := c.SendProtobuf(si, &ParticipationRequest{}, reply); err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/onet/v3"
+)
+
+func TestServiceCosi_Participation(t *testing.T) {
+	local := onet.NewTCPTest(tSuite)
+	hosts, el, _ := local.GenTree(5, false)
+	defer local.CloseAll()
+
+	client := NewClient()
+	_, err := client.SignatureRequest(el, []byte("hello cosi service"))
+	require.NoError(t, err)
+
+	// The last node is missing from the second round.
+	hosts[4].Pause()
+	req := &SignatureRequest{
+		Roster:  el,
+		Message: []byte("hello again"),
+		Timeout: 2 * time.Second,
+	}
+	require.NoError(t, client.SendProtobuf(el.List[0], req, &SignatureResponse{}))
+	hosts[4].Unpause()
+
+	reply, err := client.Participation(el.List[0])
+	require.NoError(t, err)
+	require.Equal(t, 5, len(reply.Nodes))
+	require.True(t, reply.Nodes[0].ServerIdentity.Equal(el.List[4]))
+	require.Equal(t, 2, reply.Nodes[0].Rounds)
+	require.Equal(t, 1, reply.Nodes[0].Signed)
+	require.Equal(t, 1, reply.Nodes[0].Exceptions)
+	for _, n := range reply.Nodes[1:] {
+		require.Equal(t, 2, n.Rounds)
+		require.Equal(t, 2, n.Signed)
+		require.Equal(t, 0, n.Exceptions)
+	}
+
+	// The other conodes didn't start any round.
+	reply, err = client.Participation(el.List[1])
+	require.NoError(t, err)
+	require.Empty(t, reply.Nodes)
+}
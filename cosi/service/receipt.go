@@ -0,0 +1,131 @@
+package service
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/cosi/crypto"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/sign/schnorr"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/network"
+)
+
+func init() {
+	network.RegisterMessage(&ReceiptRequest{})
+	network.RegisterMessage(&Receipt{})
+}
+
+// receiptWindow is how long a node remembers the challenges it responded
+// to, and so how long after a round it gives receipts for it.
+const receiptWindow = 10 * time.Minute
+
+// ReceiptRequest asks a node for its receipt of a collective signature of
+// the message by the roster.
+type ReceiptRequest struct {
+	Message   []byte
+	Signature []byte
+	Roster    *onet.Roster
+}
+
+// Receipt is the statement of a node that it took part in a collective
+// signature. Signature is the Schnorr signature of the node on the message
+// and the collective signature.
+type Receipt struct {
+	ServerIdentity *network.ServerIdentity
+	Signature      []byte
+}
+
+// recordChallenge remembers the challenge the node responds to.
+func (cs *CoSi) recordChallenge(ch kyber.Scalar) error {
+	cs.challengesMutex.Lock()
+	defer cs.challengesMutex.Unlock()
+	for k, t := range cs.challenges {
+		if time.Since(t) > receiptWindow {
+			delete(cs.challenges, k)
+		}
+	}
+	cs.challenges[ch.String()] = time.Now()
+	return nil
+}
+
+// ReceiptRequest returns the receipt of the node if the signature is valid
+// and the node responded to its challenge.
+func (cs *CoSi) ReceiptRequest(req *ReceiptRequest) (*Receipt, error) {
+	if req.Roster == nil {
+		return nil, errors.New("no roster given")
+	}
+	publics := req.Roster.Publics()
+	if err := crypto.VerifySignature(cs.Suite(), publics, req.Message, req.Signature); err != nil {
+		return nil, err
+	}
+	ch, err := crypto.SignatureChallenge(cs.Suite(), publics, req.Message, req.Signature)
+	if err != nil {
+		return nil, err
+	}
+	cs.challengesMutex.Lock()
+	_, ok := cs.challenges[ch.String()]
+	cs.challengesMutex.Unlock()
+	if !ok {
+		return nil, errors.New("this node didn't take part in the signature")
+	}
+
+	sig, err := schnorr.Sign(cs.Suite(), cs.ServerIdentity().GetPrivate(),
+		receiptMessage(req.Message, req.Signature))
+	if err != nil {
+		return nil, err
+	}
+	return &Receipt{ServerIdentity: cs.ServerIdentity(), Signature: sig}, nil
+}
+
+// VerifyReceipt checks that the receipt is signed by its node for the
+// collective signature of the message.
+func VerifyReceipt(suite kyber.Group, r *Receipt, msg, sig []byte) error {
+	if r.ServerIdentity == nil {
+		return errors.New("receipt without node")
+	}
+	return schnorr.Verify(suite, r.ServerIdentity.Public, receiptMessage(msg, sig), r.Signature)
+}
+
+// receiptMessage returns what a node signs in its receipt.
+func receiptMessage(msg, sig []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte("cosi receipt"))
+	h.Write(msg)
+	h.Write(sig)
+	return h.Sum(nil)
+}
+
+// CollectReceipts asks each participant of the signature of the message for
+// its receipt, and verifies it. The nodes that don't give a valid receipt
+// are listed in the error, with the receipts of the others.
+func (c *Client) CollectReceipts(resp *SignatureResponse, msg []byte) ([]*Receipt, error) {
+	var receipts []*Receipt
+	var failed []string
+	for _, si := range resp.Participants {
+		r := &Receipt{}
+		err := c.SendProtobuf(si, &ReceiptRequest{
+			Message:   msg,
+			Signature: resp.Signature,
+			Roster:    resp.Roster,
+		}, r)
+		if err == nil && (r.ServerIdentity == nil || !r.ServerIdentity.Public.Equal(si.Public)) {
+			err = errors.New("receipt of another node")
+		}
+		if err == nil {
+			err = VerifyReceipt(cothority.Suite, r, msg, resp.Signature)
+		}
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", si, err))
+			continue
+		}
+		receipts = append(receipts, r)
+	}
+	if len(failed) > 0 {
+		return receipts, fmt.Errorf("missing receipts: %v", failed)
+	}
+	return receipts, nil
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/onet/v3"
+)
+
+func TestServiceCosi_Receipts(t *testing.T) {
+	local := onet.NewTCPTest(tSuite)
+	hosts, el, _ := local.GenTree(5, false)
+	defer local.CloseAll()
+
+	msg := []byte("hello cosi service")
+	client := NewClient()
+	res := &SignatureResponse{}
+	err := client.SendProtobuf(el.List[0], &SignatureRequest{
+		Roster:   el,
+		Message:  msg,
+		Receipts: true,
+	}, res)
+	require.NoError(t, err)
+	require.Equal(t, len(el.List), len(res.Receipts))
+	for i, r := range res.Receipts {
+		require.True(t, r.ServerIdentity.Equal(res.Participants[i]))
+		require.NoError(t, VerifyReceipt(tSuite, r, msg, res.Signature))
+		require.Error(t, VerifyReceipt(tSuite, r, []byte("other"), res.Signature))
+	}
+
+	// The client can collect the receipts itself.
+	receipts, err := client.CollectReceipts(res, msg)
+	require.NoError(t, err)
+	require.Equal(t, len(el.List), len(receipts))
+
+	// A node doesn't give a receipt for a round it doesn't remember, even
+	// with a valid signature, nor for an invalid signature.
+	services := local.GetServices(hosts, onet.ServiceFactory.ServiceID(ServiceName))
+	cs := services[1].(*CoSi)
+	req := &ReceiptRequest{Message: msg, Signature: res.Signature, Roster: el}
+	_, err = cs.ReceiptRequest(req)
+	require.NoError(t, err)
+	req.Message = []byte("other")
+	_, err = cs.ReceiptRequest(req)
+	require.Error(t, err)
+	req.Message = msg
+	cs.challengesMutex.Lock()
+	cs.challenges = make(map[string]time.Time)
+	cs.challengesMutex.Unlock()
+	_, err = cs.ReceiptRequest(req)
+	require.Error(t, err)
+	_, err = client.CollectReceipts(res, msg)
+	require.Error(t, err)
+}
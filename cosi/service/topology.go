@@ -0,0 +1,67 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/network"
+)
+
+// Shapes of the tree used to sign a message.
+const (
+	// ShapeBinary is a binary tree, used when no shape is given.
+	ShapeBinary = "binary"
+	// ShapeFlat has all the nodes as children of the root.
+	ShapeFlat = "flat"
+	// ShapeNary has Branching children per node, or as many as needed for
+	// the tree to have the given Depth.
+	ShapeNary = "nary"
+)
+
+// TreeTopology describes the tree the client wants for its request, as the
+// best shape depends on the size of the roster.
+type TreeTopology struct {
+	Shape     string
+	Branching int
+	Depth     int
+}
+
+// tree returns the tree of the topology with the given root.
+func (t TreeTopology) tree(r *onet.Roster, root *network.ServerIdentity) (*onet.Tree, error) {
+	branching := 2
+	switch t.Shape {
+	case "", ShapeBinary:
+	case ShapeFlat:
+		if len(r.List) > 1 {
+			branching = len(r.List) - 1
+		}
+	case ShapeNary:
+		switch {
+		case t.Branching > 0:
+			branching = t.Branching
+		case t.Depth > 0:
+			branching = branchingForDepth(len(r.List), t.Depth)
+		default:
+			return nil, errors.New("nary tree needs a branching factor or a depth")
+		}
+	default:
+		return nil, fmt.Errorf("unknown shape of tree: %s", t.Shape)
+	}
+	return r.GenerateNaryTreeWithRoot(branching, root), nil
+}
+
+// branchingForDepth returns the smallest branching factor for n nodes to fit
+// in a tree with depth levels below the root.
+func branchingForDepth(n, depth int) int {
+	for b := 1; ; b++ {
+		size, level := 1, 1
+		for d := 0; d < depth && size < n; d++ {
+			level *= b
+			size += level
+		}
+		if size >= n {
+			return b
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/cosi/crypto"
+	"go.dedis.ch/onet/v3"
+)
+
+func TestTreeTopology(t *testing.T) {
+	local := onet.NewLocalTest(tSuite)
+	_, el, _ := local.GenTree(7, false)
+	defer local.CloseAll()
+	root := el.List[0]
+
+	tree, err := TreeTopology{}.tree(el, root)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(tree.Root.Children))
+
+	tree, err = TreeTopology{Shape: ShapeFlat}.tree(el, root)
+	require.NoError(t, err)
+	require.Equal(t, 6, len(tree.Root.Children))
+
+	tree, err = TreeTopology{Shape: ShapeNary, Branching: 3}.tree(el, root)
+	require.NoError(t, err)
+	require.Equal(t, 3, len(tree.Root.Children))
+
+	tree, err = TreeTopology{Shape: ShapeNary, Depth: 1}.tree(el, root)
+	require.NoError(t, err)
+	require.Equal(t, 6, len(tree.Root.Children))
+
+	_, err = TreeTopology{Shape: ShapeNary}.tree(el, root)
+	require.Error(t, err)
+	_, err = TreeTopology{Shape: "star"}.tree(el, root)
+	require.Error(t, err)
+
+	require.Equal(t, 1, branchingForDepth(1, 1))
+	require.Equal(t, 2, branchingForDepth(7, 2))
+	require.Equal(t, 3, branchingForDepth(8, 2))
+}
+
+func TestServiceCosi_Topology(t *testing.T) {
+	local := onet.NewTCPTest(tSuite)
+	hosts, el, _ := local.GenTree(5, false)
+	defer local.CloseAll()
+
+	client := NewClient()
+	msg := []byte("hello cosi service")
+	for _, topo := range []TreeTopology{
+		{Shape: ShapeFlat},
+		{Shape: ShapeNary, Depth: 2},
+	} {
+		res, err := client.SignatureRequestWithTopology(el, msg, topo)
+		require.NoError(t, err)
+		require.NoError(t, crypto.VerifySignature(hosts[0].Suite(), el.Publics(),
+			msg, res.Signature))
+	}
+	_, err := client.SignatureRequestWithTopology(el, msg, TreeTopology{Shape: "star"})
+	require.Error(t, err)
+}
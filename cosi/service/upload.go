@@ -0,0 +1,185 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"hash"
+	"io"
+	"time"
+
+	"go.dedis.ch/kyber/v3/util/random"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/network"
+)
+
+func init() {
+	network.RegisterMessage(&UploadChunk{})
+	network.RegisterMessage(&UploadChunkReply{})
+	network.RegisterMessage(&UploadSignatureRequest{})
+}
+
+// UploadChunkSize is the size of the chunks of a payload sent by the client.
+const UploadChunkSize = 1 << 20
+
+// UploadChunk sends a chunk of a payload to a conode, which hashes it
+// without keeping it. The chunks of an upload are sent in order, and the
+// last one closes the upload.
+type UploadChunk struct {
+	UploadID []byte
+	Data     []byte
+	Last     bool
+}
+
+// UploadChunkReply is returned for each chunk, with the SHA-256 hash of the
+// payload once the last chunk is received.
+type UploadChunkReply struct {
+	Digest []byte
+}
+
+// UploadSignatureRequest asks for the signature of the hash of an uploaded
+// payload. Each node checks that the hash matches the payload it received
+// before it signs.
+type UploadSignatureRequest struct {
+	UploadID []byte
+	Roster   *onet.Roster
+	Timeout  time.Duration
+	Retries  int
+	// Auth identifies the client for the hash of the payload.
+	Auth *Authentication
+}
+
+// upload holds the hash of a payload being uploaded.
+type upload struct {
+	hash    hash.Hash
+	digest  []byte
+	updated time.Time
+}
+
+// UploadChunk adds the chunk to the hash of its upload.
+func (cs *CoSi) UploadChunk(req *UploadChunk) (*UploadChunkReply, error) {
+	if len(req.UploadID) == 0 {
+		return nil, errors.New("no upload ID given")
+	}
+	cs.uploadsMutex.Lock()
+	defer cs.uploadsMutex.Unlock()
+	for k, u := range cs.uploads {
+		if time.Since(u.updated) > asyncExpiration {
+			delete(cs.uploads, k)
+		}
+	}
+	u, ok := cs.uploads[string(req.UploadID)]
+	if !ok {
+		u = &upload{hash: sha256.New()}
+		cs.uploads[string(req.UploadID)] = u
+	}
+	if u.digest != nil {
+		return nil, errors.New("upload is already closed")
+	}
+	u.hash.Write(req.Data)
+	u.updated = time.Now()
+	if req.Last {
+		u.digest = u.hash.Sum(nil)
+	}
+	return &UploadChunkReply{Digest: u.digest}, nil
+}
+
+// UploadSignatureRequest signs the hash of the payload the client uploaded to
+// all the nodes of the roster.
+func (cs *CoSi) UploadSignatureRequest(req *UploadSignatureRequest) (*SignatureResponse, error) {
+	digest, err := cs.uploadDigest(req.UploadID)
+	if err != nil {
+		return nil, err
+	}
+	if err := cs.authorize(digest, req.Auth); err != nil {
+		return nil, err
+	}
+	resp, err := cs.sign(&SignatureRequest{
+		Message: digest,
+		Roster:  req.Roster,
+		Timeout: req.Timeout,
+		Retries: req.Retries,
+	}, req.UploadID, clientAuth(digest, req.Auth))
+	if err != nil {
+		return nil, err
+	}
+	resp.Hash = digest
+	return resp, nil
+}
+
+// verifyUpload checks that the message is the hash of the upload given as
+// data, if any.
+func (cs *CoSi) verifyUpload(msg, data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	digest, err := cs.uploadDigest(data)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(digest, msg) {
+		return errors.New("hash doesn't match the uploaded payload")
+	}
+	return nil
+}
+
+func (cs *CoSi) uploadDigest(id []byte) ([]byte, error) {
+	cs.uploadsMutex.Lock()
+	defer cs.uploadsMutex.Unlock()
+	u, ok := cs.uploads[string(id)]
+	if !ok || u.digest == nil {
+		return nil, errors.New("unknown or unfinished upload")
+	}
+	return u.digest, nil
+}
+
+// SignPayload streams the payload to all the nodes of the roster, which hash
+// it, then asks the first node for the collective signature of the hash. The
+// signature is over the SHA-256 hash of the payload, which is the Hash of the
+// response.
+func (c *Client) SignPayload(r *onet.Roster, payload io.Reader) (*SignatureResponse, error) {
+	if len(r.List) == 0 {
+		return nil, errors.New("Got an empty roster-list")
+	}
+	id := make([]byte, 32)
+	random.Bytes(id, random.New())
+
+	h := sha256.New()
+	buf := make([]byte, UploadChunkSize)
+	for last := false; !last; {
+		n, err := io.ReadFull(payload, buf)
+		switch err {
+		case nil:
+		case io.EOF, io.ErrUnexpectedEOF:
+			last = true
+		default:
+			return nil, err
+		}
+		h.Write(buf[:n])
+		chunk := &UploadChunk{UploadID: id, Data: buf[:n], Last: last}
+		for _, si := range r.List {
+			if err := c.SendProtobuf(si, chunk, &UploadChunkReply{}); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	digest := h.Sum(nil)
+	auth, err := c.authentication(digest)
+	if err != nil {
+		return nil, err
+	}
+	reply := &SignatureResponse{}
+	err = c.SendProtobuf(r.List[0], &UploadSignatureRequest{
+		UploadID: id,
+		Roster:   r,
+		Auth:     auth,
+	}, reply)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(reply.Hash, digest) {
+		return nil, errors.New("signed hash doesn't match the payload")
+	}
+	return reply, nil
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/cosi/crypto"
+	"go.dedis.ch/onet/v3"
+)
+
+func TestServiceCosi_SignPayload(t *testing.T) {
+	local := onet.NewTCPTest(tSuite)
+	hosts, el, _ := local.GenTree(5, false)
+	defer local.CloseAll()
+
+	payload := make([]byte, 2*UploadChunkSize+10)
+	for i := range payload {
+		payload[i] = byte(i)
+	}
+	res, err := NewClient().SignPayload(el, bytes.NewReader(payload))
+	require.NoError(t, err)
+	digest := sha256.Sum256(payload)
+	require.Equal(t, digest[:], res.Hash)
+	require.NoError(t, crypto.VerifySignature(hosts[0].Suite(), el.Publics(),
+		digest[:], res.Signature))
+
+	// A node that got another payload refuses to sign.
+	services := local.GetServices(hosts, onet.ServiceFactory.ServiceID(ServiceName))
+	cs := services[0].(*CoSi)
+	id := []byte("upload")
+	for i, s := range services {
+		data := []byte("payload")
+		if i == len(services)-1 {
+			data = []byte("other payload")
+		}
+		_, err := s.(*CoSi).UploadChunk(&UploadChunk{UploadID: id, Data: data, Last: true})
+		require.NoError(t, err)
+	}
+	_, err = cs.UploadChunk(&UploadChunk{UploadID: id, Data: []byte("more")})
+	require.Error(t, err)
+	sum := sha256.Sum256([]byte("payload"))
+	require.NoError(t, cs.verifyUpload(sum[:], id))
+	require.Error(t, services[len(services)-1].(*CoSi).verifyUpload(sum[:], id))
+	require.NoError(t, cs.verifyUpload(nil, nil))
+
+	_, err = cs.UploadSignatureRequest(&UploadSignatureRequest{UploadID: []byte("unknown"), Roster: el})
+	require.Error(t, err)
+}
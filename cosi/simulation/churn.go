@@ -0,0 +1,306 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	p "go.dedis.ch/cothority/v3/cosi/protocol"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/onet/v3"
+)
+
+/*
+The churn simulation runs rounds of CoSi while nodes are killed, delayed or
+partitioned mid-round, following a schedule. The schedule is a list of events
+separated by semicolons, each of the form
+
+	<rounds>:<action>:<nodes>[:<duration>]
+
+where rounds is a round or a range of rounds like "2-4", counting from 0, and
+nodes are the roster indexes of the nodes, separated by commas. The actions
+are:
+  - kill: the nodes are down, they don't get the announcement and don't commit
+  - crash: the nodes commit, then go down before they respond
+  - delay: the nodes wait for the duration before they commit
+  - partition: the nodes can't talk to the other nodes, so the links between
+    a node and its parent are cut if only one of them is in the partition
+*/
+
+// ChurnName is the protocol run by the churn simulation.
+var ChurnName = "CoSiChurn"
+
+func init() {
+	onet.GlobalProtocolRegister(ChurnName, NewCoSiChurn)
+}
+
+const (
+	churnKill      = "kill"
+	churnCrash     = "crash"
+	churnDelay     = "delay"
+	churnPartition = "partition"
+)
+
+// churnEvent is a change of some nodes during some rounds.
+type churnEvent struct {
+	first, last int
+	action      string
+	nodes       map[int]bool
+	delay       time.Duration
+}
+
+// churnSchedule holds the events of a simulation.
+type churnSchedule []churnEvent
+
+// parseChurn returns the schedule described by s.
+func parseChurn(s string) (churnSchedule, error) {
+	var sched churnSchedule
+	for _, desc := range strings.Split(s, ";") {
+		desc = strings.TrimSpace(desc)
+		if desc == "" {
+			continue
+		}
+		fields := strings.Split(desc, ":")
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("invalid churn event %q", desc)
+		}
+		e := churnEvent{action: fields[1], nodes: make(map[int]bool)}
+		rounds := strings.SplitN(fields[0], "-", 2)
+		var err error
+		if e.first, err = strconv.Atoi(rounds[0]); err != nil {
+			return nil, fmt.Errorf("invalid rounds in %q: %v", desc, err)
+		}
+		e.last = e.first
+		if len(rounds) == 2 {
+			if e.last, err = strconv.Atoi(rounds[1]); err != nil {
+				return nil, fmt.Errorf("invalid rounds in %q: %v", desc, err)
+			}
+		}
+		if e.first < 0 || e.last < e.first {
+			return nil, fmt.Errorf("invalid rounds in %q", desc)
+		}
+		for _, n := range strings.Split(fields[2], ",") {
+			i, err := strconv.Atoi(strings.TrimSpace(n))
+			if err != nil {
+				return nil, fmt.Errorf("invalid node in %q: %v", desc, err)
+			}
+			e.nodes[i] = true
+		}
+		switch e.action {
+		case churnKill, churnCrash, churnPartition:
+			if len(fields) != 3 {
+				return nil, fmt.Errorf("invalid churn event %q", desc)
+			}
+		case churnDelay:
+			if len(fields) != 4 {
+				return nil, fmt.Errorf("delay without duration in %q", desc)
+			}
+			if e.delay, err = time.ParseDuration(fields[3]); err != nil {
+				return nil, fmt.Errorf("invalid delay in %q: %v", desc, err)
+			}
+		default:
+			return nil, fmt.Errorf("unknown action in %q", desc)
+		}
+		sched = append(sched, e)
+	}
+	return sched, nil
+}
+
+// is returns true if the node is affected by the action in the round.
+func (cs churnSchedule) is(action string, round, node int) bool {
+	for _, e := range cs {
+		if e.action == action && round >= e.first && round <= e.last && e.nodes[node] {
+			return true
+		}
+	}
+	return false
+}
+
+// delay returns the time the node waits before it commits in the round.
+func (cs churnSchedule) delay(round, node int) time.Duration {
+	var d time.Duration
+	for _, e := range cs {
+		if e.action == churnDelay && round >= e.first && round <= e.last && e.nodes[node] {
+			d += e.delay
+		}
+	}
+	return d
+}
+
+// cut returns true if a partition of the round separates the node from its
+// parent.
+func (cs churnSchedule) cut(round, node, parent int) bool {
+	if parent < 0 {
+		return false
+	}
+	for _, e := range cs {
+		if e.action == churnPartition && round >= e.first && round <= e.last &&
+			e.nodes[node] != e.nodes[parent] {
+			return true
+		}
+	}
+	return false
+}
+
+// announce applies the schedule to the node when it gets the announcement
+// of the round.
+func (cs churnSchedule) announce(round, node, parent int) error {
+	if cs.is(churnKill, round, node) {
+		return errors.New("killed")
+	}
+	if cs.cut(round, node, parent) {
+		return errors.New("partitioned")
+	}
+	time.Sleep(cs.delay(round, node))
+	return nil
+}
+
+// challenge applies the schedule to the node when it gets the challenge of
+// the round.
+func (cs churnSchedule) challenge(round, node int) error {
+	if cs.is(churnCrash, round, node) {
+		return errors.New("crashed")
+	}
+	return nil
+}
+
+// churnOutcome is the expected result of a round.
+type churnOutcome int
+
+const (
+	expectUnknown churnOutcome = iota
+	expectSuccess
+	expectFailure
+)
+
+// churnExpectation holds what a round must give: the outcome, if it can be
+// told, and the nodes that must sign and the ones that can sign if the
+// round succeeds.
+type churnExpectation struct {
+	outcome churnOutcome
+	lower   map[int]bool
+	upper   map[int]bool
+}
+
+// expect returns the expectation of the round on the tree. A node that
+// waits for less than half the timeout is sure to be in time, and a longer
+// delay leaves it and its subtree uncertain.
+func (cs churnSchedule) expect(round int, root *onet.TreeNode, timeout time.Duration,
+	threshold int) churnExpectation {
+	e := churnExpectation{lower: make(map[int]bool), upper: make(map[int]bool)}
+	var crashLower, crashUpper bool
+	var walk func(n *onet.TreeNode, parent int, sure bool)
+	walk = func(n *onet.TreeNode, parent int, sure bool) {
+		i := n.RosterIndex
+		if cs.is(churnKill, round, i) || cs.cut(round, i, parent) {
+			return
+		}
+		if cs.delay(round, i) >= timeout/2 {
+			sure = false
+		}
+		e.upper[i] = true
+		if sure {
+			e.lower[i] = true
+		}
+		if cs.is(churnCrash, round, i) {
+			crashUpper = true
+			crashLower = crashLower || sure
+		}
+		for _, c := range n.Children {
+			walk(c, i, sure)
+		}
+	}
+	walk(root, -1, true)
+
+	switch {
+	case !e.lower[root.RosterIndex] || crashLower || len(e.upper) < threshold:
+		e.outcome = expectFailure
+	case !crashUpper && len(e.lower) >= threshold:
+		e.outcome = expectSuccess
+	}
+	return e
+}
+
+// check returns an error if the result of the round doesn't match the
+// expectation. The signature itself is verified by the caller.
+func (e churnExpectation) check(sig *p.Signature, err error) error {
+	switch {
+	case sig == nil && e.outcome == expectSuccess:
+		return fmt.Errorf("round failed: %v", err)
+	case sig != nil && e.outcome == expectFailure:
+		return errors.New("round succeeded but was expected to fail")
+	case sig == nil:
+		return nil
+	}
+	signed := make(map[int]bool)
+	for _, i := range sig.Participants {
+		if !e.upper[i] {
+			return fmt.Errorf("node %d signed but was down", i)
+		}
+		signed[i] = true
+	}
+	for i := range e.lower {
+		if !signed[i] {
+			return fmt.Errorf("node %d was up but didn't sign", i)
+		}
+	}
+	return nil
+}
+
+var churnSchedules struct {
+	sync.Mutex
+	schedule churnSchedule
+}
+
+// setChurnSchedule sets the schedule followed by the nodes of this process.
+func setChurnSchedule(cs churnSchedule) {
+	churnSchedules.Lock()
+	churnSchedules.schedule = cs
+	churnSchedules.Unlock()
+}
+
+func getChurnSchedule() churnSchedule {
+	churnSchedules.Lock()
+	defer churnSchedules.Unlock()
+	return churnSchedules.schedule
+}
+
+// roundData returns the data of the announcement telling the nodes the
+// round.
+func roundData(round int) []byte {
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buf, uint64(round))
+	return buf
+}
+
+// NewCoSiChurn returns a CoSi protocol whose node follows the schedule of
+// the churn simulation.
+func NewCoSiChurn(n *onet.TreeNodeInstance) (onet.ProtocolInstance, error) {
+	pi, err := p.NewProtocol(n)
+	if err != nil {
+		return nil, err
+	}
+	c := pi.(*p.CoSi)
+	sched := getChurnSchedule()
+	node := n.TreeNode().RosterIndex
+	parent := -1
+	if n.Parent() != nil {
+		parent = n.Parent().RosterIndex
+	}
+	round := -1
+	c.RegisterAnnouncementHook(func(in *p.Announcement) error {
+		if len(in.Data) != 8 {
+			return errors.New("announcement without round")
+		}
+		round = int(binary.LittleEndian.Uint64(in.Data))
+		return sched.announce(round, node, parent)
+	})
+	c.RegisterChallengeHook(func(kyber.Scalar) error {
+		return sched.challenge(round, node)
+	})
+	return c, nil
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"go.dedis.ch/cothority/v3/cosi/crypto"
+	p "go.dedis.ch/cothority/v3/cosi/protocol"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/onet/v3/simul/monitor"
+)
+
+func init() {
+	onet.SimulationRegister(ChurnName, NewChurnSimulation)
+}
+
+// ChurnSimulation runs rounds of CoSi while the nodes follow the churn
+// schedule, and fails if a round doesn't give the expected result: the
+// rounds with enough nodes must succeed, the others must fail, and the
+// signatures must be valid and only by the nodes that were up.
+type ChurnSimulation struct {
+	onet.SimulationBFTree
+	// Churn is the schedule of the events, see churn.go.
+	Churn string
+	// Timeout is the time a node waits for a leaf, "1s" by default.
+	Timeout string
+	// Threshold is the number of nodes that have to sign, 0 for the
+	// default of the protocol.
+	Threshold int
+
+	schedule churnSchedule
+	timeout  time.Duration
+}
+
+// NewChurnSimulation returns the churn simulation of the configuration.
+func NewChurnSimulation(config string) (onet.Simulation, error) {
+	cs := &ChurnSimulation{Timeout: "1s"}
+	_, err := toml.Decode(config, cs)
+	if err != nil {
+		return nil, err
+	}
+	if cs.schedule, err = parseChurn(cs.Churn); err != nil {
+		return nil, err
+	}
+	if cs.timeout, err = time.ParseDuration(cs.Timeout); err != nil {
+		return nil, err
+	}
+	return cs, nil
+}
+
+// Setup implements onet.Simulation.
+func (cs *ChurnSimulation) Setup(dir string, hosts []string) (*onet.SimulationConfig, error) {
+	sim := new(onet.SimulationConfig)
+	cs.CreateRoster(sim, hosts, 2000)
+	err := cs.CreateTree(sim)
+	return sim, err
+}
+
+// Node implements onet.Simulation.
+func (cs *ChurnSimulation) Node(sc *onet.SimulationConfig) error {
+	setChurnSchedule(cs.schedule)
+	return cs.SimulationBFTree.Node(sc)
+}
+
+// Run implements onet.Simulation.
+func (cs *ChurnSimulation) Run(config *onet.SimulationConfig) error {
+	msg := []byte("Hello World Cosi Churn Simulation")
+	log.Lvl2("Churn simulation starting with: Size=", len(config.Roster.List), ", Rounds=", cs.Rounds)
+	for round := 0; round < cs.Rounds; round++ {
+		log.Lvl1("Starting round", round)
+		roundM := monitor.NewTimeMeasure("round")
+		node, err := config.Overlay.CreateProtocol(ChurnName, config.Tree, onet.NilServiceID)
+		if err != nil {
+			return err
+		}
+		proto := node.(*p.CoSi)
+		proto.SigningMessage(msg)
+		proto.Data = roundData(round)
+		proto.Timeout = cs.timeout
+		if cs.Threshold > 0 {
+			proto.Threshold = cs.Threshold
+		}
+		expect := cs.schedule.expect(round, config.Tree.Root, cs.timeout, proto.Threshold)
+
+		done := make(chan *p.Signature, 1)
+		failed := make(chan error, 1)
+		proto.RegisterDoneHook(func(sig *p.Signature) { done <- sig })
+		// The root can fail both when it starts and later in the round.
+		fail := func(err error) {
+			select {
+			case failed <- err:
+			default:
+			}
+		}
+		proto.RegisterErrorHook(fail)
+		if err := proto.Start(); err != nil {
+			fail(err)
+		}
+
+		var sig *p.Signature
+		select {
+		case sig = <-done:
+		case err = <-failed:
+		case <-time.After(cs.roundTimeout(round, config.Tree)):
+			err = fmt.Errorf("round %d didn't finish", round)
+		}
+		roundM.Record()
+
+		if sig != nil {
+			buf, err := sig.Bytes()
+			if err != nil {
+				return err
+			}
+			err = crypto.VerifySignatureWithPolicy(proto.Suite(), proto.Publics(), msg, buf,
+				crypto.NewThresholdPolicy(proto.Threshold))
+			if err != nil {
+				return fmt.Errorf("round %d: invalid signature: %v", round, err)
+			}
+		}
+		if err := expect.check(sig, err); err != nil {
+			return fmt.Errorf("round %d: %v", round, err)
+		}
+		log.Lvlf2("Round %d => signed: %t, %v", round, sig != nil, err)
+	}
+	log.Lvl1("Churn simulation finished")
+	return nil
+}
+
+// roundTimeout is the time after which a round that neither succeeded nor
+// failed is considered stuck: the root waits for the commitments and the
+// responses of each level of the tree, and the nodes can be delayed on top
+// of it.
+func (cs *ChurnSimulation) roundTimeout(round int, tree *onet.Tree) time.Duration {
+	var delays time.Duration
+	for i := range tree.Roster.List {
+		delays += cs.schedule.delay(round, i)
+	}
+	return 4*cs.timeout*time.Duration(treeDepth(tree.Root)+1) + delays + 5*time.Second
+}
+
+// treeDepth returns the number of levels below the node.
+func treeDepth(n *onet.TreeNode) int {
+	depth := 0
+	for _, c := range n.Children {
+		if d := treeDepth(c) + 1; d > depth {
+			depth = d
+		}
+	}
+	return depth
+}
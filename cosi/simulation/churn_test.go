@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	p "go.dedis.ch/cothority/v3/cosi/protocol"
+	"go.dedis.ch/onet/v3"
+)
+
+func TestParseChurn(t *testing.T) {
+	sched, err := parseChurn("1:kill:3; 2-4:delay:1,2:500ms ;5:partition:4,5;6:crash:2")
+	require.NoError(t, err)
+	require.Equal(t, 4, len(sched))
+	require.True(t, sched.is(churnKill, 1, 3))
+	require.False(t, sched.is(churnKill, 2, 3))
+	require.Equal(t, 500*time.Millisecond, sched.delay(3, 2))
+	require.Equal(t, time.Duration(0), sched.delay(5, 2))
+	require.True(t, sched.cut(5, 4, 1))
+	require.False(t, sched.cut(5, 4, 5))
+	require.False(t, sched.cut(5, 0, -1))
+	require.True(t, sched.is(churnCrash, 6, 2))
+
+	for _, s := range []string{"1:kill", "a:kill:1", "3-1:kill:1", "1:delay:1",
+		"1:delay:1:soon", "1:explode:1", "1:kill:x", "1:kill:1:1s"} {
+		_, err := parseChurn(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestChurnExpectation(t *testing.T) {
+	local := onet.NewLocalTest(tSuite)
+	defer local.CloseAll()
+	_, roster, _ := local.GenTree(7, false)
+	tree := roster.GenerateNaryTree(2)
+	root := tree.Root.RosterIndex
+	inner := tree.Root.Children[0].RosterIndex
+	leaf := tree.Root.Children[1].Children[0].RosterIndex
+	timeout := time.Second
+
+	expect := func(churn string, threshold int) churnExpectation {
+		sched, err := parseChurn(churn)
+		require.NoError(t, err)
+		return sched.expect(0, tree.Root, timeout, threshold)
+	}
+
+	e := expect("", 7)
+	require.Equal(t, expectSuccess, e.outcome)
+	require.Equal(t, 7, len(e.lower))
+
+	// Killing an inner node loses its subtree.
+	e = expect("0:kill:"+strconv.Itoa(inner), 5)
+	require.Equal(t, expectFailure, e.outcome)
+	e = expect("0:kill:"+strconv.Itoa(inner), 4)
+	require.Equal(t, expectSuccess, e.outcome)
+	require.Equal(t, 4, len(e.upper))
+
+	// A partition cuts the links across it.
+	e = expect("0:partition:"+strconv.Itoa(leaf), 6)
+	require.Equal(t, expectSuccess, e.outcome)
+	require.False(t, e.upper[leaf])
+
+	// A long delay makes the subtree uncertain.
+	e = expect("0:delay:"+strconv.Itoa(inner)+":2s", 6)
+	require.Equal(t, expectUnknown, e.outcome)
+	require.Equal(t, 4, len(e.lower))
+	require.Equal(t, 7, len(e.upper))
+
+	// A node crashing after its commitment makes the round fail.
+	e = expect("0:crash:"+strconv.Itoa(leaf), 4)
+	require.Equal(t, expectFailure, e.outcome)
+	e = expect("0:kill:"+strconv.Itoa(root), 1)
+	require.Equal(t, expectFailure, e.outcome)
+
+	require.NoError(t, e.check(nil, errors.New("killed")))
+	require.Error(t, e.check(&p.Signature{}, nil))
+
+	// A successful round must be signed by the nodes that were up, and only
+	// by them.
+	e = expect("0:kill:"+strconv.Itoa(leaf), 6)
+	require.Error(t, e.check(nil, errors.New("timeout")))
+	var all, up []int
+	for i := 0; i < 7; i++ {
+		all = append(all, i)
+		if i != leaf {
+			up = append(up, i)
+		}
+	}
+	require.NoError(t, e.check(&p.Signature{Participants: up}, nil))
+	require.Error(t, e.check(&p.Signature{Participants: all}, nil))
+	require.Error(t, e.check(&p.Signature{Participants: up[1:]}, nil))
+}
@@ -11,3 +11,8 @@ func TestSimulation(t *testing.T) {
 	os.Args = []string{os.Args[0], "cosi_verification.toml"}
 	main()
 }
+
+func TestChurnSimulation(t *testing.T) {
+	os.Args = []string{os.Args[0], "churn.toml"}
+	main()
+}
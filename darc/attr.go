@@ -0,0 +1,86 @@
+package darc
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// attrOps are the operators of attribute predicates. The two-character
+// operators come first so that they are not taken for "<" or ">".
+var attrOps = []string{"<=", ">=", "==", "!=", "<", ">"}
+
+// ParseAttrPredicate splits the argument given to the interpreter of an
+// attribute predicate, like ">=18", into its operator and its value.
+func ParseAttrPredicate(pred string) (op, value string, err error) {
+	for _, o := range attrOps {
+		if strings.HasPrefix(pred, o) {
+			if len(pred) == len(o) {
+				return "", "", errors.New("predicate has no value")
+			}
+			return o, pred[len(o):], nil
+		}
+	}
+	return "", "", fmt.Errorf("predicate %s has no valid operator", pred)
+}
+
+// CompareAttr returns nil if "actual op value" is true. If both actual and
+// value are numbers, they are compared numerically. Otherwise only "==" and
+// "!=" are supported, and the strings are compared.
+func CompareAttr(actual, op, value string) error {
+	var cmp int
+	a, okA := new(big.Rat).SetString(actual)
+	v, okV := new(big.Rat).SetString(value)
+	if okA && okV {
+		cmp = a.Cmp(v)
+	} else {
+		switch op {
+		case "==", "!=":
+			cmp = strings.Compare(actual, value)
+		default:
+			return fmt.Errorf("cannot compare non-numbers %s and %s with %s",
+				actual, value, op)
+		}
+	}
+
+	var ok bool
+	switch op {
+	case "<":
+		ok = cmp < 0
+	case "<=":
+		ok = cmp <= 0
+	case ">":
+		ok = cmp > 0
+	case ">=":
+		ok = cmp >= 0
+	case "==":
+		ok = cmp == 0
+	case "!=":
+		ok = cmp != 0
+	default:
+		return fmt.Errorf("unknown operator %s", op)
+	}
+	if !ok {
+		return fmt.Errorf("attribute is %s, which is not %s %s", actual, op, value)
+	}
+	return nil
+}
+
+// AttrPredicate returns an attribute interpreter for predicates like
+// "attr:tx_amount<1000". The get callback returns the current value of the
+// attribute, e.g. read from the instruction being verified, which is then
+// compared to the value of the predicate with CompareAttr.
+func AttrPredicate(get func() (string, error)) func(string) error {
+	return func(pred string) error {
+		op, value, err := ParseAttrPredicate(pred)
+		if err != nil {
+			return err
+		}
+		actual, err := get()
+		if err != nil {
+			return err
+		}
+		return CompareAttr(actual, op, value)
+	}
+}
@@ -0,0 +1,61 @@
+package darc
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareAttr(t *testing.T) {
+	require.NoError(t, CompareAttr("18", ">=", "18"))
+	require.NoError(t, CompareAttr("18", "<", "18.5"))
+	require.NoError(t, CompareAttr("18446744073709551615", ">", "18446744073709551614"))
+	require.Error(t, CompareAttr("17", ">=", "18"))
+	require.NoError(t, CompareAttr("CH", "==", "CH"))
+	require.NoError(t, CompareAttr("CH", "!=", "FR"))
+	require.Error(t, CompareAttr("CH", "!=", "CH"))
+	require.Error(t, CompareAttr("CH", "<", "FR"))
+
+	_, _, err := ParseAttrPredicate(">=")
+	require.Error(t, err)
+	_, _, err = ParseAttrPredicate("=>18")
+	require.Error(t, err)
+	op, value, err := ParseAttrPredicate("<=18")
+	require.NoError(t, err)
+	require.Equal(t, "<=", op)
+	require.Equal(t, "18", value)
+}
+
+func TestAttrPredicate(t *testing.T) {
+	amount := 500
+	attrFuncs := AttrInterpreters{
+		"tx_amount": AttrPredicate(func() (string, error) {
+			return strconv.Itoa(amount), nil
+		}),
+		"age": AttrPredicate(func() (string, error) {
+			return "", errors.New("age is unknown")
+		}),
+	}
+	id := createIdentity()
+
+	expr := []byte(id.String() + " & attr:tx_amount<1000")
+	require.NoError(t, EvalExprAttr(expr, nil, attrFuncs, id.String()))
+	amount = 1000
+	require.Error(t, EvalExprAttr(expr, nil, attrFuncs, id.String()))
+
+	// A bigger amount needs another signer.
+	other := createIdentity()
+	expr = []byte("(" + id.String() + " & attr:tx_amount<1000) | " +
+		"(" + id.String() + " & " + other.String() + ")")
+	require.Error(t, EvalExprAttr(expr, nil, attrFuncs, id.String()))
+	require.NoError(t, EvalExprAttr(expr, nil, attrFuncs, id.String(), other.String()))
+
+	err := EvalExprAttr([]byte("attr:age>=18"), nil, attrFuncs, id.String())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "age is unknown")
+	err = EvalExprAttr([]byte("attr:height>=18"), nil, attrFuncs, id.String())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "no such attr interpreter")
+}
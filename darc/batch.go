@@ -0,0 +1,156 @@
+package darc
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/sign/bls"
+	"go.dedis.ch/kyber/v3/util/random"
+)
+
+// BatchVerifier verifies many signatures at once. All the Ed25519 signatures
+// are verified with a single equation, and all the BLS signatures with a
+// single pairing check, which takes less time than verifying them one by
+// one. The signatures of the other identities are verified one by one.
+type BatchVerifier struct {
+	entries []batchEntry
+}
+
+type batchEntry struct {
+	id  Identity
+	msg []byte
+	sig []byte
+}
+
+// Add adds the signature of the message by the identity to the batch.
+func (bv *BatchVerifier) Add(id Identity, msg, sig []byte) {
+	bv.entries = append(bv.entries, batchEntry{id: id, msg: msg, sig: sig})
+}
+
+// Verify returns, for every signature in the order of Add, whether it is
+// valid. If the verification of a batch fails, its signatures are verified
+// one by one to find the invalid ones.
+func (bv *BatchVerifier) Verify() []bool {
+	valid := make([]bool, len(bv.entries))
+	var ed25519s, blss []int
+	for i, e := range bv.entries {
+		switch {
+		case e.id.Ed25519 != nil:
+			ed25519s = append(ed25519s, i)
+		case e.id.BLS != nil:
+			blss = append(blss, i)
+		default:
+			valid[i] = e.id.Verify(e.msg, e.sig) == nil
+		}
+	}
+	for _, batch := range []struct {
+		indexes []int
+		verify  func([]batchEntry) bool
+	}{
+		{ed25519s, batchVerifyEd25519},
+		{blss, batchVerifyBLS},
+	} {
+		var entries []batchEntry
+		for _, i := range batch.indexes {
+			entries = append(entries, bv.entries[i])
+		}
+		ok := len(entries) > 1 && batch.verify(entries)
+		for _, i := range batch.indexes {
+			valid[i] = ok || bv.entries[i].id.Verify(bv.entries[i].msg, bv.entries[i].sig) == nil
+		}
+	}
+	return valid
+}
+
+// batchVerifyEd25519 verifies the Schnorr signatures with a random linear
+// combination of their verification equations s*G = R + h*A. As for the
+// verification of a single signature, the equations are not multiplied by
+// the cofactor. Instead, the encodings must be canonical and the points
+// must not have a small order component, so that the combination is zero
+// only if every equation holds. Otherwise, the caller falls back to
+// verifying the signatures one by one.
+func batchVerifyEd25519(entries []batchEntry) bool {
+	suite := cothority.Suite
+	pointLen := suite.PointLen()
+	sumS := suite.Scalar().Zero()
+	sum := suite.Point().Null()
+	for _, e := range entries {
+		if e.id.Ed25519.Point == nil || len(e.sig) != pointLen+suite.ScalarLen() {
+			return false
+		}
+		r := suite.Point()
+		if err := r.UnmarshalBinary(e.sig[:pointLen]); err != nil {
+			return false
+		}
+		s := suite.Scalar()
+		if err := s.UnmarshalBinary(e.sig[pointLen:]); err != nil {
+			return false
+		}
+		if !canonical(r, e.sig[:pointLen]) || !canonical(s, e.sig[pointLen:]) ||
+			!primeOrder(r) || !primeOrder(e.id.Ed25519.Point) {
+			return false
+		}
+		h, err := schnorrChallenge(r, e.id.Ed25519.Point, e.msg)
+		if err != nil {
+			return false
+		}
+		z := suite.Scalar().Pick(random.New())
+		sumS.Add(sumS, suite.Scalar().Mul(z, s))
+		sum.Add(sum, suite.Point().Mul(z, r))
+		sum.Add(sum, suite.Point().Mul(suite.Scalar().Mul(z, h), e.id.Ed25519.Point))
+	}
+	return suite.Point().Mul(sumS, nil).Equal(sum)
+}
+
+// canonical returns true if buf is the encoding of m.
+func canonical(m encoding.BinaryMarshaler, buf []byte) bool {
+	enc, err := m.MarshalBinary()
+	return err == nil && bytes.Equal(enc, buf)
+}
+
+// primeOrder returns true if the point has no small order component, as
+// (l-1)*P = -P only holds if l*P is the neutral element.
+func primeOrder(p kyber.Point) bool {
+	suite := cothority.Suite
+	q := suite.Point().Mul(suite.Scalar().SetInt64(-1), p)
+	return q.Add(q, p).Equal(suite.Point().Null())
+}
+
+// schnorrChallenge computes the challenge of the Schnorr signatures of
+// kyber, which is the SHA-512 hash of R, the public key and the message.
+func schnorrChallenge(r, public kyber.Point, msg []byte) (kyber.Scalar, error) {
+	h := sha512.New()
+	if _, err := r.MarshalTo(h); err != nil {
+		return nil, err
+	}
+	if _, err := public.MarshalTo(h); err != nil {
+		return nil, err
+	}
+	h.Write(msg)
+	return cothority.Suite.Scalar().SetBytes(h.Sum(nil)), nil
+}
+
+// batchVerifyBLS aggregates the BLS signatures and verifies the aggregate.
+// It fails if two entries are the same message signed by the same key.
+func batchVerifyBLS(entries []batchEntry) bool {
+	publics := make([]kyber.Point, len(entries))
+	msgs := make([][]byte, len(entries))
+	sigs := make([][]byte, len(entries))
+	for i, e := range entries {
+		var err error
+		publics[i], err = e.id.BLS.point()
+		if err != nil {
+			return false
+		}
+		msgs[i] = blsMessage(e.msg, e.id.BLS.Public)
+		sigs[i] = e.sig
+	}
+	agg, err := bls.AggregateSignatures(blsSuite, sigs...)
+	if err != nil {
+		return false
+	}
+	return bls.BatchVerify(blsSuite, publics, msgs, agg) == nil
+}
@@ -0,0 +1,65 @@
+package darc
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
+)
+
+func TestBatchVerifier(t *testing.T) {
+	var signers []Signer
+	for i := 0; i < 4; i++ {
+		signers = append(signers, NewSignerEd25519(nil, nil), NewSignerBLS(nil, nil))
+	}
+	signers = append(signers, NewSignerSecp256k1(nil))
+
+	var entries []batchEntry
+	for i, s := range signers {
+		msg := []byte{byte(i)}
+		sig, err := s.Sign(msg)
+		require.NoError(t, err)
+		entries = append(entries, batchEntry{id: s.Identity(), msg: msg, sig: sig})
+	}
+	require.True(t, batchVerifyEd25519([]batchEntry{entries[0], entries[2], entries[4]}))
+	require.True(t, batchVerifyBLS([]batchEntry{entries[1], entries[3], entries[5]}))
+
+	var bv BatchVerifier
+	for _, e := range entries {
+		bv.Add(e.id, e.msg, e.sig)
+	}
+	for _, ok := range bv.Verify() {
+		require.True(t, ok)
+	}
+
+	// A wrong signature in each batch is found.
+	bv = BatchVerifier{}
+	for i, e := range entries {
+		msg := e.msg
+		if i == 2 || i == 5 || i == 8 {
+			msg = []byte("wrong")
+		}
+		bv.Add(e.id, msg, e.sig)
+	}
+	for i, ok := range bv.Verify() {
+		require.Equal(t, i != 2 && i != 5 && i != 8, ok, "signature %d", i)
+	}
+	require.False(t, batchVerifyEd25519([]batchEntry{entries[0], {entries[2].id, entries[2].msg, entries[0].sig}}))
+	require.False(t, batchVerifyBLS([]batchEntry{entries[1], entries[1]}))
+
+	// A small order component in R is refused, as the single verification
+	// does.
+	torsion := cothority.Suite.Point()
+	require.NoError(t, torsion.UnmarshalBinary(append([]byte{0xec},
+		append(bytes.Repeat([]byte{0xff}, 30), 0x7f)...)))
+	require.False(t, primeOrder(torsion))
+	r := cothority.Suite.Point()
+	require.NoError(t, r.UnmarshalBinary(entries[0].sig[:32]))
+	require.True(t, primeOrder(r))
+	rBuf, err := r.Add(r, torsion).MarshalBinary()
+	require.NoError(t, err)
+	mixed := batchEntry{entries[0].id, entries[0].msg, append(rBuf, entries[0].sig[32:]...)}
+	require.False(t, batchVerifyEd25519([]batchEntry{mixed, entries[2]}))
+	require.Error(t, mixed.id.Verify(mixed.msg, mixed.sig))
+}
@@ -0,0 +1,138 @@
+package darc
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/pairing"
+	"go.dedis.ch/kyber/v3/sign/bls"
+	"go.dedis.ch/kyber/v3/util/random"
+)
+
+// blsSuite is the pairing suite of the BLS identities. BLS12-381 is not
+// available in our version of kyber, so bn256 is used, like for the
+// collective signatures of blscosi. The keys and signatures of the two curves
+// are not compatible: BLS12-381 keys will need their own identity once kyber
+// is upgraded, and the "bls" identities stay on bn256.
+var blsSuite = pairing.NewSuiteBn256()
+
+// A BLS signer signs the message followed by its public key. As every signer
+// signs a different message, the signatures of many signers can be
+// aggregated without being vulnerable to rogue public key attacks.
+func blsMessage(msg, public []byte) []byte {
+	return append(append([]byte{}, msg...), public...)
+}
+
+// NewIdentityBLS creates a new BLS identity struct given a point of the G2
+// group of bn256.
+func NewIdentityBLS(point kyber.Point) Identity {
+	buf, err := point.MarshalBinary()
+	if err != nil {
+		return Identity{}
+	}
+	return Identity{
+		BLS: &IdentityBLS{
+			Public: buf,
+		},
+	}
+}
+
+// Equal returns true if both IdentityBLS hold the same public key.
+func (idb IdentityBLS) Equal(idb2 *IdentityBLS) bool {
+	return bytes.Equal(idb.Public, idb2.Public)
+}
+
+// Verify returns nil if the signature is correct, or an error if something
+// fails.
+func (idb IdentityBLS) Verify(msg, sig []byte) error {
+	public, err := idb.point()
+	if err != nil {
+		return err
+	}
+	return bls.Verify(blsSuite, public, blsMessage(msg, idb.Public), sig)
+}
+
+func (idb IdentityBLS) point() (kyber.Point, error) {
+	public := blsSuite.G2().Point()
+	if err := public.UnmarshalBinary(idb.Public); err != nil {
+		return nil, fmt.Errorf("invalid BLS public key: %v", err)
+	}
+	return public, nil
+}
+
+func parseIDBLS(in string) (Identity, error) {
+	id := &IdentityBLS{}
+	var err error
+	id.Public, err = hex.DecodeString(in)
+	if err != nil {
+		return Identity{}, err
+	}
+	if _, err := id.point(); err != nil {
+		return Identity{}, err
+	}
+	return Identity{BLS: id}, nil
+}
+
+// AggregateBLSSignatures aggregates the signatures of BLS signers on the same
+// message into a single signature, which can be verified with
+// VerifyBLSAggregate.
+func AggregateBLSSignatures(sigs ...[]byte) ([]byte, error) {
+	if len(sigs) == 0 {
+		return nil, errors.New("no signatures to aggregate")
+	}
+	return bls.AggregateSignatures(blsSuite, sigs...)
+}
+
+// VerifyBLSAggregate returns nil if sig is the aggregate of the signatures of
+// all the BLS identities on the message.
+func VerifyBLSAggregate(msg []byte, ids []Identity, sig []byte) error {
+	if len(ids) == 0 {
+		return errors.New("no identities to verify")
+	}
+	publics := make([]kyber.Point, len(ids))
+	msgs := make([][]byte, len(ids))
+	for i, id := range ids {
+		if id.BLS == nil {
+			return fmt.Errorf("identity %d is not a BLS identity", i)
+		}
+		var err error
+		publics[i], err = id.BLS.point()
+		if err != nil {
+			return err
+		}
+		msgs[i] = blsMessage(msg, id.BLS.Public)
+	}
+	return bls.BatchVerify(blsSuite, publics, msgs, sig)
+}
+
+// NewSignerBLS initializes a new SignerBLS given public and private keys. If
+// either of the given keys is nil, then a new key pair is generated.
+func NewSignerBLS(public kyber.Point, private kyber.Scalar) Signer {
+	if public == nil || private == nil {
+		private, public = bls.NewKeyPair(blsSuite, random.New())
+	}
+	pubBuf, err := public.MarshalBinary()
+	if err != nil {
+		return Signer{}
+	}
+	secBuf, err := private.MarshalBinary()
+	if err != nil {
+		return Signer{}
+	}
+	return Signer{BLS: &SignerBLS{
+		Public: pubBuf,
+		Secret: secBuf,
+	}}
+}
+
+// Sign creates a BLS signature on the message followed by the public key.
+func (sb SignerBLS) Sign(msg []byte) ([]byte, error) {
+	private := blsSuite.G2().Scalar()
+	if err := private.UnmarshalBinary(sb.Secret); err != nil {
+		return nil, fmt.Errorf("invalid BLS private key: %v", err)
+	}
+	return bls.Sign(blsSuite, private, blsMessage(msg, sb.Public))
+}
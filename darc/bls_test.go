@@ -0,0 +1,57 @@
+package darc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBLS(t *testing.T) {
+	signer := NewSignerBLS(nil, nil)
+	id := signer.Identity()
+	require.Equal(t, 7, id.Type())
+	require.Equal(t, "bls", id.TypeString())
+
+	msg := []byte("document")
+	sig, err := signer.Sign(msg)
+	require.NoError(t, err)
+	require.NoError(t, id.Verify(msg, sig))
+	require.Error(t, id.Verify([]byte("other document"), sig))
+	require.Error(t, NewSignerBLS(nil, nil).Identity().Verify(msg, sig))
+
+	parsed, err := ParseIdentity(id.String())
+	require.NoError(t, err)
+	require.True(t, parsed.Equal(&id))
+	_, err = ParseIdentity("bls:aabb")
+	require.Error(t, err)
+
+	require.NoError(t, EvalExpr([]byte(id.String()+" | ed25519:aa"), nil, id.String()))
+}
+
+func TestBLSAggregate(t *testing.T) {
+	msg := []byte("document")
+	var ids []Identity
+	var sigs [][]byte
+	for i := 0; i < 3; i++ {
+		signer := NewSignerBLS(nil, nil)
+		sig, err := signer.Sign(msg)
+		require.NoError(t, err)
+		ids = append(ids, signer.Identity())
+		sigs = append(sigs, sig)
+	}
+
+	agg, err := AggregateBLSSignatures(sigs...)
+	require.NoError(t, err)
+	require.NoError(t, VerifyBLSAggregate(msg, ids, agg))
+	require.Error(t, VerifyBLSAggregate([]byte("other document"), ids, agg))
+	require.Error(t, VerifyBLSAggregate(msg, ids[:2], agg))
+
+	// The same signature twice is not the aggregate of two signers.
+	agg, err = AggregateBLSSignatures(sigs[0], sigs[0])
+	require.NoError(t, err)
+	require.Error(t, VerifyBLSAggregate(msg, []Identity{ids[0], ids[0]}, agg))
+
+	_, err = AggregateBLSSignatures()
+	require.Error(t, err)
+	require.Error(t, VerifyBLSAggregate(msg, []Identity{createIdentity()}, sigs[0]))
+}
@@ -45,6 +45,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/btcsuite/btcd/btcec"
 	"go.dedis.ch/cothority/v3"
 	"go.dedis.ch/cothority/v3/darc/expression"
 	"go.dedis.ch/kyber/v3"
@@ -54,6 +55,7 @@ import (
 	"go.dedis.ch/kyber/v3/util/encoding"
 	"go.dedis.ch/kyber/v3/util/key"
 	"go.dedis.ch/protobuf"
+	"golang.org/x/crypto/sha3"
 )
 
 const evolve = "_evolve"
@@ -80,7 +82,9 @@ type GetDarc func(s string, latest bool) *Darc
 // value is the actual callback that performs the verification. If other
 // information is needed for the verification then the callback should be
 // created as a closure. The callback should return an error when the
-// verification fails with a descriptive error message.
+// verification fails with a descriptive error message. For attribute
+// predicates like "attr:age>=18", the callback gets the operator followed by
+// the value, and can be created with AttrPredicate.
 type AttrInterpreters map[string]func(string) error
 
 // InitRules initialise a set of rules with the default actions "_evolve" and
@@ -145,6 +149,9 @@ func (d *Darc) Copy() *Darc {
 		dCopy.VerificationDarcs[i] = d.VerificationDarcs[i]
 	}
 	dCopy.Rules = d.Rules.Copy()
+	if len(d.Revoked) > 0 {
+		dCopy.Revoked = append([]string{}, d.Revoked...)
+	}
 	return dCopy
 }
 
@@ -192,6 +199,14 @@ func (d Darc) GetID() ID {
 		h.Write([]byte(rule.Action))
 		h.Write(rule.Expr)
 	}
+	// Only hash the revocations if there are any, so that the IDs of the
+	// darcs created before revocations existed don't change.
+	if len(d.Revoked) > 0 {
+		h.Write([]byte("revoked"))
+		for _, id := range d.Revoked {
+			h.Write([]byte(id))
+		}
+	}
 	return h.Sum(nil)
 }
 
@@ -469,7 +484,7 @@ func (r *Request) VerifyWithCB(d *Darc, getDarc GetDarc) error {
 		}
 	}
 	validIDs := r.GetIdentityStrings()
-	err := EvalExpr(d.Rules.Get(r.Action), getDarc, validIDs...)
+	err := d.EvalRule(r.Action, getDarc, nil, 0, validIDs...)
 	if err != nil {
 		return err
 	}
@@ -488,6 +503,12 @@ func (d Darc) String() string {
 	for _, v := range d.Rules.List {
 		fmt.Fprintf(res, "\n--- %s - \"%s\"", v.Action, v.Expr)
 	}
+	if len(d.Revoked) > 0 {
+		res.WriteString("\n-- Revoked:")
+		for _, id := range d.Revoked {
+			fmt.Fprintf(res, "\n--- %s", id)
+		}
+	}
 	res.WriteString("\n-- Signatures:")
 	for i, sig := range d.Signatures {
 		fmt.Fprintf(res, "\n--- %d - id: %s, sig: %x", i, sig.Signer.String(), sig.Signature)
@@ -495,6 +516,57 @@ func (d Darc) String() string {
 	return res.String()
 }
 
+// Revoke adds the identities to the revocation list of the darc. A revoked
+// identity is refused by all the rules of the darc, and of the darcs it
+// delegates to, even if a rule holds it. This is used on a new version of
+// the darc, which is then evolved like for any other change.
+func (d *Darc) Revoke(ids ...string) {
+	for _, id := range ids {
+		if !d.IsRevoked(id) {
+			d.Revoked = append(d.Revoked, id)
+		}
+	}
+}
+
+// Unrevoke removes the identities from the revocation list of the darc.
+func (d *Darc) Unrevoke(ids ...string) {
+	for _, id := range ids {
+		for i := range d.Revoked {
+			if d.Revoked[i] == id {
+				d.Revoked = append(d.Revoked[:i], d.Revoked[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// IsRevoked returns true if the identity is in the revocation list of the
+// darc.
+func (d Darc) IsRevoked(id string) bool {
+	for _, r := range d.Revoked {
+		if r == id {
+			return true
+		}
+	}
+	return false
+}
+
+// addRevoked returns a new set holding the revoked identities and the ones
+// of the darc.
+func (d Darc) addRevoked(revoked map[string]bool) map[string]bool {
+	if len(d.Revoked) == 0 {
+		return revoked
+	}
+	res := make(map[string]bool)
+	for id := range revoked {
+		res[id] = true
+	}
+	for _, id := range d.Revoked {
+		res[id] = true
+	}
+	return res
+}
+
 // IsNull returns true if this DarcID is not initialised.
 func (id ID) IsNull() bool {
 	return id == nil
@@ -569,11 +641,12 @@ func verifyOneEvolution(newDarc, prevDarc *Darc, getDarc func(string, bool) *Dar
 		return err
 	}
 
-	// check that signers have the permission
-	if err := EvalExprWithSigs(
-		prevDarc.Rules.GetEvolutionExpr(),
-		getDarc,
-		newDarc.Signatures...); err != nil {
+	// check that signers have the permission, and are not revoked
+	signers := make([]string, len(newDarc.Signatures))
+	for i, sig := range newDarc.Signatures {
+		signers[i] = sig.Signer.String()
+	}
+	if err := prevDarc.EvalRule(evolve, getDarc, nil, 0, signers...); err != nil {
 		return err
 	}
 
@@ -628,52 +701,109 @@ func EvalExpr(expr expression.Expr, getDarc GetDarc, ids ...string) error {
 	return EvalExprDarc(expr, getDarc, false, ids...)
 }
 
+// evalAttr calls the interpreter of the attribute. For "attr:name:value" the
+// interpreter gets the value, and for a predicate like "attr:name>=value" it
+// gets the operator and the value, e.g. ">=value".
 func evalAttr(s string, attrFuncs AttrInterpreters) error {
-	tokens := strings.SplitN(s, ":", 3)
-	if len(tokens) != 3 {
+	tokens := strings.SplitN(s, ":", 2)
+	if len(tokens) != 2 {
 		return errors.New("attr has an invalid format")
 	}
 	if tokens[0] != "attr" {
 		return errors.New("first token should be attr")
 	}
+	end := strings.IndexAny(tokens[1], ":<>=!")
+	if end <= 0 {
+		return errors.New("attr has an invalid format")
+	}
+	name, arg := tokens[1][:end], tokens[1][end:]
+	if arg[0] == ':' {
+		arg = arg[1:]
+	}
 
 	var ok bool
 	var attrFunc func(string) error
-	if attrFunc, ok = attrFuncs[tokens[1]]; !ok {
-		return errors.New("no such attr interpreter: " + tokens[1])
+	if attrFunc, ok = attrFuncs[name]; !ok {
+		return errors.New("no such attr interpreter: " + name)
 	}
-	return attrFunc(tokens[2])
+	return attrFunc(arg)
+}
+
+// evalOptions holds the parameters of evalExprDarc that stay the same for all
+// the darcs of an evaluation.
+type evalOptions struct {
+	getDarc    GetDarc
+	attrFuncs  AttrInterpreters
+	acceptDarc bool
+	// maxDepth limits the number of nested darcs, 0 means no limit.
+	maxDepth int
 }
 
+// errEvalFalse and errNegationFalse are returned for expressions that are
+// only false, as opposed to the errors of the leaves that could not be
+// evaluated.
+var (
+	errEvalFalse     = errors.New("expression evaluated to false")
+	errNegationFalse = errors.New("expression evaluated to false because of a negation")
+)
+
 // evalExprDarc takes an extra visited parameter to track the visited nodes and
-// avoid infinite recursion.
-func evalExprDarc(visited map[string]bool, expr expression.Expr, getDarc GetDarc,
-	attrFuncs AttrInterpreters, acceptDarc bool, ids ...string) error {
+// avoid infinite recursion. As visited only holds the darcs of the current
+// path, its length is also the delegation depth. The revoked identities are
+// refused, even if they are one of the ids. If the expression has a
+// negation, a leaf that cannot be evaluated makes the whole expression fail,
+// as a negated error would otherwise grant access.
+func evalExprDarc(visited, revoked map[string]bool, expr expression.Expr,
+	opts evalOptions, ids ...string) error {
+
+	negation := bytes.ContainsRune(expr, '!')
+	if negation {
+		if err := expression.Validate(expr); err != nil {
+			return err
+		}
+	}
 
-	var issue error
+	// issue is the reason of the last false leaf, and failure the first
+	// leaf that could not be evaluated.
+	var issue, failure error
+	fail := func(err error) bool {
+		issue = err
+		if failure == nil {
+			failure = err
+		}
+		return false
+	}
 	Y := expression.InitParser(func(s string) bool {
 		if strings.HasPrefix(s, "attr") {
-			if err := evalAttr(s, attrFuncs); err != nil {
-				issue = err
-				return false
+			if err := evalAttr(s, opts.attrFuncs); err != nil {
+				return fail(err)
 			}
 			return true
 		}
+		if revoked[s] {
+			return fail(fmt.Errorf("identity %s is revoked", s))
+		}
 
 		found := false
 		for _, id := range ids {
-			if id == s {
+			if revoked[id] {
+				continue
+			}
+			if id == s || matchX509Cert(s, id) {
 				found = true
 			}
 		}
-		if strings.HasPrefix(s, "darc") {
-			if acceptDarc && found {
+		if strings.HasPrefix(s, "darc") || strings.HasPrefix(s, "rule") {
+			if opts.acceptDarc && found {
 				return true
 			}
 			// prevent cycles by checking the visited map
 			if _, ok := visited[s]; ok {
-				issue = errors.New("cycle detected")
-				return false
+				return fail(errors.New("cycle detected"))
+			}
+			if opts.maxDepth > 0 && len(visited) >= opts.maxDepth {
+				return fail(fmt.Errorf("delegation to %s exceeds the maximum "+
+					"depth of %d darcs", s, opts.maxDepth))
 			}
 			// we make a copy so that diamond delegation will work,
 			// seeTestDarc_DelegationDiamond
@@ -682,31 +812,42 @@ func evalExprDarc(visited map[string]bool, expr expression.Expr, getDarc GetDarc
 				newVisited[k] = v
 			}
 			newVisited[s] = true
+			// A darc identity delegates to the "sign" action, and a
+			// rule identity to the action of the rule.
+			darcID, action := s, Action(sign)
+			if strings.HasPrefix(s, "rule") {
+				id, err := ParseIdentity(s)
+				if err != nil {
+					return fail(err)
+				}
+				darcID = NewIdentityDarc(id.Rule.ID).String()
+				action = id.Rule.Action
+			}
 			// getDarc is responsible for returning the latest Darc
-			d := getDarc(s, true)
+			d := opts.getDarc(darcID, true)
 			if d == nil {
-				issue = fmt.Errorf("unable to get the darc %s", s)
-				return false
+				return fail(fmt.Errorf("unable to get the darc %s", darcID))
 			}
-			// Evaluate the "sign" action only in the latest darc
-			// because it may have revoked some rules in earlier
-			// darcs. We do this recursively because there may be
-			// further delegations.
-			if !d.Rules.Contains(sign) {
-				issue = errors.New(sign + " rule does not exist")
-				return false
+			// Evaluate the action only in the latest darc because it
+			// may have revoked some rules in earlier darcs. We do
+			// this recursively because there may be further
+			// delegations.
+			if !d.Rules.Contains(action) {
+				return fail(errors.New(string(action) + " rule does not exist"))
 			}
-			signExpr := d.Rules.GetSignExpr()
-			// Recursively evaluate the sign expression until we
-			// find the final signer.
-			if err := evalExprDarc(newVisited, signExpr, getDarc, attrFuncs, acceptDarc, ids...); err != nil {
-				issue = err
-				return false
+			// Recursively evaluate the expression until we find the
+			// final signer.
+			if err := evalExprDarc(newVisited, d.addRevoked(revoked), d.Rules.Get(action), opts, ids...); err != nil {
+				if err == errEvalFalse || err == errNegationFalse {
+					issue = err
+					return false
+				}
+				return fail(err)
 			}
 			return true
 		}
 		if !found {
-			issue = errors.New("expression evaluated to false")
+			issue = errEvalFalse
 		}
 		return found
 	})
@@ -714,7 +855,13 @@ func evalExprDarc(visited map[string]bool, expr expression.Expr, getDarc GetDarc
 	if err != nil {
 		return err
 	}
+	if negation && failure != nil {
+		return failure
+	}
 	if res != true {
+		if issue == nil && negation {
+			return errNegationFalse
+		}
 		if issue == nil {
 			return errors.New("issue is nil - file a bug if you see this error")
 		}
@@ -727,7 +874,11 @@ func evalExprDarc(visited map[string]bool, expr expression.Expr, getDarc GetDarc
 // identities. It takes 'acceptDarc', and, if it is true, doesn't recurse into
 // darcs that fit one of the ids.
 func EvalExprDarc(expr expression.Expr, getDarc GetDarc, acceptDarc bool, ids ...string) error {
-	return evalExprDarc(make(map[string]bool), expr, getDarc, make(map[string]func(string) error), acceptDarc, ids...)
+	return evalExprDarc(make(map[string]bool), nil, expr, evalOptions{
+		getDarc:    getDarc,
+		attrFuncs:  make(map[string]func(string) error),
+		acceptDarc: acceptDarc,
+	}, ids...)
 }
 
 // EvalExprAttr checks whether the expression evaluates to true given a list
@@ -735,7 +886,34 @@ func EvalExprDarc(expr expression.Expr, getDarc GetDarc, acceptDarc bool, ids ..
 // DARCs when necessary. It also needs a EvalAttr callback for evaluating
 // attributes.
 func EvalExprAttr(expr expression.Expr, getDarc GetDarc, attrFuncs AttrInterpreters, ids ...string) error {
-	return evalExprDarc(make(map[string]bool), expr, getDarc, attrFuncs, false, ids...)
+	return EvalExprMaxDepth(expr, getDarc, attrFuncs, 0, ids...)
+}
+
+// EvalExprMaxDepth works like EvalExprAttr, but limits the number of nested
+// darc identities that are evaluated to maxDepth. An expression that needs
+// to go deeper to be fulfilled returns an error. A maxDepth of 0 means that
+// there is no limit.
+func EvalExprMaxDepth(expr expression.Expr, getDarc GetDarc, attrFuncs AttrInterpreters, maxDepth int, ids ...string) error {
+	return evalExprDarc(make(map[string]bool), nil, expr, evalOptions{
+		getDarc:   getDarc,
+		attrFuncs: attrFuncs,
+		maxDepth:  maxDepth,
+	}, ids...)
+}
+
+// EvalRule checks whether the rule of the action evaluates to true given a
+// list of identities, like EvalExprMaxDepth. The identities revoked by the
+// darc, and by the darcs it delegates to, are refused.
+func (d Darc) EvalRule(action Action, getDarc GetDarc, attrFuncs AttrInterpreters, maxDepth int, ids ...string) error {
+	if !d.Rules.Contains(action) {
+		return fmt.Errorf("action '%v' does not exist", action)
+	}
+	return evalExprDarc(make(map[string]bool), d.addRevoked(nil), d.Rules.Get(action),
+		evalOptions{
+			getDarc:   getDarc,
+			attrFuncs: attrFuncs,
+			maxDepth:  maxDepth,
+		}, ids...)
 }
 
 // Type returns an integer representing the type of key held in the signer. It
@@ -748,6 +926,18 @@ func (s Signer) Type() int {
 		return 2
 	case s.Proxy != nil:
 		return 3
+	case s.Secp256k1 != nil:
+		return 4
+	case s.X509Cert != nil:
+		return 5
+	case s.DID != nil:
+		return 6
+	case s.BLS != nil:
+		return 7
+	case s.HSM != nil:
+		return s.HSM.Identity.Type()
+	case s.Token != nil:
+		return 8
 	default:
 		return -1
 	}
@@ -756,6 +946,9 @@ func (s Signer) Type() int {
 // Identity returns an identity struct with the pre initialised fields for the
 // appropriate signer.
 func (s Signer) Identity() Identity {
+	if s.HSM != nil {
+		return s.HSM.Identity
+	}
 	switch s.Type() {
 	case 1:
 		return NewIdentityEd25519(s.Ed25519.Point)
@@ -763,6 +956,21 @@ func (s Signer) Identity() Identity {
 		return NewIdentityX509EC(s.X509EC.Point)
 	case 3:
 		return NewIdentityProxy(s.Proxy)
+	case 4:
+		id, err := s.Secp256k1.identity()
+		if err != nil {
+			return Identity{}
+		}
+		return id
+	case 5:
+		return NewIdentityX509Cert(s.X509Cert.Chain)
+	case 6:
+		return NewIdentityDID(s.DID.DID)
+	case 7:
+		return Identity{BLS: &IdentityBLS{Public: s.BLS.Public}}
+	case 8:
+		token := s.Token.Token
+		return Identity{Token: &token}
 	default:
 		return Identity{}
 	}
@@ -773,6 +981,9 @@ func (s Signer) Sign(msg []byte) ([]byte, error) {
 	if msg == nil {
 		return nil, errors.New("nothing to sign, message is empty")
 	}
+	if s.HSM != nil {
+		return s.HSM.Sign(msg)
+	}
 	switch s.Type() {
 	case 0:
 		return nil, errors.New("cannot sign with a darc")
@@ -782,6 +993,16 @@ func (s Signer) Sign(msg []byte) ([]byte, error) {
 		return s.X509EC.Sign(msg)
 	case 3:
 		return s.Proxy.Sign(msg)
+	case 4:
+		return s.Secp256k1.Sign(msg)
+	case 5:
+		return s.X509Cert.Sign(msg)
+	case 6:
+		return s.DID.Sign(msg)
+	case 7:
+		return s.BLS.Sign(msg)
+	case 8:
+		return s.Token.Sign(msg)
 	default:
 		return nil, errors.New("unknown signer type")
 	}
@@ -789,10 +1010,13 @@ func (s Signer) Sign(msg []byte) ([]byte, error) {
 
 // GetPrivate returns the private key, if one exists.
 func (s Signer) GetPrivate() (kyber.Scalar, error) {
+	if s.HSM != nil {
+		return nil, errors.New("signer lacks a private key")
+	}
 	switch s.Type() {
 	case 1:
 		return s.Ed25519.Secret, nil
-	case 0, 2, 3:
+	case 0, 2, 3, 4, 5, 6, 7, 8:
 		return nil, errors.New("signer lacks a private key")
 	default:
 		return nil, errors.New("signer is of unknown type")
@@ -814,6 +1038,18 @@ func (id Identity) Equal(id2 *Identity) bool {
 		return id.X509EC.Equal(id2.X509EC)
 	case 3:
 		return id.Proxy.Equal(id2.Proxy)
+	case 4:
+		return id.Secp256k1.Equal(id2.Secp256k1)
+	case 5:
+		return id.X509Cert.Equal(id2.X509Cert)
+	case 6:
+		return id.DID.Equal(id2.DID)
+	case 7:
+		return id.BLS.Equal(id2.BLS)
+	case 8:
+		return id.Token.Equal(id2.Token)
+	case 9:
+		return id.Rule.Equal(id2.Rule)
 	}
 	return false
 }
@@ -830,6 +1066,18 @@ func (id Identity) Type() int {
 		return 2
 	case id.Proxy != nil:
 		return 3
+	case id.Secp256k1 != nil:
+		return 4
+	case id.X509Cert != nil:
+		return 5
+	case id.DID != nil:
+		return 6
+	case id.BLS != nil:
+		return 7
+	case id.Token != nil:
+		return 8
+	case id.Rule != nil:
+		return 9
 	}
 	return -1
 }
@@ -846,6 +1094,18 @@ func (id Identity) PrimaryIdentity() bool {
 		return true
 	case id.Proxy != nil:
 		return true
+	case id.Secp256k1 != nil:
+		return true
+	case id.X509Cert != nil:
+		return true
+	case id.DID != nil:
+		return true
+	case id.BLS != nil:
+		return true
+	case id.Token != nil:
+		return id.Token.Issuer.PrimaryIdentity()
+	case id.Rule != nil:
+		return false
 	}
 	return false
 }
@@ -861,6 +1121,18 @@ func (id Identity) TypeString() string {
 		return "x509ec"
 	case 3:
 		return "proxy"
+	case 4:
+		return "secp256k1"
+	case 5:
+		return "x509cert"
+	case 6:
+		return "did"
+	case 7:
+		return "bls"
+	case 8:
+		return "token"
+	case 9:
+		return "rule"
 	default:
 		return "No identity"
 	}
@@ -877,6 +1149,18 @@ func (id Identity) String() string {
 		return fmt.Sprintf("%s:%x", id.TypeString(), id.X509EC.Public)
 	case 3:
 		return fmt.Sprintf("%s:%v:%v", id.TypeString(), id.Proxy.Public, id.Proxy.Data)
+	case 4:
+		return fmt.Sprintf("%s:%x", id.TypeString(), id.Secp256k1.Address)
+	case 5:
+		return id.X509Cert.String()
+	case 6:
+		return id.DID.DID
+	case 7:
+		return fmt.Sprintf("%s:%x", id.TypeString(), id.BLS.Public)
+	case 8:
+		return id.Token.Issuer.String()
+	case 9:
+		return id.Rule.String()
 	default:
 		return "No identity"
 	}
@@ -894,6 +1178,18 @@ func (id Identity) Verify(msg, sig []byte) error {
 		return id.X509EC.Verify(msg, sig)
 	case 3:
 		return id.Proxy.Verify(msg, sig)
+	case 4:
+		return id.Secp256k1.Verify(msg, sig)
+	case 5:
+		return id.X509Cert.Verify(msg, sig)
+	case 6:
+		return id.DID.Verify(msg, sig)
+	case 7:
+		return id.BLS.Verify(msg, sig)
+	case 8:
+		return errors.New("a token must be verified with VerifyFor")
+	case 9:
+		return errors.New("cannot verify a rule-signature")
 	default:
 		return errors.New("unknown identity")
 	}
@@ -919,6 +1215,18 @@ func (id Identity) GetPublicBytes() []byte {
 			return nil
 		}
 		return buf
+	case 4:
+		return id.Secp256k1.Address
+	case 5:
+		return id.X509Cert.publicBytes()
+	case 6:
+		return []byte(id.DID.DID)
+	case 7:
+		return id.BLS.Public
+	case 8:
+		return id.Token.Issuer.GetPublicBytes()
+	case 9:
+		return id.Rule.ID
 	default:
 		return nil
 	}
@@ -982,6 +1290,57 @@ func (idkc IdentityX509EC) Equal(idkc2 *IdentityX509EC) bool {
 	return bytes.Compare(idkc.Public, idkc2.Public) == 0
 }
 
+// NewIdentitySecp256k1 creates a new secp256k1 identity struct given a public
+// key.
+func NewIdentitySecp256k1(public *ecdsa.PublicKey) Identity {
+	return Identity{
+		Secp256k1: &IdentitySecp256k1{
+			Address: secp256k1Address(public),
+		},
+	}
+}
+
+// Equal returns true if both IdentitySecp256k1 hold the same address.
+func (ids IdentitySecp256k1) Equal(ids2 *IdentitySecp256k1) bool {
+	return bytes.Equal(ids.Address, ids2.Address)
+}
+
+// Verify returns nil if the signature is correct, or an error if something
+// fails. The signature is the 65 bytes [R || S || V] signature of the
+// Keccak256 hash of the message, as it is created by Ethereum wallets. The
+// public key is recovered from the signature and compared to the address.
+func (ids IdentitySecp256k1) Verify(msg, s []byte) error {
+	if len(s) != 65 {
+		return errors.New("signature must be 65 bytes long")
+	}
+	if s[64] >= 4 {
+		return errors.New("invalid recovery id in signature")
+	}
+	// btcec expects the compact format [V || R || S], with V offset by 27.
+	compact := append([]byte{s[64] + 27}, s[:64]...)
+	public, _, err := btcec.RecoverCompact(btcec.S256(), compact, keccak256(msg))
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(secp256k1Address(public.ToECDSA()), ids.Address) {
+		return errors.New("Wrong signature")
+	}
+	return nil
+}
+
+// secp256k1Address returns the Ethereum address of the public key, which is
+// the last 20 bytes of the Keccak256 hash of its uncompressed coordinates.
+func secp256k1Address(public *ecdsa.PublicKey) []byte {
+	raw := (*btcec.PublicKey)(public).SerializeUncompressed()
+	return keccak256(raw[1:])[12:]
+}
+
+func keccak256(msg []byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	h.Write(msg)
+	return h.Sum(nil)
+}
+
 // Equal returns true if both IdentityProxy are the same.
 func (idp IdentityProxy) Equal(i2 *IdentityProxy) bool {
 	return idp.Data == i2.Data && idp.Public.Equal(i2.Public)
@@ -1042,6 +1401,16 @@ func ParseIdentity(in string) (Identity, error) {
 		return parseIDX509ec(fields[1])
 	case "proxy":
 		return parseIDProxy(fields[1])
+	case "secp256k1":
+		return parseIDSecp256k1(fields[1])
+	case "x509cert":
+		return Identity{}, errors.New("x509cert identities need their certificate chain")
+	case "did":
+		return parseIDDID(fields[1])
+	case "bls":
+		return parseIDBLS(fields[1])
+	case "rule":
+		return parseIDRule(fields[1])
 	default:
 		return Identity{}, fmt.Errorf("unknown identity type %v", fields[0])
 	}
@@ -1064,6 +1433,17 @@ func parseIDX509ec(in string) (Identity, error) {
 	return Identity{X509EC: &IdentityX509EC{Public: id}}, nil
 }
 
+func parseIDSecp256k1(in string) (Identity, error) {
+	addr, err := hex.DecodeString(in)
+	if err != nil {
+		return Identity{}, err
+	}
+	if len(addr) != 20 {
+		return Identity{}, errors.New("secp256k1 address must be 20 bytes long")
+	}
+	return Identity{Secp256k1: &IdentitySecp256k1{Address: addr}}, nil
+}
+
 func parseIDDarc(in string) (Identity, error) {
 	id := make([]byte, hex.DecodedLen(len(in)))
 	_, err := hex.Decode(id, []byte(in))
@@ -1239,6 +1619,58 @@ func (s SignerProxy) Sign(msg []byte) ([]byte, error) {
 	return sig, err
 }
 
+// NewSignerSecp256k1 creates a new SignerSecp256k1 given the private key of
+// an Ethereum key pair. If the key is nil, a new key pair is generated.
+func NewSignerSecp256k1(private *ecdsa.PrivateKey) Signer {
+	if private == nil {
+		var err error
+		key, err := btcec.NewPrivateKey(btcec.S256())
+		if err != nil {
+			panic("couldn't generate secp256k1 key: " + err.Error())
+		}
+		private = key.ToECDSA()
+	}
+	return Signer{Secp256k1: &SignerSecp256k1{
+		Secret: (*btcec.PrivateKey)(private).Serialize(),
+	}}
+}
+
+// Sign creates a [R || S || V] signature of the Keccak256 hash of the
+// message, which can be verified with IdentitySecp256k1.Verify.
+func (ss SignerSecp256k1) Sign(msg []byte) ([]byte, error) {
+	private, err := ss.private()
+	if err != nil {
+		return nil, err
+	}
+	compact, err := btcec.SignCompact(btcec.S256(), private, keccak256(msg), false)
+	if err != nil {
+		return nil, err
+	}
+	// Move V from the front to the back and remove its offset of 27, as
+	// Ethereum wallets do.
+	return append(compact[1:], compact[0]-27), nil
+}
+
+func (ss SignerSecp256k1) identity() (Identity, error) {
+	private, err := ss.private()
+	if err != nil {
+		return Identity{}, err
+	}
+	return NewIdentitySecp256k1(&private.PublicKey), nil
+}
+
+func (ss SignerSecp256k1) private() (*btcec.PrivateKey, error) {
+	if len(ss.Secret) != btcec.PrivKeyBytesLen {
+		return nil, errors.New("secp256k1 secret must be 32 bytes long")
+	}
+	d := new(big.Int).SetBytes(ss.Secret)
+	if d.Sign() == 0 || d.Cmp(btcec.S256().N) >= 0 {
+		return nil, errors.New("invalid secp256k1 secret")
+	}
+	private, _ := btcec.PrivKeyFromBytes(btcec.S256(), ss.Secret)
+	return private, nil
+}
+
 func copyBytes(a []byte) []byte {
 	b := make([]byte, len(a))
 	copy(b, a)
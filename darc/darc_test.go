@@ -386,6 +386,35 @@ func TestDarc_DelegationChain(t *testing.T) {
 	require.Nil(t, td.darc.VerifyWithCB(getDarc, true))
 }
 
+// TestDarc_DelegationDepth creates a chain of delegations and checks that it
+// can only be evaluated if it is not deeper than the maximum depth.
+func TestDarc_DelegationDepth(t *testing.T) {
+	n := 4
+	darcs := make([]*Darc, n)
+	signer := createSigner()
+	for i := n - 1; i >= 0; i-- {
+		darcs[i] = createDarc(1, "depth").darc
+		if i == n-1 {
+			require.NoError(t, darcs[i].Rules.UpdateSign([]byte(signer.Identity().String())))
+		} else {
+			require.NoError(t, darcs[i].Rules.UpdateSign([]byte(darcs[i+1].GetIdentityString())))
+		}
+	}
+	getDarc := DarcsToGetDarcs(darcs)
+	expr := []byte(darcs[0].GetIdentityString())
+	id := signer.Identity().String()
+
+	require.NoError(t, EvalExprMaxDepth(expr, getDarc, nil, 0, id))
+	require.NoError(t, EvalExprMaxDepth(expr, getDarc, nil, n, id))
+	err := EvalExprMaxDepth(expr, getDarc, nil, n-1, id)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "maximum depth of 3 darcs")
+
+	// A shallow alternative is still accepted.
+	expr = []byte(darcs[0].GetIdentityString() + " | " + id)
+	require.NoError(t, EvalExprMaxDepth(expr, getDarc, nil, 1, id))
+}
+
 // TestDarc_DelegationCycle creates n darcs and a circular delegation
 func TestDarc_DelegationCycle(t *testing.T) {
 	n := 5
@@ -445,6 +474,168 @@ func TestDarc_DelegationDiamond(t *testing.T) {
 	require.NoError(t, err)
 }
 
+// TestDarc_Threshold evaluates a threshold expression where one of the
+// identities is a darc.
+func TestDarc_Threshold(t *testing.T) {
+	td := createDarc(1, "threshold")
+	require.NoError(t, td.darc.Rules.UpdateSign([]byte(td.ids[0].String())))
+	id1 := createIdentity()
+	id2 := createIdentity()
+	getDarc := DarcsToGetDarcs([]*Darc{td.darc})
+
+	expr := expression.InitThresholdExpr(2, id1.String(), id2.String(),
+		td.darc.GetIdentityString())
+	require.Error(t, EvalExpr(expr, getDarc, id1.String()))
+	require.Error(t, EvalExpr(expr, getDarc, td.ids[0].String()))
+	require.NoError(t, EvalExpr(expr, getDarc, id1.String(), td.ids[0].String()))
+	require.NoError(t, EvalExpr(expr, getDarc, id1.String(), id2.String()))
+}
+
+// TestDarc_Secp256k1 signs a request with an Ethereum key pair.
+func TestDarc_Secp256k1(t *testing.T) {
+	signer := NewSignerSecp256k1(nil)
+	id := signer.Identity()
+	require.Equal(t, 4, id.Type())
+	require.Len(t, id.GetPublicBytes(), 20)
+
+	msg := []byte("document")
+	sig, err := signer.Sign(msg)
+	require.NoError(t, err)
+	require.NoError(t, id.Verify(msg, sig))
+	require.Error(t, id.Verify([]byte("other document"), sig))
+	require.Error(t, NewSignerSecp256k1(nil).Identity().Verify(msg, sig))
+	require.Error(t, id.Verify(msg, sig[1:]))
+	require.True(t, sig[64] < 2)
+
+	// The address of the private key 1 is a well-known Ethereum address.
+	one := Signer{Secp256k1: &SignerSecp256k1{Secret: make([]byte, 32)}}
+	_, err = one.Sign(msg)
+	require.Error(t, err)
+	one.Secp256k1.Secret[31] = 1
+	require.Equal(t, "secp256k1:7e5f4552091a69125d5dfcb7b8c2659029395bdf",
+		one.Identity().String())
+
+	_, err = signer.GetPrivate()
+	require.Error(t, err)
+
+	d := NewDarc(InitRules([]Identity{id}, []Identity{id}), []byte("secp256k1"))
+	req, err := InitAndSignRequest(d.GetBaseID(), sign, msg, signer)
+	require.NoError(t, err)
+	require.NoError(t, req.Identities[0].Verify(req.Hash(), req.Signatures[0]))
+	require.NoError(t, EvalExpr(d.Rules.GetSignExpr(), nil, id.String()))
+}
+
+// TestDarc_Not evaluates a deny-style expression, where a negated darc
+// identity blocks its signers.
+func TestDarc_Not(t *testing.T) {
+	blocked := createDarc(1, "blocked")
+	require.NoError(t, blocked.darc.Rules.UpdateSign([]byte(blocked.ids[0].String())))
+	getDarc := DarcsToGetDarcs([]*Darc{blocked.darc})
+	id1 := createIdentity()
+
+	expr := []byte("(" + id1.String() + " | " + blocked.ids[0].String() + ") & !" +
+		blocked.darc.GetIdentityString())
+	require.NoError(t, EvalExpr(expr, getDarc, id1.String()))
+	err := EvalExpr(expr, getDarc, id1.String(), blocked.ids[0].String())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "negation")
+	require.Error(t, EvalExpr(expr, getDarc, blocked.ids[0].String()))
+
+	// A lone negation would accept anybody.
+	err = EvalExpr([]byte("!"+id1.String()), getDarc)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "without any valid id")
+}
+
+// TestDarc_NotFailsClosed checks that a negated leaf that cannot be
+// evaluated doesn't grant access.
+func TestDarc_NotFailsClosed(t *testing.T) {
+	id := createIdentity().String()
+	eval := func(negated string, getDarc GetDarc, attrFuncs AttrInterpreters, maxDepth int) error {
+		return EvalExprMaxDepth([]byte(id+" & !"+negated), getDarc, attrFuncs, maxDepth, id)
+	}
+
+	// A darc that cannot be found.
+	missing := createDarc(1, "missing")
+	require.Error(t, eval(missing.darc.GetIdentityString(), DarcsToGetDarcs(nil), nil, 0))
+	require.NoError(t, eval(missing.darc.GetIdentityString(),
+		DarcsToGetDarcs([]*Darc{missing.darc}), nil, 0))
+
+	// A cycle of delegations.
+	cycle := createDarc(1, "cycle")
+	require.NoError(t, cycle.darc.Rules.UpdateSign([]byte(cycle.darc.GetIdentityString())))
+	err := eval(cycle.darc.GetIdentityString(), DarcsToGetDarcs([]*Darc{cycle.darc}), nil, 0)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "cycle detected")
+
+	// Delegations deeper than the maximum depth.
+	deep := createDarc(1, "deep")
+	deeper := createDarc(1, "deeper")
+	require.NoError(t, deep.darc.Rules.UpdateSign([]byte(deeper.darc.GetIdentityString())))
+	getDarc := DarcsToGetDarcs([]*Darc{deep.darc, deeper.darc})
+	require.NoError(t, eval(deep.darc.GetIdentityString(), getDarc, nil, 2))
+	err = eval(deep.darc.GetIdentityString(), getDarc, nil, 1)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "maximum depth")
+
+	// A revoked identity.
+	revoking := createDarc(1, "revoking")
+	other := createIdentity().String()
+	require.NoError(t, revoking.darc.Rules.UpdateSign([]byte(other)))
+	revoking.darc.Revoke(other)
+	err = eval(revoking.darc.GetIdentityString(), DarcsToGetDarcs([]*Darc{revoking.darc}), nil, 0)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "revoked")
+
+	// Attributes without interpreter, or with invalid arguments.
+	require.Error(t, eval("attr:unknown:x", nil, nil, 0))
+	attrFuncs := AttrInterpreters{"test": func(arg string) error {
+		if arg != "valid" {
+			return errors.New("invalid argument")
+		}
+		return nil
+	}}
+	require.Error(t, eval("attr:test:invalid", nil, attrFuncs, 0))
+	err = eval("attr:test:valid", nil, attrFuncs, 0)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "negation")
+}
+
+// TestDarc_Revoke checks that revoked identities are refused by the rules of
+// the darc and of the darcs it delegates to.
+func TestDarc_Revoke(t *testing.T) {
+	td := createDarc(2, "revoke")
+	id0, id1 := td.ids[0].String(), td.ids[1].String()
+	require.NoError(t, td.darc.Rules.UpdateSign(expression.InitOrExpr(id0, id1)))
+	idBefore := td.darc.GetID()
+	require.NoError(t, td.darc.EvalRule(sign, nil, nil, 0, id0))
+
+	td.darc.Revoke(id0, id0)
+	require.Equal(t, []string{id0}, td.darc.Revoked)
+	require.False(t, idBefore.Equal(td.darc.GetID()))
+	err := td.darc.EvalRule(sign, nil, nil, 0, id0)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "revoked")
+	require.NoError(t, td.darc.EvalRule(sign, nil, nil, 0, id0, id1))
+	require.True(t, td.darc.Copy().IsRevoked(id0))
+
+	// The revocation also applies to the delegations.
+	delegating := createDarc(1, "delegating")
+	require.NoError(t, delegating.darc.Rules.UpdateSign([]byte(td.darc.GetIdentityString())))
+	getDarc := DarcsToGetDarcs([]*Darc{td.darc})
+	require.Error(t, delegating.darc.EvalRule(sign, getDarc, nil, 0, id0))
+	require.NoError(t, delegating.darc.EvalRule(sign, getDarc, nil, 0, id1))
+	delegating.darc.Revoke(id1)
+	require.Error(t, delegating.darc.EvalRule(sign, getDarc, nil, 0, id1))
+	delegating.darc.Revoke(td.darc.GetIdentityString())
+	delegating.darc.Unrevoke(id1)
+	require.Error(t, delegating.darc.EvalRule(sign, getDarc, nil, 0, id1))
+
+	td.darc.Unrevoke(id0)
+	require.Empty(t, td.darc.Revoked)
+	require.True(t, idBefore.Equal(td.darc.GetID()))
+}
+
 func TestDarc_X509(t *testing.T) {
 	// TODO
 }
@@ -625,4 +816,14 @@ func TestParseIdentity(t *testing.T) {
 	require.NoError(t, err)
 	require.NotNil(t, i.Proxy)
 	require.Equal(t, in, i.String())
+
+	in = "secp256k1:010203"
+	i, err = ParseIdentity(in)
+	require.Error(t, err)
+
+	in = "secp256k1:b794f5ea0ba39494ce839613fffba74279579268"
+	i, err = ParseIdentity(in)
+	require.NoError(t, err)
+	require.NotNil(t, i.Secp256k1)
+	require.Equal(t, in, i.String())
 }
@@ -0,0 +1,123 @@
+package darc
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// DIDResolver returns the current verification keys of a decentralized
+// identifier. A resolver is registered for a DID method with
+// RegisterDIDResolver. A resolver doesn't get the state of a ledger, so the
+// nodes of a chain could disagree on the keys it returns: DID identities are
+// only for off-chain requests verified with Request.Verify, and ByzCoin
+// refuses them as signers of instructions.
+type DIDResolver interface {
+	Resolve(did string) ([]Identity, error)
+}
+
+// DIDResolverFunc is an adapter to use a function as a DIDResolver.
+type DIDResolverFunc func(did string) ([]Identity, error)
+
+// Resolve implements the DIDResolver interface.
+func (f DIDResolverFunc) Resolve(did string) ([]Identity, error) {
+	return f(did)
+}
+
+var didResolvers = struct {
+	sync.Mutex
+	m map[string]DIDResolver
+}{m: make(map[string]DIDResolver)}
+
+// RegisterDIDResolver sets the resolver of the DIDs of the given method, like
+// "web" for "did:web:example.com". A nil resolver removes the resolver of
+// the method.
+func RegisterDIDResolver(method string, r DIDResolver) {
+	didResolvers.Lock()
+	defer didResolvers.Unlock()
+	if r == nil {
+		delete(didResolvers.m, method)
+		return
+	}
+	didResolvers.m[method] = r
+}
+
+// ResolveDID returns the verification keys of the DID, using the resolver
+// registered for its method.
+func ResolveDID(did string) ([]Identity, error) {
+	method, err := didMethod(did)
+	if err != nil {
+		return nil, err
+	}
+	didResolvers.Lock()
+	r, ok := didResolvers.m[method]
+	didResolvers.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("no resolver for DID method %s", method)
+	}
+	return r.Resolve(did)
+}
+
+func didMethod(did string) (string, error) {
+	fields := strings.SplitN(did, ":", 3)
+	if len(fields) != 3 || fields[0] != "did" || fields[1] == "" || fields[2] == "" {
+		return "", errors.New("expected DID format of did:method:id")
+	}
+	return fields[1], nil
+}
+
+// NewIdentityDID creates a new DID identity struct given a DID.
+func NewIdentityDID(did string) Identity {
+	return Identity{
+		DID: &IdentityDID{
+			DID: did,
+		},
+	}
+}
+
+// Equal returns true if both IdentityDID hold the same DID.
+func (idd IdentityDID) Equal(idd2 *IdentityDID) bool {
+	return idd.DID == idd2.DID
+}
+
+// Verify returns nil if the signature is correct for one of the current
+// verification keys of the DID, or an error if something fails. Keys that
+// are DIDs themselves are ignored.
+func (idd IdentityDID) Verify(msg, s []byte) error {
+	keys, err := ResolveDID(idd.DID)
+	if err != nil {
+		return fmt.Errorf("resolving %s: %v", idd.DID, err)
+	}
+	for _, key := range keys {
+		if key.DID != nil || !key.PrimaryIdentity() {
+			continue
+		}
+		if key.Verify(msg, s) == nil {
+			return nil
+		}
+	}
+	return errors.New("no verification key of the DID matches the signature")
+}
+
+func parseIDDID(in string) (Identity, error) {
+	did := "did:" + in
+	if _, err := didMethod(did); err != nil {
+		return Identity{}, err
+	}
+	return NewIdentityDID(did), nil
+}
+
+// NewSignerDID creates a new SignerDID given a DID and the signer of one of
+// its verification keys.
+func NewSignerDID(did string, key Signer) Signer {
+	return Signer{DID: &SignerDID{
+		DID: did,
+		Key: key,
+	}}
+}
+
+// Sign creates a signature on the message with the verification key.
+func (sd SignerDID) Sign(msg []byte) ([]byte, error) {
+	return sd.Key.Sign(msg)
+}
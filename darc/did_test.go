@@ -0,0 +1,49 @@
+package darc
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDID(t *testing.T) {
+	const did = "did:example:123456789abcdefghi"
+	key := NewSignerEd25519(nil, nil)
+	keys := []Identity{key.Identity()}
+	RegisterDIDResolver("example", DIDResolverFunc(func(d string) ([]Identity, error) {
+		if d != did {
+			return nil, errors.New("unknown DID")
+		}
+		return keys, nil
+	}))
+	defer RegisterDIDResolver("example", nil)
+
+	signer := NewSignerDID(did, key)
+	id := signer.Identity()
+	require.Equal(t, 6, id.Type())
+	require.Equal(t, did, id.String())
+
+	msg := []byte("document")
+	sig, err := signer.Sign(msg)
+	require.NoError(t, err)
+	require.NoError(t, id.Verify(msg, sig))
+	require.Error(t, id.Verify([]byte("other document"), sig))
+	require.Error(t, NewIdentityDID("did:example:unknown").Verify(msg, sig))
+	require.Error(t, NewIdentityDID("did:other:123").Verify(msg, sig))
+
+	// Rotating the keys of the DID revokes the old key.
+	keys = []Identity{NewSignerEd25519(nil, nil).Identity()}
+	require.Error(t, id.Verify(msg, sig))
+	keys = append(keys, key.Identity())
+	require.NoError(t, id.Verify(msg, sig))
+
+	parsed, err := ParseIdentity(did)
+	require.NoError(t, err)
+	require.True(t, parsed.Equal(&id))
+	_, err = ParseIdentity("did:example")
+	require.Error(t, err)
+
+	require.NoError(t, EvalExpr([]byte(did+" | ed25519:aa"), nil, id.String()))
+	require.Error(t, EvalExpr([]byte("did:example:other"), nil, id.String()))
+}
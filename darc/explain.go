@@ -0,0 +1,51 @@
+package darc
+
+import (
+	"bytes"
+	"fmt"
+
+	"go.dedis.ch/cothority/v3/darc/expression"
+)
+
+// ExplainExpr evaluates the expression offline, without access to the
+// ledger, and returns which of its sub-expressions are fulfilled by the
+// identities. It lets wallets show to their users what they can do, and what
+// signatures are missing, before sending an instruction.
+//
+// As no darcs are fetched, a darc identity is only fulfilled if it is one of
+// the ids, and attributes are never fulfilled. An expression with an invalid
+// negation returns an error, as it would on the ledger.
+func ExplainExpr(expr expression.Expr, ids ...string) (*expression.Explanation, error) {
+	return explainExpr(expr, nil, ids...)
+}
+
+// ExplainRule works like ExplainExpr for the rule of the action, and does
+// not fulfil the identities revoked by the darc.
+func (d Darc) ExplainRule(action Action, ids ...string) (*expression.Explanation, error) {
+	if !d.Rules.Contains(action) {
+		return nil, fmt.Errorf("action '%v' does not exist", action)
+	}
+	return explainExpr(d.Rules.Get(action), d.addRevoked(nil), ids...)
+}
+
+func explainExpr(expr expression.Expr, revoked map[string]bool, ids ...string) (*expression.Explanation, error) {
+	if bytes.ContainsRune(expr, '!') {
+		if err := expression.Validate(expr); err != nil {
+			return nil, err
+		}
+	}
+	return expression.Explain(expr, func(s string) bool {
+		if revoked[s] {
+			return false
+		}
+		for _, id := range ids {
+			if revoked[id] {
+				continue
+			}
+			if id == s || matchX509Cert(s, id) {
+				return true
+			}
+		}
+		return false
+	})
+}
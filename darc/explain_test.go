@@ -0,0 +1,43 @@
+package darc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/darc/expression"
+)
+
+func TestExplainExpr(t *testing.T) {
+	a := createIdentity().String()
+	b := createIdentity().String()
+	other := NewDarc(nil, nil).GetIdentityString()
+
+	expr := expression.InitThresholdExpr(2, a, b, other)
+	e, err := ExplainExpr(expr, a)
+	require.NoError(t, err)
+	require.False(t, e.Result)
+	require.Len(t, e.Children, 3)
+	require.True(t, e.Children[0].Result)
+	require.False(t, e.Children[1].Result)
+
+	// Darcs are not fetched, so the darc identity must be given.
+	e, err = ExplainExpr(expr, a, other)
+	require.NoError(t, err)
+	require.True(t, e.Result)
+	require.True(t, e.Children[2].Result)
+
+	_, err = ExplainExpr(expression.Expr("!"+a), a)
+	require.Error(t, err)
+
+	d := NewDarc(InitRules([]Identity{createIdentity()}, nil), []byte("explain"))
+	require.NoError(t, d.Rules.AddRule("spawn:coin", expression.InitOrExpr(a, b)))
+	e, err = d.ExplainRule("spawn:coin", a)
+	require.NoError(t, err)
+	require.True(t, e.Result)
+	d.Revoke(a)
+	e, err = d.ExplainRule("spawn:coin", a)
+	require.NoError(t, err)
+	require.False(t, e.Result)
+	_, err = d.ExplainRule("invoke:coin.transfer", a)
+	require.Error(t, err)
+}
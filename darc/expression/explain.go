@@ -0,0 +1,182 @@
+package expression
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	parsec "github.com/prataprc/goparsec"
+)
+
+// Explanation is the result of a sub-expression of an evaluated expression.
+// The leaves are the ids and attributes, and the other nodes the operators
+// combining their Children.
+type Explanation struct {
+	// Expr is the sub-expression, with normalized spaces.
+	Expr string
+	// Result is the value of the sub-expression.
+	Result bool
+	// Children are the operands of an operator, nil for an id.
+	Children []*Explanation
+	// inner is the sub-expression without the parentheses of a group, to
+	// compare the expressions of a threshold.
+	inner string
+}
+
+// key returns the sub-expression without the parentheses around it.
+func (e *Explanation) key() string {
+	if e.inner != "" {
+		return e.inner
+	}
+	return e.Expr
+}
+
+// Explain evaluates the expression like Evaluate with InitParser, but returns
+// the tree of its sub-expressions with their results, so that it can be
+// shown which parts of a rule are fulfilled and which are missing.
+func Explain(expr Expr, fn ValueCheckFn) (*Explanation, error) {
+	v, err := parse(initParser(nodes{
+		sum:       explainSum,
+		threshold: explainThreshold,
+		not:       explainNot,
+		value:     explainValue(fn),
+		group:     explainGroup,
+	}), expr)
+	if err != nil {
+		return nil, err
+	}
+	e, ok := v.(*Explanation)
+	if !ok {
+		return nil, errors.New("explanation failed - result is not an explanation")
+	}
+	return e, nil
+}
+
+// String returns the tree of the explanation, one sub-expression per line,
+// where fulfilled sub-expressions are marked with [x].
+func (e *Explanation) String() string {
+	var b strings.Builder
+	e.write(&b, 0)
+	return b.String()
+}
+
+func (e *Explanation) write(b *strings.Builder, indent int) {
+	mark := "[ ]"
+	if e.Result {
+		mark = "[x]"
+	}
+	fmt.Fprintf(b, "%s%s %s\n", strings.Repeat("  ", indent), mark, e.Expr)
+	for _, c := range e.Children {
+		c.write(b, indent+1)
+	}
+}
+
+func explainValue(fn ValueCheckFn) func(ns []parsec.ParsecNode) parsec.ParsecNode {
+	return func(ns []parsec.ParsecNode) parsec.ParsecNode {
+		if len(ns) == 0 {
+			return nil
+		} else if term, ok := ns[0].(*parsec.Terminal); ok {
+			return &Explanation{Expr: term.Value, Result: fn(term.Value)}
+		}
+		return ns[0]
+	}
+}
+
+// explainSum flattens a sum into one node with all its operands. The
+// operators are evaluated from left to right, like in sumNode.
+func explainSum(ns []parsec.ParsecNode) parsec.ParsecNode {
+	if len(ns) == 0 {
+		return nil
+	}
+	first := ns[0].(*Explanation)
+	rest := ns[1].([]parsec.ParsecNode)
+	if len(rest) == 0 {
+		return first
+	}
+	e := &Explanation{
+		Expr:     first.Expr,
+		Result:   first.Result,
+		Children: []*Explanation{first},
+	}
+	for _, x := range rest {
+		y := x.([]parsec.ParsecNode)
+		n := y[1].(*Explanation)
+		op := y[0].(*parsec.Terminal)
+		switch op.Name {
+		case "AND":
+			e.Result = e.Result && n.Result
+		case "OR":
+			e.Result = e.Result || n.Result
+		}
+		e.Expr += " " + op.Value + " " + n.Expr
+		e.Children = append(e.Children, n)
+	}
+	return e
+}
+
+func explainThreshold(ns []parsec.ParsecNode) parsec.ParsecNode {
+	if len(ns) == 0 {
+		return nil
+	}
+	t, err := strconv.Atoi(ns[2].(*parsec.Terminal).Value)
+	if err != nil {
+		return nil
+	}
+	exprs := ns[3].([]parsec.ParsecNode)
+	if t < 1 || t > len(exprs) {
+		return nil
+	}
+	e := &Explanation{}
+	count := 0
+	args := []string{strconv.Itoa(t)}
+	seen := make(map[string]bool)
+	for _, x := range exprs {
+		n := x.([]parsec.ParsecNode)[1].(*Explanation)
+		if seen[n.key()] {
+			return nil
+		}
+		seen[n.key()] = true
+		if n.Result {
+			count++
+		}
+		args = append(args, n.Expr)
+		e.Children = append(e.Children, n)
+	}
+	e.Expr = "threshold(" + strings.Join(args, ", ") + ")"
+	e.Result = count >= t
+	return e
+}
+
+func explainNot(ns []parsec.ParsecNode) parsec.ParsecNode {
+	if len(ns) == 0 {
+		return nil
+	}
+	n, ok := ns[1].(*Explanation)
+	if !ok {
+		return nil
+	}
+	return &Explanation{
+		Expr:     "!" + n.Expr,
+		Result:   !n.Result,
+		Children: []*Explanation{n},
+	}
+}
+
+// explainGroup keeps the parentheses in the expression of the group, but
+// otherwise returns the explanation of the expression inside.
+func explainGroup(ns []parsec.ParsecNode) parsec.ParsecNode {
+	if len(ns) == 0 {
+		return nil
+	}
+	n, ok := ns[1].(*Explanation)
+	if !ok {
+		return nil
+	}
+	return &Explanation{
+		Expr:     "(" + n.Expr + ")",
+		Result:   n.Result,
+		Children: n.Children,
+		inner:    n.key(),
+	}
+}
@@ -5,17 +5,27 @@ the syntax we use is from: https://en.wikipedia.org/wiki/Extended_Backus%E2%80%9
 
 	expr = term, [ '&', term ]*
 	term = factor, [ '|', factor ]*
-	factor = '(', expr, ')' | id | openid
-	identity = (darc|ed25519|x509ec):[0-9a-fA-F]+
+	factor = '(', expr, ')' | '!', factor | threshold | id | openid
+	threshold = 'threshold', '(', digit+, ',', expr, [ ',', expr ]*, ')'
+	identity = (darc|ed25519|x509ec|secp256k1|bls):[0-9a-fA-F]+
+	x509cert = x509cert:[0-9a-fA-F]+[:[0-9a-fA-F]+]
 	proxy = proxy:[0-9a-fA-F]+:[^ \n\t]*
-	attr = attr:[0-9a-zA-Z\-\_]+:[^ \n\t]*
+	did = did:[a-z0-9]+:[a-zA-Z0-9.\-_:%]+
+	rule = rule:[0-9a-fA-F]+:[0-9a-zA-Z.\-\_:]+
+	attr = attr:[0-9a-zA-Z\-\_]+:[^ \n\t]* | attr:[0-9a-zA-Z\-\_]+, op, [0-9a-zA-Z.\-\_]+
+	op = '<' | '<=' | '>' | '>=' | '==' | '!='
 
 Examples:
 
 	ed25519:deadbeef // every id evaluates to a boolean
 	(ed25519:a & x509ec:b) | (darc:c & ed25519:d)
 	proxy:deadbeef:me@example.com // where deadbeef is a ed25519 public key
+	did:example:123456789abcdefghi // a W3C decentralized identifier
+	rule:deadbeef:invoke:coin.transfer // the rule of the darc deadbeef
 	attr:time_interval:before=5pm&after=9am & ed25519:deadbeef
+	attr:age>=18 & attr:tx_amount<1000 // attribute predicates
+	threshold(2, ed25519:a, ed25519:b, darc:c) // two of the three ids
+	(ed25519:a | ed25519:b) & !ed25519:c // a or b, but not together with c
 
 In the simplest case, the evaluation of an expression is performed against a
 set of valid ids.  Suppose we have the expression (a:a & b:b) | (c:c & d:d),
@@ -24,15 +34,27 @@ true.  If the set of valid ids is [a:a, c:c], then the expression will evaluate
 to false. However, the user is able to provide a ValueCheckFn to customise how
 the expressions are evaluated.
 
-EXTENSION - NOT YET IMPLEMENTED:
-To support threshold signatures, we extend the syntax to include the following.
-	thexpr = '[', id, [ ',', id ]*, ']', '/', digit
+A threshold expression evaluates to true if at least the given number of its
+expressions evaluate to true. The threshold must be between 1 and the number
+of expressions, and the expressions must be different, otherwise the parsing
+fails, as "threshold(2, ed25519:a, ed25519:a)" would be fulfilled by a
+single id. As proxy and attr ids can
+contain commas, they need to be followed by a space when they are used in a
+threshold expression.
+
+A negation '!' evaluates to true if the factor it applies to is false. This
+allows deny-style policies, but an expression like "!ed25519:c" is also true
+for an empty set of ids, and would thus let anybody in. Validate returns an
+error for such expressions, and should be called on every expression holding
+a negation before it is evaluated.
 */
 package expression
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 
 	parsec "github.com/prataprc/goparsec"
@@ -52,6 +74,23 @@ type Expr []byte
 
 // InitParser creates the root parser
 func InitParser(fn ValueCheckFn) parsec.Parser {
+	return initParser(nodes{
+		sum:       sumNode(fn),
+		threshold: thresholdNode,
+		not:       notNode,
+		value:     exprValueNode(fn),
+		group:     exprNode,
+	})
+}
+
+// nodes holds the functions that create the values of the non-terminals of
+// the grammar. InitParser uses them to evaluate the expression, and Explain
+// to build a tree of its sub-expressions.
+type nodes struct {
+	sum, threshold, not, value, group parsec.Nodify
+}
+
+func initParser(n nodes) parsec.Parser {
 	// Y is root Parser, usually called as `s` in CFG theory.
 	var Y parsec.Parser
 	var sum, value parsec.Parser // circular rats
@@ -61,22 +100,33 @@ func InitParser(fn ValueCheckFn) parsec.Parser {
 	var closeparan = parsec.Token(`\)`, "CLOSEPARAN")
 	var andop = parsec.Token(`&`, "AND")
 	var orop = parsec.Token(`\|`, "OR")
+	var thresholdop = parsec.Token(`threshold`, "THRESHOLD")
+	var comma = parsec.Token(`,`, "COMMA")
+	var number = parsec.Token(`[0-9]+`, "NUMBER")
+	var notop = parsec.Token(`!`, "NOT")
 
 	// NonTerminal rats
 	// sumOp -> "&" |  "|"
 	var sumOp = parsec.OrdChoice(one2one, andop, orop)
 
 	// value -> "(" expr ")"
-	var groupExpr = parsec.And(exprNode, openparan, &sum, closeparan)
+	var groupExpr = parsec.And(n.group, openparan, &sum, closeparan)
+
+	// threshold -> "threshold" "(" number ("," sum)+ ")"
+	var thresholdExpr = parsec.And(n.threshold, thresholdop, openparan, number,
+		parsec.Many(nil, parsec.And(many2many, comma, &sum)), closeparan)
+
+	// not -> "!" value
+	var notExpr = parsec.And(n.not, notop, &value)
 
 	// (andop prod)*
 	var prodK = parsec.Kleene(nil, parsec.And(many2many, sumOp, &value), nil)
 
 	// Circular rats come to life
 	// sum -> prod (andop prod)*
-	sum = parsec.And(sumNode(fn), &value, prodK)
-	// value -> id | "(" expr ")"
-	value = parsec.OrdChoice(exprValueNode(fn), identity(), proxy(), attr(), groupExpr)
+	sum = parsec.And(n.sum, &value, prodK)
+	// value -> threshold | "!" value | id | "(" expr ")"
+	value = parsec.OrdChoice(n.value, thresholdExpr, notExpr, identity(), proxy(), did(), rule(), attr(), groupExpr)
 	// expr  -> sum
 	Y = parsec.OrdChoice(one2one, sum)
 	return Y
@@ -86,11 +136,16 @@ func InitParser(fn ValueCheckFn) parsec.Parser {
 // the result of the evaluate (a boolean), but the result is only valid if
 // there are no errors.
 func Evaluate(parser parsec.Parser, expr Expr) (bool, error) {
-	v, s := parser(parsec.NewScanner(expr))
-	_, s = s.SkipWS()
-	if !s.Endof() {
-		rest, _ := s.Match(".*")
-		return false, fmt.Errorf("%v: (rest = %v)", errScannerNotEmpty, string(rest))
+	// The parser only sees the results of the expressions of a threshold,
+	// so the duplicates are found with the text of the explanation.
+	if bytes.Contains(expr, []byte("threshold")) {
+		if _, err := Explain(expr, func(string) bool { return false }); err != nil {
+			return false, err
+		}
+	}
+	v, err := parse(parser, expr)
+	if err != nil {
+		return false, err
 	}
 	vv, ok := v.(bool)
 	if !ok {
@@ -99,6 +154,32 @@ func Evaluate(parser parsec.Parser, expr Expr) (bool, error) {
 	return vv, nil
 }
 
+// parse runs the parser on the whole expression.
+func parse(parser parsec.Parser, expr Expr) (parsec.ParsecNode, error) {
+	v, s := parser(parsec.NewScanner(expr))
+	_, s = s.SkipWS()
+	if !s.Endof() {
+		rest, _ := s.Match(".*")
+		return nil, fmt.Errorf("%v: (rest = %v)", errScannerNotEmpty, string(rest))
+	}
+	return v, nil
+}
+
+// Validate returns an error if the expression cannot be parsed, or if it
+// evaluates to true when none of its ids and attributes are valid. This
+// happens if a negation is not combined with a positive requirement, like in
+// "!ed25519:c" or "ed25519:a | !ed25519:c".
+func Validate(expr Expr) error {
+	res, err := Evaluate(InitParser(func(string) bool { return false }), expr)
+	if err != nil {
+		return err
+	}
+	if res {
+		return errors.New("expression is fulfilled without any valid id")
+	}
+	return nil
+}
+
 // DefaultParser creates a parser and evaluates the expression expr, every id
 // in pks will evaluate to true.
 func DefaultParser(expr Expr, ids ...string) (bool, error) {
@@ -124,11 +205,18 @@ func InitOrExpr(ids ...string) Expr {
 	return Expr(strings.Join(ids, " | "))
 }
 
-// Accepts tokens of the form "identity_type:HEX"
+// InitThresholdExpr creates an expression that is true if at least t of the
+// IDs are valid.
+func InitThresholdExpr(t int, ids ...string) Expr {
+	return Expr(fmt.Sprintf("threshold(%d, %s)", t, strings.Join(ids, ", ")))
+}
+
+// Accepts tokens of the form "identity_type:HEX", and "x509cert:HEX:HEX"
+// where the hash of the root CA is optional
 func identity() parsec.Parser {
 	return func(s parsec.Scanner) (parsec.ParsecNode, parsec.Scanner) {
 		_, s = s.SkipAny(`^[ \n\t]+`)
-		p := parsec.Token(`(darc|ed25519|x509ec):[0-9a-fA-F]+`, "HEX")
+		p := parsec.Token(`((darc|ed25519|x509ec|secp256k1|bls):[0-9a-fA-F]+|x509cert:[0-9a-fA-F]+(:[0-9a-fA-F]+)?)`, "HEX")
 		return p(s)
 	}
 }
@@ -142,11 +230,30 @@ func proxy() parsec.Parser {
 	}
 }
 
-// Accepts tokens of the form that begins with "attr:"
+// Accepts tokens of the form "did:method:method_specific_id"
+func did() parsec.Parser {
+	return func(s parsec.Scanner) (parsec.ParsecNode, parsec.Scanner) {
+		_, s = s.SkipAny(`^[ \n\t]+`)
+		p := parsec.Token(`did:[a-z0-9]+:[a-zA-Z0-9.\-_:%]+`, "DID")
+		return p(s)
+	}
+}
+
+// Accepts tokens of the form "rule:darc_id:action"
+func rule() parsec.Parser {
+	return func(s parsec.Scanner) (parsec.ParsecNode, parsec.Scanner) {
+		_, s = s.SkipAny(`^[ \n\t]+`)
+		p := parsec.Token(`rule:[0-9a-fA-F]+:[0-9a-zA-Z.\-\_:]+`, "RULE")
+		return p(s)
+	}
+}
+
+// Accepts tokens of the form that begins with "attr:", followed either by
+// "name:value" or by a predicate like "name>=value"
 func attr() parsec.Parser {
 	return func(s parsec.Scanner) (parsec.ParsecNode, parsec.Scanner) {
 		_, s = s.SkipAny(`^[ \n\t]+`)
-		p := parsec.Token(`attr:[0-9a-zA-Z\-\_]+:[^ \n\t]*`, "ATTR")
+		p := parsec.Token(`attr:[0-9a-zA-Z\-\_]+(:[^ \n\t]*|(<=|>=|==|!=|<|>)[0-9a-zA-Z.\-\_]+)`, "ATTR")
 		return p(s)
 	}
 }
@@ -171,6 +278,42 @@ func sumNode(fn ValueCheckFn) func(ns []parsec.ParsecNode) parsec.ParsecNode {
 	}
 }
 
+// thresholdNode counts the expressions that are true. It returns nil, which
+// makes the parsing fail, if the threshold is not between 1 and the number
+// of expressions. Duplicate expressions are refused by Evaluate.
+func thresholdNode(ns []parsec.ParsecNode) parsec.ParsecNode {
+	if len(ns) == 0 {
+		return nil
+	}
+	t, err := strconv.Atoi(ns[2].(*parsec.Terminal).Value)
+	if err != nil {
+		return nil
+	}
+	exprs := ns[3].([]parsec.ParsecNode)
+	if t < 1 || t > len(exprs) {
+		return nil
+	}
+	count := 0
+	for _, x := range exprs {
+		if x.([]parsec.ParsecNode)[1].(bool) {
+			count++
+		}
+	}
+	return count >= t
+}
+
+// notNode negates the value it applies to.
+func notNode(ns []parsec.ParsecNode) parsec.ParsecNode {
+	if len(ns) == 0 {
+		return nil
+	}
+	val, ok := ns[1].(bool)
+	if !ok {
+		return nil
+	}
+	return !val
+}
+
 func exprValueNode(fn ValueCheckFn) func(ns []parsec.ParsecNode) parsec.ParsecNode {
 	return func(ns []parsec.ParsecNode) parsec.ParsecNode {
 		if len(ns) == 0 {
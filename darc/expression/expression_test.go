@@ -236,6 +236,24 @@ func TestParsing_Attr(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+
+	expr = []byte("attr:age>=18 & (attr:tx_amount<1000 | attr:country!=CH)")
+	_, err = Evaluate(InitParser(trueFn), expr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expr = []byte("attr:age>=")
+	_, err = Evaluate(InitParser(trueFn), expr)
+	if err == nil {
+		t.Fatal("attr predicate needs a value")
+	}
+
+	expr = []byte("attr:age=>18")
+	_, err = Evaluate(InitParser(trueFn), expr)
+	if err == nil {
+		t.Fatal("attr predicate needs a valid operator")
+	}
 }
 
 func TestParsing_Empty(t *testing.T) {
@@ -268,3 +286,189 @@ func TestEval_DefaultParser(t *testing.T) {
 		t.Fatal("evaluation should return false")
 	}
 }
+
+func TestEval_Threshold(t *testing.T) {
+	keys := []string{"ed25519:a", "ed25519:b", "x509ec:c"}
+	expr := InitThresholdExpr(2, keys...)
+	for _, tc := range []struct {
+		valid []string
+		ok    bool
+	}{
+		{nil, false},
+		{keys[:1], false},
+		{keys[1:], true},
+		{[]string{keys[0], keys[2]}, true},
+		{keys, true},
+	} {
+		ok, err := DefaultParser(expr, tc.valid...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok != tc.ok {
+			t.Fatalf("%v should evaluate to %v with %v", string(expr), tc.ok, tc.valid)
+		}
+	}
+
+	// Thresholds can be combined with other expressions, and contain
+	// expressions.
+	expr = Expr("threshold(2, ed25519:a & ed25519:b, (x509ec:c | darc:d), " +
+		"threshold(1, ed25519:e)) & ed25519:f")
+	ok, err := DefaultParser(expr, "ed25519:a", "darc:d", "ed25519:f")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok != false {
+		t.Fatal("evaluation should return false")
+	}
+	ok, err = DefaultParser(expr, "ed25519:a", "ed25519:b", "ed25519:e", "ed25519:f")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok != true {
+		t.Fatal("evaluation should return true")
+	}
+
+	for _, bad := range []string{
+		"threshold(0, ed25519:a)",
+		"threshold(3, ed25519:a, ed25519:b)",
+		"threshold(1)",
+		"threshold(ed25519:a)",
+		"threshold(1, ed25519:a",
+		"threshold(x, ed25519:a)",
+	} {
+		if _, err := Evaluate(InitParser(trueFn), Expr(bad)); err == nil {
+			t.Fatalf("%s should fail", bad)
+		}
+	}
+}
+
+func TestEval_ThresholdDuplicates(t *testing.T) {
+	// The same id would be counted twice, so that one signer fulfills a
+	// 2-of-n threshold.
+	for _, bad := range []string{
+		"threshold(2, ed25519:a, ed25519:a)",
+		"threshold(2, ed25519:a, (ed25519:a), ed25519:b)",
+		"threshold(1, ed25519:a & ed25519:b, ed25519:a  &  ed25519:b)",
+		"ed25519:c | threshold(2, ed25519:a, threshold(1, ed25519:b, ed25519:b))",
+	} {
+		if _, err := DefaultParser(Expr(bad), "ed25519:a"); err == nil {
+			t.Fatalf("%s should fail", bad)
+		}
+		if _, err := Explain(Expr(bad), trueFn); err == nil {
+			t.Fatalf("%s should fail to explain", bad)
+		}
+		if err := Validate(Expr(bad)); err == nil {
+			t.Fatalf("%s should not validate", bad)
+		}
+	}
+
+	ok, err := DefaultParser(Expr("threshold(2, ed25519:a, ed25519:a & ed25519:b)"), "ed25519:a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Fatal("a single id should not fulfill the threshold")
+	}
+}
+
+func TestEval_Not(t *testing.T) {
+	expr := Expr("(ed25519:a | ed25519:b) & !ed25519:c")
+	for _, tc := range []struct {
+		valid []string
+		ok    bool
+	}{
+		{nil, false},
+		{[]string{"ed25519:a"}, true},
+		{[]string{"ed25519:b"}, true},
+		{[]string{"ed25519:a", "ed25519:c"}, false},
+		{[]string{"ed25519:c"}, false},
+	} {
+		ok, err := DefaultParser(expr, tc.valid...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok != tc.ok {
+			t.Fatalf("%v should evaluate to %v with %v", string(expr), tc.ok, tc.valid)
+		}
+	}
+
+	// Negations can be nested and applied to groups and thresholds.
+	expr = Expr("ed25519:a & !(ed25519:b & !ed25519:c) & !threshold(1, ed25519:d)")
+	ok, err := DefaultParser(expr, "ed25519:a", "ed25519:b", "ed25519:c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok != true {
+		t.Fatal("evaluation should return true")
+	}
+	ok, err = DefaultParser(expr, "ed25519:a", "ed25519:b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok != false {
+		t.Fatal("evaluation should return false")
+	}
+
+	for _, bad := range []string{"!", "ed25519:a & !", "!!", "ed25519:a !ed25519:b"} {
+		if _, err := Evaluate(InitParser(trueFn), Expr(bad)); err == nil {
+			t.Fatalf("%s should fail", bad)
+		}
+	}
+
+	// Negations that let anybody in are refused.
+	for _, bad := range []string{"!ed25519:c", "ed25519:a | !ed25519:c",
+		"threshold(1, ed25519:a, !ed25519:c)"} {
+		if err := Validate(Expr(bad)); err == nil {
+			t.Fatalf("%s should not be valid", bad)
+		}
+	}
+	if err := Validate(expr); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExplain(t *testing.T) {
+	expr := Expr("(ed25519:a|ed25519:b) & threshold(2, ed25519:c, ed25519:d, !ed25519:e)")
+	e, err := Explain(expr, func(s string) bool {
+		return s == "ed25519:a" || s == "ed25519:c"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !e.Result {
+		t.Fatal("explanation should be true")
+	}
+	if e.Expr != "(ed25519:a | ed25519:b) & threshold(2, ed25519:c, ed25519:d, !ed25519:e)" {
+		t.Fatalf("wrong normalized expression: %s", e.Expr)
+	}
+	if len(e.Children) != 2 || len(e.Children[0].Children) != 2 || len(e.Children[1].Children) != 3 {
+		t.Fatal("wrong tree of sub-expressions")
+	}
+	if !e.Children[0].Children[0].Result || e.Children[0].Children[1].Result {
+		t.Fatal("only ed25519:a should be fulfilled in the group")
+	}
+	if !strings.Contains(e.String(), "[ ] ed25519:d") || !strings.Contains(e.String(), "[x] !ed25519:e") {
+		t.Fatalf("wrong string: %s", e.String())
+	}
+
+	// The result is the same as the one of Evaluate.
+	for _, s := range []string{"ed25519:a & ed25519:b | ed25519:c", "(ed25519:a)", "!(ed25519:c & ed25519:a)"} {
+		e, err := Explain(Expr(s), func(s string) bool { return s == "ed25519:c" })
+		if err != nil {
+			t.Fatal(err)
+		}
+		ok, err := DefaultParser(Expr(s), "ed25519:c")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Result != ok {
+			t.Fatalf("%s should explain to %v", s, ok)
+		}
+	}
+
+	for _, bad := range []string{"", "ed25519:a &", "threshold(3, ed25519:a, ed25519:b)"} {
+		if _, err := Explain(Expr(bad), trueFn); err == nil {
+			t.Fatalf("%s should fail", bad)
+		}
+	}
+}
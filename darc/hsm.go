@@ -0,0 +1,63 @@
+package darc
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"crypto/x509"
+	"errors"
+	"fmt"
+
+	"go.dedis.ch/cothority/v3"
+)
+
+// NewSignerHSM creates a signer whose private key never leaves the hardware
+// security module or key management service behind the crypto.Signer. The
+// PKCS#11 and cloud KMS libraries return such signers for their keys.
+//
+// An Ed25519 key gives an ed25519 identity, as our Schnorr signatures are
+// compatible with Ed25519, and an ECDSA key gives a x509ec identity. Other
+// key types are not supported.
+func NewSignerHSM(key crypto.Signer) (Signer, error) {
+	var id Identity
+	switch pub := key.Public().(type) {
+	case ed25519.PublicKey:
+		point := cothority.Suite.Point()
+		if err := point.UnmarshalBinary(pub); err != nil {
+			return Signer{}, fmt.Errorf("invalid Ed25519 key: %v", err)
+		}
+		id = NewIdentityEd25519(point)
+	case *ecdsa.PublicKey:
+		buf, err := x509.MarshalPKIXPublicKey(pub)
+		if err != nil {
+			return Signer{}, fmt.Errorf("invalid ECDSA key: %v", err)
+		}
+		id = NewIdentityX509EC(buf)
+	default:
+		return Signer{}, fmt.Errorf("unsupported key type %T", pub)
+	}
+	return Signer{HSM: &SignerHSM{
+		Identity: id,
+		key:      key,
+	}}, nil
+}
+
+// Sign asks the key to sign the message, in the format expected by the
+// identity of the key.
+func (sh SignerHSM) Sign(msg []byte) ([]byte, error) {
+	if sh.key == nil {
+		return nil, errors.New("the key of the signer is not available")
+	}
+	switch {
+	case sh.Identity.Ed25519 != nil:
+		// Ed25519 signs the message itself, without a hash.
+		return sh.key.Sign(rand.Reader, msg, crypto.Hash(0))
+	case sh.Identity.X509EC != nil:
+		digest := sha512.Sum384(msg)
+		return sh.key.Sign(rand.Reader, digest[:], crypto.SHA384)
+	default:
+		return nil, errors.New("unsupported identity type")
+	}
+}
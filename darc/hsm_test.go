@@ -0,0 +1,52 @@
+package darc
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/protobuf"
+)
+
+// The keys of the standard library implement crypto.Signer, like the keys
+// of a PKCS#11 token.
+func TestSignerHSM(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+
+	msg := []byte("genesis darc evolution")
+	for i, key := range []crypto.Signer{edKey, ecKey} {
+		signer, err := NewSignerHSM(key)
+		require.NoError(t, err)
+		require.Equal(t, i+1, signer.Type())
+		_, err = signer.GetPrivate()
+		require.Error(t, err)
+
+		sig, err := signer.Sign(msg)
+		require.NoError(t, err)
+		id := signer.Identity()
+		require.NoError(t, id.Verify(msg, sig))
+		require.Error(t, id.Verify([]byte("other message"), sig))
+
+		// The key is not stored with the signer.
+		buf, err := protobuf.Encode(&signer)
+		require.NoError(t, err)
+		var loaded Signer
+		require.NoError(t, protobuf.Decode(buf, &loaded))
+		require.True(t, loaded.Identity().Equal(&id))
+		_, err = loaded.Sign(msg)
+		require.Error(t, err)
+	}
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, err = NewSignerHSM(rsaKey)
+	require.Error(t, err)
+}
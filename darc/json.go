@@ -0,0 +1,288 @@
+package darc
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+// JSONSchema is the version of the JSON encoding of the darcs. It is stored
+// in every encoded darc, and darcs with another version are refused, so that
+// the encoding can change without the old files being misread.
+const JSONSchema = 1
+
+// The JSON encoding of the darcs is canonical: encoding a darc always gives
+// the same bytes, and decoding them gives back the same darc. The binary
+// fields are hex encoded and the identities are written with their string
+// representation, except for the X.509 certificate identities and the
+// delegation tokens, whose strings don't hold all their data.
+
+type darcJSON struct {
+	Schema            int
+	Version           uint64
+	Description       string `json:",omitempty"`
+	DescriptionHex    string `json:",omitempty"`
+	BaseID            string
+	PrevID            string
+	Rules             Rules
+	Revoked           []string    `json:",omitempty"`
+	Signatures        []Signature `json:",omitempty"`
+	VerificationDarcs []*Darc     `json:",omitempty"`
+}
+
+type ruleJSON struct {
+	Action     string
+	Expression string
+}
+
+type signatureJSON struct {
+	Signature string
+	Signer    Identity
+}
+
+type identityJSON struct {
+	X509Cert []string   `json:",omitempty"`
+	Token    *tokenJSON `json:",omitempty"`
+}
+
+type tokenJSON struct {
+	Issuer    Identity
+	Delegate  Identity
+	Actions   []Action
+	Expiry    uint64
+	Signature string
+	ChainID   string
+	Target    string `json:",omitempty"`
+}
+
+// NewFromJSON returns a darc from its JSON encoding, as created by
+// json.Marshal.
+func NewFromJSON(buf []byte) (*Darc, error) {
+	d := &Darc{}
+	if err := json.Unmarshal(buf, d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// MarshalJSON implements json.Marshaler. A description that is not valid
+// UTF-8 is hex encoded.
+func (d Darc) MarshalJSON() ([]byte, error) {
+	dj := darcJSON{
+		Schema:            JSONSchema,
+		Version:           d.Version,
+		BaseID:            hex.EncodeToString(d.BaseID),
+		PrevID:            hex.EncodeToString(d.PrevID),
+		Rules:             d.Rules,
+		Revoked:           d.Revoked,
+		Signatures:        d.Signatures,
+		VerificationDarcs: d.VerificationDarcs,
+	}
+	if utf8.Valid(d.Description) {
+		dj.Description = string(d.Description)
+	} else {
+		dj.DescriptionHex = hex.EncodeToString(d.Description)
+	}
+	return json.Marshal(dj)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Darc) UnmarshalJSON(buf []byte) error {
+	var dj darcJSON
+	if err := json.Unmarshal(buf, &dj); err != nil {
+		return err
+	}
+	if dj.Schema != JSONSchema {
+		return fmt.Errorf("unsupported darc schema version %d", dj.Schema)
+	}
+	if dj.Description != "" && dj.DescriptionHex != "" {
+		return errors.New("a darc cannot have both a text and a hex description")
+	}
+	desc, err := decodeHexJSON("description", dj.DescriptionHex)
+	if err != nil {
+		return err
+	}
+	if dj.Description != "" {
+		desc = []byte(dj.Description)
+	}
+	baseID, err := decodeHexJSON("base ID", dj.BaseID)
+	if err != nil {
+		return err
+	}
+	prevID, err := decodeHexJSON("previous ID", dj.PrevID)
+	if err != nil {
+		return err
+	}
+	*d = Darc{
+		Version:           dj.Version,
+		Description:       desc,
+		BaseID:            baseID,
+		PrevID:            prevID,
+		Rules:             dj.Rules,
+		Signatures:        dj.Signatures,
+		VerificationDarcs: dj.VerificationDarcs,
+		Revoked:           dj.Revoked,
+	}
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler. The rules are written in their
+// order, as it is part of the ID of the darc.
+func (r Rules) MarshalJSON() ([]byte, error) {
+	rjs := make([]ruleJSON, len(r.List))
+	for i, rule := range r.List {
+		rjs[i] = ruleJSON{
+			Action:     string(rule.Action),
+			Expression: string(rule.Expr),
+		}
+	}
+	return json.Marshal(rjs)
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It fails if an action is given
+// twice.
+func (r *Rules) UnmarshalJSON(buf []byte) error {
+	var rjs []ruleJSON
+	if err := json.Unmarshal(buf, &rjs); err != nil {
+		return err
+	}
+	rules := NewRules()
+	for _, rj := range rjs {
+		if err := rules.AddRule(Action(rj.Action), []byte(rj.Expression)); err != nil {
+			return err
+		}
+	}
+	*r = rules
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (s Signature) MarshalJSON() ([]byte, error) {
+	return json.Marshal(signatureJSON{
+		Signature: hex.EncodeToString(s.Signature),
+		Signer:    s.Signer,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (s *Signature) UnmarshalJSON(buf []byte) error {
+	var sj signatureJSON
+	if err := json.Unmarshal(buf, &sj); err != nil {
+		return err
+	}
+	sig, err := decodeHexJSON("signature", sj.Signature)
+	if err != nil {
+		return err
+	}
+	*s = Signature{Signature: sig, Signer: sj.Signer}
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler. Most identities are written as a
+// string, like "ed25519:abcd...". The X.509 certificate identities are
+// written as an object holding the hex encoded chain, and the delegation
+// tokens as an object holding all the fields of the token. An empty
+// identity is written as null.
+func (id Identity) MarshalJSON() ([]byte, error) {
+	switch id.Type() {
+	case -1:
+		return []byte("null"), nil
+	case 5:
+		ij := identityJSON{}
+		for _, cert := range id.X509Cert.Chain {
+			ij.X509Cert = append(ij.X509Cert, hex.EncodeToString(cert))
+		}
+		return json.Marshal(ij)
+	case 8:
+		return json.Marshal(identityJSON{Token: &tokenJSON{
+			Issuer:    id.Token.Issuer,
+			Delegate:  id.Token.Delegate,
+			Actions:   id.Token.Actions,
+			Expiry:    id.Token.Expiry,
+			Signature: hex.EncodeToString(id.Token.Signature),
+			ChainID:   hex.EncodeToString(id.Token.ChainID),
+			Target:    hex.EncodeToString(id.Token.Target),
+		}})
+	default:
+		return json.Marshal(id.String())
+	}
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (id *Identity) UnmarshalJSON(buf []byte) error {
+	buf = bytes.TrimSpace(buf)
+	if bytes.Equal(buf, []byte("null")) {
+		*id = Identity{}
+		return nil
+	}
+	if len(buf) > 0 && buf[0] == '"' {
+		var s string
+		if err := json.Unmarshal(buf, &s); err != nil {
+			return err
+		}
+		parsed, err := ParseIdentity(s)
+		if err != nil {
+			return err
+		}
+		*id = parsed
+		return nil
+	}
+
+	var ij identityJSON
+	if err := json.Unmarshal(buf, &ij); err != nil {
+		return err
+	}
+	switch {
+	case len(ij.X509Cert) > 0 && ij.Token == nil:
+		chain := make([][]byte, len(ij.X509Cert))
+		for i, cert := range ij.X509Cert {
+			var err error
+			chain[i], err = decodeHexJSON("certificate", cert)
+			if err != nil {
+				return err
+			}
+		}
+		*id = Identity{X509Cert: &IdentityX509Cert{Chain: chain}}
+	case len(ij.X509Cert) == 0 && ij.Token != nil:
+		sig, err := decodeHexJSON("token signature", ij.Token.Signature)
+		if err != nil {
+			return err
+		}
+		chainID, err := decodeHexJSON("token chain ID", ij.Token.ChainID)
+		if err != nil {
+			return err
+		}
+		target, err := decodeHexJSON("token target", ij.Token.Target)
+		if err != nil {
+			return err
+		}
+		*id = Identity{Token: &IdentityToken{
+			Issuer:    ij.Token.Issuer,
+			Delegate:  ij.Token.Delegate,
+			Actions:   ij.Token.Actions,
+			Expiry:    ij.Token.Expiry,
+			Signature: sig,
+			ChainID:   chainID,
+			Target:    target,
+		}}
+	default:
+		return errors.New("an identity must be a string, a certificate chain or a token")
+	}
+	return nil
+}
+
+// decodeHexJSON decodes a hex field, keeping empty fields nil so that they
+// are the same as the ones decoded from protobuf.
+func decodeHexJSON(name, in string) ([]byte, error) {
+	if in == "" {
+		return nil, nil
+	}
+	out, err := hex.DecodeString(in)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", name, err)
+	}
+	return out, nil
+}
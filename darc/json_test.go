@@ -0,0 +1,86 @@
+package darc
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDarc_JSON(t *testing.T) {
+	td := createDarc(2, "json")
+	d := td.darc
+	d.Revoke(createIdentity().String())
+	evolved := d.Copy()
+	require.NoError(t, evolved.Rules.AddRule("spawn:value",
+		[]byte(td.ids[0].String()+" & "+NewIdentityRule(d.GetBaseID(), "_sign").String())))
+	require.NoError(t, localEvolution(evolved, d, td.owners...))
+
+	buf, err := json.Marshal(evolved)
+	require.NoError(t, err)
+	require.Contains(t, string(buf), `"Description":"json"`)
+	decoded, err := NewFromJSON(buf)
+	require.NoError(t, err)
+	require.Equal(t, evolved.GetID(), decoded.GetID())
+	require.Equal(t, evolved.Rules, decoded.Rules)
+	require.Equal(t, evolved.Revoked, decoded.Revoked)
+	require.Equal(t, len(evolved.Signatures), len(decoded.Signatures))
+	for i, sig := range evolved.Signatures {
+		require.Equal(t, sig.Signature, decoded.Signatures[i].Signature)
+		require.True(t, sig.Signer.Equal(&decoded.Signatures[i].Signer))
+	}
+	// The encoding is canonical.
+	buf2, err := json.Marshal(decoded)
+	require.NoError(t, err)
+	require.Equal(t, buf, buf2)
+
+	// A binary description is hex encoded.
+	d = NewDarc(NewRules(), []byte{0xff, 0x00})
+	buf, err = json.Marshal(d)
+	require.NoError(t, err)
+	require.Contains(t, string(buf), `"DescriptionHex":"ff00"`)
+	decoded, err = NewFromJSON(buf)
+	require.NoError(t, err)
+	require.Equal(t, d.GetID(), decoded.GetID())
+
+	// Other schema versions are refused.
+	_, err = NewFromJSON([]byte(strings.Replace(string(buf), `"Schema":1`, `"Schema":2`, 1)))
+	require.Error(t, err)
+	_, err = NewFromJSON([]byte(`{"Rules":[]}`))
+	require.Error(t, err)
+	_, err = NewFromJSON([]byte(`{"Schema":1,"Rules":[` +
+		`{"Action":"_sign","Expression":"ed25519:a"},` +
+		`{"Action":"_sign","Expression":"ed25519:b"}]}`))
+	require.Error(t, err)
+}
+
+func TestIdentity_JSON(t *testing.T) {
+	issuer := NewSignerEd25519(nil, nil)
+	token, err := NewIdentityToken(issuer, createIdentity(), []byte("chain"),
+		[]byte("target"), []Action{"spawn:value"}, 10)
+	require.NoError(t, err)
+	ids := []Identity{
+		createIdentity(),
+		NewIdentityDarc([]byte{1, 2, 3}),
+		NewIdentityRule([]byte{1, 2, 3}, "invoke:coin.transfer"),
+		NewIdentityX509Cert([][]byte{{1, 2}, {3, 4}}),
+		NewSignerBLS(nil, nil).Identity(),
+		token,
+	}
+	buf, err := json.Marshal(append(ids, Identity{}))
+	require.NoError(t, err)
+	require.True(t, strings.HasSuffix(string(buf), ",null]"))
+	var decoded []Identity
+	require.NoError(t, json.Unmarshal(buf, &decoded))
+	require.Equal(t, len(ids)+1, len(decoded))
+	require.Equal(t, -1, decoded[len(ids)].Type())
+	for i := range ids {
+		require.True(t, ids[i].Equal(&decoded[i]), ids[i].String())
+	}
+
+	for _, bad := range []string{`"ed25519:xx"`, `{}`, `{"X509Cert":["zz"]}`} {
+		var id Identity
+		require.Error(t, json.Unmarshal([]byte(bad), &id), bad)
+	}
+}
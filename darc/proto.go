@@ -1,6 +1,8 @@
 package darc
 
 import (
+	"crypto"
+
 	"go.dedis.ch/cothority/v3/darc/expression"
 	"go.dedis.ch/kyber/v3"
 	"go.dedis.ch/onet/v3/network"
@@ -51,6 +53,9 @@ type Darc struct {
 	// verify this darc. It is not needed in online verification where the
 	// verifier stores all darcs.
 	VerificationDarcs []*Darc
+	// Revoked holds the identities that are refused by all the rules of
+	// the darc, even if a rule holds them.
+	Revoked []string
 }
 
 // Identity is a generic structure can be either an Ed25519 public key, a Darc
@@ -64,6 +69,19 @@ type Identity struct {
 	X509EC *IdentityX509EC
 	// A claim which has been signed by a proxy or proxies.
 	Proxy *IdentityProxy
+	// An Ethereum address, from a secp256k1 public key.
+	Secp256k1 *IdentitySecp256k1
+	// An X.509 certificate chain.
+	X509Cert *IdentityX509Cert
+	// A W3C decentralized identifier.
+	DID *IdentityDID
+	// Public-key identity on the pairing curve, whose signatures can be
+	// aggregated.
+	BLS *IdentityBLS
+	// A delegation token of another identity.
+	Token *IdentityToken
+	// A rule of another darc that must be fulfilled.
+	Rule *IdentityRule
 }
 
 // IdentityEd25519 holds a Ed25519 public key (Point)
@@ -76,6 +94,49 @@ type IdentityX509EC struct {
 	Public []byte
 }
 
+// IdentitySecp256k1 holds the Ethereum address of a secp256k1 public key,
+// which is the last 20 bytes of the Keccak256 hash of the public key.
+type IdentitySecp256k1 struct {
+	Address []byte
+}
+
+// IdentityX509Cert holds a chain of X.509 certificates, starting with the
+// certificate of the signer and ending with the self-signed root CA. All
+// certificates are DER encoded.
+type IdentityX509Cert struct {
+	Chain [][]byte
+}
+
+// IdentityDID holds a W3C decentralized identifier, whose verification keys
+// are given by the resolver registered for its method.
+type IdentityDID struct {
+	DID string
+}
+
+// IdentityBLS holds a marshalled BLS public key, which is a point of the G2
+// group of bn256.
+type IdentityBLS struct {
+	Public []byte
+}
+
+// IdentityToken is a delegation token, signed by the issuer, that lets the
+// delegate sign on behalf of the issuer, but only for the given actions and
+// until the expiry.
+type IdentityToken struct {
+	Issuer   Identity
+	Delegate Identity
+	Actions  []Action
+	// Expiry is the index of the first block where the token is not valid
+	// anymore.
+	Expiry    uint64
+	Signature []byte
+	// ChainID is the ID of the skipchain where the token can be used.
+	ChainID []byte
+	// Target restricts the token to the instance with this ID, if it is
+	// set.
+	Target []byte `protobuf:"opt"`
+}
+
 // IdentityProxy holds the info necessary to verify a claim
 // from an external authentication system via an Authentication Proxy.
 type IdentityProxy struct {
@@ -90,6 +151,14 @@ type IdentityDarc struct {
 	ID ID
 }
 
+// IdentityRule points to a rule of a darc. It is fulfilled by the signers
+// that are allowed to execute the action of the rule in the darc, which lets
+// a darc require the approval of another organization.
+type IdentityRule struct {
+	ID     ID
+	Action Action
+}
+
 // Signature is a signature on a Darc to accept a given decision.
 // can be verified using the appropriate identity.
 type Signature struct {
@@ -101,9 +170,15 @@ type Signature struct {
 
 // Signer is a generic structure that can hold different types of signers
 type Signer struct {
-	Ed25519 *SignerEd25519
-	X509EC  *SignerX509EC
-	Proxy   *SignerProxy
+	Ed25519   *SignerEd25519
+	X509EC    *SignerX509EC
+	Proxy     *SignerProxy
+	Secp256k1 *SignerSecp256k1
+	X509Cert  *SignerX509Cert
+	DID       *SignerDID
+	BLS       *SignerBLS
+	HSM       *SignerHSM
+	Token     *SignerToken
 }
 
 // SignerEd25519 holds a public and private keys necessary to sign Darcs
@@ -127,6 +202,47 @@ type SignerProxy struct {
 	getSignature func([]byte) ([]byte, error)
 }
 
+// SignerSecp256k1 holds the private key of an Ethereum key pair, which can
+// sign Darcs and ByzCoin instructions.
+type SignerSecp256k1 struct {
+	Secret []byte
+}
+
+// SignerX509Cert holds a chain of X.509 certificates and the PKCS #8 encoded
+// private key of the first certificate of the chain.
+type SignerX509Cert struct {
+	Chain  [][]byte
+	Secret []byte
+}
+
+// SignerDID holds a decentralized identifier and the signer of one of its
+// verification keys.
+type SignerDID struct {
+	DID string
+	Key Signer
+}
+
+// SignerBLS holds the marshalled public and private keys of a BLS key pair.
+type SignerBLS struct {
+	Public []byte
+	Secret []byte
+}
+
+// SignerHSM holds the identity of a key that is kept in a hardware security
+// module or a key management service, which creates the signatures. The key
+// itself is not stored, so it must be given again to NewSignerHSM after
+// loading the signer.
+type SignerHSM struct {
+	Identity Identity
+	key      crypto.Signer
+}
+
+// SignerToken holds a delegation token and the signer of its delegate.
+type SignerToken struct {
+	Token    IdentityToken
+	Delegate Signer
+}
+
 // Request is the structure that the client must provide to be verified
 type Request struct {
 	BaseID     ID
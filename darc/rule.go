@@ -0,0 +1,43 @@
+package darc
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// NewIdentityRule creates a new rule identity, which is fulfilled by the
+// identities that can execute the action in the darc with the given base ID.
+// Like a darc identity, the latest version of the darc is used.
+func NewIdentityRule(id ID, action Action) Identity {
+	return Identity{
+		Rule: &IdentityRule{
+			ID:     id,
+			Action: action,
+		},
+	}
+}
+
+// Equal returns true if both IdentityRule point to the same rule.
+func (idr IdentityRule) Equal(idr2 *IdentityRule) bool {
+	return bytes.Equal(idr.ID, idr2.ID) && idr.Action == idr2.Action
+}
+
+// String returns "rule:" followed by the ID of the darc and the action.
+func (idr IdentityRule) String() string {
+	return fmt.Sprintf("rule:%x:%s", idr.ID, idr.Action)
+}
+
+func parseIDRule(in string) (Identity, error) {
+	fields := strings.SplitN(in, ":", 2)
+	if len(fields) != 2 || fields[1] == "" {
+		return Identity{}, errors.New("expected rule format of rule:darc-id:action")
+	}
+	id, err := hex.DecodeString(fields[0])
+	if err != nil {
+		return Identity{}, err
+	}
+	return NewIdentityRule(id, Action(fields[1])), nil
+}
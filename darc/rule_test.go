@@ -0,0 +1,66 @@
+package darc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdentityRule(t *testing.T) {
+	alice, bob := createIdentity(), createIdentity()
+	transfer := Action("invoke:coin.transfer")
+	spawn := Action("spawn:value")
+
+	other := createDarc(1, "other organization").darc
+	require.NoError(t, other.Rules.AddRule(transfer, []byte(bob.String())))
+	rule := NewIdentityRule(other.GetBaseID(), transfer)
+	require.Equal(t, 9, rule.Type())
+	require.False(t, rule.PrimaryIdentity())
+	require.Error(t, rule.Verify([]byte("msg"), []byte("sig")))
+
+	d := createDarc(1, "rule").darc
+	require.NoError(t, d.Rules.AddRule(spawn, []byte(alice.String()+" & "+rule.String())))
+	getDarc := DarcsToGetDarcs([]*Darc{d, other})
+
+	require.NoError(t, d.EvalRule(spawn, getDarc, nil, 0, alice.String(), bob.String()))
+	require.Error(t, d.EvalRule(spawn, getDarc, nil, 0, alice.String()))
+	require.Error(t, d.EvalRule(spawn, getDarc, nil, 0, bob.String()))
+	// The sign rule of the other darc doesn't fulfil the rule identity.
+	owner := other.Rules.GetSignExpr()
+	require.Error(t, d.EvalRule(spawn, getDarc, nil, 0, alice.String(), string(owner)))
+
+	// A rule that doesn't exist is never fulfilled.
+	missing := NewIdentityRule(other.GetBaseID(), "invoke:coin.mint")
+	err := EvalExpr([]byte(missing.String()), getDarc, bob.String())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invoke:coin.mint rule does not exist")
+
+	// Rules pointing at each other must not loop forever.
+	cycle := createDarc(1, "cycle").darc
+	require.NoError(t, cycle.Rules.AddRule(spawn,
+		[]byte(NewIdentityRule(d.GetBaseID(), spawn).String())))
+	require.NoError(t, d.Rules.UpdateRule(spawn,
+		[]byte(NewIdentityRule(cycle.GetBaseID(), spawn).String())))
+	getDarc = DarcsToGetDarcs([]*Darc{d, cycle})
+	err = d.EvalRule(spawn, getDarc, nil, 0, alice.String())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "cycle detected")
+}
+
+func TestParseIdentityRule(t *testing.T) {
+	in := "rule:010203:invoke:coin.transfer"
+	id, err := ParseIdentity(in)
+	require.NoError(t, err)
+	require.NotNil(t, id.Rule)
+	require.Equal(t, Action("invoke:coin.transfer"), id.Rule.Action)
+	require.Equal(t, in, id.String())
+	require.True(t, id.Equal(&Identity{Rule: &IdentityRule{
+		ID:     ID{1, 2, 3},
+		Action: "invoke:coin.transfer",
+	}}))
+
+	for _, bad := range []string{"rule:010203", "rule:010203:", "rule:xx:_sign"} {
+		_, err = ParseIdentity(bad)
+		require.Error(t, err, bad)
+	}
+}
@@ -0,0 +1,141 @@
+package darc
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// NewIdentityToken creates a delegation token signed by the issuer, which
+// lets the delegate sign on behalf of the issuer for the given actions on
+// the skipchain with the ID chainID, until the block with the index expiry.
+// If target is not nil, the token can only be used on the instance with this
+// ID. This gives temporary access without evolving the darcs that hold the
+// issuer.
+//
+// The string of the token is the one of the issuer, so that the rules
+// holding the issuer accept the token. But as the actions and the expiry
+// must be checked, the Verify method of the token always fails, and
+// VerifyFor must be used instead.
+func NewIdentityToken(issuer Signer, delegate Identity, chainID, target []byte,
+	actions []Action, expiry uint64) (Identity, error) {
+	token := &IdentityToken{
+		Issuer:   issuer.Identity(),
+		Delegate: delegate,
+		Actions:  actions,
+		Expiry:   expiry,
+		ChainID:  chainID,
+		Target:   target,
+	}
+	if err := token.checkIdentities(); err != nil {
+		return Identity{}, err
+	}
+	if len(actions) == 0 {
+		return Identity{}, errors.New("a token needs at least one action")
+	}
+	if len(chainID) == 0 {
+		return Identity{}, errors.New("a token needs the ID of its chain")
+	}
+	var err error
+	token.Signature, err = issuer.Sign(token.hash())
+	if err != nil {
+		return Identity{}, fmt.Errorf("signing token: %v", err)
+	}
+	return Identity{Token: token}, nil
+}
+
+// checkIdentities makes sure that tokens cannot be delegated further.
+func (it IdentityToken) checkIdentities() error {
+	if it.Issuer.Token != nil || it.Delegate.Token != nil {
+		return errors.New("a token cannot be delegated")
+	}
+	if !it.Issuer.PrimaryIdentity() || !it.Delegate.PrimaryIdentity() {
+		return errors.New("issuer and delegate must be primary identities")
+	}
+	return nil
+}
+
+// hash returns the hash of the token that is signed by the issuer. All the
+// variable length fields are prefixed with their length, so that two
+// different tokens cannot have the same hash.
+func (it IdentityToken) hash() []byte {
+	h := sha256.New()
+	buf := make([]byte, 8)
+	writeUint64 := func(v uint64) {
+		binary.LittleEndian.PutUint64(buf, v)
+		h.Write(buf)
+	}
+	writeBytes := func(b []byte) {
+		writeUint64(uint64(len(b)))
+		h.Write(b)
+	}
+	h.Write([]byte("darc delegation token"))
+	writeBytes(it.ChainID)
+	writeBytes(it.Target)
+	writeBytes([]byte(it.Issuer.String()))
+	writeBytes([]byte(it.Delegate.String()))
+	writeUint64(uint64(len(it.Actions)))
+	for _, a := range it.Actions {
+		writeBytes([]byte(a))
+	}
+	writeUint64(it.Expiry)
+	return h.Sum(nil)
+}
+
+// Equal returns true if both tokens are the same.
+func (it IdentityToken) Equal(it2 *IdentityToken) bool {
+	return bytes.Equal(it.hash(), it2.hash()) && bytes.Equal(it.Signature, it2.Signature)
+}
+
+// VerifyFor returns nil if the token allows the action on the instance
+// target of the skipchain chainID, in the block with the given index, and if
+// sig is a correct signature of the message by the delegate.
+func (it IdentityToken) VerifyFor(chainID, target []byte, action Action, index uint64, msg, sig []byte) error {
+	if err := it.checkIdentities(); err != nil {
+		return err
+	}
+	if len(it.ChainID) == 0 || !bytes.Equal(it.ChainID, chainID) {
+		return errors.New("token is for another chain")
+	}
+	if it.Target != nil && !bytes.Equal(it.Target, target) {
+		return errors.New("token is for another instance")
+	}
+	if index >= it.Expiry {
+		return fmt.Errorf("token expired at block %d", it.Expiry)
+	}
+	allowed := false
+	for _, a := range it.Actions {
+		if a == action {
+			allowed = true
+		}
+	}
+	if !allowed {
+		return fmt.Errorf("token does not allow the action %s", action)
+	}
+	if err := it.Issuer.Verify(it.hash(), it.Signature); err != nil {
+		return fmt.Errorf("wrong signature of the token: %v", err)
+	}
+	return it.Delegate.Verify(msg, sig)
+}
+
+// NewSignerToken creates a signer that signs with the delegate of the token.
+func NewSignerToken(token Identity, delegate Signer) (Signer, error) {
+	if token.Token == nil {
+		return Signer{}, errors.New("not a token identity")
+	}
+	id := delegate.Identity()
+	if !token.Token.Delegate.Equal(&id) {
+		return Signer{}, errors.New("the signer is not the delegate of the token")
+	}
+	return Signer{Token: &SignerToken{
+		Token:    *token.Token,
+		Delegate: delegate,
+	}}, nil
+}
+
+// Sign creates a signature on the message with the delegate.
+func (st SignerToken) Sign(msg []byte) ([]byte, error) {
+	return st.Delegate.Sign(msg)
+}
@@ -0,0 +1,67 @@
+package darc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdentityToken(t *testing.T) {
+	issuer := NewSignerEd25519(nil, nil)
+	delegate := NewSignerEd25519(nil, nil)
+	spawn := Action("spawn:value")
+	chain, target := []byte("chain"), []byte("target")
+
+	token, err := NewIdentityToken(issuer, delegate.Identity(), chain, nil, []Action{spawn}, 10)
+	require.NoError(t, err)
+	require.Equal(t, 8, token.Type())
+	require.Equal(t, issuer.Identity().String(), token.String())
+	require.True(t, token.PrimaryIdentity())
+
+	signer, err := NewSignerToken(token, delegate)
+	require.NoError(t, err)
+	id := signer.Identity()
+	require.True(t, id.Equal(&token))
+	_, err = NewSignerToken(token, issuer)
+	require.Error(t, err)
+
+	msg := []byte("instruction")
+	sig, err := signer.Sign(msg)
+	require.NoError(t, err)
+	require.Error(t, token.Verify(msg, sig))
+	require.NoError(t, token.Token.VerifyFor(chain, target, spawn, 9, msg, sig))
+	require.Error(t, token.Token.VerifyFor(chain, target, spawn, 10, msg, sig))
+	require.Error(t, token.Token.VerifyFor(chain, target, "invoke:value.update", 9, msg, sig))
+	require.Error(t, token.Token.VerifyFor(chain, target, spawn, 9, []byte("other"), sig))
+	require.Error(t, token.Token.VerifyFor([]byte("other chain"), target, spawn, 9, msg, sig))
+
+	// Changing the scope of the token breaks its signature.
+	wider := *token.Token
+	wider.Expiry = 100
+	require.Error(t, wider.VerifyFor(chain, target, spawn, 50, msg, sig))
+	require.False(t, wider.Equal(token.Token))
+	wider = *token.Token
+	wider.ChainID = []byte("other chain")
+	require.Error(t, wider.VerifyFor(wider.ChainID, target, spawn, 9, msg, sig))
+
+	// A token with a target is only valid for that instance, and the
+	// fields cannot be shifted from one to the other.
+	targeted, err := NewIdentityToken(issuer, delegate.Identity(), chain, target, []Action{spawn}, 10)
+	require.NoError(t, err)
+	require.NoError(t, targeted.Token.VerifyFor(chain, target, spawn, 9, msg, sig))
+	require.Error(t, targeted.Token.VerifyFor(chain, []byte("other"), spawn, 9, msg, sig))
+	shifted := *targeted.Token
+	shifted.ChainID, shifted.Target = []byte("chaint"), []byte("arget")
+	require.Error(t, shifted.VerifyFor(shifted.ChainID, shifted.Target, spawn, 9, msg, sig))
+
+	// Tokens cannot be delegated further, and need an action and a chain.
+	_, err = NewIdentityToken(signer, NewSignerEd25519(nil, nil).Identity(), chain, nil, []Action{spawn}, 10)
+	require.Error(t, err)
+	_, err = NewIdentityToken(issuer, delegate.Identity(), chain, nil, nil, 10)
+	require.Error(t, err)
+	_, err = NewIdentityToken(issuer, delegate.Identity(), nil, nil, []Action{spawn}, 10)
+	require.Error(t, err)
+
+	// The rules holding the issuer accept the token.
+	require.NoError(t, EvalExpr([]byte(issuer.Identity().String()), nil, token.String()))
+}
@@ -0,0 +1,173 @@
+package darc
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// An X.509 certificate identity is written as
+//
+//   x509cert:LEAF:ROOT
+//
+// where LEAF is the SHA-256 hash of the public key of the first certificate
+// of the chain, and ROOT the SHA-256 hash of the root certificate. A rule can
+// use "x509cert:LEAF" to accept the key whatever CA issued its certificate,
+// or "x509cert:LEAF:ROOT" to pin the CA.
+//
+// The validity periods of the certificates are not checked, because all the
+// nodes must reach the same decision independently of their clocks. A
+// certificate is revoked by evolving the darcs that refer to it.
+
+// NewIdentityX509Cert creates a new X.509 certificate identity given a chain
+// of DER encoded certificates. The chain starts with the certificate of the
+// signer and ends with the self-signed root CA.
+func NewIdentityX509Cert(chain [][]byte) Identity {
+	return Identity{
+		X509Cert: &IdentityX509Cert{
+			Chain: chain,
+		},
+	}
+}
+
+// Equal returns true if both IdentityX509Cert hold the same chain.
+func (idc IdentityX509Cert) Equal(idc2 *IdentityX509Cert) bool {
+	if len(idc.Chain) != len(idc2.Chain) {
+		return false
+	}
+	for i := range idc.Chain {
+		if !bytes.Equal(idc.Chain[i], idc2.Chain[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the string representation of the identity, which holds the
+// hashes of the public key of the signer and of the root certificate.
+func (idc IdentityX509Cert) String() string {
+	certs, err := idc.certificates()
+	if err != nil {
+		return "x509cert:invalid"
+	}
+	leaf := sha256.Sum256(certs[0].RawSubjectPublicKeyInfo)
+	root := sha256.Sum256(certs[len(certs)-1].Raw)
+	return fmt.Sprintf("x509cert:%x:%x", leaf, root)
+}
+
+// Verify returns nil if the chain is valid and the signature is correct, or
+// an error if something fails.
+func (idc IdentityX509Cert) Verify(msg, s []byte) error {
+	certs, err := idc.certificates()
+	if err != nil {
+		return err
+	}
+	for i, cert := range certs[:len(certs)-1] {
+		if err := cert.CheckSignatureFrom(certs[i+1]); err != nil {
+			return fmt.Errorf("certificate %d: %v", i, err)
+		}
+	}
+	root := certs[len(certs)-1]
+	err = root.CheckSignature(root.SignatureAlgorithm, root.RawTBSCertificate, root.Signature)
+	if err != nil {
+		return fmt.Errorf("root is not self-signed: %v", err)
+	}
+	algo, err := x509CertAlgorithm(certs[0].PublicKey)
+	if err != nil {
+		return err
+	}
+	return certs[0].CheckSignature(algo, msg, s)
+}
+
+// publicBytes returns the DER encoded public key of the signer.
+func (idc IdentityX509Cert) publicBytes() []byte {
+	certs, err := idc.certificates()
+	if err != nil {
+		return nil
+	}
+	return certs[0].RawSubjectPublicKeyInfo
+}
+
+func (idc IdentityX509Cert) certificates() ([]*x509.Certificate, error) {
+	if len(idc.Chain) == 0 {
+		return nil, errors.New("empty certificate chain")
+	}
+	certs := make([]*x509.Certificate, len(idc.Chain))
+	for i, der := range idc.Chain {
+		var err error
+		certs[i], err = x509.ParseCertificate(der)
+		if err != nil {
+			return nil, fmt.Errorf("certificate %d: %v", i, err)
+		}
+	}
+	return certs, nil
+}
+
+// x509CertAlgorithm returns the signature algorithm used by the signers of
+// X.509 certificate identities for the given public key.
+func x509CertAlgorithm(public interface{}) (x509.SignatureAlgorithm, error) {
+	switch public.(type) {
+	case *rsa.PublicKey:
+		return x509.SHA256WithRSA, nil
+	case *ecdsa.PublicKey:
+		return x509.ECDSAWithSHA256, nil
+	case ed25519.PublicKey:
+		return x509.PureEd25519, nil
+	default:
+		return x509.UnknownSignatureAlgorithm, errors.New("unsupported public key")
+	}
+}
+
+// matchX509Cert returns true if the id of an expression, with or without
+// the hash of the root, matches the string of an X.509 certificate identity.
+func matchX509Cert(exprID, id string) bool {
+	return strings.HasPrefix(exprID, "x509cert:") &&
+		strings.HasPrefix(id, exprID+":")
+}
+
+// NewSignerX509Cert creates a new SignerX509Cert given a chain of DER encoded
+// certificates and the private key of the first certificate. RSA, ECDSA and
+// Ed25519 keys are supported.
+func NewSignerX509Cert(chain [][]byte, private crypto.Signer) (Signer, error) {
+	if _, err := x509CertAlgorithm(private.Public()); err != nil {
+		return Signer{}, err
+	}
+	secret, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return Signer{}, err
+	}
+	return Signer{X509Cert: &SignerX509Cert{
+		Chain:  chain,
+		Secret: secret,
+	}}, nil
+}
+
+// Sign creates a signature on the message, using SHA-256 for RSA and ECDSA
+// keys.
+func (sc SignerX509Cert) Sign(msg []byte) ([]byte, error) {
+	key, err := x509.ParsePKCS8PrivateKey(sc.Secret)
+	if err != nil {
+		return nil, err
+	}
+	private, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("private key cannot sign")
+	}
+	algo, err := x509CertAlgorithm(private.Public())
+	if err != nil {
+		return nil, err
+	}
+	if algo == x509.PureEd25519 {
+		return private.Sign(rand.Reader, msg, crypto.Hash(0))
+	}
+	digest := sha256.Sum256(msg)
+	return private.Sign(rand.Reader, digest[:], crypto.SHA256)
+}
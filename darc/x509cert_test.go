@@ -0,0 +1,93 @@
+package darc
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// createCert returns a DER encoded certificate for the public key, signed by
+// the parent, or self-signed if parent is nil.
+func createCert(t *testing.T, name string, public crypto.PublicKey, isCA bool,
+	parent *x509.Certificate, parentKey crypto.Signer) (*x509.Certificate, []byte) {
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+	if isCA {
+		tmpl.KeyUsage = x509.KeyUsageCertSign
+	}
+	if parent == nil {
+		parent = tmpl
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, public, parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert, der
+}
+
+func TestX509Cert(t *testing.T) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ca, caDER := createCert(t, "ca", caKey.Public(), true, nil, caKey)
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	_, otherDER := createCert(t, "other ca", otherKey.Public(), true, nil, otherKey)
+
+	leafPub, leafKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	_, leafDER := createCert(t, "leaf", leafPub, false, ca, caKey)
+
+	signer, err := NewSignerX509Cert([][]byte{leafDER, caDER}, leafKey)
+	require.NoError(t, err)
+	id := signer.Identity()
+	require.Equal(t, 5, id.Type())
+	require.True(t, id.Equal(&id))
+	require.True(t, strings.HasPrefix(id.String(), "x509cert:"))
+
+	msg := []byte("document")
+	sig, err := signer.Sign(msg)
+	require.NoError(t, err)
+	require.NoError(t, id.Verify(msg, sig))
+	require.Error(t, id.Verify([]byte("other document"), sig))
+
+	// A chain with a CA that didn't sign the leaf must be refused.
+	wrongChain := NewIdentityX509Cert([][]byte{leafDER, otherDER})
+	require.Error(t, wrongChain.Verify(msg, sig))
+	require.Error(t, NewIdentityX509Cert(nil).Verify(msg, sig))
+
+	// The rule can pin the CA or not.
+	fields := strings.Split(id.String(), ":")
+	require.Len(t, fields, 3)
+	unpinned := "x509cert:" + fields[1]
+	require.NoError(t, EvalExpr([]byte(unpinned), nil, id.String()))
+	require.NoError(t, EvalExpr([]byte(id.String()), nil, id.String()))
+	other := NewIdentityX509Cert([][]byte{otherDER})
+	pinnedOther := unpinned + ":" + strings.Split(other.String(), ":")[2]
+	require.Error(t, EvalExpr([]byte(pinnedOther), nil, id.String()))
+
+	// A self-signed certificate is its own CA.
+	caSigner, err := NewSignerX509Cert([][]byte{caDER}, caKey)
+	require.NoError(t, err)
+	sig, err = caSigner.Sign(msg)
+	require.NoError(t, err)
+	require.NoError(t, caSigner.Identity().Verify(msg, sig))
+
+	_, err = ParseIdentity(id.String())
+	require.Error(t, err)
+}
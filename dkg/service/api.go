@@ -0,0 +1,88 @@
+package service
+
+import (
+	"errors"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/sign/schnorr"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/network"
+)
+
+// Client is a structure to communicate with the DKG service.
+type Client struct {
+	*onet.Client
+}
+
+// NewClient instantiates a new dkg service client.
+func NewClient() *Client {
+	return &Client{Client: onet.NewClient(cothority.Suite, ServiceName)}
+}
+
+// RunDKG asks the first node of the roster to run a DKG with all the nodes
+// of the roster. A threshold of 0 uses the default of the protocol.
+func (c *Client) RunDKG(r *onet.Roster, threshold uint32) (*RunDKGReply, error) {
+	return c.RunDKGSignature(r, threshold, nil)
+}
+
+// RunDKGSignature is like RunDKG, but signs the request with the private key
+// of a client linked to the nodes, if it is given.
+func (c *Client) RunDKGSignature(r *onet.Roster, threshold uint32, priv kyber.Scalar) (*RunDKGReply, error) {
+	if len(r.List) == 0 {
+		return nil, errors.New("Got an empty roster-list")
+	}
+	req := &RunDKG{Roster: r, Threshold: threshold}
+	if priv != nil {
+		msg, err := req.Hash()
+		if err != nil {
+			return nil, err
+		}
+		req.Signature, err = schnorr.Sign(cothority.Suite, priv, msg)
+		if err != nil {
+			return nil, err
+		}
+	}
+	reply := &RunDKGReply{}
+	err := c.SendProtobuf(r.List[0], req, reply)
+	if err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+// GetPublicKey asks the node for the public part of the distributed key.
+func (c *Client) GetPublicKey(si *network.ServerIdentity, id ID) (*GetPublicKeyReply, error) {
+	reply := &GetPublicKeyReply{}
+	err := c.SendProtobuf(si, &GetPublicKey{ID: id}, reply)
+	if err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+// DeleteKey asks the node to remove its share of the distributed key. The
+// request is signed with the private key of a client linked to the node.
+func (c *Client) DeleteKey(si *network.ServerIdentity, id ID, priv kyber.Scalar) error {
+	sig, err := schnorr.Sign(cothority.Suite, priv, deleteMessage(id))
+	if err != nil {
+		return err
+	}
+	return c.SendProtobuf(si, &DeleteKey{ID: id, Signature: sig}, &EmptyReply{})
+}
+
+// CreateLinkPrivate links the public key of a client to the node, which
+// then only accepts the requests signed by its linked clients. The private
+// key of the node is needed to create the link.
+func (c *Client) CreateLinkPrivate(si *network.ServerIdentity, conodePriv kyber.Scalar,
+	pub kyber.Point) error {
+	msg, err := pub.MarshalBinary()
+	if err != nil {
+		return errors.New("couldn't marshal point: " + err.Error())
+	}
+	sig, err := schnorr.Sign(cothority.Suite, conodePriv, msg)
+	if err != nil {
+		return errors.New("couldn't sign public key: " + err.Error())
+	}
+	return c.SendProtobuf(si, &CreateLinkPrivate{Public: pub, Signature: sig}, &EmptyReply{})
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"go.dedis.ch/cothority/v3"
+	dkgprotocol "go.dedis.ch/cothority/v3/dkg/pedersen"
+	"go.dedis.ch/kyber/v3"
+	dkg "go.dedis.ch/kyber/v3/share/dkg/pedersen"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/log"
+)
+
+const dbVersion = 1
+
+var storageKey = []byte("storage")
+
+// storage holds the shares of the node for all the distributed keys, and
+// the public keys of the linked clients.
+type storage struct {
+	Keys    map[ID]*keyShare
+	Clients []kyber.Point
+
+	sync.Mutex
+}
+
+// keyShare is the share of the node of a distributed key.
+type keyShare struct {
+	Roster    *onet.Roster
+	Threshold uint32
+	Shared    *dkgprotocol.SharedSecret
+	DKS       *dkg.DistKeyShare
+}
+
+// saves all data.
+func (s *Service) save() error {
+	s.storage.Lock()
+	defer s.storage.Unlock()
+	err := s.Save(storageKey, s.storage)
+	if err != nil {
+		log.Error("Couldn't save data:", err)
+		return fmt.Errorf("saving data: %v", err)
+	}
+	return nil
+}
+
+// Tries to load the configuration and updates the data in the service
+// if it finds a valid config-file.
+func (s *Service) tryLoad() error {
+	s.storage = &storage{}
+	ver, err := s.LoadVersion()
+	if err != nil {
+		return fmt.Errorf("loading configuration: %v", err)
+	}
+
+	// Make sure we don't have any unallocated maps.
+	defer func() {
+		if len(s.storage.Keys) == 0 {
+			s.storage.Keys = make(map[ID]*keyShare)
+		}
+	}()
+
+	if ver < dbVersion {
+		// There is no version 0. Save empty storage and update version number.
+		if err = s.save(); err != nil {
+			return fmt.Errorf("saving storage: %v", err)
+		}
+		return cothority.ErrorOrNil(s.SaveVersion(dbVersion), "saving version")
+	}
+	msg, err := s.Load(storageKey)
+	if err != nil {
+		return fmt.Errorf("loading storage: %v", err)
+	}
+	if msg == nil {
+		return nil
+	}
+	var ok bool
+	s.storage, ok = msg.(*storage)
+	if !ok {
+		return errors.New("data of wrong type")
+	}
+	return nil
+}
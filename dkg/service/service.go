@@ -0,0 +1,304 @@
+// Package service runs the Pedersen DKG over a roster and keeps the shares
+// of the nodes, so that other services of the conode can use the
+// distributed keys for threshold signing, randomness or secret sharing.
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go.dedis.ch/cothority/v3"
+	dkgprotocol "go.dedis.ch/cothority/v3/dkg/pedersen"
+	dkg "go.dedis.ch/kyber/v3/share/dkg/pedersen"
+	"go.dedis.ch/kyber/v3/sign/schnorr"
+	"go.dedis.ch/kyber/v3/util/key"
+	"go.dedis.ch/kyber/v3/util/random"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/onet/v3/network"
+	"go.dedis.ch/protobuf"
+)
+
+// ServiceName is the name of the DKG service.
+const ServiceName = "DKG"
+
+// setupTimeout is how long the root waits for the DKG to finish.
+const setupTimeout = 20 * time.Second
+
+// maxKeys is the number of distributed keys a node holds shares of, so that
+// its storage is bounded. DeleteKey removes the keys that are not used
+// anymore.
+var maxKeys = 256
+
+var dkgServiceID onet.ServiceID
+
+func init() {
+	var err error
+	dkgServiceID, err = onet.RegisterNewService(ServiceName, newService)
+	log.ErrFatal(err)
+}
+
+// Service runs the DKG and stores the shares of the node.
+type Service struct {
+	*onet.ServiceProcessor
+	storage *storage
+}
+
+// RunDKG runs the DKG with the nodes of the roster, this node being the
+// root. It returns once all the nodes hold their share.
+func (s *Service) RunDKG(req *RunDKG) (*RunDKGReply, error) {
+	if req.Roster == nil {
+		return nil, errors.New("no roster given")
+	}
+	if i, _ := req.Roster.Search(s.ServerIdentity().ID); i < 0 {
+		return nil, errors.New("this node is not in the roster")
+	}
+	if int(req.Threshold) > len(req.Roster.List) {
+		return nil, fmt.Errorf("threshold %d is bigger than the roster", req.Threshold)
+	}
+	msg, err := req.Hash()
+	if err != nil {
+		return nil, fmt.Errorf("hashing request: %v", err)
+	}
+	if err := s.authenticate(msg, req.Signature); err != nil {
+		return nil, err
+	}
+	if err := s.checkKeys(ID{}); err != nil {
+		return nil, err
+	}
+	tree := req.Roster.GenerateNaryTreeWithRoot(len(req.Roster.List), s.ServerIdentity())
+	if tree == nil {
+		return nil, errors.New("error while generating tree")
+	}
+
+	var id ID
+	random.Bytes(id[:], random.New())
+	cfgBuf, err := protobuf.Encode(&setupConfig{
+		ID:        id,
+		Threshold: req.Threshold,
+		Signature: req.Signature,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("serializing configuration: %v", err)
+	}
+	pi, err := s.CreateProtocol(dkgprotocol.Name, tree)
+	if err != nil {
+		return nil, fmt.Errorf("creating dkg protocol: %v", err)
+	}
+	setupDKG := pi.(*dkgprotocol.Setup)
+	setupDKG.Wait = true
+	if req.Threshold > 0 {
+		setupDKG.Threshold = req.Threshold
+	}
+	if err := setupDKG.SetConfig(&onet.GenericConfig{Data: cfgBuf}); err != nil {
+		return nil, fmt.Errorf("set dkg config: %v", err)
+	}
+	setupDKG.KeyPair = s.getKeyPair()
+	if err := pi.Start(); err != nil {
+		return nil, fmt.Errorf("starting dkg protocol: %v", err)
+	}
+
+	select {
+	case <-setupDKG.Finished:
+		shared, dks, err := setupDKG.SharedSecret()
+		if err != nil {
+			return nil, fmt.Errorf("get shared secret: %v", err)
+		}
+		if err := s.storeShare(id, req.Roster, setupDKG.Threshold, shared, dks); err != nil {
+			return nil, err
+		}
+		log.Lvlf2("%v created distributed key %v, pk %v", s.ServerIdentity(), id, shared.X)
+		return &RunDKGReply{ID: id, X: shared.X}, nil
+	case <-time.After(setupTimeout):
+		return nil, errors.New("dkg didn't finish in time")
+	}
+}
+
+// GetPublicKey returns the public part of a distributed key this node holds
+// a share of.
+func (s *Service) GetPublicKey(req *GetPublicKey) (*GetPublicKeyReply, error) {
+	s.storage.Lock()
+	defer s.storage.Unlock()
+	ks, ok := s.storage.Keys[req.ID]
+	if !ok {
+		return nil, fmt.Errorf("unknown distributed key %v", req.ID)
+	}
+	return &GetPublicKeyReply{
+		X:         ks.Shared.X,
+		Commits:   ks.Shared.Commits,
+		Roster:    ks.Roster,
+		Threshold: ks.Threshold,
+	}, nil
+}
+
+// Share returns the share of this node of the distributed key, for the
+// other services of the conode. It is never sent over the network.
+func (s *Service) Share(id ID) (*dkgprotocol.SharedSecret, *dkg.DistKeyShare, error) {
+	s.storage.Lock()
+	defer s.storage.Unlock()
+	ks, ok := s.storage.Keys[id]
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown distributed key %v", id)
+	}
+	return ks.Shared.Clone(), ks.DKS, nil
+}
+
+// NewProtocol intercepts the DKG protocol to store the share of the node
+// once it is done.
+func (s *Service) NewProtocol(tn *onet.TreeNodeInstance, conf *onet.GenericConfig) (onet.ProtocolInstance, error) {
+	if tn.ProtocolName() != dkgprotocol.Name {
+		return nil, nil
+	}
+	if conf == nil {
+		return nil, errors.New("missing dkg config")
+	}
+	var cfg setupConfig
+	if err := protobuf.DecodeWithConstructors(conf.Data, &cfg, network.DefaultConstructors(cothority.Suite)); err != nil {
+		return nil, fmt.Errorf("decoding dkg config: %v", err)
+	}
+	msg, err := (&RunDKG{Roster: tn.Roster(), Threshold: cfg.Threshold}).Hash()
+	if err != nil {
+		return nil, fmt.Errorf("hashing request: %v", err)
+	}
+	if err := s.authenticate(msg, cfg.Signature); err != nil {
+		return nil, err
+	}
+	if err := s.checkKeys(cfg.ID); err != nil {
+		return nil, err
+	}
+
+	pi, err := dkgprotocol.NewSetup(tn)
+	if err != nil {
+		return nil, fmt.Errorf("error setting up dkg: %v", err)
+	}
+	setupDKG := pi.(*dkgprotocol.Setup)
+	setupDKG.KeyPair = s.getKeyPair()
+
+	go func() {
+		<-setupDKG.Finished
+		shared, dks, err := setupDKG.SharedSecret()
+		if err != nil {
+			log.Error(err)
+			return
+		}
+		threshold := uint32(len(dks.Commits))
+		if err := s.storeShare(cfg.ID, tn.Roster(), threshold, shared, dks); err != nil {
+			log.Error(err)
+		}
+	}()
+	return pi, nil
+}
+
+// DeleteKey removes the share of the node of a distributed key. Only the
+// linked clients can delete keys.
+func (s *Service) DeleteKey(req *DeleteKey) (*EmptyReply, error) {
+	s.storage.Lock()
+	linked := len(s.storage.Clients) > 0
+	s.storage.Unlock()
+	if !linked {
+		return nil, errors.New("only linked clients can delete keys")
+	}
+	if err := s.authenticate(deleteMessage(req.ID), req.Signature); err != nil {
+		return nil, err
+	}
+	s.storage.Lock()
+	_, ok := s.storage.Keys[req.ID]
+	delete(s.storage.Keys, req.ID)
+	s.storage.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown distributed key %v", req.ID)
+	}
+	return &EmptyReply{}, s.save()
+}
+
+// CreateLinkPrivate checks that the public key is signed with the private
+// key of the node and adds it to the linked clients.
+func (s *Service) CreateLinkPrivate(link *CreateLinkPrivate) (*EmptyReply, error) {
+	msg, err := link.Public.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't marshal public key: %v", err)
+	}
+	if err := schnorr.Verify(cothority.Suite, s.ServerIdentity().Public, msg, link.Signature); err != nil {
+		return nil, fmt.Errorf("wrong signature on public key: %v", err)
+	}
+	s.storage.Lock()
+	for _, cl := range s.storage.Clients {
+		if cl.Equal(link.Public) {
+			s.storage.Unlock()
+			return &EmptyReply{}, nil
+		}
+	}
+	s.storage.Clients = append(s.storage.Clients, link.Public)
+	s.storage.Unlock()
+	return &EmptyReply{}, s.save()
+}
+
+// authenticate checks that the signature on the message is from a linked
+// client. Without linked clients, the node is open to everybody.
+func (s *Service) authenticate(msg, sig []byte) error {
+	s.storage.Lock()
+	defer s.storage.Unlock()
+	if len(s.storage.Clients) == 0 {
+		return nil
+	}
+	for _, cl := range s.storage.Clients {
+		if schnorr.Verify(cothority.Suite, cl, msg, sig) == nil {
+			return nil
+		}
+	}
+	return errors.New("request is not signed by a linked client")
+}
+
+// checkKeys returns an error if the node already holds the distributed key
+// or as many keys as it can.
+func (s *Service) checkKeys(id ID) error {
+	s.storage.Lock()
+	defer s.storage.Unlock()
+	if _, exists := s.storage.Keys[id]; exists {
+		return fmt.Errorf("distributed key %v already exists", id)
+	}
+	if len(s.storage.Keys) >= maxKeys {
+		return fmt.Errorf("already holding %d distributed keys", maxKeys)
+	}
+	return nil
+}
+
+func deleteMessage(id ID) []byte {
+	return append([]byte("delete:"), id[:]...)
+}
+
+func (s *Service) storeShare(id ID, roster *onet.Roster, threshold uint32,
+	shared *dkgprotocol.SharedSecret, dks *dkg.DistKeyShare) error {
+	s.storage.Lock()
+	s.storage.Keys[id] = &keyShare{
+		Roster:    roster,
+		Threshold: threshold,
+		Shared:    shared,
+		DKS:       dks,
+	}
+	s.storage.Unlock()
+	return s.save()
+}
+
+func (s *Service) getKeyPair() *key.Pair {
+	return &key.Pair{
+		Public:  s.ServerIdentity().ServicePublic(ServiceName),
+		Private: s.ServerIdentity().ServicePrivate(ServiceName),
+	}
+}
+
+func newService(c *onet.Context) (onet.Service, error) {
+	s := &Service{
+		ServiceProcessor: onet.NewServiceProcessor(c),
+	}
+	if err := s.RegisterHandlers(s.RunDKG, s.GetPublicKey, s.DeleteKey,
+		s.CreateLinkPrivate); err != nil {
+		return nil, errors.New("couldn't register messages")
+	}
+	if err := s.tryLoad(); err != nil {
+		log.Error(err)
+		return nil, fmt.Errorf("loading configuration: %v", err)
+	}
+	return s, nil
+}
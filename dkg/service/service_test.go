@@ -0,0 +1,99 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/kyber/v3/share"
+	"go.dedis.ch/kyber/v3/util/key"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/log"
+)
+
+var tSuite = cothority.Suite
+
+func TestMain(m *testing.M) {
+	log.MainTest(m)
+}
+
+func TestService_RunDKG(t *testing.T) {
+	local := onet.NewTCPTest(tSuite)
+	defer local.CloseAll()
+	hosts, roster, _ := local.GenTree(5, false)
+	services := local.GetServices(hosts, dkgServiceID)
+
+	reply, err := NewClient().RunDKG(roster, 3)
+	require.NoError(t, err)
+	require.NotNil(t, reply.X)
+
+	// Every node holds a share of the same key. The other nodes store it
+	// once the root is done.
+	var shares []*share.PriShare
+	for _, s := range services {
+		shared, dks, err := s.(*Service).Share(reply.ID)
+		for i := 0; err != nil && i < 10; i++ {
+			time.Sleep(100 * time.Millisecond)
+			shared, dks, err = s.(*Service).Share(reply.ID)
+		}
+		require.NoError(t, err)
+		require.True(t, shared.X.Equal(reply.X))
+		shares = append(shares, dks.PriShare())
+	}
+
+	// Any threshold of the shares recovers the secret of the key.
+	secret, err := share.RecoverSecret(tSuite, shares[2:], 3, len(shares))
+	require.NoError(t, err)
+	require.True(t, tSuite.Point().Mul(secret, nil).Equal(reply.X))
+
+	pub, err := NewClient().GetPublicKey(roster.List[4], reply.ID)
+	require.NoError(t, err)
+	require.True(t, pub.X.Equal(reply.X))
+	require.Equal(t, uint32(3), pub.Threshold)
+	require.Equal(t, 3, len(pub.Commits))
+	require.Equal(t, roster.ID, pub.Roster.ID)
+
+	_, err = NewClient().GetPublicKey(roster.List[0], ID{})
+	require.Error(t, err)
+
+	_, err = NewClient().RunDKG(roster, 6)
+	require.Error(t, err)
+}
+
+func TestService_LinkedClients(t *testing.T) {
+	local := onet.NewTCPTest(tSuite)
+	defer local.CloseAll()
+	hosts, roster, _ := local.GenTree(3, false)
+
+	client := NewClient()
+	linked := key.NewKeyPair(tSuite)
+	other := key.NewKeyPair(tSuite)
+	require.Error(t, client.CreateLinkPrivate(hosts[0].ServerIdentity,
+		other.Private, linked.Public))
+	for _, h := range hosts {
+		require.NoError(t, client.CreateLinkPrivate(h.ServerIdentity,
+			local.GetPrivate(h), linked.Public))
+	}
+
+	// Only the linked client can run a DKG.
+	_, err := client.RunDKG(roster, 0)
+	require.Error(t, err)
+	_, err = client.RunDKGSignature(roster, 0, other.Private)
+	require.Error(t, err)
+	reply, err := client.RunDKGSignature(roster, 0, linked.Private)
+	require.NoError(t, err)
+
+	// Only the linked client can delete a key.
+	require.Error(t, client.DeleteKey(roster.List[0], reply.ID, other.Private))
+	require.NoError(t, client.DeleteKey(roster.List[0], reply.ID, linked.Private))
+	_, err = client.GetPublicKey(roster.List[0], reply.ID)
+	require.Error(t, err)
+	require.Error(t, client.DeleteKey(roster.List[0], reply.ID, linked.Private))
+
+	// The number of keys of a node is bounded.
+	defer func(m int) { maxKeys = m }(maxKeys)
+	maxKeys = 0
+	_, err = client.RunDKGSignature(roster, 0, linked.Private)
+	require.Error(t, err)
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"sort"
+
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/network"
+)
+
+func init() {
+	network.RegisterMessages(&RunDKG{}, &RunDKGReply{},
+		&GetPublicKey{}, &GetPublicKeyReply{}, &DeleteKey{},
+		&CreateLinkPrivate{}, &EmptyReply{}, &storage{}, &setupConfig{})
+}
+
+// ID identifies a distributed key, it is chosen at random by the node
+// starting the DKG.
+type ID [32]byte
+
+// String returns the ID in hex.
+func (id ID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// RunDKG asks the first node of the roster to run a DKG with all the nodes
+// of the roster. If Threshold is 0, the default threshold of the protocol is
+// used, which allows for less than a third of faulty nodes. If the nodes
+// have linked clients, Signature is the schnorr signature of Hash by one of
+// them.
+type RunDKG struct {
+	Roster    *onet.Roster
+	Threshold uint32
+	Signature []byte
+}
+
+// Hash returns the hash of the nodes and the threshold of the request. The
+// nodes are sorted, as the root of the DKG is moved first in the roster.
+func (r *RunDKG) Hash() ([]byte, error) {
+	var publics [][]byte
+	for _, si := range r.Roster.List {
+		buf, err := si.Public.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		publics = append(publics, buf)
+	}
+	sort.Slice(publics, func(i, j int) bool {
+		return bytes.Compare(publics[i], publics[j]) < 0
+	})
+	h := sha256.New()
+	for _, p := range publics {
+		h.Write(p)
+	}
+	binary.Write(h, binary.LittleEndian, r.Threshold)
+	return h.Sum(nil), nil
+}
+
+// RunDKGReply is the ID of the new distributed key, and its public key.
+type RunDKGReply struct {
+	ID ID
+	X  kyber.Point
+}
+
+// GetPublicKey asks a node for the public part of a distributed key it
+// holds a share of.
+type GetPublicKey struct {
+	ID ID
+}
+
+// GetPublicKeyReply holds the public part of the distributed key. Commits
+// are the commitments of the shares, so that the public share of each node
+// can be computed.
+type GetPublicKeyReply struct {
+	X         kyber.Point
+	Commits   []kyber.Point
+	Roster    *onet.Roster
+	Threshold uint32
+}
+
+// DeleteKey asks a node to remove its share of a distributed key. Signature
+// is the schnorr signature of a linked client on "delete:" followed by the
+// ID of the key.
+type DeleteKey struct {
+	ID        ID
+	Signature []byte
+}
+
+// CreateLinkPrivate links the client with the public key to the node. The
+// signature on the public key is made with the private key of the node.
+// Once a node has linked clients, it only runs and deletes distributed keys
+// for them.
+type CreateLinkPrivate struct {
+	Public    kyber.Point
+	Signature []byte
+}
+
+// EmptyReply is returned by the requests that only succeed or fail.
+type EmptyReply struct{}
+
+// setupConfig is sent by the root to the other nodes when starting the DKG.
+// It holds the threshold and the signature of the request, for each node to
+// check the client against its own links.
+type setupConfig struct {
+	ID        ID
+	Threshold uint32
+	Signature []byte
+}
@@ -335,6 +335,39 @@ func scUpdates(c *cli.Context) error {
 	return nil
 }
 
+// scExport writes all the blocks of a skipchain in a bundle that auditors can
+// verify offline with the inclusion package.
+func scExport(c *cli.Context) error {
+	if c.NArg() != 2 {
+		return errors.New("Please give group-file and id of skipchain")
+	}
+	group := readGroupArgs(c, 0)
+	scid, err := hex.DecodeString(c.Args().Get(1))
+	if err != nil {
+		return errors.New("bad skipchain ID")
+	}
+
+	bundle, err := skipchain.NewClient().ExportBundle(group.Roster, scid)
+	if err != nil {
+		return fmt.Errorf("couldn't export the skipchain: %v", err)
+	}
+	if _, err := bundle.Verify(); err != nil {
+		return fmt.Errorf("invalid bundle: %v", err)
+	}
+
+	w := c.App.Writer
+	if c.String("out") != "" {
+		f, err := os.Create(c.String("out"))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	log.Infof("Exporting %d blocks of %x", len(bundle.Blocks), scid)
+	return bundle.Write(w)
+}
+
 func fmtRoster(r *onet.Roster) string {
 	var roster []string
 	for _, s := range r.List {
@@ -172,6 +172,19 @@ func getCommands() cli.Commands {
 						},
 					},
 				},
+				{
+					Name:      "export",
+					Usage:     "export all the blocks of a chain in a bundle that can be verified offline",
+					Aliases:   []string{"e"},
+					ArgsUsage: groupsDef + " skipchain-id",
+					Action:    scExport,
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "out, o",
+							Usage: "file where to write the bundle (default: standard output)",
+						},
+					},
+				},
 				{
 					Name:    "optimize",
 					Usage:   "create missing forward link to optimize the proof of a given block",
@@ -4,8 +4,11 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"math"
 
 	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/blscosi/protocol"
+	"go.dedis.ch/cothority/v3/skipchain/inclusion"
 	status "go.dedis.ch/cothority/v3/status/service"
 	"go.dedis.ch/kyber/v3"
 	"go.dedis.ch/kyber/v3/sign/schnorr"
@@ -243,6 +246,59 @@ func (c *Client) OptimizeProof(ro *onet.Roster, id SkipBlockID) (*OptimizeProofR
 	return reply, err
 }
 
+// GetShortestPath asks the roster for the blocks linking the trusted block
+// from to the block to with the fewest forward-links. It verifies that the
+// path starts with the trusted block and that every block is reached by a
+// valid forward-link of the previous block, so the last block of the path can
+// be trusted.
+func (c *Client) GetShortestPath(roster *onet.Roster, from *SkipBlock, to SkipBlockID) (Proof, error) {
+	reply := &GetShortestPathReply{}
+	_, err := c.SendProtobufParallel(roster.List, &GetShortestPath{
+		From: from.Hash,
+		To:   to,
+	}, reply, c.options)
+	if err != nil {
+		return nil, err
+	}
+	path := reply.Path
+	if len(path) == 0 || !path[0].Hash.Equal(from.Hash) {
+		return nil, errors.New("path doesn't start with the trusted block")
+	}
+	if !path[len(path)-1].Hash.Equal(to) {
+		return nil, errors.New("path doesn't end with the requested block")
+	}
+	// As the hash of the first block is checked, its roster is the one
+	// of the trusted block, and all the links can be verified.
+	if err := verifyLinkedBlocks(path); err != nil {
+		return nil, err
+	}
+	return path, nil
+}
+
+// GetInclusionProof returns the proof that the block is part of the
+// skipchain of the genesis block, in a format that third-party software can
+// verify with the inclusion package.
+func (c *Client) GetInclusionProof(roster *onet.Roster, genesis *SkipBlock, id SkipBlockID) (*inclusion.Proof, error) {
+	path, err := c.GetShortestPath(roster, genesis, id)
+	if err != nil {
+		return nil, err
+	}
+	return path.ExportInclusion()
+}
+
+// ExportBundle returns all the blocks of the skipchain as a bundle of the
+// inclusion package, which auditors can verify offline.
+func (c *Client) ExportBundle(roster *onet.Roster, genesis SkipBlockID) (*inclusion.Bundle, error) {
+	blocks, err := c.GetBlocksByIndexRange(roster, genesis, 0, math.MaxInt32)
+	if err != nil {
+		return nil, err
+	}
+	if len(blocks) == 0 || !blocks[0].Hash.Equal(genesis) {
+		return nil, errors.New("didn't get the genesis block")
+	}
+	return Proof(blocks).ExportBundle()
+}
+
 // GetUpdateChain will return the chain of SkipBlocks going from the 'latest' to
 // the most current SkipBlock of the chain. It takes a roster that knows the
 // 'latest' skipblock and the id (=hash) of the latest skipblock.
@@ -285,8 +341,10 @@ func (c *Client) GetUpdateChainLevel(roster *onet.Roster, latest SkipBlockID,
 			return nil, errors.New("first returned block does not match requested hash")
 		}
 
-		// Step through the returned blocks one at a time, verifying
-		// the forward links, and that they link correctly backwards.
+		// The signatures are checked concurrently, then we step through
+		// the returned blocks one at a time, verifying that they link
+		// correctly backwards.
+		sigErrs := verifyForwardSignatures(r2.Update)
 		for j, b := range r2.Update {
 			if j == 0 && len(update) > 0 {
 				last := update[len(update)-1]
@@ -303,7 +361,7 @@ func (c *Client) GetUpdateChainLevel(roster *onet.Roster, latest SkipBlockID,
 			}
 
 			// Check the integrity of the block
-			if err := b.VerifyForwardSignatures(); err != nil {
+			if err := sigErrs[j]; err != nil {
 				return nil, err
 			}
 			// Cannot check back links until we've confirmed the first one
@@ -354,6 +412,9 @@ func (c *Client) GetUpdateChainLevel(roster *onet.Roster, latest SkipBlockID,
 			// Trust the node that it sent correct block, as it's in the roster.
 			return update, nil
 		}
+		if maxBlocks > 0 && len(update) >= maxBlocks {
+			return update, nil
+		}
 
 		// Trust the block sent back and fetch the next block it points to
 		flHeight := len(last.ForwardLink)
@@ -369,6 +430,80 @@ func (c *Client) GetUpdateChainLevel(roster *onet.Roster, latest SkipBlockID,
 	}
 }
 
+// UpdateChainPager fetches the update chain page by page, so that long chains
+// don't need to be held in one reply. Every page is verified and linked to the
+// previous one. If fetching a page fails, Next can be called again to resume
+// from the last verified block.
+type UpdateChainPager struct {
+	client   *Client
+	roster   *onet.Roster
+	latest   SkipBlockID
+	last     *SkipBlock
+	maxLevel int
+	pageSize int
+}
+
+// NewUpdateChainPager returns a pager that starts at the block latest, which
+// must be known by the roster. The blocks are linked with forward-links of at
+// most maxLevel, like in GetUpdateChainLevel, and every page holds at most
+// pageSize blocks.
+func (c *Client) NewUpdateChainPager(roster *onet.Roster, latest SkipBlockID,
+	maxLevel, pageSize int) (*UpdateChainPager, error) {
+	if pageSize <= 0 {
+		return nil, errors.New("the page size must be positive")
+	}
+	return &UpdateChainPager{
+		client:   c,
+		roster:   roster,
+		latest:   latest,
+		maxLevel: maxLevel,
+		pageSize: pageSize,
+	}, nil
+}
+
+// Next returns the next page of blocks. The first page starts with the block
+// given to NewUpdateChainPager, and the following pages start with the block
+// after the last one of the previous page. When there are no new blocks, it
+// returns an empty page.
+func (p *UpdateChainPager) Next() ([]*SkipBlock, error) {
+	if p.last == nil {
+		update, err := p.client.GetUpdateChainLevel(p.roster, p.latest,
+			p.maxLevel, p.pageSize)
+		if err != nil {
+			return nil, err
+		}
+		p.last = update[len(update)-1]
+		p.roster = p.last.Roster
+		return update, nil
+	}
+
+	// Ask for one more block, as the reply starts with the last block of the
+	// previous page. Its hash links the two pages together.
+	update, err := p.client.GetUpdateChainLevel(p.roster, p.last.Hash,
+		p.maxLevel, p.pageSize+1)
+	if err != nil {
+		return nil, err
+	}
+	if !update[0].Hash.Equal(p.last.Hash) {
+		return nil, errors.New("page doesn't start with the last block")
+	}
+	p.last = update[len(update)-1]
+	p.roster = p.last.Roster
+	return update[1:], nil
+}
+
+// Last returns the last block that has been returned, or nil if no page has
+// been fetched yet. It can be stored to resume later with a new pager.
+func (p *UpdateChainPager) Last() *SkipBlock {
+	return p.last
+}
+
+// Done returns true if the last returned block is the latest block known by
+// the roster.
+func (p *UpdateChainPager) Done() bool {
+	return p.last != nil && p.last.GetForwardLen() == 0
+}
+
 // GetAllSkipchains is deprecated and should no longer be used. See GetAllSkipChainIDs.
 func (c *Client) GetAllSkipchains(si *network.ServerIdentity) (reply *GetAllSkipchainsReply,
 	err error) {
@@ -438,6 +573,224 @@ func (c *Client) GetSingleBlockByIndex(roster *onet.Roster, genesis SkipBlockID,
 	return
 }
 
+// GetBlocksByIndexRange returns the consecutive blocks of the skipchain from
+// the index from to the index to, both included, in as few requests as
+// possible. It verifies that the blocks are consecutive and linked by valid
+// forward-links. It stops at the latest block if the skipchain is shorter than
+// the range.
+func (c *Client) GetBlocksByIndexRange(roster *onet.Roster, genesis SkipBlockID, from, to int) ([]*SkipBlock, error) {
+	var blocks []*SkipBlock
+	for next := from; next <= to; {
+		reply := &GetBlocksByIndexRangeReply{}
+		_, err := c.SendProtobufParallel(roster.List, &GetBlocksByIndexRange{
+			Genesis: genesis,
+			From:    next,
+			To:      to,
+		}, reply, c.options)
+		if err != nil {
+			return nil, err
+		}
+		if len(reply.Blocks) == 0 {
+			return nil, errors.New("got an empty reply")
+		}
+		for i, sb := range reply.Blocks {
+			if sb.Index != next+i {
+				return nil, errors.New("got the wrong block in reply")
+			}
+			if !sb.SkipChainID().Equal(genesis) {
+				return nil, errors.New("got a block of a different chain")
+			}
+		}
+		if err := reply.Blocks[0].VerifyForwardSignatures(); err != nil {
+			return nil, err
+		}
+		if len(blocks) > 0 {
+			// The pages must be linked too.
+			if err := verifyLinkedBlocks([]*SkipBlock{blocks[len(blocks)-1], reply.Blocks[0]}); err != nil {
+				return nil, err
+			}
+		}
+		if err := verifyLinkedBlocks(reply.Blocks); err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, reply.Blocks...)
+		last := reply.Blocks[len(reply.Blocks)-1]
+		if len(last.ForwardLink) == 0 {
+			break
+		}
+		next = last.Index + 1
+	}
+	return blocks, nil
+}
+
+// GetBlocksBatch returns the requested blocks, where each block has to be
+// reached by a forward-link of the previous one, like the blocks of a proof
+// during a catch-up. Instead of verifying the signature of each forward-link,
+// it verifies the aggregate signature returned by the conode, which is about
+// twice as fast. The first block has to be trusted by the caller.
+func (c *Client) GetBlocksBatch(roster *onet.Roster, ids []SkipBlockID) ([]*SkipBlock, error) {
+	reply := &GetBlocksBatchReply{}
+	_, err := c.SendProtobufParallel(roster.List, &GetBlocksBatch{IDs: ids}, reply, c.options)
+	if err != nil {
+		return nil, err
+	}
+	if len(reply.Blocks) != len(ids) {
+		return nil, errors.New("got the wrong number of blocks")
+	}
+	for i, sb := range reply.Blocks {
+		if !sb.Hash.Equal(ids[i]) {
+			return nil, errors.New("got the wrong block in reply")
+		}
+	}
+	if err := verifyLinksAggregate(reply.Blocks, reply.AggregateSignature); err != nil {
+		return nil, err
+	}
+	return reply.Blocks, nil
+}
+
+// StreamBlocks streams the blocks of the skipchain from the conode, starting
+// with the block at the index from, and then the new blocks as they are
+// added. The handler is called for each block, in order, and the stream stops
+// when the handler returns an error or the connection fails. Only the
+// integrity of the blocks and that each one follows the previous one are
+// verified. It returns the index of the next expected block, which can be
+// given as from to resume the stream, along with the error that stopped it.
+func (c *Client) StreamBlocks(si *network.ServerIdentity, genesis SkipBlockID, from int,
+	handler func(*SkipBlock) error) (int, error) {
+	conn, err := c.Stream(si, &StreamBlocks{Genesis: genesis, From: from})
+	if err != nil {
+		return from, err
+	}
+	next := from
+	var prev *SkipBlock
+	for {
+		reply := StreamBlocksReply{}
+		if err := conn.ReadMessage(&reply); err != nil {
+			return next, err
+		}
+		sb := reply.Block
+		if sb == nil || sb.Index != next || !sb.SkipChainID().Equal(genesis) {
+			return next, errors.New("got the wrong block in the stream")
+		}
+		if !sb.CalculateHash().Equal(sb.Hash) {
+			return next, errors.New("got a corrupted block in the stream")
+		}
+		if prev != nil && (len(sb.BackLinkIDs) == 0 || !sb.BackLinkIDs[0].Equal(prev.Hash)) {
+			return next, errors.New("block doesn't follow the previous one")
+		}
+		if err := handler(sb); err != nil {
+			return next, err
+		}
+		prev = sb
+		next = sb.Index + 1
+	}
+}
+
+// SetRosterPolicy sets the policy for the roster changes of the skipchain on
+// the conode, or removes it if policy is nil. It is only used if the
+// skipchain has the VerifyRosterChange verification. The conode needs to have
+// a linked client, whose private key is clientPriv.
+func (c *Client) SetRosterPolicy(si *network.ServerIdentity, clientPriv kyber.Scalar,
+	scid SkipBlockID, policy *RosterPolicy) error {
+	sig, err := schnorr.Sign(cothority.Suite, clientPriv, rosterPolicyMessage(scid, policy))
+	if err != nil {
+		return errors.New("couldn't sign message:" + err.Error())
+	}
+	return c.SendProtobuf(si, &SetRosterPolicy{
+		SkipchainID: scid,
+		Policy:      policy,
+		Signature:   sig,
+	}, &EmptyReply{})
+}
+
+// ReportEquivocation sends the evidence of an equivocation to the conode, so
+// that it can be retrieved by other clients.
+func (c *Client) ReportEquivocation(si *network.ServerIdentity, e *Equivocation) error {
+	return c.SendProtobuf(si, &ReportEquivocation{Evidence: e}, &EmptyReply{})
+}
+
+// GetEquivocations returns the evidences of equivocations known by the conode
+// for the skipchain. Evidences that don't verify are refused, but the caller
+// still has to check that the blocks of the evidences are part of the
+// skipchain.
+func (c *Client) GetEquivocations(si *network.ServerIdentity, scid SkipBlockID) ([]*Equivocation, error) {
+	reply := &GetEquivocationsReply{}
+	err := c.SendProtobuf(si, &GetEquivocations{SkipchainID: scid}, reply)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range reply.Equivocations {
+		if err := e.Verify(); err != nil {
+			return nil, fmt.Errorf("invalid evidence: %v", err)
+		}
+		if !e.Block.SkipChainID().Equal(scid) {
+			return nil, errors.New("got an evidence of another skipchain")
+		}
+	}
+	return reply.Equivocations, nil
+}
+
+// GetMilestone asks the conodes of the roster for the latest milestone of
+// the skipchain, and returns it with its block once it is signed by a
+// Byzantine threshold of the roster. The roster has to be trusted by the
+// caller, for example because it is the roster of a recent block. The
+// conodes that are behind return an older milestone, which is only used if
+// enough of them agree on it.
+func (c *Client) GetMilestone(roster *onet.Roster, scid SkipBlockID) (*Milestone, *SkipBlock, error) {
+	threshold := protocol.DefaultThreshold(len(roster.List))
+	votes := make(map[string]int)
+	var lastErr error
+	for _, si := range roster.List {
+		reply := &GetMilestoneReply{}
+		if err := c.SendProtobuf(si, &GetMilestone{SkipChainID: scid}, reply); err != nil {
+			lastErr = err
+			continue
+		}
+		if err := reply.verify(scid, si.ServicePublic(ServiceName)); err != nil {
+			lastErr = fmt.Errorf("invalid milestone from %s: %v", si, err)
+			continue
+		}
+		key := string(reply.Milestone.Hash())
+		votes[key]++
+		if votes[key] >= threshold {
+			return reply.Milestone, reply.Block, nil
+		}
+	}
+	if lastErr != nil {
+		return nil, nil, fmt.Errorf("not enough conodes agree on a milestone: %v", lastErr)
+	}
+	return nil, nil, errors.New("not enough conodes agree on a milestone")
+}
+
+// GetVerifiers returns the IDs of the verifications registered on the conode.
+func (c *Client) GetVerifiers(si *network.ServerIdentity) ([]VerifierID, error) {
+	reply := &GetVerifiersReply{}
+	if err := c.SendProtobuf(si, &GetVerifiers{}, reply); err != nil {
+		return nil, err
+	}
+	return reply.Verifiers, nil
+}
+
+// MissingVerifiers returns the conodes of the roster that don't support all
+// the verifications, for example because they haven't been updated yet. The
+// conodes that cannot be reached are returned too, as their support is
+// unknown.
+func (c *Client) MissingVerifiers(roster *onet.Roster, verifiers []VerifierID) []*network.ServerIdentity {
+	var missing []*network.ServerIdentity
+	for _, si := range roster.List {
+		ids, err := c.GetVerifiers(si)
+		if err != nil {
+			log.Lvl2("Couldn't get the verifications of", si, err)
+			missing = append(missing, si)
+			continue
+		}
+		if !VerifierIDs(ids).containsAll(verifiers) {
+			missing = append(missing, si)
+		}
+	}
+	return missing
+}
+
 // CreateLinkPrivate asks the conode to create a link by sending a public
 // key of the client, signed by the private key of the conode. The reasoning is
 // that an administrator should well be able to copy the private.toml-file from
@@ -554,3 +907,21 @@ func (c *Client) ListFollow(si *network.ServerIdentity, clientPriv kyber.Scalar)
 	}
 	return reply, nil
 }
+
+// PruneSkipchain asks the conode to remove the blocks that are older than the
+// checkpoint. The blocks needed to follow the forward-links from the genesis
+// block to the checkpoint are kept. It returns the number of removed blocks.
+func (c *Client) PruneSkipchain(si *network.ServerIdentity, clientPriv kyber.Scalar,
+	checkpoint SkipBlockID) (int, error) {
+	msg := append([]byte("prune:"), checkpoint...)
+	sig, err := schnorr.Sign(cothority.Suite, clientPriv, msg)
+	if err != nil {
+		return 0, err
+	}
+	reply := &PruneSkipchainReply{}
+	err = c.SendProtobuf(si, &PruneSkipchain{Checkpoint: checkpoint, Signature: sig}, reply)
+	if err != nil {
+		return 0, err
+	}
+	return reply.Pruned, nil
+}
@@ -526,6 +526,81 @@ func TestClient_DelFollow(t *testing.T) {
 	require.Equal(t, 0, len(ls.service.Storage.FollowIDs))
 }
 
+func TestClient_AddFollowReplace(t *testing.T) {
+	ls := linked(3)
+	defer ls.local.CloseAll()
+
+	sb, err := ls.client.CreateGenesis(ls.roster, 1, 1, VerificationNone, nil)
+	require.Nil(t, err)
+	// Following twice the same ID must not add it twice.
+	for i := 0; i < 2; i++ {
+		err = ls.client.AddFollow(ls.server.ServerIdentity, ls.priv, sb.SkipChainID(),
+			FollowID, NewChainNone, "")
+		require.Nil(t, err)
+	}
+	require.Equal(t, 1, len(ls.service.Storage.FollowIDs))
+
+	// Following again a chain replaces its policy.
+	addr := ls.server.ServerIdentity.Address.NetworkAddress()
+	err = ls.client.AddFollow(ls.server.ServerIdentity, ls.priv, sb.SkipChainID(),
+		FollowLookup, NewChainNone, addr)
+	require.Nil(t, err)
+	err = ls.client.AddFollow(ls.server.ServerIdentity, ls.priv, sb.SkipChainID(),
+		FollowLookup, NewChainAnyNode, addr)
+	require.Nil(t, err)
+	list, err := ls.client.ListFollow(ls.server.ServerIdentity, ls.priv)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(*list.Follow))
+	require.Equal(t, NewChainAnyNode, (*list.Follow)[0].NewChain)
+
+	err = ls.client.DelFollow(ls.server.ServerIdentity, ls.priv, sb.SkipChainID())
+	require.Nil(t, err)
+	list, err = ls.client.ListFollow(ls.server.ServerIdentity, ls.priv)
+	require.Nil(t, err)
+	require.Nil(t, list.Follow)
+	require.Nil(t, list.FollowIDs)
+}
+
+func TestClient_SetRosterPolicy(t *testing.T) {
+	ls := linked(4)
+	defer ls.local.CloseAll()
+
+	gen, err := ls.client.CreateGenesis(ls.roster, 1, 1, VerificationNone, nil)
+	require.NoError(t, err)
+	withRoster := func(ro *onet.Roster) *SkipBlock {
+		sb := gen.Copy()
+		sb.Index = 1
+		sb.GenesisID = gen.Hash
+		sb.BackLinkIDs = []SkipBlockID{gen.Hash}
+		sb.Roster = ro
+		return sb
+	}
+	removeOne := withRoster(onet.NewRoster(ls.roster.List[:3]))
+	removeTwo := withRoster(onet.NewRoster(ls.roster.List[:2]))
+
+	// The default policy refuses to lose the quorum of the previous roster.
+	require.True(t, ls.service.verifyFuncRosterChange(nil, removeOne))
+	require.False(t, ls.service.verifyFuncRosterChange(nil, removeTwo))
+
+	policy := &RosterPolicy{MaxChurn: 2}
+	err = ls.client.SetRosterPolicy(ls.si, ls.servPriv, gen.Hash, policy)
+	require.Error(t, err)
+	err = ls.client.SetRosterPolicy(ls.si, ls.priv, gen.Hash, policy)
+	require.NoError(t, err)
+	require.True(t, ls.service.verifyFuncRosterChange(nil, removeTwo))
+	policy.MaxChurn = 1
+	err = ls.client.SetRosterPolicy(ls.si, ls.priv, gen.Hash, policy)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(ls.service.Storage.RosterPolicies))
+	require.True(t, ls.service.verifyFuncRosterChange(nil, removeOne))
+	require.False(t, ls.service.verifyFuncRosterChange(nil, removeTwo))
+
+	err = ls.client.SetRosterPolicy(ls.si, ls.priv, gen.Hash, nil)
+	require.NoError(t, err)
+	require.Equal(t, 0, len(ls.service.Storage.RosterPolicies))
+	require.False(t, ls.service.verifyFuncRosterChange(nil, removeTwo))
+}
+
 func TestClient_ListFollow(t *testing.T) {
 	ls := linked(3)
 	defer ls.local.CloseAll()
@@ -651,6 +726,193 @@ func TestClient_ParallelWrite(t *testing.T) {
 	}
 }
 
+func TestClient_UpdateChainPager(t *testing.T) {
+	l := onet.NewTCPTest(cothority.Suite)
+	svrs, ro, _ := l.GenTree(3, true)
+	defer waitPropagationFinished(t, l)
+	defer l.CloseAll()
+
+	// Make the conodes return small replies.
+	defer func(max int) { maxUpdateChainBlocks = max }(maxUpdateChainBlocks)
+	maxUpdateChainBlocks = 3
+
+	cl := newTestClient(l)
+	gen, err := cl.CreateGenesis(ro, 1, 1, VerificationNone, nil)
+	require.NoError(t, err)
+	ids := []SkipBlockID{gen.Hash}
+	for i := 1; i < 10; i++ {
+		reply, err := cl.StoreSkipBlock(gen, nil, []byte{byte(i)})
+		require.NoError(t, err)
+		ids = append(ids, reply.Latest.Hash)
+	}
+
+	s := l.Services[svrs[0].ServerIdentity.ID][sid].(*Service)
+	reply, err := s.GetUpdateChain(&GetUpdateChain{LatestID: gen.Hash})
+	require.NoError(t, err)
+	require.Equal(t, 3, len(reply.Update))
+	update, err := cl.GetUpdateChainLevel(ro, gen.Hash, -1, 5)
+	require.NoError(t, err)
+	require.Equal(t, 5, len(update))
+
+	pager, err := cl.NewUpdateChainPager(ro, gen.Hash, -1, 4)
+	require.NoError(t, err)
+	var blocks []*SkipBlock
+	for !pager.Done() {
+		page, err := pager.Next()
+		require.NoError(t, err)
+		require.True(t, len(page) <= 4)
+		blocks = append(blocks, page...)
+	}
+	require.Equal(t, len(ids), len(blocks))
+	for i, sb := range blocks {
+		require.True(t, sb.Hash.Equal(ids[i]))
+	}
+	page, err := pager.Next()
+	require.NoError(t, err)
+	require.Equal(t, 0, len(page))
+
+	// Resume from a stored block.
+	pager, err = cl.NewUpdateChainPager(ro, ids[5], -1, 10)
+	require.NoError(t, err)
+	page, err = pager.Next()
+	require.NoError(t, err)
+	require.Equal(t, 5, len(page))
+	require.True(t, pager.Last().Hash.Equal(ids[9]))
+
+	_, err = cl.NewUpdateChainPager(ro, gen.Hash, -1, 0)
+	require.Error(t, err)
+}
+
+func TestClient_GetBlocksByIndexRange(t *testing.T) {
+	l := onet.NewTCPTest(cothority.Suite)
+	svrs, ro, _ := l.GenTree(3, true)
+	defer waitPropagationFinished(t, l)
+	defer l.CloseAll()
+
+	// Make the conodes return small replies.
+	defer func(max int) { maxUpdateChainBlocks = max }(maxUpdateChainBlocks)
+	maxUpdateChainBlocks = 2
+
+	cl := newTestClient(l)
+	gen, err := cl.CreateGenesis(ro, 2, 3, VerificationNone, nil)
+	require.NoError(t, err)
+	ids := []SkipBlockID{gen.Hash}
+	for i := 1; i < 6; i++ {
+		reply, err := cl.StoreSkipBlock(gen, nil, []byte{byte(i)})
+		require.NoError(t, err)
+		ids = append(ids, reply.Latest.Hash)
+	}
+
+	s := l.Services[svrs[0].ServerIdentity.ID][sid].(*Service)
+	reply, err := s.GetBlocksByIndexRange(&GetBlocksByIndexRange{Genesis: gen.Hash, From: 1, To: 4})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(reply.Blocks))
+	_, err = s.GetBlocksByIndexRange(&GetBlocksByIndexRange{Genesis: gen.Hash, From: 3, To: 2})
+	require.Error(t, err)
+	_, err = s.GetBlocksByIndexRange(&GetBlocksByIndexRange{Genesis: gen.Hash, From: 6, To: 7})
+	require.Error(t, err)
+
+	blocks, err := cl.GetBlocksByIndexRange(ro, gen.Hash, 1, 4)
+	require.NoError(t, err)
+	require.Equal(t, 4, len(blocks))
+	for i, sb := range blocks {
+		require.True(t, sb.Hash.Equal(ids[i+1]))
+	}
+
+	// The range is cut at the latest block.
+	blocks, err = cl.GetBlocksByIndexRange(ro, gen.Hash, 3, 10)
+	require.NoError(t, err)
+	require.Equal(t, 3, len(blocks))
+	require.True(t, blocks[2].Hash.Equal(ids[5]))
+}
+
+func TestClient_StreamBlocks(t *testing.T) {
+	l := onet.NewTCPTest(cothority.Suite)
+	_, ro, _ := l.GenTree(3, true)
+	defer waitPropagationFinished(t, l)
+	defer l.CloseAll()
+
+	cl := newTestClient(l)
+	gen, err := cl.CreateGenesis(ro, 2, 3, VerificationNone, nil)
+	require.NoError(t, err)
+	ids := []SkipBlockID{gen.Hash}
+	storeBlock := func() {
+		reply, err := cl.StoreSkipBlock(gen, nil, []byte{byte(len(ids))})
+		require.NoError(t, err)
+		ids = append(ids, reply.Latest.Hash)
+	}
+	storeBlock()
+	storeBlock()
+
+	// The handler blocks until the test reads the block, like a slow client.
+	blocks := make(chan *SkipBlock)
+	errStop := fmt.Errorf("stop")
+	type result struct {
+		next int
+		err  error
+	}
+	stream := func(from int, stop int) chan result {
+		done := make(chan result, 1)
+		go func() {
+			next, err := cl.StreamBlocks(ro.List[0], gen.Hash, from, func(sb *SkipBlock) error {
+				if sb.Index == stop {
+					return errStop
+				}
+				blocks <- sb
+				return nil
+			})
+			done <- result{next, err}
+		}()
+		return done
+	}
+
+	done := stream(1, 4)
+	for i := 1; i < 3; i++ {
+		require.True(t, (<-blocks).Hash.Equal(ids[i]))
+	}
+	// New blocks are streamed once they are added.
+	storeBlock()
+	require.True(t, (<-blocks).Hash.Equal(ids[3]))
+	storeBlock()
+	res := <-done
+	require.Equal(t, errStop, res.err)
+	require.Equal(t, 4, res.next)
+
+	// Resume the stream from the returned index.
+	done = stream(res.next, 5)
+	require.True(t, (<-blocks).Hash.Equal(ids[4]))
+	storeBlock()
+	res = <-done
+	require.Equal(t, errStop, res.err)
+	require.Equal(t, 5, res.next)
+}
+
+func TestClient_GetShortestPath(t *testing.T) {
+	l := onet.NewTCPTest(cothority.Suite)
+	_, ro, _ := l.GenTree(3, true)
+	defer waitPropagationFinished(t, l)
+	defer l.CloseAll()
+
+	cl := newTestClient(l)
+	gen, err := cl.CreateGenesis(ro, 2, 3, VerificationNone, nil)
+	require.NoError(t, err)
+	latest := gen
+	for i := 1; i < 9; i++ {
+		reply, err := cl.StoreSkipBlock(gen, nil, []byte{byte(i)})
+		require.NoError(t, err)
+		latest = reply.Latest
+	}
+
+	path, err := cl.GetShortestPath(ro, gen, latest.Hash)
+	require.NoError(t, err)
+	require.Equal(t, 3, len(path))
+	require.Equal(t, 4, path[1].Index)
+	require.True(t, path[2].Hash.Equal(latest.Hash))
+
+	_, err = cl.GetShortestPath(ro, latest, gen.Hash)
+	require.Error(t, err)
+}
+
 const testServiceName = "TestSkipChain"
 
 type corruptedService struct {
@@ -0,0 +1,133 @@
+package skipchain
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"go.dedis.ch/cothority/v3/blscosi/protocol"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/sign"
+	"go.dedis.ch/kyber/v3/sign/bdn"
+	"go.dedis.ch/kyber/v3/sign/bls"
+)
+
+// GetBlocksBatch returns the requested blocks, where each block has to be
+// reached by a forward-link of the previous one, with the aggregate of the
+// signatures of these forward-links.
+func (s *Service) GetBlocksBatch(req *GetBlocksBatch) (*GetBlocksBatchReply, error) {
+	if len(req.IDs) == 0 {
+		return nil, errors.New("no block requested")
+	}
+	if len(req.IDs) > maxUpdateChainBlocks {
+		return nil, fmt.Errorf("cannot request more than %d blocks", maxUpdateChainBlocks)
+	}
+	blocks := make([]*SkipBlock, len(req.IDs))
+	for i, id := range req.IDs {
+		blocks[i] = s.db.GetByID(id)
+		if blocks[i] == nil {
+			return nil, fmt.Errorf("unknown block %x", id)
+		}
+	}
+	agg, err := aggregateLinkSignatures(blocks)
+	if err != nil {
+		return nil, err
+	}
+	return &GetBlocksBatchReply{Blocks: blocks, AggregateSignature: agg}, nil
+}
+
+// linkTo returns the forward-link of the block pointing to the target, or nil.
+func linkTo(sb *SkipBlock, target SkipBlockID) *ForwardLink {
+	for _, fl := range sb.ForwardLink {
+		if !fl.IsEmpty() && fl.To.Equal(target) {
+			return fl
+		}
+	}
+	return nil
+}
+
+// aggregateLinkSignatures returns the sum of the signatures of the
+// forward-links between the consecutive blocks.
+func aggregateLinkSignatures(sbs []*SkipBlock) ([]byte, error) {
+	agg := suite.G1().Point().Null()
+	for i := 1; i < len(sbs); i++ {
+		fl := linkTo(sbs[i-1], sbs[i].Hash)
+		if fl == nil {
+			return nil, fmt.Errorf("missing forward-link to block %d", sbs[i].Index)
+		}
+		lenCom := suite.G1().PointLen()
+		if len(fl.Signature.Sig) < lenCom {
+			return nil, errors.New("invalid signature length")
+		}
+		sig, err := protocol.BlsSignature(fl.Signature.Sig[:lenCom]).Point(suite)
+		if err != nil {
+			return nil, err
+		}
+		agg.Add(agg, sig)
+	}
+	return agg.MarshalBinary()
+}
+
+// verifyLinksAggregate checks, like verifyLinkedBlocks, that each block is
+// reached by a valid forward-link of the previous block. Instead of verifying
+// every signature, it verifies their aggregate in one batch, which needs
+// about half of the pairings. The participation of each forward-link is still
+// checked against the threshold of its roster.
+func verifyLinksAggregate(sbs []*SkipBlock, agg []byte) error {
+	if len(sbs) == 0 {
+		return errors.New("empty list of blocks")
+	}
+	var publics []kyber.Point
+	var msgs [][]byte
+	for i, sb := range sbs {
+		if !sb.CalculateHash().Equal(sb.Hash) {
+			return errors.New("wrong hash")
+		}
+		if i == 0 {
+			continue
+		}
+		prev := sbs[i-1]
+		fl := linkTo(prev, sb.Hash)
+		if fl == nil || !fl.From.Equal(prev.Hash) {
+			return fmt.Errorf("missing forward-link to block %d", sb.Index)
+		}
+		if !bytes.Equal(fl.Signature.Msg, fl.Hash()) {
+			return errors.New("wrong hash of forward link")
+		}
+		pub, err := aggregatePublic(prev, fl)
+		if err != nil {
+			return err
+		}
+		publics = append(publics, pub)
+		msgs = append(msgs, fl.Signature.Msg)
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+	if err := bls.BatchVerify(suite, publics, msgs, agg); err != nil {
+		return fmt.Errorf("invalid aggregate signature: %v", err)
+	}
+	return nil
+}
+
+// aggregatePublic returns the aggregate public key of the nodes that signed
+// the forward-link, after checking that they are enough.
+func aggregatePublic(sb *SkipBlock, fl *ForwardLink) (kyber.Point, error) {
+	publics := sb.Roster.ServicePublics(ServiceName)
+	mask, err := protocol.BlsSignature(fl.Signature.Sig).GetMask(suite, publics)
+	if err != nil {
+		return nil, err
+	}
+	policy := sign.NewThresholdPolicy(protocol.DefaultThreshold(len(publics)))
+	if !policy.Check(mask) {
+		return nil, fmt.Errorf("not enough signers for the forward-link of block %d", sb.Index)
+	}
+	switch sb.SignatureScheme {
+	case BlsSignatureSchemeIndex:
+		return bls.AggregatePublicKeys(suite, mask.Participants()...), nil
+	case BdnSignatureSchemeIndex:
+		return bdn.AggregatePublicKeys(suite, mask)
+	default:
+		return nil, errors.New("unknown signature scheme")
+	}
+}
@@ -0,0 +1,67 @@
+package skipchain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/onet/v3"
+)
+
+func TestVerifyLinksAggregate(t *testing.T) {
+	local := onet.NewLocalTest(suite)
+	_, ro, _ := local.GenTree(4, false)
+	defer local.CloseAll()
+
+	blocks := createSignedChain(t, ro, 9, 2, 3)
+	for _, path := range [][]*SkipBlock{
+		blocks[:1],
+		blocks[:4],
+		{blocks[0], blocks[4], blocks[8]},
+		{blocks[3], blocks[4], blocks[6], blocks[7]},
+	} {
+		agg, err := aggregateLinkSignatures(path)
+		require.NoError(t, err)
+		require.NoError(t, verifyLinksAggregate(path, agg))
+	}
+
+	path := []*SkipBlock{blocks[0], blocks[4], blocks[8]}
+	_, err := aggregateLinkSignatures([]*SkipBlock{blocks[0], blocks[3]})
+	require.Error(t, err)
+	require.Error(t, verifyLinksAggregate([]*SkipBlock{blocks[0], blocks[3]}, nil))
+
+	// The aggregate of other links doesn't verify.
+	other, err := aggregateLinkSignatures(blocks[:3])
+	require.NoError(t, err)
+	require.Error(t, verifyLinksAggregate(path, other))
+
+	// A block that doesn't match its hash is refused.
+	agg, err := aggregateLinkSignatures(path)
+	require.NoError(t, err)
+	tampered := path[1].Copy()
+	tampered.Data = []byte("tampered")
+	require.Error(t, verifyLinksAggregate([]*SkipBlock{path[0], tampered, path[2]}, agg))
+}
+
+func TestService_GetBlocksBatch(t *testing.T) {
+	local := onet.NewLocalTest(suite)
+	servers, ro, _ := local.GenTree(2, true)
+	defer local.CloseAll()
+	s := local.GetServices(servers, skipchainSID)[0].(*Service)
+
+	blocks := createSignedChain(t, ro, 9, 2, 3)
+	_, err := s.db.StoreBlocks(blocks)
+	require.NoError(t, err)
+
+	ids := []SkipBlockID{blocks[0].Hash, blocks[4].Hash, blocks[5].Hash}
+	reply, err := s.GetBlocksBatch(&GetBlocksBatch{IDs: ids})
+	require.NoError(t, err)
+	require.Equal(t, 3, len(reply.Blocks))
+	require.NoError(t, verifyLinksAggregate(reply.Blocks, reply.AggregateSignature))
+
+	_, err = s.GetBlocksBatch(&GetBlocksBatch{})
+	require.Error(t, err)
+	_, err = s.GetBlocksBatch(&GetBlocksBatch{IDs: []SkipBlockID{blocks[0].Hash, blocks[2].Hash, blocks[1].Hash}})
+	require.Error(t, err)
+	_, err = s.GetBlocksBatch(&GetBlocksBatch{IDs: []SkipBlockID{{1, 2, 3}}})
+	require.Error(t, err)
+}
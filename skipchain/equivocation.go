@@ -0,0 +1,97 @@
+package skipchain
+
+import (
+	"errors"
+	"fmt"
+
+	"go.dedis.ch/onet/v3/log"
+)
+
+// Equivocation is the evidence that the roster of a block signed two
+// different forward-links at the same height, which forks the skipchain. As
+// both links are signed, anybody knowing the block can verify it.
+type Equivocation struct {
+	// Block is the block both forward-links start from. Its roster signed
+	// both of them.
+	Block *SkipBlock
+	// Height is the level of the forward-links.
+	Height int
+	// First and Second are the conflicting forward-links.
+	First  *ForwardLink
+	Second *ForwardLink
+}
+
+// Verify checks that the forward-links are different and both correctly
+// signed by the roster of the block. The caller has to make sure that the
+// block is part of the skipchain, as the roster is taken from it.
+func (e *Equivocation) Verify() error {
+	if e.Block == nil || e.First == nil || e.Second == nil {
+		return errors.New("incomplete evidence")
+	}
+	if !e.Block.CalculateHash().Equal(e.Block.Hash) {
+		return errors.New("wrong hash of the block")
+	}
+	if e.Height < 0 || e.Height >= e.Block.Height {
+		return fmt.Errorf("invalid height %d", e.Height)
+	}
+	if e.First.To.Equal(e.Second.To) {
+		return errors.New("forward-links point to the same block")
+	}
+	publics := e.Block.Roster.ServicePublics(ServiceName)
+	for _, fl := range []*ForwardLink{e.First, e.Second} {
+		if !fl.From.Equal(e.Block.Hash) {
+			return errors.New("forward-link doesn't start from the block")
+		}
+		if err := fl.VerifyWithScheme(suite, publics, e.Block.SignatureScheme); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReportEquivocation stores the evidence of an equivocation found by a client
+// or another conode. The block of the evidence has to be known by the conode.
+func (s *Service) ReportEquivocation(req *ReportEquivocation) (*EmptyReply, error) {
+	if req.Evidence == nil {
+		return nil, errors.New("missing evidence")
+	}
+	if err := req.Evidence.Verify(); err != nil {
+		return nil, fmt.Errorf("invalid evidence: %v", err)
+	}
+	if s.db.GetByID(req.Evidence.Block.Hash) == nil {
+		return nil, errors.New("unknown block in the evidence")
+	}
+	s.storeEquivocation(req.Evidence)
+	return &EmptyReply{}, nil
+}
+
+// GetEquivocations returns the evidences of equivocations known for a
+// skipchain.
+func (s *Service) GetEquivocations(req *GetEquivocations) (*GetEquivocationsReply, error) {
+	reply := &GetEquivocationsReply{}
+	s.storageMutex.Lock()
+	defer s.storageMutex.Unlock()
+	for _, e := range s.Storage.Equivocations {
+		if e.Block.SkipChainID().Equal(req.SkipchainID) {
+			reply.Equivocations = append(reply.Equivocations, e)
+		}
+	}
+	return reply, nil
+}
+
+// storeEquivocation keeps the evidence, unless one is already known for the
+// same block and height, and raises an alert in the logs.
+func (s *Service) storeEquivocation(e *Equivocation) {
+	s.storageMutex.Lock()
+	for _, known := range s.Storage.Equivocations {
+		if known.Block.Hash.Equal(e.Block.Hash) && known.Height == e.Height {
+			s.storageMutex.Unlock()
+			return
+		}
+	}
+	s.Storage.Equivocations = append(s.Storage.Equivocations, e)
+	s.storageMutex.Unlock()
+	log.Errorf("%s: equivocation in skipchain %x: block %d signed two forward-links at height %d",
+		s.ServerIdentity(), e.Block.SkipChainID(), e.Block.Index, e.Height)
+	s.save()
+}
@@ -0,0 +1,122 @@
+package skipchain
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/onet/v3"
+)
+
+// forkBlock returns a block at the same index as the given one, with a valid
+// forward-link to it from the previous block.
+func forkBlock(t *testing.T, ro *onet.Roster, prev, sb *SkipBlock) (*SkipBlock, *ForwardLink) {
+	fork := sb.Copy()
+	fork.Data = []byte("fork")
+	fork.updateHash()
+	fl := NewForwardLink(prev, fork)
+	require.NoError(t, fl.sign(ro))
+	return fork, fl
+}
+
+func TestEquivocation_Verify(t *testing.T) {
+	local := onet.NewLocalTest(suite)
+	_, ro, _ := local.GenTree(2, false)
+	defer local.CloseAll()
+
+	blocks := createSignedChain(t, ro, 3, 2, 2)
+	_, fl := forkBlock(t, ro, blocks[0], blocks[1])
+	e := &Equivocation{
+		Block:  blocks[0],
+		Height: 0,
+		First:  blocks[0].ForwardLink[0],
+		Second: fl,
+	}
+	require.NoError(t, e.Verify())
+
+	e.Second = blocks[0].ForwardLink[0]
+	require.Error(t, e.Verify())
+	e.Second = blocks[1].ForwardLink[0]
+	require.Error(t, e.Verify())
+	e.Second = fl
+	e.Height = 2
+	require.Error(t, e.Verify())
+}
+
+func TestSkipBlockDB_Equivocation(t *testing.T) {
+	local := onet.NewLocalTest(suite)
+	_, ro, _ := local.GenTree(2, false)
+	defer local.CloseAll()
+
+	db, file := setupSkipBlockDB(t)
+	defer os.Remove(file)
+	var found []*Equivocation
+	db.equivocation = func(e *Equivocation) { found = append(found, e) }
+
+	blocks := createSignedChain(t, ro, 3, 2, 2)
+	_, err := db.StoreBlocks(blocks)
+	require.NoError(t, err)
+	require.Equal(t, 0, len(found))
+
+	// Receiving the same links is fine.
+	_, err = db.StoreBlocks(blocks[:1])
+	require.NoError(t, err)
+	require.Equal(t, 0, len(found))
+
+	// A badly signed link is ignored.
+	_, fl := forkBlock(t, ro, blocks[0], blocks[1])
+	fl.Signature.Sig = blocks[0].ForwardLink[1].Signature.Sig
+	forked := blocks[0].Copy()
+	forked.ForwardLink[0] = fl
+	_, err = db.StoreBlocks([]*SkipBlock{forked})
+	require.NoError(t, err)
+	require.Equal(t, 0, len(found))
+
+	_, fl = forkBlock(t, ro, blocks[0], blocks[1])
+	forked.ForwardLink[0] = fl
+	_, err = db.StoreBlocks([]*SkipBlock{forked})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(found))
+	require.NoError(t, found[0].Verify())
+	require.Equal(t, 0, found[0].Height)
+	require.True(t, found[0].First.To.Equal(blocks[1].Hash))
+	// The stored link is kept.
+	require.True(t, db.GetByID(blocks[0].Hash).ForwardLink[0].To.Equal(blocks[1].Hash))
+}
+
+func TestService_Equivocations(t *testing.T) {
+	local := onet.NewLocalTest(suite)
+	servers, ro, _ := local.GenTree(2, true)
+	defer local.CloseAll()
+	s := local.GetServices(servers, skipchainSID)[0].(*Service)
+
+	blocks := createSignedChain(t, ro, 3, 2, 2)
+	_, err := s.db.StoreBlocks(blocks)
+	require.NoError(t, err)
+	other := createSignedChain(t, ro, 3, 2, 3)
+	_, fl := forkBlock(t, ro, other[0], other[1])
+	e := &Equivocation{Block: other[0], First: other[0].ForwardLink[0], Second: fl}
+	_, err = s.ReportEquivocation(&ReportEquivocation{Evidence: e})
+	require.Error(t, err)
+
+	// Found while storing the blocks.
+	_, fl = forkBlock(t, ro, blocks[0], blocks[1])
+	forked := blocks[0].Copy()
+	forked.ForwardLink[0] = fl
+	_, err = s.db.StoreBlocks([]*SkipBlock{forked})
+	require.NoError(t, err)
+	reply, err := s.GetEquivocations(&GetEquivocations{SkipchainID: blocks[0].Hash})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(reply.Equivocations))
+
+	// Reported twice, but only stored once.
+	e = &Equivocation{Block: blocks[0], First: blocks[0].ForwardLink[0], Second: fl}
+	_, err = s.ReportEquivocation(&ReportEquivocation{Evidence: e})
+	require.NoError(t, err)
+	reply, err = s.GetEquivocations(&GetEquivocations{SkipchainID: blocks[0].Hash})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(reply.Equivocations))
+	reply, err = s.GetEquivocations(&GetEquivocations{SkipchainID: other[0].Hash})
+	require.NoError(t, err)
+	require.Equal(t, 0, len(reply.Equivocations))
+}
@@ -0,0 +1,90 @@
+package skipchain
+
+import (
+	"errors"
+	"fmt"
+
+	"go.dedis.ch/cothority/v3/skipchain/inclusion"
+)
+
+// ExportInclusion returns the proof in the format of the inclusion package,
+// which can be verified without onet. The proof has to start with the
+// genesis block, and each block has to be reached by a forward-link of the
+// previous one, like the proofs returned by GetProofForID or GetShortestPath.
+func (sbs Proof) ExportInclusion() (*inclusion.Proof, error) {
+	if len(sbs) == 0 {
+		return nil, errors.New("empty proof")
+	}
+	p := &inclusion.Proof{Blocks: make([]inclusion.Block, len(sbs))}
+	for i, sb := range sbs {
+		b := inclusion.Block{
+			Index:           sb.Index,
+			Height:          sb.Height,
+			MaximumHeight:   sb.MaximumHeight,
+			BaseHeight:      sb.BaseHeight,
+			BackLinkIDs:     make([][]byte, len(sb.BackLinkIDs)),
+			VerifierIDs:     make([][]byte, len(sb.VerifierIDs)),
+			GenesisID:       sb.GenesisID,
+			Data:            sb.Data,
+			SignatureScheme: sb.SignatureScheme,
+		}
+		for j, bl := range sb.BackLinkIDs {
+			b.BackLinkIDs[j] = bl
+		}
+		for j := range sb.VerifierIDs {
+			b.VerifierIDs[j] = sb.VerifierIDs[j][:]
+		}
+		if sb.Roster != nil {
+			for _, pub := range sb.Roster.Publics() {
+				buf, err := pub.MarshalBinary()
+				if err != nil {
+					return nil, err
+				}
+				b.Publics = append(b.Publics, buf)
+			}
+			for _, pub := range sb.Roster.ServicePublics(ServiceName) {
+				buf, err := pub.MarshalBinary()
+				if err != nil {
+					return nil, err
+				}
+				b.ServicePublics = append(b.ServicePublics, buf)
+			}
+		}
+		if i < len(sbs)-1 {
+			fl := linkTo(sb, sbs[i+1].Hash)
+			if fl == nil {
+				return nil, fmt.Errorf("missing forward-link to block %d", sbs[i+1].Index)
+			}
+			b.Link = &inclusion.Link{
+				From:      fl.From,
+				To:        fl.To,
+				Signature: fl.Signature.Sig,
+			}
+			if fl.NewRoster != nil {
+				b.Link.NewRosterID = fl.NewRoster.ID[:]
+			}
+		}
+		p.Blocks[i] = b
+	}
+	return p, nil
+}
+
+// ExportBundle returns the consecutive blocks of the skipchain from the
+// genesis block as a bundle of the inclusion package, which auditors can
+// verify offline.
+func (sbs Proof) ExportBundle() (*inclusion.Bundle, error) {
+	for i, sb := range sbs {
+		if sb.Index != i {
+			return nil, fmt.Errorf("missing block %d", i)
+		}
+	}
+	p, err := sbs.ExportInclusion()
+	if err != nil {
+		return nil, err
+	}
+	return &inclusion.Bundle{
+		Version:   inclusion.BundleVersion,
+		GenesisID: sbs[0].Hash,
+		Blocks:    p.Blocks,
+	}, nil
+}
@@ -0,0 +1,98 @@
+package skipchain
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/skipchain/inclusion"
+	"go.dedis.ch/onet/v3"
+)
+
+func TestProof_ExportInclusion(t *testing.T) {
+	local := onet.NewLocalTest(suite)
+	_, ro, _ := local.GenTree(4, false)
+	defer local.CloseAll()
+
+	blocks := createSignedChain(t, ro, 9, 2, 3)
+	_, err := Proof{blocks[0], blocks[3]}.ExportInclusion()
+	require.Error(t, err)
+
+	p, err := Proof{blocks[0], blocks[4], blocks[6], blocks[7]}.ExportInclusion()
+	require.NoError(t, err)
+	for i, sb := range []*SkipBlock{blocks[0], blocks[4], blocks[6], blocks[7]} {
+		require.Equal(t, []byte(sb.Hash), p.Blocks[i].Hash())
+	}
+	id, err := p.Verify(blocks[0].Hash)
+	require.NoError(t, err)
+	require.Equal(t, []byte(blocks[7].Hash), id)
+	require.NoError(t, p.VerifyBlock(blocks[0].Hash, blocks[7].Hash))
+	require.Error(t, p.VerifyBlock(blocks[0].Hash, blocks[6].Hash))
+	_, err = p.Verify(blocks[1].Hash)
+	require.Error(t, err)
+
+	// Third-party software gets the proof in JSON.
+	buf, err := json.Marshal(p)
+	require.NoError(t, err)
+	decode := func() *inclusion.Proof {
+		decoded := &inclusion.Proof{}
+		require.NoError(t, json.Unmarshal(buf, decoded))
+		return decoded
+	}
+	require.NoError(t, decode().VerifyBlock(blocks[0].Hash, blocks[7].Hash))
+
+	decoded := decode()
+	decoded.Blocks[2].Data = []byte("tampered")
+	require.Error(t, decoded.VerifyBlock(blocks[0].Hash, blocks[7].Hash))
+	decoded = decode()
+	decoded.Blocks[1].Link.Signature = decoded.Blocks[0].Link.Signature
+	require.Error(t, decoded.VerifyBlock(blocks[0].Hash, blocks[7].Hash))
+	decoded = decode()
+	decoded.Blocks[1].Link = nil
+	require.Error(t, decoded.VerifyBlock(blocks[0].Hash, blocks[7].Hash))
+}
+
+func TestProof_ExportBundle(t *testing.T) {
+	local := onet.NewLocalTest(suite)
+	_, ro, _ := local.GenTree(4, false)
+	defer local.CloseAll()
+
+	blocks := createSignedChain(t, ro, 6, 2, 3)
+	_, err := Proof{blocks[0], blocks[2]}.ExportBundle()
+	require.Error(t, err)
+	_, err = Proof(blocks[1:]).ExportBundle()
+	require.Error(t, err)
+
+	b, err := Proof(blocks).ExportBundle()
+	require.NoError(t, err)
+	id, err := b.Verify()
+	require.NoError(t, err)
+	require.Equal(t, []byte(blocks[5].Hash), id)
+
+	// Auditors read the bundle from a file.
+	buf := &bytes.Buffer{}
+	require.NoError(t, b.Write(buf))
+	read := func() *inclusion.Bundle {
+		decoded, err := inclusion.ReadBundle(bytes.NewReader(buf.Bytes()))
+		require.NoError(t, err)
+		return decoded
+	}
+	_, err = read().Verify()
+	require.NoError(t, err)
+
+	decoded := read()
+	decoded.Blocks = append(decoded.Blocks[:2], decoded.Blocks[3:]...)
+	_, err = decoded.Verify()
+	require.Error(t, err)
+	decoded = read()
+	decoded.Blocks[4].Data = []byte("tampered")
+	_, err = decoded.Verify()
+	require.Error(t, err)
+	decoded = read()
+	decoded.Version++
+	_, err = decoded.Verify()
+	require.Error(t, err)
+	_, err = inclusion.ReadBundle(bytes.NewReader([]byte("{}")))
+	require.Error(t, err)
+}
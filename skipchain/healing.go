@@ -0,0 +1,116 @@
+package skipchain
+
+import (
+	"errors"
+	"sync"
+
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/log"
+)
+
+// healing holds the blocks being fetched again from the roster, with a
+// channel closed when it is done.
+type healing struct {
+	sync.Mutex
+	// the key type is string because []byte is not allowed
+	blocks map[string]chan struct{}
+}
+
+// startRepair checks the integrity of the database and fetches again the
+// damaged blocks in the background, until the service is closed.
+func (s *Service) startRepair() error {
+	corrupted := make(chan corruptedBlock, 100)
+	s.db.corrupted = corrupted
+	closing := s.closing
+	for _, run := range []func(){
+		func() { s.db.checkIntegrity(closing) },
+		func() { s.repairBlocks(corrupted, closing) },
+	} {
+		if err := s.incrementWorking(); err != nil {
+			return err
+		}
+		go func(run func()) {
+			defer s.decrementWorking()
+			run()
+		}(run)
+	}
+	return nil
+}
+
+// repairBlocks heals the blocks reported by the database until closing is
+// closed.
+func (s *Service) repairBlocks(corrupted <-chan corruptedBlock, closing <-chan bool) {
+	for {
+		select {
+		case cb := <-corrupted:
+			s.healCorrupted(cb.id, cb.sb)
+		case <-closing:
+			return
+		}
+	}
+}
+
+// healCorrupted is called with a damaged block. The roster of the decoded
+// block, if any, is used to fetch a fresh copy. Without it, the block is
+// fetched again by the request that needs it, using the roster of the
+// previous block.
+func (s *Service) healCorrupted(id SkipBlockID, sb *SkipBlock) {
+	if sb == nil || sb.Roster == nil {
+		log.Warnf("%v: no roster to fetch block %x again", s.ServerIdentity(), id)
+		return
+	}
+	if _, err := s.healBlock(sb.Roster, id); err != nil {
+		log.Errorf("%v: failed to fetch block %x again: %v", s.ServerIdentity(), id, err)
+	}
+}
+
+// healBlock fetches the block id from the other members of the roster,
+// verifies that it matches its ID and stores it in place of the missing or
+// damaged copy.
+func (s *Service) healBlock(roster *onet.Roster, id SkipBlockID) (*SkipBlock, error) {
+	if len(roster.List) < 2 {
+		return nil, errors.New("no other member in the roster")
+	}
+	key := string(id)
+	s.healing.Lock()
+	if s.healing.blocks == nil {
+		s.healing.blocks = make(map[string]chan struct{})
+	}
+	if done, ok := s.healing.blocks[key]; ok {
+		// Another request is already fetching it.
+		s.healing.Unlock()
+		<-done
+		if sb := s.db.GetByID(id); sb != nil {
+			return sb, nil
+		}
+		return nil, errors.New("failed to fetch the block again")
+	}
+	done := make(chan struct{})
+	s.healing.blocks[key] = done
+	s.healing.Unlock()
+	defer func() {
+		s.healing.Lock()
+		delete(s.healing.blocks, key)
+		s.healing.Unlock()
+		close(done)
+	}()
+
+	if err := s.incrementWorking(); err != nil {
+		return nil, err
+	}
+	defer s.decrementWorking()
+
+	blocks, err := s.getBlocks(roster, id, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(blocks) == 0 {
+		return nil, errors.New("no member of the roster knows the block")
+	}
+	sb := blocks[0]
+	if _, err := s.db.StoreBlocks([]*SkipBlock{sb}); err != nil {
+		return nil, err
+	}
+	log.Lvlf2("%v: fetched block %d / %x again", s.ServerIdentity(), sb.Index, id)
+	return sb, nil
+}
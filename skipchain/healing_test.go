@@ -0,0 +1,129 @@
+package skipchain
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/onet/v3"
+	bbolt "go.etcd.io/bbolt"
+)
+
+// tamperBlock overwrites the stored block with a copy holding other data.
+func tamperBlock(t *testing.T, db *SkipBlockDB, sb *SkipBlock) {
+	tampered := sb.Copy()
+	tampered.Data = []byte("tampered")
+	require.NoError(t, db.Update(func(tx *bbolt.Tx) error {
+		return db.storeToTx(tx, tampered)
+	}))
+}
+
+// garbleBlock overwrites the stored block with bytes that cannot be decoded.
+func garbleBlock(t *testing.T, db *SkipBlockDB, sb *SkipBlock) {
+	require.NoError(t, db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(db.bucketName).Put(sb.Hash, []byte("garbage"))
+	}))
+}
+
+func TestSkipBlockDB_Corrupted(t *testing.T) {
+	local := onet.NewLocalTest(suite)
+	_, ro, _ := local.GenTree(2, false)
+	defer local.CloseAll()
+
+	db, file := setupSkipBlockDB(t)
+	defer os.Remove(file)
+	db.corrupted = make(chan corruptedBlock, 10)
+
+	blocks := createSignedChain(t, ro, 3, 2, 2)
+	_, err := db.StoreBlocks(blocks)
+	require.NoError(t, err)
+
+	// A block that cannot be decoded is reported when it is read.
+	garbleBlock(t, db, blocks[2])
+	require.Nil(t, db.GetByID(blocks[2].Hash))
+	require.NoError(t, db.View(func(tx *bbolt.Tx) error {
+		_, err := db.getFromTx(tx, blocks[2].Hash)
+		require.Equal(t, ErrorCorruptedBlock, err)
+		return nil
+	}))
+	cb := <-db.corrupted
+	require.True(t, cb.id.Equal(blocks[2].Hash))
+	require.Nil(t, cb.sb)
+	<-db.corrupted
+
+	// A block that doesn't match its ID is only found by the integrity
+	// check.
+	tamperBlock(t, db, blocks[1])
+	require.NotNil(t, db.GetByID(blocks[1].Hash))
+	require.Equal(t, 0, len(db.corrupted))
+	db.checkIntegrity(make(chan bool))
+	require.Equal(t, 2, len(db.corrupted))
+	for i := 0; i < 2; i++ {
+		cb = <-db.corrupted
+		if cb.id.Equal(blocks[1].Hash) {
+			require.NotNil(t, cb.sb)
+		} else {
+			require.True(t, cb.id.Equal(blocks[2].Hash))
+		}
+	}
+
+	// Verified copies replace the damaged ones.
+	_, err = db.StoreBlocks(blocks[1:])
+	require.NoError(t, err)
+	for _, sb := range blocks[1:] {
+		stored := db.GetByID(sb.Hash)
+		require.NotNil(t, stored)
+		require.Equal(t, sb.Data, stored.Data)
+	}
+	db.checkIntegrity(make(chan bool))
+	require.Equal(t, 0, len(db.corrupted))
+}
+
+func TestService_HealBlocks(t *testing.T) {
+	l := onet.NewTCPTest(cothority.Suite)
+	svrs, ro, _ := l.GenTree(3, true)
+	defer waitPropagationFinished(t, l)
+	defer l.CloseAll()
+
+	cl := newTestClient(l)
+	gen, err := cl.CreateGenesis(ro, 2, 3, VerificationNone, nil)
+	require.NoError(t, err)
+	blocks := []*SkipBlock{gen}
+	for i := 1; i < 4; i++ {
+		reply, err := cl.StoreSkipBlock(gen, nil, []byte{byte(i)})
+		require.NoError(t, err)
+		blocks = append(blocks, reply.Latest)
+	}
+
+	s := l.Services[svrs[0].ServerIdentity.ID][sid].(*Service)
+	// One block is lost and another one cannot be decoded.
+	require.NoError(t, s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(s.db.bucketName).Delete(blocks[2].Hash)
+	}))
+	garbleBlock(t, s.db, blocks[3])
+
+	reply, err := s.GetBlocksByIndexRange(&GetBlocksByIndexRange{Genesis: gen.Hash, From: 1, To: 3})
+	require.NoError(t, err)
+	require.Equal(t, 3, len(reply.Blocks))
+	for i, sb := range reply.Blocks {
+		require.True(t, sb.Hash.Equal(blocks[i+1].Hash))
+	}
+	for _, sb := range blocks {
+		require.NotNil(t, s.db.GetByID(sb.Hash))
+	}
+
+	// A block that doesn't match its ID is found by the integrity check
+	// and fetched again by the repair loop.
+	tamperBlock(t, s.db, blocks[1])
+	s.db.checkIntegrity(s.closing)
+	for i := 0; ; i++ {
+		require.True(t, i < 50, "block not repaired")
+		if sb := s.db.GetByID(blocks[1].Hash); sb != nil &&
+			sb.CalculateHash().Equal(blocks[1].Hash) {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
@@ -0,0 +1,75 @@
+package inclusion
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// BundleVersion is the version of the format of the bundles.
+const BundleVersion = 1
+
+// Bundle holds all the blocks of a skipchain from the genesis block, each one
+// with the public keys of its roster and the forward-link to the next block,
+// so that an auditor can verify the whole skipchain offline. It is stored in
+// JSON, with the byte slices encoded in base64:
+//
+//	{
+//	  "Version": 1,
+//	  "GenesisID": "...",
+//	  "Blocks": [
+//	    {
+//	      "Index": 0, "Height": 1, "MaximumHeight": 2, "BaseHeight": 2,
+//	      "BackLinkIDs": [], "VerifierIDs": ["..."], "GenesisID": null,
+//	      "Data": "...", "Publics": ["..."], "ServicePublics": ["..."],
+//	      "SignatureScheme": 1,
+//	      "Link": {"From": "...", "To": "...", "Signature": "..."}
+//	    },
+//	    ...
+//	  ]
+//	}
+//
+// The fields of the blocks are the ones of Block, and the last block has no
+// link.
+type Bundle struct {
+	Version   int
+	GenesisID []byte
+	Blocks    []Block
+}
+
+// Verify checks that the bundle holds the consecutive blocks of the skipchain
+// from the genesis block, each one reached by a valid forward-link of the
+// previous one. It returns the ID of the last block.
+func (b *Bundle) Verify() ([]byte, error) {
+	if b.Version != BundleVersion {
+		return nil, fmt.Errorf("unsupported version %d", b.Version)
+	}
+	for i := range b.Blocks {
+		if b.Blocks[i].Index != i {
+			return nil, fmt.Errorf("missing block %d", i)
+		}
+	}
+	p := Proof{Blocks: b.Blocks}
+	return p.Verify(b.GenesisID)
+}
+
+// Write writes the bundle in JSON.
+func (b *Bundle) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(b)
+}
+
+// ReadBundle reads a bundle written by Bundle.Write. The bundle still has to
+// be verified.
+func ReadBundle(r io.Reader) (*Bundle, error) {
+	b := &Bundle{}
+	if err := json.NewDecoder(r).Decode(b); err != nil {
+		return nil, err
+	}
+	if len(b.Blocks) == 0 {
+		return nil, errors.New("empty bundle")
+	}
+	return b, nil
+}
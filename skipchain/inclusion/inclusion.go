@@ -0,0 +1,204 @@
+// Package inclusion verifies that a block belongs to a skipchain, without
+// depending on onet or on the skipchain service. A Proof holds the headers of
+// the blocks on a path of forward-links from the genesis block to the block,
+// with the public keys of their rosters, and can be exported as JSON for
+// third-party software.
+//
+// As in the skipchain, the hash of a block covers the public keys of the
+// conodes, but not the service keys used to sign the forward-links, which are
+// taken from the proof.
+package inclusion
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/pairing"
+	"go.dedis.ch/kyber/v3/sign"
+	"go.dedis.ch/kyber/v3/sign/bdn"
+	"go.dedis.ch/kyber/v3/sign/bls"
+)
+
+const (
+	// SchemeBLS is the signature scheme of the forward-links signed with
+	// BLS.
+	SchemeBLS = uint32(0)
+	// SchemeBDN is the signature scheme of the forward-links signed with
+	// BDN.
+	SchemeBDN = uint32(1)
+)
+
+var suite = pairing.NewSuiteBn256()
+
+// Proof is the list of the blocks from the genesis block to the proven block,
+// where each block holds a forward-link to the next one.
+type Proof struct {
+	Blocks []Block
+}
+
+// Block holds the hashed fields of a skipblock, the public keys of its roster
+// and the forward-link to the next block of the proof.
+type Block struct {
+	Index         int
+	Height        int
+	MaximumHeight int
+	BaseHeight    int
+	BackLinkIDs   [][]byte
+	VerifierIDs   [][]byte
+	GenesisID     []byte
+	Data          []byte
+	// Publics are the marshalled public keys of the conodes of the roster,
+	// which are part of the hash.
+	Publics [][]byte
+	// ServicePublics are the marshalled BN256 keys of the conodes used to
+	// sign the forward-links.
+	ServicePublics  [][]byte
+	SignatureScheme uint32
+	// Link is the forward-link to the next block of the proof, or nil for the
+	// last block.
+	Link *Link `json:",omitempty"`
+}
+
+// Link is a forward-link signed by the roster of the block it starts from.
+type Link struct {
+	From []byte
+	To   []byte
+	// NewRosterID is set if the roster changes.
+	NewRosterID []byte `json:",omitempty"`
+	// Signature is the aggregate signature followed by the participation
+	// mask.
+	Signature []byte
+}
+
+// Hash returns the ID of the block.
+func (b *Block) Hash() []byte {
+	h := sha256.New()
+	for _, i := range []int{b.Index, b.Height, b.MaximumHeight, b.BaseHeight} {
+		binary.Write(h, binary.LittleEndian, int32(i))
+	}
+	for _, bl := range b.BackLinkIDs {
+		h.Write(bl)
+	}
+	for _, v := range b.VerifierIDs {
+		h.Write(v)
+	}
+	h.Write(b.GenesisID)
+	h.Write(b.Data)
+	for _, pub := range b.Publics {
+		h.Write(pub)
+	}
+	// The signature scheme is only hashed if it is not the default one.
+	if b.SignatureScheme > 0 {
+		binary.Write(h, binary.LittleEndian, b.SignatureScheme)
+	}
+	return h.Sum(nil)
+}
+
+// Hash returns the message signed by the roster.
+func (l *Link) Hash() []byte {
+	h := sha256.New()
+	h.Write(l.From)
+	h.Write(l.To)
+	h.Write(l.NewRosterID)
+	return h.Sum(nil)
+}
+
+// Verify checks that the proof starts with the genesis block and that every
+// block is reached by a valid forward-link of the previous one. It returns the
+// ID of the last block, which is proven to belong to the skipchain.
+func (p *Proof) Verify(genesisID []byte) ([]byte, error) {
+	if len(p.Blocks) == 0 {
+		return nil, errors.New("empty proof")
+	}
+	first := p.Blocks[0]
+	if first.Index != 0 || !bytes.Equal(first.Hash(), genesisID) {
+		return nil, errors.New("proof doesn't start with the genesis block")
+	}
+
+	id := genesisID
+	for i := range p.Blocks {
+		b := &p.Blocks[i]
+		if i > 0 && !bytes.Equal(b.GenesisID, genesisID) {
+			return nil, fmt.Errorf("block %d is from another skipchain", b.Index)
+		}
+		if i == len(p.Blocks)-1 {
+			return id, nil
+		}
+
+		next := p.Blocks[i+1].Hash()
+		if b.Link == nil || !bytes.Equal(b.Link.From, id) || !bytes.Equal(b.Link.To, next) {
+			return nil, fmt.Errorf("missing forward-link to block %d", p.Blocks[i+1].Index)
+		}
+		if err := b.verifyLink(); err != nil {
+			return nil, fmt.Errorf("forward-link of block %d: %v", b.Index, err)
+		}
+		id = next
+	}
+	return id, nil
+}
+
+// VerifyBlock checks the proof and that it ends with the given block.
+func (p *Proof) VerifyBlock(genesisID, blockID []byte) error {
+	id, err := p.Verify(genesisID)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(id, blockID) {
+		return errors.New("proof doesn't end with the block")
+	}
+	return nil
+}
+
+// verifyLink checks that the forward-link is signed by at least the
+// Byzantine threshold of the roster.
+func (b *Block) verifyLink() error {
+	publics := make([]kyber.Point, len(b.ServicePublics))
+	for i, buf := range b.ServicePublics {
+		publics[i] = suite.G2().Point()
+		if err := publics[i].UnmarshalBinary(buf); err != nil {
+			return fmt.Errorf("invalid public key: %v", err)
+		}
+	}
+	if len(publics) == 0 {
+		return errors.New("empty roster")
+	}
+	sig := b.Link.Signature
+	lenSig := suite.G1().PointLen()
+	if len(sig) < lenSig {
+		return errors.New("signature too short")
+	}
+	mask, err := sign.NewMask(suite, publics, nil)
+	if err != nil {
+		return err
+	}
+	if len(sig) == lenSig {
+		for i := range publics {
+			mask.SetBit(i, true)
+		}
+	} else if err := mask.SetMask(sig[lenSig:]); err != nil {
+		return err
+	}
+	threshold := len(publics) - (len(publics)-1)/3
+	if mask.CountEnabled() < threshold {
+		return fmt.Errorf("only %d of %d signers", mask.CountEnabled(), len(publics))
+	}
+
+	msg := b.Link.Hash()
+	switch b.SignatureScheme {
+	case SchemeBLS:
+		agg := bls.AggregatePublicKeys(suite, mask.Participants()...)
+		return bls.Verify(suite, agg, msg, sig[:lenSig])
+	case SchemeBDN:
+		agg, err := bdn.AggregatePublicKeys(suite, mask)
+		if err != nil {
+			return err
+		}
+		return bdn.Verify(suite, agg, msg, sig[:lenSig])
+	default:
+		return fmt.Errorf("unknown signature scheme %d", b.SignatureScheme)
+	}
+}
@@ -0,0 +1,106 @@
+package skipchain
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/log"
+)
+
+// The phases of the creation of a block that are measured.
+const (
+	// phasePropose is the whole creation of a block by the leader.
+	phasePropose = "Propose"
+	// phaseVerify is the verification of a new block by a conode of the
+	// roster.
+	phaseVerify = "Verify"
+	// phaseSign is the collective signature of the forward-link to the new
+	// block.
+	phaseSign = "Sign"
+	// phasePropagate is the propagation of the forward-link to the roster.
+	phasePropagate = "Propagate"
+)
+
+// SlowPhase is the duration above which a phase of the creation of a block is
+// logged as a warning.
+var SlowPhase = 5 * time.Second
+
+// phaseMetrics holds the measures of one phase.
+type phaseMetrics struct {
+	count    int
+	failures int
+	total    time.Duration
+	max      time.Duration
+	last     time.Duration
+}
+
+// skipchainMetrics holds the measures of the phases of the creation of the
+// blocks since the start of the conode. It is reported in the status of the
+// conode.
+type skipchainMetrics struct {
+	sync.Mutex
+	phases map[string]*phaseMetrics
+}
+
+// observe adds the measure of a phase.
+func (m *skipchainMetrics) observe(phase string, d time.Duration, ok bool) {
+	m.Lock()
+	defer m.Unlock()
+	if m.phases == nil {
+		m.phases = make(map[string]*phaseMetrics)
+	}
+	pm := m.phases[phase]
+	if pm == nil {
+		pm = &phaseMetrics{}
+		m.phases[phase] = pm
+	}
+	pm.count++
+	if !ok {
+		pm.failures++
+	}
+	pm.total += d
+	pm.last = d
+	if d > pm.max {
+		pm.max = d
+	}
+}
+
+// GetStatus returns, for each phase, the number of measures and failures and
+// the average, maximum and last durations in milliseconds.
+func (m *skipchainMetrics) GetStatus() *onet.Status {
+	m.Lock()
+	defer m.Unlock()
+	out := make(map[string]string)
+	for phase, pm := range m.phases {
+		out[phase+"Count"] = strconv.Itoa(pm.count)
+		out[phase+"Failures"] = strconv.Itoa(pm.failures)
+		out[phase+"AvgMs"] = strconv.FormatInt(int64(pm.total/time.Duration(pm.count)/time.Millisecond), 10)
+		out[phase+"MaxMs"] = strconv.FormatInt(int64(pm.max/time.Millisecond), 10)
+		out[phase+"LastMs"] = strconv.FormatInt(int64(pm.last/time.Millisecond), 10)
+	}
+	return &onet.Status{Field: out}
+}
+
+// traceID returns the ID used in the logs of all the conodes to follow the
+// creation of a block, which is the beginning of its hash.
+func traceID(id SkipBlockID) string {
+	if len(id) > 8 {
+		id = id[:8]
+	}
+	return fmt.Sprintf("%x", []byte(id))
+}
+
+// trace measures a phase of the creation of a block started at start, and
+// logs it with the trace ID of the block.
+func (s *Service) trace(phase string, id SkipBlockID, start time.Time, ok bool) {
+	d := time.Since(start)
+	s.metrics.observe(phase, d, ok)
+	if d > SlowPhase {
+		log.Warnf("%s: trace %s: %s took %s (ok=%t)", s.ServerIdentity(), traceID(id), phase, d, ok)
+		return
+	}
+	log.Lvlf2("%s: trace %s: %s took %s (ok=%t)", s.ServerIdentity(), traceID(id), phase, d, ok)
+}
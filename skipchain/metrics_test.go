@@ -0,0 +1,51 @@
+package skipchain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/onet/v3"
+)
+
+func TestSkipchainMetrics(t *testing.T) {
+	var m skipchainMetrics
+	require.Equal(t, 0, len(m.GetStatus().Field))
+
+	m.observe(phaseSign, 10*time.Millisecond, true)
+	m.observe(phaseSign, 30*time.Millisecond, false)
+	m.observe(phaseVerify, 5*time.Millisecond, true)
+	status := m.GetStatus().Field
+	require.Equal(t, "2", status["SignCount"])
+	require.Equal(t, "1", status["SignFailures"])
+	require.Equal(t, "20", status["SignAvgMs"])
+	require.Equal(t, "30", status["SignMaxMs"])
+	require.Equal(t, "30", status["SignLastMs"])
+	require.Equal(t, "1", status["VerifyCount"])
+	require.Equal(t, "0", status["VerifyFailures"])
+
+	require.Equal(t, "0102", traceID(SkipBlockID{1, 2}))
+	require.Equal(t, "0102030405060708", traceID(SkipBlockID{1, 2, 3, 4, 5, 6, 7, 8, 9}))
+}
+
+func TestService_Metrics(t *testing.T) {
+	local := onet.NewLocalTest(cothority.Suite)
+	defer waitPropagationFinished(t, local)
+	defer local.CloseAll()
+	_, el, s := local.MakeSRS(cothority.Suite, 3, skipchainSID)
+	s1 := s.(*Service)
+
+	sbRoot, err := makeGenesisRosterArgs(s1, el, nil, VerificationNone, 1, 1)
+	require.NoError(t, err)
+	sbNext := sbRoot.Copy()
+	sbNext.BackLinkIDs = []SkipBlockID{sbRoot.Hash}
+	_, err = s1.StoreSkipBlock(&StoreSkipBlock{TargetSkipChainID: sbRoot.Hash, NewBlock: sbNext})
+	require.NoError(t, err)
+
+	status := s1.metrics.GetStatus().Field
+	require.Equal(t, "2", status["ProposeCount"])
+	require.Equal(t, "1", status["SignCount"])
+	require.Equal(t, "0", status["SignFailures"])
+	require.Equal(t, "1", status["PropagateCount"])
+}
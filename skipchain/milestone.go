@@ -0,0 +1,238 @@
+package skipchain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/sign/bls"
+)
+
+// MilestoneInterval is the number of blocks between two milestones. The
+// genesis block is the first milestone.
+var MilestoneInterval = 1000
+
+// Milestone summarizes the history of a skipchain up to one of its blocks. It
+// commits to the IDs of all the blocks from the genesis block to the milestone
+// block, and to the state of the application at this block. A client that
+// trusts a milestone can start following the skipchain from the milestone
+// block instead of the genesis block, and still check that an older block is
+// part of the history.
+type Milestone struct {
+	SkipChainID SkipBlockID
+	Index       int
+	BlockID     SkipBlockID
+	// History is the digest of the IDs of the blocks from the genesis block
+	// up to and including the milestone block, where each block extends the
+	// digest with extendHistory.
+	History []byte
+	// State is the commitment to the state of the application given by the
+	// function registered with RegisterMilestoneState, or empty.
+	State []byte
+}
+
+// Hash returns the digest of the milestone, signed by the conodes.
+func (m *Milestone) Hash() []byte {
+	h := sha256.New()
+	h.Write(m.SkipChainID)
+	binary.Write(h, binary.LittleEndian, int64(m.Index))
+	h.Write(m.BlockID)
+	h.Write(m.History)
+	h.Write(m.State)
+	return h.Sum(nil)
+}
+
+// VerifyHistory checks that the IDs of the blocks following the previous
+// milestone, up to and including the milestone block, give the history of the
+// milestone. If prev is nil, the IDs have to start with the genesis block.
+func (m *Milestone) VerifyHistory(prev *Milestone, ids []SkipBlockID) error {
+	var history []byte
+	index := -1
+	if prev != nil {
+		if !prev.SkipChainID.Equal(m.SkipChainID) {
+			return errors.New("milestones of different skipchains")
+		}
+		history = prev.History
+		index = prev.Index
+	} else if len(ids) == 0 || !ids[0].Equal(m.SkipChainID) {
+		return errors.New("history doesn't start with the genesis block")
+	}
+	if index+len(ids) != m.Index {
+		return fmt.Errorf("expected %d blocks but got %d", m.Index-index, len(ids))
+	}
+	for _, id := range ids {
+		history = extendHistory(history, id)
+	}
+	if !bytes.Equal(history, m.History) || !ids[len(ids)-1].Equal(m.BlockID) {
+		return errors.New("history doesn't match the milestone")
+	}
+	return nil
+}
+
+// extendHistory returns the digest of the history followed by the block.
+func extendHistory(history []byte, id SkipBlockID) []byte {
+	h := sha256.New()
+	h.Write(history)
+	h.Write(id)
+	return h.Sum(nil)
+}
+
+// RegisterMilestoneState sets the function returning the commitment to the
+// state of the application at a block, which is included in the milestones.
+func (s *Service) RegisterMilestoneState(f func(*SkipBlock) ([]byte, error)) {
+	s.milestoneMutex.Lock()
+	s.milestoneState = f
+	s.milestoneMutex.Unlock()
+}
+
+// GetMilestone returns the latest milestone of a skipchain with its block,
+// and the signature of the milestone by the conode. The milestones are
+// created when they are first asked for, which needs the blocks since the
+// previous milestone. When pruning a skipchain, the checkpoint should thus be
+// a milestone block.
+func (s *Service) GetMilestone(req *GetMilestone) (*GetMilestoneReply, error) {
+	m, err := s.updateMilestones(req.SkipChainID)
+	if err != nil {
+		return nil, err
+	}
+	sb := s.db.GetByID(m.BlockID)
+	if sb == nil {
+		return nil, errors.New("milestone block has been removed")
+	}
+	sig, err := bls.Sign(suite, s.ServerIdentity().ServicePrivate(ServiceName), m.Hash())
+	if err != nil {
+		return nil, err
+	}
+	return &GetMilestoneReply{Milestone: m, Block: sb, Signature: sig}, nil
+}
+
+// latestMilestone returns the milestone of the skipchain with the highest
+// index, or nil if none has been created yet.
+func (s *Service) latestMilestone(scid SkipBlockID) *Milestone {
+	s.storageMutex.Lock()
+	defer s.storageMutex.Unlock()
+	var latest *Milestone
+	for _, m := range s.Storage.Milestones {
+		if m.SkipChainID.Equal(scid) && (latest == nil || m.Index > latest.Index) {
+			latest = m
+		}
+	}
+	return latest
+}
+
+// updateMilestones creates the milestones of the skipchain up to its latest
+// block, and returns the latest one.
+func (s *Service) updateMilestones(scid SkipBlockID) (*Milestone, error) {
+	latest, err := s.db.GetLatestByID(scid)
+	if err != nil {
+		return nil, err
+	}
+	target := latest.Index - latest.Index%MilestoneInterval
+	last := s.latestMilestone(scid)
+	if last != nil && last.Index >= target {
+		return last, nil
+	}
+
+	var sb *SkipBlock
+	var history []byte
+	if last == nil {
+		sb = s.db.GetByID(scid)
+		if sb == nil || sb.Index != 0 {
+			return nil, errors.New("No such genesis-block")
+		}
+	} else {
+		history = last.History
+		if sb, err = s.nextMilestoneBlock(s.db.GetByID(last.BlockID)); err != nil {
+			return nil, err
+		}
+	}
+
+	var created []*Milestone
+	for {
+		history = extendHistory(history, sb.Hash)
+		if sb.Index%MilestoneInterval == 0 {
+			m := &Milestone{
+				SkipChainID: scid,
+				Index:       sb.Index,
+				BlockID:     sb.Hash,
+				History:     history,
+			}
+			if m.State, err = s.milestoneStateOf(sb); err != nil {
+				return nil, fmt.Errorf("state of block %d: %v", sb.Index, err)
+			}
+			created = append(created, m)
+		}
+		if sb.Index >= target {
+			break
+		}
+		if sb, err = s.nextMilestoneBlock(sb); err != nil {
+			return nil, err
+		}
+	}
+
+	s.storageMutex.Lock()
+	for _, m := range created {
+		known := false
+		for _, other := range s.Storage.Milestones {
+			if other.SkipChainID.Equal(scid) && other.Index == m.Index {
+				known = true
+				break
+			}
+		}
+		if !known {
+			s.Storage.Milestones = append(s.Storage.Milestones, m)
+		}
+	}
+	s.storageMutex.Unlock()
+	s.save()
+	return created[len(created)-1], nil
+}
+
+// nextMilestoneBlock returns the block following sb.
+func (s *Service) nextMilestoneBlock(sb *SkipBlock) (*SkipBlock, error) {
+	if sb == nil {
+		return nil, errors.New("milestone block has been removed")
+	}
+	if len(sb.ForwardLink) == 0 {
+		return nil, fmt.Errorf("missing forward-link of block %d", sb.Index)
+	}
+	next := s.db.GetByID(sb.ForwardLink[0].To)
+	if next == nil {
+		return nil, fmt.Errorf("missing block after index %d", sb.Index)
+	}
+	return next, nil
+}
+
+// milestoneStateOf returns the state of the application at the block, if a
+// function has been registered.
+func (s *Service) milestoneStateOf(sb *SkipBlock) ([]byte, error) {
+	s.milestoneMutex.Lock()
+	f := s.milestoneState
+	s.milestoneMutex.Unlock()
+	if f == nil {
+		return nil, nil
+	}
+	return f(sb)
+}
+
+// verify checks that the reply holds a milestone of the skipchain signed by
+// the public key, with its block.
+func (r *GetMilestoneReply) verify(scid SkipBlockID, public kyber.Point) error {
+	m, sb := r.Milestone, r.Block
+	if m == nil || sb == nil {
+		return errors.New("incomplete reply")
+	}
+	if !m.SkipChainID.Equal(scid) || !sb.SkipChainID().Equal(scid) {
+		return errors.New("milestone of another skipchain")
+	}
+	if !sb.CalculateHash().Equal(sb.Hash) {
+		return errors.New("wrong hash of the block")
+	}
+	if !sb.Hash.Equal(m.BlockID) || sb.Index != m.Index {
+		return errors.New("block doesn't match the milestone")
+	}
+	return bls.Verify(suite, public, m.Hash(), r.Signature)
+}
@@ -0,0 +1,74 @@
+package skipchain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/onet/v3"
+)
+
+func TestService_GetMilestone(t *testing.T) {
+	defer func(interval int) { MilestoneInterval = interval }(MilestoneInterval)
+	MilestoneInterval = 4
+
+	local := onet.NewLocalTest(suite)
+	servers, ro, _ := local.GenTree(2, true)
+	defer local.CloseAll()
+	services := local.GetServices(servers, skipchainSID)
+
+	blocks := createSignedChain(t, ro, 7, 2, 3)
+	for _, srv := range services {
+		s := srv.(*Service)
+		_, err := s.db.StoreBlocks(blocks)
+		require.NoError(t, err)
+		s.RegisterMilestoneState(func(sb *SkipBlock) ([]byte, error) {
+			return sb.Hash, nil
+		})
+	}
+	s := services[0].(*Service)
+
+	reply, err := s.GetMilestone(&GetMilestone{SkipChainID: blocks[0].Hash})
+	require.NoError(t, err)
+	m := reply.Milestone
+	require.Equal(t, 4, m.Index)
+	require.True(t, m.BlockID.Equal(blocks[4].Hash))
+	require.Equal(t, []byte(blocks[4].Hash), m.State)
+	require.NoError(t, reply.verify(blocks[0].Hash, servers[0].ServerIdentity.ServicePublic(ServiceName)))
+	require.Error(t, reply.verify(blocks[0].Hash, servers[1].ServerIdentity.ServicePublic(ServiceName)))
+
+	// The history commits to all the blocks up to the milestone.
+	var ids []SkipBlockID
+	for _, sb := range blocks[:5] {
+		ids = append(ids, sb.Hash)
+	}
+	require.NoError(t, m.VerifyHistory(nil, ids))
+	require.Error(t, m.VerifyHistory(nil, ids[:4]))
+	ids[2] = blocks[3].Hash
+	require.Error(t, m.VerifyHistory(nil, ids))
+	first := milestoneAt(t, s, blocks[0].Hash, 0)
+	require.NoError(t, m.VerifyHistory(first, []SkipBlockID{blocks[1].Hash,
+		blocks[2].Hash, blocks[3].Hash, blocks[4].Hash}))
+
+	_, err = s.GetMilestone(&GetMilestone{SkipChainID: SkipBlockID{1, 2, 3}})
+	require.Error(t, err)
+
+	cl := NewClient()
+	m, sb, err := cl.GetMilestone(ro, blocks[0].Hash)
+	require.NoError(t, err)
+	require.Equal(t, 4, m.Index)
+	require.True(t, sb.Hash.Equal(blocks[4].Hash))
+}
+
+// milestoneAt returns the milestone of the skipchain at the index, stored by
+// the service.
+func milestoneAt(t *testing.T, s *Service, scid SkipBlockID, index int) *Milestone {
+	s.storageMutex.Lock()
+	defer s.storageMutex.Unlock()
+	for _, m := range s.Storage.Milestones {
+		if m.SkipChainID.Equal(scid) && m.Index == index {
+			return m
+		}
+	}
+	require.Fail(t, "milestone not found")
+	return nil
+}
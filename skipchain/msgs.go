@@ -14,11 +14,22 @@ func init() {
 		&StoreSkipBlockReply{},
 		&OptimizeProofRequest{},
 		&OptimizeProofReply{},
+		&GetShortestPath{},
+		&GetShortestPathReply{},
 		// Requests for data
 		&GetUpdateChain{},
 		&GetUpdateChainReply{},
 		// Request updated block
 		&GetSingleBlock{},
+		// Request consecutive blocks
+		&GetBlocksByIndexRange{},
+		&GetBlocksByIndexRangeReply{},
+		// Request linked blocks in a batch
+		&GetBlocksBatch{},
+		&GetBlocksBatchReply{},
+		// Stream the blocks of a skipchain
+		&StreamBlocks{},
+		&StreamBlocksReply{},
 		// Fetch all skipchains
 		&GetAllSkipchains{},
 		&GetAllSkipchainsReply{},
@@ -44,6 +55,21 @@ func init() {
 		&ListFollow{},
 		// Returns the genesis-blocks of all skipchains we follow
 		&ListFollowReply{},
+		// Prune old blocks of a skipchain
+		&PruneSkipchain{},
+		&PruneSkipchainReply{},
+		// Set the roster policy of a skipchain
+		&SetRosterPolicy{},
+		// Report and retrieve evidences of forks
+		&ReportEquivocation{},
+		&GetEquivocations{},
+		&GetEquivocationsReply{},
+		// Get the latest milestone of a skipchain
+		&GetMilestone{},
+		&GetMilestoneReply{},
+		// List the verifications of a conode
+		&GetVerifiers{},
+		&GetVerifiersReply{},
 		// - Internal calls
 		// Propagation
 		&PropagateGenesis{},
@@ -100,6 +126,19 @@ type OptimizeProofReply struct {
 	Proof Proof
 }
 
+// GetShortestPath requests the shortest path of forward-links from a block
+// known by the client to a later block of the same skipchain.
+type GetShortestPath struct {
+	From SkipBlockID
+	To   SkipBlockID
+}
+
+// GetShortestPathReply returns the blocks of the path, starting with the From
+// block and ending with the To block.
+type GetShortestPathReply struct {
+	Path Proof
+}
+
 // GetUpdateChain - the client sends the hash of the last known
 // Skipblock and will get back a list of all necessary SkipBlocks
 // to get to the latest.
@@ -112,7 +151,9 @@ type GetUpdateChain struct {
 	// highest available forward links.
 	MaxHeight int `protobuf:"opt"`
 	// MaxBlocks is the maximum number of blocks to be returned. If it is not
-	// given, or equal to 0, all available blocks will be returned.
+	// given, or equal to 0, all available blocks will be returned, up to the
+	// limit of the conode for one reply. The following blocks can be
+	// requested starting from the last returned block.
 	MaxBlocks int `protobuf:"opt"`
 }
 
@@ -203,6 +244,63 @@ type GetSingleBlockByIndexReply struct {
 	Links     []*ForwardLink
 }
 
+// GetBlocksByIndexRange asks for the consecutive blocks of a skipchain with
+// an index between From and To, both included.
+type GetBlocksByIndexRange struct {
+	Genesis SkipBlockID
+	From    int
+	To      int
+}
+
+// GetBlocksByIndexRangeReply returns the blocks starting with the one at index
+// From. It holds less blocks than requested if the skipchain is shorter, or
+// if the range is bigger than the maximum number of blocks in a reply.
+type GetBlocksByIndexRangeReply struct {
+	Blocks []*SkipBlock
+}
+
+// GetBlocksBatch asks for a list of blocks, where each block is reached by a
+// forward-link of the previous one, like the blocks of a proof.
+type GetBlocksBatch struct {
+	IDs []SkipBlockID
+}
+
+// GetBlocksBatchReply returns the blocks, and the aggregate of the signatures
+// of the forward-links between them, so that they can be verified together.
+type GetBlocksBatchReply struct {
+	Blocks             []*SkipBlock
+	AggregateSignature []byte
+}
+
+// StreamBlocks asks for a stream of the blocks of a skipchain, starting with
+// the block at the index From. To resume a stream after a disconnection,
+// From is set to the index following the last received block.
+type StreamBlocks struct {
+	Genesis SkipBlockID
+	From    int
+}
+
+// StreamBlocksReply holds the next block of the stream.
+type StreamBlocksReply struct {
+	Block *SkipBlock
+}
+
+// ReportEquivocation sends the evidence of an equivocation to a conode.
+type ReportEquivocation struct {
+	Evidence *Equivocation
+}
+
+// GetEquivocations asks for the evidences of equivocations known for the
+// skipchain.
+type GetEquivocations struct {
+	SkipchainID SkipBlockID
+}
+
+// GetEquivocationsReply returns the evidences of equivocations.
+type GetEquivocationsReply struct {
+	Equivocations []*Equivocation
+}
+
 // Internal calls
 
 // GetBlock asks for an updated block, in case for a conode that is not
@@ -363,3 +461,52 @@ type ListFollowReply struct {
 	Follow    *[]FollowChainType
 	FollowIDs *[]SkipBlockID
 }
+
+// PruneSkipchain asks the conode to remove the blocks of a skipchain that are
+// older than the checkpoint, except the ones needed to follow the
+// forward-links from the genesis block. The signature has to be on the
+// following message:
+// "prune:" + the ID of the checkpoint block
+type PruneSkipchain struct {
+	Checkpoint SkipBlockID
+	Signature  []byte
+}
+
+// PruneSkipchainReply returns the number of blocks that have been removed.
+type PruneSkipchainReply struct {
+	Pruned int
+}
+
+// SetRosterPolicy asks the conode to use the policy for the roster changes of
+// the skipchain, or to go back to the default policy if Policy is nil. The
+// signature has to be on the following message:
+// "rosterpolicy:" + SkipchainID + MaxChurn as 8 bytes little endian + 1 byte
+// set to 1 if RequireQuorum is true, where the last two parts are left out if
+// Policy is nil.
+type SetRosterPolicy struct {
+	SkipchainID SkipBlockID
+	Policy      *RosterPolicy
+	Signature   []byte
+}
+
+// GetMilestone asks for the latest milestone of a skipchain.
+type GetMilestone struct {
+	SkipChainID SkipBlockID
+}
+
+// GetMilestoneReply returns the latest milestone with its block, and the BLS
+// signature of the hash of the milestone by the service key of the conode.
+type GetMilestoneReply struct {
+	Milestone *Milestone
+	Block     *SkipBlock
+	Signature []byte
+}
+
+// GetVerifiers asks for the verifications registered on the conode.
+type GetVerifiers struct {
+}
+
+// GetVerifiersReply returns the IDs of the verifications.
+type GetVerifiersReply struct {
+	Verifiers []VerifierID
+}
@@ -0,0 +1,47 @@
+package skipchain
+
+import (
+	"runtime"
+	"sync"
+)
+
+// verifyWorkers is the number of goroutines verifying the signatures of the
+// forward-links of downloaded blocks.
+var verifyWorkers = runtime.NumCPU()
+
+// verifyForwardSignatures checks the hashes and the forward-link signatures of
+// the blocks concurrently, which is the most expensive part of a catch-up. It
+// returns the result of VerifyForwardSignatures for each block, in the same
+// order, so that the caller still accepts the blocks one after the other.
+func verifyForwardSignatures(blocks []*SkipBlock) []error {
+	errs := make([]error, len(blocks))
+	workers := verifyWorkers
+	if workers > len(blocks) {
+		workers = len(blocks)
+	}
+	if workers <= 1 {
+		for i, sb := range blocks {
+			errs[i] = sb.VerifyForwardSignatures()
+		}
+		return errs
+	}
+
+	indexes := make(chan int, len(blocks))
+	for i := range blocks {
+		indexes <- i
+	}
+	close(indexes)
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				errs[i] = blocks[i].VerifyForwardSignatures()
+			}
+		}()
+	}
+	wg.Wait()
+	return errs
+}
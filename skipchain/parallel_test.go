@@ -0,0 +1,78 @@
+package skipchain
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/onet/v3"
+)
+
+func TestVerifyForwardSignatures(t *testing.T) {
+	defer func(w int) { verifyWorkers = w }(verifyWorkers)
+	verifyWorkers = 4
+
+	local := onet.NewLocalTest(suite)
+	_, ro, _ := local.GenTree(3, false)
+	defer local.CloseAll()
+
+	blocks := createSignedChain(t, ro, 10, 2, 3)
+	for _, err := range verifyForwardSignatures(blocks) {
+		require.NoError(t, err)
+	}
+
+	// A wrong signature is reported for its block only.
+	blocks[5].ForwardLink[0].Signature.Sig[0]++
+	errs := verifyForwardSignatures(blocks)
+	for i, err := range errs {
+		if i == 5 {
+			require.Error(t, err)
+		} else {
+			require.NoError(t, err)
+		}
+	}
+
+	// The blocks are still accepted in order when stored.
+	db, file := setupSkipBlockDB(t)
+	defer os.Remove(file)
+	_, err := db.StoreBlocks(blocks[:5])
+	require.NoError(t, err)
+	_, err = db.StoreBlocks(blocks[5:])
+	require.Error(t, err)
+	blocks[5].ForwardLink[0].Signature.Sig[0]--
+	_, err = db.StoreBlocks(blocks[5:])
+	require.NoError(t, err)
+	require.NotNil(t, db.GetByID(blocks[9].Hash))
+}
+
+// TestStoreBlocks_KnownRoster checks that the forward-links of a known block
+// are verified with the roster of the stored block.
+func TestStoreBlocks_KnownRoster(t *testing.T) {
+	local := onet.NewLocalTest(suite)
+	_, ro, _ := local.GenTree(3, false)
+	_, other, _ := local.GenTree(3, false)
+	defer local.CloseAll()
+
+	blocks := createSignedChain(t, ro, 2, 2, 1)
+	db, file := setupSkipBlockDB(t)
+	defer os.Remove(file)
+	genesis := blocks[0].Copy()
+	genesis.ForwardLink = nil
+	_, err := db.StoreBlocks([]*SkipBlock{genesis})
+	require.NoError(t, err)
+
+	// A copy of the block with another roster and a forward-link signed by
+	// that roster.
+	forged := blocks[0].Copy()
+	forged.Roster = other
+	fl := NewForwardLink(forged, blocks[1])
+	require.NoError(t, fl.sign(other))
+	forged.ForwardLink = []*ForwardLink{fl}
+	_, err = db.StoreBlocks([]*SkipBlock{forged})
+	require.NoError(t, err)
+	require.Equal(t, 0, len(db.GetByID(blocks[0].Hash).ForwardLink))
+
+	_, err = db.StoreBlocks([]*SkipBlock{blocks[0]})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(db.GetByID(blocks[0].Hash).ForwardLink))
+}
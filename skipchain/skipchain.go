@@ -13,10 +13,12 @@ package skipchain
 
 import (
 	"bytes"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"reflect"
 	"runtime"
+	"sort"
 	"strconv"
 	"sync"
 	"time"
@@ -43,6 +45,11 @@ const bftFollowBlock = "SkipchainBFTFollow"
 const bdnNewBlock = "SkipchainBDNNew"
 const bdnFollowBlock = "SkipchainBDNFollow"
 
+// maxUpdateChainBlocks is the maximum number of blocks returned in a reply to
+// GetUpdateChain, so that the replies stay small for long chains. The clients
+// ask for the following blocks starting from the last one they got.
+var maxUpdateChainBlocks = 1000
+
 var storageKey = []byte("skipchainconfig")
 var dbVersion = 1
 var suite = pairing.NewSuiteBn256()
@@ -63,11 +70,16 @@ type Service struct {
 	propagateForwardLink    messaging.PropagationFunc
 	propagateProof          messaging.PropagationFunc
 	verifiers               map[VerifierID]SkipBlockVerifier
+	verifiersMutex          sync.Mutex
+	metrics                 skipchainMetrics
 	storageMutex            sync.Mutex
 	Storage                 *Storage
 	bftTimeout              time.Duration
 	propTimeout             time.Duration
+	chainTimeouts           map[string]chainTimeouts
+	chainTimeoutsMutex      sync.Mutex
 	chains                  chainLocker
+	healing                 healing
 	verifyNewBlockBuffer    sync.Map
 	verifyFollowBlockBuffer sync.Map
 	closed                  bool
@@ -75,6 +87,11 @@ type Service struct {
 	working                 sync.WaitGroup
 	closing                 chan bool
 
+	// milestoneState returns the state of the application included in the
+	// milestones.
+	milestoneState func(*SkipBlock) ([]byte, error)
+	milestoneMutex sync.Mutex
+
 	// disableForwardLink is useful in testing mode
 	disableForwardLink bool
 }
@@ -152,6 +169,13 @@ type Storage struct {
 	// to this service. Once a client is linked to a service, only blocks signed
 	// by this client will be allowed.
 	Clients []kyber.Point
+	// RosterPolicies holds the policies for roster changes set for some
+	// skipchains. The other skipchains use the DefaultRosterPolicy.
+	RosterPolicies []RosterPolicy
+	// Equivocations holds the evidences of forks found in the skipchains.
+	Equivocations []*Equivocation
+	// Milestones holds the milestones created for the skipchains.
+	Milestones []*Milestone
 }
 
 // StoreSkipBlock stores a new skipblock in the system. This can be either a
@@ -188,6 +212,15 @@ func (s *Service) StoreSkipBlock(psbd *StoreSkipBlock) (*StoreSkipBlockReply, er
 // its behavior is to reject any new foreign resquests, even it it comes from a local
 // service, like Byzcoin.
 func (s *Service) StoreSkipBlockInternal(psbd *StoreSkipBlock) (*StoreSkipBlockReply, error) {
+	start := time.Now()
+	reply, err := s.storeSkipBlockInternal(psbd)
+	s.trace(phasePropose, psbd.NewBlock.Hash, start, err == nil)
+	return reply, err
+}
+
+// storeSkipBlockInternal does the work of StoreSkipBlockInternal, which
+// measures it.
+func (s *Service) storeSkipBlockInternal(psbd *StoreSkipBlock) (*StoreSkipBlockReply, error) {
 	err := s.incrementWorking()
 	if err != nil {
 		return nil, err
@@ -481,11 +514,22 @@ func (s *Service) OptimizeProof(req *OptimizeProofRequest) (*OptimizeProofReply,
 	newProof = append(newProof, target)
 
 	// Propagate the optimized proof to the given roster
-	err = s.startPropagation(s.propagateProof, req.Roster, &PropagateProof{newProof})
+	err = s.startPropagation(target.SkipChainID(), s.propagateProof, req.Roster, &PropagateProof{newProof})
 
 	return &OptimizeProofReply{newProof}, err
 }
 
+// GetShortestPath returns the blocks linking the From block to the To block
+// with the fewest forward-links, so that light clients don't need to get
+// every block in between.
+func (s *Service) GetShortestPath(req *GetShortestPath) (*GetShortestPathReply, error) {
+	path, err := s.db.GetShortestPath(req.From, req.To)
+	if err != nil {
+		return nil, err
+	}
+	return &GetShortestPathReply{Path: path}, nil
+}
+
 // GetUpdateChain returns a slice of SkipBlocks which describe the part of the
 // skipchain from the latest block the caller knows to the latest
 // SkipBlock we know. The last block in the returned slice of blocks is
@@ -505,11 +549,12 @@ func (s *Service) GetUpdateChain(guc *GetUpdateChain) (*GetUpdateChainReply, err
 		maxHeight = block.MaximumHeight
 	}
 	maxBlocks := guc.MaxBlocks
+	if maxBlocks <= 0 || maxBlocks > maxUpdateChainBlocks {
+		maxBlocks = maxUpdateChainBlocks
+	}
 	// Loop for as long as we have available forward links and that we don't have
-	// more than maxBlocks blocks - except if it is 0, then add as many blocks as
-	// we have.
-	for block.GetForwardLen() > 0 &&
-		(maxBlocks <= 0 || len(blocks) < maxBlocks) {
+	// more than maxBlocks blocks.
+	for block.GetForwardLen() > 0 && len(blocks) < maxBlocks {
 		var link *ForwardLink
 		if block.GetForwardLen() < maxHeight {
 			link = block.ForwardLink[block.GetForwardLen()-1]
@@ -717,6 +762,43 @@ func (s *Service) GetSingleBlockByIndex(id *GetSingleBlockByIndex) (*GetSingleBl
 	return nil, err
 }
 
+// GetBlocksByIndexRange returns the consecutive blocks of a skipchain from
+// the index From to the index To, both included. At most
+// maxUpdateChainBlocks blocks are returned, so the client has to ask again for
+// the following ones.
+func (s *Service) GetBlocksByIndexRange(req *GetBlocksByIndexRange) (*GetBlocksByIndexRangeReply, error) {
+	if req.From < 0 || req.To < req.From {
+		return nil, fmt.Errorf("invalid range of indexes [%d, %d]", req.From, req.To)
+	}
+	first, err := s.GetSingleBlockByIndex(&GetSingleBlockByIndex{
+		Genesis: req.Genesis,
+		Index:   req.From,
+	})
+	if err != nil {
+		return nil, err
+	}
+	sb := first.SkipBlock
+	blocks := []*SkipBlock{sb}
+	for sb.Index < req.To && len(blocks) < maxUpdateChainBlocks {
+		if len(sb.ForwardLink) == 0 {
+			break
+		}
+		next := s.db.GetByID(sb.ForwardLink[0].To)
+		if next == nil {
+			// The block is missing or damaged, so a copy is fetched
+			// from the roster of the previous block.
+			next, err = s.healBlock(sb.Roster, sb.ForwardLink[0].To)
+			if err != nil {
+				log.Errorf("%v: %v", s.ServerIdentity(), err)
+				return nil, fmt.Errorf("missing block after index %d", sb.Index)
+			}
+		}
+		blocks = append(blocks, next)
+		sb = next
+	}
+	return &GetBlocksByIndexRangeReply{Blocks: blocks}, nil
+}
+
 // GetAllSkipchains currently returns a list of all the known blocks.
 // This is a bug, but for backwards compatibility it is being left as is.
 //
@@ -827,7 +909,9 @@ func (s *Service) Listlink(list *Listlink) (*ListlinkReply, error) {
 	return reply, nil
 }
 
-// AddFollow adds a new skipchain to be followed
+// AddFollow adds a new skipchain to be followed. If the skipchain is already
+// followed, its entry is replaced, which allows to change its policy for new
+// chains without restarting the conode.
 func (s *Service) AddFollow(add *AddFollow) (*EmptyReply, error) {
 	msg := []byte{byte(add.Follow)}
 	msg = append(add.SkipchainID, msg...)
@@ -844,7 +928,9 @@ func (s *Service) AddFollow(add *AddFollow) (*EmptyReply, error) {
 	switch add.Follow {
 	case FollowID:
 		log.Lvlf2("%s FollowChain %x", s.ServerIdentity(), add.SkipchainID)
-		s.Storage.FollowIDs = append(s.Storage.FollowIDs, add.SkipchainID)
+		if s.followIDIndex(add.SkipchainID) < 0 {
+			s.Storage.FollowIDs = append(s.Storage.FollowIDs, add.SkipchainID)
+		}
 	case FollowSearch:
 		// First search if anybody knows that SkipBlockID
 		sis := map[string]*network.ServerIdentity{}
@@ -872,12 +958,7 @@ func (s *Service) AddFollow(add *AddFollow) (*EmptyReply, error) {
 				log.Lvl1(s.ServerIdentity(), "could not get last block: ", err)
 			} else {
 				if last.SkipChainID().Equal(add.SkipchainID) {
-					s.Storage.Follow = append(s.Storage.Follow,
-						FollowChainType{
-							Block:    last,
-							NewChain: add.NewChain,
-							closing:  make(chan bool),
-						})
+					s.setFollow(last, add.NewChain)
 					found = true
 					break
 				}
@@ -893,12 +974,7 @@ func (s *Service) AddFollow(add *AddFollow) (*EmptyReply, error) {
 		if err != nil {
 			return nil, errors.New("couldn't lookup skipchain: " + err.Error())
 		}
-		s.Storage.Follow = append(s.Storage.Follow,
-			FollowChainType{
-				Block:    last,
-				NewChain: add.NewChain,
-				closing:  make(chan bool),
-			})
+		s.setFollow(last, add.NewChain)
 		log.Lvlf2("%s FollowLookup %x", s.ServerIdentity(), add.SkipchainID)
 	default:
 		return nil, errors.New("unknown follow type")
@@ -913,23 +989,20 @@ func (s *Service) DelFollow(del *DelFollow) (*EmptyReply, error) {
 	if !s.verifySigs(msg, del.Signature) {
 		return &EmptyReply{}, errors.New("wrong signature of unknown signer")
 	}
+	s.storageMutex.Lock()
 	deleted := false
-	for i, scid := range s.Storage.FollowIDs {
-		if scid.Equal(del.SkipchainID) {
-			s.Storage.FollowIDs = append(s.Storage.FollowIDs[:i],
-				s.Storage.FollowIDs[i+1:]...)
-			deleted = true
-			break
-		}
+	if i := s.followIDIndex(del.SkipchainID); i >= 0 {
+		s.Storage.FollowIDs = append(s.Storage.FollowIDs[:i],
+			s.Storage.FollowIDs[i+1:]...)
+		deleted = true
 	}
-	for i, fct := range s.Storage.Follow {
-		if fct.Block.SkipChainID().Equal(del.SkipchainID) {
-			s.Storage.Follow = append(s.Storage.Follow[:i],
-				s.Storage.Follow[i+1:]...)
-			deleted = true
-			break
-		}
+	if i := s.followIndex(del.SkipchainID); i >= 0 {
+		s.Storage.Follow[i].Shutdown()
+		s.Storage.Follow = append(s.Storage.Follow[:i],
+			s.Storage.Follow[i+1:]...)
+		deleted = true
 	}
+	s.storageMutex.Unlock()
 	if !deleted {
 		return &EmptyReply{}, errors.New("didn't find any block of that id")
 	}
@@ -948,15 +1021,151 @@ func (s *Service) ListFollow(list *ListFollow) (*ListFollowReply, error) {
 	if !s.verifySigs(msg, list.Signature) {
 		return reply, errors.New("wrong signature of unknown signer")
 	}
+	s.storageMutex.Lock()
+	defer s.storageMutex.Unlock()
+	// Return copies, so that the reply isn't changed by concurrent calls.
 	if len(s.Storage.Follow) > 0 {
-		reply.Follow = &s.Storage.Follow
+		follow := append([]FollowChainType{}, s.Storage.Follow...)
+		reply.Follow = &follow
 	}
 	if len(s.Storage.FollowIDs) > 0 {
-		reply.FollowIDs = &s.Storage.FollowIDs
+		followIDs := append([]SkipBlockID{}, s.Storage.FollowIDs...)
+		reply.FollowIDs = &followIDs
 	}
 	return reply, nil
 }
 
+// followIDIndex returns the index of the skipchain in FollowIDs, or -1. The
+// caller must hold storageMutex.
+func (s *Service) followIDIndex(scid SkipBlockID) int {
+	for i, id := range s.Storage.FollowIDs {
+		if id.Equal(scid) {
+			return i
+		}
+	}
+	return -1
+}
+
+// followIndex returns the index of the skipchain in Follow, or -1. The caller
+// must hold storageMutex.
+func (s *Service) followIndex(scid SkipBlockID) int {
+	for i, fct := range s.Storage.Follow {
+		if fct.Block.SkipChainID().Equal(scid) {
+			return i
+		}
+	}
+	return -1
+}
+
+// setFollow adds the skipchain of the block to the followed chains, or
+// replaces its entry if it is already followed. The caller must hold
+// storageMutex.
+func (s *Service) setFollow(latest *SkipBlock, policy PolicyNewChain) {
+	fct := FollowChainType{
+		Block:    latest,
+		NewChain: policy,
+		closing:  make(chan bool),
+	}
+	if i := s.followIndex(latest.SkipChainID()); i >= 0 {
+		s.Storage.Follow[i].Shutdown()
+		s.Storage.Follow[i] = fct
+		return
+	}
+	s.Storage.Follow = append(s.Storage.Follow, fct)
+}
+
+// PruneSkipchain removes the blocks older than the checkpoint from the
+// database. As this deletes data, it needs to be signed by one of the linked
+// clients, and fails if no client is linked.
+func (s *Service) PruneSkipchain(req *PruneSkipchain) (*PruneSkipchainReply, error) {
+	s.storageMutex.Lock()
+	linked := len(s.Storage.Clients) > 0
+	s.storageMutex.Unlock()
+	if !linked {
+		return nil, errors.New("pruning needs a linked client")
+	}
+	msg := append([]byte("prune:"), req.Checkpoint...)
+	if !s.verifySigs(msg, req.Signature) {
+		return nil, errors.New("wrong signature of unknown signer")
+	}
+	checkpoint := s.db.GetByID(req.Checkpoint)
+	if checkpoint == nil {
+		return nil, errors.New("unknown checkpoint block")
+	}
+
+	// Don't remove blocks while new ones are added to the skipchain.
+	s.chains.lock(checkpoint.SkipChainID())
+	defer s.chains.unlock(checkpoint.SkipChainID())
+	pruned, err := s.db.Prune(req.Checkpoint)
+	if err != nil {
+		return nil, err
+	}
+	return &PruneSkipchainReply{Pruned: pruned}, nil
+}
+
+// SetRosterPolicy sets the policy for the roster changes of a skipchain, used
+// by the VerifyRosterChange verification, or removes it if the policy is nil.
+// As it can loosen the verification, it needs to be signed by one of the
+// linked clients, and fails if no client is linked.
+func (s *Service) SetRosterPolicy(req *SetRosterPolicy) (*EmptyReply, error) {
+	s.storageMutex.Lock()
+	linked := len(s.Storage.Clients) > 0
+	s.storageMutex.Unlock()
+	if !linked {
+		return nil, errors.New("setting a roster policy needs a linked client")
+	}
+	if !s.verifySigs(rosterPolicyMessage(req.SkipchainID, req.Policy), req.Signature) {
+		return nil, errors.New("wrong signature of unknown signer")
+	}
+	if req.Policy != nil && req.Policy.MaxChurn < 0 {
+		return nil, errors.New("maximum churn cannot be negative")
+	}
+
+	s.storageMutex.Lock()
+	for i, rp := range s.Storage.RosterPolicies {
+		if rp.SkipchainID.Equal(req.SkipchainID) {
+			s.Storage.RosterPolicies = append(s.Storage.RosterPolicies[:i],
+				s.Storage.RosterPolicies[i+1:]...)
+			break
+		}
+	}
+	if req.Policy != nil {
+		policy := *req.Policy
+		policy.SkipchainID = req.SkipchainID
+		s.Storage.RosterPolicies = append(s.Storage.RosterPolicies, policy)
+	}
+	s.storageMutex.Unlock()
+	s.save()
+	return &EmptyReply{}, nil
+}
+
+// getRosterPolicy returns the roster policy of the skipchain.
+func (s *Service) getRosterPolicy(scid SkipBlockID) RosterPolicy {
+	s.storageMutex.Lock()
+	defer s.storageMutex.Unlock()
+	for _, rp := range s.Storage.RosterPolicies {
+		if rp.SkipchainID.Equal(scid) {
+			return rp
+		}
+	}
+	return DefaultRosterPolicy
+}
+
+// rosterPolicyMessage returns the message signed by the client to set a
+// roster policy.
+func rosterPolicyMessage(scid SkipBlockID, rp *RosterPolicy) []byte {
+	msg := append([]byte("rosterpolicy:"), scid...)
+	if rp != nil {
+		buf := make([]byte, 9)
+		binary.LittleEndian.PutUint64(buf, uint64(rp.MaxChurn))
+		if rp.RequireQuorum {
+			buf[8] = 1
+		}
+		msg = append(msg, buf...)
+	}
+	return msg
+}
+
 // WaitBlock returns a block by its ID instantly if already stored in the DB
 // or check if the block is inside the buffer. If the block is known, false will
 // be returned because a catch up is not necessary, true otherwise.
@@ -1050,6 +1259,7 @@ func (s *Service) TestRestart() error {
 	s.TestClose()
 	db, bucket := s.GetAdditionalBucket([]byte("skipblocks"))
 	s.db = NewSkipBlockDB(db, bucket)
+	s.db.equivocation = s.storeEquivocation
 	s.Storage = &Storage{}
 	// Don't reset the verifiers, keep them
 	//s.verifiers = map[VerifierID]SkipBlockVerifier{}
@@ -1057,7 +1267,10 @@ func (s *Service) TestRestart() error {
 	s.blockBuffer = newSkipBlockBuffer()
 	s.closed = false
 	s.closing = make(chan bool)
-	return s.tryLoad()
+	if err := s.tryLoad(); err != nil {
+		return err
+	}
+	return s.startRepair()
 }
 
 func (s *Service) verifySigs(msg, sig []byte) bool {
@@ -1109,7 +1322,9 @@ func (s *Service) forwardLinkLevel0(src, dst *SkipBlock) error {
 	}
 	fwd := NewForwardLink(src, dst)
 	protoName, _ := src.SignatureProtocol()
-	sig, err := s.startBFT(protoName, roster, dst.Roster, fwd.Hash(), data)
+	start := time.Now()
+	sig, err := s.startBFT(src.SkipChainID(), protoName, roster, dst.Roster, fwd.Hash(), data)
+	s.trace(phaseSign, dst.Hash, start, err == nil)
 	if err != nil {
 		log.Error(s.ServerIdentity().Address, "startBFT failed with", err)
 		return err
@@ -1130,7 +1345,9 @@ func (s *Service) forwardLinkLevel0(src, dst *SkipBlock) error {
 	}
 
 	// We send the new forward link to the previous roster only
-	err = s.startPropagation(s.propagateForwardLink, roster, &PropagateForwardLink{fwd, 0})
+	start = time.Now()
+	err = s.startPropagation(src.SkipChainID(), s.propagateForwardLink, roster, &PropagateForwardLink{fwd, 0})
+	s.trace(phasePropagate, dst.Hash, start, err == nil)
 	if err != nil {
 		log.Error("Failed to propagate the forward link to the previous roster:", err)
 	}
@@ -1157,7 +1374,7 @@ func (s *Service) forwardLinkLevel0(src, dst *SkipBlock) error {
 
 	// current conode needs to be in the propagation roster
 	newRoster = append(newRoster, s.ServerIdentity())
-	return s.startPropagation(s.propagateProof, onet.NewRoster(newRoster), &PropagateProof{proof})
+	return s.startPropagation(src.SkipChainID(), s.propagateProof, onet.NewRoster(newRoster), &PropagateProof{proof})
 }
 
 // bftForwardLinkLevel0 makes sure that a signature-request for a forward-link
@@ -1214,6 +1431,7 @@ func (s *Service) bftForwardLinkLevel0(msg, data []byte) bool {
 		return false
 	}
 
+	start := time.Now()
 	ok = func() bool {
 		for i, verifier := range prevSB.VerifierIDs {
 			if !verifier.Equal(fs.Newest.VerifierIDs[i]) {
@@ -1222,7 +1440,7 @@ func (s *Service) bftForwardLinkLevel0(msg, data []byte) bool {
 			}
 		}
 		for _, ver := range fs.Newest.VerifierIDs {
-			f, exists := s.verifiers[ver]
+			f, exists := s.getVerifier(ver)
 			if !exists {
 				log.Lvlf2("Found no user verification for %s", ver)
 				return false
@@ -1248,6 +1466,7 @@ func (s *Service) bftForwardLinkLevel0(msg, data []byte) bool {
 		}
 		return true
 	}()
+	s.trace(phaseVerify, fs.Newest.Hash, start, ok)
 	if ok {
 		s.verifyNewBlockBuffer.Store(sliceToArr(msg), true)
 		// We can cache the block because it has been verified by this conode
@@ -1353,7 +1572,7 @@ func (s *Service) ForwardLinkHandler(req *ForwardSignature) (*ForwardSignatureRe
 		}
 		fl := NewForwardLink(from, fs.Newest)
 		_, protoName := from.SignatureProtocol()
-		sig, err := s.startBFT(protoName, from.Roster, fs.Newest.Roster, fl.Hash(), data)
+		sig, err := s.startBFT(from.SkipChainID(), protoName, from.Roster, fs.Newest.Roster, fl.Hash(), data)
 		if err != nil {
 			return nil, errors.New("Couldn't get signature: " + err.Error())
 		}
@@ -1371,7 +1590,7 @@ func (s *Service) ForwardLinkHandler(req *ForwardSignature) (*ForwardSignatureRe
 		// is exluded from the cothority, it will need to catch up the forward link later when
 		// re-entering the cothority.
 		ro := fs.Newest.Roster.Concat(s.ServerIdentity())
-		return fl, s.startPropagation(s.propagateForwardLink, ro, &PropagateForwardLink{fl, fs.TargetHeight})
+		return fl, s.startPropagation(from.SkipChainID(), s.propagateForwardLink, ro, &PropagateForwardLink{fl, fs.TargetHeight})
 	}()
 	if err != nil {
 		return nil, fmt.Errorf("%v couldn't create forwardLink: %v", s.ServerIdentity(), err)
@@ -1483,8 +1702,8 @@ func (s *Service) bftForwardLinkAck(msg, data []byte) bool {
 // be used if the ID between the two rosters are different but the aggregate is
 // the same. This is an optimisation because the newer roster might have an
 // order that is more likely to give us non-failing subleaders in the byzcoinx
-// protocol.
-func (s *Service) startBFT(proto string, origRoster, newRoster *onet.Roster, msg, data []byte) (*byzcoinx.FinalSignature, error) {
+// protocol. The timeout of the protocol is the one of the skipchain scid.
+func (s *Service) startBFT(scid SkipBlockID, proto string, origRoster, newRoster *onet.Roster, msg, data []byte) (*byzcoinx.FinalSignature, error) {
 	// Before BDN signatures, the new roster was used when it was a rotation so
 	// that subleaders were more likely to be alive. It doesn't work anymore with
 	// BDN signatures because the way coefficients are computed.
@@ -1517,11 +1736,8 @@ func (s *Service) startBFT(proto string, origRoster, newRoster *onet.Roster, msg
 	root.Data = data
 	root.CreateProtocol = s.CreateProtocol
 	root.FinalSignatureChan = make(chan byzcoinx.FinalSignature, 1)
-	root.Timeout = s.propTimeout
+	root.Timeout = s.bftTimeoutOf(scid)
 	root.Threshold = byzcoinx.Threshold(len(tree.List()))
-	if s.bftTimeout != 0 {
-		root.Timeout = s.bftTimeout
-	}
 
 	log.Lvl3(s.ServerIdentity(), "starts bft-cosi")
 	if err := node.Start(); err != nil {
@@ -1630,7 +1846,7 @@ func (s *Service) PropagateProof(roster *onet.Roster, sid SkipBlockID) error {
 	// The propagation protocol expect this server to be present in the roster.
 	rosterWithRoot := roster.Concat(s.ServerIdentity())
 
-	return s.startPropagation(s.propagateProof, rosterWithRoot, &PropagateProof{proof})
+	return s.startPropagation(proof[0].SkipChainID(), s.propagateProof, rosterWithRoot, &PropagateProof{proof})
 }
 
 // propagateProofHandler handles a chain propagation message that
@@ -1661,10 +1877,52 @@ func (s *Service) propagateProofHandler(msg network.Message) error {
 // RegisterVerification stores the verification in a map and will
 // call it whenever a verification needs to be done.
 func (s *Service) registerVerification(v VerifierID, f SkipBlockVerifier) error {
+	s.verifiersMutex.Lock()
+	defer s.verifiersMutex.Unlock()
 	s.verifiers[v] = f
 	return nil
 }
 
+// unregisterVerification removes the verification, so that the blocks
+// needing it are refused. The verifications of the skipchain service itself
+// cannot be removed.
+func (s *Service) unregisterVerification(v VerifierID) error {
+	if v.Equal(VerifyBase) || v.Equal(VerifyRosterChange) {
+		return errors.New("cannot unregister a verification of the skipchain service")
+	}
+	s.verifiersMutex.Lock()
+	defer s.verifiersMutex.Unlock()
+	if _, exists := s.verifiers[v]; !exists {
+		return fmt.Errorf("unknown verification %s", v)
+	}
+	delete(s.verifiers, v)
+	return nil
+}
+
+// getVerifier returns the verification registered for the ID.
+func (s *Service) getVerifier(v VerifierID) (SkipBlockVerifier, bool) {
+	s.verifiersMutex.Lock()
+	defer s.verifiersMutex.Unlock()
+	f, exists := s.verifiers[v]
+	return f, exists
+}
+
+// GetVerifiers returns the IDs of the verifications registered on the conode,
+// so that clients can check that all the conodes of a roster support the
+// verifications of a new skipchain before creating it.
+func (s *Service) GetVerifiers(req *GetVerifiers) (*GetVerifiersReply, error) {
+	s.verifiersMutex.Lock()
+	ids := make([]VerifierID, 0, len(s.verifiers))
+	for v := range s.verifiers {
+		ids = append(ids, v)
+	}
+	s.verifiersMutex.Unlock()
+	sort.Slice(ids, func(i, j int) bool {
+		return bytes.Compare(ids[i][:], ids[j][:]) < 0
+	})
+	return &GetVerifiersReply{Verifiers: ids}, nil
+}
+
 // verifyBlock makes sure the basic parameters of a block are correct and returns
 // an error if something fails.
 func (s *Service) verifyBlock(sb *SkipBlock) error {
@@ -1695,14 +1953,16 @@ func (s *Service) verifyBlock(sb *SkipBlock) error {
 	return nil
 }
 
-func (s *Service) startPropagation(propagate messaging.PropagationFunc, ro *onet.Roster, msg network.Message) error {
+// startPropagation sends the message to the roster, with the propagation
+// timeout of the skipchain scid.
+func (s *Service) startPropagation(scid SkipBlockID, propagate messaging.PropagationFunc, ro *onet.Roster, msg network.Message) error {
 	err := s.incrementWorking()
 	if err != nil {
 		return err
 	}
 	defer s.decrementWorking()
 
-	replies, err := propagate(ro, msg, s.propTimeout)
+	replies, err := propagate(ro, msg, s.propTimeoutOf(scid))
 	if err != nil {
 		return err
 	}
@@ -1719,7 +1979,7 @@ func (s *Service) startGenesisPropagation(genesis *SkipBlock) error {
 	roster := genesis.Roster
 	log.Lvlf3("%s: propagating %x to %s", s.ServerIdentity(), genesis.Hash, roster.List)
 
-	return s.startPropagation(s.propagateGenesis, roster, &PropagateGenesis{genesis})
+	return s.startPropagation(genesis.SkipChainID(), s.propagateGenesis, roster, &PropagateGenesis{genesis})
 }
 
 // authenticate searches if this node or any follower-node can verify the
@@ -1872,14 +2132,26 @@ func newSkipchainService(c *onet.Context) (onet.Service, error) {
 		s.GetSingleBlock, s.GetSingleBlockByIndex, s.GetAllSkipchains,
 		s.GetAllSkipChainIDs, s.OptimizeProof,
 		s.CreateLinkPrivate, s.Unlink, s.AddFollow, s.ListFollow,
-		s.DelFollow, s.Listlink, s.ForwardLinkHandler))
+		s.DelFollow, s.Listlink, s.ForwardLinkHandler, s.PruneSkipchain,
+		s.GetShortestPath, s.GetBlocksByIndexRange, s.SetRosterPolicy,
+		s.ReportEquivocation, s.GetEquivocations, s.GetBlocksBatch,
+		s.GetMilestone, s.GetVerifiers))
+	s.db.equivocation = s.storeEquivocation
+	if err := s.startRepair(); err != nil {
+		return nil, err
+	}
+	log.ErrFatal(s.RegisterStreamingHandlers(s.StreamBlocks))
 	s.ServiceProcessor.RegisterStatusReporter("Skipblock", s.db)
+	s.ServiceProcessor.RegisterStatusReporter("SkipchainMetrics", &s.metrics)
 	// Deprecated: the handler should be used instead
 	s.RegisterProcessorFunc(network.RegisterMessage(&ForwardSignature{}), s.forwardLink)
 
 	if err := s.registerVerification(VerifyBase, s.verifyFuncBase); err != nil {
 		return nil, err
 	}
+	if err := s.registerVerification(VerifyRosterChange, s.verifyFuncRosterChange); err != nil {
+		return nil, err
+	}
 
 	var err error
 	s.propagateGenesis, err = messaging.NewPropagationFunc(c, "SkipchainPropagate", s.propagateGenesisHandler, -1)
@@ -387,6 +387,43 @@ func TestService_ProtocolVerificationPanic(t *testing.T) {
 	require.Contains(t, err.Error(), "couldn't sign forward-link")
 }
 
+func TestService_UnregisterVerification(t *testing.T) {
+	local := onet.NewLocalTest(cothority.Suite)
+	defer waitPropagationFinished(t, local)
+	defer local.CloseAll()
+	hosts, el, s := local.MakeSRS(cothority.Suite, 3, skipchainSID)
+	s1 := s.(*Service)
+	verifyFunc := func(newID []byte, newSB *SkipBlock) bool {
+		return true
+	}
+	verifyID := VerifierID(uuid.NewV1())
+	for _, s := range local.Services {
+		require.NoError(t, s[skipchainSID].(*Service).registerVerification(verifyID, verifyFunc))
+	}
+
+	cl := NewClient()
+	ids, err := cl.GetVerifiers(hosts[0].ServerIdentity)
+	require.NoError(t, err)
+	require.True(t, VerifierIDs(ids).containsAll([]VerifierID{VerifyBase, VerifyRosterChange, verifyID}))
+	require.Equal(t, 0, len(cl.MissingVerifiers(el, []VerifierID{VerifyBase, verifyID})))
+
+	s3 := hosts[2].Service(ServiceName).(*Service)
+	require.Error(t, s3.unregisterVerification(VerifyBase))
+	require.NoError(t, UnregisterVerification(hosts[2], verifyID))
+	require.Error(t, s3.unregisterVerification(verifyID))
+	missing := cl.MissingVerifiers(el, []VerifierID{VerifyBase, verifyID})
+	require.Equal(t, 1, len(missing))
+	require.True(t, missing[0].Equal(hosts[2].ServerIdentity))
+
+	// The conode without the verification refuses to sign the new blocks.
+	sbRoot, err := makeGenesisRosterArgs(s1, el, nil, []VerifierID{verifyID}, 1, 1)
+	require.NoError(t, err)
+	sbNext := sbRoot.Copy()
+	sbNext.BackLinkIDs = []SkipBlockID{sbRoot.Hash}
+	_, err = s1.StoreSkipBlock(&StoreSkipBlock{TargetSkipChainID: sbRoot.Hash, NewBlock: sbNext})
+	require.Error(t, err)
+}
+
 func TestService_RegisterVerification(t *testing.T) {
 	// Testing whether we sign correctly the SkipBlocks
 	onet.RegisterNewService("ServiceVerify", newServiceVerify)
@@ -1645,3 +1682,28 @@ func testOptimizeProof(t *testing.T, numBlock, base, max, expected int) {
 	require.NoError(t, err)
 	require.Equal(t, expected, len(sbs))
 }
+
+func TestNewTimestampVerifier(t *testing.T) {
+	// The test blocks hold the timestamp in their data.
+	verifier := NewTimestampVerifier(time.Minute, func(sb *SkipBlock) (time.Time, error) {
+		ts, err := time.Parse(time.RFC3339, string(sb.Data))
+		if err != nil {
+			return time.Time{}, errors.New("invalid header")
+		}
+		return ts, nil
+	})
+	sb := NewSkipBlock()
+	for _, c := range []struct {
+		data     string
+		accepted bool
+	}{
+		{time.Now().Format(time.RFC3339), true},
+		{time.Now().Add(-30 * time.Second).Format(time.RFC3339), true},
+		{time.Now().Add(2 * time.Minute).Format(time.RFC3339), false},
+		{time.Now().Add(-2 * time.Minute).Format(time.RFC3339), false},
+		{"now", false},
+	} {
+		sb.Data = []byte(c.data)
+		require.Equal(t, c.accepted, verifier(nil, sb), c.data)
+	}
+}
@@ -0,0 +1,99 @@
+package skipchain
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// BlockStore is a storage for the marshalled skipblocks, indexed by their
+// hash. It is used as cold storage by the SkipBlockDB: the blocks removed by
+// Prune are moved to it, and the blocks missing from the database are
+// searched in it. Implementations can keep the bulky history of the chains on
+// cheaper storage, like a network file system or an object store, and must be
+// safe for concurrent use.
+type BlockStore interface {
+	// Put stores the marshalled block under its hash.
+	Put(id SkipBlockID, buf []byte) error
+	// Get returns the marshalled block, or nil if it is not stored.
+	Get(id SkipBlockID) ([]byte, error)
+	// Delete removes the block. Deleting a missing block is not an error.
+	Delete(id SkipBlockID) error
+}
+
+// SetColdStore sets the storage where the pruned blocks are moved to. Once it
+// is set, Prune doesn't delete the old blocks anymore, and GetByID returns
+// them from the cold storage. Only the blocks in the database are used to
+// list the skipchains, so the latest blocks must never be moved.
+func (db *SkipBlockDB) SetColdStore(cs BlockStore) {
+	db.coldMutex.Lock()
+	defer db.coldMutex.Unlock()
+	db.coldStore = cs
+}
+
+func (db *SkipBlockDB) getColdStore() BlockStore {
+	db.coldMutex.Lock()
+	defer db.coldMutex.Unlock()
+	return db.coldStore
+}
+
+// DirBlockStore is a BlockStore that keeps each block in a file of a
+// directory.
+type DirBlockStore struct {
+	dir string
+}
+
+// NewDirBlockStore returns a BlockStore using the given directory, which is
+// created if it doesn't exist.
+func NewDirBlockStore(dir string) (*DirBlockStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &DirBlockStore{dir: dir}, nil
+}
+
+// Put implements BlockStore. The file is written under a temporary name and
+// then renamed, so that a crash never leaves a partial block.
+func (ds *DirBlockStore) Put(id SkipBlockID, buf []byte) error {
+	tmp, err := ioutil.TempFile(ds.dir, "tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(buf); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), ds.path(id))
+}
+
+// Get implements BlockStore.
+func (ds *DirBlockStore) Get(id SkipBlockID) ([]byte, error) {
+	buf, err := ioutil.ReadFile(ds.path(id))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read block %x: %v", id, err)
+	}
+	return buf, nil
+}
+
+// Delete implements BlockStore.
+func (ds *DirBlockStore) Delete(id SkipBlockID) error {
+	err := os.Remove(ds.path(id))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (ds *DirBlockStore) path(id SkipBlockID) string {
+	return filepath.Join(ds.dir, hex.EncodeToString(id)+".block")
+}
@@ -0,0 +1,76 @@
+package skipchain
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/onet/v3"
+)
+
+func TestDirBlockStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "skipchain-cold")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ds, err := NewDirBlockStore(dir)
+	require.NoError(t, err)
+	buf, err := ds.Get(SkipBlockID{1, 2})
+	require.NoError(t, err)
+	require.Nil(t, buf)
+
+	require.NoError(t, ds.Put(SkipBlockID{1, 2}, []byte("block")))
+	buf, err = ds.Get(SkipBlockID{1, 2})
+	require.NoError(t, err)
+	require.Equal(t, []byte("block"), buf)
+
+	require.NoError(t, ds.Delete(SkipBlockID{1, 2}))
+	require.NoError(t, ds.Delete(SkipBlockID{1, 2}))
+	buf, err = ds.Get(SkipBlockID{1, 2})
+	require.NoError(t, err)
+	require.Nil(t, buf)
+}
+
+func TestSkipBlockDB_ColdStore(t *testing.T) {
+	local := onet.NewLocalTest(suite)
+	_, ro, _ := local.GenTree(2, false)
+	defer local.CloseAll()
+
+	db, file := setupSkipBlockDB(t)
+	defer os.Remove(file)
+	dir, err := ioutil.TempDir("", "skipchain-cold")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	ds, err := NewDirBlockStore(dir)
+	require.NoError(t, err)
+	db.SetColdStore(ds)
+
+	blocks := createSignedChain(t, ro, 20, 2, 5)
+	_, err = db.StoreBlocks(blocks)
+	require.NoError(t, err)
+
+	// The pruned blocks are moved to the cold storage and can still be
+	// retrieved.
+	pruned, err := db.Prune(blocks[13].Hash)
+	require.NoError(t, err)
+	require.Equal(t, 10, pruned)
+	require.Equal(t, 10, db.Length())
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Equal(t, 10, len(files))
+	for _, sb := range blocks {
+		stored := db.GetByID(sb.Hash)
+		require.NotNil(t, stored, "block %d", sb.Index)
+		require.True(t, stored.Hash.Equal(sb.Hash))
+	}
+	latest, err := db.GetLatestByID(blocks[0].Hash)
+	require.NoError(t, err)
+	require.True(t, latest.Hash.Equal(blocks[19].Hash))
+
+	require.NoError(t, db.RemoveSkipchain(blocks[0].Hash))
+	require.Equal(t, 0, db.Length())
+	files, err = ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Equal(t, 0, len(files))
+}
@@ -0,0 +1,103 @@
+package skipchain
+
+import (
+	"errors"
+	"fmt"
+
+	"go.dedis.ch/onet/v3/log"
+)
+
+// StreamBlocks streams the blocks of a skipchain, starting at the index From,
+// and then the new blocks as they are added, until the client closes the
+// connection. The blocks are read from the database one at a time, only once
+// the previous one has been sent, so a slow client only delays the stream
+// instead of making the conode buffer the blocks.
+func (s *Service) StreamBlocks(req *StreamBlocks) (chan *StreamBlocksReply, chan bool, error) {
+	if req.From < 0 {
+		return nil, nil, fmt.Errorf("invalid index %d", req.From)
+	}
+	if s.db.GetByID(req.Genesis) == nil {
+		return nil, nil, errors.New("No such genesis-block")
+	}
+	if err := s.incrementWorking(); err != nil {
+		return nil, nil, err
+	}
+	s.closedMutex.Lock()
+	closing := s.closing
+	s.closedMutex.Unlock()
+
+	outChan := make(chan *StreamBlocksReply)
+	stopChan := make(chan bool)
+	go func() {
+		defer s.decrementWorking()
+		// Closing the channel makes onet close the connection.
+		defer close(outChan)
+
+		var prev *SkipBlock
+		for {
+			// Get the channel before reading the database, so that no
+			// new block is missed.
+			update := s.db.waitUpdate()
+			sb, err := s.nextStreamBlock(req, prev)
+			if err != nil {
+				log.Error(s.ServerIdentity(), err)
+				return
+			}
+			if sb == nil {
+				select {
+				case <-update:
+					continue
+				case <-stopChan:
+				case <-closing:
+				}
+				return
+			}
+
+			select {
+			case outChan <- &StreamBlocksReply{Block: sb}:
+				prev = sb
+			case <-stopChan:
+				return
+			case <-closing:
+				return
+			}
+		}
+	}()
+	return outChan, stopChan, nil
+}
+
+// nextStreamBlock returns the block following prev, or the block at the
+// starting index if prev is nil. It returns nil if the block is not yet known.
+func (s *Service) nextStreamBlock(req *StreamBlocks, prev *SkipBlock) (*SkipBlock, error) {
+	if prev == nil {
+		latest, err := s.db.GetLatestByID(req.Genesis)
+		if err != nil {
+			return nil, err
+		}
+		if latest.Index < req.From {
+			return nil, nil
+		}
+		reply, err := s.GetSingleBlockByIndex(&GetSingleBlockByIndex{
+			Genesis: req.Genesis,
+			Index:   req.From,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return reply.SkipBlock, nil
+	}
+
+	// The forward-link might have been added since the block was sent.
+	prev = s.db.GetByID(prev.Hash)
+	if prev == nil {
+		return nil, errors.New("streamed block has been removed")
+	}
+	if len(prev.ForwardLink) == 0 {
+		return nil, nil
+	}
+	next := s.db.GetByID(prev.ForwardLink[0].To)
+	if next == nil {
+		return nil, fmt.Errorf("missing block after index %d", prev.Index)
+	}
+	return next, nil
+}
@@ -8,10 +8,12 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"sort"
 	"strconv"
 	"sync"
 	"time"
 
+	"github.com/golang/snappy"
 	"go.dedis.ch/cothority/v3/blscosi/bdnproto"
 	"go.dedis.ch/cothority/v3/blscosi/protocol"
 	"go.dedis.ch/cothority/v3/byzcoinx"
@@ -28,6 +30,9 @@ import (
 // doesn't respect the consistency of the chain.
 var ErrorInconsistentForwardLink = errors.New("found inconsistent forward-link")
 
+// ErrorCorruptedBlock is returned when a stored block cannot be decoded.
+var ErrorCorruptedBlock = errors.New("stored block is corrupted")
+
 // How long to wait before a timeout is generated in the propagation. It is not
 // set to a constant because we'd like to change it in the test.
 var defaultPropagateTimeout = 15 * time.Second
@@ -91,6 +96,24 @@ func (vids VerifierIDs) Equal(others []VerifierID) bool {
 	return true
 }
 
+// containsAll returns true when all the others are in the array, in any
+// order.
+func (vids VerifierIDs) containsAll(others []VerifierID) bool {
+	for _, other := range others {
+		found := false
+		for _, vid := range vids {
+			if vid.Equal(other) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
 // SkipBlockVerifier is function that should return whether this skipblock is
 // accepted or not. This function is used during a BFTCosi round, but wrapped
 // around so it accepts a block.
@@ -113,6 +136,51 @@ const (
 	NewChainAnyNode
 )
 
+// RosterPolicy restricts the roster changes a new block can introduce. It is
+// enforced by the VerifyRosterChange verification.
+type RosterPolicy struct {
+	// SkipchainID is the skipchain the policy applies to
+	SkipchainID SkipBlockID
+	// MaxChurn is the maximum number of nodes that can be added and removed
+	// in one block. 0 means no limit.
+	MaxChurn int
+	// RequireQuorum makes sure that the nodes kept from the previous roster
+	// are a Byzantine quorum of both the previous and the new roster, so
+	// that the new nodes can never take over the skipchain in one block.
+	RequireQuorum bool
+}
+
+// DefaultRosterPolicy is used for the skipchains with the VerifyRosterChange
+// verification and without a policy set on the conode.
+var DefaultRosterPolicy = RosterPolicy{RequireQuorum: true}
+
+// Check returns an error if the change from the previous roster to the new
+// one doesn't respect the policy.
+func (rp RosterPolicy) Check(prev, next *onet.Roster) error {
+	kept := 0
+	for _, si := range prev.List {
+		if i, _ := next.Search(si.ID); i >= 0 {
+			kept++
+		}
+	}
+	churn := len(prev.List) - kept + len(next.List) - kept
+	if rp.MaxChurn > 0 && churn > rp.MaxChurn {
+		return fmt.Errorf("%d nodes changed, but at most %d are allowed",
+			churn, rp.MaxChurn)
+	}
+	if rp.RequireQuorum {
+		if kept < byzcoinx.Threshold(len(prev.List)) {
+			return fmt.Errorf("only %d nodes of the previous roster of %d are kept",
+				kept, len(prev.List))
+		}
+		if kept < byzcoinx.Threshold(len(next.List)) {
+			return fmt.Errorf("the %d kept nodes are not a quorum of the new roster of %d",
+				kept, len(next.List))
+		}
+	}
+	return nil
+}
+
 // FollowType defines how a followed skipchain is stored
 type FollowType int
 
@@ -233,11 +301,25 @@ func RegisterVerification(s GetService, v VerifierID, f SkipBlockVerifier) error
 	return scs.(*Service).registerVerification(v, f)
 }
 
+// UnregisterVerification removes a verification stored with
+// RegisterVerification. New blocks of the skipchains using it are then refused
+// by the conode.
+func UnregisterVerification(s GetService, v VerifierID) error {
+	scs := s.Service(ServiceName)
+	if scs == nil {
+		return errors.New("Didn't find our service: " + ServiceName)
+	}
+	return scs.(*Service).unregisterVerification(v)
+}
+
 var (
 	// VerifyBase checks that the base-parameters are correct, i.e.,
 	// the links are correctly set up, the height-parameters and the
 	// verification didn't change.
 	VerifyBase = VerifierID(uuid.NewV5(uuid.NamespaceURL, "Base"))
+	// VerifyRosterChange checks that the roster of the new block respects
+	// the RosterPolicy of the skipchain.
+	VerifyRosterChange = VerifierID(uuid.NewV5(uuid.NamespaceURL, "RosterChange"))
 )
 
 // VerificationStandard makes sure that all links are correct and that the
@@ -741,6 +823,19 @@ type SkipBlockDB struct {
 	latestBlocks map[string]SkipBlockID
 	latestMutex  sync.Mutex
 	callback     func(SkipBlockID) error
+	// coldStore holds the pruned blocks, if it is set
+	coldStore BlockStore
+	coldMutex sync.Mutex
+	// updated is closed and replaced each time blocks are stored
+	updated      chan struct{}
+	updatedMutex sync.Mutex
+	// equivocation is called with the evidences found while storing blocks
+	equivocation func(*Equivocation)
+	// corrupted receives the stored blocks that cannot be decoded or don't
+	// match their ID, if it is set
+	corrupted chan corruptedBlock
+	// uncompressed disables the compression of the stored blocks
+	uncompressed bool
 }
 
 // NewSkipBlockDB returns an initialized SkipBlockDB structure.
@@ -793,19 +888,32 @@ func (db *SkipBlockDB) GetByID(sbID SkipBlockID) *SkipBlock {
 // so that the db is consistent at every moment.
 func (db *SkipBlockDB) StoreBlocks(blocks []*SkipBlock) ([]SkipBlockID, error) {
 	var result []SkipBlockID
+	var equivocations []*Equivocation
+	// The signatures of the new blocks with a valid hash are checked
+	// concurrently, and the other blocks link by link. The forward-links of
+	// a known block are always checked against the roster of the stored
+	// copy.
+	sigErrs := verifyForwardSignatures(blocks)
 	err := db.Update(func(tx *bbolt.Tx) error {
+		equivocations = nil
 		for i, sb := range blocks {
 			log.Lvlf2("Storing skipblock %d / %x", sb.Index, sb.Hash)
 			sbOld, err := db.getFromTx(tx, sb.Hash)
-			if err != nil {
+			if err != nil && err != ErrorCorruptedBlock {
 				return errors.New("failed to get skipblock with error: " + err.Error())
 			}
+			if sbOld != nil && !sbOld.CalculateHash().Equal(sb.Hash) {
+				log.Errorf("stored block %x doesn't match its ID", sb.Hash)
+				sbOld = nil
+			}
+			// A damaged copy is overwritten by the new block.
 			if sbOld != nil {
+				equivocations = append(equivocations, findEquivocations(sbOld, sb)...)
 				numFL := len(sbOld.ForwardLink)
 				// If this skipblock already exists, only copy forward-links and
 				// new children.
 				if len(sb.ForwardLink) > numFL {
-					for i, fl := range sb.ForwardLink[numFL:] {
+					for j, fl := range sb.ForwardLink[numFL:] {
 						if fl.IsEmpty() {
 							// Ignore empty links.
 							continue
@@ -813,7 +921,7 @@ func (db *SkipBlockDB) StoreBlocks(blocks []*SkipBlock) ([]SkipBlockID, error) {
 
 						publics := sbOld.Roster.ServicePublics(ServiceName)
 
-						if err := fl.VerifyWithScheme(suite, publics, sb.SignatureScheme); err != nil {
+						if err := fl.VerifyWithScheme(suite, publics, sbOld.SignatureScheme); err != nil {
 							// Only keep a log of the failing forward links but keep trying others.
 							log.Error("Got a known block with wrong signature in forward-link with error: " + err.Error())
 							continue
@@ -828,13 +936,13 @@ func (db *SkipBlockDB) StoreBlocks(blocks []*SkipBlock) ([]SkipBlockID, error) {
 						if target != nil {
 							diff := math.Log(float64(target.Index - sbOld.Index))
 							base := math.Log(float64(sbOld.BaseHeight))
-							if int(diff/base) != i+numFL {
-								log.Errorf("Received a forward link with an invalid height: %x/%d", sb.Hash, i+numFL)
+							if int(diff/base) != j+numFL {
+								log.Errorf("Received a forward link with an invalid height: %x/%d", sb.Hash, j+numFL)
 								continue
 							}
 						}
 
-						if err := sbOld.AddForwardLink(fl, i+numFL); err != nil {
+						if err := sbOld.AddForwardLink(fl, j+numFL); err != nil {
 							log.Error(err)
 						}
 					}
@@ -869,16 +977,17 @@ func (db *SkipBlockDB) StoreBlocks(blocks []*SkipBlock) ([]SkipBlockID, error) {
 						len(sb.ForwardLink), sb.Height)
 				}
 
-				publics := sb.Roster.ServicePublics(ServiceName)
-
 				for _, fl := range sb.ForwardLink {
 					if !fl.IsEmpty() {
 						if !fl.From.Equal(sb.Hash) {
 							return ErrorInconsistentForwardLink
 						}
 
-						if err := fl.VerifyWithScheme(suite, publics, sb.SignatureScheme); err != nil {
-							return errors.New("invalid forward-link signature: " + err.Error())
+						if sigErrs[i] != nil {
+							publics := sb.Roster.ServicePublics(ServiceName)
+							if err := fl.VerifyWithScheme(suite, publics, sb.SignatureScheme); err != nil {
+								return errors.New("invalid forward-link signature: " + err.Error())
+							}
 						}
 					}
 				}
@@ -904,10 +1013,66 @@ func (db *SkipBlockDB) StoreBlocks(blocks []*SkipBlock) ([]SkipBlockID, error) {
 			}
 		}
 	}
+	if err == nil && len(result) > 0 {
+		db.notifyUpdate()
+	}
+	if db.equivocation != nil {
+		for _, e := range equivocations {
+			db.equivocation(e)
+		}
+	}
 
 	return result, err
 }
 
+// findEquivocations compares the forward-links of a stored block with the ones
+// of the same block received again, and returns the evidences of the
+// different targets at the same height that are correctly signed.
+func findEquivocations(stored, received *SkipBlock) []*Equivocation {
+	var found []*Equivocation
+	publics := stored.Roster.ServicePublics(ServiceName)
+	for i, fl := range received.ForwardLink {
+		if i >= len(stored.ForwardLink) {
+			break
+		}
+		known := stored.ForwardLink[i]
+		if fl.IsEmpty() || known.IsEmpty() || known.To.Equal(fl.To) {
+			continue
+		}
+		if !fl.From.Equal(stored.Hash) ||
+			fl.VerifyWithScheme(suite, publics, stored.SignatureScheme) != nil {
+			continue
+		}
+		found = append(found, &Equivocation{
+			Block:  stored.Copy(),
+			Height: i,
+			First:  known.Copy(),
+			Second: fl.Copy(),
+		})
+	}
+	return found
+}
+
+// waitUpdate returns a channel that is closed the next time blocks are
+// stored.
+func (db *SkipBlockDB) waitUpdate() <-chan struct{} {
+	db.updatedMutex.Lock()
+	defer db.updatedMutex.Unlock()
+	if db.updated == nil {
+		db.updated = make(chan struct{})
+	}
+	return db.updated
+}
+
+func (db *SkipBlockDB) notifyUpdate() {
+	db.updatedMutex.Lock()
+	defer db.updatedMutex.Unlock()
+	if db.updated != nil {
+		close(db.updated)
+		db.updated = nil
+	}
+}
+
 // Store stores the given SkipBlock in the service-list
 func (db *SkipBlockDB) Store(sb *SkipBlock) SkipBlockID {
 	ids, err := db.StoreBlocks([]*SkipBlock{sb})
@@ -1086,21 +1251,21 @@ func (db *SkipBlockDB) GetFuzzy(id string) (*SkipBlock, error) {
 		c := tx.Bucket([]byte(db.bucketName)).Cursor()
 		for k, v := c.First(); k != nil; k, v = c.Next() {
 			if bytes.HasPrefix(k, match) {
-				_, msg, err := network.Unmarshal(v, suite)
+				msg, err := decodeBlock(v)
 				if err != nil {
 					return errors.New("Unmarshal failed with error: " + err.Error())
 				}
-				sb = msg.(*SkipBlock).Copy()
+				sb = msg.Copy()
 				return nil
 			}
 		}
 		for k, v := c.First(); k != nil; k, v = c.Next() {
 			if bytes.HasSuffix(k, match) {
-				_, msg, err := network.Unmarshal(v, suite)
+				msg, err := decodeBlock(v)
 				if err != nil {
 					return errors.New("Unmarshal failed with error: " + err.Error())
 				}
-				sb = msg.(*SkipBlock).Copy()
+				sb = msg.Copy()
 				return nil
 			}
 		}
@@ -1218,6 +1383,61 @@ func (db *SkipBlockDB) GetProofForID(bid SkipBlockID) (sbs Proof, err error) {
 	return
 }
 
+// GetShortestPath returns the blocks to go from one block to a later block of
+// the same skipchain. At every block, it follows the highest forward-link
+// that doesn't go past the target, so that the path holds O(log n) blocks.
+// The first block of the path is the from block, and the last one the to
+// block.
+func (db *SkipBlockDB) GetShortestPath(from, to SkipBlockID) (sbs Proof, err error) {
+	err = db.View(func(tx *bbolt.Tx) error {
+		sb, err := db.getFromTx(tx, from)
+		if err != nil {
+			return err
+		}
+		target, err := db.getFromTx(tx, to)
+		if err != nil {
+			return err
+		}
+		if sb == nil || target == nil {
+			return errors.New("couldn't find the block")
+		}
+		if !sb.SkipChainID().Equal(target.SkipChainID()) {
+			return errors.New("blocks are not in the same skipchain")
+		}
+		if sb.Index > target.Index {
+			return errors.New("target block is before the starting block")
+		}
+
+		sbs = append(sbs, sb)
+		for !sb.Hash.Equal(target.Hash) {
+			var next *SkipBlock
+			for h := len(sb.ForwardLink) - 1; h >= 0 && next == nil; h-- {
+				fl := sb.ForwardLink[h]
+				if fl.IsEmpty() {
+					continue
+				}
+				n, err := db.getFromTx(tx, fl.To)
+				if err != nil {
+					return err
+				}
+				if n != nil && n.Index <= target.Index {
+					next = n
+				}
+			}
+			if next == nil {
+				return fmt.Errorf("couldn't find a path from block %d", sb.Index)
+			}
+			if next.Index <= sb.Index {
+				return ErrorInconsistentForwardLink
+			}
+			sb = next
+			sbs = append(sbs, sb)
+		}
+		return nil
+	})
+	return
+}
+
 // GetSkipchains returns all latest skipblocks from all skipchains.
 func (db *SkipBlockDB) GetSkipchains() (map[string]*SkipBlock, error) {
 	return db.getAllSkipchains()
@@ -1233,11 +1453,17 @@ func (db *SkipBlockDB) RemoveSkipchain(scid SkipBlockID) error {
 		if err != nil {
 			return err
 		}
+		cs := db.getColdStore()
 		for {
 			err := b.Delete(sb.Hash)
 			if err != nil {
 				return err
 			}
+			if cs != nil {
+				if err := cs.Delete(sb.Hash); err != nil {
+					return err
+				}
+			}
 			if len(sb.ForwardLink) == 0 {
 				return nil
 			}
@@ -1245,7 +1471,9 @@ func (db *SkipBlockDB) RemoveSkipchain(scid SkipBlockID) error {
 			var next *SkipBlock
 			for _, fl := range sb.ForwardLink {
 				n, err := db.getFromTx(tx, fl.To)
-				if err == nil {
+				// The target of a forward-link is missing in a
+				// pruned skipchain.
+				if err == nil && n != nil {
 					next = n
 					break
 				}
@@ -1263,42 +1491,299 @@ func (db *SkipBlockDB) RemoveSkipchain(scid SkipBlockID) error {
 func (db *SkipBlockDB) RemoveBlock(blockID SkipBlockID) error {
 	return db.Update(func(tx *bbolt.Tx) error {
 		b := tx.Bucket([]byte(db.bucketName))
+		if cs := db.getColdStore(); cs != nil {
+			if err := cs.Delete(blockID); err != nil {
+				return err
+			}
+		}
 		return b.Delete(blockID)
 	})
 }
 
+// Prune removes the blocks of a skipchain that are older than the checkpoint
+// block, to bound the size of the database. The proof from the genesis block
+// to the checkpoint is verified, and the blocks of this proof are kept, so that
+// new clients can still follow the forward-links from the genesis block. The
+// latest block of each height below the checkpoint is also kept, as the
+// back-links of new blocks point to them. The checkpoint and all the blocks
+// after it are left untouched. If a cold storage is set, the removed blocks
+// are moved there. It returns the number of removed blocks.
+func (db *SkipBlockDB) Prune(checkpoint SkipBlockID) (int, error) {
+	proof, err := db.GetProofForID(checkpoint)
+	if err != nil {
+		return 0, fmt.Errorf("couldn't get the proof of the checkpoint: %v", err)
+	}
+	if proof[0].Index != 0 {
+		return 0, errors.New("proof of the checkpoint must start with a genesis block")
+	}
+	if err := verifyLinkedBlocks(proof); err != nil {
+		return 0, fmt.Errorf("invalid proof of the checkpoint: %v", err)
+	}
+	target := proof[len(proof)-1]
+	keep := make(map[string]bool)
+	for _, sb := range proof {
+		keep[string(sb.Hash)] = true
+	}
+
+	pruned := 0
+	cs := db.getColdStore()
+	err = db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(db.bucketName)
+		var older []*SkipBlock
+		err := b.ForEach(func(k, v []byte) error {
+			sb, err := db.getFromTx(tx, k)
+			if err == ErrorCorruptedBlock {
+				// It is kept until it is fetched again.
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if sb.SkipChainID().Equal(target.SkipChainID()) && sb.Index < target.Index {
+				older = append(older, sb)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		sort.Slice(older, func(i, j int) bool { return older[i].Index > older[j].Index })
+		height := 0
+		for _, sb := range older {
+			if sb.Height > height {
+				keep[string(sb.Hash)] = true
+				height = sb.Height
+			}
+		}
+		for _, sb := range older {
+			if keep[string(sb.Hash)] {
+				continue
+			}
+			if cs != nil {
+				if err := cs.Put(sb.Hash, b.Get(sb.Hash)); err != nil {
+					return fmt.Errorf("couldn't move block to cold storage: %v", err)
+				}
+			}
+			if err := b.Delete(sb.Hash); err != nil {
+				return err
+			}
+			pruned++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	log.Lvlf2("Pruned %d blocks of skipchain %x", pruned, target.SkipChainID())
+	return pruned, nil
+}
+
+// verifyLinkedBlocks checks that each block is reached by a valid
+// forward-link of the previous block. Unlike Proof.Verify, the forward-link
+// doesn't need to be the highest one.
+func verifyLinkedBlocks(sbs []*SkipBlock) error {
+	if len(sbs) == 0 {
+		return errors.New("empty list of blocks")
+	}
+	for i, sb := range sbs {
+		if !sb.CalculateHash().Equal(sb.Hash) {
+			return errors.New("wrong hash")
+		}
+		if i == 0 {
+			continue
+		}
+		prev := sbs[i-1]
+		var link *ForwardLink
+		for _, fl := range prev.ForwardLink {
+			if !fl.IsEmpty() && fl.To.Equal(sb.Hash) {
+				link = fl
+			}
+		}
+		if link == nil || !link.From.Equal(prev.Hash) {
+			return fmt.Errorf("missing forward-link to block %d", sb.Index)
+		}
+		publics := prev.Roster.ServicePublics(ServiceName)
+		if err := link.VerifyWithScheme(suite, publics, prev.SignatureScheme); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // storeToTx stores the skipblock into the database.
 // An error is returned on failure.
 // The caller must ensure that this function is called from within a valid transaction.
 func (db *SkipBlockDB) storeToTx(tx *bbolt.Tx, sb *SkipBlock) error {
 	key := sb.Hash
-	val, err := network.Marshal(sb)
+	val, err := db.encodeBlock(sb)
 	if err != nil {
 		return err
 	}
 	return tx.Bucket([]byte(db.bucketName)).Put(key, val)
 }
 
-// getFromTx returns the skipblock identified by sbID.
-// nil is returned if the key does not exist.
-// An error is thrown if marshalling fails.
+// compressedPrefix starts the stored blocks that are compressed with snappy.
+// The uncompressed blocks start with the message type of SkipBlock instead.
+var compressedPrefix = []byte("\x00snappy")
+
+// SetCompression enables or disables the compression of the blocks stored
+// from now on. It is enabled by default. The blocks are read in both formats,
+// so it can be changed at any time.
+func (db *SkipBlockDB) SetCompression(enabled bool) {
+	db.uncompressed = !enabled
+}
+
+// encodeBlock marshals the block, and compresses it if it makes it smaller.
+func (db *SkipBlockDB) encodeBlock(sb *SkipBlock) ([]byte, error) {
+	buf, err := network.Marshal(sb)
+	if err != nil {
+		return nil, err
+	}
+	if db.uncompressed {
+		return buf, nil
+	}
+	compressed := append(append([]byte{}, compressedPrefix...), snappy.Encode(nil, buf)...)
+	if len(compressed) >= len(buf) {
+		return buf, nil
+	}
+	return compressed, nil
+}
+
+// decodeBlock returns the block stored in the value, decompressing it if
+// needed. The value is not modified and can be released afterwards.
+func decodeBlock(val []byte) (*SkipBlock, error) {
+	var buf []byte
+	if bytes.HasPrefix(val, compressedPrefix) {
+		var err error
+		buf, err = snappy.Decode(nil, val[len(compressedPrefix):])
+		if err != nil {
+			return nil, fmt.Errorf("couldn't decompress block: %v", err)
+		}
+	} else {
+		// For some reason boltdb changes the val before Unmarshal
+		// finishes. When copying the value into a buffer, there is no
+		// SIGSEGV anymore.
+		buf = make([]byte, len(val))
+		copy(buf, val)
+	}
+	_, msg, err := network.Unmarshal(buf, suite)
+	if err != nil {
+		return nil, err
+	}
+	sb, ok := msg.(*SkipBlock)
+	if !ok {
+		return nil, errors.New("stored value is not a skipblock")
+	}
+	return sb, nil
+}
+
+// getFromTx returns the skipblock identified by sbID, looking in the cold
+// storage if it is not in the database.
+// nil is returned if the key does not exist, and ErrorCorruptedBlock if the
+// stored block cannot be decoded. The hash of the block is not checked here,
+// but by checkIntegrity when the service starts.
 // The caller must ensure that this function is called from within a valid transaction.
 func (db *SkipBlockDB) getFromTx(tx *bbolt.Tx, sbID SkipBlockID) (*SkipBlock, error) {
 	val := tx.Bucket([]byte(db.bucketName)).Get(sbID)
 	if val == nil {
-		return nil, nil
+		cs := db.getColdStore()
+		if cs == nil {
+			return nil, nil
+		}
+		var err error
+		val, err = cs.Get(sbID)
+		if err != nil {
+			return nil, err
+		}
+		if val == nil {
+			return nil, nil
+		}
 	}
 
-	// For some reason boltdb changes the val before Unmarshal finishes. When
-	// copying the value into a buffer, there is no SIGSEGV anymore.
-	buf := make([]byte, len(val))
-	copy(buf, val)
-	_, sbMsg, err := network.Unmarshal(buf, suite)
+	sb, err := decodeBlock(val)
 	if err != nil {
-		return nil, err
+		log.Errorf("stored block %x cannot be decoded: %v", sbID, err)
+		db.reportCorrupted(corruptedBlock{id: sbID})
+		return nil, ErrorCorruptedBlock
+	}
+	return sb.Copy(), nil
+}
+
+// corruptedBlock is the ID of a damaged block, with its decoded copy if it
+// could be decoded.
+type corruptedBlock struct {
+	id SkipBlockID
+	sb *SkipBlock
+}
+
+// reportCorrupted sends the block to the corrupted channel without blocking.
+// If the channel is full, the block is reported again the next time it is
+// read.
+func (db *SkipBlockDB) reportCorrupted(cb corruptedBlock) {
+	if db.corrupted == nil {
+		return
+	}
+	select {
+	case db.corrupted <- cb:
+	default:
 	}
+}
 
-	return sbMsg.(*SkipBlock).Copy(), nil
+// checkIntegrity decodes all the blocks of the database and checks that they
+// match their ID, which is too costly to be done at each read. The damaged
+// blocks are sent to the corrupted channel. It returns early when stop is
+// closed.
+func (db *SkipBlockDB) checkIntegrity(stop <-chan bool) {
+	if db.corrupted == nil {
+		return
+	}
+	var keys [][]byte
+	err := db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(db.bucketName).ForEach(func(k, v []byte) error {
+			keys = append(keys, append([]byte{}, k...))
+			return nil
+		})
+	})
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	// Each block is checked in its own transaction to not hold the
+	// database for too long.
+	for _, k := range keys {
+		var cb *corruptedBlock
+		err := db.View(func(tx *bbolt.Tx) error {
+			val := tx.Bucket(db.bucketName).Get(k)
+			if val == nil {
+				return nil
+			}
+			sb, err := decodeBlock(val)
+			if err != nil {
+				cb = &corruptedBlock{id: k}
+			} else if !sb.CalculateHash().Equal(k) {
+				cb = &corruptedBlock{id: k, sb: sb}
+			}
+			return nil
+		})
+		if err != nil {
+			log.Error(err)
+			return
+		}
+		if cb != nil {
+			log.Errorf("stored block %x is corrupted", k)
+			select {
+			case db.corrupted <- *cb:
+			case <-stop:
+				return
+			}
+		}
+		select {
+		case <-stop:
+			return
+		default:
+		}
+	}
 }
 
 // getAll returns all the data in the database as a map
@@ -1310,14 +1795,11 @@ func (db *SkipBlockDB) getAll() (map[string]*SkipBlock, error) {
 	err := db.View(func(tx *bbolt.Tx) error {
 		b := tx.Bucket([]byte(db.bucketName))
 		return b.ForEach(func(k, v []byte) error {
-			_, sbMsg, err := network.Unmarshal(v, suite)
+			sb, err := decodeBlock(v)
 			if err != nil {
 				return err
 			}
-			sb, ok := sbMsg.(*SkipBlock)
-			if ok {
-				data[string(sb.Hash)] = sb
-			}
+			data[string(sb.Hash)] = sb
 			return nil
 		})
 	})
@@ -1339,20 +1821,17 @@ func (db *SkipBlockDB) getAllSkipchains() (map[string]*SkipBlock, error) {
 	err := db.View(func(tx *bbolt.Tx) error {
 		b := tx.Bucket([]byte(db.bucketName))
 		return b.ForEach(func(k, v []byte) error {
-			_, sbMsg, err := network.Unmarshal(v, suite)
+			sb, err := decodeBlock(v)
 			if err != nil {
 				return err
 			}
-			sb, ok := sbMsg.(*SkipBlock)
-			if ok {
-				k := string(sb.SkipChainID())
-				if cur, ok := gen[k]; ok {
-					if cur.Index < sb.Index {
-						gen[k] = sb
-					}
-				} else {
-					gen[k] = sb
+			id := string(sb.SkipChainID())
+			if cur, ok := gen[id]; ok {
+				if cur.Index < sb.Index {
+					gen[id] = sb
 				}
+			} else {
+				gen[id] = sb
 			}
 			return nil
 		})
@@ -415,6 +415,146 @@ func TestSkipBlockDB_GetProof(t *testing.T) {
 	require.Error(t, err)
 }
 
+func TestSkipBlockDB_Compression(t *testing.T) {
+	local := onet.NewLocalTest(suite)
+	_, ro, _ := local.GenTree(2, false)
+	defer local.CloseAll()
+
+	db, file := setupSkipBlockDB(t)
+	defer os.Remove(file)
+	stored := func(sb *SkipBlock) []byte {
+		var val []byte
+		require.NoError(t, db.View(func(tx *bbolt.Tx) error {
+			val = append([]byte{}, tx.Bucket(db.bucketName).Get(sb.Hash)...)
+			return nil
+		}))
+		return val
+	}
+
+	gen := createSignedChain(t, ro, 1, 2, 2)[0]
+	gen.Data = bytes.Repeat([]byte("transaction"), 1000)
+	gen.updateHash()
+	plain, err := network.Marshal(gen)
+	require.NoError(t, err)
+	require.False(t, bytes.HasPrefix(plain, compressedPrefix))
+
+	db.Store(gen)
+	val := stored(gen)
+	require.True(t, bytes.HasPrefix(val, compressedPrefix))
+	require.True(t, len(val) < len(plain)/10)
+	require.True(t, db.GetByID(gen.Hash).Hash.Equal(gen.Hash))
+	require.Equal(t, gen.Data, db.GetByID(gen.Hash).Data)
+
+	// Blocks stored uncompressed are still read.
+	db.SetCompression(false)
+	other := createSignedChain(t, ro, 1, 2, 3)[0]
+	db.Store(other)
+	require.False(t, bytes.HasPrefix(stored(other), compressedPrefix))
+	db.SetCompression(true)
+	require.True(t, db.GetByID(other.Hash).Hash.Equal(other.Hash))
+	chains, err := db.GetSkipchains()
+	require.NoError(t, err)
+	require.Equal(t, 2, len(chains))
+}
+
+func TestRosterPolicy_Check(t *testing.T) {
+	local := onet.NewLocalTest(suite)
+	_, ro, _ := local.GenTree(7, false)
+	defer local.CloseAll()
+	prev := onet.NewRoster(ro.List[:4])
+
+	require.NoError(t, DefaultRosterPolicy.Check(prev, prev))
+	require.NoError(t, DefaultRosterPolicy.Check(prev, onet.NewRoster(ro.List[:5])))
+	require.NoError(t, DefaultRosterPolicy.Check(prev, onet.NewRoster(ro.List[1:5])))
+	// Half of the previous roster is removed.
+	require.Error(t, DefaultRosterPolicy.Check(prev, onet.NewRoster(ro.List[2:])))
+	// The new nodes are more than a third of the new roster.
+	require.Error(t, DefaultRosterPolicy.Check(prev, ro))
+
+	rp := RosterPolicy{MaxChurn: 2}
+	require.NoError(t, rp.Check(prev, onet.NewRoster(ro.List[1:5])))
+	require.NoError(t, rp.Check(prev, onet.NewRoster(ro.List[2:4])))
+	require.Error(t, rp.Check(prev, onet.NewRoster(ro.List[2:5])))
+	require.Error(t, rp.Check(prev, onet.NewRoster(ro.List[:7])))
+}
+
+func TestSkipBlockDB_Prune(t *testing.T) {
+	local := onet.NewLocalTest(suite)
+	_, ro, _ := local.GenTree(2, false)
+	defer local.CloseAll()
+
+	db, file := setupSkipBlockDB(t)
+	defer os.Remove(file)
+
+	blocks := createSignedChain(t, ro, 20, 2, 5)
+	_, err := db.StoreBlocks(blocks)
+	require.NoError(t, err)
+
+	_, err = db.Prune(SkipBlockID{1, 2, 3})
+	require.Error(t, err)
+
+	// The proof from the genesis to the checkpoint goes through the blocks 8
+	// and 12, which are also the latest blocks of their heights.
+	pruned, err := db.Prune(blocks[13].Hash)
+	require.NoError(t, err)
+	require.Equal(t, 10, pruned)
+	for _, sb := range blocks {
+		kept := sb.Index >= 13 || sb.Index == 0 || sb.Index == 8 || sb.Index == 12
+		require.Equal(t, kept, db.GetByID(sb.Hash) != nil, "block %d", sb.Index)
+	}
+
+	// New clients can still verify the chain from the genesis block.
+	proof, err := db.GetProofForID(blocks[19].Hash)
+	require.NoError(t, err)
+	require.NoError(t, proof.Verify())
+	update, err := db.GetProof(blocks[0].Hash)
+	require.NoError(t, err)
+	require.True(t, update[len(update)-1].Hash.Equal(blocks[19].Hash))
+
+	pruned, err = db.Prune(blocks[13].Hash)
+	require.NoError(t, err)
+	require.Equal(t, 0, pruned)
+	require.NoError(t, db.RemoveSkipchain(blocks[0].Hash))
+	require.Equal(t, 0, db.Length())
+}
+
+func TestSkipBlockDB_GetShortestPath(t *testing.T) {
+	local := onet.NewLocalTest(suite)
+	_, ro, _ := local.GenTree(2, false)
+	defer local.CloseAll()
+
+	db, file := setupSkipBlockDB(t)
+	defer os.Remove(file)
+
+	blocks := createSignedChain(t, ro, 20, 2, 5)
+	_, err := db.StoreBlocks(blocks)
+	require.NoError(t, err)
+
+	path, err := db.GetShortestPath(blocks[3].Hash, blocks[19].Hash)
+	require.NoError(t, err)
+	var indexes []int
+	for _, sb := range path {
+		indexes = append(indexes, sb.Index)
+	}
+	require.Equal(t, []int{3, 4, 8, 16, 18, 19}, indexes)
+	require.NoError(t, verifyLinkedBlocks(path))
+
+	path, err = db.GetShortestPath(blocks[5].Hash, blocks[5].Hash)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(path))
+
+	_, err = db.GetShortestPath(blocks[19].Hash, blocks[3].Hash)
+	require.Error(t, err)
+	_, err = db.GetShortestPath(blocks[3].Hash, SkipBlockID{1})
+	require.Error(t, err)
+
+	// A wrong signature is detected.
+	path, err = db.GetShortestPath(blocks[0].Hash, blocks[19].Hash)
+	require.NoError(t, err)
+	path[0].ForwardLink[len(path[0].ForwardLink)-1].Signature.Sig[0] ^= 1
+	require.Error(t, verifyLinkedBlocks(path))
+}
+
 // Test the edge cases of the verification function
 func TestProof_Verify(t *testing.T) {
 	sb := NewSkipBlock()
@@ -510,3 +650,37 @@ func (fl *ForwardLink) sign(ro *onet.Roster) error {
 
 	return nil
 }
+
+// createSignedChain creates a skipchain of n blocks with the given base and
+// maximum height, where all the forward-links are signed by the roster.
+func createSignedChain(t *testing.T, ro *onet.Roster, n, base, maxHeight int) []*SkipBlock {
+	blocks := make([]*SkipBlock, n)
+	for i := range blocks {
+		sb := NewSkipBlock()
+		sb.Roster = ro
+		sb.Index = i
+		sb.BaseHeight = base
+		sb.MaximumHeight = maxHeight
+		sb.Height = maxHeight
+		if i > 0 {
+			sb.GenesisID = blocks[0].Hash
+			sb.Height = 1
+			for step := base; sb.Height < maxHeight && i%step == 0; step *= base {
+				sb.Height++
+			}
+			for h, step := 0, 1; h < sb.Height; h, step = h+1, step*base {
+				sb.BackLinkIDs = append(sb.BackLinkIDs, blocks[i-step].Hash)
+			}
+		}
+		sb.updateHash()
+		blocks[i] = sb
+	}
+	for _, sb := range blocks {
+		for h, step := 0, 1; h < sb.Height && sb.Index+step < n; h, step = h+1, step*base {
+			fl := NewForwardLink(sb, blocks[sb.Index+step])
+			require.NoError(t, fl.sign(ro))
+			sb.ForwardLink = append(sb.ForwardLink, fl)
+		}
+	}
+	return blocks
+}
@@ -0,0 +1,72 @@
+package skipchain
+
+import (
+	"time"
+
+	"go.dedis.ch/onet/v3/log"
+)
+
+// chainTimeouts holds the timeouts of a skipchain that replace the ones of
+// the service. A zero duration keeps the timeout of the service.
+type chainTimeouts struct {
+	propagation  time.Duration
+	verification time.Duration
+}
+
+// SetChainTimeouts sets the timeouts used for the propagation of the blocks
+// and forward-links of a skipchain, and for the collective verification and
+// signature of its new blocks. This lets an application store the timeouts
+// with its chain, for example if its roster spans slow links, and let the
+// roster adjust them. A zero duration uses the timeout of the service.
+func (s *Service) SetChainTimeouts(scid SkipBlockID, propagation, verification time.Duration) {
+	s.chainTimeoutsMutex.Lock()
+	defer s.chainTimeoutsMutex.Unlock()
+	if s.chainTimeouts == nil {
+		s.chainTimeouts = make(map[string]chainTimeouts)
+	}
+	key := string(scid)
+	ct := chainTimeouts{propagation: propagation, verification: verification}
+	if s.chainTimeouts[key] == ct {
+		return
+	}
+	log.Lvlf2("%s: timeouts of %x set to propagation=%s verification=%s",
+		s.ServerIdentity(), []byte(scid), propagation, verification)
+	if propagation == 0 && verification == 0 {
+		delete(s.chainTimeouts, key)
+		return
+	}
+	s.chainTimeouts[key] = ct
+}
+
+// ChainTimeouts returns the propagation and verification timeouts used for
+// the skipchain.
+func (s *Service) ChainTimeouts(scid SkipBlockID) (propagation, verification time.Duration) {
+	return s.propTimeoutOf(scid), s.bftTimeoutOf(scid)
+}
+
+// getChainTimeouts returns the timeouts set for the skipchain.
+func (s *Service) getChainTimeouts(scid SkipBlockID) chainTimeouts {
+	s.chainTimeoutsMutex.Lock()
+	defer s.chainTimeoutsMutex.Unlock()
+	return s.chainTimeouts[string(scid)]
+}
+
+// propTimeoutOf returns the propagation timeout of the skipchain.
+func (s *Service) propTimeoutOf(scid SkipBlockID) time.Duration {
+	if t := s.getChainTimeouts(scid).propagation; t > 0 {
+		return t
+	}
+	return s.propTimeout
+}
+
+// bftTimeoutOf returns the timeout of the collective signature of a new block
+// of the skipchain.
+func (s *Service) bftTimeoutOf(scid SkipBlockID) time.Duration {
+	if t := s.getChainTimeouts(scid).verification; t > 0 {
+		return t
+	}
+	if s.bftTimeout != 0 {
+		return s.bftTimeout
+	}
+	return s.propTimeout
+}
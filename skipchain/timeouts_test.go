@@ -0,0 +1,46 @@
+package skipchain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/onet/v3"
+)
+
+func TestService_SetChainTimeouts(t *testing.T) {
+	local := onet.NewLocalTest(cothority.Suite)
+	defer waitPropagationFinished(t, local)
+	defer local.CloseAll()
+	_, ro, genService := local.MakeSRS(cothority.Suite, 3, skipchainSID)
+	s := genService.(*Service)
+	s.SetPropTimeout(time.Second)
+	s.SetBFTTimeout(0)
+
+	scid := SkipBlockID{1, 2, 3}
+	require.Equal(t, time.Second, s.propTimeoutOf(scid))
+	require.Equal(t, time.Second, s.bftTimeoutOf(scid))
+
+	s.SetChainTimeouts(scid, 2*time.Second, 3*time.Second)
+	require.Equal(t, 2*time.Second, s.propTimeoutOf(scid))
+	require.Equal(t, 3*time.Second, s.bftTimeoutOf(scid))
+	require.Equal(t, time.Second, s.propTimeoutOf(SkipBlockID{4}))
+
+	s.SetChainTimeouts(scid, 0, 3*time.Second)
+	require.Equal(t, time.Second, s.propTimeoutOf(scid))
+	s.SetChainTimeouts(scid, 0, 0)
+	require.Equal(t, time.Second, s.bftTimeoutOf(scid))
+	require.Empty(t, s.chainTimeouts)
+
+	// Blocks are still created with the timeouts of the chain.
+	s.SetPropTimeout(defaultPropagateTimeout)
+	genesis, err := makeGenesisRosterArgs(s, ro, nil, VerificationNone, 2, 3)
+	require.NoError(t, err)
+	s.SetChainTimeouts(genesis.Hash, 10*time.Second, 10*time.Second)
+	next := genesis.Copy()
+	next.BackLinkIDs = []SkipBlockID{genesis.Hash}
+	reply, err := s.StoreSkipBlock(&StoreSkipBlock{TargetSkipChainID: genesis.Hash, NewBlock: next})
+	require.NoError(t, err)
+	require.Equal(t, 1, reply.Latest.Index)
+}
@@ -1,6 +1,11 @@
 package skipchain
 
-import "go.dedis.ch/onet/v3/log"
+import (
+	"fmt"
+	"time"
+
+	"go.dedis.ch/onet/v3/log"
+)
 
 /*
 This file holds all verification-functions for the skipchain.
@@ -43,3 +48,56 @@ func (s *Service) verifyFuncBase(newID []byte, newSB *SkipBlock) bool {
 	log.Lvl4("No verification - accepted")
 	return true
 }
+
+// verifyFuncRosterChange checks that the roster change between the previous
+// block and the new one respects the policy of the skipchain.
+func (s *Service) verifyFuncRosterChange(newID []byte, newSB *SkipBlock) bool {
+	if newSB.Index == 0 {
+		return true
+	}
+	prev := s.db.GetByID(newSB.BackLinkIDs[0])
+	if prev == nil {
+		log.Lvl2("Didn't find previous block")
+		return false
+	}
+	policy := s.getRosterPolicy(newSB.SkipChainID())
+	if err := policy.Check(prev.Roster, newSB.Roster); err != nil {
+		log.Lvl2("Refused roster change:", err)
+		return false
+	}
+	return true
+}
+
+// CheckTimestamp returns an error if the timestamp is more than window away
+// from the local clock, so that a leader cannot pre- or post-date a block.
+func CheckTimestamp(ts time.Time, window time.Duration) error {
+	now := time.Now()
+	t1 := now.Add(-window)
+	t2 := now.Add(window)
+	if ts.Before(t1) || ts.After(t2) {
+		return fmt.Errorf("timestamp %v is outside the acceptable range %v to %v", ts, t1, t2)
+	}
+	return nil
+}
+
+// NewTimestampVerifier returns a verification that refuses the new blocks
+// whose timestamp is more than window away from the local clock of the
+// verifier. As the skipblocks don't have a timestamp, the application gives
+// the function reading it from its header in the data of the block. The
+// verification has to be registered with RegisterVerification under an ID of
+// the application.
+func NewTimestampVerifier(window time.Duration,
+	timestamp func(*SkipBlock) (time.Time, error)) SkipBlockVerifier {
+	return func(newID []byte, newSB *SkipBlock) bool {
+		ts, err := timestamp(newSB)
+		if err != nil {
+			log.Lvl2("Couldn't get the timestamp:", err)
+			return false
+		}
+		if err := CheckTimestamp(ts, window); err != nil {
+			log.Lvl2("Refused block:", err)
+			return false
+		}
+		return true
+	}
+}